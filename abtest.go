@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/report"
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// runABTest implements the ab-test command, which builds selected resources at two obfuscation
+// levels as separately named resources, so their client load times can be compared
+func runABTest(args []string) error {
+	fs := flag.NewFlagSet("ab-test", flag.ExitOnError)
+	levels := fs.String("levels", "2,3", "the two obfuscation levels to compare, comma-separated")
+	only := fs.String("only", "", "comma-separated resource names or path globs to build (default all)")
+	outputDir := fs.String("o", "", "output directory for the variant resources (required)")
+	strip := fs.Bool("s", true, "strip debug information")
+	suppress := fs.Bool("d", false, "suppress decompile warning")
+	merge := fs.Bool("m", false, "merge all scripts into client.luac and server.luac")
+	reportPath := fs.String("report", "", "path of the measurement template (default <output>/ab-report.md)")
+	sizes := fs.String("size-units", "", "units of sizes in the template: binary (KiB, MiB) or decimal (kB, MB)")
+	locale := fs.String("locale", "", "format numbers in the template for this locale, such as de or pt-BR (auto reads LANG)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ab-test -o <dir> [options] <input_path>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Builds each selected resource once per obfuscation level as <resource>_e<level>,\n")
+		fmt.Fprintf(os.Stderr, "with a client script logging its load time, and writes a report template\n")
+		fmt.Fprintf(os.Stderr, "for recording the measured load times.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one input path")
+	}
+	inputPath := fs.Arg(0)
+	if *outputDir == "" {
+		return fmt.Errorf("an output directory is required (-o)")
+	}
+	if err := validateInputPath(inputPath); err != nil {
+		return err
+	}
+
+	abUnits, err := units.ParseSizeUnits(*sizes)
+	if err != nil {
+		return fmt.Errorf("-size-units: %v", err)
+	}
+	abLocale, err := units.ParseLocale(*locale)
+	if err != nil {
+		return fmt.Errorf("-locale: %v", err)
+	}
+
+	variants, err := parseVariants(*levels, compiler.CompilationOptions{
+		StripDebug:               *strip,
+		SuppressDecompileWarning: *suppress,
+	})
+	if err != nil {
+		return err
+	}
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+
+	b := bundler.NewBundler(cliCompiler, bundler.Options{
+		InputPath: inputPath,
+		OutputDir: *outputDir,
+		MergeMode: *merge,
+		Only:      splitList(*only),
+	})
+
+	metaPaths, err := b.FindResources()
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Building A/B variants", "resources", len(metaPaths), "variants", len(variants))
+	results := b.BuildVariants(metaPaths, variants)
+
+	if *reportPath == "" {
+		*reportPath = filepath.Join(*outputDir, "ab-report.md")
+	}
+	if err := report.WriteABTemplate(*reportPath, inputPath, results, units.Format{Sizes: abUnits, Locale: abLocale}); err != nil {
+		return err
+	}
+	slog.Info("Wrote A/B report template", "path", *reportPath, "success", true)
+
+	var failed int
+	for _, result := range results {
+		if result.Result.Error != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d variant(s) failed to build", failed, len(results))
+	}
+	return nil
+}
+
+// parseVariants parses a comma-separated pair of distinct obfuscation levels into variants
+// sharing the other options
+func parseVariants(spec string, options compiler.CompilationOptions) ([]bundler.Variant, error) {
+	parts := strings.Split(spec, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid levels %q: expected two comma-separated obfuscation levels", spec)
+	}
+
+	variants := make([]bundler.Variant, 0, len(parts))
+	for _, part := range parts {
+		level, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || level < 0 || level > 3 {
+			return nil, fmt.Errorf("invalid obfuscation level %q (must be 0-3)", part)
+		}
+		variant := bundler.Variant{Name: fmt.Sprintf("e%d", level), Options: options}
+		variant.Options.ObfuscationLevel = compiler.ObfuscationLevel(level)
+		variants = append(variants, variant)
+	}
+
+	if variants[0].Name == variants[1].Name {
+		return nil, fmt.Errorf("invalid levels %q: the two levels must differ", spec)
+	}
+	return variants, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+)
+
+// checkResources validates the resources of inputPath for -check-only: the compiler must be
+// available and match the lock file, and every meta.xml must parse and reference existing
+// files. Nothing is compiled or written. Each problem is logged, and an error is returned
+// when there is any, so the exit status reports the result. Cases a build would leave for a
+// person to review are logged as warnings and do not fail the check.
+func checkResources(inputPath string, exclude []string) error {
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	// A check never records the compiler, an existing lock file must already match it
+	if _, err := os.Stat(lockFilePath); err == nil {
+		*frozenLock = true
+		if err := checkLockFile(cliCompiler); err != nil {
+			return err
+		}
+	}
+
+	b := bundler.NewBundler(cliCompiler, bundler.Options{
+		InputPath:      inputPath,
+		Inputs:         inputPaths,
+		MergeMode:      *mergeMode,
+		BundleName:     *bundleName,
+		Exclude:        append(exclude, splitList(*excludeList)...),
+		Ignore:         append(ignorePatterns, splitList(*ignoreList)...),
+		MaxDepth:       *maxDepth,
+		SkipCategories: append(categoryPatterns, splitList(*skipCategories)...),
+		FollowSymlinks: *followSymlinks,
+		Subtrees:       subtrees,
+		Only:           splitList(*onlyResources),
+		Verbatim:       append(verbatimPatterns, splitList(*verbatimList)...),
+		Unmerged:       append(mergeExcludePatterns, splitList(*mergeExclude)...),
+		Scan:           *scanBackdoors,
+		NoSyntaxCheck:  !*syntaxCheck,
+		Lint:           lintRules,
+	})
+	result, err := b.Check()
+	if err != nil {
+		return err
+	}
+	return reportCheck(result, "Check completed")
+}
+
+// reportCheck logs each problem, lint warning and attention case of result and a summary with message, and
+// returns an error when there is any problem
+func reportCheck(result bundler.CheckResult, message string) error {
+	for _, problem := range result.Problems {
+		attrs := []any{"resource", problem.Resource}
+		if problem.Src != "" {
+			attrs = append(attrs, "file", problem.Src)
+		}
+		slog.Error(problem.Message, attrs...)
+	}
+
+	for _, warning := range result.Warnings {
+		attrs := []any{"resource", warning.Resource}
+		if warning.Src != "" {
+			attrs = append(attrs, "file", warning.Src)
+		}
+		slog.Warn(warning.Message, attrs...)
+	}
+	bundler.LogAttention(result.Attention)
+
+	summary := []any{"resources", result.Resources, "problems", len(result.Problems)}
+	if len(result.Warnings) > 0 {
+		summary = append(summary, "warnings", len(result.Warnings))
+	}
+	if len(result.Attention) > 0 {
+		summary = append(summary, "attention", len(result.Attention))
+	}
+	slog.Log(context.Background(), bundler.LevelSummary, message, summary...)
+	if len(result.Problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(result.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// runCompilerCommand implements the compiler command, which manages the luac_mta binary of a project
+func runCompilerCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compiler vendor [options] [project_dir]\n", filepath.Base(os.Args[0]))
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("expected a compiler subcommand")
+	}
+
+	switch args[0] {
+	case "vendor":
+		return runCompilerVendor(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown compiler subcommand %q", args[0])
+	}
+}
+
+// runCompilerVendor copies the detected luac_mta binary into the project and pins its hash in
+// the project config file, so every build of the project uses that exact binary
+func runCompilerVendor(args []string) error {
+	fs := flag.NewFlagSet("compiler vendor", flag.ExitOnError)
+	dir := fs.String("dir", "tools", "directory the binary is copied to, relative to the project")
+	cfgPath := fs.String("config", "", "config file to pin the binary in (default is "+config.FileName+" in the project)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compiler vendor [options] [project_dir]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Copies the detected or downloaded luac_mta binary into the project (default: the\n")
+		fmt.Fprintf(os.Stderr, "current directory) and records its path and SHA-256 hash in the lock section of\n")
+		fmt.Fprintf(os.Stderr, "the config file. Builds then use that binary and fail if it was modified.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected at most one project directory")
+	}
+	projectDir := "."
+	if fs.NArg() == 1 {
+		projectDir = fs.Arg(0)
+	}
+	if *cfgPath == "" {
+		*cfgPath = filepath.Join(projectDir, config.FileName)
+	}
+
+	source, explicit, err := explicitCompiler()
+	if err == nil && !explicit {
+		source, err = detectCompiler()
+	}
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(projectDir, *dir, filepath.Base(source))
+	if err := copyBinary(source, target); err != nil {
+		return err
+	}
+	hash, err := compiler.HashBinary(target)
+	if err != nil {
+		return err
+	}
+
+	// The lock path is relative to the config file so the project can be moved or cloned
+	absConfig, err := filepath.Abs(*cfgPath)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute config path: %v", err)
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute binary path: %v", err)
+	}
+	lockPath, err := filepath.Rel(filepath.Dir(absConfig), absTarget)
+	if err != nil {
+		lockPath = absTarget
+	}
+
+	if err := config.SetCompilerLock(absConfig, config.CompilerLock{Path: filepath.ToSlash(lockPath), SHA256: hash}); err != nil {
+		return err
+	}
+	if _, err := config.Load(absConfig); err != nil {
+		return err
+	}
+
+	slog.Info("Vendored luac_mta", "from", source, "to", target, "sha256", hash[:12], "config", absConfig, "success", true)
+	return nil
+}
+
+// explicitCompiler returns the luac_mta binary given with -compiler, or else with the LUAC_MTA
+// environment variable, after checking that it runs. It returns false when neither is set and
+// the binary is detected.
+func explicitCompiler() (string, bool, error) {
+	path, source := *compilerPath, "-compiler"
+	if path == "" {
+		path, source = os.Getenv(compiler.BinaryEnv), compiler.BinaryEnv
+	}
+	if path == "" {
+		return "", false, nil
+	}
+
+	// An absolute path, so a bare file name is not looked up in PATH
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: cannot get absolute path: %v", source, err)
+	}
+	if err := newBinaryDetector(nil).ValidatePath(absPath); err != nil {
+		return "", true, fmt.Errorf("%s: %v", source, err)
+	}
+
+	slog.Info("Using given compiler", "path", absPath, "from", source)
+	return absPath, true, nil
+}
+
+// lockedCompiler returns the path of the binary pinned by a compiler lock after checking that
+// it still has the pinned hash and runs on this system
+func lockedCompiler(lock config.CompilerLock) (string, error) {
+	hash, err := compiler.HashBinary(lock.Path)
+	if err != nil {
+		return "", fmt.Errorf("vendored compiler: %v", err)
+	}
+	if hash != lock.SHA256 {
+		return "", fmt.Errorf("vendored compiler %s does not match the hash pinned in the config file (expected %s, got %s), run \"compiler vendor\" to pin the new binary",
+			lock.Path, lock.SHA256[:12], hash[:12])
+	}
+	if err := newBinaryDetector(nil).ValidatePath(lock.Path); err != nil {
+		return "", fmt.Errorf("vendored compiler: %v", err)
+	}
+
+	slog.Info("Using vendored compiler", "path", lock.Path)
+	return lock.Path, nil
+}
+
+// copyBinary copies an executable to target, creating its directory
+func copyBinary(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(target), err)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to read luac_mta binary: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", target, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %v", target, err)
+	}
+	return out.Close()
+}
+
+// lockedChecksum returns the hash the lock file records for the compiler of this platform, which
+// a downloaded luac_mta must have, or an empty string when there is none
+func lockedChecksum() string {
+	if lockFilePath == "" {
+		return ""
+	}
+	lock, _, err := config.LoadLockFile(lockFilePath)
+	if err != nil {
+		// Reported by checkLockFile after the compiler is resolved
+		return ""
+	}
+	return lock.Compiler[runtime.GOOS+"/"+runtime.GOARCH]
+}
+
+// checkLockFile compares the resolved luac_mta binary with the one recorded in the lock file.
+// A different or missing entry is recorded, or fails the build with -frozen. A missing lock file is
+// only created when lockWritable is set.
+func checkLockFile(cliCompiler compiler.CLICompiler) error {
+	// The compile API is not a binary that can be pinned
+	if lockFilePath == "" || cliCompiler.Remote() {
+		return nil
+	}
+
+	hash, err := cliCompiler.Fingerprint()
+	if err != nil {
+		return err
+	}
+	lock, exists, err := config.LoadLockFile(lockFilePath)
+	if err != nil {
+		return err
+	}
+
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	locked := lock.Compiler[platform]
+	if locked == hash {
+		slog.Debug("Compiler matches the lock file", "path", lockFilePath, "platform", platform)
+		return nil
+	}
+
+	if *frozenLock {
+		switch {
+		case !exists:
+			return fmt.Errorf("-frozen: no lock file at %s, run a build without -frozen to create it", lockFilePath)
+		case locked == "":
+			return fmt.Errorf("-frozen: %s has no compiler for %s, run a build without -frozen on this platform to add it", lockFilePath, platform)
+		default:
+			return fmt.Errorf("-frozen: luac_mta resolved to %s but %s pins %s for %s", hash[:12], lockFilePath, locked[:12], platform)
+		}
+	}
+
+	// Without a config file, builds do not leave a lock file in the input tree unless asked to
+	if !exists && !lockWritable {
+		slog.Debug("Not creating a lock file without a config file, use -lock to create one", "path", lockFilePath)
+		return nil
+	}
+	if locked != "" {
+		slog.Warn("Compiler changed since the lock file was written", "platform", platform, "from", locked[:12], "to", hash[:12])
+	}
+	lock.Compiler[platform] = hash
+	if err := lock.Write(lockFilePath); err != nil {
+		return err
+	}
+	slog.Info("Updated lock file", "path", lockFilePath, "platform", platform)
+	return nil
+}
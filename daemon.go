@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// daemonRequest is what a request command sends the daemon, one JSON line per connection
+type daemonRequest struct {
+	Workspace string `json:"workspace,omitempty"` // Workspace to build, required by daemons serving a workspaces file
+	Force     bool   `json:"force,omitempty"`     // Rebuild every resource, even those unchanged since the last build
+	Stop      bool   `json:"stop,omitempty"`      // Stop the daemon instead of building
+}
+
+// daemonResponse is the outcome of a request, sent back as one JSON line
+type daemonResponse struct {
+	Resources int             `json:"resources"`
+	Unchanged int             `json:"unchanged"`
+	Failed    []daemonFailure `json:"failed,omitempty"`
+	Duration  time.Duration   `json:"duration"`
+	Error     string          `json:"error,omitempty"` // Why the build failed, empty when it succeeded
+}
+
+// daemonFailure is a resource that failed to build
+type daemonFailure struct {
+	Resource string `json:"resource"`
+	Error    string `json:"error"`
+}
+
+// daemon builds the input whenever a request comes in, keeping the compiler, the bundler and
+// its cache between builds. A daemon serving a workspaces file builds the workspace each
+// request names instead.
+type daemon struct {
+	bundler    bundler.Bundler
+	cache      *bundler.Cache
+	inputPath  string
+	reportPath string
+	workspaces map[string]daemonWorkspace // Workspaces by name, nil for a daemon building a single input
+	compiler   compiler.CLICompiler       // Compiler of the workspace builds
+	ctx        context.Context            // Stops the build running once done
+	stop       context.CancelFunc
+	mu         sync.Mutex // Builds run one at a time
+}
+
+// daemonWorkspace is a workspace served by a daemon
+type daemonWorkspace struct {
+	workspace config.Workspace
+	cache     *bundler.Cache // Shared with no other workspace
+}
+
+// runDaemon implements the daemon command, which keeps running and builds the input whenever
+// the request command asks for it
+func runDaemon(args []string) error {
+	socketPath := flag.String("socket", "", "unix socket accepting build requests (default: one per input path in the user cache directory)")
+	workspacesPath := flag.String("workspaces", "", "serve every workspace listed in this file, each request naming the workspace to build")
+	flag.Usage = func() {
+		binaryName := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s daemon [options] <input_path>\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s daemon -workspaces <file>\n\n", binaryName)
+		fmt.Fprintf(os.Stderr, "Builds the input, then keeps the compiler, file hashes and parsed meta.xml files in memory\n")
+		fmt.Fprintf(os.Stderr, "and builds again whenever \"%s request\" asks for it, until interrupted.\n", binaryName)
+		fmt.Fprintf(os.Stderr, "All build options are accepted and apply to every build.\n")
+		fmt.Fprintf(os.Stderr, "With -workspaces, each request builds the workspace it names, only from its own config file.\n\nOptions:\n")
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+
+	if watchMode {
+		return fmt.Errorf("the daemon does not support watch mode")
+	}
+	if *stampSpec != "" {
+		return fmt.Errorf("-stamp cannot be used with the daemon, every build would carry the same number")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if *workspacesPath != "" {
+		return serveDaemonWorkspaces(ctx, cancel, *workspacesPath, *socketPath)
+	}
+
+	inputPath, reportPath, cfg, err := prepareBuild()
+	if err != nil {
+		return err
+	}
+	path := *socketPath
+	if path == "" {
+		if path, err = daemonSocketPath(inputPath); err != nil {
+			return err
+		}
+	}
+	listener, err := listenDaemon(path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	if err := checkLockFile(cliCompiler); err != nil {
+		return err
+	}
+	buildCache = bundler.NewCache()
+	defer buildCache.Close()
+	b, err := newBundler(cliCompiler, inputPath, cfg.Exclude, nil)
+	if err != nil {
+		return err
+	}
+
+	// Interrupting or stopping the daemon also stops the build running
+	d := &daemon{bundler: b.WithContext(ctx), cache: buildCache, inputPath: inputPath, reportPath: reportPath, ctx: ctx, stop: cancel}
+	// The first build fills the cache
+	d.build(daemonRequest{})
+	slog.Info("Daemon listening", "socket", path, "input", inputPath)
+	return d.accept(listener)
+}
+
+// serveDaemonWorkspaces implements the daemon command for a workspaces file. Each workspace
+// keeps its own cache, filled by its first build.
+func serveDaemonWorkspaces(ctx context.Context, cancel context.CancelFunc, workspacesPath, socketPath string) error {
+	if err := setupWorkspaces("socket"); err != nil {
+		return err
+	}
+	workspaces, err := config.LoadWorkspaces(workspacesPath)
+	if err != nil {
+		return err
+	}
+	path := socketPath
+	if path == "" {
+		if path, err = daemonSocketPath(workspacesPath); err != nil {
+			return err
+		}
+	}
+	listener, err := listenDaemon(path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	d := &daemon{workspaces: make(map[string]daemonWorkspace), compiler: cliCompiler, ctx: ctx, stop: cancel}
+	targets := make(map[string]string)
+	for _, ws := range workspaces {
+		// Config files are read again for every build, checked now so a broken one is found early
+		if err := validateInputPath(ws.Input); err != nil {
+			return fmt.Errorf("workspace %q: %v", ws.Name, err)
+		}
+		cfg, err := ws.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("workspace %q: %v", ws.Name, err)
+		}
+		if _, err := workspaceBundler(ws, cfg, cliCompiler, nil); err != nil {
+			return fmt.Errorf("workspace %q: %v", ws.Name, err)
+		}
+		if err := claimOutput(targets, ws, cfg.Output); err != nil {
+			return err
+		}
+		cache := bundler.NewCache()
+		defer cache.Close()
+		d.workspaces[ws.Name] = daemonWorkspace{workspace: ws, cache: cache}
+	}
+	slog.Info("Daemon listening", "socket", path, "workspaces", len(workspaces))
+	return d.accept(listener)
+}
+
+// accept serves the connections of listener until the daemon stops
+func (d *daemon) accept(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if d.ctx.Err() != nil {
+				slog.Info("Daemon stopped")
+				return nil
+			}
+			return fmt.Errorf("daemon socket failed: %v", err)
+		}
+		go d.serve(conn)
+	}
+}
+
+// serve answers the request of a connection
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var request daemonRequest
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		// Daemons starting for the same socket connect to check whether it is in use
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		slog.Warn("Ignoring unreadable daemon request", "error", err)
+		return
+	}
+	var response daemonResponse
+	if request.Stop {
+		slog.Info("Stop requested")
+		d.stop()
+	} else {
+		response = d.build(request)
+	}
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		slog.Warn("Cannot answer daemon request", "error", err)
+	}
+}
+
+// build runs a build for request and returns its outcome
+func (d *daemon) build(request daemonRequest) daemonResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b, cache, inputPath, reportPath, hooks := d.bundler, d.cache, d.inputPath, d.reportPath, webhooks
+	if d.workspaces != nil {
+		ws, err := d.workspace(request.Workspace)
+		if err != nil {
+			slog.Warn("Rejected build request", "error", err)
+			return daemonResponse{Error: err.Error()}
+		}
+		// The config file is read for every build, so its changes apply without a restart
+		cfg, err := ws.workspace.LoadConfig()
+		if err == nil {
+			b, err = workspaceBundler(ws.workspace, cfg, d.compiler, ws.cache)
+		}
+		if err != nil {
+			err = fmt.Errorf("workspace %q: %v", ws.workspace.Name, err)
+			slog.Error("Build failed", "error", err)
+			return daemonResponse{Error: err.Error()}
+		}
+		slog.Info("Building workspace", "workspace", ws.workspace.Name)
+		b, cache, inputPath, reportPath, hooks = b.WithContext(d.ctx), ws.cache, ws.workspace.Input, "", configWebhooks(cfg)
+	} else if request.Workspace != "" {
+		err := fmt.Errorf("the daemon builds %s, not workspaces: start it with -workspaces to build workspace %q", d.inputPath, request.Workspace)
+		slog.Warn("Rejected build request", "error", err)
+		return daemonResponse{Error: err.Error()}
+	}
+
+	result, err := b.WithForce(request.Force).Run()
+	recordBuild(inputPath, result, err)
+	notifyBuild(hooks, inputPath, result, err)
+	if err == nil {
+		err = writeReport(result, nil, reportPath)
+	}
+	if err == nil {
+		err = buildError(result)
+	}
+
+	response := daemonResponse{Resources: len(result.Resources), Unchanged: result.UnchangedCount(), Duration: result.Duration}
+	for _, res := range result.Resources {
+		if res.Error != nil {
+			name := filepath.Base(filepath.Dir(res.MetaXMLPath))
+			response.Failed = append(response.Failed, daemonFailure{Resource: name, Error: res.Error.Error()})
+		}
+	}
+	if err != nil {
+		response.Error = err.Error()
+		slog.Error("Build failed", "error", err)
+	}
+	hashes, resources := cache.Len()
+	slog.Debug("Daemon cache", "files", hashes, "resources", resources)
+	return response
+}
+
+// workspace returns the workspace named in a request
+func (d *daemon) workspace(name string) (daemonWorkspace, error) {
+	if name == "" {
+		return daemonWorkspace{}, fmt.Errorf("the daemon serves workspaces, name the one to build with -workspace")
+	}
+	ws, ok := d.workspaces[name]
+	if !ok {
+		return daemonWorkspace{}, fmt.Errorf("unknown workspace %q", name)
+	}
+	return ws, nil
+}
+
+// runRequest implements the request command, which asks a running daemon to build and prints
+// the outcome
+func runRequest(args []string) error {
+	fs := flag.NewFlagSet("request", flag.ExitOnError)
+	socketPath := fs.String("socket", "", "unix socket of the daemon (default: the socket of the input path)")
+	workspace := fs.String("workspace", "", "workspace to build, for a daemon serving a workspaces file (input_path is then the workspaces file)")
+	force := fs.Bool("force", false, "rebuild every resource, even those unchanged since the last build")
+	stop := fs.Bool("stop", false, "stop the daemon instead of building")
+	fs.Usage = func() {
+		binaryName := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s request [options] [input_path]\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s request -workspace <name> [options] <workspaces_file>\n\n", binaryName)
+		fmt.Fprintf(os.Stderr, "Asks the daemon started for input_path (default: the current directory) to build it,\n")
+		fmt.Fprintf(os.Stderr, "or the daemon serving a workspaces file to build one of its workspaces.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	inputPath := "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		inputPath = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := *socketPath
+	if path == "" {
+		var err error
+		if path, err = daemonSocketPath(inputPath); err != nil {
+			return err
+		}
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return fmt.Errorf("no daemon is listening on %s, start one with: %s daemon [options] %s", path, filepath.Base(os.Args[0]), inputPath)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Workspace: *workspace, Force: *force, Stop: *stop}); err != nil {
+		return fmt.Errorf("cannot send request to the daemon: %v", err)
+	}
+	var response daemonResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return fmt.Errorf("the daemon did not answer: %v", err)
+	}
+	if *stop {
+		slog.Info("Daemon stopped", "socket", path)
+		return nil
+	}
+
+	for _, failure := range response.Failed {
+		slog.Error("Resource failed", "resource", failure.Resource, "error", failure.Error)
+	}
+	if response.Resources > 0 {
+		slog.Info("Build completed", "resources", response.Resources, "unchanged", response.Unchanged,
+			"failed", len(response.Failed), "duration", response.Duration)
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+	return nil
+}
+
+// listenDaemon listens on the unix socket at path. A socket left behind by a daemon that did
+// not stop cleanly is replaced, one a daemon still listens on is an error.
+func listenDaemon(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("cannot create socket directory: %v", err)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot remove stale socket: %v", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %v", path, err)
+	}
+	return listener, nil
+}
+
+// daemonSocketPath returns the socket of the daemon building inputPath, so requests find it
+// from the input path alone
+func daemonSocketPath(inputPath string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %v", err)
+	}
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot get absolute input path: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(absInput))
+	return filepath.Join(cacheDir, "mta-bundler", "daemon", hex.EncodeToString(sum[:8])+".sock"), nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/audit"
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/deploy"
+)
+
+// runDeploy implements the deploy command. Deployments are split in two steps so that
+// builders and server admins can be different people: -request packages a build into a
+// signed bundle, -approve verifies the bundle and writes it to the server. -server copies a
+// build straight to a server of the config file and makes it load the changes.
+func runDeploy(args []string) error {
+	fs := flag.NewFlagSet("deploy", flag.ExitOnError)
+	request := fs.Bool("request", false, "create a signed deployment bundle from <build_dir>")
+	approve := fs.String("approve", "", "verify and execute a deployment bundle")
+	keygen := fs.String("keygen", "", "generate a signing key pair as <prefix>.key and <prefix>.pub")
+	keyFile := fs.String("key", "", "private key used to sign the request (-request)")
+	pubKeyFile := fs.String("pubkey", "", "public key of the requester (-approve)")
+	target := fs.String("target", "", "server resources directory (required with -request, overrides the requested target with -approve)")
+	out := fs.String("out", "", "bundle path (-request, default is deploy-<time>"+deploy.Extension+")")
+	requestedBy := fs.String("by", "", "name recorded as the requester (-request, default is the current user)")
+	server := fs.String("server", "", "copy <build_dir> to this server of the config file, then refresh and restart the changed resources")
+	configFile := fs.String("config", "", "config file defining the servers (-server, default is "+config.FileName+" in the current directory)")
+	fs.Usage = func() {
+		binaryName := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s deploy -keygen <prefix>\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s deploy -request -key <file> -target <dir> [-out <bundle>] <build_dir>\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s deploy -approve <bundle> -pubkey <file> [-target <dir>]\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s deploy -server <name> [-config <file>] <build_dir>\n\n", binaryName)
+		fmt.Fprintf(os.Stderr, "A builder requests a deployment with their private key, a server admin approves\n")
+		fmt.Fprintf(os.Stderr, "it with the builder's public key, possibly on another machine. With -server the\n")
+		fmt.Fprintf(os.Stderr, "build is copied directly to a server defined in the config file.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	modes := 0
+	for _, set := range []bool{*request, *approve != "", *keygen != "", *server != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one of -keygen, -request, -approve or -server is required")
+	}
+
+	switch {
+	case *keygen != "":
+		return deployKeygen(*keygen)
+	case *request:
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected exactly one build directory")
+		}
+		return deployRequest(fs.Arg(0), *keyFile, *target, *out, *requestedBy)
+	case *server != "":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected exactly one build directory")
+		}
+		return deployServer(*server, *configFile, fs.Arg(0))
+	default:
+		return deployApprove(*approve, *pubKeyFile, *target)
+	}
+}
+
+// deployKeygen generates a signing key pair
+func deployKeygen(prefix string) error {
+	privatePath, publicPath := prefix+".key", prefix+".pub"
+	if err := deploy.GenerateKeys(privatePath, publicPath); err != nil {
+		return err
+	}
+
+	slog.Info("Generated signing key pair", "private", privatePath, "public", publicPath, "success", true)
+	slog.Info("Keep the private key with the builder and give the public key to the server admins")
+	return nil
+}
+
+// deployRequest packages buildDir into a signed deployment bundle
+func deployRequest(buildDir, keyFile, target, out, requestedBy string) error {
+	if keyFile == "" {
+		return fmt.Errorf("a private key is required (-key)")
+	}
+	if target == "" {
+		return fmt.Errorf("a target directory is required (-target)")
+	}
+	if info, err := os.Stat(buildDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("build directory does not exist: %s", buildDir)
+	}
+
+	key, err := deploy.LoadPrivateKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	if requestedBy == "" {
+		requestedBy = "unknown"
+		if current, err := user.Current(); err == nil {
+			requestedBy = current.Username
+		}
+	}
+
+	createdAt := time.Now().UTC()
+	if out == "" {
+		out = "deploy-" + createdAt.Format("20060102-150405") + deploy.Extension
+	}
+
+	absBuildDir, err := filepath.Abs(buildDir)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute build path: %v", err)
+	}
+
+	manifest, err := deploy.Create(out, buildDir, deploy.Manifest{
+		Target:      target,
+		Source:      absBuildDir,
+		RequestedBy: requestedBy,
+		CreatedAt:   createdAt,
+	}, key)
+	entry := audit.NewEntry(audit.ActionDeployRequest, target)
+	if err != nil {
+		entry.Summary = err.Error()
+		recordAudit(entry)
+		return err
+	}
+	entry.Success = true
+	entry.Summary = fmt.Sprintf("%d file(s) from %s in %s", len(manifest.Files), absBuildDir, out)
+	entry.ManifestHash = manifest.Hash()
+	recordAudit(entry)
+
+	slog.Info("Created deployment request", "bundle", out, "files", len(manifest.Files), "total_size", manifest.TotalSize(), "target", target, "success", true)
+	return nil
+}
+
+// deployApprove verifies a deployment bundle and writes its files to the target
+func deployApprove(bundlePath, pubKeyFile, target string) error {
+	if pubKeyFile == "" {
+		return fmt.Errorf("the requester's public key is required (-pubkey)")
+	}
+
+	key, err := deploy.LoadPublicKey(pubKeyFile)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := deploy.Open(bundlePath, key)
+	if err != nil {
+		return err
+	}
+	defer bundle.Close()
+
+	manifest := bundle.Manifest
+	slog.Info("Verified deployment request",
+		"requested_by", manifest.RequestedBy,
+		"created_at", manifest.CreatedAt.Format(time.RFC3339),
+		"files", len(manifest.Files),
+		"total_size", manifest.TotalSize(),
+		"success", true,
+	)
+
+	if target == "" {
+		target = manifest.Target
+	} else if target != manifest.Target {
+		slog.Warn("Deploying to a different target than requested", "requested", manifest.Target, "target", target)
+	}
+
+	written, err := bundle.Apply(target)
+	entry := audit.NewEntry(audit.ActionDeploy, target)
+	entry.ManifestHash = manifest.Hash()
+	if err != nil {
+		err = fmt.Errorf("deployment stopped after %d of %d file(s): %v", written, len(manifest.Files), err)
+		entry.Summary = err.Error()
+		recordAudit(entry)
+		return err
+	}
+	entry.Success = true
+	entry.Summary = fmt.Sprintf("%d file(s) requested by %s at %s", written, manifest.RequestedBy, manifest.CreatedAt.Format(time.RFC3339))
+	recordAudit(entry)
+
+	slog.Info("Deployed", "target", target, "files", written, "success", true)
+	return nil
+}
+
+// deployServer copies buildDir to the server named name in the config file at configFile, or
+// in the config file of the current directory
+func deployServer(name, configFile, buildDir string) error {
+	if configFile == "" {
+		found, ok := config.Find(".")
+		if !ok {
+			return fmt.Errorf("no %s in the current directory, servers are defined in a config file (-config)", config.FileName)
+		}
+		configFile = found
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	server, ok := cfg.Server(name)
+	if !ok {
+		return fmt.Errorf("server %q is not defined in %s", name, cfg.Path)
+	}
+	return deployToServer(server, buildDir)
+}
+
+// deployToServer copies the files of buildDir that changed into the resources directory of
+// server. When the server has an HTTP URL it then refreshes its resources and restarts the
+// running resources that changed, through the helper resource installed next to them.
+func deployToServer(server config.Server, buildDir string) error {
+	if info, err := os.Stat(buildDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("build directory does not exist: %s", buildDir)
+	}
+	password, err := server.ResolvePassword()
+	if err != nil {
+		return err
+	}
+
+	changed, err := deploy.Sync(buildDir, server.Resources)
+	entry := audit.NewEntry(audit.ActionDeploy, server.Resources)
+	if err != nil {
+		entry.Summary = err.Error()
+		recordAudit(entry)
+		return err
+	}
+	names := restartOrder(buildDir, deploy.ResourceNames(changed))
+	slog.Info("Copied build to server", "server", server.Name, "files", len(changed), "resources", len(names), "success", true)
+
+	if err := refreshServer(server, password, names); err != nil {
+		entry.Summary = fmt.Sprintf("%d file(s) copied to %s, %v", len(changed), server.Name, err)
+		recordAudit(entry)
+		return err
+	}
+	entry.Success = true
+	entry.Summary = fmt.Sprintf("%d file(s) of %d resource(s) to server %s", len(changed), len(names), server.Name)
+	recordAudit(entry)
+	return nil
+}
+
+// restartOrder sorts the resource names of buildDir so that every resource is restarted after
+// the resources it includes. Names without a meta.xml in buildDir, such as zipped resources,
+// keep their order after the others.
+func restartOrder(buildDir string, names []string) []string {
+	metaPaths, err := bundler.FindMTAResourceMetas(buildDir, bundler.WalkOptions{})
+	if err != nil {
+		return names
+	}
+	order, err := bundler.IncludeGraph(metaPaths).Order()
+	if err != nil {
+		slog.Warn("Cannot order restarts by includes", "error", err)
+		return names
+	}
+
+	position := make(map[string]int, len(order))
+	for i, name := range order {
+		position[name] = i
+	}
+	sorted := slices.Clone(names)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		pa, okA := position[a]
+		pb, okB := position[b]
+		switch {
+		case okA && okB:
+			return pa - pb
+		case okA:
+			return -1
+		case okB:
+			return 1
+		}
+		return 0
+	})
+	return sorted
+}
+
+// refreshServer makes the server load the changed resources names through its HTTP interface
+func refreshServer(server config.Server, password string, names []string) error {
+	if server.URL == "" {
+		slog.Info("No server url configured, refresh the server from its console", "server", server.Name)
+		return nil
+	}
+
+	installed, err := deploy.InstallHelper(server.Resources)
+	if err != nil {
+		return err
+	}
+	if installed {
+		slog.Warn("Installed the deploy helper resource, start it on the server and grant its ACL request before the next deployment",
+			"resource", deploy.HelperResource)
+	}
+	if len(names) == 0 && !installed {
+		slog.Info("Server is up to date, nothing to refresh", "server", server.Name)
+		return nil
+	}
+
+	client := deploy.Client{URL: server.URL, User: server.User, Password: password}
+	if err := client.Refresh(); err != nil {
+		if installed {
+			slog.Warn("Cannot refresh the server until the deploy helper resource is running", "error", err)
+			return nil
+		}
+		return fmt.Errorf("failed to refresh server %s: %v", server.Name, err)
+	}
+	slog.Info("Refreshed server resources", "server", server.Name, "success", true)
+
+	if len(names) == 0 || !server.RestartsResources() {
+		return nil
+	}
+	restarted, err := client.Restart(names)
+	if err != nil {
+		return fmt.Errorf("failed to restart resources on server %s: %v", server.Name, err)
+	}
+	if len(restarted) > 0 {
+		slog.Info("Restarted resources", "server", server.Name, "resources", strings.Join(restarted, ","), "success", true)
+	}
+	return nil
+}
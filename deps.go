@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/dependency"
+)
+
+// runDepsCommand implements the deps command, which checks the third-party dependencies of a
+// project for newer upstream releases and updates their pins
+func runDepsCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s deps outdated|update [options] [input_path]\n", filepath.Base(os.Args[0]))
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("expected a deps subcommand")
+	}
+
+	switch args[0] {
+	case "outdated":
+		return runDepsOutdated(args[1:])
+	case "update":
+		return runDepsUpdate(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown deps subcommand %q", args[0])
+	}
+}
+
+// runDepsOutdated lists the dependencies whose GitHub repository has a newer release than the
+// pinned one
+func runDepsOutdated(args []string) error {
+	fs := flag.NewFlagSet("deps outdated", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "config file declaring the dependencies (default is "+config.FileName+" in the input)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s deps outdated [options] [input_path]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Checks the latest GitHub release of every dependency with a github repository\n")
+		fmt.Fprintf(os.Stderr, "and lists the ones pinned to an older release. Set $%s to raise the\n", dependency.TokenEnv)
+		fmt.Fprintf(os.Stderr, "API rate limit.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, _, err := loadDependencyConfig(fs, *cfgPath)
+	if err != nil {
+		return err
+	}
+
+	outdated, failed := 0, 0
+	fmt.Printf("%-24s %-16s %-16s %s\n", "DEPENDENCY", "PINNED", "LATEST", "STATUS")
+	for _, dep := range cfg.Dependencies {
+		if dep.GitHub == "" {
+			fmt.Printf("%-24s %-16s %-16s %s\n", dep.Name, versionLabel(dep.Version), "-", "not tracked (no github repository)")
+			continue
+		}
+		release, err := dependency.Latest(dep)
+		if err != nil {
+			slog.Error("Cannot check dependency", "resource", dep.Name, "error", err)
+			failed++
+			continue
+		}
+		status := "up to date"
+		if release.Outdated(dep) {
+			status = "outdated"
+			outdated++
+		}
+		fmt.Printf("%-24s %-16s %-16s %s\n", dep.Name, versionLabel(dep.Version), release.Version, status)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to check %d dependencies", failed)
+	}
+	if outdated > 0 {
+		fmt.Printf("\n%d outdated, run \"deps update\" to pin the latest releases\n", outdated)
+	}
+	return nil
+}
+
+// runDepsUpdate pins the dependencies to the latest releases of their GitHub repositories and
+// rebuilds the project with the new pins
+func runDepsUpdate(args []string) error {
+	fs := flag.NewFlagSet("deps update", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "config file declaring the dependencies (default is "+config.FileName+" in the input)")
+	only := fs.String("only", "", "comma-separated names of the dependencies to update (default all with a github repository)")
+	outputDir := fs.String("o", "", "output directory of the rebuild (default is the output of the config file)")
+	noBuild := fs.Bool("no-build", false, "only update the pins, without rebuilding")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s deps update [options] [input_path]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Downloads the latest GitHub release of the outdated dependencies, writes their\n")
+		fmt.Fprintf(os.Stderr, "url, sha256 and version into the config file and rebuilds the input (default:\n")
+		fmt.Fprintf(os.Stderr, "the current directory) with the new pins.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, inputPath, err := loadDependencyConfig(fs, *cfgPath)
+	if err != nil {
+		return err
+	}
+	names := splitList(*only)
+	for _, name := range names {
+		if !slices.ContainsFunc(cfg.Dependencies, func(dep config.Dependency) bool { return dep.Name == name }) {
+			return fmt.Errorf("no dependency %q in %s", name, cfg.Path)
+		}
+	}
+
+	cacheDir, err := dependencyCacheDir(cfg.Dependencies)
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for _, dep := range cfg.Dependencies {
+		if len(names) > 0 && !slices.Contains(names, dep.Name) {
+			continue
+		}
+		if dep.GitHub == "" {
+			if len(names) > 0 {
+				return fmt.Errorf("dependency %q has no github repository to check", dep.Name)
+			}
+			slog.Debug("Skipping untracked dependency", "resource", dep.Name)
+			continue
+		}
+
+		release, err := dependency.Latest(dep)
+		if err != nil {
+			return err
+		}
+		if !release.Outdated(dep) {
+			slog.Info("Dependency is up to date", "resource", dep.Name, "version", dep.Version)
+			continue
+		}
+
+		next := dep
+		next.URL = release.URL
+		next.Version = release.Version
+		next, err = dependency.Pin(next, cacheDir)
+		if err != nil {
+			return fmt.Errorf("dependency %s %s: %v", dep.Name, release.Version, err)
+		}
+		if err := config.SetDependencyPin(cfg.Path, next); err != nil {
+			return err
+		}
+		slog.Info("Updated dependency", "resource", dep.Name, "from", versionLabel(dep.Version), "to", next.Version, "sha256", next.SHA256[:12], "success", true)
+		updated++
+	}
+
+	if _, err := config.Load(cfg.Path); err != nil {
+		return err
+	}
+	if updated == 0 || *noBuild {
+		return nil
+	}
+
+	// Rebuilt like a regular build of the input, which picks up the new pins from the config
+	// file; incremental builds skip the resources that did not change
+	buildArgs := []string{"-config", cfg.Path}
+	if *outputDir != "" {
+		buildArgs = append(buildArgs, "-o", *outputDir)
+	}
+	if err := flag.CommandLine.Parse(append(buildArgs, inputPath)); err != nil {
+		return err
+	}
+	return runCompiler()
+}
+
+// loadDependencyConfig loads the config file declaring the dependencies, given by cfgPath or
+// found in the input path argument of fs, and returns it with the input path
+func loadDependencyConfig(fs *flag.FlagSet, cfgPath string) (config.Config, string, error) {
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return config.Config{}, "", fmt.Errorf("expected at most one input path")
+	}
+	inputPath := "."
+	if fs.NArg() == 1 {
+		inputPath = fs.Arg(0)
+	}
+
+	if cfgPath == "" {
+		found, ok := config.Find(inputPath)
+		if !ok {
+			return config.Config{}, "", fmt.Errorf("no %s found in %s", config.FileName, inputPath)
+		}
+		cfgPath = found
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return config.Config{}, "", err
+	}
+	if len(cfg.Dependencies) == 0 {
+		return config.Config{}, "", fmt.Errorf("%s declares no dependencies", cfg.Path)
+	}
+	return cfg, inputPath, nil
+}
+
+// versionLabel returns the version of a pin for display
+func versionLabel(version string) string {
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/buildfile"
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// parseEmitSpec parses the -emit value ("ninja" or "make", optionally followed by "=path") and
+// returns the build file format and path. An empty spec returns an empty format.
+func parseEmitSpec(spec string) (string, string, error) {
+	if spec == "" {
+		return "", "", nil
+	}
+
+	format, path, hasPath := strings.Cut(spec, "=")
+	defaultPath, ok := buildfile.DefaultPaths[format]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported build file format: %s (supported: ninja, make)", format)
+	}
+	if !hasPath {
+		return format, defaultPath, nil
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("empty build file path in -emit %s", spec)
+	}
+	return format, path, nil
+}
+
+// emitBuildFile writes the build plan of inputPath for -emit: a build file with a target per
+// resource, running the bundler on the resource's meta.xml with the options this build would
+// use. Nothing is compiled, but the compiler is recorded in the lock file first, so the
+// resource builds run by the build file never write it concurrently.
+func emitBuildFile(inputPath string, cfg config.Config) error {
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	if err := checkLockFile(cliCompiler); err != nil {
+		return err
+	}
+
+	b := bundler.NewBundler(cliCompiler, bundler.Options{
+		InputPath: inputPath,
+		Inputs:    inputPaths,
+		OutputDir: *outputFile,
+		Compilation: compiler.CompilationOptions{
+			ObfuscationLevel:         compiler.ObfuscationLevel(*obfuscateLevel),
+			StripDebug:               *stripDebug,
+			SuppressDecompileWarning: *suppressWarn,
+		},
+		MergeMode:      *mergeMode,
+		Exclude:        append(cfg.Exclude, splitList(*excludeList)...),
+		Ignore:         append(ignorePatterns, splitList(*ignoreList)...),
+		MaxDepth:       *maxDepth,
+		SkipCategories: append(categoryPatterns, splitList(*skipCategories)...),
+		FollowSymlinks: *followSymlinks,
+		Subtrees:       subtrees,
+		Only:           splitList(*onlyResources),
+		Packs:          packs,
+		Splits:         splits,
+	})
+	steps, err := b.Plan()
+	if err != nil {
+		return err
+	}
+
+	if *buildInfo != "" || *checksums {
+		slog.Warn("Build info and checksums cover the whole build, the build file does not write them")
+	}
+	if len(cfg.Dependencies) > 0 {
+		slog.Warn("Dependencies of the config file are not downloaded by the build file, build them with a regular build")
+	}
+	if len(cfg.Webhooks) > 0 {
+		slog.Warn("Webhooks of the config file are notified after every resource the build file builds")
+	}
+
+	program, err := os.Executable()
+	if err != nil {
+		program = "mta-bundler"
+	}
+	file := buildfile.File{
+		Program: program,
+		Comment: "Generated by mta-bundler -emit " + emitFormat + ", emit it again after adding or removing resources",
+	}
+	for _, step := range steps {
+		inputs := step.Inputs
+		if cfg.Path != "" {
+			inputs = append(inputs, absolutePath(cfg.Path))
+		}
+		if *escrowKeyFile != "" {
+			inputs = append(inputs, absolutePath(*escrowKeyFile))
+		}
+		file.Targets = append(file.Targets, buildfile.Target{
+			Name: step.Resource,
+			// Every resource build writes its manifest, even when the resource has no script
+			Output: filepath.Join(step.OutputDir, bundler.ManifestFileName),
+			Inputs: inputs,
+			Args:   emitArgs(step, cfg),
+		})
+	}
+
+	if emitPath == "-" {
+		return file.Write(os.Stdout, emitFormat)
+	}
+
+	out, err := os.Create(emitPath)
+	if err != nil {
+		return fmt.Errorf("failed to create build file: %v", err)
+	}
+	if err := file.Write(out, emitFormat); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write build file: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write build file: %v", err)
+	}
+	slog.Info("Wrote build file", "path", emitPath, "format", emitFormat, "resources", len(steps))
+	return nil
+}
+
+// emitArgs returns the bundler arguments building a planned resource on its own. Options are
+// passed explicitly, so the project config file only supplies what a resource build cannot
+// get from flags. The resource is always rebuilt, as the build file already decided it is
+// out of date.
+func emitArgs(step bundler.PlanStep, cfg config.Config) []string {
+	args := []string{
+		"-q", "-force",
+		"-o", step.OutputDir,
+		"-e", strconv.Itoa(int(step.Compilation.ObfuscationLevel)),
+		"-s=" + strconv.FormatBool(step.Compilation.StripDebug),
+		"-d=" + strconv.FormatBool(step.Compilation.SuppressDecompileWarning),
+		"-m=" + strconv.FormatBool(step.MergeMode),
+	}
+	// The resource builds share the lock file written while emitting, and do not create one
+	if _, err := os.Stat(lockFilePath); err == nil {
+		args = append(args, "-lock-file", absolutePath(lockFilePath))
+	}
+	if cfg.Path != "" {
+		args = append(args, "-config", absolutePath(cfg.Path))
+	}
+	if *mergeOrder != "" {
+		args = append(args, "-merge-order", *mergeOrder)
+	}
+	if *mergeStrategy != "" {
+		args = append(args, "-merge-strategy", *mergeStrategy)
+	}
+	if *bundleName != "" {
+		args = append(args, "-bundle-name", *bundleName)
+	}
+	if *buildInfo != "" {
+		args = append(args, "-build-info=")
+	}
+	if *checksums {
+		args = append(args, "-checksums=false")
+	}
+	if verbatim := append(step.Verbatim, splitList(*verbatimList)...); len(verbatim) > 0 {
+		args = append(args, "-verbatim", strings.Join(verbatim, ","))
+	}
+	if excluded := append(step.Unmerged, splitList(*mergeExclude)...); len(excluded) > 0 {
+		args = append(args, "-merge-exclude", strings.Join(excluded, ","))
+	}
+	if !*clientCache {
+		args = append(args, "-client-cache=false")
+	}
+	if *escrowKeyFile != "" {
+		args = append(args, "-escrow-key", absolutePath(*escrowKeyFile))
+	}
+	if *compilerPath != "" {
+		args = append(args, "-compiler", absolutePath(*compilerPath))
+	}
+	if *compStrategy != "" {
+		args = append(args, "-compiler-strategy", *compStrategy)
+	}
+	if *compilerProxy != "" {
+		args = append(args, "-compiler-proxy", *compilerProxy)
+	}
+	if *compRetries > 0 {
+		args = append(args, "-retries", strconv.Itoa(*compRetries))
+	}
+	for _, arg := range compilerArgs {
+		args = append(args, "-compiler-arg="+arg)
+	}
+	for _, flagArg := range []struct {
+		name string
+		set  bool
+	}{
+		{"-scripts-only", *scriptsOnly},
+		{"-link-assets", *linkAssets},
+		{"-meta-regex", *metaRegex},
+		{"-clean", *cleanOutput},
+		{"-frozen", *frozenLock},
+		{"-sandbox", *sandboxMode},
+		{"-offline", *offlineMode},
+		{"-remote-compile", *remoteCompile},
+		{"-merge-isolate", *mergeIsolate},
+		{"-scan", *scanBackdoors},
+		{"-source-map", *sourceMaps},
+		{"-source-map-shim", *sourceMapShim},
+	} {
+		if flagArg.set {
+			args = append(args, flagArg.name)
+		}
+	}
+	return append(args, step.MetaXMLPath)
+}
+
+// absolutePath returns the absolute form of path, or path itself when it cannot be resolved
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
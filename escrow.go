@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/escrow"
+)
+
+// runEscrowRebuild implements the escrow-rebuild command, which recompiles deployed resources
+// in place from the source escrow written by a previous build
+func runEscrowRebuild(args []string) error {
+	fs := flag.NewFlagSet("escrow-rebuild", flag.ExitOnError)
+	keyFile := fs.String("key", "", "escrow key file used when the resources were built (required)")
+	level := fs.Int("e", 3, "obfuscation level (0-3)")
+	strip := fs.Bool("s", true, "strip debug information")
+	suppress := fs.Bool("d", false, "suppress decompile warning")
+	merge := fs.Bool("m", false, "merge all scripts into client.luac and server.luac")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s escrow-rebuild -key <file> [options] <dir>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Finds every resource below <dir> containing %s and recompiles its\n", escrow.FileName)
+		fmt.Fprintf(os.Stderr, "scripts in place with the given options. Assets are left untouched.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one directory")
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("an escrow key file is required (-key)")
+	}
+	if *level < 0 || *level > 3 {
+		return fmt.Errorf("invalid obfuscation level: %d (must be 0-3)", *level)
+	}
+
+	key, err := escrow.LoadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := bundler.FindEscrowResources(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no escrowed resources found in %s", fs.Arg(0))
+	}
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+
+	b := bundler.NewBundler(cliCompiler, bundler.Options{
+		Compilation: compiler.CompilationOptions{
+			ObfuscationLevel:         compiler.ObfuscationLevel(*level),
+			StripDebug:               *strip,
+			SuppressDecompileWarning: *suppress,
+		},
+		MergeMode: *merge,
+		EscrowKey: key,
+	})
+
+	var failed int
+	for i, dir := range dirs {
+		slog.Info("Rebuilding from escrow", "progress", fmt.Sprintf("%d/%d", i+1, len(dirs)), "dir", dir)
+		if err := b.RebuildFromEscrow(dir); err != nil {
+			slog.Error("Failed to rebuild resource", "dir", dir, "error", err)
+			failed++
+			continue
+		}
+		slog.Info("Rebuilt resource", "resource", filepath.Base(dir), "success", true)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resource(s) failed to rebuild", failed, len(dirs))
+	}
+	return nil
+}
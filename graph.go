@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/graph"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// runGraph implements the graph command, which writes the resources of a path and their
+// <include> dependencies as a DOT or Mermaid graph, for auditing large servers before
+// restructuring them
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := fs.String("format", graph.FormatDOT, "graph format: "+graph.FormatDOT+" (Graphviz) or "+graph.FormatMermaid)
+	files := fs.Bool("files", false, "add the number and total size of the files referenced by each meta.xml")
+	exclude := fs.String("exclude", "", "comma-separated resource names or path globs to leave out, added to the config file's exclude list")
+	outPath := fs.String("o", "-", "file to write the graph to (\"-\" writes to stdout)")
+	cfgPath := fs.String("config", "", "config file with the exclude list (default is "+config.FileName+" in the input)")
+	sizes := fs.String("size-units", "", "units of sizes with -files: binary (KiB, MiB) or decimal (kB, MB)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s graph [options] <input_path>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Writes the resources of the input as a graph with an edge from each resource\n")
+		fmt.Fprintf(os.Stderr, "to the resources it includes. Included resources that are not part of the input\n")
+		fmt.Fprintf(os.Stderr, "are drawn dashed. Render DOT output with Graphviz: dot -Tsvg -o resources.svg\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one input path")
+	}
+	inputPath := fs.Arg(0)
+	if err := validateInputPath(inputPath); err != nil {
+		return err
+	}
+	if *format != graph.FormatDOT && *format != graph.FormatMermaid {
+		return fmt.Errorf("-format: unknown graph format %q (use %s or %s)", *format, graph.FormatDOT, graph.FormatMermaid)
+	}
+	sizeUnits, err := units.ParseSizeUnits(*sizes)
+	if err != nil {
+		return fmt.Errorf("-size-units: %v", err)
+	}
+	if *outPath == "-" {
+		// Logs go to stderr, so they do not end up in the graph
+		slog.SetDefault(slog.New(bundler.NewConsoleHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	}
+
+	var cfg config.Config
+	if *cfgPath == "" {
+		*cfgPath, _ = config.Find(inputPath)
+	}
+	if *cfgPath != "" {
+		loaded, err := config.Load(*cfgPath)
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+	}
+
+	metaPaths, err := bundler.FindMTAResourceMetas(inputPath, bundler.WalkOptions{Ignore: cfg.Ignore, MaxDepth: cfg.MaxDepth,
+		SkipCategories: cfg.SkipCategories, FollowSymlinks: cfg.FollowSymlinks != nil && *cfg.FollowSymlinks})
+	if err != nil {
+		return err
+	}
+	metaPaths = bundler.ExcludeResourceMetas(inputPath, metaPaths, append(cfg.Exclude, splitList(*exclude)...))
+	includes := bundler.IncludeGraph(metaPaths)
+
+	var details map[string][]string
+	if *files {
+		details = fileDetails(metaPaths, units.Format{Sizes: sizeUnits})
+	}
+
+	if *outPath == "-" {
+		return includes.Write(os.Stdout, *format, details)
+	}
+	out, err := os.Create(*outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create graph file: %v", err)
+	}
+	if err := includes.Write(out, *format, details); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write graph file: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write graph file: %v", err)
+	}
+	slog.Info("Wrote resource graph", "path", *outPath, "format", *format, "resources", len(includes.Names()))
+	return nil
+}
+
+// fileDetails returns the number and total size of the files referenced by each meta.xml, by
+// resource name. Resources whose meta.xml cannot be parsed are marked as such.
+func fileDetails(metaPaths []string, format units.Format) map[string][]string {
+	details := make(map[string][]string, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		res, err := resource.NewResource(metaPath)
+		if err != nil {
+			slog.Warn("Cannot read resource", "path", metaPath, "error", err)
+			details[filepath.Base(filepath.Dir(metaPath))] = []string{"invalid meta.xml"}
+			continue
+		}
+		var size int64
+		for _, file := range res.Files {
+			if info, err := os.Stat(file.FullPath); err == nil && info.Mode().IsRegular() {
+				size += info.Size()
+			}
+		}
+		details[res.Name] = []string{fmt.Sprintf("%d files, %s", len(res.Files), format.Size(size))}
+	}
+	return details
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/audit"
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+)
+
+// runHistory implements the history command, which lists the audit log of builds and deployments
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := fs.Int("n", 20, "number of most recent entries to show (0 shows all)")
+	action := fs.String("action", "", "only show entries of this action ("+audit.ActionBuild+", "+audit.ActionDeployRequest+", "+audit.ActionDeploy+", "+audit.ActionUpload+")")
+	asJSON := fs.Bool("json", false, "print entries as JSON lines")
+	verify := fs.Bool("verify", false, "check that no entry was modified or removed")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s history [options]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Lists builds and deployments recorded in the audit log ($%s overrides its location).\n\nOptions:\n", audit.PathEnv)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	path, err := audit.DefaultPath()
+	if err != nil {
+		return err
+	}
+	entries, err := audit.Read(path)
+	if err != nil {
+		return err
+	}
+
+	if *verify {
+		if err := audit.Verify(entries); err != nil {
+			return fmt.Errorf("audit log %s failed verification: %v", path, err)
+		}
+		fmt.Printf("✓ Audit log verified: %d entries, chain intact\n", len(entries))
+		return nil
+	}
+
+	var selected []audit.Entry
+	for _, entry := range entries {
+		if *action == "" || entry.Action == *action {
+			selected = append(selected, entry)
+		}
+	}
+	if *limit > 0 && len(selected) > *limit {
+		selected = selected[len(selected)-*limit:]
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range selected {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(selected) == 0 {
+		fmt.Printf("No entries in %s\n", path)
+		return nil
+	}
+
+	for _, entry := range selected {
+		mark := "✓"
+		if !entry.Success {
+			mark = "✗"
+		}
+		hash := entry.ManifestHash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		fmt.Printf("%s  %s %-14s %-20s %-12s %s\n", entry.Time.Local().Format("2006-01-02 15:04:05"), mark, entry.Action, entry.User+"@"+entry.Host, hash, entry.Subject)
+		if entry.Summary != "" {
+			fmt.Printf("%24s%s\n", "", entry.Summary)
+		}
+	}
+	return nil
+}
+
+// recordAudit appends an entry to the audit log. Failures are logged but never fail the action.
+func recordAudit(entry audit.Entry) {
+	path, err := audit.DefaultPath()
+	if err == nil {
+		err = audit.Append(path, entry)
+	}
+	if err != nil {
+		slog.Warn("Cannot write audit log", "error", err)
+	}
+}
+
+// recordBuild records the outcome of a build in the audit log
+func recordBuild(inputPath string, result bundler.BuildResult, buildErr error) {
+	subject := inputPath
+	if absInput, err := filepath.Abs(inputPath); err == nil {
+		subject = absInput
+	}
+	entry := audit.NewEntry(audit.ActionBuild, subject)
+
+	if buildErr != nil {
+		entry.Summary = buildErr.Error()
+		recordAudit(entry)
+		return
+	}
+
+	failed := result.FailedCount()
+	output := result.OutputDir
+	if output == "" {
+		output = "in place"
+	}
+	entry.Success = failed == 0
+	entry.Summary = fmt.Sprintf("%d resource(s), %d failed, output: %s", len(result.Resources), failed, output)
+	entry.ManifestHash = audit.HashFiles(result.OutputFiles())
+	recordAudit(entry)
+}
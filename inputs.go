@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+)
+
+// isInputList reports whether the input argument names a list of resources: @path for a list
+// file, or "-" for stdin
+func isInputList(arg string) bool {
+	return arg == "-" || strings.HasPrefix(arg, "@")
+}
+
+// readInputList reads the resources of an input list given as @path or "-" (stdin). Each line
+// is a meta.xml path or a resource directory, relative to the current directory, which is the
+// input root output paths are calculated from. Empty lines and lines starting with # are
+// skipped. It returns the absolute meta.xml paths without duplicates, in list order.
+func readInputList(arg string) ([]string, error) {
+	name, in := "stdin", io.Reader(os.Stdin)
+	if arg != "-" {
+		name = strings.TrimPrefix(arg, "@")
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read input list: %v", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get absolute input path: %v", err)
+	}
+
+	var metaPaths []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		metaPath, err := listedMeta(root, entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, line, err)
+		}
+		if !seen[metaPath] {
+			seen[metaPath] = true
+			metaPaths = append(metaPaths, metaPath)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read input list: %v", err)
+	}
+	if len(metaPaths) == 0 {
+		return nil, fmt.Errorf("input list %s names no resources", name)
+	}
+	return metaPaths, nil
+}
+
+// listedMeta returns the absolute meta.xml path of an input list entry, a meta.xml file or a
+// resource directory below root
+func listedMeta(root, entry string) (string, error) {
+	path, err := filepath.Abs(entry)
+	if err != nil {
+		return "", fmt.Errorf("cannot get absolute path of %s: %v", entry, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot access %s: %v", entry, err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, "meta.xml")
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%s is not a resource directory, it has no meta.xml", entry)
+		}
+	} else if strings.ToLower(filepath.Base(path)) != "meta.xml" {
+		return "", fmt.Errorf("%s is neither a meta.xml file nor a resource directory", entry)
+	}
+
+	if !containsPath(root, filepath.Dir(path)) {
+		return "", fmt.Errorf("%s is outside the current directory, the root of the output paths", entry)
+	}
+	return path, nil
+}
+
+// resolveInputs checks several input paths, meta.xml files and directories searched for
+// resources, and returns the input root output paths are calculated from: the deepest directory
+// containing every input
+func resolveInputs(args []string) (string, error) {
+	var root string
+	for _, arg := range args {
+		if isInputList(arg) {
+			return "", fmt.Errorf("an input list (%s) cannot be combined with other input paths", arg)
+		}
+		if err := validateInputPath(arg); err != nil {
+			return "", err
+		}
+		absPath, err := filepath.Abs(arg)
+		if err != nil {
+			return "", fmt.Errorf("cannot get absolute path of %s: %v", arg, err)
+		}
+
+		// Like a single input, a meta.xml is placed relative to its resource directory and a
+		// category folder is kept in the output
+		inputRoot := bundler.CategoryRoot(absPath)
+		if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+			inputRoot = filepath.Dir(absPath)
+		}
+		if root == "" {
+			root = inputRoot
+		}
+		for !containsPath(root, inputRoot) && filepath.Dir(root) != root {
+			root = filepath.Dir(root)
+		}
+	}
+	return root, nil
+}
+
+// containsPath reports whether path is dir or below it
+func containsPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bytecode"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// runInspect implements the inspect command, which prints header information and
+// statistics of compiled Lua files to help diagnose "bad header in precompiled chunk" errors,
+// or the resolved structure of resources as JSON when given meta.xml files
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Usage = func() {
+		binaryName := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s inspect <file.luac> [file.luac...]\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s inspect <meta.xml> [meta.xml...]\n\n", binaryName)
+		fmt.Fprintf(os.Stderr, "Prints Lua version, endianness, MTA obfuscation marker, strip status and basic statistics.\n")
+		fmt.Fprintf(os.Stderr, "Given meta.xml files, prints the resolved resources as JSON: name, base directory,\n")
+		fmt.Fprintf(os.Stderr, "referenced files with their type and absolute path, and scripts grouped by type.\n")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no file provided")
+	}
+
+	var metas int
+	for _, path := range fs.Args() {
+		if strings.ToLower(filepath.Base(path)) == "meta.xml" {
+			metas++
+		}
+	}
+	if metas > 0 {
+		if metas < fs.NArg() {
+			return fmt.Errorf("meta.xml files and compiled files cannot be inspected together")
+		}
+		return inspectResources(fs.Args())
+	}
+
+	var failed int
+	for i, path := range fs.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		info, err := bytecode.InspectFile(path)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		printBytecodeInfo(info)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to inspect %d file(s)", failed)
+	}
+	return nil
+}
+
+// inspectResources prints the resolved structure of the resources of metaPaths as JSON, an
+// object for a single meta.xml and an array of objects for several
+func inspectResources(metaPaths []string) error {
+	descriptions := make([]resource.Description, 0, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		res, err := resource.NewResource(metaPath)
+		if err != nil {
+			return fmt.Errorf("%s: %v", metaPath, err)
+		}
+		descriptions = append(descriptions, res.Describe())
+	}
+
+	var value any = descriptions
+	if len(descriptions) == 1 {
+		value = descriptions[0]
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode resources: %v", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printBytecodeInfo prints the inspection result of a single file
+func printBytecodeInfo(info bytecode.Info) {
+	fmt.Printf("File: %s\n", info.Path)
+	fmt.Printf("  Size: %s\n", compiler.FormatSize(info.Size))
+	fmt.Printf("  Type: %s\n", info.Kind)
+	fmt.Printf("  MTA obfuscation marker: %s\n", yesNo(info.Obfuscated()))
+
+	if info.Kind == bytecode.KindCompiled {
+		header := info.Header
+		endianness := "big-endian"
+		if header.LittleEndian {
+			endianness = "little-endian"
+		}
+
+		fmt.Printf("  Lua version: %d.%d\n", header.Version>>4, header.Version&0x0F)
+		fmt.Printf("  Format: %d\n", header.Format)
+		fmt.Printf("  Endianness: %s\n", endianness)
+		fmt.Printf("  Sizes: int=%d size_t=%d instruction=%d number=%d (integral: %s)\n",
+			header.SizeInt, header.SizeSizeT, header.SizeInstruction, header.SizeNumber, yesNo(header.IntegralNumbers))
+
+		if info.Stats.Functions > 0 {
+			stats := info.Stats
+			fmt.Printf("  Debug info stripped: %s\n", yesNo(info.Stripped))
+			if stats.Source != "" {
+				fmt.Printf("  Source name: %s\n", stats.Source)
+			}
+			fmt.Printf("  Functions: %d (%d vararg)\n", stats.Functions, stats.VarargFunction)
+			fmt.Printf("  Instructions: %d\n", stats.Instructions)
+			fmt.Printf("  Constants: %d (%d strings)\n", stats.Constants, stats.Strings)
+			fmt.Printf("  Debug entries: %d line info, %d locals, %d upvalue names\n", stats.LineInfo, stats.Locals, stats.Upvalues)
+			fmt.Printf("  Max stack size: %d, max parameters: %d\n", stats.MaxStackSize, stats.MaxParameters)
+		}
+	}
+
+	if len(info.Diagnostics) == 0 {
+		fmt.Printf("  ✓ No problems detected\n")
+		return
+	}
+	for _, diagnostic := range info.Diagnostics {
+		fmt.Printf("  ⚠ %s\n", diagnostic)
+	}
+}
+
+// yesNo formats a boolean for human-readable output
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
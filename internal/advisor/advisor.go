@@ -0,0 +1,218 @@
+package advisor
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// DefaultSampleSize is the number of scripts trial-compiled per resource
+const DefaultSampleSize = 5
+
+// MinSizeChange is the smallest relative size difference worth a recommendation
+const MinSizeChange = 0.05
+
+// Options that recommendations are about
+const (
+	OptionStripDebug       = "strip_debug"
+	OptionObfuscationLevel = "obfuscation_level"
+)
+
+// Recommendation suggests a per-resource option change that makes the output smaller
+type Recommendation struct {
+	Resource    string  // Resource name
+	Option      string  // OptionStripDebug or OptionObfuscationLevel
+	Current     string  // Current value of the option
+	Suggested   string  // Value producing smaller output
+	SizeChange  float64 // Output size with the current value relative to the suggested one, minus 1 (0.4 means 40% larger)
+	SampleFiles int     // Number of scripts the estimate is based on
+	Tradeoff    string  // What is lost by following the recommendation
+}
+
+// Message describes the cost of the current setting, e.g. "strip_debug=false adds 40% size compared to true"
+func (r Recommendation) Message() string {
+	return fmt.Sprintf("%s=%s adds %.0f%% size compared to %s", r.Option, r.Current, r.SizeChange*100, r.Suggested)
+}
+
+// Advisor trial-compiles a sample of every built resource with alternative options in
+// the background and collects the changes that would reduce the output size
+type Advisor struct {
+	compiler   compiler.CLICompiler
+	sampleSize int
+	slots      chan struct{}
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	results    []Recommendation
+}
+
+// New creates an advisor trial-compiling up to sampleSize scripts per resource
+func New(comp compiler.CLICompiler, sampleSize int) *Advisor {
+	return &Advisor{
+		compiler:   comp,
+		sampleSize: sampleSize,
+		slots:      make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+// Submit starts the analysis of a built resource in the background. Failed resources are ignored.
+func (a *Advisor) Submit(res bundler.ResourceResult) {
+	if res.Error != nil || res.Resource == nil {
+		return
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.slots <- struct{}{}
+		defer func() { <-a.slots }()
+
+		recommendations, err := a.analyze(res.Resource, res.Options)
+		if err != nil {
+			slog.Debug("Size analysis failed", "resource", res.Resource.Name, "error", err)
+			return
+		}
+
+		a.mu.Lock()
+		a.results = append(a.results, recommendations...)
+		a.mu.Unlock()
+	}()
+}
+
+// Wait waits for every submitted analysis and returns the recommendations sorted by
+// resource and option
+func (a *Advisor) Wait() []Recommendation {
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sort.Slice(a.results, func(i, j int) bool {
+		if a.results[i].Resource != a.results[j].Resource {
+			return a.results[i].Resource < a.results[j].Resource
+		}
+		return a.results[i].Option < a.results[j].Option
+	})
+	return append([]Recommendation{}, a.results...)
+}
+
+// analyze compiles the sample of a resource with its options and with each alternative
+func (a *Advisor) analyze(res *resource.Resource, options compiler.CompilationOptions) ([]Recommendation, error) {
+	sample := a.sample(res)
+	if len(sample) == 0 {
+		return nil, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "mta-bundler-advisor-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	baseSize, err := a.trialSize(tempDir, sample, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var recommendations []Recommendation
+	for _, alt := range alternatives(options) {
+		size, err := a.trialSize(tempDir, sample, alt.options)
+		if err != nil {
+			return nil, err
+		}
+		if size <= 0 {
+			continue
+		}
+		change := float64(baseSize)/float64(size) - 1
+		slog.Debug("Trial compilation", "resource", res.Name, "option", alt.option, "value", alt.suggested, "output_size", size, "current_size", baseSize)
+		if change < MinSizeChange {
+			continue
+		}
+		recommendations = append(recommendations, Recommendation{
+			Resource:    res.Name,
+			Option:      alt.option,
+			Current:     alt.current,
+			Suggested:   alt.suggested,
+			SizeChange:  change,
+			SampleFiles: len(sample),
+			Tradeoff:    alt.tradeoff,
+		})
+	}
+	return recommendations, nil
+}
+
+// sample returns the largest Lua scripts of a resource, which dominate its output size
+func (a *Advisor) sample(res *resource.Resource) []string {
+	type script struct {
+		path string
+		size int64
+	}
+	var scripts []script
+	for _, fileRef := range res.GetLuaFiles() {
+		if size, err := compiler.CalculateFileSize(fileRef.FullPath); err == nil {
+			scripts = append(scripts, script{fileRef.FullPath, size})
+		}
+	}
+	sort.SliceStable(scripts, func(i, j int) bool { return scripts[i].size > scripts[j].size })
+
+	var paths []string
+	for i := 0; i < len(scripts) && i < a.sampleSize; i++ {
+		paths = append(paths, scripts[i].path)
+	}
+	return paths
+}
+
+// trialSize compiles every sample file with options and returns the total output size
+func (a *Advisor) trialSize(tempDir string, sample []string, options compiler.CompilationOptions) (int64, error) {
+	var total int64
+	for i, path := range sample {
+		outputPath := filepath.Join(tempDir, strconv.Itoa(i)+".luac")
+		result, err := a.compiler.CompileFile(path, outputPath, options)
+		if err != nil {
+			return 0, err
+		}
+		total += result.OutputSize
+	}
+	return total, nil
+}
+
+// alternative is an option change evaluated by the advisor
+type alternative struct {
+	option, current, suggested, tradeoff string
+	options                              compiler.CompilationOptions
+}
+
+// alternatives returns the option changes that may reduce the output size
+func alternatives(options compiler.CompilationOptions) []alternative {
+	var alts []alternative
+	if !options.StripDebug {
+		alt := options
+		alt.StripDebug = true
+		alts = append(alts, alternative{
+			option:    OptionStripDebug,
+			current:   "false",
+			suggested: "true",
+			tradeoff:  "error messages lose line numbers",
+			options:   alt,
+		})
+	}
+	if options.ObfuscationLevel > compiler.ObfuscationNone {
+		alt := options
+		alt.ObfuscationLevel--
+		alts = append(alts, alternative{
+			option:    OptionObfuscationLevel,
+			current:   strconv.Itoa(int(options.ObfuscationLevel)),
+			suggested: strconv.Itoa(int(alt.ObfuscationLevel)),
+			tradeoff:  "compiled scripts are easier to decompile",
+			options:   alt,
+		})
+	}
+	return alts
+}
@@ -0,0 +1,31 @@
+package advisor
+
+import (
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+func TestAlternatives(t *testing.T) {
+	alts := alternatives(compiler.CompilationOptions{ObfuscationLevel: compiler.ObfuscationMaximum})
+	if len(alts) != 2 {
+		t.Fatalf("Expected 2 alternatives, got %d", len(alts))
+	}
+	if alts[0].option != OptionStripDebug || !alts[0].options.StripDebug {
+		t.Errorf("Expected strip debug to be suggested first, got %+v", alts[0])
+	}
+	if alts[1].option != OptionObfuscationLevel || alts[1].options.ObfuscationLevel != compiler.ObfuscationEnhanced || alts[1].options.StripDebug {
+		t.Errorf("Expected obfuscation level 2 with the other options unchanged, got %+v", alts[1])
+	}
+
+	if alts := alternatives(compiler.CompilationOptions{StripDebug: true}); len(alts) != 0 {
+		t.Errorf("Expected no alternatives for stripped unobfuscated output, got %+v", alts)
+	}
+}
+
+func TestRecommendationMessage(t *testing.T) {
+	rec := Recommendation{Option: OptionStripDebug, Current: "false", Suggested: "true", SizeChange: 0.4}
+	if got, want := rec.Message(), "strip_debug=false adds 40% size compared to true"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionBuild         = "build"
+	ActionDeployRequest = "deploy-request"
+	ActionDeploy        = "deploy"
+	ActionUpload        = "upload"
+)
+
+// PathEnv overrides the location of the audit log
+const PathEnv = "MTA_BUNDLER_AUDIT_LOG"
+
+// Entry is a single audit log record. Entries are chained: each one stores the hash of
+// the previous entry, so removed or modified entries are detected by Verify.
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Action       string    `json:"action"`
+	User         string    `json:"user"`
+	Host         string    `json:"host"`
+	Subject      string    `json:"subject"`                 // Input path of a build, target of a deployment
+	Summary      string    `json:"summary,omitempty"`       // Human-readable outcome
+	ManifestHash string    `json:"manifest_hash,omitempty"` // SHA-256 identifying what was built or deployed
+	Success      bool      `json:"success"`
+	PrevHash     string    `json:"prev_hash"`
+	Hash         string    `json:"hash"`
+}
+
+// DefaultPath returns the audit log location: $MTA_BUNDLER_AUDIT_LOG, or audit.jsonl in
+// the user config directory
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine config directory: %w", err)
+	}
+	return filepath.Join(configDir, "mta-bundler", "audit.jsonl"), nil
+}
+
+// NewEntry creates an entry for the current user, host and time
+func NewEntry(action, subject string) Entry {
+	entry := Entry{Time: time.Now().UTC(), Action: action, Subject: subject, User: "unknown", Host: "unknown"}
+	if current, err := user.Current(); err == nil {
+		entry.User = current.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		entry.Host = host
+	}
+	return entry
+}
+
+// Append adds an entry to the audit log at path, chaining it to the last entry
+func Append(path string, entry Entry) error {
+	entries, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		entry.PrevHash = entries[len(entries)-1].Hash
+	}
+	entry.Hash = entry.computeHash()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Close()
+}
+
+// Read returns all entries of the audit log at path. A missing log has no entries.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry Entry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				return nil, fmt.Errorf("audit log %s is corrupt at line %d: %w", path, lineNumber, jsonErr)
+			}
+			entries = append(entries, entry)
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+}
+
+// Verify checks the hash chain of the entries and returns an error describing the first
+// entry that was modified, removed or inserted
+func Verify(entries []Entry) error {
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.PrevHash != prevHash {
+			return fmt.Errorf("entry %d does not follow entry %d (an entry was removed or inserted)", i+1, i)
+		}
+		if entry.computeHash() != entry.Hash {
+			return fmt.Errorf("entry %d was modified", i+1)
+		}
+		prevHash = entry.Hash
+	}
+	return nil
+}
+
+// computeHash returns the hash of the entry content and the previous hash
+func (e Entry) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFiles returns a SHA-256 identifying the content of a set of files. Missing files are
+// ignored, so only what was actually produced is covered.
+func HashFiles(paths []string) string {
+	sorted := append([]string{}, paths...)
+	sort.Strings(sorted)
+
+	manifest := sha256.New()
+	for _, path := range sorted {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(manifest, "%x  %s\n", sum, filepath.ToSlash(path))
+	}
+	return hex.EncodeToString(manifest.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	for _, action := range []string{ActionBuild, ActionDeployRequest, ActionDeploy} {
+		entry := NewEntry(action, "/srv/mta")
+		entry.Success = true
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(entries) != 3 || entries[2].Action != ActionDeploy {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].Hash {
+		t.Errorf("Entries are not chained: %+v", entries)
+	}
+	if err := Verify(entries); err != nil {
+		t.Errorf("Verify failed on an untouched log: %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for _, subject := range []string{"first", "second", "third"} {
+		if err := Append(path, NewEntry(ActionBuild, subject)); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	modified := append([]Entry{}, entries...)
+	modified[1].Subject = "rewritten"
+	if err := Verify(modified); err == nil || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("Expected a modification error, got %v", err)
+	}
+
+	removed := []Entry{entries[0], entries[2]}
+	if err := Verify(removed); err == nil || !strings.Contains(err.Error(), "removed") {
+		t.Errorf("Expected a removal error, got %v", err)
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.luac"), filepath.Join(dir, "b.luac")
+	os.WriteFile(a, []byte("a"), 0644)
+	os.WriteFile(b, []byte("b"), 0644)
+
+	if HashFiles([]string{a, b}) != HashFiles([]string{b, a}) {
+		t.Error("Expected the hash to ignore file order")
+	}
+
+	before := HashFiles([]string{a, b})
+	os.WriteFile(b, []byte("changed"), 0644)
+	if HashFiles([]string{a, b}) == before {
+		t.Error("Expected the hash to change with file content")
+	}
+}
@@ -0,0 +1,200 @@
+package backdoor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// Severity tells how likely a finding is a backdoor rather than an unusual but legitimate use
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"   // Hardly ever found in legitimate resources
+	SeverityMedium Severity = "medium" // Found in legitimate resources, but also a common way to hide code
+)
+
+// Rules of the scanner. They are stable, so acknowledgments keep matching across versions.
+const (
+	RuleRemoteCode         = "remote-code"          // Code downloaded with fetchRemote or callRemote is loaded
+	RuleObfuscatedLoad     = "obfuscated-load"      // loadstring or load runs a decoded or escaped string
+	RuleEncodedPayload     = "encoded-payload"      // A long escaped or base64 string literal
+	RuleSerialBackdoor     = "serial-backdoor"      // A player serial is compared with a hard-coded one
+	RuleACLEscalation      = "acl-escalation"       // Code adds an object to a staff ACL group
+	RuleSuspiciousACLRight = "suspicious-acl-right" // meta.xml requests an ACL right that grants control of the server
+)
+
+// Finding is a match of a rule in a script or meta.xml of a resource
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Resource string
+	File     string // Script src, or meta.xml
+	Line     int
+	Message  string
+	Excerpt  string // The matching line, trimmed
+}
+
+// ID returns the identifier acknowledging the finding: its rule and a hash of the file and the
+// matching line. It survives the line moving, but not the line or file changing.
+func (f Finding) ID() string {
+	sum := sha256.Sum256([]byte(f.Rule + "\x00" + f.File + "\x00" + f.Excerpt))
+	return f.Rule + ":" + hex.EncodeToString(sum[:6])
+}
+
+// String returns the finding as a single line
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s/%s:%d: %s", f.Rule, f.Resource, f.File, f.Line, f.Message)
+}
+
+// lineRule is a rule matched against single lines of scripts
+type lineRule struct {
+	rule     string
+	severity Severity
+	pattern  *regexp.Regexp
+	message  string
+}
+
+var lineRules = []lineRule{
+	{RuleObfuscatedLoad, SeverityHigh,
+		regexp.MustCompile(`\b(loadstring|load)\s*\(\s*\(?\s*(base64Decode|teaDecode|decodeString|string\.char|string\.reverse|utf8\.char|["'](\\\d{1,3}|\\x[0-9A-Fa-f]{2}){4,})`),
+		"loads code from a decoded or escaped string"},
+	{RuleEncodedPayload, SeverityMedium,
+		regexp.MustCompile(`(\\\d{1,3}|\\x[0-9A-Fa-f]{2}){40,}|["'][A-Za-z0-9+/]{200,}={0,2}["']`),
+		"contains a long escaped or base64 string"},
+	{RuleSerialBackdoor, SeverityHigh,
+		regexp.MustCompile(`(?i)getPlayerSerial\s*\([^)]*\)\s*[=~]=\s*["'][0-9A-F]{32}["']|["'][0-9A-F]{32}["']\s*[=~]=\s*getPlayerSerial`),
+		"compares a player serial with a hard-coded one"},
+	{RuleACLEscalation, SeverityHigh,
+		regexp.MustCompile(`\baclGroupAddObject\s*\(\s*aclGetGroup\s*\(\s*["'](Admin|SuperModerator|Moderator|Console)["']`),
+		"adds an object to a staff ACL group"},
+}
+
+// remoteFetchPattern matches functions downloading content, and codeLoadPattern functions
+// running a string as code
+var (
+	remoteFetchPattern = regexp.MustCompile(`\b(fetchRemote|callRemote)\s*\(`)
+	codeLoadPattern    = regexp.MustCompile(`\b(loadstring|load)\s*\(`)
+	timerPattern       = regexp.MustCompile(`\bsetTimer\s*\(`)
+)
+
+// dangerousRights are ACL rights that let a resource take control of the server
+var dangerousRights = map[string]bool{
+	"function.aclgroupaddobject":  true,
+	"function.aclsetright":        true,
+	"function.aclcreategroup":     true,
+	"function.aclcreate":          true,
+	"function.acldestroy":         true,
+	"function.aclreload":          true,
+	"function.addaccount":         true,
+	"function.setaccountpassword": true,
+	"function.removeban":          true,
+	"general.modifyotherobjects":  true,
+}
+
+// Scan scans the scripts and the ACL requests of res for known backdoor patterns. Scripts with a
+// URL src are not scanned, and neither are compiled ones.
+func Scan(res *resource.Resource) ([]Finding, error) {
+	var findings []Finding
+	for _, fileRef := range res.GetLuaFiles() {
+		data, err := os.ReadFile(fileRef.FullPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", fileRef.RelativePath, err)
+		}
+		findings = append(findings, scanScript(res.Name, fileRef.RelativePath, data)...)
+	}
+
+	data, err := os.ReadFile(res.MetaXMLPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meta.xml of %s: %v", res.Name, err)
+	}
+	aclFindings, err := scanACLRequests(res.Name, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse meta.xml of %s: %v", res.Name, err)
+	}
+	return append(findings, aclFindings...), nil
+}
+
+// scanScript returns the findings of the script src of resource
+func scanScript(resourceName, src string, data []byte) []Finding {
+	var findings []Finding
+	add := func(rule string, severity Severity, line int, text, message string) {
+		excerpt := strings.TrimSpace(text)
+		if len(excerpt) > 120 {
+			excerpt = excerpt[:120]
+		}
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Resource: resourceName, File: src, Line: line, Message: message, Excerpt: excerpt})
+	}
+
+	fetches := remoteFetchPattern.Match(data)
+	timed := timerPattern.Match(data)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, rule := range lineRules {
+			if rule.pattern.MatchString(text) {
+				add(rule.rule, rule.severity, line, text, rule.message)
+			}
+		}
+		// Loading code is common, loading it in a script that downloads content is not
+		if fetches && codeLoadPattern.MatchString(text) {
+			message := "loads code in a script that downloads content with fetchRemote or callRemote"
+			if timed {
+				message += ", on a timer"
+			}
+			add(RuleRemoteCode, SeverityHigh, line, text, message)
+		}
+	}
+	return findings
+}
+
+// aclRequests holds the rights requested in a meta.xml
+type aclRequests struct {
+	Rights []struct {
+		Name   string `xml:"name,attr"`
+		Access string `xml:"access,attr"`
+	} `xml:"aclrequest>right"`
+}
+
+// scanACLRequests returns the findings of the <aclrequest> rights of a meta.xml
+func scanACLRequests(resourceName string, data []byte) ([]Finding, error) {
+	var requests aclRequests
+	if err := xml.Unmarshal(data, &requests); err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, right := range requests.Rights {
+		if !dangerousRights[strings.ToLower(right.Name)] || !strings.EqualFold(right.Access, "true") {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:     RuleSuspiciousACLRight,
+			Severity: SeverityHigh,
+			Resource: resourceName,
+			File:     "meta.xml",
+			Line:     lineOf(data, right.Name),
+			Message:  "requests " + right.Name + ", which grants control of the server",
+			Excerpt:  right.Name,
+		})
+	}
+	return findings, nil
+}
+
+// lineOf returns the line of the first occurrence of s in data, 0 when missing
+func lineOf(data []byte, s string) int {
+	index := bytes.Index(data, []byte(s))
+	if index < 0 {
+		return 0
+	}
+	return bytes.Count(data[:index], []byte("\n")) + 1
+}
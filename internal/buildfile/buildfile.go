@@ -0,0 +1,137 @@
+package buildfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats of build files
+const (
+	FormatNinja = "ninja"
+	FormatMake  = "make"
+)
+
+// Default paths of build files, by format
+var DefaultPaths = map[string]string{
+	FormatNinja: "build.ninja",
+	FormatMake:  "mta-bundler.mk",
+}
+
+// Target is an output file built by running the bundler with arguments
+type Target struct {
+	Name   string   // Shown by ninja while the target builds, such as the resource name
+	Output string   // File the command writes, its timestamp tells whether the target is up to date
+	Inputs []string // Files the command reads, the target is rebuilt when any is newer than the output
+	Args   []string // Arguments of the bundler
+}
+
+// File is a build file running the bundler for every target
+type File struct {
+	Program string // Path of the bundler executable
+	Comment string // Header comment line, such as how the file was generated
+	Targets []Target
+}
+
+// Write writes the file in format
+func (f File) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatNinja:
+		return f.WriteNinja(w)
+	case FormatMake:
+		return f.WriteMake(w)
+	}
+	return fmt.Errorf("unsupported build file format: %s (supported: ninja, make)", format)
+}
+
+// WriteNinja writes the file as a ninja build file with an "all" default target
+func (f File) WriteNinja(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	if f.Comment != "" {
+		fmt.Fprintf(out, "# %s\n\n", f.Comment)
+	}
+	fmt.Fprintf(out, "bundler = %s\n\n", ninjaValue(shellQuote(f.Program)))
+	fmt.Fprintf(out, "rule bundle\n  command = $bundler $args\n  description = BUNDLE $name\n")
+
+	outputs := make([]string, 0, len(f.Targets))
+	for _, target := range f.Targets {
+		output := ninjaPath(target.Output)
+		outputs = append(outputs, output)
+
+		inputs := make([]string, 0, len(target.Inputs))
+		for _, input := range target.Inputs {
+			inputs = append(inputs, ninjaPath(input))
+		}
+		fmt.Fprintf(out, "\nbuild %s: bundle %s\n", output, strings.Join(inputs, " "))
+		fmt.Fprintf(out, "  args = %s\n", ninjaValue(shellCommand(target.Args)))
+		fmt.Fprintf(out, "  name = %s\n", ninjaValue(target.Name))
+	}
+
+	fmt.Fprintf(out, "\nbuild all: phony %s\ndefault all\n", strings.Join(outputs, " "))
+	return out.Flush()
+}
+
+// WriteMake writes the file as a makefile with an "all" target. The bundler can be replaced
+// by setting MTA_BUNDLER.
+func (f File) WriteMake(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	if f.Comment != "" {
+		fmt.Fprintf(out, "# %s\n\n", f.Comment)
+	}
+	fmt.Fprintf(out, "MTA_BUNDLER ?= %s\n\n", makeValue(shellQuote(f.Program)))
+
+	outputs := make([]string, 0, len(f.Targets))
+	for _, target := range f.Targets {
+		outputs = append(outputs, makePath(target.Output))
+	}
+	fmt.Fprintf(out, ".PHONY: all\nall: %s\n", strings.Join(outputs, " "))
+
+	for i, target := range f.Targets {
+		inputs := make([]string, 0, len(target.Inputs))
+		for _, input := range target.Inputs {
+			inputs = append(inputs, makePath(input))
+		}
+		fmt.Fprintf(out, "\n%s: %s\n", outputs[i], strings.Join(inputs, " "))
+		fmt.Fprintf(out, "\t$(MTA_BUNDLER) %s\n", makeValue(shellCommand(target.Args)))
+	}
+	return out.Flush()
+}
+
+// shellQuote quotes s for POSIX shells when it holds characters the shell would interpret
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=.,/:@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellCommand joins args into a shell command line
+func shellCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ninjaValue escapes a ninja variable value
+func ninjaValue(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+// ninjaPath escapes a path in a ninja build statement
+func ninjaPath(path string) string {
+	return strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace(path)
+}
+
+// makeValue escapes a variable value or recipe line of a makefile
+func makeValue(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+// makePath escapes a target or prerequisite of a makefile. Wildcards are escaped too, so
+// resource categories such as [gameplay] are not taken for globs.
+func makePath(path string) string {
+	return strings.NewReplacer("$", "$$", " ", `\ `, "#", `\#`, ":", `\:`, "[", `\[`, "]", `\]`, "*", `\*`, "?", `\?`).Replace(path)
+}
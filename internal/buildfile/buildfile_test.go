@@ -0,0 +1,52 @@
+package buildfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	file := File{
+		Program: "/opt/mta bundler/mta-bundler",
+		Targets: []Target{{
+			Name:   "race",
+			Output: "/out/[gameplay]/race/.mta-bundler-manifest.json",
+			Inputs: []string{"/src/[gameplay]/race/meta.xml", "/src/[gameplay]/race/my map.map"},
+			Args:   []string{"-q", "-o", "/out/[gameplay]/race", "-verbatim", "$x", "/src/[gameplay]/race/meta.xml"},
+		}},
+	}
+
+	var ninja strings.Builder
+	if err := file.Write(&ninja, FormatNinja); err != nil {
+		t.Fatalf("WriteNinja failed: %v", err)
+	}
+	for _, line := range []string{
+		"bundler = '/opt/mta bundler/mta-bundler'",
+		"build /out/[gameplay]/race/.mta-bundler-manifest.json: bundle /src/[gameplay]/race/meta.xml /src/[gameplay]/race/my$ map.map",
+		"  args = -q -o '/out/[gameplay]/race' -verbatim '$$x' '/src/[gameplay]/race/meta.xml'",
+		"build all: phony /out/[gameplay]/race/.mta-bundler-manifest.json",
+	} {
+		if !strings.Contains(ninja.String(), line+"\n") {
+			t.Errorf("Ninja file misses %q:\n%s", line, ninja.String())
+		}
+	}
+
+	var makefile strings.Builder
+	if err := file.Write(&makefile, FormatMake); err != nil {
+		t.Fatalf("WriteMake failed: %v", err)
+	}
+	for _, line := range []string{
+		"MTA_BUNDLER ?= '/opt/mta bundler/mta-bundler'",
+		`all: /out/\[gameplay\]/race/.mta-bundler-manifest.json`,
+		`/out/\[gameplay\]/race/.mta-bundler-manifest.json: /src/\[gameplay\]/race/meta.xml /src/\[gameplay\]/race/my\ map.map`,
+		"\t$(MTA_BUNDLER) -q -o '/out/[gameplay]/race' -verbatim '$$x' '/src/[gameplay]/race/meta.xml'",
+	} {
+		if !strings.Contains(makefile.String(), line+"\n") {
+			t.Errorf("Makefile misses %q:\n%s", line, makefile.String())
+		}
+	}
+
+	if err := file.Write(&strings.Builder{}, "cmake"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
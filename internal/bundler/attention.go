@@ -0,0 +1,49 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// Attention returns the cases of every resource of the build that need a decision, in
+// processing order
+func (r BuildResult) Attention() []resource.Attention {
+	var cases []resource.Attention
+	for _, res := range r.Resources {
+		cases = append(cases, res.Attention...)
+	}
+	return cases
+}
+
+// resourceAttention returns the cases of res built with options and mergeMode that need a
+// decision, including an obfuscation level lowered by its nested config files or overrides
+func (b Bundler) resourceAttention(res *resource.Resource, options compiler.CompilationOptions, mergeMode bool) []resource.Attention {
+	cases := res.Attention(mergeMode)
+	if options.ObfuscationLevel < b.options.Compilation.ObfuscationLevel {
+		cases = append(cases, resource.Attention{
+			Code:     resource.AttentionObfuscationLowered,
+			Resource: res.Name,
+			Message:  fmt.Sprintf("built with obfuscation level %d instead of %d, lowered by a nested config file or its overrides", options.ObfuscationLevel, b.options.Compilation.ObfuscationLevel),
+		})
+	}
+	return cases
+}
+
+// LogAttention logs the cases that need a decision as a section of their own, so they stand
+// out from the warnings logged while building
+func LogAttention(cases []resource.Attention) {
+	if len(cases) == 0 {
+		return
+	}
+	slog.Warn("Resources need attention", "cases", len(cases))
+	for _, c := range cases {
+		attrs := []any{"code", c.Code, "resource", c.Resource}
+		if c.Src != "" {
+			attrs = append(attrs, "file", c.Src)
+		}
+		slog.Warn(c.Message, attrs...)
+	}
+}
@@ -0,0 +1,148 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/audit"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// buildInfo is the metadata shown by the build info resource
+type buildInfo struct {
+	ID        string // Short hash of every output file of the build, as recorded in the audit log
+	BuiltAt   string // Build start time in UTC
+	Resources int    // Number of resources built successfully
+}
+
+// buildInfoMeta is the meta.xml of the build info resource. Its download priority group makes
+// clients download it before the other resources, so it is shown while they download.
+const buildInfoMeta = `<meta>
+    <info name="Build information" author="mta-bundler" type="script" description="Generated by mta-bundler: shows which build the server runs" />
+    <download_priority_group>100</download_priority_group>
+    <script src="client.luac" type="client" />
+    <script src="server.luac" type="server" />
+    <export function="getBuildInfo" type="client" />
+    <export function="getBuildInfo" type="server" />
+</meta>
+`
+
+// buildInfoHeader declares the build metadata in both scripts of the build info resource
+const buildInfoHeader = `-- Generated by mta-bundler, rebuilt with every build
+local BUILD = {
+	id = {{printf "%q" .ID}},
+	builtAt = {{printf "%q" .BuiltAt}},
+	resources = {{.Resources}},
+}
+local label = "Build " .. BUILD.id .. " (" .. BUILD.builtAt .. ")"
+
+function getBuildInfo()
+	return BUILD
+end
+`
+
+// buildInfoScripts are the client and server scripts of the build info resource
+var buildInfoScripts = map[string]*template.Template{
+	"client": template.Must(template.New("client").Parse(buildInfoHeader + `
+addEventHandler("onClientRender", root, function()
+	if isTransferBoxActive() then
+		local width, height = guiGetScreenSize()
+		dxDrawText(label, 0, 0, width - 10, height - 10, tocolor(255, 255, 255, 180), 1, "default-bold", "right", "bottom")
+	end
+end)
+
+addCommandHandler("buildinfo", function()
+	outputChatBox(label)
+end)
+`)),
+	"server": template.Must(template.New("server").Parse(buildInfoHeader + `
+addEventHandler("onResourceStart", resourceRoot, function()
+	outputServerLog("Running " .. label)
+end)
+
+addCommandHandler("buildinfo", function(source)
+	if getElementType(source) == "console" then
+		outputServerLog(label)
+	end
+end)
+`)),
+}
+
+// writeBuildInfo generates the build info resource next to the built resources
+func (b Bundler) writeBuildInfo(result BuildResult) error {
+	name := b.options.BuildInfo
+	for _, res := range result.Resources {
+		if res.Resource != nil && res.Resource.Name == name {
+			return fmt.Errorf("build info resource %q conflicts with a built resource", name)
+		}
+	}
+
+	info := buildInfo{
+		ID:        audit.HashFiles(result.OutputFiles())[:12],
+		BuiltAt:   result.StartedAt.UTC().Format(time.DateTime + " UTC"),
+		Resources: len(result.Resources) - result.FailedCount(),
+	}
+
+	outputDir := filepath.Join(b.options.OutputDir, name)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create build info resource: %v", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "mta-bundler-buildinfo-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for kind, script := range buildInfoScripts {
+		sourcePath := filepath.Join(tempDir, kind+".lua")
+		source, err := os.Create(sourcePath)
+		if err != nil {
+			return fmt.Errorf("failed to write build info script: %v", err)
+		}
+		err = script.Execute(source, info)
+		source.Close()
+		if err != nil {
+			return fmt.Errorf("failed to generate build info script: %v", err)
+		}
+
+		if _, err := b.compiler.CompileFile(sourcePath, filepath.Join(outputDir, kind+".luac"), b.options.Compilation); err != nil {
+			return fmt.Errorf("failed to compile build info script: %v", err)
+		}
+	}
+
+	meta := buildInfoMeta
+	if version := b.minVersion(b.options.Compilation); version != "" {
+		if meta, err = resource.RaiseMinVersion(meta, version); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "meta.xml"), []byte(meta), 0644); err != nil {
+		return fmt.Errorf("failed to write build info meta.xml: %v", err)
+	}
+
+	stampPath, err := b.stampOutput(outputDir)
+	if err != nil {
+		return err
+	}
+
+	if b.options.Zip {
+		files := []string{filepath.Join(outputDir, "meta.xml")}
+		for kind := range buildInfoScripts {
+			files = append(files, filepath.Join(outputDir, kind+".luac"))
+		}
+		if stampPath != "" {
+			files = append(files, stampPath)
+		}
+		if _, err := zipResource(outputDir, files); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Generated build info resource", "resource", name, "build", info.ID, "success", true)
+	return nil
+}
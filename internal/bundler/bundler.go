@@ -0,0 +1,683 @@
+package bundler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/escrow"
+	"github.com/davidbozo/mta-bundler/internal/lint"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// Options holds the settings shared by every resource processed in a build
+type Options struct {
+	InputPath       string                      // Input path given by the user (meta.xml file or directory)
+	Inputs          []string                    // meta.xml files and directories to build instead of InputPath, a directory containing them all
+	Ignore          []string                    // Directory name or path globs not searched for resources, in addition to DefaultIgnore
+	SkipCategories  []string                    // Category folders not searched for resources, by name without brackets
+	FollowSymlinks  bool                        // Search symlinked directories for resources, each directory at most once
+	MaxDepth        int                         // Levels of directories searched below an input directory, 0 for no limit
+	OutputDir       string                      // Output directory (empty means same directory as source files)
+	Compilation     compiler.CompilationOptions // Options forwarded to luac_mta
+	MergeMode       bool                        // Merge all scripts into client.luac and server.luac
+	MetaOrder       bool                        // Merge scripts in their meta.xml order instead of appending shared scripts last
+	Concat          bool                        // Merge scripts by concatenating their sources into one chunk per bundle
+	Isolate         bool                        // Run every script of concatenated bundles through pcall (requires Concat)
+	Minify          bool                        // Strip comments and whitespace from scripts before compiling them
+	RenameLocals    bool                        // Rename the locals of scripts to short meaningless names before compiling them
+	TreeShake       bool                        // Drop the top-level functions of merged bundles that nothing calls
+	FoldConstants   bool                        // Fold constant expressions and remove constant if branches of scripts before compiling them
+	Constants       map[string]any              // Values inlined for reads of these globals, which enables constant folding
+	Banner          string                      // Comment written at the top of output meta.xml files and merged bundles (empty disables it)
+	Info            []resource.InfoAttr         // Attributes set on the <info> tag of output meta.xml files
+	Commit          string                      // Commit the build is made from, replacing {commit} in the banner and info attributes
+	BundleName      string                      // Path of the merged bundles, {type} replaced by client or server (empty for client.luac and server.luac)
+	Exclude         []string                    // Resource name or path globs to skip
+	Subtrees        []config.Config             // Nested config files overriding the settings of the resources below them, parents first
+	Only            []string                    // Resource name or path globs to build, all resources when empty
+	Verbatim        []string                    // Script src globs copied as source instead of compiled
+	Unmerged        []string                    // Script src globs compiled on their own in merge mode instead of into the bundles
+	ScriptsOnly     bool                        // Write only meta.xml and scripts, without copying non-script files
+	LinkAssets      bool                        // Hardlink (or symlink) non-script files into the output instead of copying them
+	NoCache         bool                        // Set cache="false" on client and shared script tags, so clients do not keep them on disk
+	NoMinVersion    bool                        // Leave <min_mta_version> as it is, instead of raising it to the version the obfuscation level needs
+	LevelFallback   bool                        // Lower an obfuscation level the luac_mta binary does not support to the highest it does, instead of failing
+	RegexMeta       bool                        // Rewrite meta.xml with regular expressions instead of the token editor
+	Packs           []Pack                      // Groups of resources built into a single resource each (requires OutputDir)
+	Splits          []Split                     // Resources built as several resources each (requires OutputDir)
+	Dependencies    []config.Dependency         // Third-party resources downloaded and built into the output (requires OutputDir)
+	DependencyCache string                      // Directory the dependency archives are extracted to
+	Zip             bool                        // Package each resource as <name>.zip instead of a directory (requires OutputDir)
+	Stamp           BuildStamp                  // Build stamped into every output resource (zero Number disables stamping)
+	EscrowKey       []byte                      // Key for source escrow archives (nil disables escrow)
+	FailFast        bool                        // Stop the build at the first resource that fails
+	Force           bool                        // Rebuild resources even when their build manifest shows no change
+	Clean           bool                        // Remove files of the resource output directories that the build did not write (requires OutputDir)
+	BuildInfo       string                      // Name of the generated build info resource (empty disables it, requires OutputDir)
+	Checksums       bool                        // Write checksums.txt and checksums.json listing every output file (requires OutputDir)
+	Scan            bool                        // Scan scripts for backdoor patterns, failing resources with unacknowledged findings
+	NoSyntaxCheck   bool                        // Leave syntax errors to luac_mta instead of parsing every script before the build
+	Preflight       bool                        // Parse every script with luac_mta -p in batches before the build, reporting all syntax errors at once
+	Lint            map[string]lint.Setting     // meta.xml lint rules, rules missing from the map use their defaults
+	SourceMaps      bool                        // Write a source map next to each merged bundle
+	MapShim         bool                        // Also add a script translating bundle positions in error messages (requires SourceMaps)
+	Progress        ProgressReporter            // Receives the build progress (nil disables progress reporting)
+	OnResource      func(ResourceResult)        // Called by Run after each resource is built (optional)
+	Cache           *Cache                      // Keeps file hashes and parsed resources between builds (nil disables it)
+}
+
+// Bundler drives the compilation of MTA resources found under an input path
+type Bundler struct {
+	compiler compiler.CLICompiler
+	options  Options
+	ctx      context.Context // Stops the build once done, nil never does
+}
+
+// NewBundler creates a new bundler using the given compiler and options
+func NewBundler(comp compiler.CLICompiler, options Options) Bundler {
+	return Bundler{
+		compiler: comp,
+		options:  options,
+	}
+}
+
+// WithContext returns a copy of the bundler that stops once ctx is done: resources not started
+// yet are skipped, and the compilations running are killed without leaving partial outputs
+func (b Bundler) WithContext(ctx context.Context) Bundler {
+	b.ctx = ctx
+	b.compiler = b.compiler.WithContext(ctx)
+	return b
+}
+
+// interrupted reports whether the context of the bundler is done
+func (b Bundler) interrupted() bool {
+	return b.ctx != nil && b.ctx.Err() != nil
+}
+
+// WithForce returns a copy of the bundler that rebuilds every resource when force is set, even
+// those whose build manifest shows no change
+func (b Bundler) WithForce(force bool) Bundler {
+	b.options.Force = force
+	return b
+}
+
+// FindResources returns the absolute paths of all meta.xml files to process for the input path
+func (b Bundler) FindResources() ([]string, error) {
+	if len(b.options.Inputs) == 0 {
+		// Get file info (validation is expected to be done by the caller)
+		fileInfo, err := os.Stat(b.options.InputPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access input path '%s': %v", b.options.InputPath, err)
+		}
+
+		// Single meta.xml file
+		if !fileInfo.IsDir() {
+			absPath, err := filepath.Abs(b.options.InputPath)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get absolute path: %v", err)
+			}
+			return []string{absPath}, nil
+		}
+	}
+
+	inputs := b.options.Inputs
+	if len(inputs) == 0 {
+		inputs = []string{b.options.InputPath}
+	}
+	var metaPaths []string
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		found, err := b.findInput(input)
+		if err != nil {
+			return nil, err
+		}
+		// Resources reached through several inputs are built once
+		for _, metaPath := range found {
+			if !seen[metaPath] {
+				seen[metaPath] = true
+				metaPaths = append(metaPaths, metaPath)
+			}
+		}
+	}
+	return b.selectResources(metaPaths)
+}
+
+// findInput returns the absolute meta.xml paths of an input, a meta.xml file or a directory
+// searched for resources
+func (b Bundler) findInput(input string) ([]string, error) {
+	fileInfo, err := os.Stat(input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access input path '%s': %v", input, err)
+	}
+	if !fileInfo.IsDir() {
+		absPath, err := filepath.Abs(input)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get absolute path: %v", err)
+		}
+		return []string{absPath}, nil
+	}
+
+	// If it's a directory, find all meta.xml files
+	metaPaths, err := b.findResourceMetas(input)
+	if err != nil {
+		return nil, fmt.Errorf("error finding meta.xml files: %v", err)
+	}
+
+	if len(metaPaths) == 0 {
+		return nil, fmt.Errorf("no meta.xml files found in directory: %s", input)
+	}
+	return metaPaths, nil
+}
+
+// walkOptions returns how input directories are searched. An output directory inside the
+// input is never searched, it holds copies of the resources.
+func (b Bundler) walkOptions() WalkOptions {
+	options := WalkOptions{Ignore: b.options.Ignore, Root: b.options.InputPath, MaxDepth: b.options.MaxDepth,
+		SkipCategories: b.options.SkipCategories, FollowSymlinks: b.options.FollowSymlinks}
+	if b.options.OutputDir != "" {
+		options.Skip = []string{b.options.OutputDir}
+	}
+	return options
+}
+
+// selectResources removes the meta.xml paths of excluded resources and keeps the selected ones
+// when Only is set
+func (b Bundler) selectResources(metaPaths []string) ([]string, error) {
+	metaPaths = b.excludeSubtrees(ExcludeResourceMetas(b.options.InputPath, metaPaths, b.options.Exclude))
+	if len(metaPaths) == 0 {
+		return nil, fmt.Errorf("all resources in %s are excluded", b.options.InputPath)
+	}
+
+	if len(b.options.Only) > 0 {
+		metaPaths = SelectResourceMetas(b.options.InputPath, metaPaths, b.options.Only)
+		if len(metaPaths) == 0 {
+			return nil, fmt.Errorf("no resource in %s matches %s", b.options.InputPath, strings.Join(b.options.Only, ", "))
+		}
+	}
+	warnDuplicateNames(metaPaths)
+	return metaPaths, nil
+}
+
+// warnDuplicateNames warns about resources sharing a name in different folders, such as a copy
+// kept under [disabled]. MTA refuses to load any resource whose name is used more than once.
+func warnDuplicateNames(metaPaths []string) {
+	dirs := make(map[string][]string)
+	var names []string
+	for _, metaPath := range metaPaths {
+		name := filepath.Base(filepath.Dir(metaPath))
+		if len(dirs[name]) == 0 {
+			names = append(names, name)
+		}
+		dirs[name] = append(dirs[name], filepath.Dir(metaPath))
+	}
+	for _, name := range names {
+		if len(dirs[name]) > 1 {
+			slog.Warn("Resource name used more than once, MTA loads none of them", "resource", name,
+				"dirs", strings.Join(dirs[name], ", "))
+		}
+	}
+}
+
+// ResourceResult represents the outcome of building a single resource
+type ResourceResult struct {
+	MetaXMLPath string                      // Path of the resource's meta.xml
+	Resource    *resource.Resource          // Parsed resource (nil when meta.xml could not be parsed)
+	Options     compiler.CompilationOptions // Effective options after per-resource overrides
+	Compile     resource.CompileResult      // Compilation and file copy results
+	Generated   []string                    // Other files written to the output directory (lazy loader, escrow archive, manifest)
+	Archive     string                      // Zip archive the output directory was packaged into (Zip)
+	Unchanged   bool                        // Resource was not rebuilt because nothing changed since the last build
+	Reused      []string                    // Compiled scripts and copied files kept from the last build when Unchanged
+	Attention   []resource.Attention        // Decisions the build made on its own that a person should review
+	Affected    []string                    // Resources including this one, directly or not, when it failed
+	Duration    time.Duration               // Time spent building the resource
+	Error       error                       // Error if the resource failed to build
+}
+
+// BuildResult represents the outcome of building every resource under the input path
+type BuildResult struct {
+	InputPath string           // Input path given by the user
+	OutputDir string           // Output directory (empty means same directory as source files)
+	StartedAt time.Time        // When the build started
+	Duration  time.Duration    // Total build time
+	Resources []ResourceResult // Per-resource results in processing order
+	Skipped   int              // Resources not built because the build stopped early (FailFast)
+}
+
+// FailedCount returns the number of resources that failed to build
+func (r BuildResult) FailedCount() int {
+	var failed int
+	for _, res := range r.Resources {
+		if res.Error != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// UnchangedCount returns the number of resources skipped because nothing changed
+func (r BuildResult) UnchangedCount() int {
+	var unchanged int
+	for _, res := range r.Resources {
+		if res.Unchanged {
+			unchanged++
+		}
+	}
+	return unchanged
+}
+
+// OutputFiles returns the paths of every compiled script and copied file of the build. Resources
+// packaged as zip archives are represented by their archive.
+func (r BuildResult) OutputFiles() []string {
+	var paths []string
+	for _, res := range r.Resources {
+		if res.Archive != "" {
+			paths = append(paths, res.Archive)
+			continue
+		}
+		paths = append(paths, res.outputFiles()...)
+	}
+	return paths
+}
+
+// outputFiles returns the paths of the compiled scripts and copied files of the resource,
+// including those kept from the last build
+func (r ResourceResult) outputFiles() []string {
+	paths := append([]string{}, r.Reused...)
+	for _, result := range r.Compile.Compilation.Results {
+		if result.Success {
+			paths = append(paths, result.OutputFile)
+		}
+	}
+	for _, result := range r.Compile.FileCopy.Results {
+		if result.Success {
+			paths = append(paths, result.OutputPath)
+		}
+	}
+	return paths
+}
+
+// Run compiles every resource found under the input path
+func (b Bundler) Run() (BuildResult, error) {
+	slog.Info("Starting compilation", "input", b.options.InputPath)
+
+	result := BuildResult{
+		InputPath: b.options.InputPath,
+		OutputDir: b.options.OutputDir,
+		StartedAt: time.Now(),
+	}
+
+	metaPaths, err := b.FindResources()
+	if err != nil {
+		return result, err
+	}
+
+	slog.Info("Found resources to process", "count", len(metaPaths))
+
+	if b.options.Compilation, err = b.supportedOptions(b.options.Compilation, ""); err != nil {
+		return result, err
+	}
+	if err := b.checkSyntax(metaPaths); err != nil {
+		return result, err
+	}
+	if err := b.Warmup(); err != nil {
+		return result, err
+	}
+	if err := b.preflight(metaPaths); err != nil {
+		return result, err
+	}
+
+	allPaths := metaPaths
+	metaPaths, packs, packMembers, err := b.planPacks(metaPaths)
+	if err != nil {
+		return result, err
+	}
+	var packed []string
+	for _, members := range packMembers {
+		packed = append(packed, members...)
+	}
+	if err := b.checkSplits(allPaths, packed); err != nil {
+		return result, err
+	}
+	if err := b.checkDependencies(allPaths); err != nil {
+		return result, err
+	}
+	// Resources are built after the resources they include
+	includes := IncludeGraph(allPaths)
+	if metaPaths, err = orderByIncludes(metaPaths, includes); err != nil {
+		return result, err
+	}
+	total := len(metaPaths) + len(packs) + len(b.options.Dependencies)
+
+	if b.options.Progress != nil {
+		b.options.Progress.StartProgress(total)
+	}
+
+	// Process each meta.xml file
+	stopped := false
+	for i, metaPath := range metaPaths {
+		if b.interrupted() {
+			result.Skipped = total - i
+			stopped = true
+			break
+		}
+		slog.Info("Processing resource", "progress", fmt.Sprintf("%d/%d", i+1, total), "meta", metaPath)
+
+		resResult := b.BuildResource(metaPath)
+		if resResult.Error != nil {
+			slog.Error("Failed to process resource", "meta", metaPath, "error", resResult.Error)
+		}
+		reportAffected(&resResult, resourceName(metaPath), includes)
+		result.Resources = append(result.Resources, resResult)
+		if b.options.OnResource != nil {
+			b.options.OnResource(resResult)
+		}
+		if b.options.Progress != nil {
+			b.options.Progress.AdvanceProgress(i + 1)
+		}
+		if resResult.Error != nil && b.options.FailFast {
+			result.Skipped = total - (i + 1)
+			stopped = true
+			break
+		}
+	}
+
+	for i, pack := range packs {
+		if stopped {
+			break
+		}
+		done := len(metaPaths) + i + 1
+		if b.interrupted() {
+			result.Skipped = total - done + 1
+			break
+		}
+		slog.Info("Processing pack", "progress", fmt.Sprintf("%d/%d", done, total), "pack", pack.Name, "resources", len(packMembers[i]))
+
+		// Packs are not passed to OnResource, they have no source resource to analyze
+		resResult := b.BuildPack(pack, packMembers[i])
+		result.Resources = append(result.Resources, resResult)
+		if resResult.Error != nil {
+			slog.Error("Failed to process pack", "pack", pack.Name, "error", resResult.Error)
+		}
+		if b.options.Progress != nil {
+			b.options.Progress.AdvanceProgress(done)
+		}
+		if resResult.Error != nil && b.options.FailFast {
+			result.Skipped = total - done
+			stopped = true
+		}
+	}
+
+	for i, dep := range b.options.Dependencies {
+		if stopped {
+			break
+		}
+		done := len(metaPaths) + len(packs) + i + 1
+		if b.interrupted() {
+			result.Skipped = total - done + 1
+			break
+		}
+		slog.Info("Processing dependency", "progress", fmt.Sprintf("%d/%d", done, total), "resource", dep.Name, "url", dep.URL)
+
+		// Dependencies are not passed to OnResource, their overrides cannot be changed
+		resResult := b.BuildDependency(dep)
+		if resResult.Error != nil {
+			slog.Error("Failed to process dependency", "resource", dep.Name, "error", resResult.Error)
+		}
+		reportAffected(&resResult, dep.Name, includes)
+		result.Resources = append(result.Resources, resResult)
+		if b.options.Progress != nil {
+			b.options.Progress.AdvanceProgress(done)
+		}
+		if resResult.Error != nil && b.options.FailFast {
+			result.Skipped = total - done
+			stopped = true
+		}
+	}
+
+	if b.options.Progress != nil {
+		b.options.Progress.StopProgress()
+	}
+
+	// Build info and checksums would describe a partial build
+	if b.interrupted() {
+		result.Duration = time.Since(result.StartedAt)
+		slog.Warn("Build interrupted", "built", len(result.Resources), "skipped", result.Skipped, "duration", result.Duration)
+		return result, fmt.Errorf("build interrupted, %d resource(s) not built", result.Skipped)
+	}
+
+	if b.options.BuildInfo != "" && b.options.OutputDir != "" {
+		if err := b.writeBuildInfo(result); err != nil {
+			slog.Error("Failed to generate build info resource", "error", err)
+		}
+	}
+
+	// Written last, so the checksums cover every file of the build
+	b.updateChecksums()
+
+	result.Duration = time.Since(result.StartedAt)
+
+	if result.Skipped > 0 {
+		slog.Warn("Stopped at the first failed resource", "skipped", result.Skipped)
+	}
+	attention := result.Attention()
+	LogAttention(attention)
+
+	failed := result.FailedCount()
+	summary := []any{
+		"resources", len(result.Resources),
+		"succeeded", len(result.Resources) - failed,
+		"failed", failed,
+	}
+	if unchanged := result.UnchangedCount(); unchanged > 0 {
+		summary = append(summary, "unchanged", unchanged)
+	}
+	if result.Skipped > 0 {
+		summary = append(summary, "skipped", result.Skipped)
+	}
+	if len(attention) > 0 {
+		summary = append(summary, "attention", len(attention))
+	}
+	slog.Log(context.Background(), LevelSummary, "Build completed", append(summary, "duration", result.Duration)...)
+	return result, nil
+}
+
+// BuildResource parses and compiles a single resource, as several resources when it is split.
+// Failures are reported through the result's Error field.
+func (b Bundler) BuildResource(metaPath string) ResourceResult {
+	if split, ok := b.splitFor(metaPath); ok {
+		return b.BuildSplit(split, metaPath)
+	}
+
+	startTime := time.Now()
+	result := ResourceResult{MetaXMLPath: metaPath}
+
+	res, err := b.parseResource(metaPath)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	result.Resource = res
+
+	options, mergeMode, err := b.resourceOptions(res)
+	result.Options = options
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	if err := b.applyVerbatim(res); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if err := b.lintResource(res); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if err := b.scanResource(res); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	res.SkipAssets = b.options.ScriptsOnly
+	res.LinkAssets = b.options.LinkAssets
+	res.NoCache = b.options.NoCache
+	res.RegexMeta = b.options.RegexMeta
+	res.MetaOrder = b.options.MetaOrder
+	res.Concat = b.options.Concat
+	res.Isolate = b.options.Isolate
+	res.BundleName = b.options.BundleName
+	res.Transform = b.sourceTransform()
+	res.Info = b.infoAttrs()
+	res.Banner = b.expand(b.options.Banner)
+	res.MinVersion = b.minVersion(options)
+	result.Attention = b.resourceAttention(res, options, mergeMode)
+	exportCases, err := b.checkExports(res, mergeMode)
+	result.Attention = append(result.Attention, exportCases...)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	inputs, skip := b.prepareManifest(res, options, mergeMode, &result)
+	if skip {
+		// The outputs are kept, but carry the number of this build
+		if err := b.stampResult(&result); err != nil {
+			result.Error = err
+		}
+		result.Duration = time.Since(startTime)
+		slog.Info("Resource unchanged, skipped", "resource", res.Name, "success", true)
+		return result
+	}
+
+	result.Compile, err = res.Compile(b.compiler, b.inputRoot(), b.options.OutputDir, options, mergeMode)
+	if err != nil {
+		result.Error = fmt.Errorf("error compiling resource %s: %v", res.Name, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	mapPaths, err := b.writeSourceMaps(result.Compile, res.BaseDir)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	result.Generated = append(result.Generated, mapPaths...)
+
+	loaderPath, err := b.applyLazyFiles(res, result.Compile.OutputDir, options)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if loaderPath != "" {
+		result.Generated = append(result.Generated, loaderPath)
+	}
+
+	if len(b.options.EscrowKey) > 0 {
+		if err := b.writeEscrow(res, result.Compile.OutputDir, options, mergeMode); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		result.Generated = append(result.Generated, filepath.Join(result.Compile.OutputDir, escrow.FileName))
+	}
+
+	if err := b.stampResult(&result); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	if inputs != nil {
+		manifestPath, err := writeManifest(result, *inputs)
+		if err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		result.Generated = append(result.Generated, manifestPath)
+	}
+
+	if b.options.Clean && b.options.OutputDir != "" {
+		if err := b.cleanOutput(result); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+	}
+
+	if b.options.Zip && b.options.OutputDir != "" {
+		if err := b.zipOutput(&result, result.WrittenFiles()); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+	slog.Info("Compiled resource", "resource", res.Name, "success", true, "duration", result.Duration)
+	return result
+}
+
+// minVersion returns the MTA version scripts compiled with options need, empty when every version
+// runs them or raising <min_mta_version> is disabled
+func (b Bundler) minVersion(options compiler.CompilationOptions) string {
+	if b.options.NoMinVersion {
+		return ""
+	}
+	return options.ObfuscationLevel.MinMTAVersion()
+}
+
+// resourceOptions returns the compilation options and merge mode for a resource, applying the
+// nested config files of its subtrees and then the resource's override file on top of the
+// global options
+func (b Bundler) resourceOptions(res *resource.Resource) (compiler.CompilationOptions, bool, error) {
+	options, mergeMode := b.subtreeOptions(res)
+
+	overrides, ok, err := config.LoadResourceOverrides(res.BaseDir)
+	if err != nil {
+		return options, mergeMode, err
+	}
+	if ok {
+		slog.Info("Using resource overrides", "resource", res.Name, "path", overrides.Path)
+		options, mergeMode = overrides.Apply(options, mergeMode)
+	}
+
+	options, err = b.supportedOptions(options, res.Name)
+	return options, mergeMode, err
+}
+
+// subtreeOptions returns the compilation options and merge mode for a resource after applying
+// the nested config files of its subtrees, without its override file
+func (b Bundler) subtreeOptions(res *resource.Resource) (compiler.CompilationOptions, bool) {
+	options, mergeMode := b.options.Compilation, b.options.MergeMode
+	for _, subtree := range b.subtreesOf(res.BaseDir) {
+		slog.Debug("Using subtree config", "resource", res.Name, "path", subtree.Path)
+		options, mergeMode = subtree.Apply(options, mergeMode)
+	}
+	return options, mergeMode
+}
+
+// inputRoot returns the directory output paths are calculated from. For a single meta.xml
+// input this is the directory containing it, so the resource is written to the output root.
+// An input category folder, such as [gamemodes], is kept in the output paths.
+func (b Bundler) inputRoot() string {
+	if info, err := os.Stat(b.options.InputPath); err == nil && !info.IsDir() {
+		return filepath.Dir(b.options.InputPath)
+	}
+	return CategoryRoot(b.options.InputPath)
+}
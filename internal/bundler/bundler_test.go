@@ -0,0 +1,50 @@
+package bundler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunInterrupted(t *testing.T) {
+	// Writes part of its output, then hangs until it is killed, except for the warmup script
+	comp := fakeCompiler(t, `while [ $# -gt 1 ]; do [ "$1" = "-o" ] && out="$2"; shift; done
+printf '\033Lua' > "$out"
+case "$1" in *warmup.lua) exit 0;; esac
+exec sleep 10
+`)
+
+	inputDir := t.TempDir()
+	for _, name := range []string{"race", "shop"} {
+		resourceDir := filepath.Join(inputDir, name)
+		os.MkdirAll(resourceDir, 0755)
+		os.WriteFile(filepath.Join(resourceDir, "meta.xml"), []byte(`<meta><script src="server.lua" type="server" /></meta>`), 0644)
+		os.WriteFile(filepath.Join(resourceDir, "server.lua"), []byte("print(1)"), 0644)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	b := NewBundler(comp, Options{InputPath: inputDir, OutputDir: outputDir, NoSyntaxCheck: true}).WithContext(ctx)
+	start := time.Now()
+	result, err := b.Run()
+	if err == nil {
+		t.Fatal("Expected an interrupted build to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the running compilation to be killed, the build took %v", elapsed)
+	}
+	if len(result.Resources) != 1 || result.Skipped != 1 {
+		t.Errorf("Expected one resource built and one skipped, got %d and %d", len(result.Resources), result.Skipped)
+	}
+	for _, name := range []string{"server.luac", "server.luac.part"} {
+		if _, err := os.Stat(filepath.Join(outputDir, "race", name)); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s to be left behind", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "race", ManifestFileName)); !os.IsNotExist(err) {
+		t.Error("Expected no build manifest for the interrupted resource")
+	}
+}
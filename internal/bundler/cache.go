@@ -0,0 +1,360 @@
+package bundler
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/fsnotify/fsnotify"
+)
+
+// racyWindow is how recent a modification time must be for a file not to be cached. A file
+// written again within the resolution of its file system keeps its modification time, so its
+// cached entry could hide the change.
+const racyWindow = 2 * time.Second
+
+// Cache keeps file hashes, parsed meta.xml files, the scripts known to parse and the resources
+// found in input directories between the builds of a long-running process, such as the daemon.
+// An entry is used as long as the file it was read from keeps its size and modification time,
+// and the resources found as long as no directory or meta.xml file of the searched tree is
+// created, removed or renamed. A Cache is safe for concurrent use and shared by the copies of
+// the bundler it is set on.
+type Cache struct {
+	mu        sync.Mutex
+	hashes    map[string]cachedHash     // SHA-256 hashes by file path
+	resources map[string]cachedResource // Parsed resources by meta.xml path
+	parsed    map[string]fileStamp      // Scripts without syntax errors by path
+	metas     map[string][]string       // meta.xml paths found by directory and walk options
+	watcher   *fsnotify.Watcher         // Watches the searched directories, nil until the first search
+	watched   map[string]bool           // Directories added to the watcher
+	changes   int                       // Number of times the found meta.xml paths were dropped
+}
+
+// fileStamp identifies the version of a file
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+type cachedHash struct {
+	stamp fileStamp
+	hash  string
+}
+
+type cachedResource struct {
+	stamp    fileStamp
+	resource *resource.Resource
+}
+
+// NewCache creates an empty cache
+func NewCache() *Cache {
+	return &Cache{
+		hashes:    make(map[string]cachedHash),
+		resources: make(map[string]cachedResource),
+		parsed:    make(map[string]fileStamp),
+		metas:     make(map[string][]string),
+		watched:   make(map[string]bool),
+	}
+}
+
+// Close stops watching the searched directories
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watcher == nil {
+		return nil
+	}
+	err := c.watcher.Close()
+	c.watcher, c.watched = nil, make(map[string]bool)
+	clear(c.metas)
+	return err
+}
+
+// Len returns the number of cached file hashes and resources
+func (c *Cache) Len() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.hashes), len(c.resources)
+}
+
+// stampFile returns the stamp of path, false when it cannot be cached because it was modified
+// too recently
+func stampFile(path string) (fileStamp, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, false, err
+	}
+	stamp := fileStamp{size: info.Size(), modTime: info.ModTime()}
+	return stamp, time.Since(stamp.modTime) >= racyWindow, nil
+}
+
+// hash returns the SHA-256 hash of a file, hashing it only when it changed
+func (c *Cache) hash(path string) (string, error) {
+	stamp, cacheable, err := stampFile(path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	entry, ok := c.hashes[path]
+	c.mu.Unlock()
+	if ok && entry.stamp == stamp {
+		return entry.hash, nil
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	if cacheable {
+		c.hashes[path] = cachedHash{stamp: stamp, hash: hash}
+	} else {
+		delete(c.hashes, path)
+	}
+	c.mu.Unlock()
+	return hash, nil
+}
+
+// resource returns the resource of a meta.xml file, parsing it only when it changed. Resources
+// with wildcard script srcs are parsed every time: their scripts change with the files of the
+// resource directory. The build modifies resources, so each call returns a copy.
+func (c *Cache) resource(metaPath string) (*resource.Resource, error) {
+	stamp, cacheable, err := stampFile(metaPath)
+	if err != nil {
+		return resource.NewResource(metaPath)
+	}
+	c.mu.Lock()
+	entry, ok := c.resources[metaPath]
+	c.mu.Unlock()
+	if ok && entry.stamp == stamp {
+		return cloneResource(entry.resource), nil
+	}
+
+	res, err := resource.NewResource(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if cacheable && len(res.Globs) == 0 {
+		c.resources[metaPath] = cachedResource{stamp: stamp, resource: cloneResource(res)}
+	} else {
+		delete(c.resources, metaPath)
+	}
+	c.mu.Unlock()
+	return res, nil
+}
+
+// checked reports whether the script at path was found to parse and has not changed since,
+// always false without a cache. It returns the stamp to record with markChecked once the
+// script parses, zero when it cannot be cached.
+func (c *Cache) checked(path string) (fileStamp, bool) {
+	if c == nil {
+		return fileStamp{}, false
+	}
+	stamp, cacheable, err := stampFile(path)
+	if err != nil {
+		return fileStamp{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if known, ok := c.parsed[path]; ok && known == stamp {
+		return stamp, true
+	}
+	if !cacheable {
+		return fileStamp{}, false
+	}
+	return stamp, false
+}
+
+// markChecked records that the script at path with stamp parses, when there is a cache
+func (c *Cache) markChecked(path string, stamp fileStamp) {
+	if c == nil || stamp == (fileStamp{}) {
+		return
+	}
+	c.mu.Lock()
+	c.parsed[path] = stamp
+	c.mu.Unlock()
+}
+
+// resourceMetas returns the meta.xml files found in the directory dir with options, searching
+// it again only after a directory or meta.xml file of the tree was created, removed or renamed.
+// Searches following symlinks are not kept, the watcher does not see the linked directories.
+func (c *Cache) resourceMetas(dir string, options WalkOptions) ([]string, error) {
+	if options.FollowSymlinks {
+		return searchResourceMetas(dir, options)
+	}
+	key := fmt.Sprintf("%s\x00%+v", dir, options)
+	c.mu.Lock()
+	metaPaths, ok := c.metas[key]
+	changes := c.changes
+	c.mu.Unlock()
+	if ok {
+		slog.Debug("No resource added or removed since the last search", "dir", dir)
+		return slices.Clone(metaPaths), nil
+	}
+
+	// Watched before searching, so a change during the search drops its result
+	err := c.watchTree(dir, options)
+	if err != nil {
+		slog.Debug("Cannot watch the input directory, resources are searched on every build", "dir", dir, "error", err)
+	}
+	metaPaths, searchErr := searchResourceMetas(dir, options)
+	if searchErr != nil || err != nil {
+		return metaPaths, searchErr
+	}
+	c.mu.Lock()
+	if c.changes == changes {
+		c.metas[key] = slices.Clone(metaPaths)
+	}
+	c.mu.Unlock()
+	return metaPaths, nil
+}
+
+// watchTree watches the directories of dir searched with options, starting the watcher on
+// first use
+func (c *Cache) watchTree(dir string, options WalkOptions) error {
+	filter, err := newDirFilter(dir, options)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.watcher == nil {
+		if c.watcher, err = fsnotify.NewWatcher(); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		go c.dropMetasOnChange(c.watcher)
+	}
+	watcher := c.watcher
+	c.mu.Unlock()
+
+	return filepath.WalkDir(filter.walkRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if filter.reason(path) != "" {
+			return filepath.SkipDir
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.watched[path] {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			return err
+		}
+		c.watched[path] = true
+		return nil
+	})
+}
+
+// dropMetasOnChange drops the found meta.xml files when the events of watcher show a change of
+// the searched trees, until the watcher is closed. Writes to files never change which resources
+// exist, so compiling in place or editing scripts keeps them.
+func (c *Cache) dropMetasOnChange(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			c.mu.Lock()
+			changed := strings.ToLower(filepath.Base(event.Name)) == "meta.xml" || c.watched[event.Name]
+			if c.watched[event.Name] && !event.Has(fsnotify.Create) {
+				// No longer watched with its subdirectories, the next search adds the directories
+				// created in their place
+				for path := range c.watched {
+					if isWithinDir(path, event.Name) {
+						delete(c.watched, path)
+					}
+				}
+			}
+			c.mu.Unlock()
+			if !changed && event.Has(fsnotify.Create) {
+				info, err := os.Stat(event.Name)
+				changed = err == nil && info.IsDir()
+			}
+			if changed {
+				c.dropMetas("path", event.Name)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost
+			c.dropMetas("error", err)
+		}
+	}
+}
+
+// dropMetas forgets the found meta.xml files, so the next build searches the input again. args
+// are logged with the reason.
+func (c *Cache) dropMetas(args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes++
+	if len(c.metas) > 0 {
+		slog.Debug("Input changed, resources will be searched again", args...)
+		clear(c.metas)
+	}
+}
+
+// cloneResource copies res and the slices of its meta.xml, so changes to the copy do not reach
+// the cached resource
+func cloneResource(res *resource.Resource) *resource.Resource {
+	clone := *res
+	clone.Meta.Scripts = slices.Clone(res.Meta.Scripts)
+	clone.Meta.Maps = slices.Clone(res.Meta.Maps)
+	clone.Meta.Files = slices.Clone(res.Meta.Files)
+	clone.Meta.Configs = slices.Clone(res.Meta.Configs)
+	clone.Meta.HTMLs = slices.Clone(res.Meta.HTMLs)
+	clone.Meta.Exports = slices.Clone(res.Meta.Exports)
+	clone.Meta.Include = slices.Clone(res.Meta.Include)
+	clone.Files = slices.Clone(res.Files)
+	return &clone
+}
+
+// parseResource parses the resource of a meta.xml file, through the cache when one is set
+func (b Bundler) parseResource(metaPath string) (*resource.Resource, error) {
+	if b.options.Cache != nil {
+		return b.options.Cache.resource(metaPath)
+	}
+	return resource.NewResource(metaPath)
+}
+
+// findResourceMetas returns the meta.xml files found in the input directory dir, through the
+// cache when one is set
+func (b Bundler) findResourceMetas(dir string) ([]string, error) {
+	if b.options.Cache != nil {
+		return b.options.Cache.resourceMetas(dir, b.walkOptions())
+	}
+	return searchResourceMetas(dir, b.walkOptions())
+}
+
+// searchResourceMetas returns the meta.xml files found in the directory dir with options
+func searchResourceMetas(dir string, options WalkOptions) ([]string, error) {
+	slog.Info("Searching for meta.xml files", "dir", dir)
+	return FindMTAResourceMetas(dir, options)
+}
+
+// hashInput returns the SHA-256 hash of an input file, through the cache when one is set
+func (b Bundler) hashInput(path string) (string, error) {
+	if b.options.Cache != nil {
+		return b.options.Cache.hash(path)
+	}
+	return hashFile(path)
+}
@@ -0,0 +1,118 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.xml")
+	scriptPath := filepath.Join(dir, "server.lua")
+	os.WriteFile(metaPath, []byte(`<meta><script src="server.lua" type="server" /></meta>`), 0644)
+	os.WriteFile(scriptPath, []byte("print(1)"), 0644)
+	// Files written just now are never cached
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(metaPath, old, old)
+	os.Chtimes(scriptPath, old, old)
+
+	cache := NewCache()
+	first, err := cache.hash(scriptPath)
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if hashes, _ := cache.Len(); hashes != 1 {
+		t.Fatalf("Expected the hash to be cached, got %d entries", hashes)
+	}
+
+	res, err := cache.resource(metaPath)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	res.Meta.Scripts[0].Src = "changed.lua"
+	again, _ := cache.resource(metaPath)
+	if again.Meta.Scripts[0].Src != "server.lua" {
+		t.Errorf("Expected changes to a resource not to reach the cache, got src %q", again.Meta.Scripts[0].Src)
+	}
+
+	stamp, known := cache.checked(scriptPath)
+	if known {
+		t.Fatal("Expected the script not to be known to parse")
+	}
+	cache.markChecked(scriptPath, stamp)
+	if _, known := cache.checked(scriptPath); !known {
+		t.Error("Expected the script to be known to parse")
+	}
+
+	// Same size, new modification time
+	os.WriteFile(scriptPath, []byte("print(2)"), 0644)
+	second, _ := cache.hash(scriptPath)
+	if second == first {
+		t.Error("Expected a changed file to be hashed again")
+	}
+	if _, known := cache.checked(scriptPath); known {
+		t.Error("Expected a changed script to be parsed again")
+	}
+	if hashes, _ := cache.Len(); hashes != 0 {
+		t.Errorf("Expected a file modified just now not to be cached, got %d entries", hashes)
+	}
+}
+
+func TestCacheResourceMetas(t *testing.T) {
+	dir := t.TempDir()
+	writeMeta := func(name string) {
+		os.MkdirAll(filepath.Join(dir, name), 0755)
+		os.WriteFile(filepath.Join(dir, name, "meta.xml"), []byte(`<meta />`), 0644)
+	}
+	writeMeta("race")
+
+	cache := NewCache()
+	defer cache.Close()
+	// Found meta.xml files are dropped in the background, wait for the next search to see them
+	expect := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			metaPaths, err := cache.resourceMetas(dir, WalkOptions{})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(metaPaths) == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d resources, got %v", want, metaPaths)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	expect(1)
+
+	// Compiling in place writes files into resources but adds none
+	os.WriteFile(filepath.Join(dir, "race", "server.luac"), []byte("compiled"), 0644)
+	time.Sleep(100 * time.Millisecond)
+	cache.mu.Lock()
+	kept := len(cache.metas)
+	cache.mu.Unlock()
+	if kept != 1 {
+		t.Error("Expected writing a file not to drop the found resources")
+	}
+
+	writeMeta("admin")
+	expect(2)
+	writeMeta(filepath.Join("[gamemodes]", "dm"))
+	expect(3)
+	os.RemoveAll(filepath.Join(dir, "admin"))
+	expect(2)
+
+	// A folder renamed and created again is watched again, with its subfolders
+	os.Rename(filepath.Join(dir, "[gamemodes]"), filepath.Join(dir, "[old]"))
+	expect(2)
+	os.MkdirAll(filepath.Join(dir, "[gamemodes]", "dm"), 0755)
+	time.Sleep(100 * time.Millisecond)
+	expect(2)
+	writeMeta(filepath.Join("[gamemodes]", "dm", "race"))
+	expect(3)
+}
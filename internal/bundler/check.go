@@ -0,0 +1,80 @@
+package bundler
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/graph"
+	"github.com/davidbozo/mta-bundler/internal/lint"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// CheckResult is the outcome of checking the resources of an input path
+type CheckResult struct {
+	Resources int                  // Resources checked
+	Problems  []resource.Problem   // Problems found, empty when every resource is valid
+	Warnings  []resource.Problem   // Findings of lint rules with warning severity, which do not fail the check
+	Attention []resource.Attention // Decisions a build would make on its own that a person should review
+}
+
+// Check validates the resources of the input path without compiling or writing anything. A
+// meta.xml that cannot be parsed is reported as a problem of its resource.
+func (b Bundler) Check() (CheckResult, error) {
+	metaPaths, err := b.FindResources()
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	result := CheckResult{Resources: len(metaPaths)}
+	var cycle *graph.CycleError
+	if _, err := IncludeGraph(metaPaths).Order(); errors.As(err, &cycle) {
+		result.Problems = append(result.Problems, resource.Problem{Resource: cycle.Cycle[0], Message: err.Error()})
+	}
+	for _, metaPath := range metaPaths {
+		res, err := b.parseResource(metaPath)
+		if err != nil {
+			result.Problems = append(result.Problems, resource.Problem{Resource: filepath.Base(filepath.Dir(metaPath)), Message: err.Error()})
+			continue
+		}
+		result.Problems = append(result.Problems, res.Check()...)
+		if !b.options.NoSyntaxCheck {
+			result.Problems = append(result.Problems, syntaxProblems(res)...)
+		}
+		for _, finding := range lint.Lint(res, b.options.Lint) {
+			problem := resource.Problem{Resource: res.Name, Src: finding.Src, Message: fmt.Sprintf("%s [%s]", finding.Message, finding.Rule)}
+			if finding.Severity == lint.SeverityError {
+				result.Problems = append(result.Problems, problem)
+			} else {
+				result.Warnings = append(result.Warnings, problem)
+			}
+		}
+		if b.options.Scan {
+			pending, _, err := scanFindings(res)
+			if err != nil {
+				result.Problems = append(result.Problems, resource.Problem{Resource: res.Name, Message: err.Error()})
+			}
+			for _, finding := range pending {
+				result.Problems = append(result.Problems, resource.Problem{Resource: res.Name, Src: finding.File,
+					Message: fmt.Sprintf("line %d: %s [%s]", finding.Line, finding.Message, finding.ID())})
+			}
+		}
+		if options, mergeMode, err := b.resourceOptions(res); err == nil {
+			// Scripts left out of the bundles change what merge mode cases are found
+			if mergeMode {
+				if err := b.applyVerbatim(res); err != nil {
+					result.Problems = append(result.Problems, resource.Problem{Resource: res.Name, Message: err.Error()})
+					continue
+				}
+				res.BundleName = b.options.BundleName
+			}
+			result.Attention = append(result.Attention, b.resourceAttention(res, options, mergeMode)...)
+			if mergeMode {
+				problems, cases := res.CheckExports()
+				result.Problems = append(result.Problems, problems...)
+				result.Attention = append(result.Attention, cases...)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package bundler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Checksum files written to the output root, listing every produced file
+const (
+	ChecksumsFileName     = "checksums.txt"  // sha256sum format, checked with sha256sum -c
+	ChecksumsJSONFileName = "checksums.json" // The Checksums as JSON
+)
+
+// unlistedFiles are build bookkeeping files left out of the checksums
+var unlistedFiles = map[string]bool{
+	ManifestFileName:      true,
+	StampStateFileName:    true,
+	ChecksumsFileName:     true,
+	ChecksumsJSONFileName: true,
+}
+
+// Checksums lists the files of an output directory
+type Checksums struct {
+	GeneratedAt time.Time  `json:"generated_at"`
+	Build       int        `json:"build,omitempty"` // Stamped build number, 0 without -stamp
+	Files       []Checksum `json:"files"`
+}
+
+// Checksum is a file of an output directory
+type Checksum struct {
+	Path   string `json:"path"`   // Path relative to the output directory, slash-separated
+	Size   int64  `json:"size"`   // Size in bytes
+	SHA256 string `json:"sha256"` // Hex SHA-256 of the content
+}
+
+// ComputeChecksums hashes every file below dir, sorted by path. Symlinked files, as written
+// by -link-assets, are listed with the content they point to.
+func ComputeChecksums(dir string) ([]Checksum, error) {
+	var checksums []Checksum
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path); err != nil {
+				return fmt.Errorf("broken link %s: %v", path, err)
+			}
+		}
+		if !info.Mode().IsRegular() || unlistedFiles[info.Name()] {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		checksums = append(checksums, Checksum{Path: filepath.ToSlash(relPath), Size: info.Size(), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash output files: %v", err)
+	}
+	return checksums, nil
+}
+
+// WriteChecksums writes checksums.txt and checksums.json to the root of the output directory,
+// listing every file it holds. It returns the number of files listed.
+func (b Bundler) WriteChecksums() (int, error) {
+	outputDir := b.options.OutputDir
+	files, err := ComputeChecksums(outputDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var text strings.Builder
+	for _, file := range files {
+		// Two spaces mark a binary-safe entry for sha256sum -c
+		fmt.Fprintf(&text, "%s  %s\n", file.SHA256, file.Path)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, ChecksumsFileName), []byte(text.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %v", ChecksumsFileName, err)
+	}
+
+	data, err := json.MarshalIndent(Checksums{GeneratedAt: time.Now().UTC(), Build: b.options.Stamp.Number, Files: files}, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode checksums: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, ChecksumsJSONFileName), append(data, '\n'), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %v", ChecksumsJSONFileName, err)
+	}
+	return len(files), nil
+}
+
+// updateChecksums writes the checksum files when enabled, after a build or a rebuild in watch mode
+func (b Bundler) updateChecksums() {
+	if !b.options.Checksums || b.options.OutputDir == "" {
+		return
+	}
+	count, err := b.WriteChecksums()
+	if err != nil {
+		slog.Error("Failed to write checksums", "error", err)
+		return
+	}
+	slog.Info("Checksums written", "path", filepath.Join(b.options.OutputDir, ChecksumsFileName), "files", count)
+}
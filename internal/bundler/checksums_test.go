@@ -0,0 +1,49 @@
+package bundler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+func TestWriteChecksums(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string, parts ...string) {
+		path := filepath.Join(append([]string{dir}, parts...)...)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	write("abc", "shop", "client.luac")
+	write("<meta/>", "shop", "meta.xml")
+	write("{}", "shop", ManifestFileName)
+	write("{}", StampStateFileName)
+	write("stale", ChecksumsFileName)
+
+	b := NewBundler(compiler.CLICompiler{}, Options{OutputDir: dir, Checksums: true, Stamp: BuildStamp{Number: 7}})
+	count, err := b.WriteChecksums()
+	if err != nil {
+		t.Fatalf("WriteChecksums failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 files, got %d", count)
+	}
+
+	text, _ := os.ReadFile(filepath.Join(dir, ChecksumsFileName))
+	expected := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad  shop/client.luac\n" +
+		"8ca2509d4661824d7d04664645ba88b1a4f1043e8dfc805a297dc9d94438be02  shop/meta.xml\n"
+	if string(text) != expected {
+		t.Errorf("Unexpected %s:\n%s", ChecksumsFileName, text)
+	}
+
+	var checksums Checksums
+	data, _ := os.ReadFile(filepath.Join(dir, ChecksumsJSONFileName))
+	if err := json.Unmarshal(data, &checksums); err != nil {
+		t.Fatalf("Invalid %s: %v", ChecksumsJSONFileName, err)
+	}
+	if checksums.Build != 7 || len(checksums.Files) != 2 || checksums.Files[0] != (Checksum{Path: "shop/client.luac", Size: 3, SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}) {
+		t.Errorf("Unexpected checksums: %+v", checksums)
+	}
+}
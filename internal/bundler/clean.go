@@ -0,0 +1,100 @@
+package bundler
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// WrittenFiles returns the paths of every file the build wrote for the resource: meta.xml,
+// compiled scripts, copied files and generated files. For an unchanged resource these are
+// the files of the last build.
+func (r ResourceResult) WrittenFiles() []string {
+	var paths []string
+	if r.Compile.OutputDir != "" {
+		paths = append(paths, filepath.Join(r.Compile.OutputDir, "meta.xml"))
+	}
+	paths = append(paths, r.outputFiles()...)
+	return append(paths, r.Generated...)
+}
+
+// cleanOutput removes the files of a resource's output directory that the build did not write,
+// such as compiled scripts of renamed sources or deleted assets, and the directories left empty
+func (b Bundler) cleanOutput(result ResourceResult) error {
+	if result.Compile.OutputDir == "" {
+		return nil
+	}
+
+	removed, err := pruneDir(result.Compile.OutputDir, result.WrittenFiles())
+	if err != nil {
+		return fmt.Errorf("failed to clean output directory: %v", err)
+	}
+
+	log := slog.With("resource", result.Resource.Name)
+	for _, path := range removed {
+		log.Debug("Removed stale output file", "path", path)
+	}
+	if len(removed) > 0 {
+		log.Info("Removed stale output files", "success", true, "count", len(removed))
+	}
+	return nil
+}
+
+// pruneDir removes every file below dir that is not listed in keep, then the directories left
+// empty. Subdirectories containing a meta.xml are separate resources and are left untouched.
+// It returns the removed files.
+func pruneDir(dir string, keep []string) ([]string, error) {
+	kept := make(map[string]bool, len(keep))
+	for _, path := range keep {
+		if abs, err := filepath.Abs(path); err == nil {
+			kept[abs] = true
+		}
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed, dirs []string
+	err = filepath.WalkDir(absDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == absDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != absDir {
+				if _, err := os.Stat(filepath.Join(path, "meta.xml")); err == nil {
+					return filepath.SkipDir
+				}
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		if kept[path] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed = append(removed, path)
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// Remove the deepest directories first so parents become empty in turn
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, path := range dirs {
+		if entries, err := os.ReadDir(path); err == nil && len(entries) == 0 {
+			os.Remove(path)
+		}
+	}
+	return removed, nil
+}
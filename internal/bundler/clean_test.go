@@ -0,0 +1,38 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPruneDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(parts ...string) string {
+		path := filepath.Join(append([]string{dir}, parts...)...)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+		return path
+	}
+
+	keep := []string{write("meta.xml"), write("client.luac"), write("files", "logo.png")}
+	stale := []string{write("files", "old", "removed.png"), write("shared.luac")}
+	nested := write("[maps]", "map", "meta.xml")
+
+	removed, err := pruneDir(dir, keep)
+	if err != nil {
+		t.Fatalf("pruneDir failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, stale) {
+		t.Errorf("Expected %v to be removed, got %v", stale, removed)
+	}
+	for _, path := range append(keep, nested) {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "files", "old")); !os.IsNotExist(err) {
+		t.Error("Expected the emptied directory to be removed")
+	}
+}
@@ -0,0 +1,54 @@
+package bundler
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/dependency"
+)
+
+// checkDependencies fails when dependencies are configured without an output directory, or
+// when a dependency would be written over the output of a resource
+func (b Bundler) checkDependencies(metaPaths []string) error {
+	if len(b.options.Dependencies) == 0 {
+		return nil
+	}
+	if b.options.OutputDir == "" {
+		return fmt.Errorf("dependencies require an output directory (-o)")
+	}
+	for _, dep := range b.options.Dependencies {
+		for _, metaPath := range metaPaths {
+			if rel, err := filepath.Rel(b.inputRoot(), filepath.Dir(metaPath)); err == nil && filepath.ToSlash(rel) == dep.Name {
+				return fmt.Errorf("dependency %q conflicts with the resource at %s", dep.Name, filepath.Dir(metaPath))
+			}
+		}
+	}
+	return nil
+}
+
+// BuildDependency downloads the third-party resource dep, verifies its archive against the
+// pinned hash and builds it into the output directory like a resource of the input, so it is
+// rebuilt with the options of every release. Nested config files and splits of the project do
+// not apply to it.
+func (b Bundler) BuildDependency(dep config.Dependency) ResourceResult {
+	startTime := time.Now()
+	dir, err := dependency.Fetch(dep, b.options.DependencyCache)
+	if err != nil {
+		return ResourceResult{
+			MetaXMLPath: dep.URL,
+			Error:       fmt.Errorf("dependency %s: %v", dep.Name, err),
+			Duration:    time.Since(startTime),
+		}
+	}
+
+	// Built from the cache directory, which holds only this resource, into <output>/<name>
+	depBundler := b
+	depBundler.options.InputPath = filepath.Dir(dir)
+	depBundler.options.Subtrees = nil
+	depBundler.options.Splits = nil
+	result := depBundler.BuildResource(filepath.Join(dir, "meta.xml"))
+	result.Duration = time.Since(startTime)
+	return result
+}
@@ -0,0 +1,84 @@
+package bundler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// DiagnosticsHandler writes warnings and errors as newline-delimited JSON, one object per
+// record with the time, level, message and attributes (including those bound with
+// Logger.With, such as the resource). Each line is written as soon as it is logged, so
+// wrappers can react to a warning while the build is still running. Info and debug records,
+// and the final summary, are left to the console.
+type DiagnosticsHandler struct {
+	json slog.Handler
+}
+
+// NewDiagnosticsHandler creates a diagnostics handler writing to w
+func NewDiagnosticsHandler(w io.Writer) *DiagnosticsHandler {
+	return &DiagnosticsHandler{json: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelWarn})}
+}
+
+// Enabled reports whether records of the given level are written
+func (h *DiagnosticsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level < LevelSummary && h.json.Enabled(ctx, level)
+}
+
+// Handle writes a record as a JSON line
+func (h *DiagnosticsHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.json.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler adding attrs to every line
+func (h *DiagnosticsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &DiagnosticsHandler{json: h.json.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with name
+func (h *DiagnosticsHandler) WithGroup(name string) slog.Handler {
+	return &DiagnosticsHandler{json: h.json.WithGroup(name)}
+}
+
+// TeeHandler sends every record to several handlers, such as the console and diagnostics
+type TeeHandler []slog.Handler
+
+// Enabled reports whether one of the handlers writes records of the given level
+func (t TeeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to the handlers enabled for its level
+func (t TeeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a tee of the handlers with attrs added
+func (t TeeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	tee := make(TeeHandler, len(t))
+	for i, h := range t {
+		tee[i] = h.WithAttrs(attrs)
+	}
+	return tee
+}
+
+// WithGroup returns a tee of the handlers with the group added
+func (t TeeHandler) WithGroup(name string) slog.Handler {
+	tee := make(TeeHandler, len(t))
+	for i, h := range t {
+		tee[i] = h.WithGroup(name)
+	}
+	return tee
+}
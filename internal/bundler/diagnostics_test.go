@@ -0,0 +1,36 @@
+package bundler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	var console, diagnostics bytes.Buffer
+	logger := slog.New(TeeHandler{NewConsoleHandler(&console, nil), NewDiagnosticsHandler(&diagnostics)})
+
+	log := logger.With("resource", "race")
+	log.Info("Compiled resource")
+	log.Warn("Script is not referenced", "file", "unused.lua")
+	logger.Log(context.Background(), LevelSummary, "Build completed")
+
+	if !strings.Contains(console.String(), "Compiled resource") || !strings.Contains(console.String(), "Build completed") {
+		t.Errorf("Console is missing records:\n%s", console.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(diagnostics.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the warning in diagnostics, got:\n%s", diagnostics.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Diagnostics line is not JSON: %v", err)
+	}
+	if record["level"] != "WARN" || record["resource"] != "race" || record["file"] != "unused.lua" {
+		t.Errorf("Unexpected diagnostics record: %v", record)
+	}
+}
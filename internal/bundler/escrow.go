@@ -0,0 +1,130 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/escrow"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// writeEscrow stores the resource sources in an encrypted escrow archive inside its output directory
+func (b Bundler) writeEscrow(res *resource.Resource, outputDir string, options compiler.CompilationOptions, mergeMode bool) error {
+	files := []string{filepath.Base(res.MetaXMLPath)}
+	for _, fileRef := range res.GetLuaFiles() {
+		files = append(files, fileRef.RelativePath)
+	}
+	if _, err := os.Stat(filepath.Join(res.BaseDir, config.ResourceFileName)); err == nil {
+		files = append(files, config.ResourceFileName)
+	}
+
+	manifest := escrow.Manifest{
+		Resource:         res.Name,
+		CreatedAt:        time.Now().UTC(),
+		ObfuscationLevel: int(options.ObfuscationLevel),
+		StripDebug:       options.StripDebug,
+		MergeMode:        mergeMode,
+		BundleName:       res.BundleName,
+	}
+
+	if err := escrow.Write(filepath.Join(outputDir, escrow.FileName), b.options.EscrowKey, res.BaseDir, files, manifest); err != nil {
+		return fmt.Errorf("failed to write escrow archive: %v", err)
+	}
+
+	slog.With("resource", res.Name).Info("Wrote source escrow", "success", true, "files", len(files))
+	return nil
+}
+
+// FindEscrowResources returns the directories below rootDir that contain an escrow archive
+func FindEscrowResources(rootDir string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			slog.Warn("Cannot access path", "path", path, "error", err)
+			return nil
+		}
+		if !info.IsDir() && info.Name() == escrow.FileName {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return dirs, fmt.Errorf("error walking directory tree: %v", err)
+	}
+	return dirs, nil
+}
+
+// RebuildFromEscrow recompiles a deployed resource in place from its escrow archive, using the
+// bundler's compilation options. Only meta.xml and scripts are replaced; assets stay untouched.
+func (b Bundler) RebuildFromEscrow(resourceDir string) error {
+	if len(b.options.EscrowKey) == 0 {
+		return fmt.Errorf("no escrow key configured")
+	}
+
+	absResourceDir, err := filepath.Abs(resourceDir)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute resource path: %v", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "mta-bundler-escrow-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, filepath.Base(absResourceDir))
+	manifest, err := escrow.Extract(filepath.Join(absResourceDir, escrow.FileName), b.options.EscrowKey, sourceDir)
+	if err != nil {
+		return err
+	}
+
+	metaPath := filepath.Join(sourceDir, "meta.xml")
+	if _, err := os.Stat(metaPath); err != nil {
+		return fmt.Errorf("escrow archive does not contain a meta.xml")
+	}
+
+	res, err := resource.NewResource(metaPath)
+	if err != nil {
+		return err
+	}
+
+	// Assets are not escrowed, they are already present in the deployed resource
+	var scripts []resource.FileReference
+	for _, fileRef := range res.Files {
+		if fileRef.ReferenceType == resource.ReferenceTypeScript {
+			scripts = append(scripts, fileRef)
+		}
+	}
+	res.Files = scripts
+
+	options, mergeMode, err := b.resourceOptions(res)
+	if err != nil {
+		return err
+	}
+	if err := b.applyVerbatim(res); err != nil {
+		return err
+	}
+	// The rebuilt bundles replace the deployed ones
+	res.BundleName = manifest.BundleName
+
+	if int(options.ObfuscationLevel) < manifest.ObfuscationLevel {
+		slog.Warn("Lowering obfuscation level", "code", resource.AttentionObfuscationLowered, "resource", res.Name, "from", manifest.ObfuscationLevel, "to", int(options.ObfuscationLevel))
+	}
+	if manifest.MergeMode != mergeMode {
+		slog.Warn("Merge mode changed, previously compiled scripts are left in place", "resource", res.Name, "merge", mergeMode)
+	}
+
+	if _, err := res.Compile(b.compiler, sourceDir, absResourceDir, options, mergeMode); err != nil {
+		return fmt.Errorf("error compiling resource %s: %v", res.Name, err)
+	}
+	if _, err := b.applyLazyFiles(res, absResourceDir, options); err != nil {
+		return err
+	}
+
+	return b.writeEscrow(res, absResourceDir, options, mergeMode)
+}
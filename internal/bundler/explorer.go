@@ -0,0 +1,296 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultIgnore are the directories never searched for resources: version control metadata and
+// package manager folders, which can hold thousands of files but no resources
+var DefaultIgnore = []string{".git", ".svn", ".hg", "node_modules"}
+
+// WalkOptions limits the search for meta.xml files
+type WalkOptions struct {
+	Ignore         []string // Directory name or path globs not searched, in addition to DefaultIgnore
+	Root           string   // Directory the Ignore paths are relative to, the searched directory when empty
+	MaxDepth       int      // Levels of directories below the root that are searched, 0 for no limit
+	Skip           []string // Directories not searched, such as an output directory inside the input
+	SkipCategories []string // Category folders not searched, by their name without brackets (globs, any case)
+	FollowSymlinks bool     // Enter symlinked directories, each directory at most once
+}
+
+// IsCategory reports whether a directory name is an MTA category folder, such as [gamemodes].
+// Category folders group resources and are not resources themselves.
+func IsCategory(name string) bool {
+	return len(name) > 2 && strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]")
+}
+
+// CategoryRoot returns the directory above dir and the category folders dir is part of, so a
+// category given as input keeps its folder in the output
+func CategoryRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for IsCategory(filepath.Base(dir)) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// FindMTAResourceMetas recursively searches for meta.xml files in MTA resources
+// and returns a slice of their full paths
+func FindMTAResourceMetas(rootDir string, options WalkOptions) ([]string, error) {
+	// Check if the root directory exists
+	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("directory does not exist: %s", rootDir)
+	}
+	filter, err := newDirFilter(rootDir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	s := search{filter: filter, follow: options.FollowSymlinks, visited: make(map[int64][]os.FileInfo)}
+	if err := s.walk(filter.walkRoot, filter.walkRoot); err != nil {
+		return s.metaPaths, fmt.Errorf("error walking directory tree: %v", err)
+	}
+	return s.metaPaths, nil
+}
+
+// search collects the meta.xml files of a directory tree
+type search struct {
+	filter    dirFilter
+	follow    bool                    // Enter symlinked directories
+	visited   map[int64][]os.FileInfo // Directories entered when following symlinks, by modification time
+	metaPaths []string
+}
+
+// walk searches the directory real, reporting the paths below it as paths below dir, the path
+// it was reached through. dir and real differ for the target of a followed symlink.
+func (s *search) walk(dir, real string) error {
+	return filepath.Walk(real, func(walked string, info os.FileInfo, err error) error {
+		path := dir
+		if rel, relErr := filepath.Rel(real, walked); relErr == nil && rel != "." {
+			path = filepath.Join(dir, rel)
+		}
+		if err != nil {
+			// Log the error but continue walking
+			slog.Warn("Cannot access path", "path", path, "error", err)
+			return nil
+		}
+
+		if info.IsDir() {
+			if reason := s.filter.reason(path); reason != "" {
+				slog.Debug("Not searching directory", "dir", path, "reason", reason)
+				return filepath.SkipDir
+			}
+			if s.follow && s.seen(info) {
+				slog.Debug("Not searching directory", "dir", path, "reason", "already searched")
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if s.follow && info.Mode()&os.ModeSymlink != 0 {
+			return s.followLink(path, walked)
+		}
+
+		// Check if it's a meta.xml file
+		if strings.ToLower(info.Name()) == "meta.xml" {
+			s.metaPaths = append(s.metaPaths, path)
+		}
+
+		return nil
+	})
+}
+
+// followLink searches the directory a symlink points to. Links to files are handled like the
+// file itself.
+func (s *search) followLink(path, link string) error {
+	target, err := os.Stat(link)
+	if err != nil {
+		slog.Warn("Cannot follow symlink", "path", path, "error", err)
+		return nil
+	}
+	if !target.IsDir() {
+		if strings.ToLower(filepath.Base(path)) == "meta.xml" {
+			s.metaPaths = append(s.metaPaths, path)
+		}
+		return nil
+	}
+	real, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		slog.Warn("Cannot follow symlink", "path", path, "error", err)
+		return nil
+	}
+	slog.Debug("Following symlink", "dir", path, "target", real)
+	return s.walk(path, real)
+}
+
+// seen reports whether the directory was already entered, and records it otherwise. Directories
+// are compared by identity (device and inode), so a symlink back to a parent directory or to an
+// already searched tree is not entered again.
+func (s *search) seen(info os.FileInfo) bool {
+	key := info.ModTime().UnixNano()
+	for _, visited := range s.visited[key] {
+		if os.SameFile(visited, info) {
+			return true
+		}
+	}
+	s.visited[key] = append(s.visited[key], info)
+	return false
+}
+
+type dirFilter struct {
+	walkRoot   string   // Absolute directory searched
+	ignoreRoot string   // Absolute directory the ignore paths are relative to
+	ignore     []string // DefaultIgnore and the configured ignore globs
+	skip       []string // Absolute directories not searched
+	maxDepth   int      // Levels of directories searched, 0 for no limit
+	categories []string // Lower case globs of the category names not searched
+}
+
+// newDirFilter resolves the walk options for a search of rootDir
+func newDirFilter(rootDir string, options WalkOptions) (dirFilter, error) {
+	f := dirFilter{ignore: append(append([]string{}, DefaultIgnore...), options.Ignore...), maxDepth: options.MaxDepth}
+	var err error
+	if f.walkRoot, err = filepath.Abs(rootDir); err != nil {
+		return dirFilter{}, fmt.Errorf("cannot get absolute path: %v", err)
+	}
+	f.ignoreRoot = f.walkRoot
+	if options.Root != "" {
+		if f.ignoreRoot, err = filepath.Abs(options.Root); err != nil {
+			return dirFilter{}, fmt.Errorf("cannot get absolute path: %v", err)
+		}
+	}
+	for _, category := range options.SkipCategories {
+		f.categories = append(f.categories, strings.ToLower(strings.Trim(category, "[]")))
+	}
+	for _, dir := range options.Skip {
+		if abs, err := filepath.Abs(dir); err == nil {
+			f.skip = append(f.skip, abs)
+		}
+	}
+	return f, nil
+}
+
+// reason returns why the absolute directory dir is not searched, or "" when it is
+func (f dirFilter) reason(dir string) string {
+	rel, err := filepath.Rel(f.walkRoot, dir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	if f.maxDepth > 0 && strings.Count(filepath.ToSlash(rel), "/")+1 > f.maxDepth {
+		return "max depth"
+	}
+	for _, skip := range f.skip {
+		if dir == skip {
+			return "output directory"
+		}
+	}
+	if name := filepath.Base(dir); IsCategory(name) {
+		category := strings.ToLower(name[1 : len(name)-1])
+		for _, pattern := range f.categories {
+			if matched, _ := path.Match(pattern, category); matched || pattern == category {
+				return "skipped category"
+			}
+		}
+	}
+
+	if ignoreRel, err := filepath.Rel(f.ignoreRoot, dir); err == nil && filepath.IsLocal(ignoreRel) {
+		rel = ignoreRel
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range f.ignore {
+		pattern = filepath.ToSlash(pattern)
+		for _, candidate := range []string{path.Base(rel), rel} {
+			// Literal comparison first, MTA category folders like [gamemodes] are glob character classes
+			if pattern == candidate {
+				return "ignored"
+			}
+			if matched, _ := path.Match(pattern, candidate); matched {
+				return "ignored"
+			}
+		}
+	}
+	return ""
+}
+
+// ExcludeResourceMetas removes the meta.xml paths whose resource matches any of the exclude
+// patterns. Patterns are globs matched against the resource name (its directory name) and
+// against its slash-separated directory path relative to rootDir, including parent directories,
+// so "[disabled]" skips every resource below a [disabled] folder.
+func ExcludeResourceMetas(rootDir string, metaPaths []string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return metaPaths
+	}
+
+	if absRoot, err := filepath.Abs(rootDir); err == nil {
+		rootDir = absRoot
+	}
+
+	var kept []string
+	for _, metaPath := range metaPaths {
+		if matchesResource(rootDir, metaPath, patterns) {
+			slog.Info("Skipping excluded resource", "meta", metaPath)
+			continue
+		}
+		kept = append(kept, metaPath)
+	}
+	return kept
+}
+
+// SelectResourceMetas returns the meta.xml paths whose resource matches one of the patterns.
+// Patterns are matched like in ExcludeResourceMetas.
+func SelectResourceMetas(rootDir string, metaPaths []string, patterns []string) []string {
+	if absRoot, err := filepath.Abs(rootDir); err == nil {
+		rootDir = absRoot
+	}
+
+	var selected []string
+	for _, metaPath := range metaPaths {
+		if matchesResource(rootDir, metaPath, patterns) {
+			selected = append(selected, metaPath)
+		}
+	}
+	return selected
+}
+
+// matchesResource reports whether the resource owning metaPath matches any pattern
+func matchesResource(rootDir, metaPath string, patterns []string) bool {
+	resourceDir := filepath.Dir(metaPath)
+	return matchesPattern(filepath.Base(resourceDir), patterns) || matchesDirPath(rootDir, resourceDir, patterns)
+}
+
+// matchesDirPath reports whether the slash-separated path of dir relative to rootDir, or of one
+// of its parent directories, matches any pattern, so every resource below dir matches it
+func matchesDirPath(rootDir, dir string, patterns []string) bool {
+	rel, err := filepath.Rel(rootDir, dir)
+	if err != nil || rel == "." {
+		return false
+	}
+	// Parent directories, so a folder pattern excludes everything below it
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(parts); i++ {
+		if matchesPattern(strings.Join(parts[:i], "/"), patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether candidate matches any of the glob patterns
+func matchesPattern(candidate string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		// Literal comparison first, MTA category folders like [gamemodes] are glob character classes
+		if pattern == candidate {
+			return true
+		}
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+	}
+	return false
+}
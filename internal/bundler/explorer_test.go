@@ -0,0 +1,174 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+func TestExcludeResourceMetas(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "srv", "resources")
+	meta := func(parts ...string) string {
+		return filepath.Join(append(append([]string{root}, parts...), "meta.xml")...)
+	}
+
+	metaPaths := []string{
+		meta("[gamemodes]", "race"),
+		meta("[disabled]", "old-race"),
+		meta("test-map"),
+		meta("admin"),
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{"No patterns", nil, metaPaths},
+		{"Name glob", []string{"test-*"}, []string{metaPaths[0], metaPaths[1], metaPaths[3]}},
+		{"Category folder", []string{"[disabled]"}, []string{metaPaths[0], metaPaths[2], metaPaths[3]}},
+		{"Relative path", []string{"[[]gamemodes]/race"}, []string{metaPaths[1], metaPaths[2], metaPaths[3]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExcludeResourceMetas(root, metaPaths, tt.patterns)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSelectResourceMetas(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "srv", "resources")
+	metaPaths := []string{
+		filepath.Join(root, "[gamemodes]", "race", "meta.xml"),
+		filepath.Join(root, "freeroam", "meta.xml"),
+		filepath.Join(root, "admin", "meta.xml"),
+	}
+
+	got := SelectResourceMetas(root, metaPaths, []string{"race", "free*"})
+	if expected := metaPaths[:2]; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := SelectResourceMetas(root, metaPaths, []string{"missing"}); len(got) != 0 {
+		t.Errorf("Expected no resources, got %v", got)
+	}
+}
+
+func TestFindResourcesInputs(t *testing.T) {
+	root := t.TempDir()
+	meta := func(parts ...string) string {
+		path := filepath.Join(append(append([]string{root}, parts...), "meta.xml")...)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("<meta></meta>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	race := meta("[gamemodes]", "race")
+	freeroam := meta("[gamemodes]", "freeroam")
+	testMap := meta("test-map")
+	admin := meta("admin")
+	meta("[gamemodes]", "node_modules", "lib")
+	meta("[gamemodes]", "race", "backup", "old")
+	meta("compiled", "admin")
+	disabledRace := meta("[disabled]", "race")
+
+	tests := []struct {
+		name     string
+		options  Options
+		expected []string
+	}{
+		{"Input directory", Options{InputPath: root, OutputDir: filepath.Join(root, "compiled"), Ignore: []string{"backup"}},
+			[]string{race, admin, freeroam, testMap, disabledRace}},
+		{"Skipped categories", Options{InputPath: root, OutputDir: filepath.Join(root, "compiled"), SkipCategories: []string{"DISABLED", "game*"}},
+			[]string{admin, testMap}},
+		{"Max depth", Options{InputPath: root, OutputDir: filepath.Join(root, "compiled"), MaxDepth: 1},
+			[]string{admin, testMap}},
+		{"Several inputs", Options{InputPath: root, Ignore: []string{"[[]gamemodes]/race/*"}, Exclude: []string{"test-*"},
+			Inputs: []string{filepath.Join(root, "[gamemodes]"), admin, race, testMap}}, []string{race, freeroam, admin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBundler(compiler.CLICompiler{}, tt.options).FindResources()
+			if err != nil {
+				t.Fatalf("FindResources failed: %v", err)
+			}
+			sort.Strings(got)
+			sort.Strings(tt.expected)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	b := NewBundler(compiler.CLICompiler{}, Options{InputPath: root, Inputs: []string{admin}, Only: []string{"missing"}})
+	if _, err := b.FindResources(); err == nil {
+		t.Error("Expected an error when no input resource matches -only")
+	}
+}
+
+func TestCategoryRoot(t *testing.T) {
+	tests := map[string]string{
+		"resources":                    "resources",
+		"resources/[gamemodes]":        "resources",
+		"resources/[gamemodes]/[race]": "resources",
+		"resources/[gamemodes]/race":   "resources/[gamemodes]/race",
+		"resources/[]":                 "resources/[]",
+		"[gamemodes]":                  ".",
+	}
+	for dir, expected := range tests {
+		if got := CategoryRoot(filepath.FromSlash(dir)); got != filepath.FromSlash(expected) {
+			t.Errorf("CategoryRoot(%q) = %q, expected %q", dir, got, expected)
+		}
+	}
+}
+
+func TestFindMTAResourceMetasSymlinks(t *testing.T) {
+	root, shared := t.TempDir(), t.TempDir()
+	for _, dir := range []string{filepath.Join(root, "admin"), filepath.Join(shared, "scoreboard")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "meta.xml"), []byte("<meta></meta>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "[shared]"):                shared,
+		filepath.Join(root, "[mirror]"):                shared,
+		filepath.Join(shared, "scoreboard", "loop"):    root,
+		filepath.Join(root, "admin", "missing-target"): filepath.Join(root, "missing"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+	}
+
+	got, err := FindMTAResourceMetas(root, WalkOptions{})
+	if err != nil {
+		t.Fatalf("FindMTAResourceMetas failed: %v", err)
+	}
+	if expected := []string{filepath.Join(root, "admin", "meta.xml")}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v without following symlinks, got %v", expected, got)
+	}
+
+	// The loop back to root and the second link to shared are not entered again
+	got, err = FindMTAResourceMetas(root, WalkOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("FindMTAResourceMetas failed: %v", err)
+	}
+	expected := []string{filepath.Join(root, "[mirror]", "scoreboard", "meta.xml"), filepath.Join(root, "admin", "meta.xml")}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
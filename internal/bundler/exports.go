@@ -0,0 +1,26 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// checkExports verifies in merge mode that the exported functions of res are defined by its
+// merged scripts. It returns the exports defined only by scripts left out of the merge as cases
+// needing attention, and fails when an exported function is not defined at all, as the bundles
+// would load without it and calls from other resources would fail at runtime.
+func (b Bundler) checkExports(res *resource.Resource, mergeMode bool) ([]resource.Attention, error) {
+	if !mergeMode {
+		return nil, nil
+	}
+	problems, cases := res.CheckExports()
+	for _, problem := range problems {
+		slog.Error("Export not defined", "resource", res.Name, "detail", problem.Message)
+	}
+	if len(problems) > 0 {
+		return cases, fmt.Errorf("%d exported functions of %s are not defined by its scripts", len(problems), res.Name)
+	}
+	return cases, nil
+}
@@ -0,0 +1,218 @@
+package bundler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FrontendProtocolVersion is the version of the JSON frontend protocol, increased when events
+// change incompatibly
+const FrontendProtocolVersion = 1
+
+// Events of the JSON frontend protocol
+const (
+	EventHello         = "hello"          // First event, with the protocol and bundler versions
+	EventLog           = "log"            // A log record: message, level and attributes
+	EventProgressStart = "progress_start" // The build found total resources
+	EventProgress      = "progress"       // done resources have been processed
+	EventProgressEnd   = "progress_end"   // The build is over
+	EventDownloadStart = "download_start" // A luac_mta download started, total is -1 when unknown
+	EventDownload      = "download"       // done bytes have been downloaded
+	EventDownloadEnd   = "download_end"   // The download is over
+	EventSummary       = "summary"        // The final summary of a build or check
+	EventFatal         = "fatal"          // Last event when the bundler exits with an error
+)
+
+// FrontendHandler is a slog.Handler writing the build as newline-delimited JSON events, for
+// graphical frontends wrapping the bundler. Every event has an "event" type and a "time".
+// Records about a resource carry a "resource_id" and records about one of its files also a
+// "file_id", so frontends can attach them to the right row. It also reports the build and
+// download progress as events.
+type FrontendHandler struct {
+	w       io.Writer
+	level   slog.Leveler
+	mu      *sync.Mutex
+	context []slog.Attr
+	group   string
+}
+
+// NewFrontendHandler creates a frontend handler writing to w. A nil opts logs at info level.
+func NewFrontendHandler(w io.Writer, opts *slog.HandlerOptions) *FrontendHandler {
+	h := &FrontendHandler{w: w, level: slog.LevelInfo, mu: &sync.Mutex{}}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// Enabled reports whether records of the given level are written
+func (h *FrontendHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes a record as a log event, or as the summary event for the final summary
+func (h *FrontendHandler) Handle(_ context.Context, r slog.Record) error {
+	event := map[string]any{"event": EventLog, "level": levelName(r.Level), "message": r.Message}
+	if r.Level >= LevelSummary {
+		event = map[string]any{"event": EventSummary, "message": r.Message}
+	}
+	if !r.Time.IsZero() {
+		event["time"] = r.Time
+	}
+
+	attrs := make(map[string]any)
+	add := func(a slog.Attr) {
+		key := a.Key
+		if h.group != "" {
+			key = h.group + "." + key
+		}
+		attrs[key] = frontendValue(a.Value)
+	}
+	for _, a := range h.context {
+		add(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		add(a)
+		return true
+	})
+
+	if resourceID := frontendResourceID(attrs); resourceID != "" {
+		event["resource_id"] = resourceID
+		if file, ok := attrs["file"].(string); ok && file != "" {
+			event["file_id"] = resourceID + ":" + filepath.ToSlash(file)
+		} else if bundle, ok := attrs["bundle"].(string); ok && bundle != "" {
+			event["file_id"] = resourceID + ":" + bundle
+		}
+	}
+	if len(attrs) > 0 {
+		event["attrs"] = attrs
+	}
+	return h.write(event)
+}
+
+// WithAttrs returns a handler adding attrs to every event
+func (h *FrontendHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.context = append(append([]slog.Attr{}, h.context...), attrs...)
+	return &clone
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with name
+func (h *FrontendHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	if clone.group != "" {
+		name = clone.group + "." + name
+	}
+	clone.group = name
+	return &clone
+}
+
+// Hello writes the first event of the stream
+func (h *FrontendHandler) Hello(version string) {
+	h.write(map[string]any{"event": EventHello, "time": time.Now(), "protocol": FrontendProtocolVersion, "version": version})
+}
+
+// Fatal writes the error the bundler exits with
+func (h *FrontendHandler) Fatal(err error) {
+	h.write(map[string]any{"event": EventFatal, "time": time.Now(), "message": err.Error()})
+}
+
+// StartProgress reports that the build found total resources
+func (h *FrontendHandler) StartProgress(total int) {
+	h.write(map[string]any{"event": EventProgressStart, "time": time.Now(), "total": total})
+}
+
+// AdvanceProgress reports the number of processed resources
+func (h *FrontendHandler) AdvanceProgress(done int) {
+	h.write(map[string]any{"event": EventProgress, "time": time.Now(), "done": done})
+}
+
+// StopProgress reports that the build is over
+func (h *FrontendHandler) StopProgress() {
+	h.write(map[string]any{"event": EventProgressEnd, "time": time.Now()})
+}
+
+// StartDownload reports that a download of total bytes started
+func (h *FrontendHandler) StartDownload(total int64) {
+	h.write(map[string]any{"event": EventDownloadStart, "time": time.Now(), "total": total})
+}
+
+// AdvanceDownload reports the number of downloaded bytes
+func (h *FrontendHandler) AdvanceDownload(done int64) {
+	h.write(map[string]any{"event": EventDownload, "time": time.Now(), "done": done})
+}
+
+// StopDownload reports that the download is over
+func (h *FrontendHandler) StopDownload() {
+	h.write(map[string]any{"event": EventDownloadEnd, "time": time.Now()})
+}
+
+// write encodes event as a single line
+func (h *FrontendHandler) write(event map[string]any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.w.Write(append(data, '\n'))
+	return err
+}
+
+// frontendResourceID returns the ID of the resource a record is about: its name, or the name
+// of the directory of its meta.xml before the resource is parsed. Resource names are unique
+// on an MTA server, so they identify a resource across the events of a build.
+func frontendResourceID(attrs map[string]any) string {
+	if name, ok := attrs["resource"].(string); ok && name != "" {
+		return name
+	}
+	if name, ok := attrs["pack"].(string); ok && name != "" {
+		return name
+	}
+	if meta, ok := attrs["meta"].(string); ok && meta != "" {
+		return filepath.Base(filepath.Dir(meta))
+	}
+	return ""
+}
+
+// frontendValue returns the JSON value of an attribute. Durations are written in milliseconds.
+func frontendValue(v slog.Value) any {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindDuration:
+		return float64(v.Duration()) / float64(time.Millisecond)
+	case slog.KindGroup:
+		group := make(map[string]any)
+		for _, a := range v.Group() {
+			group[a.Key] = frontendValue(a.Value)
+		}
+		return group
+	case slog.KindAny:
+		switch value := v.Any().(type) {
+		case error:
+			return value.Error()
+		case json.Marshaler:
+			return value
+		}
+		return v.String()
+	}
+	return v.Any()
+}
+
+// levelName returns the lowercase name of a log level
+func levelName(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "error"
+	case level >= slog.LevelWarn:
+		return "warn"
+	case level >= slog.LevelInfo:
+		return "info"
+	}
+	return "debug"
+}
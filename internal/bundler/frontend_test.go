@@ -0,0 +1,58 @@
+package bundler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrontendHandler(t *testing.T) {
+	var out bytes.Buffer
+	frontend := NewFrontendHandler(&out, nil)
+	logger := slog.New(frontend)
+
+	frontend.Hello("1.0.0")
+	frontend.StartProgress(1)
+	logger.Debug("Skipped")
+	logger.Info("Processing resource", "meta", "/res/[gm]/race/meta.xml")
+	logger.With("resource", "race").Warn("Script is not referenced", "file", "utils/unused.lua")
+	frontend.AdvanceProgress(1)
+	frontend.StopProgress()
+	logger.Log(context.Background(), LevelSummary, "Build completed", "duration", 1500*time.Microsecond)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var events []map[string]any
+	for _, line := range lines {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Event is not JSON: %v\n%s", err, line)
+		}
+		events = append(events, event)
+	}
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event["event"].(string))
+	}
+	want := "hello progress_start log log progress progress_end summary"
+	if strings.Join(types, " ") != want {
+		t.Fatalf("Expected events %q, got %q", want, strings.Join(types, " "))
+	}
+
+	if events[0]["protocol"] != float64(FrontendProtocolVersion) || events[0]["version"] != "1.0.0" {
+		t.Errorf("Unexpected hello event: %v", events[0])
+	}
+	if events[2]["resource_id"] != "race" {
+		t.Errorf("Expected the meta.xml directory as resource ID: %v", events[2])
+	}
+	if events[3]["level"] != "warn" || events[3]["resource_id"] != "race" || events[3]["file_id"] != "race:utils/unused.lua" {
+		t.Errorf("Unexpected warning event: %v", events[3])
+	}
+	if attrs := events[6]["attrs"].(map[string]any); attrs["duration"] != 1.5 {
+		t.Errorf("Expected the duration in milliseconds: %v", events[6])
+	}
+}
@@ -0,0 +1,79 @@
+package bundler
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/graph"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// IncludeGraph returns the include graph of the resources of metaPaths, named after their
+// directories. A meta.xml that cannot be read adds its resource without includes, building it
+// reports the error.
+func IncludeGraph(metaPaths []string) *graph.Graph {
+	g := graph.New()
+	for _, metaPath := range metaPaths {
+		var includes []string
+		if data, err := os.ReadFile(metaPath); err == nil {
+			var meta resource.Meta
+			if xml.Unmarshal(data, &meta) == nil {
+				for _, include := range meta.Include {
+					if name := strings.TrimSpace(include.Resource); name != "" {
+						includes = append(includes, name)
+					}
+				}
+			}
+		}
+		g.Add(resourceName(metaPath), includes)
+	}
+	return g
+}
+
+// orderByIncludes sorts metaPaths so that every resource is built after the resources it
+// includes, otherwise keeping their order. Resources including each other are an error.
+func orderByIncludes(metaPaths []string, includes *graph.Graph) ([]string, error) {
+	order, err := includes.Order()
+	if err != nil {
+		return nil, err
+	}
+	position := make(map[string]int, len(order))
+	for i, name := range order {
+		position[name] = i
+	}
+
+	byName := make(map[string][]string)
+	for _, metaPath := range metaPaths {
+		name := resourceName(metaPath)
+		byName[name] = append(byName[name], metaPath)
+		for _, missing := range includes.Missing(name) {
+			slog.Debug("Included resource is not part of the build", "resource", name, "include", missing)
+		}
+	}
+	ordered := make([]string, 0, len(metaPaths))
+	for _, name := range order {
+		ordered = append(ordered, byName[name]...)
+	}
+	return ordered, nil
+}
+
+// reportAffected logs the resources that include a failed resource, directly or through other
+// resources, and records them in its result
+func reportAffected(result *ResourceResult, name string, includes *graph.Graph) {
+	if result.Error == nil {
+		return
+	}
+	result.Affected = includes.Dependents(name)
+	if len(result.Affected) > 0 {
+		slog.Warn("Resources including the failed resource are affected", "resource", name,
+			"dependents", strings.Join(result.Affected, ","))
+	}
+}
+
+// resourceName returns the name of the resource of a meta.xml path
+func resourceName(metaPath string) string {
+	return filepath.Base(filepath.Dir(metaPath))
+}
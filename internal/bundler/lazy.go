@@ -0,0 +1,156 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// LazyLoaderName is the compiled client script added to resources with lazy files
+const LazyLoaderName = "mta_bundler_lazy.luac"
+
+// lazyLoaderTemplate is the Lua source of the lazy file loader. Scripts of the resource
+// call requestLazyFile(path, callback) before using a lazy file.
+var lazyLoaderTemplate = template.Must(template.New("loader").Parse(`-- Generated by mta-bundler: files downloaded on demand instead of when joining
+local lazyFiles = {
+{{- range .}}
+	[{{printf "%q" .}}] = true,
+{{- end}}
+}
+local ready = {}
+local callbacks = {}
+
+local function finish(path, success)
+	ready[path] = success or nil
+	local waiting = callbacks[path]
+	callbacks[path] = nil
+	for _, callback in ipairs(waiting or {}) do
+		callback(path, success)
+	end
+end
+
+-- isLazyFileReady returns whether a file can be used: it is not lazy or was downloaded
+function isLazyFileReady(path)
+	return not lazyFiles[path] or ready[path] == true
+end
+
+-- requestLazyFile downloads a lazy file if needed and calls callback(path, success) once it
+-- is available. Files that are not lazy are reported as available immediately.
+function requestLazyFile(path, callback)
+	if isLazyFileReady(path) then
+		if callback then
+			callback(path, true)
+		end
+		return true
+	end
+
+	if callback then
+		callbacks[path] = callbacks[path] or {}
+		table.insert(callbacks[path], callback)
+	end
+	if ready[path] == nil then
+		ready[path] = false
+		if not downloadFile(path) then
+			finish(path, false)
+			return false
+		end
+	end
+	return true
+end
+
+addEventHandler("onClientFileDownloadComplete", resourceRoot, function(path, success)
+	if lazyFiles[path] then
+		finish(path, success)
+	end
+end)
+`))
+
+// applyLazyFiles adds the lazy file loader to the built resource when its override file
+// lists lazy files. It returns the path of the loader, or an empty string when none was written.
+func (b Bundler) applyLazyFiles(res *resource.Resource, outputDir string, options compiler.CompilationOptions) (string, error) {
+	overrides, ok, err := config.LoadResourceOverrides(res.BaseDir)
+	if err != nil || !ok || len(overrides.Lazy) == 0 {
+		return "", err
+	}
+	// Resources without scripts are not written to the output
+	if _, err := os.Stat(filepath.Join(outputDir, "meta.xml")); os.IsNotExist(err) {
+		return "", nil
+	}
+	return b.writeLazyLoader(res, outputDir, overrides.Lazy, options)
+}
+
+// writeLazyLoader marks the client files matching patterns as download="false" in the output
+// meta.xml and adds a compiled loader script that downloads them on demand
+func (b Bundler) writeLazyLoader(res *resource.Resource, outputDir string, patterns []string, options compiler.CompilationOptions) (string, error) {
+	log := slog.With("resource", res.Name)
+
+	lazy := make(map[string]bool)
+	for _, pattern := range patterns {
+		matched := false
+		for _, file := range res.Meta.Files {
+			src := filepath.ToSlash(file.Src)
+			if matchSrc(pattern, src) {
+				lazy[src] = true
+				matched = true
+			}
+		}
+		if !matched {
+			log.Warn("Lazy pattern matches no client file", "pattern", pattern)
+		}
+	}
+	if len(lazy) == 0 {
+		return "", nil
+	}
+
+	files := make([]string, 0, len(lazy))
+	for src := range lazy {
+		files = append(files, src)
+	}
+	sort.Strings(files)
+
+	sourceFile, err := os.CreateTemp("", "mta-bundler-lazy-*.lua")
+	if err != nil {
+		return "", fmt.Errorf("failed to create lazy loader: %v", err)
+	}
+	defer os.Remove(sourceFile.Name())
+
+	if err := lazyLoaderTemplate.Execute(sourceFile, files); err != nil {
+		sourceFile.Close()
+		return "", fmt.Errorf("failed to generate lazy loader: %v", err)
+	}
+	if err := sourceFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write lazy loader: %v", err)
+	}
+
+	loaderPath := filepath.Join(outputDir, LazyLoaderName)
+	if _, err := b.compiler.CompileFile(sourceFile.Name(), loaderPath, options); err != nil {
+		return "", fmt.Errorf("failed to compile lazy loader: %v", err)
+	}
+	if err := resource.SetLazyFiles(filepath.Join(outputDir, "meta.xml"), lazy, LazyLoaderName); err != nil {
+		return "", err
+	}
+
+	log.Info("Added lazy file loader", "success", true, "files", len(files))
+	return loaderPath, nil
+}
+
+// matchSrc reports whether a slash-separated src matches a lazy or verbatim pattern, either
+// directly or through one of its parent directories
+func matchSrc(pattern, src string) bool {
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+	for p := src; p != "." && p != "/"; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package bundler
+
+import "testing"
+
+func TestMatchSrc(t *testing.T) {
+	tests := []struct {
+		pattern, src string
+		want         bool
+	}{
+		{"images/*.png", "images/map.png", true},
+		{"images", "images/hud/map.png", true},
+		{"images/", "images/map.png", true},
+		{"*.mp3", "sounds/intro.mp3", false},
+		{"sounds/*.mp3", "sounds/intro.mp3", true},
+		{"images/*.png", "logo.png", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchSrc(tt.pattern, tt.src); got != tt.want {
+			t.Errorf("matchSrc(%q, %q) = %t, want %t", tt.pattern, tt.src, got, tt.want)
+		}
+	}
+}
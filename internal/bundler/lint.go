@@ -0,0 +1,31 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/davidbozo/mta-bundler/internal/lint"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// lintResource applies the lint rules to the meta.xml of res. Warnings are logged, and findings
+// of rules with error severity are logged and fail the resource.
+func (b Bundler) lintResource(res *resource.Resource) error {
+	failed := 0
+	for _, finding := range lint.Lint(res, b.options.Lint) {
+		attrs := []any{"resource", res.Name, "rule", finding.Rule}
+		if finding.Src != "" {
+			attrs = append(attrs, "file", finding.Src)
+		}
+		if finding.Severity == lint.SeverityError {
+			slog.Error(finding.Message, attrs...)
+			failed++
+			continue
+		}
+		slog.Warn(finding.Message, attrs...)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d lint errors in %s", failed, res.Name)
+	}
+	return nil
+}
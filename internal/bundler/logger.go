@@ -0,0 +1,244 @@
+package bundler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// LevelSummary is the level of the final build summary. It is above LevelError so the
+// summary is shown even when only errors are logged.
+const LevelSummary = slog.LevelError + 4
+
+// Attribute keys with a special meaning for ConsoleHandler
+const (
+	keySuccess = "success"   // Outcome of an operation, rendered as ✓ or ✗
+	keyError   = "error"     // Error of a failed operation, rendered after the message
+	keyFailed  = "failed"    // Number of failures, highlighted when not zero
+	keyReduced = "reduction" // Size reduction, highlighted as a gain
+)
+
+// ConsoleHandler is a slog.Handler producing the human-readable build output.
+//
+// Attributes bound with Logger.With (typically the resource) are context for a
+// block of lines: they are not repeated, the lines are indented instead. When
+// info lines are disabled the block header is missing, so the context is
+// written inline instead. A
+// "success" attribute renders as ✓ or ✗, an "error" attribute is appended after
+// the message, and keys ending in "_size" are formatted as byte sizes. How
+// outcomes, warnings, failure counts and size reductions are highlighted is
+// decided by the handler Style, set with SetStyle, and how sizes and durations
+// are written by the units.Format set with SetFormat. While a progress bar is
+// shown (see StartProgress), info lines only update the bar.
+type ConsoleHandler struct {
+	w        io.Writer
+	level    slog.Leveler
+	mu       *sync.Mutex
+	style    *Style         // Shared with the handlers returned by WithAttrs and WithGroup
+	format   *units.Format  // Shared like style
+	progress *progressState // Shared like style
+	context  []slog.Attr
+	group    string
+}
+
+// NewConsoleHandler creates a console handler writing to w. A nil opts logs at info level.
+func NewConsoleHandler(w io.Writer, opts *slog.HandlerOptions) *ConsoleHandler {
+	h := &ConsoleHandler{w: w, level: slog.LevelInfo, mu: &sync.Mutex{}, style: &Style{}, format: &units.Format{}, progress: &progressState{}}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// SetStyle changes the style of this handler and of all handlers derived from it
+func (h *ConsoleHandler) SetStyle(style Style) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.style = style
+}
+
+// SetFormat changes how this handler and all handlers derived from it write sizes and durations
+func (h *ConsoleHandler) SetFormat(format units.Format) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.format = format
+}
+
+// Enabled reports whether records of the given level are written
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// WithAttrs returns a handler whose lines are nested under the given context
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.context = append(append([]slog.Attr{}, h.context...), attrs...)
+	return &clone
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with name
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// Handle writes a record as a single line
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	style, format := *h.style, *h.format
+
+	if h.progress.active && r.Level < slog.LevelWarn {
+		if name := h.currentName(r); name != h.progress.current {
+			h.progress.current = name
+			h.drawProgress()
+		}
+		return nil
+	}
+
+	var line strings.Builder
+
+	nested := len(h.context) > 0 && h.level.Level() <= slog.LevelInfo && !h.progress.active
+	if nested {
+		line.WriteString("  ")
+	}
+
+	var errText string
+	var attrs []slog.Attr
+	if !nested {
+		attrs = append(attrs, h.context...)
+	}
+	success, hasSuccess := false, false
+	r.Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		switch {
+		case a.Key == keySuccess && a.Value.Kind() == slog.KindBool:
+			success, hasSuccess = a.Value.Bool(), true
+		case a.Key == keyError:
+			errText = a.Value.String()
+		default:
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+
+	mark, message := "", r.Message
+	switch {
+	case hasSuccess && success:
+		mark = style.Success("✓") + " "
+	case r.Level >= LevelSummary:
+		message = style.Emphasis(message)
+	case hasSuccess || r.Level >= slog.LevelError:
+		mark = style.Failure("✗") + " "
+	case r.Level >= slog.LevelWarn:
+		mark = style.Warning("Warning:") + " "
+	}
+	if mark != "" && nested {
+		line.WriteString("  ")
+	}
+	line.WriteString(mark)
+	line.WriteString(message)
+
+	for _, a := range attrs {
+		writeAttr(&line, style, format, h.group, a)
+	}
+	if errText != "" {
+		line.WriteString(": ")
+		line.WriteString(style.Failure(errText))
+	}
+	line.WriteByte('\n')
+
+	if h.progress.active {
+		h.clearProgress()
+	}
+	_, err := io.WriteString(h.w, line.String())
+	if h.progress.active {
+		h.drawProgress()
+	}
+	return err
+}
+
+// currentName returns the resource and file a record is about, shown next to the progress bar
+func (h *ConsoleHandler) currentName(r slog.Record) string {
+	resource, file := "", ""
+	for _, a := range h.context {
+		if a.Key == "resource" {
+			resource = a.Value.String()
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "file" {
+			file = a.Value.String()
+		}
+		return true
+	})
+
+	switch {
+	case resource == "":
+		return h.progress.current
+	case file == "":
+		return resource
+	default:
+		return resource + "/" + file
+	}
+}
+
+// writeAttr appends " key=value" for an attribute, flattening groups
+func writeAttr(line *strings.Builder, style Style, format units.Format, prefix string, a slog.Attr) {
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeAttr(line, style, format, prefix+a.Key+".", slog.Attr{Key: ga.Key, Value: ga.Value.Resolve()})
+		}
+		return
+	}
+
+	line.WriteByte(' ')
+	line.WriteString(prefix + a.Key)
+	line.WriteByte('=')
+	line.WriteString(highlight(style, a.Key, a.Value, formatValue(format, a.Key, a.Value)))
+}
+
+// highlight applies the style to attribute values that carry an outcome
+func highlight(style Style, key string, v slog.Value, text string) string {
+	switch {
+	case key == keyReduced:
+		return style.Success(text)
+	case key == keyFailed && v.Kind() == slog.KindInt64 && v.Int64() > 0:
+		return style.Failure(text)
+	}
+	return text
+}
+
+// formatValue renders an attribute value for the console
+func formatValue(format units.Format, key string, v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindInt64:
+		if strings.HasSuffix(key, "_size") {
+			return format.Size(v.Int64())
+		}
+	case slog.KindDuration:
+		return format.Duration(v.Duration())
+	case slog.KindString:
+		s := v.String()
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			return strconv.Quote(s)
+		}
+		return s
+	}
+	return v.String()
+}
@@ -0,0 +1,136 @@
+package bundler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewConsoleHandler(&buf, nil))
+
+	log.Info("Starting compilation", "input", "/srv/my resources")
+	res := log.With("resource", "alpha")
+	res.Info("Found Lua scripts to compile", "count", 2)
+	res.Info("Compiled", "file", "client.lua", "success", true, "duration", 1500*time.Microsecond, "input_size", int64(2048))
+	res.Error("Failed to compile", "file", "server.lua", "error", errors.New("syntax error"))
+	log.Warn("Cannot access path", "path", "/tmp/x")
+	log.Debug("Hidden at info level")
+
+	want := `Starting compilation input="/srv/my resources"
+  Found Lua scripts to compile count=2
+    ✓ Compiled file=client.lua duration=1.5ms input_size=2.0 KB
+    ✗ Failed to compile file=server.lua: syntax error
+Warning: Cannot access path path=/tmp/x
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConsoleHandlerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewConsoleHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	log.Debug("Processing script", "file", "client.lua")
+	if got, want := buf.String(), "Processing script file=client.lua\n"; got != want {
+		t.Errorf("Expected debug output %q, got %q", want, got)
+	}
+}
+
+func TestConsoleHandlerQuiet(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewConsoleHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	res := log.With("resource", "alpha")
+	res.Info("Found Lua scripts to compile", "count", 2)
+	res.Error("Failed to compile", "file", "server.lua", "error", errors.New("syntax error"))
+	log.Log(context.Background(), LevelSummary, "Build completed", "failed", 1)
+
+	want := `✗ Failed to compile resource=alpha file=server.lua: syntax error
+Build completed failed=1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConsoleHandlerColor(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewConsoleHandler(&buf, nil)
+	log := slog.New(handler).With("resource", "alpha")
+	handler.SetStyle(NewStyle(true))
+
+	log.Info("Compiled", "file", "client.lua", "success", true, "reduction", "40%")
+	log.Error("Failed to compile", "error", errors.New("syntax error"))
+	slog.New(handler).Log(context.Background(), LevelSummary, "Build completed", "failed", 1)
+
+	want := "    \x1b[32m✓\x1b[0m Compiled file=client.lua reduction=\x1b[32m40%\x1b[0m\n" +
+		"    \x1b[31m✗\x1b[0m Failed to compile: \x1b[31msyntax error\x1b[0m\n" +
+		"\x1b[1mBuild completed\x1b[0m failed=\x1b[31m1\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+
+	buf.Reset()
+	handler.SetStyle(NewStyle(false))
+	log.Info("Compiled", "success", true)
+	if got, want := buf.String(), "    ✓ Compiled\n"; got != want {
+		t.Errorf("Expected plain output %q, got %q", want, got)
+	}
+}
+
+func TestConsoleHandlerProgress(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewConsoleHandler(&buf, nil)
+	log := slog.New(handler)
+
+	handler.StartProgress(2)
+	log.With("resource", "alpha").Info("Compiled", "file", "client.lua", "success", true)
+	handler.AdvanceProgress(1)
+	log.With("resource", "beta").Warn("Script not found", "file", "server.lua")
+	handler.AdvanceProgress(2)
+	handler.StopProgress()
+	log.Info("Build completed")
+
+	got := buf.String()
+	if strings.Contains(got, "Compiled") {
+		t.Errorf("Expected info lines to be replaced by the progress bar, got %q", got)
+	}
+	for _, want := range []string{
+		"] 0/2  alpha/client.lua",
+		"\r\x1b[KWarning: Script not found resource=beta file=server.lua\n",
+		"] 2/2  alpha/client.lua",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, got)
+		}
+	}
+	if !strings.HasSuffix(got, "\r\x1b[KBuild completed\n") {
+		t.Errorf("Expected the bar to be cleared before later lines, got %q", got)
+	}
+}
+
+func TestConsoleHandlerDownloadProgress(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewConsoleHandler(&buf, nil)
+
+	handler.StartDownload(2048)
+	handler.AdvanceDownload(1024)
+	handler.StopDownload()
+	handler.StartDownload(-1)
+	handler.AdvanceDownload(512)
+	handler.StopDownload()
+
+	got := buf.String()
+	for _, want := range []string{"] 1.0 KB/2.0 KB", "[------------------------------] 512 B"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, got)
+		}
+	}
+}
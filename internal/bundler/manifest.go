@@ -0,0 +1,253 @@
+package bundler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// ManifestFileName is the build manifest written into every resource output directory
+const ManifestFileName = ".mta-bundler-manifest.json"
+
+// manifestVersion changes whenever the build output changes for identical inputs
+const manifestVersion = 1
+
+// buildManifest records what a resource was built from and what the build wrote, so the next
+// build can skip the resource when nothing changed
+type buildManifest struct {
+	Version   int            `json:"version"`
+	Inputs    manifestInputs `json:"inputs"`
+	Outputs   []string       `json:"outputs"`   // Compiled scripts and copied files, relative to the output directory
+	Generated []string       `json:"generated"` // Other files written, relative to the output directory
+}
+
+// manifestInputs are the inputs of a resource build. The resource is rebuilt when any changes.
+type manifestInputs struct {
+	Meta             string            `json:"meta"`                // Hash of meta.xml
+	Overrides        string            `json:"overrides,omitempty"` // Hash of the override file
+	Files            map[string]string `json:"files"`               // Hashes of scripts and files by meta.xml path
+	ObfuscationLevel int               `json:"obfuscation_level"`
+	StripDebug       bool              `json:"strip_debug"`
+	SuppressWarnings bool              `json:"suppress_warnings"`
+	MergeMode        bool              `json:"merge_mode"`
+	MetaOrder        bool              `json:"meta_order,omitempty"`  // Merged scripts keep their meta.xml order
+	Concat           bool              `json:"concat,omitempty"`      // Merged scripts are concatenated into one source
+	Isolate          bool              `json:"isolate,omitempty"`     // Concatenated scripts run through pcall
+	BundleName       string            `json:"bundle_name,omitempty"` // Path of the merged bundles when not the default
+	Banner           string            `json:"banner,omitempty"`      // Banner written to meta.xml and the merged bundles
+	Info             []string          `json:"info,omitempty"`        // Attributes set on the <info> tag, as name=value or name+=value
+	MinVersion       string            `json:"min_version,omitempty"` // Version <min_mta_version> is raised to
+	Passes           []string          `json:"passes,omitempty"`      // Source passes rewriting scripts before they are compiled
+	Constants        map[string]any    `json:"constants,omitempty"`   // Constants inlined by constant folding
+	Verbatim         []string          `json:"verbatim,omitempty"`    // Scripts copied as source instead of compiled
+	Unmerged         []string          `json:"unmerged,omitempty"`    // Scripts compiled on their own in merge mode
+	ScriptsOnly      bool              `json:"scripts_only,omitempty"`
+	LinkAssets       bool              `json:"link_assets,omitempty"`
+	NoCache          bool              `json:"no_cache,omitempty"`        // Client script tags get cache="false"
+	RegexMeta        bool              `json:"regex_meta,omitempty"`      // meta.xml is rewritten with regular expressions
+	SourceMaps       bool              `json:"source_maps,omitempty"`     // Source maps are written next to merged bundles
+	SourceMapShim    bool              `json:"source_map_shim,omitempty"` // The translation shim is added with the source maps
+	Stamped          bool              `json:"stamped,omitempty"`         // The build number is stamped into the output
+	EscrowKey        string            `json:"escrow_key,omitempty"`      // Short hash of the escrow key
+	CompilerArgs     []string          `json:"compiler_args,omitempty"`   // Arguments passed to luac_mta after the modeled options
+	Compiler         string            `json:"compiler"`                  // Hash of the luac_mta binary
+}
+
+// resourceInputs hashes everything the build of res depends on
+func (b Bundler) resourceInputs(res *resource.Resource, options compiler.CompilationOptions, mergeMode bool) (manifestInputs, error) {
+	inputs := manifestInputs{
+		Files:            make(map[string]string, len(res.Files)),
+		ObfuscationLevel: int(options.ObfuscationLevel),
+		StripDebug:       options.StripDebug,
+		SuppressWarnings: options.SuppressDecompileWarning,
+		MergeMode:        mergeMode,
+		MetaOrder:        mergeMode && res.MetaOrder,
+		Concat:           mergeMode && res.Concat,
+		Isolate:          mergeMode && res.Isolate,
+		ScriptsOnly:      res.SkipAssets,
+		LinkAssets:       res.LinkAssets,
+		NoCache:          res.NoCache,
+		RegexMeta:        res.RegexMeta,
+		Banner:           res.Banner,
+		MinVersion:       res.MinVersion,
+		SourceMaps:       b.options.SourceMaps,
+		SourceMapShim:    b.options.MapShim,
+		Stamped:          b.options.Stamp.Number != 0,
+		Passes:           b.sourcePasses(),
+		Constants:        b.options.Constants,
+	}
+
+	var err error
+	if inputs.Meta, err = b.hashInput(res.MetaXMLPath); err != nil {
+		return inputs, err
+	}
+	overridesPath := filepath.Join(res.BaseDir, config.ResourceFileName)
+	if _, statErr := os.Stat(overridesPath); statErr == nil {
+		if inputs.Overrides, err = b.hashInput(overridesPath); err != nil {
+			return inputs, err
+		}
+	}
+	for src := range res.Verbatim {
+		inputs.Verbatim = append(inputs.Verbatim, src)
+	}
+	sort.Strings(inputs.Verbatim)
+	for _, attr := range res.Info {
+		inputs.Info = append(inputs.Info, attr.String())
+	}
+	if mergeMode {
+		inputs.BundleName = res.BundleName
+		for src := range res.Unmerged {
+			inputs.Unmerged = append(inputs.Unmerged, src)
+		}
+		sort.Strings(inputs.Unmerged)
+	}
+	for _, fileRef := range res.Files {
+		if inputs.Files[fileRef.RelativePath], err = b.hashInput(fileRef.FullPath); err != nil {
+			return inputs, err
+		}
+	}
+	if len(b.options.EscrowKey) > 0 {
+		sum := sha256.Sum256(b.options.EscrowKey)
+		inputs.EscrowKey = hex.EncodeToString(sum[:8])
+	}
+	inputs.CompilerArgs = b.compiler.Args()
+	if inputs.Compiler, err = b.compiler.Fingerprint(); err != nil {
+		return inputs, err
+	}
+	return inputs, nil
+}
+
+// prepareManifest hashes the inputs of res for its build manifest. When the manifest in the
+// output directory matches them, result is filled from it and true is returned: the resource
+// does not need to be built. Otherwise the outdated manifest is removed first, so an interrupted
+// build is never taken for a complete one. The inputs are nil when no manifest is kept, which
+// is the case for in-place builds and zipped resources.
+func (b Bundler) prepareManifest(res *resource.Resource, options compiler.CompilationOptions, mergeMode bool, result *ResourceResult) (*manifestInputs, bool) {
+	if b.options.OutputDir == "" || b.options.Zip {
+		return nil, false
+	}
+
+	outputDir, err := res.OutputDir(b.inputRoot(), b.options.OutputDir)
+	if err != nil {
+		return nil, false
+	}
+	inputs, err := b.resourceInputs(res, options, mergeMode)
+	if err != nil {
+		slog.Debug("Cannot hash resource inputs, the resource is always rebuilt", "resource", res.Name, "error", err)
+		return nil, false
+	}
+
+	if !b.options.Force {
+		if outputs, generated, ok := unchanged(outputDir, inputs); ok {
+			result.Unchanged = true
+			result.Compile.OutputDir = outputDir
+			result.Compile.MergeMode = mergeMode
+			result.Reused = outputs
+			result.Generated = generated
+			return &inputs, true
+		}
+	}
+
+	if err := os.Remove(filepath.Join(outputDir, ManifestFileName)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Cannot remove outdated build manifest", "resource", res.Name, "error", err)
+	}
+	return &inputs, false
+}
+
+// unchanged reports whether the manifest in outputDir was written for the same inputs and
+// every file it lists still exists. It returns the absolute paths of the outputs and generated files.
+func unchanged(outputDir string, inputs manifestInputs) ([]string, []string, bool) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestFileName))
+	if err != nil {
+		return nil, nil, false
+	}
+	var manifest buildManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		slog.Debug("Ignoring unreadable build manifest", "dir", outputDir, "error", err)
+		return nil, nil, false
+	}
+	if manifest.Version != manifestVersion || !reflect.DeepEqual(manifest.Inputs, inputs) {
+		return nil, nil, false
+	}
+
+	outputs := absolutePaths(outputDir, manifest.Outputs)
+	generated := absolutePaths(outputDir, manifest.Generated)
+	for _, path := range append(append([]string{filepath.Join(outputDir, "meta.xml")}, outputs...), generated...) {
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, false
+		}
+	}
+	return outputs, generated, true
+}
+
+// writeManifest records the inputs and written files of a successful resource build
+func writeManifest(result ResourceResult, inputs manifestInputs) (string, error) {
+	outputDir := result.Compile.OutputDir
+	manifest := buildManifest{Version: manifestVersion, Inputs: inputs}
+
+	var err error
+	if manifest.Outputs, err = relativePaths(outputDir, result.outputFiles()); err != nil {
+		return "", err
+	}
+	if manifest.Generated, err = relativePaths(outputDir, result.Generated); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode build manifest: %v", err)
+	}
+	path := filepath.Join(outputDir, ManifestFileName)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write build manifest: %v", err)
+	}
+	return path, nil
+}
+
+// hashFile returns the SHA-256 hash of a file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// relativePaths converts paths to slash-separated paths relative to dir
+func relativePaths(dir string, paths []string) ([]string, error) {
+	relative := make([]string, 0, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot record output path %s: %v", path, err)
+		}
+		relative = append(relative, filepath.ToSlash(rel))
+	}
+	return relative, nil
+}
+
+// absolutePaths converts slash-separated paths relative to dir back to absolute paths
+func absolutePaths(dir string, paths []string) []string {
+	absolute := make([]string, 0, len(paths))
+	for _, path := range paths {
+		absolute = append(absolute, filepath.Join(dir, filepath.FromSlash(path)))
+	}
+	return absolute
+}
@@ -0,0 +1,52 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildResourceSkipsUnchanged(t *testing.T) {
+	comp := fakeCompiler(t, `while [ $# -gt 0 ]; do [ "$1" = "-o" ] && printf '\033LuaQ' > "$2"; shift; done`+"\n")
+
+	inputDir := t.TempDir()
+	resourceDir := filepath.Join(inputDir, "race")
+	os.MkdirAll(resourceDir, 0755)
+	metaPath := filepath.Join(resourceDir, "meta.xml")
+	os.WriteFile(metaPath, []byte(`<meta><script src="server.lua" type="server" /></meta>`), 0644)
+	os.WriteFile(filepath.Join(resourceDir, "server.lua"), []byte("print(1)"), 0644)
+
+	options := Options{InputPath: inputDir, OutputDir: filepath.Join(t.TempDir(), "out")}
+	build := func(options Options) ResourceResult {
+		t.Helper()
+		result := NewBundler(comp, options).BuildResource(metaPath)
+		if result.Error != nil {
+			t.Fatalf("Build failed: %v", result.Error)
+		}
+		return result
+	}
+
+	if build(options).Unchanged {
+		t.Fatal("Expected the first build to compile the resource")
+	}
+	second := build(options)
+	if !second.Unchanged || len(second.Reused) != 1 {
+		t.Fatalf("Expected the second build to reuse the output, got %+v", second)
+	}
+
+	forced := options
+	forced.Force = true
+	if build(forced).Unchanged {
+		t.Error("Expected -force to rebuild the resource")
+	}
+
+	os.WriteFile(filepath.Join(resourceDir, "server.lua"), []byte("print(2)"), 0644)
+	if build(options).Unchanged {
+		t.Error("Expected a changed script to rebuild the resource")
+	}
+
+	os.Remove(filepath.Join(options.OutputDir, "race", "server.luac"))
+	if build(options).Unchanged {
+		t.Error("Expected a missing output file to rebuild the resource")
+	}
+}
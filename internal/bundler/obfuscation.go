@@ -0,0 +1,40 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// supportedOptions returns options with an obfuscation level the luac_mta binary supports, so an
+// old binary fails before the build instead of with its own error in the middle of it. With
+// LevelFallback an unsupported level is lowered to the highest supported one with a
+// warning. Levels every binary supports are not probed, and a binary that cannot be probed is
+// left to fail on its first compile.
+func (b Bundler) supportedOptions(options compiler.CompilationOptions, resourceName string) (compiler.CompilationOptions, error) {
+	if options.ObfuscationLevel <= compiler.ObfuscationBasic {
+		return options, nil
+	}
+	version, err := b.compiler.Version()
+	if err != nil {
+		slog.Debug("Cannot probe luac_mta version", "error", err)
+		return options, nil
+	}
+	if version.Supports(options.ObfuscationLevel) {
+		return options, nil
+	}
+
+	if !b.options.LevelFallback {
+		return options, fmt.Errorf("luac_mta (%v) does not support obfuscation level %d, which needs MTA %s or newer: update the binary, lower -e or use -level-fallback",
+			version, options.ObfuscationLevel, options.ObfuscationLevel.MinMTAVersion())
+	}
+	log := slog.Default()
+	if resourceName != "" {
+		log = log.With("resource", resourceName)
+	}
+	log.Warn("luac_mta does not support the obfuscation level, falling back",
+		"binary", version.String(), "level", int(options.ObfuscationLevel), "fallback", int(version.MaxLevel))
+	options.ObfuscationLevel = version.MaxLevel
+	return options, nil
+}
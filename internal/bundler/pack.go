@@ -0,0 +1,403 @@
+package bundler
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// Pack combines several resources into a single output resource
+type Pack struct {
+	Name      string   // Name of the pack resource
+	Resources []string // Resource name or path globs of its members
+}
+
+// luaIdentifier matches the export names a shim can forward
+var luaIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shimTemplate is the Lua source of the scripts that keep exports working after they moved to
+// another resource, by forwarding them to it
+var shimTemplate = template.Must(template.New("shim").Parse(`-- Generated by mta-bundler: {{.Comment}}
+{{- range .Functions}}
+
+function {{.Name}}(...)
+	return exports[{{printf "%q" .Target}}]:{{.Name}}(...)
+end
+{{- end}}
+`))
+
+// shimData is the input of shimTemplate
+type shimData struct {
+	Comment   string
+	Functions []shimFunction
+}
+
+// shimFunction is an export forwarded by a shim script to the resource now defining it
+type shimFunction struct {
+	Name   string
+	Target string
+}
+
+// assignPacks splits metaPaths into the resources built on their own and the members of each
+// pack, indexed like the packs. A resource matching several packs belongs to the first one.
+func (b Bundler) assignPacks(metaPaths []string) ([]string, [][]string) {
+	members := make([][]string, len(b.options.Packs))
+	packed := make(map[string]bool)
+	for i, pack := range b.options.Packs {
+		for _, metaPath := range SelectResourceMetas(b.inputRoot(), metaPaths, pack.Resources) {
+			if !packed[metaPath] {
+				packed[metaPath] = true
+				members[i] = append(members[i], metaPath)
+			}
+		}
+	}
+
+	var rest []string
+	for _, metaPath := range metaPaths {
+		if !packed[metaPath] {
+			rest = append(rest, metaPath)
+		}
+	}
+	return rest, members
+}
+
+// planPacks splits metaPaths into the resources built on their own and the packs to build with
+// their members. Packs matching no resource are left out.
+func (b Bundler) planPacks(metaPaths []string) ([]string, []Pack, [][]string, error) {
+	if len(b.options.Packs) == 0 {
+		return metaPaths, nil, nil, nil
+	}
+	if b.options.OutputDir == "" {
+		return nil, nil, nil, fmt.Errorf("packs require an output directory (-o)")
+	}
+	if err := b.checkPackNames(metaPaths); err != nil {
+		return nil, nil, nil, err
+	}
+
+	rest, members := b.assignPacks(metaPaths)
+	var packs []Pack
+	var packMembers [][]string
+	for i, pack := range b.options.Packs {
+		if len(members[i]) == 0 {
+			slog.Warn("Pack matches no resource", "pack", pack.Name)
+			continue
+		}
+		packs = append(packs, pack)
+		packMembers = append(packMembers, members[i])
+	}
+	return rest, packs, packMembers, nil
+}
+
+// checkPackNames fails when a pack would be written over the output of a resource
+func (b Bundler) checkPackNames(metaPaths []string) error {
+	for _, pack := range b.options.Packs {
+		for _, metaPath := range metaPaths {
+			if rel, err := filepath.Rel(b.inputRoot(), filepath.Dir(metaPath)); err == nil && filepath.ToSlash(rel) == pack.Name {
+				return fmt.Errorf("pack %q conflicts with the resource at %s", pack.Name, filepath.Dir(metaPath))
+			}
+		}
+	}
+	return nil
+}
+
+// BuildPack builds the member resources of pack into a single resource named after the pack,
+// and replaces the output of each member with a shim resource forwarding its exports to the pack.
+// Per-resource overrides other than verbatim patterns do not apply to packed resources.
+func (b Bundler) BuildPack(pack Pack, metaPaths []string) ResourceResult {
+	startTime := time.Now()
+	outputDir := filepath.Join(b.options.OutputDir, pack.Name)
+	result := ResourceResult{
+		MetaXMLPath: filepath.Join(outputDir, "meta.xml"),
+		Resource:    &resource.Resource{Name: pack.Name, BaseDir: outputDir, MetaXMLPath: filepath.Join(outputDir, "meta.xml")},
+		Options:     b.options.Compilation,
+	}
+	log := slog.With("resource", pack.Name)
+
+	members := make([]*resource.Resource, 0, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		res, err := b.parseResource(metaPath)
+		if err != nil {
+			result.Error = fmt.Errorf("error packing %s: %v", metaPath, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		if err := b.applyVerbatim(res); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		if err := b.lintResource(res); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		if err := b.scanResource(res); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		res.SkipAssets = b.options.ScriptsOnly
+		res.LinkAssets = b.options.LinkAssets
+		res.NoCache = b.options.NoCache
+		res.RegexMeta = b.options.RegexMeta
+		res.MetaOrder = b.options.MetaOrder
+		res.Concat = b.options.Concat
+		res.Isolate = b.options.Isolate
+		res.BundleName = b.options.BundleName
+		res.Transform = b.sourceTransform()
+		res.Info = b.infoAttrs()
+		res.Banner = b.expand(b.options.Banner)
+		res.MinVersion = b.minVersion(b.options.Compilation)
+		if overrides, ok, _ := config.LoadResourceOverrides(res.BaseDir); ok && overridesBuild(overrides) {
+			log.Warn("Resource overrides are ignored in packs", "member", res.Name, "path", overrides.Path)
+		}
+		cases, err := res.PackAttention()
+		if err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		result.Attention = append(result.Attention, cases...)
+		exportCases, err := b.checkExports(res, true)
+		result.Attention = append(result.Attention, exportCases...)
+		if err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		members = append(members, res)
+	}
+
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, member.Name)
+	}
+	log.Info("Packing resources", "members", strings.Join(names, ", "))
+
+	var err error
+	result.Compile, err = resource.CompilePack(b.compiler, pack.Name, members, outputDir, b.options.Compilation)
+	if err != nil {
+		result.Error = fmt.Errorf("error compiling pack %s: %v", pack.Name, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	mapPaths, err := b.writeSourceMaps(result.Compile, b.inputRoot())
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	result.Generated = append(result.Generated, mapPaths...)
+
+	var shimArchives []string
+	for _, member := range members {
+		shimDir, shimFiles, err := b.writeShim(member, pack.Name)
+		if err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		result.Generated = append(result.Generated, shimFiles...)
+
+		stampPath, err := b.stampOutput(shimDir)
+		if err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		if stampPath != "" {
+			shimFiles = append(shimFiles, stampPath)
+			result.Generated = append(result.Generated, stampPath)
+		}
+
+		if b.options.Zip {
+			archivePath, err := zipResource(shimDir, shimFiles)
+			if err != nil {
+				result.Error = err
+				result.Duration = time.Since(startTime)
+				return result
+			}
+			shimArchives = append(shimArchives, archivePath)
+			continue
+		}
+
+		if b.options.Clean {
+			removed, err := pruneDir(shimDir, shimFiles)
+			if err != nil {
+				result.Error = fmt.Errorf("failed to clean output directory: %v", err)
+				result.Duration = time.Since(startTime)
+				return result
+			}
+			if len(removed) > 0 {
+				log.Info("Removed stale output files", "success", true, "member", member.Name, "count", len(removed))
+			}
+		}
+	}
+
+	packStamp, err := b.stampOutput(outputDir)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if packStamp != "" {
+		result.Generated = append(result.Generated, packStamp)
+	}
+
+	if b.options.Clean {
+		if err := b.cleanOutput(result); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+	}
+
+	if b.options.Zip {
+		// The shims were packaged on their own, the pack archive holds only the pack directory
+		files := append([]string{result.MetaXMLPath}, result.outputFiles()...)
+		files = append(files, mapPaths...)
+		if packStamp != "" {
+			files = append(files, packStamp)
+		}
+		if err := b.zipOutput(&result, files); err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		result.Generated = shimArchives
+	}
+
+	result.Duration = time.Since(startTime)
+	log.Info("Compiled pack", "success", true, "members", len(members), "duration", result.Duration)
+	return result
+}
+
+// writeShim writes the shim resource of a packed member to the member's output directory:
+// it includes the pack, so starting the member starts the pack, and forwards its exports.
+// It returns the shim directory and the files written.
+func (b Bundler) writeShim(member *resource.Resource, packName string) (string, []string, error) {
+	shimDir, err := member.OutputDir(b.inputRoot(), b.options.OutputDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.MkdirAll(shimDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create shim directory: %v", err)
+	}
+	// A manifest left by an earlier build of the member would describe files it no longer has
+	if err := os.Remove(filepath.Join(shimDir, ManifestFileName)); err != nil && !os.IsNotExist(err) {
+		return "", nil, fmt.Errorf("failed to remove build manifest: %v", err)
+	}
+
+	functions := map[string][]shimFunction{}
+	var exports []string
+	for _, export := range member.Meta.Exports {
+		if !luaIdentifier.MatchString(export.Function) {
+			slog.Warn("Cannot forward export, not a Lua identifier", "resource", member.Name, "function", export.Function)
+			continue
+		}
+		for _, kind := range exportKinds(export) {
+			functions[kind] = append(functions[kind], shimFunction{Name: export.Function, Target: packName})
+		}
+
+		tag := fmt.Sprintf(`<export function="%s" type="%s"`, export.Function, html.EscapeString(strings.ToLower(export.Type)))
+		if export.Type == "" {
+			tag = fmt.Sprintf(`<export function="%s"`, export.Function)
+		}
+		if export.HTTP != "" {
+			tag += fmt.Sprintf(` http="%s"`, html.EscapeString(export.HTTP))
+		}
+		exports = append(exports, tag+" />")
+	}
+
+	lines := []string{
+		fmt.Sprintf(`    <info name="%s" author="mta-bundler" type="script" description="Generated by mta-bundler: packed into %s" />`,
+			html.EscapeString(member.Name), html.EscapeString(packName)),
+		fmt.Sprintf(`    <include resource="%s" />`, html.EscapeString(packName)),
+	}
+	written, tags, err := b.writeShimScripts(shimDir, member.Name+" was packed into "+packName, functions)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, tag := range tags {
+		lines = append(lines, "    "+tag)
+	}
+	for _, export := range exports {
+		lines = append(lines, "    "+export)
+	}
+
+	meta := "<meta>\n" + strings.Join(lines, "\n") + "\n</meta>\n"
+	if version := b.minVersion(b.options.Compilation); version != "" && len(tags) > 0 {
+		if meta, err = resource.RaiseMinVersion(meta, version); err != nil {
+			return "", nil, err
+		}
+	}
+	metaPath := filepath.Join(shimDir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write shim meta.xml: %v", err)
+	}
+
+	slog.With("resource", member.Name).Info("Wrote shim forwarding to the pack", "success", true, "pack", packName, "exports", len(exports))
+	return shimDir, append(written, metaPath), nil
+}
+
+// exportKinds returns the script types of the shims forwarding an export
+func exportKinds(export resource.Export) []string {
+	switch strings.ToLower(export.Type) {
+	case "shared":
+		return []string{"client", "server"}
+	case "client":
+		return []string{"client"}
+	default:
+		return []string{"server"}
+	}
+}
+
+// writeShimScripts compiles the client and server shim scripts forwarding functions, by script
+// type, into dir as shim_client.luac and shim_server.luac. It returns the files written and
+// their meta.xml script tags.
+func (b Bundler) writeShimScripts(dir, comment string, functions map[string][]shimFunction) ([]string, []string, error) {
+	tempDir, err := os.MkdirTemp("", "mta-bundler-shim-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var written, tags []string
+	for _, kind := range []string{"client", "server"} {
+		if len(functions[kind]) == 0 {
+			continue
+		}
+		sourcePath := filepath.Join(tempDir, kind+".lua")
+		source, err := os.Create(sourcePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to write shim script: %v", err)
+		}
+		err = shimTemplate.Execute(source, shimData{Comment: comment, Functions: functions[kind]})
+		source.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate shim script: %v", err)
+		}
+
+		name := "shim_" + kind + ".luac"
+		if _, err := b.compiler.CompileFile(sourcePath, filepath.Join(dir, name), b.options.Compilation); err != nil {
+			return nil, nil, fmt.Errorf("failed to compile shim script: %v", err)
+		}
+		written = append(written, filepath.Join(dir, name))
+		tags = append(tags, fmt.Sprintf(`<script src="%s" type="%s" />`, name, kind))
+	}
+	return written, tags, nil
+}
+
+// overridesBuild reports whether overrides change how a resource is built, beyond verbatim and merge exclude patterns
+func overridesBuild(o config.ResourceOverrides) bool {
+	return o.Obfuscation != nil || o.StripDebug != nil || o.SuppressWarnings != nil || o.Merge != nil || len(o.Lazy) > 0
+}
@@ -0,0 +1,73 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildPack(t *testing.T) {
+	// Concatenates the sources into the -o path, so the output shows what was compiled
+	comp := fakeCompiler(t, `out=; files=
+while [ $# -gt 0 ]; do case "$1" in -o) out="$2"; shift;; -*) ;; *) files="$files $1";; esac; shift; done
+cat $files > "$out"
+`)
+
+	inputDir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(inputDir, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("util1/meta.xml", `<meta>
+    <script src="server.lua" type="server" />
+    <file src="logo.png" />
+    <export function="getThing" type="server" />
+    <include resource="util2" />
+    <include resource="scoreboard" />
+</meta>`)
+	write("util1/server.lua", "function getThing() end\n")
+	write("util1/logo.png", "PNG")
+	write("util2/meta.xml", `<meta><script src="shared.lua" type="shared" /><file src="logo.png" download="false" /></meta>`)
+	write("util2/shared.lua", "-- util2\n")
+	write("util2/logo.png", "PNG")
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	b := NewBundler(comp, Options{InputPath: inputDir, OutputDir: outputDir, Packs: []Pack{{Name: "utils", Resources: []string{"util*"}}}})
+	rest, packs, members, err := b.planPacks([]string{filepath.Join(inputDir, "util1", "meta.xml"), filepath.Join(inputDir, "util2", "meta.xml")})
+	if err != nil || len(rest) != 0 || len(packs) != 1 {
+		t.Fatalf("Expected both resources in the pack, got rest=%v packs=%v err=%v", rest, packs, err)
+	}
+
+	result := b.BuildPack(packs[0], members[0])
+	if result.Error != nil {
+		t.Fatalf("BuildPack failed: %v", result.Error)
+	}
+
+	server, _ := os.ReadFile(filepath.Join(outputDir, "utils", "server.luac"))
+	if string(server) != "function getThing() end\n-- util2\n" {
+		t.Errorf("Expected the server scripts of both members in order, got %q", server)
+	}
+	meta, _ := os.ReadFile(filepath.Join(outputDir, "utils", "meta.xml"))
+	for _, want := range []string{`<file src="logo.png" />`, `<export function="getThing" type="server" />`, `<include resource="scoreboard" />`} {
+		if !strings.Contains(string(meta), want) {
+			t.Errorf("Expected the pack meta.xml to contain %q, got:\n%s", want, meta)
+		}
+	}
+	if strings.Contains(string(meta), `resource="util2"`) || strings.Count(string(meta), "logo.png") != 1 {
+		t.Errorf("Expected members includes and duplicate files to be dropped, got:\n%s", meta)
+	}
+
+	shim, _ := os.ReadFile(filepath.Join(outputDir, "util1", "shim_server.luac"))
+	if !strings.Contains(string(shim), `return exports["utils"]:getThing(...)`) {
+		t.Errorf("Expected the shim to forward getThing, got:\n%s", shim)
+	}
+	shimMeta, _ := os.ReadFile(filepath.Join(outputDir, "util2", "meta.xml"))
+	if !strings.Contains(string(shimMeta), `<include resource="utils" />`) {
+		t.Errorf("Expected the shim to include the pack, got:\n%s", shimMeta)
+	}
+}
@@ -0,0 +1,82 @@
+package bundler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// PlanStep is the build of a single resource, planned without building anything
+type PlanStep struct {
+	Resource    string                      // Resource name
+	MetaXMLPath string                      // Absolute path of the resource's meta.xml
+	OutputDir   string                      // Absolute directory the resource is written to
+	Compilation compiler.CompilationOptions // Options after the nested config files, before the resource's override file
+	MergeMode   bool                        // Merge mode after the nested config files, before the resource's override file
+	Verbatim    []string                    // Verbatim script globs of the nested config files
+	Unmerged    []string                    // Merge exclude script globs of the nested config files
+	Inputs      []string                    // Files the build reads: meta.xml, config files and referenced files
+}
+
+// Plan returns the build of every resource of the input path, so each one can be built on its
+// own with a meta.xml input. Packs and splits combine or divide resources, so builds using them
+// cannot be planned.
+func (b Bundler) Plan() ([]PlanStep, error) {
+	if b.options.OutputDir == "" {
+		return nil, fmt.Errorf("a build plan requires an output directory (-o)")
+	}
+	if len(b.options.Packs) > 0 || len(b.options.Splits) > 0 {
+		return nil, fmt.Errorf("builds with packs or splits cannot be planned one resource at a time")
+	}
+
+	metaPaths, err := b.FindResources()
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]PlanStep, 0, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		res, err := b.parseResource(metaPath)
+		if err != nil {
+			return nil, err
+		}
+		outputDir, err := res.OutputDir(b.inputRoot(), b.options.OutputDir)
+		if err != nil {
+			return nil, err
+		}
+		if outputDir, err = filepath.Abs(outputDir); err != nil {
+			return nil, fmt.Errorf("cannot get absolute path: %v", err)
+		}
+
+		step := PlanStep{Resource: res.Name, MetaXMLPath: metaPath, OutputDir: outputDir}
+		step.Compilation, step.MergeMode = b.subtreeOptions(res)
+		step.Inputs = append(step.Inputs, metaPath)
+		if overridesPath := filepath.Join(res.BaseDir, config.ResourceFileName); fileExists(overridesPath) {
+			step.Inputs = append(step.Inputs, overridesPath)
+		}
+		for _, subtree := range b.subtreesOf(res.BaseDir) {
+			step.Verbatim = append(step.Verbatim, subtree.Verbatim...)
+			step.Unmerged = append(step.Unmerged, subtree.MergeExclude...)
+			step.Inputs = append(step.Inputs, subtree.Path)
+		}
+
+		seen := make(map[string]bool)
+		for _, file := range res.Files {
+			if !seen[file.FullPath] {
+				seen[file.FullPath] = true
+				step.Inputs = append(step.Inputs, file.FullPath)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// fileExists reports whether path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
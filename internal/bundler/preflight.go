@@ -0,0 +1,144 @@
+package bundler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bytecode"
+)
+
+const (
+	preflightBatchFiles = 100       // Scripts parsed by one luac_mta run at most
+	preflightBatchBytes = 24 * 1024 // Bytes of script paths passed to one luac_mta run at most, below every command line limit
+)
+
+// preflightScript is a script checked by the preflight
+type preflightScript struct {
+	resource string // Name of the resource
+	src      string // Script src as written in meta.xml
+	path     string
+}
+
+// luacError matches the "file:line: message" part of a luac_mta syntax error
+var luacError = regexp.MustCompile(`:(\d+): (.*)$`)
+
+// preflight runs luac_mta in parse-only mode over the scripts of every resource before
+// anything is compiled, when Preflight is set. Scripts are parsed in large batches, much faster
+// than the obfuscating compile. luac_mta stops at the first script of a batch that does not
+// parse, so a failing batch is split in halves until every broken script is found. Syntax
+// errors are logged together and stop the build, like those of checkSyntax.
+func (b Bundler) preflight(metaPaths []string) error {
+	if !b.options.Preflight {
+		return nil
+	}
+
+	var scripts []preflightScript
+	for _, metaPath := range metaPaths {
+		res, err := b.parseResource(metaPath)
+		if err != nil {
+			continue
+		}
+		for _, fileRef := range res.GetLuaFiles() {
+			data, err := os.ReadFile(fileRef.FullPath)
+			if err != nil {
+				continue
+			}
+			if kind := bytecode.DetectKind(data); kind == bytecode.KindCompiled || kind == bytecode.KindObfuscated {
+				continue
+			}
+			scripts = append(scripts, preflightScript{resource: res.Name, src: fileRef.RelativePath, path: fileRef.FullPath})
+		}
+	}
+
+	failed := 0
+	for _, batch := range preflightBatches(scripts) {
+		n, err := b.parseScripts(batch)
+		if err != nil {
+			if b.interrupted() {
+				return fmt.Errorf("build interrupted before any resource was built")
+			}
+			return fmt.Errorf("preflight failed: %v", err)
+		}
+		failed += n
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scripts have syntax errors, nothing was built", failed, len(scripts))
+	}
+	slog.Debug("Preflight parsed scripts", "scripts", len(scripts), "resources", len(metaPaths))
+	return nil
+}
+
+// preflightBatches splits scripts into batches small enough for one luac_mta command line
+func preflightBatches(scripts []preflightScript) [][]preflightScript {
+	var batches [][]preflightScript
+	start, size := 0, 0
+	for i, script := range scripts {
+		if i > start && (i-start == preflightBatchFiles || size+len(script.path)+1 > preflightBatchBytes) {
+			batches = append(batches, scripts[start:i])
+			start, size = i, 0
+		}
+		size += len(script.path) + 1
+	}
+	if start < len(scripts) {
+		batches = append(batches, scripts[start:])
+	}
+	return batches
+}
+
+// parseScripts parses a batch of scripts with luac_mta, logs the syntax errors and returns
+// their number. A failing batch is split in halves, parsed in order, until the failing runs
+// are down to single scripts.
+func (b Bundler) parseScripts(scripts []preflightScript) (int, error) {
+	paths := make([]string, len(scripts))
+	for i, script := range scripts {
+		paths[i] = script.path
+	}
+	output, parseErr := b.compiler.Parse(paths)
+	if parseErr == nil {
+		return 0, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(parseErr, &exitErr) {
+		return 0, parseErr
+	}
+
+	if len(scripts) == 1 {
+		script := scripts[0]
+		line, message := luacSyntaxError(output)
+		slog.Error("Syntax error", "resource", script.resource, "file", script.src, "line", line, "error", message)
+		return 1, nil
+	}
+	half := len(scripts) / 2
+	first, err := b.parseScripts(scripts[:half])
+	if err != nil {
+		return 0, err
+	}
+	second, err := b.parseScripts(scripts[half:])
+	if err != nil {
+		return 0, err
+	}
+	if first+second == 0 {
+		// Every script parses on its own, luac_mta failed for another reason
+		return 0, fmt.Errorf("luac_mta failed: %v: %s", parseErr, strings.TrimSpace(string(output)))
+	}
+	return first + second, nil
+}
+
+// luacSyntaxError returns the line and message of a luac_mta syntax error, line 0 and the whole
+// output when it cannot be read
+func luacSyntaxError(output []byte) (int, string) {
+	text := strings.TrimSpace(string(output))
+	lines := strings.Split(text, "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if match := luacError.FindStringSubmatch(last); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line, match[2]
+	}
+	return 0, text
+}
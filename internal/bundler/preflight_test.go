@@ -0,0 +1,49 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	// Parses like luac -p, stopping at the first script containing SYNTAXERR, and compiles
+	// anything else
+	comp := fakeCompiler(t, `if [ "$1" = "-p" ]; then
+  shift 2
+  for f in "$@"; do
+    if grep -q SYNTAXERR "$f"; then echo "luac_mta: $f:2: unexpected symbol near 'SYNTAXERR'" >&2; exit 1; fi
+  done
+  exit 0
+fi
+while [ $# -gt 0 ]; do [ "$1" = "-o" ] && printf '\033LuaQ' > "$2"; shift; done
+`)
+
+	inputDir := t.TempDir()
+	for i, name := range []string{"race", "shop", "admin", "map"} {
+		resourceDir := filepath.Join(inputDir, name)
+		os.MkdirAll(resourceDir, 0755)
+		os.WriteFile(filepath.Join(resourceDir, "meta.xml"), []byte(`<meta><script src="server.lua" type="server" /><script src="client.lua" type="client" /></meta>`), 0644)
+		server := "print(1)"
+		if i%2 == 1 {
+			server = "print(1)\nSYNTAXERR"
+		}
+		os.WriteFile(filepath.Join(resourceDir, "server.lua"), []byte(server), 0644)
+		os.WriteFile(filepath.Join(resourceDir, "client.lua"), []byte("print(2)"), 0644)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+
+	_, err := NewBundler(comp, Options{InputPath: inputDir, OutputDir: outputDir, NoSyntaxCheck: true, Preflight: true}).Run()
+	if err == nil || !strings.Contains(err.Error(), "2 of 8 scripts") {
+		t.Fatalf("Expected both broken scripts to be reported, got %v", err)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) > 0 {
+		t.Errorf("Expected nothing to be built, got %d entries", len(entries))
+	}
+
+	line, message := luacSyntaxError([]byte("luac_mta: /src/race/server.lua:2: unexpected symbol near 'SYNTAXERR'\n"))
+	if line != 2 || message != "unexpected symbol near 'SYNTAXERR'" {
+		t.Errorf("Expected line 2 and the luac_mta message, got %d and %q", line, message)
+	}
+}
@@ -0,0 +1,123 @@
+package bundler
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// ProgressReporter is notified as a build advances through its resources
+type ProgressReporter interface {
+	StartProgress(total int)  // The build found total resources
+	AdvanceProgress(done int) // done resources have been processed
+	StopProgress()            // The build is over
+}
+
+// Width of the progress bar and maximum length of the current file shown after it
+const (
+	progressBarWidth   = 30
+	progressNameLength = 40
+)
+
+// progressState is the live progress bar of a ConsoleHandler
+type progressState struct {
+	active  bool
+	bytes   bool // Counts are byte sizes (downloads) instead of resources
+	total   int  // Negative when unknown
+	done    int
+	started time.Time
+	current string // Resource or file being processed
+}
+
+// StartProgress replaces the info lines of the handler with a progress bar until
+// StopProgress. Warnings and errors are still written above the bar.
+func (h *ConsoleHandler) StartProgress(total int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.progress = progressState{active: true, total: total, started: time.Now()}
+	h.drawProgress()
+}
+
+// AdvanceProgress updates the number of processed resources
+func (h *ConsoleHandler) AdvanceProgress(done int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.progress.active {
+		return
+	}
+	h.progress.done = done
+	h.drawProgress()
+}
+
+// StopProgress removes the progress bar and restores the info lines
+func (h *ConsoleHandler) StopProgress() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.progress.active {
+		return
+	}
+	h.clearProgress()
+	h.progress.active = false
+}
+
+// StartDownload shows a progress bar counting downloaded bytes until StopDownload.
+// total is -1 when the download size is unknown.
+func (h *ConsoleHandler) StartDownload(total int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.progress = progressState{active: true, bytes: true, total: int(total), started: time.Now()}
+	h.drawProgress()
+}
+
+// AdvanceDownload updates the number of downloaded bytes
+func (h *ConsoleHandler) AdvanceDownload(done int64) {
+	h.AdvanceProgress(int(done))
+}
+
+// StopDownload removes the download progress bar
+func (h *ConsoleHandler) StopDownload() {
+	h.StopProgress()
+}
+
+// drawProgress redraws the progress bar on the current line. The caller holds h.mu.
+func (h *ConsoleHandler) drawProgress() {
+	p := h.progress
+	filled := progressBarWidth
+	switch {
+	case p.total > 0:
+		filled = min(progressBarWidth*p.done/p.total, progressBarWidth)
+	case p.total < 0:
+		filled = 0
+	}
+	bar := h.style.Success(strings.Repeat("#", filled)) + strings.Repeat("-", progressBarWidth-filled)
+
+	var line string
+	switch {
+	case !p.bytes:
+		line = fmt.Sprintf("\r\x1b[K[%s] %d/%d", bar, p.done, p.total)
+	case p.total < 0:
+		line = fmt.Sprintf("\r\x1b[K[%s] %s", bar, h.format.Size(int64(p.done)))
+	default:
+		line = fmt.Sprintf("\r\x1b[K[%s] %s/%s", bar, h.format.Size(int64(p.done)), h.format.Size(int64(p.total)))
+	}
+	if p.done > 0 && p.done < p.total {
+		elapsed := time.Since(p.started)
+		eta := elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+		line += " ETA " + units.Format{DurationPrecision: time.Second, Locale: h.format.Locale}.Duration(eta)
+	}
+	if current := p.current; current != "" {
+		if len(current) > progressNameLength {
+			current = "..." + current[len(current)-progressNameLength+3:]
+		}
+		line += "  " + current
+	}
+	io.WriteString(h.w, line)
+}
+
+// clearProgress erases the progress bar line. The caller holds h.mu.
+func (h *ConsoleHandler) clearProgress() {
+	io.WriteString(h.w, "\r\x1b[K")
+}
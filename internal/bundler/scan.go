@@ -0,0 +1,71 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/davidbozo/mta-bundler/internal/backdoor"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// scanResource scans res for backdoor patterns when Scan is set. Findings that are not
+// acknowledged in the resource's override file are logged and fail the resource, as compiling
+// and obfuscating them would hide them from later reviews.
+func (b Bundler) scanResource(res *resource.Resource) error {
+	if !b.options.Scan {
+		return nil
+	}
+	pending, acknowledged, err := scanFindings(res)
+	if err != nil {
+		return err
+	}
+
+	log := slog.With("resource", res.Name)
+	for _, finding := range pending {
+		log.Error("Suspicious code", "rule", finding.Rule, "severity", finding.Severity, "file", finding.File, "line", finding.Line,
+			"id", finding.ID(), "detail", finding.Message)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d suspicious patterns in %s, review them and add their ids to scan_acknowledge in %s to build it anyway",
+			len(pending), res.Name, config.ResourceFileName)
+	}
+	if acknowledged > 0 {
+		log.Info("Scanned for backdoors", "acknowledged", acknowledged, "success", true)
+	}
+	return nil
+}
+
+// scanFindings scans res and returns the findings that are not acknowledged in its override
+// file and the number of acknowledged ones. Acknowledgments matching no finding are reported,
+// they are left over from code that changed or was removed.
+func scanFindings(res *resource.Resource) ([]backdoor.Finding, int, error) {
+	findings, err := backdoor.Scan(res)
+	if err != nil {
+		return nil, 0, err
+	}
+	overrides, _, err := config.LoadResourceOverrides(res.BaseDir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	acknowledged := make(map[string]bool, len(overrides.ScanAcknowledge))
+	for _, id := range overrides.ScanAcknowledge {
+		acknowledged[id] = true
+	}
+	var pending []backdoor.Finding
+	matched := make(map[string]bool)
+	for _, finding := range findings {
+		if acknowledged[finding.ID()] {
+			matched[finding.ID()] = true
+			continue
+		}
+		pending = append(pending, finding)
+	}
+	for _, id := range overrides.ScanAcknowledge {
+		if !matched[id] {
+			slog.Warn("Acknowledged scan finding no longer found", "resource", res.Name, "id", id, "path", overrides.Path)
+		}
+	}
+	return pending, len(matched), nil
+}
@@ -0,0 +1,220 @@
+package bundler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// SourceMapExtension is appended to the name of a merged bundle to name its source map
+const SourceMapExtension = ".map"
+
+// SourceMapShimName is the compiled shared script translating bundle positions in error messages
+const SourceMapShimName = "mta_bundler_sourcemap.luac"
+
+// sourceMapVersion is the version of the source map format
+const sourceMapVersion = 1
+
+// SourceMap lists the scripts merged into a bundle, in order, with the lines each one covers
+type SourceMap struct {
+	Version int           `json:"version"`
+	Bundle  string        `json:"bundle"` // Path of the bundle in its resource, such as client.luac
+	Chunks  []SourceChunk `json:"chunks"`
+}
+
+// SourceChunk is a script merged into a bundle. Lines are numbered across the bundle, as if
+// the scripts were concatenated in order.
+type SourceChunk struct {
+	Src       string `json:"src"`        // Script path, relative to the resource for resources and to the input root for packs
+	FirstLine int    `json:"first_line"` // First bundle line of the script
+	LastLine  int    `json:"last_line"`  // Last bundle line of the script
+}
+
+// Translate returns the script and script line of a bundle line
+func (m SourceMap) Translate(line int) (string, int, bool) {
+	for _, chunk := range m.Chunks {
+		if line >= chunk.FirstLine && line <= chunk.LastLine {
+			return chunk.Src, line - chunk.FirstLine + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// sourceMapShimTemplate is the Lua source of the translation shim. It holds the chunks of the
+// bundles of its resource, reports the original position of errors raised in them and lets
+// scripts translate positions themselves.
+var sourceMapShimTemplate = template.Must(template.New("shim").Parse(`-- Generated by mta-bundler: translates positions in merged bundles to the original scripts
+local bundles = {
+{{- range .}}
+	[{{printf "%q" .Bundle}}] = {
+	{{- range .Chunks}}
+		{ {{.FirstLine}}, {{.LastLine}}, {{printf "%q" .Src}} },
+	{{- end}}
+	},
+{{- end}}
+}
+
+-- translateBundleLine returns the original script and line of a line of a merged bundle of
+-- this resource, or nil when the line is not in a bundle
+function translateBundleLine(bundle, line)
+	local chunks = bundles[bundle]
+	if not chunks or not line then
+		return nil
+	end
+	for _, chunk in ipairs(chunks) do
+		if line >= chunk[1] and line <= chunk[2] then
+			return chunk[3], line - chunk[1] + 1
+		end
+	end
+	return nil
+end
+
+-- translateTraceback replaces the bundle positions in an error message or traceback with the
+-- original positions
+function translateTraceback(text)
+	return (text:gsub("([%w_%-%./]+%.luac):(%d+)", function(path, line)
+		for bundle in pairs(bundles) do
+			if path == bundle or path:sub(-#bundle - 1) == "/" .. bundle then
+				local src, srcLine = translateBundleLine(bundle, tonumber(line))
+				if src then
+					return path:sub(1, #path - #bundle) .. src .. ":" .. srcLine
+				end
+			end
+		end
+	end))
+end
+
+local resourcePrefix = getResourceName(getThisResource()) .. "/"
+local translating = false
+
+addEventHandler(localPlayer and "onClientDebugMessage" or "onDebugMessage", root, function(message, level, file, line)
+	-- Only errors raised in this resource, never the message written below or the errors
+	-- isolated bundles already report at their original position
+	if translating or MTA_BUNDLER_ISOLATED or level ~= 1 or not file then
+		return
+	end
+	file = file:gsub("\\", "/")
+	local bundle
+	for name in pairs(bundles) do
+		if file:sub(-#resourcePrefix - #name) == resourcePrefix .. name then
+			bundle = name
+		end
+	end
+	if not bundle then
+		return
+	end
+	local src, srcLine = translateBundleLine(bundle, line)
+	if src then
+		translating = true
+		outputDebugString(resourcePrefix .. src .. ":" .. srcLine .. ": " .. translateTraceback(message), 3)
+		translating = false
+	end
+end)
+`))
+
+// writeSourceMaps writes a source map next to each merged bundle of compiled, and the
+// translation shim when enabled. Script paths are written relative to srcRoot. It returns the
+// paths of the files written.
+func (b Bundler) writeSourceMaps(compiled resource.CompileResult, srcRoot string) ([]string, error) {
+	if !b.options.SourceMaps || !compiled.MergeMode {
+		return nil, nil
+	}
+
+	var written []string
+	var maps []SourceMap
+	for _, result := range compiled.Compilation.Results {
+		if !result.Success || len(result.InputFiles) == 0 {
+			continue
+		}
+		bundle, err := filepath.Rel(compiled.OutputDir, result.OutputFile)
+		if err != nil || compiled.OutputDir == "" {
+			bundle = filepath.Base(result.OutputFile)
+		}
+		sourceMap, err := newSourceMap(filepath.ToSlash(bundle), result.InputFiles, srcRoot)
+		if err != nil {
+			return written, err
+		}
+		data, err := json.MarshalIndent(sourceMap, "", "  ")
+		if err != nil {
+			return written, fmt.Errorf("failed to encode source map: %v", err)
+		}
+		mapPath := result.OutputFile + SourceMapExtension
+		if err := os.WriteFile(mapPath, append(data, '\n'), 0644); err != nil {
+			return written, fmt.Errorf("failed to write source map: %v", err)
+		}
+		written = append(written, mapPath)
+		maps = append(maps, sourceMap)
+	}
+	if len(maps) == 0 || !b.options.MapShim {
+		return written, nil
+	}
+
+	shimPath, err := b.writeSourceMapShim(compiled.OutputDir, maps)
+	if err != nil {
+		return written, err
+	}
+	return append(written, shimPath), nil
+}
+
+// writeSourceMapShim compiles the translation shim for maps into outputDir and adds it to the
+// output meta.xml before the bundles, so it also reports errors raised while they load
+func (b Bundler) writeSourceMapShim(outputDir string, maps []SourceMap) (string, error) {
+	sort.Slice(maps, func(i, j int) bool { return maps[i].Bundle < maps[j].Bundle })
+
+	sourceFile, err := os.CreateTemp("", "mta-bundler-sourcemap-*.lua")
+	if err != nil {
+		return "", fmt.Errorf("failed to create source map shim: %v", err)
+	}
+	defer os.Remove(sourceFile.Name())
+
+	if err := sourceMapShimTemplate.Execute(sourceFile, maps); err != nil {
+		sourceFile.Close()
+		return "", fmt.Errorf("failed to generate source map shim: %v", err)
+	}
+	if err := sourceFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write source map shim: %v", err)
+	}
+
+	shimPath := filepath.Join(outputDir, SourceMapShimName)
+	if _, err := b.compiler.CompileFile(sourceFile.Name(), shimPath, b.options.Compilation); err != nil {
+		return "", fmt.Errorf("failed to compile source map shim: %v", err)
+	}
+	if err := resource.AddScript(filepath.Join(outputDir, "meta.xml"), SourceMapShimName, "shared"); err != nil {
+		return "", err
+	}
+
+	slog.Debug("Added source map shim", "dir", outputDir, "bundles", len(maps))
+	return shimPath, nil
+}
+
+// newSourceMap returns the source map of a bundle compiled from files, in order
+func newSourceMap(bundle string, files []string, srcRoot string) (SourceMap, error) {
+	sourceMap := SourceMap{Version: sourceMapVersion, Bundle: bundle}
+	line := 1
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return sourceMap, fmt.Errorf("failed to read %s for the source map: %v", file, err)
+		}
+		lines := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			lines++
+		}
+
+		src, err := filepath.Rel(srcRoot, file)
+		if err != nil {
+			src = file
+		}
+		// Empty scripts end before they start and cover no line
+		sourceMap.Chunks = append(sourceMap.Chunks, SourceChunk{Src: filepath.ToSlash(src), FirstLine: line, LastLine: line + lines - 1})
+		line += lines
+	}
+	return sourceMap, nil
+}
@@ -0,0 +1,68 @@
+package bundler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+func TestWriteSourceMaps(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+	shared := write("shared/config.lua", "a = 1\nb = 2\n")
+	empty := write("client/empty.lua", "")
+	hud := write("client/hud.lua", "local x\nlocal y\nprint(x)")
+	bundle := filepath.Join(dir, "out", "client.luac")
+	os.MkdirAll(filepath.Dir(bundle), 0755)
+
+	compiled := resource.CompileResult{MergeMode: true, OutputDir: filepath.Dir(bundle)}
+	compiled.Compilation.Results = []compiler.CompilationResult{
+		{Success: true, InputFiles: []string{shared, empty, hud}, OutputFile: bundle},
+		{Success: false, InputFiles: []string{hud}, OutputFile: filepath.Join(dir, "out", "server.luac")},
+	}
+
+	b := NewBundler(compiler.CLICompiler{}, Options{SourceMaps: true})
+	written, err := b.writeSourceMaps(compiled, dir)
+	if err != nil {
+		t.Fatalf("writeSourceMaps failed: %v", err)
+	}
+	if !reflect.DeepEqual(written, []string{bundle + SourceMapExtension}) {
+		t.Fatalf("Expected only the map of the compiled bundle, got %v", written)
+	}
+
+	var sourceMap SourceMap
+	data, _ := os.ReadFile(written[0])
+	if err := json.Unmarshal(data, &sourceMap); err != nil {
+		t.Fatalf("Invalid source map: %v", err)
+	}
+	expected := SourceMap{Version: sourceMapVersion, Bundle: "client.luac", Chunks: []SourceChunk{
+		{Src: "shared/config.lua", FirstLine: 1, LastLine: 2},
+		{Src: "client/empty.lua", FirstLine: 3, LastLine: 2},
+		{Src: "client/hud.lua", FirstLine: 3, LastLine: 5},
+	}}
+	if !reflect.DeepEqual(sourceMap, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sourceMap)
+	}
+
+	if src, line, ok := sourceMap.Translate(4); !ok || src != "client/hud.lua" || line != 2 {
+		t.Errorf("Expected line 4 to be client/hud.lua:2, got %s:%d (%t)", src, line, ok)
+	}
+	if _, _, ok := sourceMap.Translate(6); ok {
+		t.Error("Expected a line past the bundle not to translate")
+	}
+
+	compiled.MergeMode = false
+	if written, _ := NewBundler(compiler.CLICompiler{}, Options{SourceMaps: true}).writeSourceMaps(compiled, dir); len(written) > 0 {
+		t.Errorf("Expected no source map without merge mode, got %v", written)
+	}
+}
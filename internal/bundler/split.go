@@ -0,0 +1,296 @@
+package bundler
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// Split divides a resource into several output resources by folder
+type Split struct {
+	Resource string               // Resource name or path glob of the split resource
+	Parts    []resource.SplitPart // Resources split off it, the rest keeps the resource name
+}
+
+// splitFor returns the split applying to the resource at metaPath
+func (b Bundler) splitFor(metaPath string) (Split, bool) {
+	for _, split := range b.options.Splits {
+		if len(SelectResourceMetas(b.inputRoot(), []string{metaPath}, []string{split.Resource})) > 0 {
+			return split, true
+		}
+	}
+	return Split{}, false
+}
+
+// checkSplits fails when splits cannot be built: they require an output directory, and a part
+// must not be written over the output of a resource or be split off a packed resource
+func (b Bundler) checkSplits(metaPaths, packed []string) error {
+	if len(b.options.Splits) == 0 {
+		return nil
+	}
+	if b.options.OutputDir == "" {
+		return fmt.Errorf("splits require an output directory (-o)")
+	}
+
+	dirs := make(map[string]bool, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		dirs[filepath.Dir(metaPath)] = true
+	}
+	for _, metaPath := range packed {
+		if _, ok := b.splitFor(metaPath); ok {
+			return fmt.Errorf("resource at %s is both packed and split", filepath.Dir(metaPath))
+		}
+	}
+	for _, metaPath := range metaPaths {
+		split, ok := b.splitFor(metaPath)
+		if !ok {
+			continue
+		}
+		for _, part := range split.Parts {
+			if dir := filepath.Join(filepath.Dir(filepath.Dir(metaPath)), part.Name); dirs[dir] {
+				return fmt.Errorf("split part %q conflicts with the resource at %s", part.Name, dir)
+			}
+		}
+	}
+	return nil
+}
+
+// BuildSplit builds the resource at metaPath as several resources: one per part of split, next
+// to the resource output, and the resource itself with the remaining scripts and files. The
+// resource includes its parts, so starting it starts them, and forwards the exports that moved
+// to a part. Split resources are always rebuilt.
+func (b Bundler) BuildSplit(split Split, metaPath string) ResourceResult {
+	startTime := time.Now()
+	result := ResourceResult{MetaXMLPath: metaPath}
+
+	res, err := b.parseResource(metaPath)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	result.Resource = res
+	log := slog.With("resource", res.Name)
+
+	options, mergeMode, err := b.resourceOptions(res)
+	result.Options = options
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if err := b.applyVerbatim(res); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if err := b.lintResource(res); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if err := b.scanResource(res); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	res.SkipAssets = b.options.ScriptsOnly
+	res.LinkAssets = b.options.LinkAssets
+	res.NoCache = b.options.NoCache
+	res.RegexMeta = b.options.RegexMeta
+	res.MetaOrder = b.options.MetaOrder
+	res.Concat = b.options.Concat
+	res.Isolate = b.options.Isolate
+	res.BundleName = b.options.BundleName
+	res.Transform = b.sourceTransform()
+	res.Info = b.infoAttrs()
+	res.Banner = b.expand(b.options.Banner)
+	res.MinVersion = b.minVersion(options)
+	result.Attention = b.resourceAttention(res, options, mergeMode)
+	exportCases, err := b.checkExports(res, mergeMode)
+	result.Attention = append(result.Attention, exportCases...)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if overrides, ok, _ := config.LoadResourceOverrides(res.BaseDir); ok && len(overrides.Lazy) > 0 {
+		log.Warn("Lazy files are ignored in split resources", "path", overrides.Path)
+	}
+
+	outputDir, err := res.OutputDir(b.inputRoot(), b.options.OutputDir)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	// A manifest left by an earlier build would describe files the resource no longer has
+	if err := os.Remove(filepath.Join(outputDir, ManifestFileName)); err != nil && !os.IsNotExist(err) {
+		result.Error = fmt.Errorf("failed to remove build manifest: %v", err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	metaDir, err := os.MkdirTemp("", "mta-bundler-split-*")
+	if err != nil {
+		result.Error = fmt.Errorf("failed to create temporary directory: %v", err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	defer os.RemoveAll(metaDir)
+
+	mainRes, parts, owners, err := res.Split(split.Parts, metaDir)
+	if err != nil {
+		result.Error = fmt.Errorf("error splitting resource %s: %v", res.Name, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	// Files written per output directory, to clean and package each resource on its own
+	written := make(map[string][]string)
+	build := func(sub *resource.Resource, dir string) (resource.CompileResult, error) {
+		compiled, err := sub.CompileTo(b.compiler, dir, options, mergeMode)
+		addCompileResult(&result.Compile, compiled)
+		files := ResourceResult{Compile: compiled}.WrittenFiles()
+		written[dir] = append(written[dir], files...)
+		if err != nil {
+			return compiled, err
+		}
+		mapPaths, err := b.writeSourceMaps(compiled, res.BaseDir)
+		written[dir] = append(written[dir], mapPaths...)
+		result.Generated = append(result.Generated, mapPaths...)
+		return compiled, err
+	}
+
+	var partDirs, includes []string
+	for _, part := range parts {
+		partDir := filepath.Join(filepath.Dir(outputDir), part.Name)
+		if _, err := build(part, partDir); err != nil {
+			result.Error = fmt.Errorf("error compiling split part %s: %v", part.Name, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		if err := os.Remove(filepath.Join(partDir, ManifestFileName)); err != nil && !os.IsNotExist(err) {
+			result.Error = fmt.Errorf("failed to remove build manifest: %v", err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		partDirs = append(partDirs, partDir)
+		includes = append(includes, fmt.Sprintf(`<include resource="%s" />`, html.EscapeString(part.Name)))
+		result.Generated = append(result.Generated, filepath.Join(partDir, "meta.xml"))
+	}
+
+	compiled, err := build(mainRes, outputDir)
+	result.Compile.OutputDir = compiled.OutputDir
+	result.Compile.MergeMode = compiled.MergeMode
+	if err != nil {
+		result.Error = fmt.Errorf("error compiling resource %s: %v", res.Name, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	shimFiles, shimTags, err := b.writeSplitShims(res, outputDir, owners)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	written[outputDir] = append(written[outputDir], shimFiles...)
+	result.Generated = append(result.Generated, shimFiles...)
+	if err := resource.AppendMetaEntries(filepath.Join(outputDir, "meta.xml"), append(includes, shimTags...)); err != nil {
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	var archives []string
+	for _, dir := range append([]string{outputDir}, partDirs...) {
+		stampPath, err := b.stampOutput(dir)
+		if err != nil {
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		if stampPath != "" {
+			written[dir] = append(written[dir], stampPath)
+			result.Generated = append(result.Generated, stampPath)
+		}
+
+		if b.options.Clean {
+			removed, err := pruneDir(dir, written[dir])
+			if err != nil {
+				result.Error = fmt.Errorf("failed to clean output directory: %v", err)
+				result.Duration = time.Since(startTime)
+				return result
+			}
+			if len(removed) > 0 {
+				log.Info("Removed stale output files", "success", true, "dir", filepath.Base(dir), "count", len(removed))
+			}
+		}
+		if b.options.Zip {
+			archivePath, err := zipResource(dir, written[dir])
+			if err != nil {
+				result.Error = err
+				result.Duration = time.Since(startTime)
+				return result
+			}
+			log.Info("Packaged resource", "success", true, "archive", archivePath)
+			if dir == outputDir {
+				result.Archive = archivePath
+			} else {
+				archives = append(archives, archivePath)
+			}
+		}
+	}
+	if b.options.Zip {
+		// The part meta.xml files and shims were packaged with their resources
+		result.Generated = archives
+	}
+
+	result.Duration = time.Since(startTime)
+	log.Info("Compiled split resource", "success", true, "parts", len(parts), "duration", result.Duration)
+	return result
+}
+
+// writeSplitShims writes the shim scripts forwarding the exports of res that moved to a part
+// into outputDir. It returns the files written and their meta.xml script tags.
+func (b Bundler) writeSplitShims(res *resource.Resource, outputDir string, owners map[string]string) ([]string, []string, error) {
+	functions := map[string][]shimFunction{}
+	for _, export := range res.Meta.Exports {
+		part, ok := owners[export.Function]
+		if !ok {
+			continue
+		}
+		if !luaIdentifier.MatchString(export.Function) {
+			slog.Warn("Cannot forward export, not a Lua identifier", "resource", res.Name, "function", export.Function)
+			continue
+		}
+		for _, kind := range exportKinds(export) {
+			functions[kind] = append(functions[kind], shimFunction{Name: export.Function, Target: part})
+		}
+	}
+	return b.writeShimScripts(outputDir, "exports of "+res.Name+" moved to its split parts", functions)
+}
+
+// addCompileResult adds the compilation and file copy results of src to dst
+func addCompileResult(dst *resource.CompileResult, src resource.CompileResult) {
+	dst.Compilation.Results = append(dst.Compilation.Results, src.Compilation.Results...)
+	dst.Compilation.TotalFiles += src.Compilation.TotalFiles
+	dst.Compilation.SuccessCount += src.Compilation.SuccessCount
+	dst.Compilation.ErrorCount += src.Compilation.ErrorCount
+	dst.Compilation.TotalInputSize += src.Compilation.TotalInputSize
+	dst.Compilation.TotalOutputSize += src.Compilation.TotalOutputSize
+	dst.Compilation.TotalTime += src.Compilation.TotalTime
+
+	dst.FileCopy.Results = append(dst.FileCopy.Results, src.FileCopy.Results...)
+	dst.FileCopy.TotalFiles += src.FileCopy.TotalFiles
+	dst.FileCopy.SuccessCount += src.FileCopy.SuccessCount
+	dst.FileCopy.ErrorCount += src.FileCopy.ErrorCount
+	dst.FileCopy.TotalSize += src.FileCopy.TotalSize
+}
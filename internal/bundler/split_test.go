@@ -0,0 +1,85 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+func TestBuildSplit(t *testing.T) {
+	// Copies the source to the -o path, so the output shows what was compiled
+	comp := fakeCompiler(t, `out=; files=
+while [ $# -gt 0 ]; do case "$1" in -o) out="$2"; shift;; -*) ;; *) files="$files $1";; esac; shift; done
+cat $files > "$out"
+`)
+
+	inputDir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(inputDir, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("world/meta.xml", `<meta>
+    <script src="server.lua" type="server" />
+    <script src="client/models.lua" type="client" />
+    <file src="models/car.dff" />
+    <export function="getWorld" type="server" />
+    <export function="loadModels" type="client" />
+    <include resource="scoreboard" />
+</meta>`)
+	write("world/server.lua", "function getWorld() end\n")
+	write("world/client/models.lua", "function loadModels()\nend\n")
+	write("world/models/car.dff", "DFF")
+
+	outputDir := filepath.Join(t.TempDir(), "out")
+	b := NewBundler(comp, Options{InputPath: inputDir, OutputDir: outputDir, Splits: []Split{{
+		Resource: "world",
+		Parts: []resource.SplitPart{
+			{Name: "world_models", Folders: []string{"models"}},
+			{Name: "world_client", Folders: []string{"client"}},
+		},
+	}}})
+
+	result := b.BuildResource(filepath.Join(inputDir, "world", "meta.xml"))
+	if result.Error != nil {
+		t.Fatalf("BuildResource failed: %v", result.Error)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "world_models", "models", "car.dff")); err != nil {
+		t.Errorf("Expected the models in their part: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "world", "models")); !os.IsNotExist(err) {
+		t.Errorf("Expected the models to leave the main resource")
+	}
+
+	meta, _ := os.ReadFile(filepath.Join(outputDir, "world", "meta.xml"))
+	for _, want := range []string{`<include resource="world_models" />`, `<include resource="world_client" />`, `<script src="shim_client.luac" type="client" />`, `<export function="loadModels" type="client" />`} {
+		if !strings.Contains(string(meta), want) {
+			t.Errorf("Expected the main meta.xml to contain %q, got:\n%s", want, meta)
+		}
+	}
+	if strings.Contains(string(meta), "models.luac") || strings.Contains(string(meta), "car.dff") {
+		t.Errorf("Expected the part entries to leave the main meta.xml, got:\n%s", meta)
+	}
+
+	partMeta, _ := os.ReadFile(filepath.Join(outputDir, "world_client", "meta.xml"))
+	for _, want := range []string{`<script src="client/models.luac" type="client" />`, `<export function="loadModels" type="client" />`, `<include resource="scoreboard" />`} {
+		if !strings.Contains(string(partMeta), want) {
+			t.Errorf("Expected the part meta.xml to contain %q, got:\n%s", want, partMeta)
+		}
+	}
+	if strings.Contains(string(partMeta), "getWorld") {
+		t.Errorf("Expected getWorld to stay in the main resource, got:\n%s", partMeta)
+	}
+
+	shim, _ := os.ReadFile(filepath.Join(outputDir, "world", "shim_client.luac"))
+	if !strings.Contains(string(shim), `return exports["world_client"]:loadModels(...)`) {
+		t.Errorf("Expected the shim to forward loadModels, got:\n%s", shim)
+	}
+}
@@ -0,0 +1,148 @@
+package bundler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// StampScriptName is the compiled shared script holding the build constants of stamped resources
+const StampScriptName = "mta_bundler_stamp.luac"
+
+// StampStateFileName records the last build number stamped into an output directory
+const StampStateFileName = ".mta-bundler-stamp.json"
+
+// BuildStamp identifies a build in the resources it produced
+type BuildStamp struct {
+	Number int       `json:"build"`    // Build number, increasing with every stamped build
+	At     time.Time `json:"built_at"` // When the build started
+}
+
+// stampTemplate is the Lua source of the build constants script
+var stampTemplate = template.Must(template.New("stamp").Parse(`-- Generated by mta-bundler: build constants
+MTA_BUNDLER_BUILD = {{.Number}}
+MTA_BUNDLER_BUILT_AT = {{printf "%q" (.At.UTC.Format "2006-01-02 15:04:05 UTC")}}
+`))
+
+// ReserveBuildNumber returns the stamp of a new build to outputDir and records it, so the next
+// build gets a higher number. When number is 0 the build number following the last one is used,
+// otherwise number is used as is, for example a build number given by a CI system.
+func ReserveBuildNumber(outputDir string, number int) (BuildStamp, error) {
+	statePath := filepath.Join(outputDir, StampStateFileName)
+
+	var last BuildStamp
+	data, err := os.ReadFile(statePath)
+	if err != nil && !os.IsNotExist(err) {
+		return last, fmt.Errorf("failed to read build number: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &last); err != nil {
+			return last, fmt.Errorf("failed to parse %s: %v", statePath, err)
+		}
+	}
+
+	stamp := BuildStamp{Number: number, At: time.Now()}
+	if number == 0 {
+		stamp.Number = last.Number + 1
+	} else if number <= last.Number {
+		slog.Warn("Build number does not increase", "build", number, "last", last.Number)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return stamp, fmt.Errorf("failed to create output directory: %v", err)
+	}
+	data, err = json.MarshalIndent(stamp, "", "  ")
+	if err != nil {
+		return stamp, fmt.Errorf("failed to encode build number: %v", err)
+	}
+	if err := os.WriteFile(statePath, append(data, '\n'), 0644); err != nil {
+		return stamp, fmt.Errorf("failed to record build number: %v", err)
+	}
+	return stamp, nil
+}
+
+// expand returns text, the banner or an <info> attribute value of the output resources, with
+// {build} replaced by the stamped build number and {commit} by the commit of the build. The
+// placeholders of each resource are left to it.
+func (b Bundler) expand(text string) string {
+	replacements := []string{"{commit}", b.options.Commit}
+	if b.options.Stamp.Number != 0 {
+		replacements = append(replacements, "{build}", strconv.Itoa(b.options.Stamp.Number))
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
+}
+
+// infoAttrs returns the <info> attributes of the output resources, with the placeholders of the
+// build replaced
+func (b Bundler) infoAttrs() []resource.InfoAttr {
+	var attrs []resource.InfoAttr
+	for _, attr := range b.options.Info {
+		attr.Value = b.expand(attr.Value)
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// stampOutput stamps the build into the resource written to outputDir: the build number is set
+// on the <info> tag of its meta.xml and a shared script defines MTA_BUNDLER_BUILD and
+// MTA_BUNDLER_BUILT_AT. It returns the path of the script, or an empty string when stamping is
+// disabled.
+func (b Bundler) stampOutput(outputDir string) (string, error) {
+	stamp := b.options.Stamp
+	if stamp.Number == 0 {
+		return "", nil
+	}
+	// Resources without scripts are not written to the output
+	if _, err := os.Stat(filepath.Join(outputDir, "meta.xml")); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	sourceFile, err := os.CreateTemp("", "mta-bundler-stamp-*.lua")
+	if err != nil {
+		return "", fmt.Errorf("failed to create build constants script: %v", err)
+	}
+	defer os.Remove(sourceFile.Name())
+
+	if err := stampTemplate.Execute(sourceFile, stamp); err != nil {
+		sourceFile.Close()
+		return "", fmt.Errorf("failed to generate build constants script: %v", err)
+	}
+	if err := sourceFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write build constants script: %v", err)
+	}
+
+	scriptPath := filepath.Join(outputDir, StampScriptName)
+	if _, err := b.compiler.CompileFile(sourceFile.Name(), scriptPath, b.options.Compilation); err != nil {
+		return "", fmt.Errorf("failed to compile build constants script: %v", err)
+	}
+	if err := resource.StampMeta(filepath.Join(outputDir, "meta.xml"), stamp.Number, StampScriptName); err != nil {
+		return "", err
+	}
+
+	slog.Debug("Stamped build number", "dir", outputDir, "build", stamp.Number)
+	return scriptPath, nil
+}
+
+// stampResult stamps the build into the output directory of a resource result and records the
+// constants script as generated
+func (b Bundler) stampResult(result *ResourceResult) error {
+	scriptPath, err := b.stampOutput(result.Compile.OutputDir)
+	if err != nil || scriptPath == "" {
+		return err
+	}
+	for _, path := range result.Generated {
+		if path == scriptPath {
+			return nil
+		}
+	}
+	result.Generated = append(result.Generated, scriptPath)
+	return nil
+}
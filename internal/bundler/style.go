@@ -0,0 +1,77 @@
+package bundler
+
+import "os"
+
+// ANSI escape sequences used by Style
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBold   = "\x1b[1m"
+)
+
+// Style decides how meaningful parts of the console output (outcomes, warnings,
+// errors, size reductions) are rendered. The zero value renders plain text.
+type Style struct {
+	color bool
+}
+
+// NewStyle returns a style rendering ANSI colors when color is true
+func NewStyle(color bool) Style {
+	return Style{color: color}
+}
+
+// Color reports whether the style renders ANSI colors
+func (s Style) Color() bool {
+	return s.color
+}
+
+// Success renders text describing a successful outcome
+func (s Style) Success(text string) string {
+	return s.paint(ansiGreen, text)
+}
+
+// Failure renders text describing a failure or an error
+func (s Style) Failure(text string) string {
+	return s.paint(ansiRed, text)
+}
+
+// Warning renders text describing a warning
+func (s Style) Warning(text string) string {
+	return s.paint(ansiYellow, text)
+}
+
+// Emphasis renders text that should stand out, such as summaries
+func (s Style) Emphasis(text string) string {
+	return s.paint(ansiBold, text)
+}
+
+// paint wraps text in an escape sequence when colors are enabled
+func (s Style) paint(code, text string) string {
+	if !s.color || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// ColorEnabled reports whether colors should be written to f: it must be a terminal,
+// NO_COLOR must not be set (see https://no-color.org) and TERM must not be "dumb"
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return IsTerminal(f)
+}
+
+// IsTerminal reports whether f is an interactive terminal able to redraw lines
+func IsTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
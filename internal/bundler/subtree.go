@@ -0,0 +1,144 @@
+package bundler
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// FindSubtrees loads the config files nested below the input root, for monorepos where teams
+// own folders of resources with their own policies. Each one, named config.SubtreeFileName or
+// config.FileName, applies to the resources in its directory and below. Parents are returned
+// before their subdirectories, so applying them in order lets deeper files override shallower
+// ones. Directories the search for resources skips with options are not searched, nor are those
+// whose resources are all excluded by the exclude patterns or a nested file above them. The
+// config file at the input root itself is the project config and is not included.
+func FindSubtrees(inputPath string, options WalkOptions, exclude []string) ([]config.Config, error) {
+	root, err := filepath.Abs(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get absolute path: %v", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, nil
+	}
+	if !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+	filter, err := newDirFilter(root, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtrees []config.Config
+	err = filepath.WalkDir(root, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			slog.Warn("Cannot access path", "path", dir, "error", err)
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if dir == root {
+			if _, err := os.Stat(filepath.Join(dir, config.SubtreeFileName)); err == nil {
+				slog.Warn("Ignoring the subtree config file at the input root, the project config file is "+config.FileName, "dir", dir)
+			}
+			return nil
+		}
+		if reason := filter.reason(dir); reason != "" {
+			return filepath.SkipDir
+		}
+		if matchesDirPath(root, dir, exclude) || excludedDir(subtrees, dir) {
+			slog.Debug("Not searching excluded directory for config files", "dir", dir)
+			return filepath.SkipDir
+		}
+
+		path, err := subtreeFile(dir)
+		if err != nil || path == "" {
+			return err
+		}
+		subtree, err := config.LoadSubtree(path)
+		if err != nil {
+			return err
+		}
+		subtrees = append(subtrees, subtree)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subtrees, nil
+}
+
+// subtreeFile returns the path of the nested config file of dir, or an empty string when there is
+// none
+func subtreeFile(dir string) (string, error) {
+	var found []string
+	for _, name := range []string{config.SubtreeFileName, config.FileName} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			found = append(found, filepath.Join(dir, name))
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("%s has both %s and %s, keep one of them", dir, config.SubtreeFileName, config.FileName)
+}
+
+// excludedDir reports whether the nested config file of a subtree above dir excludes every
+// resource below dir
+func excludedDir(subtrees []config.Config, dir string) bool {
+	for _, subtree := range subtrees {
+		if dir != subtree.Dir() && isWithinDir(dir, subtree.Dir()) && matchesDirPath(subtree.Dir(), dir, subtree.Exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+// subtreesOf returns the nested config files applying to the resource in dir, shallowest first
+func (b Bundler) subtreesOf(dir string) []config.Config {
+	var subtrees []config.Config
+	for _, subtree := range b.options.Subtrees {
+		if isWithinDir(dir, subtree.Dir()) {
+			subtrees = append(subtrees, subtree)
+		}
+	}
+	return subtrees
+}
+
+// excludedBySubtree reports whether the nested config file of a subtree containing the resource
+// excludes it. Patterns are matched like the root exclude patterns, relative to the directory of
+// the file.
+func (b Bundler) excludedBySubtree(metaPath string) bool {
+	for _, subtree := range b.subtreesOf(filepath.Dir(metaPath)) {
+		if matchesResource(subtree.Dir(), metaPath, subtree.Exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeSubtrees removes the meta.xml paths excluded by the nested config files
+func (b Bundler) excludeSubtrees(metaPaths []string) []string {
+	if len(b.options.Subtrees) == 0 {
+		return metaPaths
+	}
+
+	var kept []string
+	for _, metaPath := range metaPaths {
+		if b.excludedBySubtree(metaPath) {
+			slog.Info("Skipping excluded resource", "meta", metaPath)
+			continue
+		}
+		kept = append(kept, metaPath)
+	}
+	return kept
+}
@@ -0,0 +1,112 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+func TestSubtrees(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	for _, dir := range []string{"shop", "[gameplay]/race", "[gameplay]/[legacy]/derby", "[gameplay]/[events]/halloween"} {
+		write(filepath.Join(dir, "meta.xml"), `<meta><script src="main.lua" type="server"/></meta>`)
+	}
+	write(filepath.Join("[gameplay]", config.FileName), "obfuscation: 3\nexclude: [\"[legacy]\"]\n")
+	write(filepath.Join("[gameplay]", "[events]", config.FileName), "obfuscation: 1\nmerge: true\n")
+	write(filepath.Join("[gameplay]", "[events]", "halloween", config.ResourceFileName), "strip_debug = false\n")
+
+	subtrees, err := FindSubtrees(root, WalkOptions{}, nil)
+	if err != nil {
+		t.Fatalf("FindSubtrees failed: %v", err)
+	}
+	b := NewBundler(compiler.CLICompiler{}, Options{
+		InputPath:   root,
+		Compilation: compiler.CompilationOptions{StripDebug: true},
+		Subtrees:    subtrees,
+	})
+
+	metaPaths, err := b.FindResources()
+	if err != nil {
+		t.Fatalf("FindResources failed: %v", err)
+	}
+	var names []string
+	for _, metaPath := range metaPaths {
+		names = append(names, filepath.Base(filepath.Dir(metaPath)))
+	}
+	if expected := []string{"halloween", "race", "shop"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected resources %v without the excluded subtree, got %v", expected, names)
+	}
+
+	tests := []struct {
+		dir       string
+		expected  compiler.CompilationOptions
+		mergeMode bool
+	}{
+		{"shop", compiler.CompilationOptions{StripDebug: true}, false},
+		{"[gameplay]/race", compiler.CompilationOptions{ObfuscationLevel: 3, StripDebug: true}, false},
+		{"[gameplay]/[events]/halloween", compiler.CompilationOptions{ObfuscationLevel: 1}, true},
+	}
+	for _, tt := range tests {
+		res := &resource.Resource{Name: filepath.Base(tt.dir), BaseDir: filepath.Join(root, tt.dir)}
+		options, mergeMode, err := b.resourceOptions(res)
+		if err != nil {
+			t.Fatalf("resourceOptions(%s) failed: %v", tt.dir, err)
+		}
+		if options != tt.expected || mergeMode != tt.mergeMode {
+			t.Errorf("%s: expected %+v merge=%t, got %+v merge=%t", tt.dir, tt.expected, tt.mergeMode, options, mergeMode)
+		}
+	}
+}
+
+func TestFindSubtrees(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write(config.FileName, "output: build\n")
+	write(filepath.Join("[gameplay]", config.SubtreeFileName), "obfuscation: 3\nexclude: [\"[wip]\"]\n")
+	write(filepath.Join("[gameplay]", "[race]", config.FileName), "merge: true\n")
+	write(filepath.Join("[admin]", config.SubtreeFileName), "strip_debug: false\nexclude: [\"legacy-*\"]\n")
+	// Folders the search for resources skips, and excluded ones, are not searched either
+	for _, dir := range []string{"node_modules/lib", ".git", "backup", "[disabled]/old", "[gameplay]/[wip]", "build"} {
+		write(filepath.Join(dir, config.SubtreeFileName), "output: elsewhere\n")
+	}
+
+	subtrees, err := FindSubtrees(root, WalkOptions{Ignore: []string{"backup"}, Skip: []string{filepath.Join(root, "build")}}, []string{"[disabled]"})
+	if err != nil {
+		t.Fatalf("FindSubtrees failed: %v", err)
+	}
+	var dirs []string
+	for _, subtree := range subtrees {
+		rel, _ := filepath.Rel(root, subtree.Dir())
+		dirs = append(dirs, filepath.ToSlash(rel))
+	}
+	expected := []string{"[admin]", "[gameplay]", "[gameplay]/[race]"}
+	if strings.Join(dirs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected subtrees %v (parents first, without the root config), got %v", expected, dirs)
+	}
+
+	write(filepath.Join("[gameplay]", config.SubtreeFileName), "obfuscation: 3\noutput: ../build\n")
+	if _, err := FindSubtrees(root, WalkOptions{}, []string{"[disabled]", "[gameplay]/[wip]"}); err == nil || !strings.Contains(err.Error(), "output") {
+		t.Errorf("Expected an error for a build-wide setting in a nested config, got %v", err)
+	}
+	write(filepath.Join("[gameplay]", config.FileName), "obfuscation: 3\n")
+	if _, err := FindSubtrees(root, WalkOptions{}, []string{"[disabled]", "[gameplay]/[wip]"}); err == nil || !strings.Contains(err.Error(), "both") {
+		t.Errorf("Expected an error for two nested config files in one folder, got %v", err)
+	}
+}
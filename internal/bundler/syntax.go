@@ -0,0 +1,84 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/davidbozo/mta-bundler/internal/bytecode"
+	"github.com/davidbozo/mta-bundler/internal/lua"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// scriptSyntaxError is a script of a resource that does not parse
+type scriptSyntaxError struct {
+	src string // Script src as written in meta.xml
+	err *lua.SyntaxError
+}
+
+// syntaxErrors parses every Lua script of res and returns the syntax errors, one at most per
+// script. Scripts that are already compiled are skipped, and so are missing ones, which the
+// build and the check report on their own. Scripts that cache knows to parse are not parsed
+// again, cache may be nil.
+func syntaxErrors(res *resource.Resource, cache *Cache) []scriptSyntaxError {
+	var errs []scriptSyntaxError
+	for _, fileRef := range res.GetLuaFiles() {
+		stamp, known := cache.checked(fileRef.FullPath)
+		if known {
+			continue
+		}
+		data, err := os.ReadFile(fileRef.FullPath)
+		if err != nil {
+			continue
+		}
+		if kind := bytecode.DetectKind(data); kind == bytecode.KindCompiled || kind == bytecode.KindObfuscated {
+			continue
+		}
+		if _, err := lua.Parse(data); err != nil {
+			errs = append(errs, scriptSyntaxError{src: fileRef.RelativePath, err: err.(*lua.SyntaxError)})
+			continue
+		}
+		cache.markChecked(fileRef.FullPath, stamp)
+	}
+	return errs
+}
+
+// checkSyntax parses the scripts of every resource before anything is compiled, unless
+// NoSyntaxCheck is set. Syntax errors are logged together and stop the build, so a broken
+// script does not leave an output tree where only some resources were rebuilt. Resources whose
+// meta.xml cannot be parsed are left to the build, which reports them.
+func (b Bundler) checkSyntax(metaPaths []string) error {
+	if b.options.NoSyntaxCheck {
+		return nil
+	}
+
+	failed, scripts := 0, 0
+	for _, metaPath := range metaPaths {
+		res, err := b.parseResource(metaPath)
+		if err != nil {
+			continue
+		}
+		errs := syntaxErrors(res, b.options.Cache)
+		for _, syntaxErr := range errs {
+			slog.Error("Syntax error", "resource", res.Name, "file", syntaxErr.src, "line", syntaxErr.err.Line,
+				"column", syntaxErr.err.Column, "error", syntaxErr.err.Message)
+		}
+		failed += len(errs)
+		scripts += len(res.GetLuaFiles())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scripts have syntax errors, nothing was built", failed, scripts)
+	}
+	slog.Debug("Checked script syntax", "scripts", scripts, "resources", len(metaPaths))
+	return nil
+}
+
+// syntaxProblems returns the syntax errors of res as problems of a check
+func syntaxProblems(res *resource.Resource) []resource.Problem {
+	var problems []resource.Problem
+	for _, syntaxErr := range syntaxErrors(res, nil) {
+		problems = append(problems, resource.Problem{Resource: res.Name, Src: syntaxErr.src,
+			Message: fmt.Sprintf("line %d, column %d: %s", syntaxErr.err.Line, syntaxErr.err.Column, syntaxErr.err.Message)})
+	}
+	return problems
+}
@@ -0,0 +1,55 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"good/meta.xml":   `<meta><script src="server.lua" type="server"/></meta>`,
+		"good/server.lua": "local x = 1\nreturn x\n",
+		"bad/meta.xml":    `<meta><script src="client.lua" type="client"/><script src="shared.lua" type="shared"/></meta>`,
+		"bad/client.lua":  "function f()\n  return 1\n",
+		"bad/shared.lua":  "print('ok')\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(t.TempDir(), "out")
+	comp := fakeCompiler(t, `while [ $# -gt 0 ]; do [ "$1" = "-o" ] && printf '\033LuaQ' > "$2"; shift; done`)
+
+	_, err := NewBundler(comp, Options{InputPath: root, OutputDir: output}).Run()
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 scripts have syntax errors") {
+		t.Fatalf("Expected a syntax error for 1 of 3 scripts, got %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be built, the output directory exists")
+	}
+
+	result, err := NewBundler(comp, Options{InputPath: root}).Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	var found []string
+	for _, problem := range result.Problems {
+		found = append(found, problem.String())
+	}
+	expected := "bad/client.lua: line 3, column 1: 'end' expected (to close 'function' at line 1) near '<eof>'"
+	if len(found) != 1 || found[0] != expected {
+		t.Errorf("Expected the problem %q, got %q", expected, found)
+	}
+
+	if _, err := NewBundler(comp, Options{InputPath: root, OutputDir: output, NoSyntaxCheck: true}).Run(); err != nil {
+		t.Errorf("Expected the build to run without the syntax check, got %v", err)
+	}
+}
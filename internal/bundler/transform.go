@@ -0,0 +1,163 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bytecode"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/transform"
+)
+
+// sourcePass is a rewrite of scripts before they are compiled
+type sourcePass struct {
+	name string
+	run  func(src []byte) ([]byte, error) // Rewrites one script
+	// Rewrites the scripts of a merged bundle together instead, leaving single scripts alone
+	bundle func(res *resource.Resource, bundle string, files []resource.FileReference, sources [][]byte)
+}
+
+// enabledPasses returns the enabled source passes, in the order they run
+func (b Bundler) enabledPasses() []sourcePass {
+	var passes []sourcePass
+	if b.options.FoldConstants || len(b.options.Constants) > 0 {
+		passes = append(passes, sourcePass{name: "fold-constants", run: func(src []byte) ([]byte, error) {
+			return transform.Fold(src, b.options.Constants)
+		}})
+	}
+	if b.options.TreeShake {
+		passes = append(passes, sourcePass{name: "tree-shake", bundle: shake})
+	}
+	if b.options.RenameLocals {
+		passes = append(passes, sourcePass{name: "rename-locals", run: transform.RenameLocals})
+	}
+	if b.options.Minify {
+		passes = append(passes, sourcePass{name: "minify", run: transform.Minify})
+	}
+	return passes
+}
+
+// sourcePasses returns the names of the enabled passes rewriting scripts before they are
+// compiled, in the order they run
+func (b Bundler) sourcePasses() []string {
+	var names []string
+	for _, pass := range b.enabledPasses() {
+		names = append(names, pass.name)
+	}
+	return names
+}
+
+// sourceTransform returns the transform applying the enabled source passes to scripts, nil
+// when none is enabled. Compiled scripts are left as they are, and so are scripts that do not
+// parse, so luac_mta reports their syntax errors against the original source.
+func (b Bundler) sourceTransform() resource.SourceTransform {
+	passes := b.enabledPasses()
+	if len(passes) == 0 {
+		return nil
+	}
+	return func(res *resource.Resource, bundle string, files []resource.FileReference, sources [][]byte) error {
+		// Scripts a pass failed on are left to the later passes as they are
+		skipped := make([]bool, len(files))
+		for i := range files {
+			skipped[i] = isBytecode(sources[i])
+		}
+		for _, pass := range passes {
+			if pass.bundle != nil {
+				if bundle != "" {
+					pass.bundle(res, bundle, files, sources)
+				}
+				continue
+			}
+			for i, fileRef := range files {
+				if skipped[i] {
+					continue
+				}
+				out, err := pass.run(sources[i])
+				if err != nil {
+					slog.Debug("Source pass skipped", "pass", pass.name, "file", fileRef.RelativePath, "error", err)
+					skipped[i] = true
+					continue
+				}
+				slog.Debug("Ran source pass", "pass", pass.name, "file", fileRef.RelativePath, "size", len(sources[i]), "new_size", len(out))
+				sources[i] = out
+			}
+		}
+		return nil
+	}
+}
+
+// shake removes the top-level functions of the merged bundle of res for side bundle that nothing
+// calls, and logs the functions removed. Exported functions and globals read by the scripts of
+// the side left out of the bundle are kept. The bundle is left as it is when one of the scripts
+// of its side cannot be analyzed: compiled, remote or reading globals by name.
+func shake(res *resource.Resource, bundle string, files []resource.FileReference, sources [][]byte) {
+	log := slog.With("resource", res.Name, "bundle", res.BundlePath(bundle))
+	keep, err := outsideReferences(res, bundle, files)
+	if err != nil {
+		log.Info("Unused functions kept", "reason", err)
+		return
+	}
+	keep = append(keep, res.ExportedFunctions(bundle)...)
+
+	names := make([]string, len(files))
+	for i, fileRef := range files {
+		if isBytecode(sources[i]) {
+			log.Info("Unused functions kept", "reason", fileRef.RelativePath+" is compiled")
+			return
+		}
+		names[i] = filepath.ToSlash(fileRef.RelativePath)
+	}
+	removed, err := transform.Shake(names, sources, keep)
+	if err != nil {
+		log.Info("Unused functions kept", "reason", err)
+		return
+	}
+	if len(removed) == 0 {
+		return
+	}
+	functions := make([]string, len(removed))
+	for i, r := range removed {
+		functions[i] = fmt.Sprintf("%s (%s:%d)", r.Name, r.Script, r.Line)
+	}
+	log.Info("Removed unused functions", "count", len(removed), "functions", strings.Join(functions, ", "))
+}
+
+// outsideReferences returns the globals read by the scripts of res running on side that are
+// not among files, the scripts of its bundle
+func outsideReferences(res *resource.Resource, side string, files []resource.FileReference) ([]string, error) {
+	bundled := make(map[string]bool)
+	for _, fileRef := range files {
+		bundled[filepath.ToSlash(fileRef.RelativePath)] = true
+	}
+	var references []string
+	for _, src := range res.SideScripts(side) {
+		if bundled[filepath.ToSlash(src)] {
+			continue
+		}
+		if resource.IsURL(src) {
+			return nil, fmt.Errorf("%s cannot be read", src)
+		}
+		data, err := os.ReadFile(filepath.Join(res.BaseDir, src))
+		if err != nil {
+			return nil, err
+		}
+		if isBytecode(data) {
+			return nil, fmt.Errorf("%s is compiled", src)
+		}
+		names, err := transform.References(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", src, err)
+		}
+		references = append(references, names...)
+	}
+	return references, nil
+}
+
+// isBytecode reports whether data is a compiled script rather than a source
+func isBytecode(data []byte) bool {
+	kind := bytecode.DetectKind(data)
+	return kind == bytecode.KindCompiled || kind == bytecode.KindObfuscated
+}
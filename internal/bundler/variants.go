@@ -0,0 +1,135 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// TimingScriptName is the client script added to every variant resource to log its load time
+const TimingScriptName = "mta_bundler_ab_timing.luac"
+
+// timingScriptTemplate measures the time between the first client script of a resource being
+// loaded and the resource start event, which fires once every client script has run
+var timingScriptTemplate = template.Must(template.New("timing").Parse(`-- Generated by mta-bundler for A/B testing, do not deploy to production
+local loadStartedAt = getTickCount()
+
+addEventHandler("onClientResourceStart", resourceRoot, function()
+	outputDebugString(string.format("[ab-test] %s loaded in %d ms", {{printf "%q" .}}, getTickCount() - loadStartedAt))
+end)
+`))
+
+// Variant is one side of an A/B build: a name used as resource suffix and the options it is built with
+type Variant struct {
+	Name    string                      // Suffix appended to resource names, e.g. "e3"
+	Options compiler.CompilationOptions // Compilation options of the variant
+}
+
+// VariantResult represents the outcome of building one resource as one variant
+type VariantResult struct {
+	Variant   Variant        // Variant the resource was built as
+	Name      string         // Resource name of the variant
+	OutputDir string         // Directory the variant resource was written to
+	Result    ResourceResult // Build result of the resource
+}
+
+// BuildVariants builds every resource in metaPaths once per variant. Each variant is written
+// to the output directory as a separate resource named <resource>_<variant>, so both can be
+// deployed side by side, and gets a client script logging its load time.
+func (b Bundler) BuildVariants(metaPaths []string, variants []Variant) []VariantResult {
+	var results []VariantResult
+	for _, variant := range variants {
+		stageDir := filepath.Join(b.options.OutputDir, ".mta-bundler-"+variant.Name)
+		if err := os.RemoveAll(stageDir); err != nil {
+			slog.Warn("Cannot clean staging directory", "dir", stageDir, "error", err)
+		}
+
+		stage := b
+		stage.options.OutputDir = stageDir
+		stage.options.Compilation = variant.Options
+
+		for _, metaPath := range metaPaths {
+			result := VariantResult{Variant: variant, Result: stage.BuildResource(metaPath)}
+			if result.Result.Error == nil {
+				result.Result.Error = b.finishVariant(&result, stageDir)
+			}
+			if result.Result.Error != nil {
+				slog.Error("Failed to build variant", "meta", metaPath, "variant", variant.Name, "error", result.Result.Error)
+			}
+			results = append(results, result)
+		}
+
+		if err := os.RemoveAll(stageDir); err != nil {
+			slog.Warn("Cannot remove staging directory", "dir", stageDir, "error", err)
+		}
+	}
+	return results
+}
+
+// finishVariant adds the timing script to a resource built in stageDir and moves it to its
+// variant name in the output directory
+func (b Bundler) finishVariant(result *VariantResult, stageDir string) error {
+	res := result.Result.Resource
+	log := slog.With("resource", res.Name)
+	if result.Result.Options.ObfuscationLevel != result.Variant.Options.ObfuscationLevel {
+		log.Warn("Resource overrides change the obfuscation level, variants are not comparable",
+			"variant", result.Variant.Name, "obfuscation", int(result.Result.Options.ObfuscationLevel))
+	}
+
+	builtDir := result.Result.Compile.OutputDir
+	if _, err := os.Stat(filepath.Join(builtDir, "meta.xml")); err == nil {
+		if err := b.writeTimingScript(res, builtDir, result.Variant, result.Result.Options); err != nil {
+			return err
+		}
+	} else {
+		log.Warn("Resource has no scripts, load time is not logged", "variant", result.Variant.Name)
+	}
+
+	rel, err := filepath.Rel(stageDir, builtDir)
+	if err != nil {
+		return fmt.Errorf("cannot get variant output path: %v", err)
+	}
+	result.Name = res.Name + "_" + result.Variant.Name
+	result.OutputDir = filepath.Join(b.options.OutputDir, filepath.Dir(rel), result.Name)
+
+	if err := os.MkdirAll(filepath.Dir(result.OutputDir), 0755); err != nil {
+		return fmt.Errorf("failed to create variant directory: %v", err)
+	}
+	if err := os.RemoveAll(result.OutputDir); err != nil {
+		return fmt.Errorf("failed to replace previous variant: %v", err)
+	}
+	if err := os.Rename(builtDir, result.OutputDir); err != nil {
+		return fmt.Errorf("failed to move variant: %v", err)
+	}
+
+	log.Info("Built variant", "variant", result.Variant.Name, "name", result.Name, "success", true)
+	return nil
+}
+
+// writeTimingScript compiles the load time script of a variant into outputDir and adds it
+// as the first client script of the resource
+func (b Bundler) writeTimingScript(res *resource.Resource, outputDir string, variant Variant, options compiler.CompilationOptions) error {
+	sourceFile, err := os.CreateTemp("", "mta-bundler-timing-*.lua")
+	if err != nil {
+		return fmt.Errorf("failed to create timing script: %v", err)
+	}
+	defer os.Remove(sourceFile.Name())
+
+	if err := timingScriptTemplate.Execute(sourceFile, res.Name+"_"+variant.Name); err != nil {
+		sourceFile.Close()
+		return fmt.Errorf("failed to generate timing script: %v", err)
+	}
+	if err := sourceFile.Close(); err != nil {
+		return fmt.Errorf("failed to write timing script: %v", err)
+	}
+
+	if _, err := b.compiler.CompileFile(sourceFile.Name(), filepath.Join(outputDir, TimingScriptName), options); err != nil {
+		return fmt.Errorf("failed to compile timing script: %v", err)
+	}
+	return resource.AddClientScript(filepath.Join(outputDir, "meta.xml"), TimingScriptName)
+}
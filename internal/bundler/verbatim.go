@@ -0,0 +1,61 @@
+package bundler
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// applyVerbatim marks the scripts of res matching the global, subtree or per-resource verbatim
+// patterns, so they are copied as source instead of compiled, and the scripts matching the
+// merge exclude patterns, so they keep their own .luac file in merge mode
+func (b Bundler) applyVerbatim(res *resource.Resource) error {
+	overrides, _, err := config.LoadResourceOverrides(res.BaseDir)
+	if err != nil {
+		return err
+	}
+
+	verbatim := append([]string{}, b.options.Verbatim...)
+	mergeExclude := append([]string{}, b.options.Unmerged...)
+	for _, subtree := range b.subtreesOf(res.BaseDir) {
+		verbatim = append(verbatim, subtree.Verbatim...)
+		mergeExclude = append(mergeExclude, subtree.MergeExclude...)
+	}
+	res.Verbatim = resourceScripts(res, "Verbatim", verbatim, overrides.Verbatim)
+	res.Unmerged = resourceScripts(res, "Merge exclude", mergeExclude, overrides.MergeExclude)
+	return nil
+}
+
+// resourceScripts returns the slash-separated srcs of the scripts of res matching the global
+// patterns or the patterns of its override file, warning about the latter when they match no
+// script. Global patterns are not reported, they rarely apply to every resource.
+func resourceScripts(res *resource.Resource, kind string, patterns, resourcePatterns []string) map[string]bool {
+	scripts := matchingScripts(res, patterns)
+	for _, pattern := range resourcePatterns {
+		matched := matchingScripts(res, []string{pattern})
+		if len(matched) == 0 {
+			slog.Warn(kind+" pattern matches no script", "resource", res.Name, "pattern", pattern)
+		}
+		for src := range matched {
+			scripts[src] = true
+		}
+	}
+	return scripts
+}
+
+// matchingScripts returns the slash-separated srcs of the scripts of res matching any pattern
+func matchingScripts(res *resource.Resource, patterns []string) map[string]bool {
+	matched := make(map[string]bool)
+	for _, script := range res.Meta.Scripts {
+		src := filepath.ToSlash(script.Src)
+		for _, pattern := range patterns {
+			if matchSrc(pattern, src) {
+				matched[src] = true
+				break
+			}
+		}
+	}
+	return matched
+}
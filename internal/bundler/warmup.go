@@ -0,0 +1,85 @@
+package bundler
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// warmupScript is the script compiled by the warmup check
+const warmupScript = "local warmup = 1\nreturn warmup\n"
+
+// luaSignature starts every precompiled Lua chunk
+var luaSignature = []byte("\x1bLua")
+
+// Warmup compiles a single test script before the build to check the whole toolchain: the
+// temporary directory, the luac_mta binary, the compilation options and the output directory.
+// It fails with a diagnostic for the broken part instead of every resource failing the same way.
+func (b Bundler) Warmup() error {
+	tempDir, err := os.MkdirTemp("", "mta-bundler-warmup-*")
+	if err != nil {
+		return fmt.Errorf("warmup failed: cannot create a temporary directory (check TMPDIR): %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sourcePath := filepath.Join(tempDir, "warmup.lua")
+	if err := os.WriteFile(sourcePath, []byte(warmupScript), 0644); err != nil {
+		return fmt.Errorf("warmup failed: cannot write to the temporary directory %s: %v", tempDir, err)
+	}
+
+	outputPath := filepath.Join(tempDir, "warmup.luac")
+	if _, err := b.compiler.CompileFile(sourcePath, outputPath, b.options.Compilation); err != nil {
+		if b.interrupted() {
+			return fmt.Errorf("build interrupted before any resource was built")
+		}
+		return b.diagnoseCompile(sourcePath, outputPath, err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		return fmt.Errorf("warmup failed: luac_mta reported success but wrote no output: %v", err)
+	}
+	if !bytes.HasPrefix(content, luaSignature) {
+		return fmt.Errorf("warmup failed: luac_mta output is not Lua bytecode, check that the binary is luac_mta")
+	}
+
+	if b.options.OutputDir != "" {
+		if err := checkWritable(b.options.OutputDir); err != nil {
+			return fmt.Errorf("warmup failed: output directory %s is not writable: %v", b.options.OutputDir, err)
+		}
+	}
+
+	slog.Debug("Compiler warmup succeeded")
+	return nil
+}
+
+// diagnoseCompile explains a failed warmup compilation. When the script compiles without
+// obfuscation, the binary is too old for the requested level rather than broken.
+func (b Bundler) diagnoseCompile(sourcePath, outputPath string, err error) error {
+	if b.options.Compilation.ObfuscationLevel != compiler.ObfuscationNone {
+		plain := b.options.Compilation
+		plain.ObfuscationLevel = compiler.ObfuscationNone
+		if _, plainErr := b.compiler.CompileFile(sourcePath, outputPath, plain); plainErr == nil {
+			return fmt.Errorf("warmup failed: luac_mta does not support obfuscation level %d, update the binary or lower -e: %v",
+				b.options.Compilation.ObfuscationLevel, err)
+		}
+	}
+	return fmt.Errorf("warmup failed: luac_mta cannot compile a test script: %v", err)
+}
+
+// checkWritable creates dir if needed and checks that files can be created in it
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".mta-bundler-warmup-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,65 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// fakeCompiler returns a compiler running a shell script as luac_mta
+func fakeCompiler(t *testing.T, script string) compiler.CLICompiler {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake compilers are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	comp, err := compiler.NewCLICompiler(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return comp
+}
+
+func TestWarmup(t *testing.T) {
+	// Writes a bytecode header to the -o path and rejects -e3 like old luac_mta versions
+	const oldCompiler = `for a in "$@"; do [ "$a" = "-e3" ] && exit 1; done
+while [ $# -gt 0 ]; do [ "$1" = "-o" ] && printf '\033LuaQ' > "$2"; shift; done
+`
+	tests := []struct {
+		name    string
+		script  string
+		level   compiler.ObfuscationLevel
+		wantErr string
+	}{
+		{"Working compiler", oldCompiler, compiler.ObfuscationEnhanced, ""},
+		{"Unsupported obfuscation", oldCompiler, compiler.ObfuscationMaximum, "does not support obfuscation level 3"},
+		{"Broken compiler", "exit 1\n", compiler.ObfuscationNone, "cannot compile a test script"},
+		{"Not bytecode", `while [ $# -gt 0 ]; do [ "$1" = "-o" ] && echo text > "$2"; shift; done` + "\n", compiler.ObfuscationNone, "not Lua bytecode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBundler(fakeCompiler(t, tt.script), Options{
+				OutputDir:   filepath.Join(t.TempDir(), "out"),
+				Compilation: compiler.CompilationOptions{ObfuscationLevel: tt.level},
+			})
+			err := b.Warmup()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected warmup to succeed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package bundler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits for more events before rebuilding.
+// Editors usually emit several events per save (truncate, write, chmod, rename).
+const watchDebounce = 300 * time.Millisecond
+
+// Watch monitors the input tree and recompiles only the affected resource (or only the
+// affected Lua file in individual mode) whenever a meta.xml or script file changes.
+// It blocks until the underlying watcher fails.
+func (b Bundler) Watch() error {
+	if b.options.OutputDir == "" {
+		return fmt.Errorf("watch mode requires an output directory (-o), in-place compilation would rewrite the watched sources")
+	}
+
+	rootDir, err := b.watchRoot()
+	if err != nil {
+		return err
+	}
+
+	outputDir, err := filepath.Abs(b.options.OutputDir)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute output path: %v", err)
+	}
+
+	metaPaths, err := b.FindResources()
+	if err != nil {
+		return err
+	}
+
+	resources := make(map[string]*resource.Resource, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		res, err := b.parseResource(metaPath)
+		if err != nil {
+			slog.Warn("Cannot parse resource", "meta", metaPath, "error", err)
+			continue
+		}
+		resources[res.MetaXMLPath] = res
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	if err := addWatchDirs(watcher, rootDir, outputDir); err != nil {
+		return err
+	}
+
+	slog.Info("Watching for changes (press Ctrl+C to stop)", "dir", rootDir)
+
+	pending := make(map[string]fsnotify.Op)
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			absPath, err := filepath.Abs(event.Name)
+			if err != nil || isWithinDir(absPath, outputDir) {
+				continue
+			}
+
+			// Newly created directories need to be watched as well
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+					if err := addWatchDirs(watcher, absPath, outputDir); err != nil {
+						slog.Warn("Cannot watch new directory", "path", absPath, "error", err)
+					}
+					continue
+				}
+			}
+
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			pending[absPath] |= event.Op
+			timer.Reset(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("Watcher error", "error", err)
+
+		case <-timer.C:
+			b.rebuildChanged(resources, pending)
+			b.updateChecksums()
+			pending = make(map[string]fsnotify.Op)
+		}
+	}
+}
+
+// watchRoot returns the absolute directory that should be monitored for the input path
+func (b Bundler) watchRoot() (string, error) {
+	absInput, err := filepath.Abs(b.options.InputPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot get absolute input path: %v", err)
+	}
+
+	info, err := os.Stat(absInput)
+	if err != nil {
+		return "", fmt.Errorf("cannot access input path '%s': %v", b.options.InputPath, err)
+	}
+
+	if info.IsDir() {
+		return absInput, nil
+	}
+	return filepath.Dir(absInput), nil
+}
+
+// rebuildChanged recompiles the resources and scripts affected by a batch of changed paths
+func (b Bundler) rebuildChanged(resources map[string]*resource.Resource, changes map[string]fsnotify.Op) {
+	rebuildMetas := make(map[string]bool)
+	changedScripts := make(map[string][]resource.FileReference)
+
+	for path, op := range changes {
+		if strings.ToLower(filepath.Base(path)) == "meta.xml" {
+			if op.Has(fsnotify.Remove) || op.Has(fsnotify.Rename) {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					delete(resources, path)
+					slog.Info("Resource removed", "meta", path)
+					continue
+				}
+			}
+			if b.isWatchedMeta(path) {
+				rebuildMetas[path] = true
+			}
+			continue
+		}
+
+		res := resourceForPath(resources, path)
+		if res == nil {
+			continue
+		}
+
+		// Changed overrides affect every script of the resource
+		if filepath.Base(path) == config.ResourceFileName && filepath.Dir(path) == res.BaseDir {
+			rebuildMetas[res.MetaXMLPath] = true
+			continue
+		}
+
+		fileRef, ok := res.FindLuaFile(path)
+		// Scripts added to or removed from a src with wildcards change the meta.xml of the output
+		if res.MatchesGlob(path) && (!ok || op.Has(fsnotify.Remove) || op.Has(fsnotify.Rename)) {
+			rebuildMetas[res.MetaXMLPath] = true
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// Verbatim scripts are copied with the rest of the resource files
+		if res.IsVerbatim(fileRef.RelativePath) {
+			rebuildMetas[res.MetaXMLPath] = true
+			continue
+		}
+
+		_, mergeMode, err := b.resourceOptions(res)
+		if err != nil {
+			slog.Error("Failed to process resource", "meta", res.MetaXMLPath, "error", err)
+			continue
+		}
+
+		// Scripts of split resources are compiled into the output of their part
+		if _, split := b.splitFor(res.MetaXMLPath); mergeMode || split {
+			rebuildMetas[res.MetaXMLPath] = true
+		} else {
+			changedScripts[res.MetaXMLPath] = append(changedScripts[res.MetaXMLPath], fileRef)
+		}
+	}
+
+	// Changes to packed resources rebuild their whole pack
+	if len(b.options.Packs) > 0 {
+		known := make(map[string]bool, len(resources)+len(rebuildMetas))
+		for metaPath := range resources {
+			known[metaPath] = true
+		}
+		for metaPath := range rebuildMetas {
+			known[metaPath] = true
+		}
+		_, packMembers := b.assignPacks(sortedKeys(known))
+		for i, pack := range b.options.Packs {
+			changed := false
+			for _, metaPath := range packMembers[i] {
+				changed = changed || rebuildMetas[metaPath] || len(changedScripts[metaPath]) > 0
+				delete(rebuildMetas, metaPath)
+				delete(changedScripts, metaPath)
+			}
+			if !changed {
+				continue
+			}
+			slog.Info("Change detected, rebuilding pack", "pack", pack.Name)
+			if result := b.BuildPack(pack, packMembers[i]); result.Error != nil {
+				slog.Error("Failed to process pack", "pack", pack.Name, "error", result.Error)
+			}
+		}
+	}
+
+	for _, metaPath := range sortedKeys(rebuildMetas) {
+		slog.Info("Change detected, rebuilding resource", "meta", metaPath)
+		result := b.BuildResource(metaPath)
+		if result.Resource != nil {
+			resources[metaPath] = result.Resource
+		}
+		if result.Error != nil {
+			slog.Error("Failed to process resource", "meta", metaPath, "error", result.Error)
+		}
+	}
+
+	for metaPath, fileRefs := range changedScripts {
+		// A full rebuild already covered these scripts
+		if rebuildMetas[metaPath] {
+			continue
+		}
+
+		res := resources[metaPath]
+		options, _, err := b.resourceOptions(res)
+		if err != nil {
+			slog.Error("Failed to process resource", "meta", metaPath, "error", err)
+			continue
+		}
+
+		slog.Info("Change detected, recompiling scripts", "resource", res.Name, "count", len(fileRefs))
+		for _, fileRef := range fileRefs {
+			// Failures are logged by CompileScript
+			res.CompileScript(b.compiler, b.inputRoot(), b.options.OutputDir, options, fileRef)
+		}
+	}
+}
+
+// isWatchedMeta reports whether a meta.xml path belongs to the configured input
+func (b Bundler) isWatchedMeta(metaPath string) bool {
+	info, err := os.Stat(b.options.InputPath)
+	if err != nil {
+		return false
+	}
+
+	absInput, err := filepath.Abs(b.options.InputPath)
+	if err != nil {
+		return false
+	}
+
+	if info.IsDir() {
+		if matchesResource(absInput, metaPath, b.options.Exclude) || b.excludedBySubtree(metaPath) {
+			return false
+		}
+		return len(b.options.Only) == 0 || matchesResource(absInput, metaPath, b.options.Only)
+	}
+	return filepath.Clean(absInput) == filepath.Clean(metaPath)
+}
+
+// resourceForPath returns the resource whose base directory contains path.
+// When resources are nested, the deepest base directory wins.
+func resourceForPath(resources map[string]*resource.Resource, path string) *resource.Resource {
+	var match *resource.Resource
+	for _, res := range resources {
+		if !isWithinDir(path, res.BaseDir) {
+			continue
+		}
+		if match == nil || len(res.BaseDir) > len(match.BaseDir) {
+			match = res
+		}
+	}
+	return match
+}
+
+// addWatchDirs adds root and all of its subdirectories to the watcher, skipping the output directory
+func addWatchDirs(watcher *fsnotify.Watcher, root, outputDir string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			slog.Warn("Cannot access path", "path", path, "error", err)
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if isWithinDir(path, outputDir) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// isWithinDir reports whether path is dir itself or located below it
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// sortedKeys returns the keys of a set in sorted order for deterministic processing
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
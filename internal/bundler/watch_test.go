@@ -0,0 +1,45 @@
+package bundler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+func TestResourceForPath(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "srv", "resources")
+	outer := &resource.Resource{Name: "outer", BaseDir: filepath.Join(root, "outer"), MetaXMLPath: filepath.Join(root, "outer", "meta.xml")}
+	inner := &resource.Resource{Name: "inner", BaseDir: filepath.Join(root, "outer", "inner"), MetaXMLPath: filepath.Join(root, "outer", "inner", "meta.xml")}
+	other := &resource.Resource{Name: "outer2", BaseDir: filepath.Join(root, "outer2"), MetaXMLPath: filepath.Join(root, "outer2", "meta.xml")}
+
+	resources := map[string]*resource.Resource{
+		outer.MetaXMLPath: outer,
+		inner.MetaXMLPath: inner,
+		other.MetaXMLPath: other,
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Top-level script", filepath.Join(root, "outer", "client.lua"), "outer"},
+		{"Nested resource wins", filepath.Join(root, "outer", "inner", "server.lua"), "inner"},
+		{"Sibling with common prefix", filepath.Join(root, "outer2", "utils", "helper.lua"), "outer2"},
+		{"Outside every resource", filepath.Join(root, "loose.lua"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := resourceForPath(resources, tt.path)
+			name := ""
+			if res != nil {
+				name = res.Name
+			}
+			if name != tt.expected {
+				t.Errorf("resourceForPath(%q) = %q, expected %q", tt.path, name, tt.expected)
+			}
+		})
+	}
+}
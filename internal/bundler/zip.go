@@ -0,0 +1,109 @@
+package bundler
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ZipExtension is the file extension of zipped resources
+const ZipExtension = ".zip"
+
+// zipModTime is the modification time of every archive entry, the earliest a zip can record
+var zipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// zipOutput packages the output directory of a built resource into <name>.zip next to it, with
+// meta.xml at the archive root, then removes the directory. Only the files the build wrote are
+// packaged, so files left by earlier builds are never shipped.
+func (b Bundler) zipOutput(result *ResourceResult, files []string) error {
+	dir := result.Compile.OutputDir
+	archivePath, err := zipResource(dir, files)
+	if err != nil {
+		return err
+	}
+	result.Archive = archivePath
+
+	slog.Info("Packaged resource", "resource", filepath.Base(dir), "success", true, "archive", archivePath)
+	return nil
+}
+
+// zipResource writes the files below dir into dir.zip and removes dir. Nested resources in
+// subdirectories of dir are left in place. It returns the path of the archive.
+func zipResource(dir string, files []string) (string, error) {
+	archivePath := filepath.Clean(dir) + ZipExtension
+
+	entries := make(map[string]string, len(files))
+	names := make([]string, 0, len(files))
+	for _, path := range files {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return "", fmt.Errorf("cannot package %s: %v", path, err)
+		}
+		name := filepath.ToSlash(rel)
+		if _, ok := entries[name]; !ok {
+			names = append(names, name)
+		}
+		entries[name] = path
+	}
+	sort.Strings(names)
+
+	// The archive is written under a temporary name, so a failed build never leaves a partial
+	// archive where the server would load it
+	out, err := os.CreateTemp(filepath.Dir(archivePath), ".mta-bundler-zip-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %v", err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, name := range names {
+		if err := addZipEntry(zw, name, entries[name]); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finish archive: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+	if err := os.Rename(out.Name(), archivePath); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+
+	if _, err := pruneDir(dir, nil); err != nil {
+		return archivePath, fmt.Errorf("failed to remove packaged output directory: %v", err)
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+	return archivePath, nil
+}
+
+// addZipEntry copies the file at path into the archive as name. Entries share a fixed
+// modification time, so identical builds produce identical archives.
+func addZipEntry(zw *zip.Writer, name, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer in.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: zipModTime})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %v", name, err)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("failed to add %s to archive: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package bundler
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestZipResource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "race")
+	for path, content := range map[string]string{
+		"meta.xml":          "<meta />",
+		"client.luac":       "compiled",
+		"img/logo.png":      "PNG",
+		"stale.luac":        "left by an earlier build",
+		"nested/meta.xml":   "<meta />",
+		"nested/server.lua": "-- separate resource",
+	} {
+		path = filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := []string{filepath.Join(dir, "meta.xml"), filepath.Join(dir, "client.luac"), filepath.Join(dir, "img", "logo.png")}
+	archivePath, err := zipResource(dir, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := dir + ".zip"; archivePath != want {
+		t.Errorf("archive path = %s, want %s", archivePath, want)
+	}
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, entry := range zr.File {
+		names = append(names, entry.Name)
+	}
+	if want := []string{"client.luac", "img/logo.png", "meta.xml"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "client.luac")); !os.IsNotExist(err) {
+		t.Errorf("packaged files were not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested", "server.lua")); err != nil {
+		t.Errorf("nested resource was removed: %v", err)
+	}
+}
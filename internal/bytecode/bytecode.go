@@ -0,0 +1,455 @@
+package bytecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+const (
+	// CompiledMarker is the first byte of a plain Lua bytecode chunk (ESC)
+	CompiledMarker byte = 0x1B
+	// ObfuscatedMarker is the first byte MTA writes for obfuscated (encrypted) chunks
+	ObfuscatedMarker byte = 0x1C
+
+	// LuaVersion51 is the version byte of Lua 5.1 chunks, the only version MTA loads
+	LuaVersion51 byte = 0x51
+
+	// headerSize is the size of a Lua 5.1 chunk header in bytes
+	headerSize = 12
+)
+
+// luaSignature is the signature every plain Lua bytecode chunk starts with
+var luaSignature = []byte("\x1bLua")
+
+// Kind describes what a file looks like from its leading bytes
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindSource
+	KindCompiled
+	KindObfuscated
+)
+
+// String returns a human-readable name for the kind
+func (k Kind) String() string {
+	switch k {
+	case KindSource:
+		return "plain Lua source"
+	case KindCompiled:
+		return "Lua bytecode"
+	case KindObfuscated:
+		return "MTA obfuscated bytecode"
+	default:
+		return "unknown"
+	}
+}
+
+// Header holds the fields of a Lua 5.1 chunk header
+type Header struct {
+	Version         byte // Lua version (0x51 for Lua 5.1)
+	Format          byte // Format version (0 is the official format)
+	LittleEndian    bool // Byte order of the chunk
+	SizeInt         byte // sizeof(int)
+	SizeSizeT       byte // sizeof(size_t)
+	SizeInstruction byte // sizeof(Instruction)
+	SizeNumber      byte // sizeof(lua_Number)
+	IntegralNumbers bool // Whether lua_Number is an integral type
+}
+
+// Stats holds counters collected while walking the function prototypes of a chunk
+type Stats struct {
+	Functions      int    // Number of function prototypes (including the main chunk)
+	Instructions   int    // Total number of VM instructions
+	Constants      int    // Total number of constants
+	Strings        int    // Number of string constants
+	LineInfo       int    // Number of line info entries (0 when stripped)
+	Locals         int    // Number of local variable debug entries (0 when stripped)
+	Upvalues       int    // Number of upvalue name debug entries (0 when stripped)
+	Source         string // Source name of the main chunk (empty when stripped)
+	MaxStackSize   int    // Largest stack size required by any function
+	MaxParameters  int    // Largest parameter count of any function
+	VarargFunction int    // Number of vararg functions
+}
+
+// Info is the result of inspecting a compiled Lua file
+type Info struct {
+	Path        string   // Path of the inspected file
+	Size        int64    // File size in bytes
+	Kind        Kind     // What the file looks like
+	Header      Header   // Parsed header (only valid for KindCompiled)
+	Stats       Stats    // Prototype statistics (only valid for KindCompiled)
+	Stripped    bool     // Whether debug information has been stripped
+	Diagnostics []string // Problems that would prevent MTA from loading the chunk
+}
+
+// Obfuscated reports whether the chunk carries the MTA obfuscation marker
+func (i Info) Obfuscated() bool {
+	return i.Kind == KindObfuscated
+}
+
+// InspectFile reads and inspects a compiled Lua file
+func InspectFile(path string) (Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	info, err := Inspect(data)
+	info.Path = path
+	return info, err
+}
+
+// Inspect inspects compiled Lua data and returns header information and statistics
+func Inspect(data []byte) (Info, error) {
+	info := Info{
+		Size: int64(len(data)),
+		Kind: DetectKind(data),
+	}
+
+	switch info.Kind {
+	case KindObfuscated:
+		// Everything after the marker is encrypted, so only the marker can be checked
+		info.Diagnostics = append(info.Diagnostics, "chunk is encrypted by luac_mta, header and debug information cannot be read")
+		return info, nil
+	case KindSource:
+		info.Diagnostics = append(info.Diagnostics, "file is not compiled, it looks like plain Lua source")
+		return info, nil
+	case KindUnknown:
+		info.Diagnostics = append(info.Diagnostics, "file does not start with a Lua bytecode signature (this causes \"bad header in precompiled chunk\")")
+		return info, nil
+	}
+
+	header, err := parseHeader(data)
+	if err != nil {
+		return info, err
+	}
+	info.Header = header
+	info.Diagnostics = append(info.Diagnostics, header.diagnose()...)
+
+	// Only the standard layout can be walked safely
+	if header.Version != LuaVersion51 || header.Format != 0 {
+		return info, nil
+	}
+
+	r := &chunkReader{data: data[headerSize:], header: header}
+	if err := r.readFunction(&info.Stats, true); err != nil {
+		info.Diagnostics = append(info.Diagnostics, fmt.Sprintf("chunk is truncated or corrupt: %v", err))
+		return info, nil
+	}
+	if len(r.data) > 0 {
+		info.Diagnostics = append(info.Diagnostics, fmt.Sprintf("%d trailing byte(s) after the main function", len(r.data)))
+	}
+
+	info.Stripped = info.Stats.Source == "" && info.Stats.LineInfo == 0 && info.Stats.Locals == 0
+	return info, nil
+}
+
+// DetectKind classifies data from its leading bytes
+func DetectKind(data []byte) Kind {
+	if len(data) == 0 {
+		return KindUnknown
+	}
+
+	switch {
+	case data[0] == ObfuscatedMarker:
+		return KindObfuscated
+	case bytes.HasPrefix(data, luaSignature):
+		return KindCompiled
+	case data[0] == CompiledMarker:
+		return KindUnknown
+	}
+
+	// Plain sources are text; treat anything without control bytes at the start as source
+	probe := data
+	if len(probe) > 512 {
+		probe = probe[:512]
+	}
+	for _, b := range probe {
+		if b < 0x09 || (b > 0x0D && b < 0x20) {
+			return KindUnknown
+		}
+	}
+	return KindSource
+}
+
+// parseHeader parses the 12 byte Lua 5.1 chunk header
+func parseHeader(data []byte) (Header, error) {
+	if len(data) < headerSize {
+		return Header{}, fmt.Errorf("chunk header is truncated (%d bytes)", len(data))
+	}
+
+	return Header{
+		Version:         data[4],
+		Format:          data[5],
+		LittleEndian:    data[6] == 1,
+		SizeInt:         data[7],
+		SizeSizeT:       data[8],
+		SizeInstruction: data[9],
+		SizeNumber:      data[10],
+		IntegralNumbers: data[11] != 0,
+	}, nil
+}
+
+// diagnose returns header problems that would make MTA reject the chunk
+func (h Header) diagnose() []string {
+	var problems []string
+
+	if h.Version != LuaVersion51 {
+		problems = append(problems, fmt.Sprintf("Lua version %d.%d is not supported by MTA (expects 5.1)", h.Version>>4, h.Version&0x0F))
+	}
+	if h.Format != 0 {
+		problems = append(problems, fmt.Sprintf("non-standard chunk format %d", h.Format))
+	}
+	if !h.LittleEndian {
+		problems = append(problems, "big-endian chunk, MTA expects little-endian bytecode")
+	}
+	if h.SizeInt != 4 || h.SizeInstruction != 4 {
+		problems = append(problems, fmt.Sprintf("unexpected int/instruction size %d/%d (MTA expects 4/4)", h.SizeInt, h.SizeInstruction))
+	}
+	if h.SizeSizeT != 4 {
+		problems = append(problems, fmt.Sprintf("size_t is %d bytes, chunk was compiled by a 64-bit luac (MTA expects 4, use luac_mta)", h.SizeSizeT))
+	}
+	if h.SizeNumber != 8 || h.IntegralNumbers {
+		problems = append(problems, "lua_Number is not a double, chunk was compiled by a non-standard luac")
+	}
+
+	return problems
+}
+
+// chunkReader sequentially decodes the body of a Lua 5.1 chunk
+type chunkReader struct {
+	data   []byte
+	header Header
+}
+
+// next consumes n bytes
+func (r *chunkReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of chunk")
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
+
+// readUint reads an unsigned integer of the given size using the chunk byte order
+func (r *chunkReader) readUint(size byte) (uint64, error) {
+	b, err := r.next(int(size))
+	if err != nil {
+		return 0, err
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if r.header.LittleEndian {
+		order = binary.LittleEndian
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(order.Uint16(b)), nil
+	case 4:
+		return uint64(order.Uint32(b)), nil
+	case 8:
+		return order.Uint64(b), nil
+	default:
+		return 0, fmt.Errorf("unsupported integer size %d", size)
+	}
+}
+
+// readCount reads an int used as an element count
+func (r *chunkReader) readCount() (int, error) {
+	v, err := r.readUint(r.header.SizeInt)
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(len(r.data)) {
+		return 0, fmt.Errorf("count %d exceeds remaining chunk size", v)
+	}
+	return int(v), nil
+}
+
+// readString reads a size_t prefixed string (the length includes the trailing NUL)
+func (r *chunkReader) readString() (string, error) {
+	size, err := r.readUint(r.header.SizeSizeT)
+	if err != nil {
+		return "", err
+	}
+	if size == 0 {
+		return "", nil
+	}
+	if size > uint64(len(r.data)) {
+		return "", fmt.Errorf("string length %d exceeds remaining chunk size", size)
+	}
+	b, err := r.next(int(size))
+	if err != nil {
+		return "", err
+	}
+	return string(b[:len(b)-1]), nil
+}
+
+// readFunction decodes a function prototype and its nested prototypes, updating stats
+func (r *chunkReader) readFunction(stats *Stats, main bool) error {
+	source, err := r.readString()
+	if err != nil {
+		return err
+	}
+	if main {
+		stats.Source = source
+	}
+	stats.Functions++
+
+	// linedefined, lastlinedefined
+	if _, err := r.next(2 * int(r.header.SizeInt)); err != nil {
+		return err
+	}
+
+	// nups, numparams, is_vararg, maxstacksize
+	fields, err := r.next(4)
+	if err != nil {
+		return err
+	}
+	stats.MaxParameters = max(stats.MaxParameters, int(fields[1]))
+	if fields[2] != 0 {
+		stats.VarargFunction++
+	}
+	stats.MaxStackSize = max(stats.MaxStackSize, int(fields[3]))
+
+	// Code
+	sizeCode, err := r.readCount()
+	if err != nil {
+		return err
+	}
+	if _, err := r.next(sizeCode * int(r.header.SizeInstruction)); err != nil {
+		return err
+	}
+	stats.Instructions += sizeCode
+
+	// Constants
+	sizeK, err := r.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < sizeK; i++ {
+		t, err := r.next(1)
+		if err != nil {
+			return err
+		}
+		switch t[0] {
+		case 0: // nil
+		case 1: // boolean
+			_, err = r.next(1)
+		case 3: // number
+			_, err = r.next(int(r.header.SizeNumber))
+		case 4: // string
+			_, err = r.readString()
+			stats.Strings++
+		default:
+			err = fmt.Errorf("unknown constant type %d", t[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	stats.Constants += sizeK
+
+	// Nested prototypes
+	sizeP, err := r.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < sizeP; i++ {
+		if err := r.readFunction(stats, false); err != nil {
+			return err
+		}
+	}
+
+	// Debug information: line info
+	sizeLineInfo, err := r.readCount()
+	if err != nil {
+		return err
+	}
+	if _, err := r.next(sizeLineInfo * int(r.header.SizeInt)); err != nil {
+		return err
+	}
+	stats.LineInfo += sizeLineInfo
+
+	// Debug information: local variables
+	sizeLocVars, err := r.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < sizeLocVars; i++ {
+		if _, err := r.readString(); err != nil {
+			return err
+		}
+		if _, err := r.next(2 * int(r.header.SizeInt)); err != nil {
+			return err
+		}
+	}
+	stats.Locals += sizeLocVars
+
+	// Debug information: upvalue names
+	sizeUpvalues, err := r.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < sizeUpvalues; i++ {
+		if _, err := r.readString(); err != nil {
+			return err
+		}
+	}
+	stats.Upvalues += sizeUpvalues
+
+	return nil
+}
+
+// Risk rates how easily a compiled file can be turned back into readable source
+type Risk int
+
+const (
+	RiskLow Risk = iota
+	RiskMedium
+	RiskHigh
+	RiskCritical
+)
+
+// String returns a human-readable name for the risk level
+func (r Risk) String() string {
+	switch r {
+	case RiskLow:
+		return "low"
+	case RiskMedium:
+		return "medium"
+	case RiskHigh:
+		return "high"
+	default:
+		return "critical"
+	}
+}
+
+// DecompileRisk rates the file and explains the rating.
+// Obfuscated chunks are hard to decompile, plain bytecode with debug information
+// decompiles to nearly the original source including local variable names.
+func (i Info) DecompileRisk() (Risk, string) {
+	switch i.Kind {
+	case KindObfuscated:
+		return RiskLow, "obfuscated by luac_mta"
+	case KindSource:
+		return RiskCritical, "not compiled, plain source"
+	case KindUnknown:
+		return RiskMedium, "unrecognized format"
+	}
+
+	if i.Stats.Functions == 0 {
+		return RiskHigh, "not obfuscated, debug information could not be checked"
+	}
+	if i.Stripped {
+		return RiskMedium, "not obfuscated, debug information stripped"
+	}
+	return RiskHigh, "not obfuscated and debug information present"
+}
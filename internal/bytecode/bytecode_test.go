@@ -0,0 +1,169 @@
+package bytecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// chunkBuilder writes a little-endian Lua 5.1 chunk with 4 byte ints and size_t
+type chunkBuilder struct {
+	bytes.Buffer
+}
+
+func (b *chunkBuilder) int(v int) {
+	binary.Write(&b.Buffer, binary.LittleEndian, uint32(v))
+}
+
+func (b *chunkBuilder) str(s string) {
+	if s == "" {
+		b.int(0)
+		return
+	}
+	b.int(len(s) + 1)
+	b.WriteString(s)
+	b.WriteByte(0)
+}
+
+// buildChunk builds a main function with one nested function, with or without debug info
+func buildChunk(withDebug bool) []byte {
+	b := &chunkBuilder{}
+	b.Write([]byte{0x1B, 'L', 'u', 'a', 0x51, 0, 1, 4, 4, 4, 8, 0})
+
+	function := func(source string, nested func()) {
+		b.str(source)
+		b.int(0)                    // linedefined
+		b.int(0)                    // lastlinedefined
+		b.Write([]byte{0, 0, 1, 2}) // nups, numparams, is_vararg, maxstacksize
+		b.int(2)                    // sizecode
+		b.int(0x1E)
+		b.int(0x1E)
+		b.int(2) // constants
+		b.WriteByte(4)
+		b.str("print")
+		b.WriteByte(3)
+		binary.Write(&b.Buffer, binary.LittleEndian, float64(1))
+		if nested != nil {
+			b.int(1)
+			nested()
+		} else {
+			b.int(0)
+		}
+		if withDebug {
+			b.int(2) // lineinfo
+			b.int(1)
+			b.int(1)
+			b.int(1) // locvars
+			b.str("x")
+			b.int(0)
+			b.int(1)
+		} else {
+			b.int(0)
+			b.int(0)
+		}
+		b.int(0) // upvalues
+	}
+
+	source := ""
+	if withDebug {
+		source = "@client.lua"
+	}
+	function(source, func() { function("", nil) })
+	return b.Bytes()
+}
+
+func TestInspectCompiledChunk(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		stripped bool
+		source   string
+	}{
+		{"With debug info", buildChunk(true), false, "@client.lua"},
+		{"Stripped", buildChunk(false), true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.data)
+			if err != nil {
+				t.Fatalf("Inspect failed: %v", err)
+			}
+			if info.Kind != KindCompiled {
+				t.Fatalf("Expected kind %v, got %v", KindCompiled, info.Kind)
+			}
+			if len(info.Diagnostics) != 0 {
+				t.Errorf("Unexpected diagnostics: %v", info.Diagnostics)
+			}
+			if info.Stripped != tt.stripped {
+				t.Errorf("Expected stripped=%t, got %t", tt.stripped, info.Stripped)
+			}
+			if info.Stats.Source != tt.source {
+				t.Errorf("Expected source %q, got %q", tt.source, info.Stats.Source)
+			}
+			if info.Stats.Functions != 2 || info.Stats.Instructions != 4 || info.Stats.Constants != 4 || info.Stats.Strings != 2 {
+				t.Errorf("Unexpected stats: %+v", info.Stats)
+			}
+		})
+	}
+}
+
+func TestInspectDiagnostics(t *testing.T) {
+	truncated := buildChunk(true)
+	truncated = truncated[:len(truncated)-10]
+
+	wide := buildChunk(false)
+	wide[8] = 8 // 64-bit size_t
+
+	tests := []struct {
+		name string
+		data []byte
+		kind Kind
+	}{
+		{"Obfuscated", []byte{0x1C, 0x01, 0x02}, KindObfuscated},
+		{"Plain source", []byte("outputChatBox('hi')\n"), KindSource},
+		{"Garbage", []byte{0x00, 0x01, 0x02}, KindUnknown},
+		{"Truncated", truncated, KindCompiled},
+		{"64-bit size_t", wide, KindCompiled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.data)
+			if err != nil {
+				t.Fatalf("Inspect failed: %v", err)
+			}
+			if info.Kind != tt.kind {
+				t.Errorf("Expected kind %v, got %v", tt.kind, info.Kind)
+			}
+			if len(info.Diagnostics) == 0 {
+				t.Error("Expected at least one diagnostic")
+			}
+		})
+	}
+}
+
+func TestDecompileRisk(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected Risk
+	}{
+		{"Obfuscated", []byte{0x1C, 0x01}, RiskLow},
+		{"Stripped", buildChunk(false), RiskMedium},
+		{"Debug info", buildChunk(true), RiskHigh},
+		{"Plain source", []byte("local x = 1\n"), RiskCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.data)
+			if err != nil {
+				t.Fatalf("Inspect failed: %v", err)
+			}
+			if risk, reason := info.DecompileRisk(); risk != tt.expected {
+				t.Errorf("Expected risk %v, got %v (%s)", tt.expected, risk, reason)
+			}
+		})
+	}
+}
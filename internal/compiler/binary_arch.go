@@ -0,0 +1,125 @@
+package compiler
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// ArchitectureError reports a luac_mta binary that cannot be executed on this host,
+// usually because it was built for another operating system or CPU architecture
+type ArchitectureError struct {
+	Path   string // Path of the binary
+	Binary string // Platform the binary was built for, e.g. "windows/386"
+	Host   string // Platform of the host, e.g. "linux/amd64"
+	Err    error  // Error returned when executing the binary
+}
+
+// Error describes the mismatch between the binary and the host
+func (e ArchitectureError) Error() string {
+	return fmt.Sprintf("luac_mta binary %s is built for %s and cannot run on this %s host", e.Path, e.Binary, e.Host)
+}
+
+// Unwrap returns the original execution error
+func (e ArchitectureError) Unwrap() error {
+	return e.Err
+}
+
+// checkExecFormat translates an "exec format error" from running binaryPath into an
+// ArchitectureError naming the binary's platform. Other errors are returned unchanged.
+func checkExecFormat(binaryPath string, err error) error {
+	if !isExecFormatError(err) {
+		return err
+	}
+	return ArchitectureError{
+		Path:   binaryPath,
+		Binary: binaryPlatform(binaryPath),
+		Host:   runtime.GOOS + "/" + runtime.GOARCH,
+		Err:    err,
+	}
+}
+
+// isExecFormatError reports whether err means the executable format is not supported
+func isExecFormatError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ENOEXEC) {
+		return true
+	}
+	// Windows reports ERROR_BAD_EXE_FORMAT instead of ENOEXEC, and macOS EBADARCH for a binary
+	// of another CPU
+	return strings.Contains(err.Error(), "is not a valid Win32 application") || strings.Contains(err.Error(), "bad CPU type in executable")
+}
+
+// binaryPlatform returns the operating system and architecture an executable was built for,
+// read from its ELF, PE or Mach-O header
+func binaryPlatform(binaryPath string) string {
+	if f, err := elf.Open(binaryPath); err == nil {
+		defer f.Close()
+		return "linux/" + elfArch(f.Machine)
+	}
+	if f, err := pe.Open(binaryPath); err == nil {
+		defer f.Close()
+		return "windows/" + peArch(f.Machine)
+	}
+	if f, err := macho.Open(binaryPath); err == nil {
+		defer f.Close()
+		return "darwin/" + machoArch(f.Cpu)
+	}
+	if f, err := macho.OpenFat(binaryPath); err == nil {
+		defer f.Close()
+		archs := make([]string, 0, len(f.Arches))
+		for _, arch := range f.Arches {
+			archs = append(archs, machoArch(arch.Cpu))
+		}
+		return "darwin/" + strings.Join(archs, "+")
+	}
+	return "an unknown format (not an ELF, PE or Mach-O executable)"
+}
+
+// elfArch converts an ELF machine to a GOARCH style name
+func elfArch(machine elf.Machine) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM:
+		return "arm"
+	}
+	return machine.String()
+}
+
+// peArch converts a PE machine to a GOARCH style name
+func peArch(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "386"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	}
+	return fmt.Sprintf("machine 0x%x", machine)
+}
+
+// machoArch converts a Mach-O CPU to a GOARCH style name
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.Cpu386:
+		return "386"
+	case macho.CpuArm64:
+		return "arm64"
+	}
+	return cpu.String()
+}
@@ -0,0 +1,42 @@
+package compiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBinaryPlatform(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the test binary is only an ELF executable on Linux")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Cannot find test executable: %v", err)
+	}
+	if got, expected := binaryPlatform(executable), "linux/"+runtime.GOARCH; got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestValidatePathExecFormat(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows does not execute files by permission bits")
+	}
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	if err := os.WriteFile(path, []byte("\x00\x01\x02not an executable"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewBinaryDetector(nil).ValidatePath(path)
+	var mismatch ArchitectureError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected an ArchitectureError, got %v", err)
+	}
+	if !strings.Contains(mismatch.Binary, "unknown format") || mismatch.Host != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("Unexpected diagnostic: %v", err)
+	}
+}
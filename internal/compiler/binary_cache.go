@@ -0,0 +1,141 @@
+package compiler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cacheKeyLength is the number of hex digits of the SHA-256 hash naming a cached binary
+const cacheKeyLength = 16
+
+// currentFile names the file of a platform cache directory holding the key of the binary
+// downloaded last
+const currentFile = "current"
+
+// binaryCache keeps downloaded luac_mta binaries in the user cache directory, one directory per
+// binary named after its hash, as the MTA servers publish no version numbers. Binaries survive
+// reboots that clean the temporary directory, and the binaries pinned by several projects
+// coexist.
+type binaryCache struct {
+	dir      string // Directory of the binaries of this platform
+	filename string // File name of the binary, luac_mta or luac_mta.exe
+}
+
+// BinaryCacheDir returns the directory downloaded luac_mta binaries are kept in, under the user
+// cache directory (XDG_CACHE_HOME on Linux)
+func BinaryCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "mta-bundler", "bin"), nil
+}
+
+// newBinaryCache returns the cache of the binaries of platform, such as linux-amd64, under root
+func newBinaryCache(root, platform, filename string) binaryCache {
+	return binaryCache{
+		dir:      filepath.Join(root, platform),
+		filename: filename,
+	}
+}
+
+// path returns the path of the cached binary with the hash key
+func (c binaryCache) path(key string) string {
+	return filepath.Join(c.dir, key, c.filename)
+}
+
+// downloadPath returns the path binaries are downloaded to before they are stored
+func (c binaryCache) downloadPath() string {
+	return filepath.Join(c.dir, c.filename)
+}
+
+// lookup returns the cached binary with the SHA-256 hash checksum, or the binary downloaded
+// last when checksum is empty. A binary whose hash does not match its key is removed.
+func (c binaryCache) lookup(checksum string) (string, bool) {
+	key := strings.ToLower(checksum)
+	if key == "" {
+		data, err := os.ReadFile(filepath.Join(c.dir, currentFile))
+		if err != nil {
+			return "", false
+		}
+		key = strings.TrimSpace(string(data))
+	}
+	if len(key) < cacheKeyLength {
+		return "", false
+	}
+
+	path := c.path(key[:cacheKeyLength])
+	hash, err := HashBinary(path)
+	if err != nil {
+		return "", false
+	}
+	if !strings.HasPrefix(hash, key) {
+		slog.Warn("Cached luac_mta binary does not match its hash, removing it", "path", path, "sha256", hash[:cacheKeyLength])
+		os.RemoveAll(filepath.Dir(path))
+		return "", false
+	}
+	return path, true
+}
+
+// lookupAny returns the cached binary with one of the SHA-256 hashes checksums, preferring the
+// binary downloaded last, or the binary downloaded last without checksums
+func (c binaryCache) lookupAny(checksums []string) (string, bool) {
+	if len(checksums) == 0 {
+		return c.lookup("")
+	}
+	if data, err := os.ReadFile(filepath.Join(c.dir, currentFile)); err == nil {
+		if current := strings.TrimSpace(string(data)); slices.Contains(checksums, current) {
+			if path, ok := c.lookup(current); ok {
+				return path, true
+			}
+		}
+	}
+	for _, checksum := range checksums {
+		if path, ok := c.lookup(checksum); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// age returns the time since the binary downloaded last was stored, false when there is none
+func (c binaryCache) age() (time.Duration, bool) {
+	info, err := os.Stat(filepath.Join(c.dir, currentFile))
+	if err != nil {
+		return 0, false
+	}
+	return time.Since(info.ModTime()), true
+}
+
+// store moves the binary downloaded to downloadPath into the cache and marks it as the binary
+// downloaded last, returning its cached path. A cached binary with the same hash is replaced
+// atomically.
+func (c binaryCache) store() (string, error) {
+	hash, err := HashBinary(c.downloadPath())
+	if err != nil {
+		return "", err
+	}
+	key := hash[:cacheKeyLength]
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.Rename(c.downloadPath(), path); err != nil {
+		return "", fmt.Errorf("failed to store binary in cache: %w", err)
+	}
+
+	// Replaced atomically, so concurrent builds never read a partial key
+	current := filepath.Join(c.dir, currentFile)
+	if err := os.WriteFile(current+".tmp", []byte(hash+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to record cached binary: %w", err)
+	}
+	if err := os.Rename(current+".tmp", current); err != nil {
+		return "", fmt.Errorf("failed to record cached binary: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,56 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBinaryCache(t *testing.T) {
+	cache := newBinaryCache(t.TempDir(), "linux-amd64", "luac_mta")
+	if _, ok := cache.lookup(""); ok {
+		t.Fatal("Expected an empty cache to have no binary")
+	}
+
+	versions := []string{"first luac_mta", "second luac_mta"}
+	var paths, hashes []string
+	for _, content := range versions {
+		if err := os.MkdirAll(cache.dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(cache.downloadPath(), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+		path, err := cache.store()
+		if err != nil {
+			t.Fatalf("store failed: %v", err)
+		}
+		hash, err := HashBinary(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(filepath.Dir(path)) != hash[:cacheKeyLength] {
+			t.Errorf("Expected %s to be keyed by its hash %s", path, hash)
+		}
+		paths, hashes = append(paths, path), append(hashes, hash)
+	}
+
+	if path, ok := cache.lookup(""); !ok || path != paths[1] {
+		t.Errorf("Expected the binary downloaded last, got %q", path)
+	}
+	if path, ok := cache.lookup(strings.ToUpper(hashes[0])); !ok || path != paths[0] {
+		t.Errorf("Expected the pinned binary to coexist with the newer one, got %q", path)
+	}
+
+	// A modified binary does not match its key anymore
+	if err := os.WriteFile(paths[0], []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.lookup(hashes[0]); ok {
+		t.Error("Expected a modified binary to be rejected")
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Error("Expected a modified binary to be removed")
+	}
+}
@@ -1,28 +1,145 @@
 package compiler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
 )
 
+// BinaryEnv is the environment variable giving the luac_mta binary to use instead of detecting one
+const BinaryEnv = "LUAC_MTA"
+
 // BinaryDetector handles detection and validation of the luac_mta binary
 type BinaryDetector struct {
 	providers []BinaryProvider
+	runner    runner // Sandbox or container binaries are validated in, nil runs them directly
 }
 
-// NewBinaryDetector creates a new binary detector instance with default providers
-func NewBinaryDetector() BinaryDetector {
+// NewBinaryDetector creates a new binary detector instance with default providers.
+// progress receives the progress of binary downloads and may be nil.
+func NewBinaryDetector(progress DownloadProgress) BinaryDetector {
 	return BinaryDetector{
 		providers: []BinaryProvider{
 			NewLocalBinaryProvider(),
-			NewWebBinaryProvider(),
+			NewWebBinaryProvider(progress),
 		},
 	}
 }
 
-// DetectPath attempts to find the luac_mta binary using configured providers
-func (bd BinaryDetector) DetectPath() (string, error) {
+// WithSandbox returns a copy of the detector running binaries in sandbox to validate them
+func (bd BinaryDetector) WithSandbox(sandbox Sandbox) BinaryDetector {
+	bd.runner = sandbox
+	return bd
+}
+
+// WithContainer returns a copy of the detector running binaries in container to validate them
+func (bd BinaryDetector) WithContainer(container Container) BinaryDetector {
+	bd.runner = container
+	return bd
+}
+
+// WithPin returns a copy of the detector only downloading the binary with the SHA-256 hash
+// checksum, hex-encoded, when it is a trusted one (see WebBinaryProvider.WithPin). Local
+// binaries are not checked.
+func (bd BinaryDetector) WithPin(checksum string) BinaryDetector {
+	return bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithPin(checksum)
+	})
+}
+
+// WithTrusted returns a copy of the detector also accepting downloads with the SHA-256 hashes
+// checksums, hex-encoded
+func (bd BinaryDetector) WithTrusted(checksums []string) BinaryDetector {
+	return bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithTrusted(checksums)
+	})
+}
+
+// WithRefresh returns a copy of the detector downloading the binary again even when it is cached
+// with force, or when the binary downloaded last is older than maxAge. Local binaries are not
+// affected.
+func (bd BinaryDetector) WithRefresh(force bool, maxAge time.Duration) BinaryDetector {
+	return bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithRefresh(force, maxAge)
+	})
+}
+
+// WithOffline returns a copy of the detector that never downloads the binary, using local and
+// cached binaries only
+func (bd BinaryDetector) WithOffline() BinaryDetector {
+	return bd.withWeb(WebBinaryProvider.WithOffline)
+}
+
+// WithMirror returns a copy of the detector downloading the binary from the URL template mirror
+// instead of the MTA servers
+func (bd BinaryDetector) WithMirror(mirror string) BinaryDetector {
+	return bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithMirror(mirror)
+	})
+}
+
+// WithClient returns a copy of the detector downloading the binary with client, such as one
+// created by NewDownloadClient
+func (bd BinaryDetector) WithClient(client *http.Client) BinaryDetector {
+	return bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithClient(client)
+	})
+}
+
+// WithRetries returns a copy of the detector retrying a failed download up to retries times
+func (bd BinaryDetector) WithRetries(retries int) BinaryDetector {
+	return bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithRetries(retries)
+	})
+}
+
+// WithPlatform returns a copy of the detector finding the binary of another platform, such as
+// linux/amd64 to run it in a container. Local binaries are for this host, so the binary is
+// always downloaded or taken from the cache.
+func (bd BinaryDetector) WithPlatform(goos, goarch string) BinaryDetector {
+	bd = bd.withWeb(func(web WebBinaryProvider) WebBinaryProvider {
+		return web.WithPlatform(goos, goarch)
+	})
+	bd.providers = slices.DeleteFunc(bd.providers, func(provider BinaryProvider) bool {
+		_, local := provider.(LocalBinaryProvider)
+		return local
+	})
+	return bd
+}
+
+// WithoutDownloads returns a copy of the detector only using local binaries, for a binary the
+// MTA servers do not publish
+func (bd BinaryDetector) WithoutDownloads() BinaryDetector {
+	bd.providers = slices.DeleteFunc(slices.Clone(bd.providers), func(provider BinaryProvider) bool {
+		_, web := provider.(WebBinaryProvider)
+		return web
+	})
+	return bd
+}
+
+// withWeb returns a copy of the detector with its web providers replaced by configure
+func (bd BinaryDetector) withWeb(configure func(WebBinaryProvider) WebBinaryProvider) BinaryDetector {
+	providers := make([]BinaryProvider, len(bd.providers))
+	for i, provider := range bd.providers {
+		if web, ok := provider.(WebBinaryProvider); ok {
+			provider = configure(web)
+		}
+		providers[i] = provider
+	}
+	bd.providers = providers
+	return bd
+}
+
+// DetectPath attempts to find the luac_mta binary using configured providers, until ctx is
+// cancelled
+func (bd BinaryDetector) DetectPath(ctx context.Context) (string, error) {
 	if len(bd.providers) == 0 {
 		return "", fmt.Errorf("no binary providers configured")
 	}
@@ -31,11 +148,12 @@ func (bd BinaryDetector) DetectPath() (string, error) {
 
 	// Try each provider in order
 	for _, provider := range bd.providers {
-		if path, err := provider.GetBinary(); err == nil {
-			fmt.Printf("Binary found using %s provider: %s\n", provider.Name(), path)
+		slog.Debug("Trying binary provider", "provider", provider.Name())
+		if path, err := provider.GetBinary(ctx); err == nil {
+			slog.Info("Binary found", "provider", provider.Name(), "path", path)
 			return path, nil
 		} else {
-			fmt.Printf("Provider %s failed: %v\n", provider.Name(), err)
+			slog.Debug("Provider failed", "provider", provider.Name(), "error", err)
 			lastErr = err
 		}
 	}
@@ -51,29 +169,69 @@ func (bd BinaryDetector) ValidatePath(binaryPath string) error {
 	}
 
 	// Test if binary is executable by running with no arguments
+	slog.Debug("Validating binary", "path", binaryPath)
 	cmd := exec.Command(binaryPath)
+	if bd.runner != nil {
+		absPath, err := filepath.Abs(binaryPath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		cmd = bd.runner.command(context.Background(), absPath, "", nil, "", nil)
+	}
 	if err := cmd.Run(); err != nil {
 		// luac_mta returns non-zero when no files are provided, which is expected
-		if _, ok := err.(*exec.ExitError); ok {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// Unless docker failed, or could not start the binary in the container
+			if _, ok := bd.runner.(Container); ok && exitErr.ExitCode() >= 125 {
+				return fmt.Errorf("binary cannot run in the container: %w", err)
+			}
 			// Check if it's the expected "no input files" error
 			return nil
 		}
+		if isExecFormatError(err) {
+			return checkExecFormat(binaryPath, err)
+		}
 		return fmt.Errorf("binary is not executable: %w", err)
 	}
 
 	return nil
 }
 
-// DetectAndValidate performs both detection and validation in one step
-func (bd BinaryDetector) DetectAndValidate() (string, error) {
-	path, err := bd.DetectPath()
-	if err != nil {
-		return "", err
+// DetectAndValidate performs both detection and validation in one step. A binary built for
+// another platform does not end the detection: the next provider is tried instead. Cancelling
+// ctx interrupts a download.
+func (bd BinaryDetector) DetectAndValidate(ctx context.Context) (string, error) {
+	if len(bd.providers) == 0 {
+		return "", fmt.Errorf("no binary providers configured")
 	}
 
-	if err := bd.ValidatePath(path); err != nil {
-		return "", err
+	var lastErr, mismatchErr error
+	for _, provider := range bd.providers {
+		slog.Debug("Trying binary provider", "provider", provider.Name())
+		path, err := provider.GetBinary(ctx)
+		if err != nil {
+			slog.Debug("Provider failed", "provider", provider.Name(), "error", err)
+			lastErr = err
+			continue
+		}
+
+		if err := bd.ValidatePath(path); err != nil {
+			var mismatch ArchitectureError
+			if !errors.As(err, &mismatch) {
+				return "", err
+			}
+			slog.Warn("Binary cannot run on this system, trying the next provider", "provider", provider.Name(), "path", path, "binary", mismatch.Binary, "host", mismatch.Host)
+			mismatchErr = err
+			continue
+		}
+
+		slog.Info("Binary found", "provider", provider.Name(), "path", path)
+		return path, nil
 	}
 
-	return path, nil
+	// A binary that exists but cannot run explains the failure better than a missing one
+	if mismatchErr != nil {
+		return "", fmt.Errorf("no usable binary found: %w", mismatchErr)
+	}
+	return "", fmt.Errorf("all providers failed, last error: %w", lastErr)
 }
@@ -1,18 +1,26 @@
 package compiler
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
 )
 
-// BinaryProvider defines the strategy interface for obtaining luac_mta binary
+// BinaryProvider defines the strategy interface for obtaining luac_mta binary. Cancelling ctx
+// interrupts a provider downloading the binary.
 type BinaryProvider interface {
-	GetBinary() (string, error)
+	GetBinary(ctx context.Context) (string, error)
 	Name() string
 }
 
@@ -30,7 +38,7 @@ func (p LocalBinaryProvider) Name() string {
 }
 
 // GetBinary attempts to find the luac_mta binary locally
-func (p LocalBinaryProvider) GetBinary() (string, error) {
+func (p LocalBinaryProvider) GetBinary(_ context.Context) (string, error) {
 	var candidates []string
 
 	// Platform-specific binary names
@@ -55,23 +63,111 @@ func (p LocalBinaryProvider) GetBinary() (string, error) {
 	if path, err := exec.LookPath("luac_mta"); err == nil {
 		return path, nil
 	}
+	slog.Debug("luac_mta not found in PATH")
 
 	// Check candidate locations
 	for _, candidate := range candidates {
 		if _, err := os.Stat(candidate); err == nil {
 			return candidate, nil
 		}
+		slog.Debug("Binary candidate not found", "path", candidate)
 	}
 
 	return "", fmt.Errorf("luac_mta binary not found in PATH or common locations")
 }
 
 // WebBinaryProvider downloads binary from MTA servers
-type WebBinaryProvider struct{}
+type WebBinaryProvider struct {
+	progress DownloadProgress
+	pin      string        // SHA-256 hash of the binary pinned by the lock file, used when it is a trusted one
+	trusted  []string      // SHA-256 hashes trusted in addition to the published ones
+	refresh  bool          // Download the binary even when it is cached
+	maxAge   time.Duration // Download the binary again when the one downloaded last is older, 0 keeps it
+	offline  bool          // Only use cached binaries, never download
+	mirror   string        // URL template the binary is downloaded from, empty uses DefaultMirror
+	client   *http.Client  // Client the binary is downloaded with
+	retries  int           // Number of times a failed download is retried
+	delay    time.Duration // Delay before the first retry, doubled for each retry
+	goos     string        // Operating system the binary is for, empty for this host
+	goarch   string        // Architecture the binary is for, empty for this host
+}
+
+// ErrOffline is returned by the web provider in offline mode when the binary is not cached
+var ErrOffline = errors.New("luac_mta is not cached and downloads are disabled in offline mode")
+
+// NewWebBinaryProvider creates a new web binary provider downloading through the proxy of the
+// environment, with DefaultDownloadTimeout and DefaultDownloadRetries. progress receives the
+// download progress and may be nil.
+func NewWebBinaryProvider(progress DownloadProgress) WebBinaryProvider {
+	client, _ := NewDownloadClient("", DefaultDownloadTimeout)
+	return WebBinaryProvider{progress: progress, client: client, retries: DefaultDownloadRetries, delay: defaultRetryDelay}
+}
+
+// WithPin returns a copy of the provider only accepting the binary with the SHA-256 hash
+// checksum, hex-encoded, when it is one of the trusted binaries. A pin that is not, such as
+// the hash of a local binary, does not apply to downloads.
+func (p WebBinaryProvider) WithPin(checksum string) WebBinaryProvider {
+	p.pin = strings.ToLower(checksum)
+	return p
+}
+
+// WithTrusted returns a copy of the provider also accepting binaries with the SHA-256 hashes
+// checksums, hex-encoded, such as those a mirror serves
+func (p WebBinaryProvider) WithTrusted(checksums []string) WebBinaryProvider {
+	p.trusted = checksums
+	return p
+}
+
+// WithRefresh returns a copy of the provider downloading the binary again even when it is cached
+// with force, or when the binary downloaded last is older than maxAge. A stale binary is still
+// used when it cannot be downloaded again.
+func (p WebBinaryProvider) WithRefresh(force bool, maxAge time.Duration) WebBinaryProvider {
+	p.refresh, p.maxAge = force, maxAge
+	return p
+}
+
+// WithOffline returns a copy of the provider only using cached binaries, failing with ErrOffline
+// instead of downloading
+func (p WebBinaryProvider) WithOffline() WebBinaryProvider {
+	p.offline = true
+	return p
+}
+
+// WithMirror returns a copy of the provider downloading the binary from the URL template mirror
+// (see ExpandMirror) instead of the MTA servers
+func (p WebBinaryProvider) WithMirror(mirror string) WebBinaryProvider {
+	p.mirror = mirror
+	return p
+}
+
+// WithClient returns a copy of the provider downloading the binary with client, such as one
+// created by NewDownloadClient
+func (p WebBinaryProvider) WithClient(client *http.Client) WebBinaryProvider {
+	p.client = client
+	return p
+}
+
+// WithRetries returns a copy of the provider retrying a download that failed because of the
+// connection or the server up to retries times, with exponential backoff. Each retry resumes
+// the partial download.
+func (p WebBinaryProvider) WithRetries(retries int) WebBinaryProvider {
+	p.retries = retries
+	return p
+}
+
+// WithPlatform returns a copy of the provider downloading the binary of another platform, such
+// as linux/amd64 to run it in a container
+func (p WebBinaryProvider) WithPlatform(goos, goarch string) WebBinaryProvider {
+	p.goos, p.goarch = goos, goarch
+	return p
+}
 
-// NewWebBinaryProvider creates a new web binary provider
-func NewWebBinaryProvider() WebBinaryProvider {
-	return WebBinaryProvider{}
+// platform returns the operating system and architecture the binary is for
+func (p WebBinaryProvider) platform() (string, string) {
+	if p.goos == "" {
+		return runtime.GOOS, runtime.GOARCH
+	}
+	return p.goos, p.goarch
 }
 
 // Name returns the provider name
@@ -79,82 +175,181 @@ func (p WebBinaryProvider) Name() string {
 	return "web"
 }
 
-// GetBinary downloads and returns the luac_mta binary from MTA servers
-func (p WebBinaryProvider) GetBinary() (string, error) {
-	url, filename, err := p.getBinaryURL()
+// checksums returns the SHA-256 hashes the binary may have: the pinned one when it is trusted,
+// otherwise every published and trusted one. With no published or trusted hash for the platform
+// the pin applies as is, and without a pin either there is none. pinned reports whether the pin
+// applies.
+func (p WebBinaryProvider) checksums(osName, arch, filename string) (checksums []string, pinned bool) {
+	checksums = append(slices.Clone(PublishedChecksums(osName, arch, filename)), p.trusted...)
+	if p.pin == "" {
+		return checksums, false
+	}
+	if len(checksums) == 0 {
+		return []string{p.pin}, true
+	}
+	if !slices.Contains(checksums, p.pin) {
+		slog.Debug("The pinned luac_mta is not a trusted download, not applying the pin to downloads", "sha256", p.pin)
+		return checksums, false
+	}
+	return []string{p.pin}, true
+}
+
+// GetBinary returns the luac_mta binary from the cache, downloading it from MTA servers when it
+// is not cached yet
+func (p WebBinaryProvider) GetBinary(ctx context.Context) (string, error) {
+	osName, arch, filename, err := p.serverPlatform()
 	if err != nil {
 		return "", fmt.Errorf("failed to determine binary URL: %w", err)
 	}
+	url := p.binaryURL(osName, arch, filename)
+	// Every binary used is checked against a trusted hash, or the one the lock file pins when
+	// this build knows no published hash for the platform
+	checksums, pinned := p.checksums(osName, arch, filename)
+	unpublished := len(PublishedChecksums(osName, arch, filename)) == 0 && len(p.trusted) == 0
 
-	// Use system temp directory
-	tempDir := os.TempDir()
-	binaryPath := filepath.Join(tempDir, filename)
+	root, err := BinaryCacheDir()
+	if err != nil {
+		slog.Debug("Caching downloads in the temp directory", "error", err)
+		root = filepath.Join(os.TempDir(), "mta-bundler", "bin")
+	}
+	goos, goarch := p.platform()
+	cache := newBinaryCache(root, goos+"-"+goarch, filename)
+
+	slog.Debug("Resolved download URL", "url", url, "cache", cache.dir)
 
-	// Check if already downloaded
-	if _, err := os.Stat(binaryPath); err == nil {
-		fmt.Printf("Found existing %s binary in temp directory: %s\n", runtime.GOOS, binaryPath)
-		return binaryPath, nil
+	cached, ok := cache.lookupAny(checksums)
+	if p.offline {
+		if !ok {
+			return "", ErrOffline
+		}
+		slog.Info("Found cached binary", "os", goos, "path", cached)
+		return cached, nil
+	}
+	age, _ := cache.age()
+	// A pinned binary never changes, only the binary downloaded last gets stale
+	stale := ok && !p.refresh && !pinned && p.maxAge > 0 && age > p.maxAge
+	switch {
+	case ok && p.refresh:
+		slog.Info("Refreshing cached binary", "path", cached)
+	case stale:
+		slog.Info("Cached binary is stale, downloading it again", "path", cached, "age", age.Round(time.Minute), "max_age", p.maxAge)
+	case ok:
+		slog.Info("Found cached binary", "os", goos, "path", cached)
+		return cached, nil
 	}
 
-	fmt.Printf("Downloading %s binary from MTA servers to temp directory...\n", runtime.GOOS)
+	if err := os.MkdirAll(cache.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if p.refresh || stale {
+		// A partial download may come from an older binary
+		os.Remove(cache.downloadPath() + partialSuffix)
+	}
+	switch {
+	case unpublished && pinned:
+		slog.Warn("No published SHA-256 of luac_mta is known for this platform, only accepting the binary the lock file pins",
+			"os", osName, "arch", arch, "sha256", p.pin)
+	case unpublished:
+		slog.Warn("No published SHA-256 of luac_mta is known for this platform, the download cannot be verified: "+
+			"check the binary, then commit the lock file recording it or set "+ChecksumEnv+" to its hash", "os", osName, "arch", arch)
+	}
+	if p.mirror != "" {
+		slog.Info("Downloading binary from mirror", "url", url, "cache", cache.dir)
+	} else {
+		slog.Info("Downloading binary from MTA servers", "os", goos, "cache", cache.dir)
+	}
 
-	// Download the binary
-	if err := p.downloadFile(url, binaryPath); err != nil {
+	// Download the binary, checked against the expected hash before it is used
+	err = retrying(ctx, "Download", p.retries, p.delay, transientError, func() error {
+		return download(ctx, p.client, url, cache.downloadPath(), checksums, p.progress)
+	})
+	if err != nil {
+		if stale {
+			slog.Warn("Cannot download the binary again, using the stale one", "path", cached, "error", err)
+			return cached, nil
+		}
 		return "", fmt.Errorf("failed to download binary: %w", err)
 	}
 
 	// Make binary executable on Unix-like systems
-	if runtime.GOOS != "windows" {
-		if err := os.Chmod(binaryPath, 0755); err != nil {
+	if goos != "windows" {
+		if err := os.Chmod(cache.downloadPath(), 0755); err != nil {
 			return "", fmt.Errorf("failed to make binary executable: %w", err)
 		}
 	}
 
-	fmt.Printf("Binary downloaded successfully: %s\n", binaryPath)
+	binaryPath, err := cache.store()
+	if err != nil {
+		return "", err
+	}
+	slog.Info("Binary downloaded", "path", binaryPath, "success", true)
 	return binaryPath, nil
 }
 
-// getBinaryURL returns the download URL and filename based on the current OS and architecture
-func (p WebBinaryProvider) getBinaryURL() (string, string, error) {
-	switch runtime.GOOS {
+// DefaultMirror is the URL template of the luac_mta binaries on the MTA servers
+const DefaultMirror = "https://luac.mtasa.com/files/{os}/{arch}/{file}"
+
+// MirrorEnv is the environment variable giving the URL template luac_mta is downloaded from
+// instead of the MTA servers
+const MirrorEnv = "LUAC_MTA_MIRROR"
+
+// mirrorPlaceholderPattern matches the placeholders of a mirror URL template
+var mirrorPlaceholderPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// ExpandMirror returns the URL of the binary named file for an operating system and architecture
+// named like on the MTA servers (linux and x64 for example), from a mirror URL template with
+// {os}, {arch} and {file} placeholders. A template without placeholders is a copy of the files
+// directory of the MTA servers, {os}/{arch}/{file} is appended to it.
+func ExpandMirror(template, osName, arch, file string) string {
+	if !mirrorPlaceholderPattern.MatchString(template) {
+		template = strings.TrimSuffix(template, "/") + "/{os}/{arch}/{file}"
+	}
+	return strings.NewReplacer("{os}", osName, "{arch}", arch, "{file}", file).Replace(template)
+}
+
+// ValidateMirror checks that template is an HTTP or HTTPS URL template with known placeholders
+func ValidateMirror(template string) error {
+	if template == "" {
+		return nil
+	}
+	for _, placeholder := range mirrorPlaceholderPattern.FindAllString(template, -1) {
+		if placeholder != "{os}" && placeholder != "{arch}" && placeholder != "{file}" {
+			return fmt.Errorf("unknown placeholder %s in mirror %q (use {os}, {arch} and {file})", placeholder, template)
+		}
+	}
+	parsed, err := url.Parse(ExpandMirror(template, "linux", "x64", "luac_mta"))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("mirror %q is not an HTTP or HTTPS URL", template)
+	}
+	return nil
+}
+
+// serverPlatform returns the operating system, architecture and file name of the binary as
+// named on the MTA servers
+func (p WebBinaryProvider) serverPlatform() (string, string, string, error) {
+	goos, goarch := p.platform()
+	switch goos {
 	case "windows":
-		return "https://luac.mtasa.com/files/windows/x86/luac_mta.exe", "luac_mta.exe", nil
+		return "windows", "x86", "luac_mta.exe", nil
 	case "linux":
-		switch runtime.GOARCH {
+		switch goarch {
 		case "amd64":
-			return "https://luac.mtasa.com/files/linux/x64/luac_mta", "luac_mta", nil
+			return "linux", "x64", "luac_mta", nil
 		case "386":
-			return "https://luac.mtasa.com/files/linux/x86/luac_mta", "luac_mta", nil
+			return "linux", "x86", "luac_mta", nil
 		default:
-			return "", "", fmt.Errorf("unsupported Linux architecture: %s", runtime.GOARCH)
+			return "", "", "", fmt.Errorf("unsupported Linux architecture: %s", goarch)
 		}
 	default:
-		return "", "", fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+		return "", "", "", fmt.Errorf("unsupported operating system: %s", goos)
 	}
 }
 
-// downloadFile downloads a file from the given URL to the specified path
-func (p WebBinaryProvider) downloadFile(url, filepath string) error {
-	// Create the file
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	// Get the data
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
+// binaryURL returns the download URL of the binary, from the mirror when one is set
+func (p WebBinaryProvider) binaryURL(osName, arch, filename string) string {
+	mirror := p.mirror
+	if mirror == "" {
+		mirror = DefaultMirror
 	}
-	defer resp.Body.Close()
-
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
-
-	// Copy data to file
-	_, err = io.Copy(out, resp.Body)
-	return err
+	return ExpandMirror(mirror, osName, arch, filename)
 }
@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpandMirror(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{DefaultMirror, "https://luac.mtasa.com/files/linux/x64/luac_mta"},
+		{"https://mirror.example.com/luac/", "https://mirror.example.com/luac/linux/x64/luac_mta"},
+		{"http://10.0.0.5/tools/luac_mta-{os}-{arch}", "http://10.0.0.5/tools/luac_mta-linux-x64"},
+	}
+	for _, tt := range tests {
+		if got := ExpandMirror(tt.template, "linux", "x64", "luac_mta"); got != tt.expected {
+			t.Errorf("ExpandMirror(%q) = %q, expected %q", tt.template, got, tt.expected)
+		}
+		if err := ValidateMirror(tt.template); err != nil {
+			t.Errorf("Expected %q to be valid: %v", tt.template, err)
+		}
+	}
+
+	for _, template := range []string{"https://mirror.example.com/{version}/{file}", "ftp://mirror.example.com/luac", "mirror/luac"} {
+		if err := ValidateMirror(template); err == nil {
+			t.Errorf("Expected %q to be rejected", template)
+		}
+	}
+}
+
+func TestWebBinaryProviderChecksums(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	content := []byte("\x7fELF luac_mta")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	provider := NewWebBinaryProvider(nil).WithMirror(server.URL+"/{file}").WithPlatform("linux", "amd64").WithClient(server.Client())
+
+	if _, err := provider.WithTrusted([]string{strings.Repeat("0", 64)}).GetBinary(context.Background()); !errors.As(err, new(ChecksumError)) {
+		t.Fatalf("Expected a download with an untrusted hash to fail, got %v", err)
+	}
+	// The pin of a local binary does not replace the trusted hashes
+	path, err := provider.WithTrusted([]string{checksum}).WithPin(strings.Repeat("1", 64)).GetBinary(context.Background())
+	if err != nil {
+		t.Fatalf("Expected a download with a trusted hash to succeed: %v", err)
+	}
+	if hash, _ := HashBinary(path); hash != checksum {
+		t.Errorf("Expected the trusted binary, got SHA-256 %s", hash)
+	}
+}
+
+func TestWebBinaryProviderUnpublished(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	published := publishedChecksums
+	publishedChecksums = map[string][]string{}
+	t.Cleanup(func() { publishedChecksums = published })
+
+	content := []byte("\x7fELF luac_mta")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	provider := NewWebBinaryProvider(nil).WithMirror(server.URL+"/{file}").WithPlatform("linux", "amd64").WithClient(server.Client())
+
+	// Without a published hash, the pin of the lock file is the only binary accepted
+	if _, err := provider.WithPin(strings.Repeat("1", 64)).GetBinary(context.Background()); !errors.As(err, new(ChecksumError)) {
+		t.Fatalf("Expected a download that is not the pinned binary to fail, got %v", err)
+	}
+	if _, err := provider.WithPin(checksum).GetBinary(context.Background()); err != nil {
+		t.Fatalf("Expected the pinned binary to be downloaded: %v", err)
+	}
+	// Without a pin either, the binary downloaded last is used
+	path, err := provider.WithOffline().GetBinary(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the binary downloaded last to be used: %v", err)
+	}
+	if hash, _ := HashBinary(path); hash != checksum {
+		t.Errorf("Expected the downloaded binary, got SHA-256 %s", hash)
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	if _, err := parseChecksums(checksumsFile); err != nil {
+		t.Fatalf("checksums.txt: %v", err)
+	}
+	checksums, err := parseChecksums("# comment\n" + strings.Repeat("AB", 32) + "  linux/x64/luac_mta\n")
+	if err != nil || len(checksums["linux/x64/luac_mta"]) != 1 || checksums["linux/x64/luac_mta"][0] != strings.Repeat("ab", 32) {
+		t.Errorf("Expected one lowercase hash for linux/x64/luac_mta, got %v (%v)", checksums, err)
+	}
+	if _, err := parseChecksums("abc  linux/x64/luac_mta\n"); err == nil {
+		t.Error("Expected a malformed hash to be rejected")
+	}
+}
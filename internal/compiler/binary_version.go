@@ -0,0 +1,161 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// versions caches the versions probed by Version, keyed by binary path
+var versions sync.Map
+
+// Patterns used to read what a luac_mta binary tells about itself
+var (
+	// mtaVersionPattern matches MTA versions such as 1.5.9 or 1.5.9-9.21026, major version 1
+	// keeping them apart from the Lua version
+	mtaVersionPattern = regexp.MustCompile(`\b(1\.(\d+)\.(\d+)(?:-\d+\.(\d+))?)\b`)
+	// obfuscationOptionPattern matches the obfuscation options listed in the usage text
+	obfuscationOptionPattern = regexp.MustCompile(`(?m)^\s*-e([23]?)\b`)
+)
+
+// BinaryVersion is what a luac_mta binary tells about itself
+type BinaryVersion struct {
+	MTA      string           // MTA version the binary comes with, such as 1.5.9-9.21026, empty when it does not tell
+	MaxLevel ObfuscationLevel // Highest obfuscation level the binary supports
+	Known    bool             // The binary told its version or options, MaxLevel is ObfuscationMaximum otherwise
+}
+
+// Supports reports whether the binary compiles scripts at level
+func (v BinaryVersion) Supports(level ObfuscationLevel) bool {
+	return level <= v.MaxLevel
+}
+
+// String describes the binary version for messages
+func (v BinaryVersion) String() string {
+	switch {
+	case v.MTA != "":
+		return "MTA " + v.MTA
+	case v.Known:
+		return fmt.Sprintf("a binary supporting obfuscation up to level %d", v.MaxLevel)
+	}
+	return "unknown"
+}
+
+// Version probes the luac_mta binary for its version, running it with -v and without arguments
+// for its usage text. The result is cached per binary path. Binaries that tell neither their
+// version nor their options are reported as supporting every obfuscation level, so they are
+// not refused on a guess, like the compile API.
+func (c CLICompiler) Version() (BinaryVersion, error) {
+	if c.remote != nil {
+		// The MTA servers run the latest luac_mta
+		return BinaryVersion{MaxLevel: ObfuscationMaximum}, nil
+	}
+	if version, ok := versions.Load(c.binaryPath); ok {
+		return version.(BinaryVersion), nil
+	}
+
+	var output strings.Builder
+	for _, args := range [][]string{{"-v"}, nil} {
+		out, err := c.probe(args)
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return BinaryVersion{}, fmt.Errorf("failed to run luac_mta: %w", checkExecFormat(c.binaryPath, err))
+		}
+		output.Write(out)
+		output.WriteByte('\n')
+	}
+
+	version := ParseVersion(output.String())
+	slog.Debug("Probed luac_mta version", "path", c.binaryPath, "mta", version.MTA, "max_level", int(version.MaxLevel), "known", version.Known)
+	versions.Store(c.binaryPath, version)
+	return version, nil
+}
+
+// probe runs the binary with args, in the sandbox or container when there is one, and returns
+// its combined output. Its standard input is empty, so it never waits for a script.
+func (c CLICompiler) probe(args []string) ([]byte, error) {
+	cmd := exec.Command(c.binaryPath, args...)
+	if c.runner != nil {
+		absPath, err := filepath.Abs(c.binaryPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		cmd = c.runner.command(context.Background(), absPath, "", nil, "", args)
+	}
+	return cmd.CombinedOutput()
+}
+
+// ParseVersion reads the version output and usage text of a luac_mta binary. The obfuscation
+// options listed by the usage text tell the supported levels; without them, the levels are
+// those of the MTA version found on a line naming MTA.
+func ParseVersion(output string) BinaryVersion {
+	var version BinaryVersion
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(strings.ToLower(line), "mta") {
+			continue
+		}
+		if match := mtaVersionPattern.FindStringSubmatch(line); match != nil {
+			version.MTA = match[1]
+			break
+		}
+	}
+
+	version.MaxLevel = ObfuscationMaximum
+	if options := obfuscationOptionPattern.FindAllStringSubmatch(output, -1); len(options) > 0 {
+		version.Known, version.MaxLevel = true, ObfuscationBasic
+		for _, option := range options {
+			if level, err := strconv.Atoi(option[1]); err == nil && ObfuscationLevel(level) > version.MaxLevel {
+				version.MaxLevel = ObfuscationLevel(level)
+			}
+		}
+		return version
+	}
+
+	if version.MTA != "" {
+		version.Known = true
+		for level := ObfuscationMaximum; level > ObfuscationBasic; level-- {
+			if comparison, err := CompareVersions(version.MTA, level.MinMTAVersion()); err == nil && comparison < 0 {
+				version.MaxLevel = level - 1
+			}
+		}
+	}
+	return version
+}
+
+// mtaVersionRegex matches a whole MTA version such as 1.5.2 or 1.5.2-9.07903, build type and
+// number
+var mtaVersionRegex = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-\d+\.(\d+))?$`)
+
+// CompareVersions returns -1, 0 or 1 as MTA version a is older than, the same as or newer than
+// b. A version without build number is older than its builds, such as 1.5.2 and 1.5.2-9.07903.
+// An error is returned when a version cannot be parsed.
+func CompareVersions(a, b string) (int, error) {
+	var parsed [2][4]int
+	for i, version := range []string{a, b} {
+		match := mtaVersionRegex.FindStringSubmatch(strings.TrimSpace(version))
+		if match == nil {
+			return 0, fmt.Errorf("invalid MTA version %q", version)
+		}
+		for j, part := range match[1:] {
+			if part != "" {
+				parsed[i][j], _ = strconv.Atoi(part)
+			}
+		}
+	}
+	for j := range parsed[0] {
+		if parsed[0][j] != parsed[1][j] {
+			if parsed[0][j] < parsed[1][j] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
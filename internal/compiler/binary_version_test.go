@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.5.2-9.07903", "1.5.2-9.07903", 0},
+		{"1.5.2", "1.5.2-9.07903", -1},
+		{"1.5.6", "1.5.2-9.07903", 1},
+		{"1.5.6-9.18000", "1.5.6-9.18728", -1},
+		{"1.6.0", "1.5.6-9.18728", 1},
+		{"1.10.0", "1.9.9", 1},
+	}
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("CompareVersions(%q, %q) failed: %v", tt.a, tt.b, err)
+		}
+		if got != tt.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+	if _, err := CompareVersions("latest", "1.5.2"); err == nil {
+		t.Error("Expected an invalid version to fail")
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected BinaryVersion
+	}{
+		{
+			"Usage lists the obfuscation options",
+			"Lua 5.1.5  Copyright (C) 1994-2012 Lua.org, PUC-Rio\n" +
+				"usage: luac_mta [options] [filenames].\nAvailable options are:\n" +
+				"  -o name  output to file 'name'\n  -s       strip debug information\n" +
+				"  -e       obfuscate\n  -e2      obfuscate more\n",
+			BinaryVersion{MaxLevel: ObfuscationEnhanced, Known: true},
+		},
+		{
+			"Old MTA version",
+			"luac_mta (MTA:SA 1.5.1-9.07100)\nLua 5.1.5  Copyright (C) 1994-2012 Lua.org, PUC-Rio\n",
+			BinaryVersion{MTA: "1.5.1-9.07100", MaxLevel: ObfuscationBasic, Known: true},
+		},
+		{
+			"MTA version between levels",
+			"MTA:SA luac 1.5.4\n",
+			BinaryVersion{MTA: "1.5.4", MaxLevel: ObfuscationEnhanced, Known: true},
+		},
+		{
+			"Recent MTA version",
+			"luac_mta 1.6.0-9.22195\n",
+			BinaryVersion{MTA: "1.6.0-9.22195", MaxLevel: ObfuscationMaximum, Known: true},
+		},
+		{
+			"Nothing told",
+			"Lua 5.1.5  Copyright (C) 1994-2012 Lua.org, PUC-Rio\nluac_mta: no input files given\n",
+			BinaryVersion{MaxLevel: ObfuscationMaximum},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseVersion(tt.output); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	version := ParseVersion("luac_mta 1.5.1\n")
+	if version.Supports(ObfuscationEnhanced) || !version.Supports(ObfuscationBasic) {
+		t.Errorf("Unexpected supported levels for %v", version)
+	}
+}
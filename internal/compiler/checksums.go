@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ChecksumEnv is the environment variable listing the SHA-256 hashes of luac_mta binaries to
+// trust in addition to the published ones, comma-separated, such as those a mirror serves
+const ChecksumEnv = "LUAC_MTA_SHA256"
+
+// checksumPattern matches a hex-encoded SHA-256 hash
+var checksumPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// checksumsFile lists the hashes of the published binaries, see checksums.txt
+//
+//go:embed checksums.txt
+var checksumsFile string
+
+// publishedChecksums are the hashes of the published binaries by path under DefaultMirror
+var publishedChecksums = mustParseChecksums(checksumsFile)
+
+// PublishedChecksums returns the SHA-256 hashes, hex-encoded, a binary published on the MTA
+// servers may have, for an operating system and architecture named like on the servers
+func PublishedChecksums(osName, arch, filename string) []string {
+	return publishedChecksums[osName+"/"+arch+"/"+filename]
+}
+
+// parseChecksums parses a list of hashes in the sha256sum format, ignoring blank lines and
+// # comments
+func parseChecksums(data string) (map[string][]string, error) {
+	checksums := make(map[string][]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !checksumPattern.MatchString(fields[0]) {
+			return nil, fmt.Errorf("line %d: expected a SHA-256 hash and a path", i+1)
+		}
+		path := strings.TrimPrefix(fields[1], "*")
+		checksums[path] = append(checksums[path], strings.ToLower(fields[0]))
+	}
+	return checksums, nil
+}
+
+// mustParseChecksums parses the embedded list of hashes, which the tests check
+func mustParseChecksums(data string) map[string][]string {
+	checksums, err := parseChecksums(data)
+	if err != nil {
+		panic(fmt.Sprintf("checksums.txt: %v", err))
+	}
+	return checksums
+}
+
+// ParseChecksumList parses a comma-separated list of hex-encoded SHA-256 hashes, such as the
+// value of ChecksumEnv
+func ParseChecksumList(list string) ([]string, error) {
+	var checksums []string
+	for _, checksum := range strings.Split(list, ",") {
+		checksum = strings.TrimSpace(checksum)
+		if checksum == "" {
+			continue
+		}
+		if !checksumPattern.MatchString(checksum) {
+			return nil, fmt.Errorf("%q is not a SHA-256 hash of 64 hexadecimal characters", checksum)
+		}
+		checksums = append(checksums, strings.ToLower(checksum))
+	}
+	return checksums, nil
+}
@@ -0,0 +1,19 @@
+//go:build release
+
+package compiler
+
+import "testing"
+
+// Run before a release with go test -tags release ./internal/compiler, so a release never ships
+// without the hashes the first download of each platform is checked against
+func TestPublishedChecksumsComplete(t *testing.T) {
+	for _, platform := range [][2]string{{"linux", "amd64"}, {"linux", "386"}, {"windows", "386"}} {
+		osName, arch, filename, err := NewWebBinaryProvider(nil).WithPlatform(platform[0], platform[1]).serverPlatform()
+		if err != nil {
+			t.Fatalf("%s/%s: %v", platform[0], platform[1], err)
+		}
+		if len(PublishedChecksums(osName, arch, filename)) == 0 {
+			t.Errorf("checksums.txt has no SHA-256 for %s/%s/%s", osName, arch, filename)
+		}
+	}
+}
@@ -1,19 +1,42 @@
 package compiler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
+// fingerprints caches the binary hashes computed by Fingerprint, keyed by binary path
+var fingerprints sync.Map
+
 // CLICompiler implements LuaCompiler using the luac_mta CLI binary
 type CLICompiler struct {
 	binaryPath string
+	runner     runner          // Sandbox or container luac_mta runs in, nil runs it directly
+	args       []string        // Arguments passed to luac_mta after the modeled options
+	remote     *RemoteCompiler // Compile API used instead of luac_mta, nil runs luac_mta
+	ctx        context.Context // Kills running compilations and fails new ones once done, nil never does
+	retries    int             // Times a compilation failing for a transient reason is run again
+	retryDelay time.Duration   // Delay before the first retry, doubled for each retry
 }
 
+// DefaultCompileRetryDelay is the delay before a compilation that failed for a transient reason
+// is run again, doubled for each retry
+const DefaultCompileRetryDelay = 500 * time.Millisecond
+
 // NewCLICompiler creates a new CLI-based Lua compiler
 func NewCLICompiler(binaryPath string) (CLICompiler, error) {
 	if binaryPath == "" {
@@ -27,6 +50,131 @@ func NewCLICompiler(binaryPath string) (CLICompiler, error) {
 	return compiler, nil
 }
 
+// WithSandbox returns a copy of the compiler running luac_mta in sandbox
+func (c CLICompiler) WithSandbox(sandbox Sandbox) CLICompiler {
+	c.runner = sandbox
+	return c
+}
+
+// WithContainer returns a copy of the compiler running luac_mta in container
+func (c CLICompiler) WithContainer(container Container) CLICompiler {
+	c.runner = container
+	return c
+}
+
+// WithRemote returns a copy of the compiler uploading scripts to the compile API of remote
+// instead of running a luac_mta binary
+func (c CLICompiler) WithRemote(remote RemoteCompiler) CLICompiler {
+	c.remote = &remote
+	return c
+}
+
+// Remote reports whether the compiler uploads scripts to the compile API
+func (c CLICompiler) Remote() bool {
+	return c.remote != nil
+}
+
+// WithRetries returns a copy of the compiler running a compilation up to retries more times
+// when it fails for a transient reason, such as a busy binary or a locked file, rather than
+// because luac_mta rejected the script
+func (c CLICompiler) WithRetries(retries int) CLICompiler {
+	c.retries = retries
+	c.retryDelay = DefaultCompileRetryDelay
+	return c
+}
+
+// WithContext returns a copy of the compiler bound to ctx: once ctx is done, running luac_mta
+// processes are killed and compilations fail without starting
+func (c CLICompiler) WithContext(ctx context.Context) CLICompiler {
+	c.ctx = ctx
+	return c
+}
+
+// Err returns why the context of the compiler is done, nil while compilations can run
+func (c CLICompiler) Err() error {
+	return c.context().Err()
+}
+
+// context returns the context of the compiler, the background context when it has none
+func (c CLICompiler) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithArgs returns a copy of the compiler passing args to luac_mta after the options it models,
+// for luac_mta options the bundler does not know about
+func (c CLICompiler) WithArgs(args []string) CLICompiler {
+	c.args = slices.Clone(args)
+	return c
+}
+
+// Args returns the arguments passed to luac_mta after the modeled options
+func (c CLICompiler) Args() []string {
+	return c.args
+}
+
+// reservedArgs are the luac_mta arguments the bundler sets itself, with the option to use instead
+var reservedArgs = map[string]string{
+	"-o":  "the output is set by the bundler",
+	"-":   "the inputs are set by the bundler",
+	"--":  "the inputs are set by the bundler",
+	"-s":  "use -s",
+	"-e":  "use -e",
+	"-e2": "use -e",
+	"-e3": "use -e",
+	"-d":  "use -d",
+	"-p":  "use -preflight",
+}
+
+// ValidateArgs checks that args can be passed to luac_mta after the modeled options: they may not
+// set the output, inputs or an option the bundler models
+func ValidateArgs(args []string) error {
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("empty luac_mta argument")
+		}
+		if reason, ok := reservedArgs[arg]; ok {
+			return fmt.Errorf("luac_mta argument %q is not allowed: %s", arg, reason)
+		}
+	}
+	return nil
+}
+
+// Fingerprint returns a hash of the luac_mta binary, identifying the compiler version, or of
+// the URL of the compile API. The hash is computed once per binary path.
+func (c CLICompiler) Fingerprint() (string, error) {
+	if c.remote != nil {
+		return c.remote.fingerprint(), nil
+	}
+	if fingerprint, ok := fingerprints.Load(c.binaryPath); ok {
+		return fingerprint.(string), nil
+	}
+
+	fingerprint, err := HashBinary(c.binaryPath)
+	if err != nil {
+		return "", err
+	}
+	fingerprints.Store(c.binaryPath, fingerprint)
+	return fingerprint, nil
+}
+
+// HashBinary returns the hex-encoded SHA-256 hash of a luac_mta binary
+func HashBinary(binaryPath string) (string, error) {
+	file, err := os.Open(binaryPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read luac_mta binary: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read luac_mta binary: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // ValidateFiles checks if all provided files exist and are Lua files
 func (c CLICompiler) ValidateFiles(filePaths []string) error {
 	if len(filePaths) == 0 {
@@ -58,6 +206,7 @@ func (c CLICompiler) Compile(filePaths []string, outputPath string, options Comp
 
 	result := CompilationResult{
 		InputFile:  strings.Join(filePaths, ", "),
+		InputFiles: filePaths,
 		OutputFile: outputPath,
 	}
 
@@ -80,18 +229,14 @@ func (c CLICompiler) Compile(filePaths []string, outputPath string, options Comp
 		return result, result.Error
 	}
 
-	// Build command arguments
-	args := c.buildArgs(options, outputPath)
-	args = append(args, filePaths...)
-
 	// Execute compilation
-	cmd := exec.Command(c.binaryPath, args...)
-	output, err := cmd.CombinedOutput()
+	output, retries, err := c.run("", filePaths, outputPath, options)
 
 	result.CompileTime = time.Since(startTime)
+	result.Retries = retries
 
 	if err != nil {
-		result.Error = fmt.Errorf("compilation failed: %w\nOutput: %s", err, string(output))
+		result.Error = fmt.Errorf("compilation failed: %w\nOutput: %s", checkExecFormat(c.binaryPath, err), string(output))
 		return result, result.Error
 	}
 
@@ -111,6 +256,7 @@ func (c CLICompiler) CompileFile(filePath string, outputPath string, options Com
 
 	result := CompilationResult{
 		InputFile:  filePath,
+		InputFiles: []string{filePath},
 		OutputFile: outputPath,
 	}
 
@@ -133,18 +279,95 @@ func (c CLICompiler) CompileFile(filePath string, outputPath string, options Com
 		return result, result.Error
 	}
 
-	// Build command arguments
-	args := c.buildArgs(options, outputPath)
-	args = append(args, filePath)
+	// Execute compilation
+	output, retries, err := c.run("", []string{filePath}, outputPath, options)
+
+	result.CompileTime = time.Since(startTime)
+	result.Retries = retries
+
+	if err != nil {
+		result.Error = fmt.Errorf("compilation failed: %w\nOutput: %s", checkExecFormat(c.binaryPath, err), string(output))
+		return result, result.Error
+	}
+
+	result.Success = true
+
+	// Calculate output file size and update metrics
+	if outputSize, err := CalculateFileSize(outputPath); err == nil {
+		result.OutputSize = outputSize
+	}
+
+	return result, nil
+}
+
+// CompileSource compiles Lua source as a single chunk named name, such as client.lua. The
+// source is written to a temporary directory the compiler runs in, so the chunk name in the
+// output does not depend on where the build runs.
+func (c CLICompiler) CompileSource(name string, source []byte, outputPath string, options CompilationOptions) (CompilationResult, error) {
+	return c.CompileSources([]Source{{Name: name, Data: source}}, outputPath, options)
+}
+
+// Source is a Lua source compiled from memory
+type Source struct {
+	Name string // Slash-separated path luac_mta reads the source from, the chunk name in the output
+	Data []byte
+}
+
+// CompileSources compiles Lua sources into a single merged output file, each as its own chunk.
+// Like CompileSource, the sources are written to a temporary directory the compiler runs in.
+func (c CLICompiler) CompileSources(sources []Source, outputPath string, options CompilationOptions) (CompilationResult, error) {
+	startTime := time.Now()
+
+	var names []string
+	var inputSize int64
+	for _, source := range sources {
+		names = append(names, source.Name)
+		inputSize += int64(len(source.Data))
+	}
+	result := CompilationResult{
+		InputFile:  strings.Join(names, ", "),
+		InputFiles: names,
+		OutputFile: outputPath,
+		InputSize:  inputSize,
+	}
+
+	dir, err := os.MkdirTemp("", "mta-bundler-source-*")
+	if err != nil {
+		result.Error = fmt.Errorf("failed to create source directory: %w", err)
+		return result, result.Error
+	}
+	defer os.RemoveAll(dir)
+	for _, source := range sources {
+		path := filepath.Join(dir, filepath.FromSlash(source.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			result.Error = fmt.Errorf("failed to write source: %w", err)
+			return result, result.Error
+		}
+		if err := os.WriteFile(path, source.Data, 0644); err != nil {
+			result.Error = fmt.Errorf("failed to write source: %w", err)
+			return result, result.Error
+		}
+	}
+
+	// Ensure output directory exists, outputPath may be relative to the build's directory
+	if outputPath, err = filepath.Abs(outputPath); err != nil {
+		result.Error = fmt.Errorf("failed to get absolute path: %w", err)
+		return result, result.Error
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		result.Error = fmt.Errorf("failed to create output directory: %w", err)
+		result.CompileTime = time.Since(startTime)
+		return result, result.Error
+	}
 
 	// Execute compilation
-	cmd := exec.Command(c.binaryPath, args...)
-	output, err := cmd.CombinedOutput()
+	output, retries, err := c.run(dir, names, outputPath, options)
 
 	result.CompileTime = time.Since(startTime)
+	result.Retries = retries
 
 	if err != nil {
-		result.Error = fmt.Errorf("compilation failed: %w\nOutput: %s", err, string(output))
+		result.Error = fmt.Errorf("compilation failed: %w\nOutput: %s", checkExecFormat(c.binaryPath, err), string(output))
 		return result, result.Error
 	}
 
@@ -158,6 +381,115 @@ func (c CLICompiler) CompileFile(filePath string, outputPath string, options Com
 	return result, nil
 }
 
+// run runs luac_mta to compile inputs into outputPath, in the sandbox or container when there
+// is one or with the compile API, and returns its combined output and the number of retries.
+// When dir is set, luac_mta runs in it and inputs are relative. The output is written next to
+// outputPath and renamed once it is complete, a failed compilation leaves outputPath as it was.
+func (c CLICompiler) run(dir string, inputs []string, outputPath string, options CompilationOptions) ([]byte, int, error) {
+	ctx := c.context()
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if c.remote != nil {
+		// The compile API retries its requests on its own
+		output, err := c.remote.run(ctx, dir, inputs, outputPath, options)
+		return output, 0, err
+	}
+
+	var output []byte
+	tries := 0
+	err := retrying(ctx, "Compilation of "+strings.Join(inputs, ", "), c.retries, c.retryDelay, transientCompileError, func() error {
+		tries++
+		var err error
+		output, err = c.runOnce(ctx, dir, inputs, outputPath, options)
+		return err
+	})
+	return output, tries - 1, err
+}
+
+// runOnce runs luac_mta once for run
+func (c CLICompiler) runOnce(ctx context.Context, dir string, inputs []string, outputPath string, options CompilationOptions) ([]byte, error) {
+	args := func(outputPath string, inputs []string) []string {
+		return append(c.buildArgs(options, outputPath), inputs...)
+	}
+	if c.runner != nil {
+		return runIsolated(ctx, c.runner, c.binaryPath, dir, inputs, outputPath, args)
+	}
+
+	partialPath := outputPath + partialSuffix
+	slog.Debug("Running luac_mta", "argv", strings.Join(append([]string{c.binaryPath}, args(partialPath, inputs)...), " "))
+	cmd := exec.CommandContext(ctx, c.binaryPath, args(partialPath, inputs)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(partialPath)
+		if ctx.Err() != nil {
+			return output, ctx.Err()
+		}
+		return output, err
+	}
+	if err := os.Rename(partialPath, outputPath); err != nil {
+		os.Remove(partialPath)
+		return output, fmt.Errorf("failed to write compiled file: %w", err)
+	}
+	return output, nil
+}
+
+// transientCompileError tells whether a compilation failed for a reason that may be gone on the
+// next attempt, such as a binary busy being written, a file locked by an antivirus on Windows or
+// a temporary file that could not be written. A script luac_mta rejected, a missing binary, a
+// binary that cannot run on this system and a cancellation are not transient.
+func transientCompileError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) || errors.Is(err, fs.ErrNotExist) || isExecFormatError(err) {
+		return false
+	}
+	// Windows reports files locked by another process as access denied
+	return runtime.GOOS == "windows" || !errors.Is(err, fs.ErrPermission)
+}
+
+// Parse runs luac_mta in parse-only mode (-p) over the Lua files, checking their syntax without
+// compiling them, and returns its combined output. luac_mta stops at the first file that does
+// not parse and exits with an error, an *exec.ExitError whose message is in the output. The
+// compile API has no parse-only mode.
+func (c CLICompiler) Parse(filePaths []string) ([]byte, error) {
+	if c.remote != nil {
+		return nil, fmt.Errorf("the compile API cannot parse scripts without compiling them")
+	}
+	ctx := c.context()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if c.runner != nil {
+		absBinary, err := filepath.Abs(c.binaryPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		absInputs := make([]string, 0, len(filePaths))
+		for _, path := range filePaths {
+			absInput, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get absolute path: %w", err)
+			}
+			absInputs = append(absInputs, absInput)
+		}
+		cmd = c.runner.command(ctx, absBinary, "", absInputs, "", append([]string{"-p", "--"}, absInputs...))
+	} else {
+		cmd = exec.CommandContext(ctx, c.binaryPath, append([]string{"-p", "--"}, filePaths...)...)
+	}
+	slog.Debug("Parsing with luac_mta", "argv", strings.Join(cmd.Args, " "))
+	output, err := cmd.CombinedOutput()
+	if err != nil && ctx.Err() != nil {
+		return output, ctx.Err()
+	}
+	return output, checkExecFormat(c.binaryPath, err)
+}
+
 // buildArgs builds the command line arguments for luac_mta
 func (c CLICompiler) buildArgs(options CompilationOptions, outputPath string) []string {
 	var args []string
@@ -187,5 +519,8 @@ func (c CLICompiler) buildArgs(options CompilationOptions, outputPath string) []
 		args = append(args, "-d")
 	}
 
+	// Options the bundler does not model
+	args = append(args, c.args...)
+
 	return args
 }
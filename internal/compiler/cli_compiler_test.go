@@ -0,0 +1,62 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildArgsExtra(t *testing.T) {
+	c := CLICompiler{binaryPath: "luac_mta"}.WithArgs([]string{"-x", "value"})
+	got := c.buildArgs(CompilationOptions{ObfuscationLevel: ObfuscationMaximum, StripDebug: true}, "out.luac")
+	expected := []string{"-o", "out.luac", "-s", "-e3", "-x", "value"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if err := ValidateArgs([]string{"-x", "value"}); err != nil {
+		t.Errorf("Expected unmodeled arguments to be allowed: %v", err)
+	}
+	for _, arg := range []string{"-o", "-e2", "--", " "} {
+		if err := ValidateArgs([]string{arg}); err == nil {
+			t.Errorf("Expected %q to be rejected", arg)
+		}
+	}
+}
+
+func TestCompileRetries(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("a binary open for writing is only busy on Linux")
+	}
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "luac_mta")
+	// Running a binary still open for writing fails with "text file busy"
+	binary, err := os.OpenFile(binaryPath, os.O_CREATE|os.O_WRONLY, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.WriteString("#!/bin/sh\nwhile [ $# -gt 0 ]; do [ \"$1\" = \"-o\" ] && printf '\\033LuaQ' > \"$2\"; shift; done\n")
+	time.AfterFunc(100*time.Millisecond, func() { binary.Close() })
+
+	sourcePath := filepath.Join(dir, "server.lua")
+	os.WriteFile(sourcePath, []byte("print(1)"), 0644)
+	c := CLICompiler{binaryPath: binaryPath}.WithRetries(5)
+	c.retryDelay = 50 * time.Millisecond
+	result, err := c.CompileFile(sourcePath, filepath.Join(dir, "server.luac"), CompilationOptions{})
+	if err != nil {
+		t.Fatalf("Expected the compilation to succeed once the binary is closed: %v", err)
+	}
+	if result.Retries == 0 {
+		t.Error("Expected the busy binary to be retried")
+	}
+
+	// A script luac_mta rejects is not retried
+	os.WriteFile(binaryPath, []byte("#!/bin/sh\necho 'syntax error' >&2\nexit 1\n"), 0755)
+	result, err = c.CompileFile(sourcePath, filepath.Join(dir, "server.luac"), CompilationOptions{})
+	if err == nil || result.Retries != 0 {
+		t.Errorf("Expected a rejected script to fail without retries, got %d retries and error %v", result.Retries, err)
+	}
+}
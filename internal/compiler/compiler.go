@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/units"
 )
 
 // ObfuscationLevel defines the level of code obfuscation
@@ -20,6 +22,18 @@ const (
 	ObfuscationMaximum
 )
 
+// MinMTAVersion returns the oldest MTA version that runs scripts compiled at level l, or an
+// empty string when every version does
+func (l ObfuscationLevel) MinMTAVersion() string {
+	switch l {
+	case ObfuscationEnhanced:
+		return "1.5.2-9.07903"
+	case ObfuscationMaximum:
+		return "1.5.6-9.18728"
+	}
+	return ""
+}
+
 // CompilationOptions holds configuration for the compilation process
 type CompilationOptions struct {
 	// ObfuscationLevel defines the level of code obfuscation
@@ -28,19 +42,19 @@ type CompilationOptions struct {
 	StripDebug bool
 	// SuppressDecompileWarning suppresses decompile warnings
 	SuppressDecompileWarning bool
-	// BinaryPath is the path to luac_mta executable (optional, will auto-detect)
-	BinaryPath string
 }
 
 // CompilationResult holds the result of a single file compilation operation
 type CompilationResult struct {
 	InputFile   string
+	InputFiles  []string // Files compiled into the output, in order
 	OutputFile  string
 	Success     bool
 	Error       error
 	CompileTime time.Duration
 	InputSize   int64 // Size before compilation in bytes
 	OutputSize  int64 // Size after compilation in bytes
+	Retries     int   // Times the compilation was run again after a transient failure
 }
 
 // LuaCompiler interface defines the contract for Lua compilation
@@ -85,16 +99,7 @@ func (r *CompilationResult) CompressionRatio() float64 {
 
 // FormatSize formats a size in bytes to a human-readable string
 func FormatSize(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return units.Format{}.Size(bytes)
 }
 
 // DefaultOptions returns sensible default compilation options
@@ -0,0 +1,101 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultContainerImage is the image the Linux luac_mta binary runs in with the docker strategy.
+// luac_mta only needs the C library, so any glibc based image works.
+const DefaultContainerImage = "debian:bookworm-slim"
+
+// containerPlatform is the platform of the luac_mta binary run in containers, emulated by
+// Docker on other CPUs (with Rosetta on Apple silicon)
+const containerPlatform = "linux/amd64"
+
+// Container runs the Linux luac_mta binary with Docker, on systems the MTA servers publish no
+// binary for such as macOS. Like the sandbox, the container has no network access and only
+// sees the binary, its input scripts (read-only) and an empty output directory.
+type Container struct {
+	dockerPath string
+	image      string
+}
+
+// NewContainer finds docker, checks that its daemon runs and pulls image when it is missing.
+// An empty image uses DefaultContainerImage.
+func NewContainer(image string) (Container, error) {
+	if runtime.GOOS == "windows" {
+		return Container{}, fmt.Errorf("the docker strategy is not supported on Windows, which runs luac_mta.exe directly")
+	}
+	if image == "" {
+		image = DefaultContainerImage
+	}
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return Container{}, fmt.Errorf("the docker strategy requires Docker, install Docker Desktop or another Docker engine")
+	}
+
+	if output, err := exec.Command(dockerPath, "version", "--format", "{{.Server.Version}}").CombinedOutput(); err != nil {
+		return Container{}, fmt.Errorf("cannot reach the Docker daemon (is Docker running?): %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	if exec.Command(dockerPath, "image", "inspect", image).Run() != nil {
+		slog.Info("Pulling compiler image", "image", image, "platform", containerPlatform)
+		if output, err := exec.Command(dockerPath, "pull", "--platform", containerPlatform, image).CombinedOutput(); err != nil {
+			return Container{}, fmt.Errorf("failed to pull image %s: %v: %s", image, err, strings.TrimSpace(string(output)))
+		}
+	}
+	slog.Debug("Compiler container available", "docker", dockerPath, "image", image)
+	return Container{dockerPath: dockerPath, image: image}, nil
+}
+
+// containerStopDelay is how long a stopped container has to exit before docker is killed
+const containerStopDelay = 10 * time.Second
+
+// command returns the command running the absolute binaryPath with args in a container. When
+// ctx is done, docker is sent SIGTERM instead of being killed: it forwards the signal to the
+// container, which would keep running after docker is killed.
+func (c Container) command(ctx context.Context, binaryPath string, dir string, inputs []string, outputDir string, args []string) *exec.Cmd {
+	dockerArgs := append(containerArgs(binaryPath, dir, inputs, outputDir), c.image, binaryPath)
+	cmd := exec.CommandContext(ctx, c.dockerPath, append(dockerArgs, args...)...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = containerStopDelay
+	return cmd
+}
+
+// containerArgs returns the docker run arguments of a container for the absolute binaryPath
+// and inputs, mounted like in the sandbox (see sandboxArgs)
+func containerArgs(binaryPath string, dir string, inputs []string, outputDir string) []string {
+	args := []string{"run", "--rm", "--network", "none", "--platform", containerPlatform}
+	if runtime.GOOS != "windows" {
+		// Output files belong to the user instead of root
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+
+	mounted := make(map[string]bool)
+	paths := []string{binaryPath}
+	if dir == "" {
+		paths = append(paths, inputs...)
+	}
+	for _, path := range paths {
+		if !mounted[path] {
+			mounted[path] = true
+			args = append(args, "--volume", path+":"+path+":ro")
+		}
+	}
+	if outputDir != "" {
+		args = append(args, "--volume", outputDir+":"+sandboxOutputDir)
+	}
+	if dir != "" {
+		return append(args, "--volume", dir+":"+sandboxWorkDir+":ro", "--workdir", sandboxWorkDir)
+	}
+	return append(args, "--workdir", "/")
+}
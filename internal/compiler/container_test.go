@@ -0,0 +1,29 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContainerArgs(t *testing.T) {
+	args := strings.Join(containerArgs("/opt/luac_mta", "", []string{"/tmp/res/a.lua", "/tmp/res/a.lua"}, "/tmp/out"), " ")
+
+	for _, expected := range []string{
+		"--network none ",
+		"--platform " + containerPlatform + " ",
+		"--volume /opt/luac_mta:/opt/luac_mta:ro ",
+		"--volume /tmp/out:" + sandboxOutputDir + " ",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in %s", expected, args)
+		}
+	}
+	if strings.Count(args, "/tmp/res/a.lua:/tmp/res/a.lua:ro") != 1 {
+		t.Errorf("Expected every input to be mounted once: %s", args)
+	}
+
+	args = strings.Join(containerArgs("/opt/luac_mta", "/tmp/concat", []string{"client.lua"}, "/tmp/out"), " ")
+	if !strings.HasSuffix(args, "--volume /tmp/concat:"+sandboxWorkDir+":ro --workdir "+sandboxWorkDir) || strings.Contains(args, "client.lua") {
+		t.Errorf("Expected the working directory to be mounted instead of relative inputs: %s", args)
+	}
+}
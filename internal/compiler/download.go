@@ -0,0 +1,266 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DownloadProgress is notified while the luac_mta binary is downloaded
+type DownloadProgress interface {
+	StartDownload(total int64)  // The download started, total is -1 when the size is unknown
+	AdvanceDownload(done int64) // done bytes have been received, including resumed ones
+	StopDownload()              // The download is over
+}
+
+// progressInterval is the minimum time between two progress updates during a download
+const progressInterval = 100 * time.Millisecond
+
+// partialSuffix is appended to the path of a download or compiled file until it is complete,
+// so an interrupted download or compilation never leaves a truncated file behind
+const partialSuffix = ".part"
+
+// DefaultDownloadTimeout is the time a download of luac_mta may take before it is interrupted
+const DefaultDownloadTimeout = 2 * time.Minute
+
+// NewDownloadClient returns the HTTP client luac_mta is downloaded with. Requests go through
+// proxy when it is set, and else through the proxy of the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+// environment variables. A download taking longer than timeout is interrupted, to be resumed by
+// the next run, and 0 sets no limit.
+func NewDownloadClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	if err := ValidateProxy(proxy); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != "" {
+		proxyURL, _ := url.Parse(proxy)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// ValidateProxy checks that proxy is an HTTP, HTTPS or SOCKS5 proxy URL
+func ValidateProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+	parsed, err := url.Parse(proxy)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("proxy %q is not a URL, such as http://proxy.example.com:3128", proxy)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("proxy %q has unsupported scheme %q (use http, https or socks5)", proxy, parsed.Scheme)
+	}
+}
+
+// download fetches url to path with client. Data is written to path.part first and only renamed to path
+// once the received size matches the announced size, so an interrupted download never leaves
+// a truncated binary behind. An existing path.part is resumed with a range request. When
+// checksums are given, the complete download must have one of these SHA-256 hashes or it is
+// deleted.
+// Cancelling ctx interrupts the download, keeping path.part to be resumed.
+func download(ctx context.Context, client *http.Client, url, path string, checksums []string, progress DownloadProgress) error {
+	partPath := path + partialSuffix
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)):
+		slog.Info("Resuming download", "downloaded_size", offset)
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range request or there was nothing to resume
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable || resp.StatusCode == http.StatusPartialContent:
+		// The partial file does not match the file on the server anymore
+		slog.Warn("Cannot resume download, starting over", "path", partPath)
+		if err := os.Remove(partPath); err != nil {
+			return err
+		}
+		return download(ctx, client, url, path, checksums, progress)
+	default:
+		return StatusError{Status: resp.Status, Code: resp.StatusCode}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	counter := &progressWriter{done: offset, progress: progress}
+	if progress != nil {
+		progress.StartDownload(total)
+		progress.AdvanceDownload(offset)
+	}
+	_, err = io.Copy(out, io.TeeReader(resp.Body, counter))
+	if progress != nil {
+		progress.StopDownload()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download interrupted after %s, run again to resume: %w", FormatSize(counter.done), err)
+	}
+
+	if total >= 0 && counter.done != total {
+		return fmt.Errorf("download incomplete: received %s of %s, run again to resume", FormatSize(counter.done), FormatSize(total))
+	}
+
+	if err := verifyChecksum(partPath, checksums); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	return os.Rename(partPath, path)
+}
+
+// StatusError reports a download answered with an unexpected HTTP status
+type StatusError struct {
+	Status string // Status line, such as "404 Not Found"
+	Code   int    // Status code
+}
+
+// Error describes the status
+func (e StatusError) Error() string {
+	return "bad status: " + e.Status
+}
+
+// Transient tells whether the server may answer differently later, so the download is retried
+func (e StatusError) Transient() bool {
+	return e.Code >= 500 || e.Code == http.StatusTooManyRequests || e.Code == http.StatusRequestTimeout
+}
+
+// DefaultDownloadRetries is the number of times a failed download of luac_mta is retried
+const DefaultDownloadRetries = 3
+
+// defaultRetryDelay is the delay before the first retry of a download, doubled for each retry
+const defaultRetryDelay = time.Second
+
+// maxRetryDelay is the longest delay between two attempts of a download
+const maxRetryDelay = 30 * time.Second
+
+// retrying runs attempt, such as a download resuming the partial file of the previous attempt,
+// until it succeeds or fails for good. Failures transient reports are retried up to retries
+// times, the delay between attempts starting at delay and doubling each time, up to
+// maxRetryDelay. what names the attempt in warnings, such as Download.
+func retrying(ctx context.Context, what string, retries int, delay time.Duration, transient func(context.Context, error) bool, attempt func() error) error {
+	for try := 1; ; try++ {
+		err := attempt()
+		if err == nil || try > retries || !transient(ctx, err) {
+			return err
+		}
+
+		slog.Warn(what+" failed, retrying", "attempt", try, "retries", retries, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// transientError tells whether a request failed because of the connection or the server
+// rather than a wrong binary or script, a missing file, a local error or a cancellation
+func transientError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var status StatusError
+	if errors.As(err, &status) {
+		return status.Transient()
+	}
+	var mismatch ChecksumError
+	var compileErr RemoteCompileError
+	var pathErr *fs.PathError
+	return !errors.As(err, &mismatch) && !errors.As(err, &compileErr) && !errors.As(err, &pathErr)
+}
+
+// ChecksumError reports a luac_mta binary whose SHA-256 hash is not the expected one, because
+// it was corrupted or tampered with, or replaced by a new release
+type ChecksumError struct {
+	Expected []string // Hex-encoded SHA-256 hashes the binary may have
+	Actual   string   // Hex-encoded SHA-256 hash of the binary
+}
+
+// Error describes the mismatch with shortened hashes
+func (e ChecksumError) Error() string {
+	expected := make([]string, len(e.Expected))
+	for i, checksum := range e.Expected {
+		expected[i] = checksum[:min(12, len(checksum))]
+	}
+	return fmt.Sprintf("luac_mta binary has SHA-256 %.12s, expected %s", e.Actual, strings.Join(expected, " or "))
+}
+
+// verifyChecksum checks that the file at path has one of the SHA-256 hashes checksums, when
+// there are any
+func verifyChecksum(path string, checksums []string) error {
+	if len(checksums) == 0 {
+		return nil
+	}
+	hash, err := HashBinary(path)
+	if err != nil {
+		return err
+	}
+	for _, checksum := range checksums {
+		if strings.EqualFold(hash, checksum) {
+			return nil
+		}
+	}
+	return ChecksumError{Expected: checksums, Actual: hash}
+}
+
+// progressWriter counts the bytes written through it and reports them at most once per progressInterval
+type progressWriter struct {
+	done     int64
+	progress DownloadProgress
+	reported time.Time
+}
+
+// Write counts p and reports the progress when the interval has elapsed
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	if w.progress != nil && time.Since(w.reported) >= progressInterval {
+		w.progress.AdvanceDownload(w.done)
+		w.reported = time.Now()
+	}
+	return len(p), nil
+}
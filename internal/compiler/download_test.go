@@ -0,0 +1,190 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadResumes(t *testing.T) {
+	content := bytes.Repeat([]byte("luac_mta"), 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "luac_mta", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	if err := os.WriteFile(path+partialSuffix, content[:1000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := download(context.Background(), server.Client(), server.URL, path, nil, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("Expected the resumed download to match the original (%d bytes), got %d bytes: %v", len(content), len(got), err)
+	}
+	if _, err := os.Stat(path + partialSuffix); !os.IsNotExist(err) {
+		t.Error("Expected the partial file to be renamed")
+	}
+}
+
+func TestDownloadTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("only part of the binary"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	err := download(context.Background(), server.Client(), server.URL, path, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "run again to resume") {
+		t.Fatalf("Expected an interrupted download error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected no binary to be written for a truncated download")
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	content := []byte("\x7fELF not quite luac_mta")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	err := download(context.Background(), server.Client(), server.URL, path, []string{strings.Repeat("0", 64)}, nil)
+	var mismatch ChecksumError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a ChecksumError, got %v", err)
+	}
+	for _, leftover := range []string{path, path + partialSuffix} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted after a checksum mismatch", leftover)
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	if err := download(context.Background(), server.Client(), server.URL, path, []string{strings.Repeat("1", 64), hex.EncodeToString(sum[:])}, nil); err != nil {
+		t.Fatalf("Expected a matching download to succeed: %v", err)
+	}
+}
+
+func TestDownloadClientProxy(t *testing.T) {
+	content := []byte("luac_mta through the proxy")
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Write(content)
+	}))
+	defer proxy.Close()
+
+	client, err := NewDownloadClient(proxy.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	if err := download(context.Background(), client, "http://luac.invalid/files/linux/x64/luac_mta", path, nil, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if requested != "http://luac.invalid/files/linux/x64/luac_mta" {
+		t.Errorf("Expected the download to go through the proxy, the proxy got %q", requested)
+	}
+
+	for _, invalid := range []string{"proxy.example.com:3128", "ftp://proxy.example.com"} {
+		if _, err := NewDownloadClient(invalid, time.Second); err == nil {
+			t.Errorf("Expected proxy %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestDownloadCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("the first bytes"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := cancelOnProgress(cancel)
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	err := download(ctx, server.Client(), server.URL, path, nil, progress)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancelled download, got %v", err)
+	}
+	if data, err := os.ReadFile(path + partialSuffix); err != nil || string(data) != "the first bytes" {
+		t.Errorf("Expected the partial file to be kept for resuming, got %q: %v", data, err)
+	}
+}
+
+// cancelOnProgress cancels a download once it received data
+type cancelOnProgress context.CancelFunc
+
+func (c cancelOnProgress) StartDownload(int64) {}
+func (c cancelOnProgress) AdvanceDownload(done int64) {
+	if done > 0 {
+		c()
+	}
+}
+func (c cancelOnProgress) StopDownload() {}
+
+func TestDownloadRetries(t *testing.T) {
+	content := bytes.Repeat([]byte("luac_mta"), 1024)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// The connection drops in the middle of the binary
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.Write(content[:1000])
+			panic(http.ErrAbortHandler)
+		default:
+			http.ServeContent(w, r, "luac_mta", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "luac_mta")
+	err := retrying(context.Background(), "Download", 3, time.Millisecond, transientError, func() error {
+		return download(context.Background(), server.Client(), server.URL, path, nil, nil)
+	})
+	if err != nil {
+		t.Fatalf("Expected the download to succeed after retries: %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("Expected the retried download to match the original (%d bytes), got %d bytes: %v", len(content), len(got), err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+
+	// A missing binary will not appear by asking again
+	requests = 0
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	err = retrying(context.Background(), "Download", 3, time.Millisecond, transientError, func() error {
+		requests++
+		return download(context.Background(), missing.Client(), missing.URL, path+"2", nil, nil)
+	})
+	var status StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusNotFound || requests != 1 {
+		t.Errorf("Expected a single 404 attempt, got %d attempts: %v", requests, err)
+	}
+}
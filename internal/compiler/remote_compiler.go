@@ -0,0 +1,198 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRemoteURL is the compile API of the MTA servers, the web version of luac_mta
+const DefaultRemoteURL = "https://luac.mtasa.com/"
+
+// RemoteEnv is the environment variable giving the compile API used instead of DefaultRemoteURL,
+// such as a proxy of the MTA servers
+const RemoteEnv = "LUAC_MTA_REMOTE"
+
+// DefaultRemoteJobs is the number of scripts compiled remotely at the same time
+const DefaultRemoteJobs = 4
+
+// RemoteCompiler compiles scripts with the compile API of the MTA servers instead of a local
+// luac_mta binary, for systems no binary can run on. Every script is uploaded, so it must be
+// enabled explicitly. The API compiles one script per request: scripts cannot be merged into
+// one chunk list like luac_mta does, and luac_mta arguments cannot be passed.
+type RemoteCompiler struct {
+	url     string
+	client  *http.Client
+	slots   chan struct{} // Limits the requests running at the same time, shared by copies
+	retries int           // Number of times a request failing because of the connection is retried
+	delay   time.Duration // Delay before the first retry, doubled for each retry
+}
+
+// RemoteCompileError reports a script the compile API refused, usually a syntax error
+type RemoteCompileError struct {
+	Message string // Message of the API
+}
+
+// Error returns the message of the API
+func (e RemoteCompileError) Error() string {
+	return "remote compilation failed: " + e.Message
+}
+
+// NewRemoteCompiler creates a compiler uploading scripts to the compile API at apiURL, empty for
+// DefaultRemoteURL, with client. At most jobs scripts are compiled at the same time, and a
+// request failing because of the connection or the server is retried up to retries times.
+func NewRemoteCompiler(apiURL string, client *http.Client, jobs, retries int) RemoteCompiler {
+	if apiURL == "" {
+		apiURL = DefaultRemoteURL
+	}
+	if jobs < 1 {
+		jobs = DefaultRemoteJobs
+	}
+	return RemoteCompiler{
+		url:     apiURL,
+		client:  client,
+		slots:   make(chan struct{}, jobs),
+		retries: retries,
+		delay:   defaultRetryDelay,
+	}
+}
+
+// ValidateRemoteURL checks that apiURL is an HTTP or HTTPS URL, when it is set
+func ValidateRemoteURL(apiURL string) error {
+	if apiURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(apiURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("compile API %q is not an HTTP or HTTPS URL", apiURL)
+	}
+	return nil
+}
+
+// URL returns the URL of the compile API
+func (r RemoteCompiler) URL() string {
+	return r.url
+}
+
+// Compile compiles a single Lua file with the compile API, filePaths cannot list several files
+func (r RemoteCompiler) Compile(filePaths []string, outputPath string, options CompilationOptions) (CompilationResult, error) {
+	return CLICompiler{}.WithRemote(r).Compile(filePaths, outputPath, options)
+}
+
+// CompileFile compiles a single Lua file with the compile API
+func (r RemoteCompiler) CompileFile(filePath string, outputPath string, options CompilationOptions) (CompilationResult, error) {
+	return CLICompiler{}.WithRemote(r).CompileFile(filePath, outputPath, options)
+}
+
+// ValidateFiles checks if all provided files exist and are Lua files
+func (r RemoteCompiler) ValidateFiles(filePaths []string) error {
+	return CLICompiler{}.WithRemote(r).ValidateFiles(filePaths)
+}
+
+// fingerprint identifies the compile API in place of a binary hash
+func (r RemoteCompiler) fingerprint() string {
+	hash := sha256.Sum256([]byte("remote " + r.url))
+	return hex.EncodeToString(hash[:])
+}
+
+// run compiles the input into outputPath with the compile API, like CLICompiler.run. When dir
+// is set, the input is relative to it. Requests are cancelled when ctx is done.
+func (r RemoteCompiler) run(ctx context.Context, dir string, inputs []string, outputPath string, options CompilationOptions) ([]byte, error) {
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("the compile API compiles one script per request and cannot merge %d scripts, use -merge-strategy concat", len(inputs))
+	}
+	source, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(inputs[0])))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	if options.SuppressDecompileWarning {
+		slog.Debug("The compile API has no option to suppress the decompile warning", "file", inputs[0])
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.slots }()
+
+	var compiled []byte
+	err = retrying(ctx, "Remote compilation", r.retries, r.delay, transientError, func() error {
+		compiled, err = r.request(ctx, source, options)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	partialPath := outputPath + partialSuffix
+	if err := os.WriteFile(partialPath, compiled, 0644); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to write compiled script: %w", err)
+	}
+	if err := os.Rename(partialPath, outputPath); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to write compiled script: %w", err)
+	}
+	return nil, nil
+}
+
+// request uploads source to the compile API and returns the compiled script
+func (r RemoteCompiler) request(ctx context.Context, source []byte, options CompilationOptions) ([]byte, error) {
+	query := url.Values{}
+	query.Set("compile", "1")
+	query.Set("debug", "1")
+	if options.StripDebug {
+		query.Set("debug", "0")
+	}
+	query.Set("obfuscate", strconv.Itoa(int(options.ObfuscationLevel)))
+
+	apiURL := r.url
+	if strings.Contains(apiURL, "?") {
+		apiURL += "&" + query.Encode()
+	} else {
+		apiURL += "?" + query.Encode()
+	}
+	slog.Debug("Compiling remotely", "url", apiURL, "size", len(source))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, StatusError{Status: resp.Status, Code: resp.StatusCode}
+	}
+	compiled, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compiled script: %w", err)
+	}
+
+	// Compiled scripts start with a control byte, messages of the API are text
+	switch {
+	case len(compiled) == 0:
+		return nil, fmt.Errorf("the compile API returned nothing")
+	case bytes.HasPrefix(compiled, []byte("ERROR")):
+		return nil, RemoteCompileError{Message: strings.TrimSpace(string(compiled))}
+	case compiled[0] >= 0x20:
+		return nil, fmt.Errorf("the compile API returned text instead of a compiled script: %.80q", compiled)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,93 @@
+package compiler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoteCompiler(t *testing.T) {
+	var requests, running, maxRunning atomic.Int32
+	var failed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !failed.Swap(true) {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		now := running.Add(1)
+		defer running.Add(-1)
+		for {
+			peak := maxRunning.Load()
+			if now <= peak || maxRunning.CompareAndSwap(peak, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		source, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(source), "syntax error") {
+			w.Write([]byte("ERROR Could not compile file"))
+			return
+		}
+		query := r.URL.Query()
+		w.Write([]byte("\x1bLua " + query.Get("debug") + query.Get("obfuscate") + " " + string(source)))
+	}))
+	defer server.Close()
+
+	remote := NewRemoteCompiler(server.URL+"/", server.Client(), 2, 1)
+	remote.delay = time.Millisecond
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// The first request fails with 502 and is retried
+	options := CompilationOptions{ObfuscationLevel: ObfuscationMaximum, StripDebug: true}
+	result, err := remote.CompileFile(write("a.lua", "print(1)"), filepath.Join(dir, "a.luac"), options)
+	if err != nil {
+		t.Fatalf("CompileFile failed: %v", err)
+	}
+	if got, _ := os.ReadFile(result.OutputFile); string(got) != "\x1bLua 03 print(1)" {
+		t.Errorf("Expected the compiled script of the API, got %q", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := string(rune('b'+i)) + ".lua"
+			if _, err := remote.CompileFile(write(name, "print(2)"), filepath.Join(dir, name+"c"), options); err != nil {
+				t.Errorf("CompileFile failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak := maxRunning.Load(); peak > 2 {
+		t.Errorf("Expected at most 2 requests at the same time, got %d", peak)
+	}
+
+	requests.Store(0)
+	_, err = remote.CompileFile(write("bad.lua", "syntax error"), filepath.Join(dir, "bad.luac"), options)
+	var compileErr RemoteCompileError
+	if !errors.As(err, &compileErr) || requests.Load() != 1 {
+		t.Errorf("Expected a single failed request, got %d: %v", requests.Load(), err)
+	}
+
+	if _, err := remote.Compile([]string{result.InputFile, result.InputFile}, filepath.Join(dir, "merged.luac"), options); err == nil {
+		t.Error("Expected several scripts to be refused")
+	}
+}
@@ -0,0 +1,176 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// sandboxOutputDir is where the sandboxed compiler writes its output. It is a private
+// directory of the build mounted over the sandbox root, the only writable place besides /tmp.
+const sandboxOutputDir = "/mta-bundler-output"
+
+// sandboxWorkDir is where the working directory of a compilation with relative inputs is
+// mounted read-only, and where the sandboxed compiler runs
+const sandboxWorkDir = "/mta-bundler-input"
+
+// sandboxSystemPaths are mounted read-only into the sandbox when they exist, so dynamically
+// linked binaries find their libraries and script launchers their interpreter
+var sandboxSystemPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc/ld.so.cache", "/etc/alternatives"}
+
+// Sandbox runs luac_mta with bubblewrap (bwrap): without network access or other namespaces
+// of the host, seeing only the system libraries, its input scripts (read-only) and an empty
+// output directory
+type Sandbox struct {
+	bwrapPath string
+}
+
+// NewSandbox finds bwrap and checks that it can create sandboxes, which needs unprivileged
+// user namespaces. Sandboxing is only supported on Linux.
+func NewSandbox() (Sandbox, error) {
+	if runtime.GOOS != "linux" {
+		return Sandbox{}, fmt.Errorf("compiler sandboxing is only supported on Linux")
+	}
+	bwrapPath, err := exec.LookPath("bwrap")
+	if err != nil {
+		return Sandbox{}, fmt.Errorf("compiler sandboxing requires bubblewrap (bwrap), install it from your distribution's packages")
+	}
+
+	if output, err := exec.Command(bwrapPath, "--unshare-all", "--ro-bind", "/", "/", "true").CombinedOutput(); err != nil {
+		return Sandbox{}, fmt.Errorf("bwrap cannot create a sandbox (are unprivileged user namespaces disabled?): %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	slog.Debug("Compiler sandbox available", "bwrap", bwrapPath)
+	return Sandbox{bwrapPath: bwrapPath}, nil
+}
+
+// runner runs luac_mta isolated from the host, such as in a sandbox or a container, seeing only
+// its inputs and an output directory mounted at sandboxOutputDir
+type runner interface {
+	// command returns the command running the absolute binaryPath with args, seeing the
+	// absolute inputs and outputDir, or dir instead of the inputs when it is set. The command is
+	// stopped when ctx is done.
+	command(ctx context.Context, binaryPath string, dir string, inputs []string, outputDir string, args []string) *exec.Cmd
+}
+
+// runIsolated runs binaryPath with r to compile inputs into outputPath. args returns the
+// compiler arguments for the output path and absolute inputs inside the isolation. The output
+// is moved to outputPath once the compiler succeeded. When dir is set, inputs stay relative
+// to it and the compiler runs in a read-only copy of it.
+func runIsolated(ctx context.Context, r runner, binaryPath string, dir string, inputs []string, outputPath string, args func(outputPath string, inputs []string) []string) ([]byte, error) {
+	absBinary, err := filepath.Abs(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	absInputs := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		if dir != "" {
+			absInputs = append(absInputs, input)
+			continue
+		}
+		absInput, err := filepath.Abs(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		absInputs = append(absInputs, absInput)
+	}
+	if dir != "" {
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+	}
+
+	outputDir, err := os.MkdirTemp("", "mta-bundler-sandbox-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox output directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	cmd := r.command(ctx, absBinary, dir, absInputs, outputDir, args(sandboxOutputDir+"/"+filepath.Base(outputPath), absInputs))
+	slog.Debug("Running luac_mta isolated", "argv", strings.Join(cmd.Args, " "))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return output, ctx.Err()
+		}
+		return output, err
+	}
+	if err := moveFile(filepath.Join(outputDir, filepath.Base(outputPath)), outputPath); err != nil {
+		return output, fmt.Errorf("failed to move compiled file out of the isolation: %w", err)
+	}
+	return output, nil
+}
+
+// command returns the command running the absolute binaryPath with args in the sandbox
+func (s Sandbox) command(ctx context.Context, binaryPath string, dir string, inputs []string, outputDir string, args []string) *exec.Cmd {
+	bwrapArgs := append(sandboxArgs(binaryPath, dir, inputs, outputDir), binaryPath)
+	return exec.CommandContext(ctx, s.bwrapPath, append(bwrapArgs, args...)...)
+}
+
+// sandboxArgs returns the bwrap arguments of a sandbox for the absolute binaryPath and
+// inputs, with outputDir mounted writable at sandboxOutputDir unless it is empty. Inputs keep
+// their paths, so compiler messages name the original files. When dir is set, it is mounted
+// at sandboxWorkDir instead of the inputs, which are relative to it.
+func sandboxArgs(binaryPath string, dir string, inputs []string, outputDir string) []string {
+	args := []string{
+		"--unshare-all", "--die-with-parent", "--new-session",
+		"--clearenv", "--setenv", "PATH", "/usr/bin:/bin",
+		"--proc", "/proc", "--dev", "/dev", "--tmpfs", "/tmp",
+	}
+	for _, path := range sandboxSystemPaths {
+		args = append(args, "--ro-bind-try", path, path)
+	}
+
+	mounted := make(map[string]bool)
+	paths := []string{binaryPath}
+	if dir == "" {
+		paths = append(paths, inputs...)
+	}
+	for _, path := range paths {
+		if !mounted[path] {
+			mounted[path] = true
+			args = append(args, "--ro-bind", path, path)
+		}
+	}
+	if outputDir != "" {
+		args = append(args, "--bind", outputDir, sandboxOutputDir)
+	}
+	if dir != "" {
+		return append(args, "--ro-bind", dir, sandboxWorkDir, "--chdir", sandboxWorkDir)
+	}
+	return append(args, "--chdir", "/")
+}
+
+// moveFile moves src to dst, copying it when they are on different file systems. A copy is
+// written next to dst and renamed once complete, so dst is never left truncated.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	partialPath := dst + partialSuffix
+	out, err := os.Create(partialPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(partialPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+	return os.Rename(partialPath, dst)
+}
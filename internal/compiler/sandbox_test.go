@@ -0,0 +1,37 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSandboxArgs(t *testing.T) {
+	args := strings.Join(sandboxArgs("/opt/luac_mta", "", []string{"/tmp/res/a.lua", "/tmp/res/b.lua", "/tmp/res/a.lua"}, "/tmp/out"), " ")
+
+	for _, expected := range []string{
+		"--unshare-all ",
+		"--clearenv ",
+		"--ro-bind /opt/luac_mta /opt/luac_mta ",
+		"--bind /tmp/out " + sandboxOutputDir + " ",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in %s", expected, args)
+		}
+	}
+	if strings.Count(args, "--ro-bind /tmp/res/a.lua") != 1 {
+		t.Errorf("Expected every input to be mounted once: %s", args)
+	}
+	// Inputs below /tmp must be mounted over the private /tmp, not hidden by it
+	if strings.Index(args, "--tmpfs /tmp") > strings.Index(args, "--ro-bind /tmp/res/a.lua") {
+		t.Errorf("Expected /tmp to be mounted before the inputs: %s", args)
+	}
+
+	if args := strings.Join(sandboxArgs("/opt/luac_mta", "", nil, ""), " "); strings.Contains(args, "--bind ") {
+		t.Errorf("Expected no writable mount without an output directory: %s", args)
+	}
+
+	args = strings.Join(sandboxArgs("/opt/luac_mta", "/tmp/concat", []string{"client.lua"}, "/tmp/out"), " ")
+	if !strings.HasSuffix(args, "--ro-bind /tmp/concat "+sandboxWorkDir+" --chdir "+sandboxWorkDir) || strings.Contains(args, "client.lua") {
+		t.Errorf("Expected the working directory to be mounted instead of relative inputs: %s", args)
+	}
+}
@@ -0,0 +1,50 @@
+package compiler
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Compiler strategies, how luac_mta runs on systems the MTA servers publish no binary for
+const (
+	StrategyNative  = "native"  // Run a local or downloaded binary of this platform (default)
+	StrategyDocker  = "docker"  // Run the Linux binary in a Docker container
+	StrategyRosetta = "rosetta" // Run a macOS binary built by the user, under Rosetta on Apple silicon
+	StrategyRemote  = "remote"  // Upload scripts to the compile API of the MTA servers
+)
+
+// Strategies lists the compiler strategies
+var Strategies = []string{StrategyNative, StrategyDocker, StrategyRosetta, StrategyRemote}
+
+// ValidateStrategy checks that strategy is a known compiler strategy, an empty strategy is the
+// native one. Whether the strategy can be used on this system is only known once it is used.
+func ValidateStrategy(strategy string) error {
+	if strategy != "" && !slices.Contains(Strategies, strategy) {
+		return fmt.Errorf("unknown compiler strategy %q (use %s)", strategy, strings.Join(Strategies, ", "))
+	}
+	return nil
+}
+
+// CheckRosetta checks that binaryPath is a macOS binary for the rosetta strategy, and returns
+// whether it runs under Rosetta because it was only built for Intel Macs and the host has an
+// Apple silicon CPU. It fails when Rosetta is needed but not installed.
+func CheckRosetta(binaryPath string) (bool, error) {
+	if runtime.GOOS != "darwin" {
+		return false, fmt.Errorf("the rosetta strategy is only supported on macOS")
+	}
+	platform := binaryPlatform(binaryPath)
+	if !strings.HasPrefix(platform, "darwin/") {
+		return false, fmt.Errorf("luac_mta binary %s is built for %s, the rosetta strategy needs a macOS binary", binaryPath, platform)
+	}
+	archs := strings.Split(strings.TrimPrefix(platform, "darwin/"), "+")
+	if slices.Contains(archs, runtime.GOARCH) || runtime.GOARCH != "arm64" || !slices.Contains(archs, "amd64") {
+		return false, nil
+	}
+	if exec.Command("/usr/bin/arch", "-x86_64", "/usr/bin/true").Run() != nil {
+		return true, fmt.Errorf("luac_mta binary %s is built for Intel Macs and needs Rosetta, install it with: softwareupdate --install-rosetta", binaryPath)
+	}
+	return true, nil
+}
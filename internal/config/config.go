@@ -0,0 +1,506 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/lua"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/retention"
+	"github.com/davidbozo/mta-bundler/internal/schedule"
+	"github.com/davidbozo/mta-bundler/internal/units"
+	"gopkg.in/yaml.v3"
+)
+
+// Orders of merged scripts
+const (
+	MergeOrderType = "type" // Client or server scripts first, then shared scripts (default)
+	MergeOrderMeta = "meta" // Every script at its position in meta.xml
+)
+
+// Strategies of merging scripts into a bundle
+const (
+	MergeStrategyFiles  = "files"  // Every script is passed to luac_mta as a separate chunk (default)
+	MergeStrategyConcat = "concat" // Scripts are concatenated into one source, each in its own do...end block
+)
+
+// FileName is the name of the project config file looked up at the input root
+const FileName = ".mtabundler.yml"
+
+// resourceNamePattern matches the names accepted for generated resources
+var resourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// luaNamePattern matches the names of Lua variables, keywords aside
+var luaNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateResourceName checks that name can be used for a generated resource
+func ValidateResourceName(name string) error {
+	if !resourceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid resource name %q (use letters, digits, '-' and '_')", name)
+	}
+	return nil
+}
+
+// Config represents a project configuration file. Pointer fields distinguish
+// "not set" from zero values so that only configured settings are applied.
+type Config struct {
+	Output           string       `yaml:"output"`            // Output directory, relative paths are resolved from the config file
+	Obfuscation      *int         `yaml:"obfuscation"`       // Obfuscation level (0-3)
+	StripDebug       *bool        `yaml:"strip_debug"`       // Strip debug information
+	SuppressWarnings *bool        `yaml:"suppress_warnings"` // Suppress decompile warning
+	Merge            *bool        `yaml:"merge"`             // Merge scripts into client.luac and server.luac
+	MergeOrder       string       `yaml:"merge_order"`       // Order of merged scripts: type or meta
+	MergeStrategy    string       `yaml:"merge_strategy"`    // How scripts are merged: files or concat
+	MergeIsolate     *bool        `yaml:"merge_isolate"`     // Run every merged script through pcall (implies concat)
+	BundleName       string       `yaml:"bundle_name"`       // Path of the merged bundles in the output resource, {type} is client or server
+	Banner           string       `yaml:"banner"`            // Comment written at the top of output meta.xml files and merged bundles
+	Info             []string     `yaml:"info"`              // Attributes set on the <info> tag of output meta.xml files, as name=value or name+=value
+	Exclude          []string     `yaml:"exclude"`           // Resource name or path globs to skip
+	Ignore           []string     `yaml:"ignore"`            // Directory name or path globs not searched for resources
+	MaxDepth         int          `yaml:"max_depth"`         // Levels of directories searched below the input, unlimited when 0
+	SkipCategories   []string     `yaml:"skip_categories"`   // Category folders not searched for resources, by name without brackets
+	FollowSymlinks   *bool        `yaml:"follow_symlinks"`   // Search symlinked directories for resources
+	Verbatim         []string     `yaml:"verbatim"`          // Script src globs copied as source instead of compiled
+	MergeExclude     []string     `yaml:"merge_exclude"`     // Script src globs compiled on their own in merge mode
+	Packs            []Pack       `yaml:"packs"`             // Groups of resources built into a single resource each
+	Splits           []Split      `yaml:"splits"`            // Resources built as several resources each
+	Dependencies     []Dependency `yaml:"dependencies"`      // Third-party resources downloaded and built with the project
+	BuildInfo        string       `yaml:"build_info"`        // Name of the generated build info resource
+	Checksums        *bool        `yaml:"checksums"`         // Write checksums.txt and checksums.json to the output directory
+	Scan             *bool        `yaml:"scan"`              // Scan scripts for backdoor patterns before compiling
+	SyntaxCheck      *bool        `yaml:"syntax_check"`      // false leaves syntax errors to luac_mta instead of parsing scripts before the build
+	Preflight        *bool        `yaml:"preflight"`         // Parse every script with luac_mta -p in batches before the build
+	Minify           *bool        `yaml:"minify"`            // Strip comments and whitespace from scripts before compiling them
+	RenameLocals     *bool        `yaml:"rename_locals"`     // Rename the locals of scripts to short meaningless names before compiling them
+	TreeShake        *bool        `yaml:"tree_shake"`        // Drop the top-level functions of merged bundles that nothing calls
+	FoldConstants    *bool        `yaml:"fold_constants"`    // Fold constant expressions and remove constant if branches of scripts before compiling them
+	Constants        Constants    `yaml:"constants"`         // Values inlined for reads of these globals by the constant folding pass
+	ClientCache      *bool        `yaml:"client_cache"`      // false sets cache="false" on every client script of the output
+	RaiseMinVersion  *bool        `yaml:"raise_min_version"` // false leaves <min_mta_version> as it is, whatever the obfuscation level needs
+	LevelFallback    *bool        `yaml:"level_fallback"`    // Lower an obfuscation level luac_mta is too old for to the highest it supports instead of failing
+	CompilerArgs     []string     `yaml:"compiler_args"`     // Arguments passed to luac_mta after the options the bundler models
+	CompilerMaxAge   string       `yaml:"compiler_max_age"`  // Download luac_mta again when the cached binary is older than this, such as 30d
+	CompilerMirror   string       `yaml:"compiler_mirror"`   // URL template luac_mta is downloaded from instead of the MTA servers
+	CompilerProxy    string       `yaml:"compiler_proxy"`    // Proxy luac_mta is downloaded through instead of the one of the environment
+	DownloadTimeout  string       `yaml:"download_timeout"`  // Time a luac_mta download may take before it is interrupted, such as 5m
+	DownloadRetries  *int         `yaml:"download_retries"`  // Number of times a luac_mta download failing because of the connection is retried
+	Retries          *int         `yaml:"retries"`           // Number of times a compilation failing for a transient reason is run again
+	CompilerStrategy string       `yaml:"compiler_strategy"` // How luac_mta runs: native, docker or rosetta
+	CompilerImage    string       `yaml:"compiler_image"`    // Image luac_mta runs in with the docker strategy
+	Schedules        []Schedule   `yaml:"schedules"`         // Scheduled builds run by the serve command
+	Servers          []Server     `yaml:"servers"`           // MTA servers the deploy command copies builds to
+	Uploads          []Upload     `yaml:"uploads"`           // Object storage buckets the upload command pushes builds to
+	Webhooks         []Webhook    `yaml:"webhooks"`          // URLs receiving a summary after each build
+	Retention        Retention    `yaml:"retention"`         // How many cached dependencies and deployment bundles are kept
+	Lint             LintRules    `yaml:"lint"`              // meta.xml lint rules by name
+	Format           Format       `yaml:"format"`            // How sizes and durations are written in the output and reports
+	Lock             Lock         `yaml:"lock"`              // Pinned tools, written by "compiler vendor"
+
+	Path string `yaml:"-"` // Path the config was loaded from
+}
+
+// Pack is a group of resources built into a single resource
+type Pack struct {
+	Name      string   `yaml:"name"`      // Name of the pack resource
+	Resources []string `yaml:"resources"` // Resource names or relative paths (globs) of its members
+}
+
+// Split divides a resource into several resources by folder
+type Split struct {
+	Resource string      `yaml:"resource"` // Resource name or relative path (glob) of the split resource
+	Parts    []SplitPart `yaml:"parts"`    // Resources split off it, the rest keeps the resource name
+}
+
+// SplitPart is a resource split off another one
+type SplitPart struct {
+	Name    string   `yaml:"name"`    // Name of the part resource
+	Folders []string `yaml:"folders"` // Folders of the split resource moved to the part
+}
+
+// Format selects how sizes and durations are written in the console output and reports
+type Format struct {
+	SizeUnits         string `yaml:"size_units"`         // binary or decimal
+	DurationPrecision string `yaml:"duration_precision"` // Durations are rounded to a multiple of it, such as 1ms
+	Locale            string `yaml:"locale"`             // Language tag selecting number separators, or auto
+}
+
+// Schedule is a scheduled build entry
+type Schedule struct {
+	Name    string `yaml:"name"`     // Unique name, used to track the last run
+	Cron    string `yaml:"cron"`     // Five-field cron expression or @daily style macro
+	CatchUp bool   `yaml:"catch_up"` // Run at startup if a scheduled time was missed while stopped
+}
+
+// Load reads and parses a config file
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to get absolute config path: %w", err)
+	}
+	cfg.Path = absPath
+
+	if cfg.Output != "" && !filepath.IsAbs(cfg.Output) {
+		cfg.Output = filepath.Join(filepath.Dir(absPath), cfg.Output)
+	}
+	cfg.resolveServerPaths(filepath.Dir(absPath))
+	if cfg.Retention.Bundles != "" && !filepath.IsAbs(cfg.Retention.Bundles) {
+		cfg.Retention.Bundles = filepath.Join(filepath.Dir(absPath), cfg.Retention.Bundles)
+	}
+	if c := cfg.Lock.Compiler; c != nil && c.Path != "" && !filepath.IsAbs(c.Path) {
+		c.Path = filepath.Join(filepath.Dir(absPath), filepath.FromSlash(c.Path))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Find looks for the project config file at the root of the input path.
+// For a meta.xml input the root is the directory containing it.
+func Find(inputPath string) (string, bool) {
+	path := filepath.Join(inputRoot(inputPath), FileName)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// inputRoot returns the directory of the input path, or the directory containing it for a meta.xml input
+func inputRoot(inputPath string) string {
+	if info, err := os.Stat(inputPath); err == nil && !info.IsDir() {
+		return filepath.Dir(inputPath)
+	}
+	return inputPath
+}
+
+// ValidateMergeOrder checks that order is a known order of merged scripts, or empty for the default
+func ValidateMergeOrder(order string) error {
+	switch order {
+	case "", MergeOrderType, MergeOrderMeta:
+		return nil
+	}
+	return fmt.Errorf("invalid merge order %q (use %s or %s)", order, MergeOrderType, MergeOrderMeta)
+}
+
+// ValidateMergeStrategy checks that strategy is a known merge strategy, or empty for the default
+func ValidateMergeStrategy(strategy string) error {
+	switch strategy {
+	case "", MergeStrategyFiles, MergeStrategyConcat:
+		return nil
+	}
+	return fmt.Errorf("invalid merge strategy %q (use %s or %s)", strategy, MergeStrategyFiles, MergeStrategyConcat)
+}
+
+// ValidateBundleName checks that name is a path of merged bundles inside the resource, with a
+// {type} placeholder in its file name so the client and server bundles differ, or empty for the
+// default
+func ValidateBundleName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if !strings.Contains(filepath.Base(filepath.FromSlash(name)), "{type}") {
+		return fmt.Errorf("bundle name %q needs {type} in its file name, such as dist/{type}.luac", name)
+	}
+	if !strings.HasSuffix(name, ".luac") {
+		return fmt.Errorf("bundle name %q must end with .luac", name)
+	}
+	if !filepath.IsLocal(filepath.FromSlash(name)) || strings.Contains(name, "\\") {
+		return fmt.Errorf("bundle name %q must be a relative path inside the resource, with / separators", name)
+	}
+	return nil
+}
+
+// placeholderPattern matches the placeholders of a banner or <info> attribute value
+var placeholderPattern = regexp.MustCompile(`\{(\w*)\}`)
+
+// placeholders are the placeholders a banner or <info> attribute value may contain
+var placeholders = []string{"resource", "version", "build", "commit"}
+
+// ValidatePlaceholders checks that the placeholders of text, a banner or <info> attribute value,
+// are known
+func ValidatePlaceholders(text string) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(placeholders, match[1]) {
+			return fmt.Errorf("unknown placeholder %s (use {resource}, {version}, {build} or {commit})", match[0])
+		}
+	}
+	return nil
+}
+
+// ValidateInfoAttr checks that spec is an <info> attribute given as name=value or name+=value,
+// with known placeholders
+func ValidateInfoAttr(spec string) error {
+	attr, err := resource.ParseInfoAttr(spec)
+	if err != nil {
+		return err
+	}
+	return ValidatePlaceholders(attr.Value)
+}
+
+// Constants are values of Lua globals, by name, inlined into scripts by the constant folding pass
+type Constants map[string]any
+
+// ValidateConstant checks that name is a Lua global name and value a boolean, a number, a string
+// or nil, as the constant folding pass inlines
+func ValidateConstant(name string, value any) error {
+	if !luaNamePattern.MatchString(name) || lua.IsKeyword(name) {
+		return fmt.Errorf("%q is not a valid Lua name", name)
+	}
+	switch value.(type) {
+	case nil, bool, int, float64, string:
+		return nil
+	}
+	return fmt.Errorf("%s has an unsupported value %v (use a boolean, number, string or null)", name, value)
+}
+
+// Validate checks that configured values are within their allowed ranges
+func (c Config) Validate() error {
+	if c.Obfuscation != nil && (*c.Obfuscation < 0 || *c.Obfuscation > 3) {
+		return fmt.Errorf("invalid obfuscation level: %d (must be 0-3)", *c.Obfuscation)
+	}
+
+	if err := ValidateMergeOrder(c.MergeOrder); err != nil {
+		return fmt.Errorf("merge_order: %w", err)
+	}
+
+	if err := ValidateMergeStrategy(c.MergeStrategy); err != nil {
+		return fmt.Errorf("merge_strategy: %w", err)
+	}
+	if c.MergeIsolate != nil && *c.MergeIsolate && c.MergeStrategy == MergeStrategyFiles {
+		return fmt.Errorf("merge_isolate requires the concat merge strategy")
+	}
+
+	if err := ValidateBundleName(c.BundleName); err != nil {
+		return fmt.Errorf("bundle_name: %w", err)
+	}
+
+	if err := ValidatePlaceholders(c.Banner); err != nil {
+		return fmt.Errorf("banner: %w", err)
+	}
+	for _, spec := range c.Info {
+		if err := ValidateInfoAttr(spec); err != nil {
+			return fmt.Errorf("info: %w", err)
+		}
+	}
+
+	if err := compiler.ValidateMirror(c.CompilerMirror); err != nil {
+		return fmt.Errorf("compiler_mirror: %w", err)
+	}
+	if err := compiler.ValidateProxy(c.CompilerProxy); err != nil {
+		return fmt.Errorf("compiler_proxy: %w", err)
+	}
+	if c.DownloadTimeout != "" {
+		if timeout, err := time.ParseDuration(c.DownloadTimeout); err != nil || timeout < 0 {
+			return fmt.Errorf("download_timeout: invalid duration %q, such as 30s or 5m", c.DownloadTimeout)
+		}
+	}
+	if err := compiler.ValidateStrategy(c.CompilerStrategy); err != nil {
+		return fmt.Errorf("compiler_strategy: %w", err)
+	}
+	if c.DownloadRetries != nil && *c.DownloadRetries < 0 {
+		return fmt.Errorf("download_retries: must not be negative, got %d", *c.DownloadRetries)
+	}
+	if c.Retries != nil && *c.Retries < 0 {
+		return fmt.Errorf("retries: must not be negative, got %d", *c.Retries)
+	}
+	if _, err := retention.ParseAge(c.CompilerMaxAge); err != nil {
+		return fmt.Errorf("compiler_max_age: %w", err)
+	}
+	if err := compiler.ValidateArgs(c.CompilerArgs); err != nil {
+		return fmt.Errorf("compiler_args: %w", err)
+	}
+
+	for _, pattern := range c.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	for _, pattern := range c.Ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("invalid max_depth: %d (must be 0 or more)", c.MaxDepth)
+	}
+	for _, category := range c.SkipCategories {
+		if _, err := filepath.Match(strings.Trim(category, "[]"), ""); err != nil {
+			return fmt.Errorf("invalid skip_categories pattern %q: %w", category, err)
+		}
+	}
+
+	for name, value := range c.Constants {
+		if err := ValidateConstant(name, value); err != nil {
+			return fmt.Errorf("constants: %w", err)
+		}
+	}
+
+	for _, pattern := range c.Verbatim {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid verbatim pattern %q: %w", pattern, err)
+		}
+	}
+
+	for _, pattern := range c.MergeExclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid merge_exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.BuildInfo != "" {
+		if err := ValidateResourceName(c.BuildInfo); err != nil {
+			return fmt.Errorf("build_info: %w", err)
+		}
+	}
+
+	packNames := make(map[string]bool)
+	for i, pack := range c.Packs {
+		if err := ValidateResourceName(pack.Name); err != nil {
+			return fmt.Errorf("pack %d: %w", i+1, err)
+		}
+		if packNames[pack.Name] {
+			return fmt.Errorf("duplicate pack name %q", pack.Name)
+		}
+		packNames[pack.Name] = true
+		if len(pack.Resources) == 0 {
+			return fmt.Errorf("pack %q has no resources", pack.Name)
+		}
+		for _, pattern := range pack.Resources {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("pack %q: invalid resource pattern %q: %w", pack.Name, pattern, err)
+			}
+		}
+	}
+
+	for i, split := range c.Splits {
+		if err := c.validateSplit(split, packNames); err != nil {
+			return fmt.Errorf("split %d: %w", i+1, err)
+		}
+	}
+
+	if err := c.validateDependencies(); err != nil {
+		return err
+	}
+
+	if err := c.validateServers(); err != nil {
+		return err
+	}
+	if err := c.validateUploads(); err != nil {
+		return err
+	}
+	if err := c.validateWebhooks(); err != nil {
+		return err
+	}
+	if err := c.validateRetention(); err != nil {
+		return err
+	}
+	if _, err := c.LintSettings(); err != nil {
+		return err
+	}
+
+	if _, err := units.ParseSizeUnits(c.Format.SizeUnits); err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+	if _, err := units.ParseDurationPrecision(c.Format.DurationPrecision); err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+	if _, err := units.ParseLocale(c.Format.Locale); err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+
+	if c := c.Lock.Compiler; c != nil {
+		if c.Path == "" {
+			return fmt.Errorf("lock.compiler: path is required")
+		}
+		if !sha256Pattern.MatchString(c.SHA256) {
+			return fmt.Errorf("lock.compiler: sha256 must be 64 hexadecimal characters")
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, entry := range c.Schedules {
+		if entry.Name == "" {
+			return fmt.Errorf("schedule %d has no name", i+1)
+		}
+		if names[entry.Name] {
+			return fmt.Errorf("duplicate schedule name %q", entry.Name)
+		}
+		names[entry.Name] = true
+
+		if _, err := schedule.ParseCron(entry.Cron); err != nil {
+			return fmt.Errorf("schedule %q: %w", entry.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ScheduleEntries converts the configured schedules into schedule entries
+func (c Config) ScheduleEntries() ([]schedule.Entry, error) {
+	entries := make([]schedule.Entry, 0, len(c.Schedules))
+	for _, entry := range c.Schedules {
+		cron, err := schedule.ParseCron(entry.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", entry.Name, err)
+		}
+		entries = append(entries, schedule.Entry{Name: entry.Name, Cron: cron, CatchUp: entry.CatchUp})
+	}
+	return entries, nil
+}
+
+// validateSplit checks a split entry. Part names must be unique among packs and parts.
+func (c Config) validateSplit(split Split, names map[string]bool) error {
+	if split.Resource == "" {
+		return fmt.Errorf("resource is required")
+	}
+	if _, err := filepath.Match(split.Resource, ""); err != nil {
+		return fmt.Errorf("invalid resource pattern %q: %w", split.Resource, err)
+	}
+	if len(split.Parts) == 0 {
+		return fmt.Errorf("%s has no parts", split.Resource)
+	}
+
+	folders := make(map[string]string)
+	for _, part := range split.Parts {
+		if err := ValidateResourceName(part.Name); err != nil {
+			return err
+		}
+		if names[part.Name] {
+			return fmt.Errorf("duplicate resource name %q", part.Name)
+		}
+		names[part.Name] = true
+		if len(part.Folders) == 0 {
+			return fmt.Errorf("part %q has no folders", part.Name)
+		}
+		for _, folder := range part.Folders {
+			clean := filepath.ToSlash(filepath.Clean(folder))
+			if filepath.IsAbs(folder) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+				return fmt.Errorf("part %q: folder %q must be a subdirectory of the resource", part.Name, folder)
+			}
+			if other, ok := folders[clean]; ok {
+				return fmt.Errorf("folder %q is in parts %q and %q", folder, other, part.Name)
+			}
+			folders[clean] = part.Name
+		}
+	}
+	return nil
+}
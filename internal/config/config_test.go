@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	content := `output: build
+obfuscation: 3
+strip_debug: true
+exclude:
+  - "test-*"
+  - "[disabled]"
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Output != filepath.Join(dir, "build") {
+		t.Errorf("Expected output to be resolved relative to the config file, got %q", cfg.Output)
+	}
+	if cfg.Obfuscation == nil || *cfg.Obfuscation != 3 {
+		t.Errorf("Expected obfuscation 3, got %v", cfg.Obfuscation)
+	}
+	if cfg.StripDebug == nil || !*cfg.StripDebug {
+		t.Errorf("Expected strip_debug true, got %v", cfg.StripDebug)
+	}
+	if cfg.Merge != nil {
+		t.Errorf("Expected merge to be unset, got %v", *cfg.Merge)
+	}
+	if len(cfg.Exclude) != 2 {
+		t.Errorf("Expected 2 exclude patterns, got %v", cfg.Exclude)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Obfuscation out of range", "obfuscation: 4\n"},
+		{"Bad exclude pattern", "exclude: [\"[\"]\n"},
+		{"Malformed YAML", "output: [\n"},
+		{"Bad build info name", "build_info: \"build info\"\n"},
+		{"Bad compiler hash", "lock:\n  compiler:\n    path: tools/luac_mta\n    sha256: abc\n"},
+		{"Pack without resources", "packs:\n  - name: utils\n"},
+		{"Duplicate pack", "packs:\n  - {name: utils, resources: [a]}\n  - {name: utils, resources: [b]}\n"},
+		{"Split part without folders", "splits:\n  - resource: world\n    parts:\n      - name: world_models\n"},
+		{"Split folder outside resource", "splits:\n  - resource: world\n    parts:\n      - {name: world_models, folders: [../models]}\n"},
+		{"Split part named like pack", "packs:\n  - {name: utils, resources: [a]}\nsplits:\n  - resource: world\n    parts:\n      - {name: utils, folders: [models]}\n"},
+		{"Server without resources", "servers:\n  - name: live\n"},
+		{"Upload without bucket", "uploads:\n  - {name: artifacts, endpoint: https://s3.amazonaws.com}\n"},
+		{"Webhook with unknown format", "webhooks:\n  - {url: https://example.com/hook, format: teams}\n"},
+		{"Unknown size units", "format:\n  size_units: metric\n"},
+		{"Unknown locale", "format:\n  locale: xx\n"},
+		{"Server with invalid url", "servers:\n  - {name: live, resources: /srv/mta, url: 127.0.0.1:22005}\n"},
+		{"Bundle name without type", "bundle_name: dist/bundle.luac\n"},
+		{"Bundle name with type in directory", "bundle_name: \"{type}/bundle.luac\"\n"},
+		{"Bundle name outside resource", "bundle_name: \"../{type}.luac\"\n"},
+		{"Banner with unknown placeholder", "banner: \"(c) {author}\"\n"},
+		{"Info attribute without value", "info: [version]\n"},
+		{"Info attribute with unknown placeholder", "info: [\"version+=-{branch}\"]\n"},
+		{"Invalid merge strategy", "merge_strategy: inline\n"},
+		{"Unknown lint rule", "lint:\n  no-tabs: {severity: error}\n"},
+		{"Invalid lint severity", "lint:\n  oop: {severity: fatal}\n"},
+		{"Lint size of other rule", "lint:\n  oop: {max_size: 5MB}\n"},
+		{"Invalid lint size", "lint:\n  client-file-size: {max_size: big}\n"},
+		{"Lint globals of other rule", "lint:\n  oop: {globals: [cache]}\n"},
+		{"Bad ignore pattern", "ignore: [\"[\"]\n"},
+		{"Negative max depth", "max_depth: -1\n"},
+		{"Bad skip category pattern", "skip_categories: [\"w[ip\"]\n"},
+		{"Constant with keyword name", "constants:\n  end: 1\n"},
+		{"Constant with table value", "constants:\n  DEBUG: {enabled: true}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestResourceOverrides(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, ok, err := LoadResourceOverrides(dir); ok || err != nil {
+		t.Fatalf("Expected no overrides without a file, got ok=%t err=%v", ok, err)
+	}
+
+	content := "obfuscation = 0\nmerge = false\n"
+	if err := os.WriteFile(filepath.Join(dir, ResourceFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write overrides: %v", err)
+	}
+
+	overrides, ok, err := LoadResourceOverrides(dir)
+	if err != nil || !ok {
+		t.Fatalf("Expected overrides to load, got ok=%t err=%v", ok, err)
+	}
+
+	global := compiler.CompilationOptions{
+		ObfuscationLevel: compiler.ObfuscationMaximum,
+		StripDebug:       true,
+	}
+	options, mergeMode := overrides.Apply(global, true)
+
+	if options.ObfuscationLevel != compiler.ObfuscationNone {
+		t.Errorf("Expected obfuscation to be overridden to none, got %d", options.ObfuscationLevel)
+	}
+	if !options.StripDebug {
+		t.Error("Expected strip debug to keep the global value")
+	}
+	if mergeMode {
+		t.Error("Expected merge mode to be overridden to false")
+	}
+}
+
+func TestResourceOverridesUnknownSetting(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ResourceFileName), []byte("obfuscate = 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write overrides: %v", err)
+	}
+
+	if _, _, err := LoadResourceOverrides(dir); err == nil {
+		t.Error("Expected an error for an unknown setting")
+	}
+}
+
+func TestLoadWorkspaces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workspaces.yml")
+	content := `workspaces:
+  - name: community-a
+    input: a/resources
+  - name: community-b
+    input: /srv/b/resources
+    config: b.yml
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write workspaces file: %v", err)
+	}
+
+	workspaces, err := LoadWorkspaces(path)
+	if err != nil {
+		t.Fatalf("LoadWorkspaces failed: %v", err)
+	}
+	if len(workspaces) != 2 {
+		t.Fatalf("Expected 2 workspaces, got %d", len(workspaces))
+	}
+	if workspaces[0].Input != filepath.Join(dir, "a", "resources") {
+		t.Errorf("Expected input to be resolved relative to the workspaces file, got %q", workspaces[0].Input)
+	}
+	if workspaces[1].Input != "/srv/b/resources" {
+		t.Errorf("Expected absolute input to be kept, got %q", workspaces[1].Input)
+	}
+	if workspaces[1].Config != filepath.Join(dir, "b.yml") {
+		t.Errorf("Expected config to be resolved relative to the workspaces file, got %q", workspaces[1].Config)
+	}
+}
+
+func TestLoadWorkspacesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", "workspaces: []\n"},
+		{"missing input", "workspaces:\n  - name: a\n"},
+		{"unsafe name", "workspaces:\n  - name: ../a\n    input: a\n"},
+		{"duplicate name", "workspaces:\n  - name: a\n    input: a\n  - name: a\n    input: b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "workspaces.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write workspaces file: %v", err)
+			}
+			if _, err := LoadWorkspaces(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
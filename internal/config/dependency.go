@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubRepoPattern matches a GitHub repository in owner/name form
+var githubRepoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// Dependency is a third-party resource downloaded as a zip archive and built with the project,
+// pinned by the SHA-256 hash of the archive so every build uses the same files
+type Dependency struct {
+	Name    string `yaml:"name"`    // Name of the resource in the output directory
+	URL     string `yaml:"url"`     // URL of the zip archive
+	SHA256  string `yaml:"sha256"`  // Expected SHA-256 hash of the archive
+	Path    string `yaml:"path"`    // Directory of the archive holding meta.xml, found automatically when empty
+	GitHub  string `yaml:"github"`  // Repository (owner/name) whose releases are checked for updates
+	Version string `yaml:"version"` // Release tag the pin belongs to
+}
+
+// validateDependencies checks the dependency entries. Their names must not be taken by a pack.
+func (c Config) validateDependencies() error {
+	names := make(map[string]bool)
+	for _, pack := range c.Packs {
+		names[pack.Name] = true
+	}
+	for i, dep := range c.Dependencies {
+		if err := ValidateResourceName(dep.Name); err != nil {
+			return fmt.Errorf("dependency %d: %w", i+1, err)
+		}
+		if names[dep.Name] {
+			return fmt.Errorf("duplicate dependency or pack name %q", dep.Name)
+		}
+		names[dep.Name] = true
+
+		parsed, err := url.Parse(dep.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("dependency %q: invalid url %q (use https://host/path.zip)", dep.Name, dep.URL)
+		}
+		if !sha256Pattern.MatchString(dep.SHA256) {
+			return fmt.Errorf("dependency %q: sha256 must be 64 lowercase hexadecimal characters", dep.Name)
+		}
+		if dep.Path != "" && (!filepath.IsLocal(filepath.FromSlash(dep.Path)) || strings.Contains(dep.Path, "\\")) {
+			return fmt.Errorf("dependency %q: path %q must be a relative path inside the archive, with / separators", dep.Name, dep.Path)
+		}
+		if dep.GitHub != "" && !githubRepoPattern.MatchString(dep.GitHub) {
+			return fmt.Errorf("dependency %q: github must be a repository in owner/name form, got %q", dep.Name, dep.GitHub)
+		}
+	}
+	return nil
+}
+
+// SetDependencyPin writes the url, sha256 and version of dep into its entry of the dependencies
+// in the config file at path. The rest of the file, including comments, is kept.
+func SetDependencyPin(path string, dep Dependency) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a mapping", path)
+	}
+
+	entry := dependencyNode(mappingValue(doc.Content[0], "dependencies"), dep.Name)
+	if entry == nil {
+		return fmt.Errorf("config file %s has no dependency %q", path, dep.Name)
+	}
+	for _, field := range []struct{ key, value string }{
+		{"url", dep.URL},
+		{"sha256", dep.SHA256},
+		{"version", dep.Version},
+	} {
+		node := mappingValue(entry, field.key)
+		node.Kind, node.Tag, node.Value, node.Style = yaml.ScalarNode, "!!str", field.value, 0
+	}
+	return writeConfigNode(path, &doc)
+}
+
+// dependencyNode returns the mapping of the dependency called name in a dependencies node
+func dependencyNode(deps *yaml.Node, name string) *yaml.Node {
+	if deps.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range deps.Content {
+		if entry.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			if entry.Content[i].Value == "name" && entry.Content[i+1].Value == name {
+				return entry
+			}
+		}
+	}
+	return nil
+}
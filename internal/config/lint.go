@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/lint"
+)
+
+// LintRule configures a meta.xml lint rule. Fields left out keep the defaults of the rule.
+type LintRule struct {
+	Enabled  *bool    `yaml:"enabled"`  // Whether the rule runs
+	Severity string   `yaml:"severity"` // warning or error
+	MaxSize  string   `yaml:"max_size"` // Size limit of client-file-size, such as 5MB or 512KB
+	Globals  []string `yaml:"globals"`  // Extra globals undefined-global accepts, such as ones set through loadstring
+}
+
+// LintRules configures the meta.xml lint rules by name
+type LintRules map[string]LintRule
+
+// LintSettings returns the settings of every lint rule, the configured ones applied on top of
+// their defaults
+func (c Config) LintSettings() (map[string]lint.Setting, error) {
+	settings := make(map[string]lint.Setting)
+	for _, name := range lint.Rules() {
+		setting, _ := lint.Defaults(name)
+		settings[name] = setting
+	}
+
+	for name, rule := range c.Lint {
+		setting, ok := settings[name]
+		if !ok {
+			return nil, fmt.Errorf("lint: unknown rule %q (use %s)", name, strings.Join(lint.Rules(), ", "))
+		}
+		// A configured rule is enabled unless it says otherwise
+		setting.Enabled = true
+		if rule.Enabled != nil {
+			setting.Enabled = *rule.Enabled
+		}
+		switch lint.Severity(rule.Severity) {
+		case "":
+		case lint.SeverityWarning, lint.SeverityError:
+			setting.Severity = lint.Severity(rule.Severity)
+		default:
+			return nil, fmt.Errorf("lint: %s: invalid severity %q (use warning or error)", name, rule.Severity)
+		}
+		if rule.MaxSize != "" {
+			if name != lint.RuleClientFileSize {
+				return nil, fmt.Errorf("lint: %s: max_size only applies to %s", name, lint.RuleClientFileSize)
+			}
+			size, err := parseSize(rule.MaxSize)
+			if err != nil {
+				return nil, fmt.Errorf("lint: %s: %w", name, err)
+			}
+			setting.MaxSize = size
+		}
+		if len(rule.Globals) > 0 {
+			if name != lint.RuleUndefinedGlobal {
+				return nil, fmt.Errorf("lint: %s: globals only applies to %s", name, lint.RuleUndefinedGlobal)
+			}
+			setting.Globals = rule.Globals
+		}
+		settings[name] = setting
+	}
+	return settings, nil
+}
+
+// parseSize parses a size such as 5MB, 512KB or 1048576 (bytes)
+func parseSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if trimmed, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, multiplier = strings.TrimSpace(trimmed), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (use a size such as 5MB or 512KB)", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
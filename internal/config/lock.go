@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sha256Pattern matches a hex-encoded SHA-256 hash
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Lock pins the tools used by builds so they do not depend on the machine running them
+type Lock struct {
+	Compiler *CompilerLock `yaml:"compiler,omitempty"` // Vendored luac_mta binary
+}
+
+// CompilerLock pins the luac_mta binary used by builds
+type CompilerLock struct {
+	Path   string `yaml:"path"`   // Binary path, relative paths are resolved from the config file
+	SHA256 string `yaml:"sha256"` // Expected hash of the binary
+}
+
+// SetCompilerLock writes the compiler lock into the config file at path, creating the file if
+// needed. The rest of the file, including comments, is kept. A relative lock path is stored
+// as is and must be relative to the config file.
+func SetCompilerLock(path string, lock CompilerLock) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a mapping", path)
+	}
+	lockNode := mappingValue(root, "lock")
+	if lockNode.Kind != yaml.MappingNode {
+		*lockNode = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	if err := mappingValue(lockNode, "compiler").Encode(lock); err != nil {
+		return fmt.Errorf("failed to encode compiler lock: %w", err)
+	}
+
+	return writeConfigNode(path, &doc)
+}
+
+// writeConfigNode encodes doc into the config file at path, creating its directory if needed
+func writeConfigNode(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value node of key in a mapping node, adding the key when missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	valueNode := &yaml.Node{}
+	mapping.Content = append(mapping.Content, keyNode, valueNode)
+	return valueNode
+}
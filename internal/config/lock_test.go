@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetCompilerLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	os.WriteFile(path, []byte("# project settings\nobfuscation: 3 # maximum\n"), 0644)
+
+	hash := strings.Repeat("ab", 32)
+	for _, binary := range []string{"old/luac_mta", "tools/luac_mta"} {
+		if err := SetCompilerLock(path, CompilerLock{Path: binary, SHA256: hash}); err != nil {
+			t.Fatalf("SetCompilerLock failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# project settings") || !strings.Contains(string(data), "# maximum") {
+		t.Errorf("Expected comments to be kept, got:\n%s", data)
+	}
+	if strings.Count(string(data), "compiler:") != 1 {
+		t.Errorf("Expected the lock to be replaced, got:\n%s", data)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Obfuscation == nil || *cfg.Obfuscation != 3 {
+		t.Errorf("Expected the other settings to be kept, got %v", cfg.Obfuscation)
+	}
+	lock := cfg.Lock.Compiler
+	if lock == nil || lock.Path != filepath.Join(dir, "tools", "luac_mta") || lock.SHA256 != hash {
+		t.Errorf("Expected the lock to resolve relative to the config file, got %+v", lock)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	dir := t.TempDir()
+	path := LockFilePath(dir, Config{})
+	if path != filepath.Join(dir, LockFileName) {
+		t.Errorf("Expected the lock file at the input root, got %s", path)
+	}
+
+	lock, exists, err := LoadLockFile(path)
+	if err != nil || exists {
+		t.Fatalf("Expected no lock file, got exists=%t err=%v", exists, err)
+	}
+	lock.Compiler["linux/amd64"] = strings.Repeat("ab", 32)
+	if err := lock.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	loaded, exists, err := LoadLockFile(path)
+	if err != nil || !exists {
+		t.Fatalf("Expected the lock file to load, got exists=%t err=%v", exists, err)
+	}
+	if loaded.Compiler["linux/amd64"] != lock.Compiler["linux/amd64"] {
+		t.Errorf("Expected the compiler hash to round-trip, got %v", loaded.Compiler)
+	}
+
+	os.WriteFile(path, []byte(`{"version": 1, "compiler": {"linux/amd64": "abc"}}`), 0644)
+	if _, _, err := LoadLockFile(path); err == nil {
+		t.Error("Expected an invalid hash to be rejected")
+	}
+}
+
+func TestSetDependencyPin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	content := `dependencies:
+  - name: scoreboard # community scoreboard
+    url: https://example.com/scoreboard-1.0.zip
+    sha256: ` + strings.Repeat("ab", 32) + `
+    github: example/scoreboard
+  - name: editor
+    url: https://example.com/editor.zip
+    sha256: ` + strings.Repeat("cd", 32) + `
+`
+	os.WriteFile(path, []byte(content), 0644)
+
+	pin := Dependency{Name: "scoreboard", URL: "https://example.com/scoreboard-1.1.zip", SHA256: strings.Repeat("ef", 32), Version: "v1.1"}
+	if err := SetDependencyPin(path, pin); err != nil {
+		t.Fatalf("SetDependencyPin failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# community scoreboard") {
+		t.Errorf("Expected comments to be kept, got:\n%s", data)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	got := cfg.Dependencies[0]
+	if got.URL != pin.URL || got.SHA256 != pin.SHA256 || got.Version != pin.Version || got.GitHub != "example/scoreboard" {
+		t.Errorf("Expected the pin to be replaced, got %+v", got)
+	}
+	if cfg.Dependencies[1].SHA256 != strings.Repeat("cd", 32) {
+		t.Errorf("Expected the other dependencies to be kept, got %+v", cfg.Dependencies[1])
+	}
+
+	if err := SetDependencyPin(path, Dependency{Name: "missing"}); err == nil {
+		t.Error("Expected an unknown dependency to be rejected")
+	}
+}
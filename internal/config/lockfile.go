@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFileName is the name of the lock file written next to the project config file, or at the
+// input root when there is none
+const LockFileName = "mta-bundler.lock"
+
+// lockFileVersion is the format version of the lock file
+const lockFileVersion = 1
+
+// LockFile records what the build inputs resolved to, so builds on other machines or at a
+// later time can check they use the same ones
+type LockFile struct {
+	Version  int               `json:"version"`
+	Compiler map[string]string `json:"compiler"` // SHA-256 of the resolved luac_mta binary by platform (GOOS/GOARCH)
+}
+
+// LockFilePath returns the path of the lock file for a build of inputPath with cfg
+func LockFilePath(inputPath string, cfg Config) string {
+	if cfg.Path != "" {
+		return filepath.Join(filepath.Dir(cfg.Path), LockFileName)
+	}
+	return filepath.Join(inputRoot(inputPath), LockFileName)
+}
+
+// LoadLockFile reads the lock file at path. The boolean result is false when it does not exist.
+func LoadLockFile(path string) (LockFile, bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return LockFile{Version: lockFileVersion, Compiler: make(map[string]string)}, false, nil
+	}
+	if err != nil {
+		return LockFile{}, false, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	var lock LockFile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return LockFile{}, false, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	if lock.Version != lockFileVersion {
+		return LockFile{}, false, fmt.Errorf("unsupported lock file version %d in %s", lock.Version, path)
+	}
+	for platform, hash := range lock.Compiler {
+		if !sha256Pattern.MatchString(hash) {
+			return LockFile{}, false, fmt.Errorf("invalid compiler hash for %s in %s", platform, path)
+		}
+	}
+	if lock.Compiler == nil {
+		lock.Compiler = make(map[string]string)
+	}
+	return lock, true, nil
+}
+
+// Write saves the lock file to path
+func (l LockFile) Write(path string) error {
+	l.Version = lockFileVersion
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
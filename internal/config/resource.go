@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// ResourceFileName is the name of the per-resource override file looked up next to meta.xml
+const ResourceFileName = "mta-bundler.toml"
+
+// ResourceOverrides holds compilation settings that apply to a single resource only.
+// Unset fields keep the global value.
+type ResourceOverrides struct {
+	Obfuscation      *int     `toml:"obfuscation"`       // Obfuscation level (0-3)
+	StripDebug       *bool    `toml:"strip_debug"`       // Strip debug information
+	SuppressWarnings *bool    `toml:"suppress_warnings"` // Suppress decompile warning
+	Merge            *bool    `toml:"merge"`             // Merge scripts into client.luac and server.luac
+	Lazy             []string `toml:"lazy"`              // Globs of client files downloaded on demand instead of on join
+	Verbatim         []string `toml:"verbatim"`          // Globs of scripts copied as source instead of compiled, added to the global ones
+	MergeExclude     []string `toml:"merge_exclude"`     // Globs of scripts compiled on their own in merge mode, added to the global ones
+	ScanAcknowledge  []string `toml:"scan_acknowledge"`  // IDs of backdoor scan findings reviewed as safe, such as remote-code:1a2b3c4d5e6f
+
+	Path string `toml:"-"` // Path the overrides were loaded from
+}
+
+// LoadResourceOverrides reads the override file of the resource in resourceDir.
+// The boolean result is false when the resource has no override file.
+func LoadResourceOverrides(resourceDir string) (ResourceOverrides, bool, error) {
+	path := filepath.Join(resourceDir, ResourceFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ResourceOverrides{}, false, nil
+	}
+
+	var overrides ResourceOverrides
+	meta, err := toml.DecodeFile(path, &overrides)
+	if err != nil {
+		return ResourceOverrides{}, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return ResourceOverrides{}, false, fmt.Errorf("unknown setting %q in %s", undecoded[0].String(), path)
+	}
+
+	if overrides.Obfuscation != nil && (*overrides.Obfuscation < 0 || *overrides.Obfuscation > 3) {
+		return ResourceOverrides{}, false, fmt.Errorf("invalid obfuscation level in %s: %d (must be 0-3)", path, *overrides.Obfuscation)
+	}
+
+	for _, pattern := range overrides.Lazy {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return ResourceOverrides{}, false, fmt.Errorf("invalid lazy pattern %q in %s: %w", pattern, path, err)
+		}
+	}
+
+	for _, pattern := range overrides.Verbatim {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return ResourceOverrides{}, false, fmt.Errorf("invalid verbatim pattern %q in %s: %w", pattern, path, err)
+		}
+	}
+
+	for _, pattern := range overrides.MergeExclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return ResourceOverrides{}, false, fmt.Errorf("invalid merge_exclude pattern %q in %s: %w", pattern, path, err)
+		}
+	}
+
+	for _, id := range overrides.ScanAcknowledge {
+		if rule, hash, ok := strings.Cut(id, ":"); !ok || rule == "" || hash == "" {
+			return ResourceOverrides{}, false, fmt.Errorf("invalid scan_acknowledge id %q in %s (use the id of the finding, such as remote-code:1a2b3c4d5e6f)", id, path)
+		}
+	}
+
+	overrides.Path = path
+	return overrides, true, nil
+}
+
+// Apply merges the overrides on top of the global options and merge mode
+func (o ResourceOverrides) Apply(options compiler.CompilationOptions, mergeMode bool) (compiler.CompilationOptions, bool) {
+	if o.Obfuscation != nil {
+		options.ObfuscationLevel = compiler.ObfuscationLevel(*o.Obfuscation)
+	}
+	if o.StripDebug != nil {
+		options.StripDebug = *o.StripDebug
+	}
+	if o.SuppressWarnings != nil {
+		options.SuppressDecompileWarning = *o.SuppressWarnings
+	}
+	if o.Merge != nil {
+		mergeMode = *o.Merge
+	}
+	return options, mergeMode
+}
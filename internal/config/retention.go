@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/davidbozo/mta-bundler/internal/retention"
+)
+
+// Retention limits the artifacts kept by the prune command and by serve after each build
+type Retention struct {
+	KeepLast int    `yaml:"keep_last"` // Most recent artifacts kept of each kind, 0 keeps any number
+	MaxAge   string `yaml:"max_age"`   // Artifacts older than this are removed, such as 30d or 12h
+	Bundles  string `yaml:"bundles"`   // Directory of deployment bundles, relative paths are resolved from the config file
+}
+
+// Policy returns the retention policy of the settings
+func (r Retention) Policy() (retention.Policy, error) {
+	maxAge, err := retention.ParseAge(r.MaxAge)
+	if err != nil {
+		return retention.Policy{}, fmt.Errorf("retention: max_age: %w", err)
+	}
+	return retention.Policy{KeepLast: r.KeepLast, MaxAge: maxAge}, nil
+}
+
+// validateRetention checks the retention settings
+func (c Config) validateRetention() error {
+	if c.Retention.KeepLast < 0 {
+		return fmt.Errorf("retention: keep_last must not be negative")
+	}
+	if _, err := c.Retention.Policy(); err != nil {
+		return err
+	}
+	return nil
+}
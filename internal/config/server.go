@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Server is an MTA server the deploy command copies builds to
+type Server struct {
+	Name        string `yaml:"name"`         // Unique server name
+	Resources   string `yaml:"resources"`    // Resources directory of the server, relative paths are resolved from the config file
+	URL         string `yaml:"url"`          // Base URL of the server's HTTP interface, no refresh when empty
+	User        string `yaml:"user"`         // Account used for the HTTP interface
+	Password    string `yaml:"password"`     // Password of the account
+	PasswordEnv string `yaml:"password_env"` // Environment variable holding the password, instead of password
+	Restart     *bool  `yaml:"restart"`      // Restart the running resources that changed (default true)
+}
+
+// Server returns the server named name
+func (c Config) Server(name string) (Server, bool) {
+	for _, server := range c.Servers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+	return Server{}, false
+}
+
+// ResolvePassword returns the password of the HTTP account, read from PasswordEnv when set
+func (s Server) ResolvePassword() (string, error) {
+	if s.PasswordEnv == "" {
+		return s.Password, nil
+	}
+	password := os.Getenv(s.PasswordEnv)
+	if password == "" {
+		return "", fmt.Errorf("server %q: environment variable %s is not set", s.Name, s.PasswordEnv)
+	}
+	return password, nil
+}
+
+// RestartsResources reports whether changed resources are restarted after a deployment
+func (s Server) RestartsResources() bool {
+	return s.Restart == nil || *s.Restart
+}
+
+// resolveServerPaths makes the resources directories of the servers absolute
+func (c *Config) resolveServerPaths(configDir string) {
+	for i := range c.Servers {
+		if c.Servers[i].Resources != "" && !filepath.IsAbs(c.Servers[i].Resources) {
+			c.Servers[i].Resources = filepath.Join(configDir, c.Servers[i].Resources)
+		}
+	}
+}
+
+// validateServers checks the server entries
+func (c Config) validateServers() error {
+	names := make(map[string]bool)
+	for i, server := range c.Servers {
+		if server.Name == "" {
+			return fmt.Errorf("server %d has no name", i+1)
+		}
+		if names[server.Name] {
+			return fmt.Errorf("duplicate server name %q", server.Name)
+		}
+		names[server.Name] = true
+
+		if server.Resources == "" {
+			return fmt.Errorf("server %q: resources directory is required", server.Name)
+		}
+		if server.URL != "" {
+			parsed, err := url.Parse(server.URL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("server %q: invalid url %q (use http://host:port)", server.Name, server.URL)
+			}
+		}
+		if server.Password != "" && server.PasswordEnv != "" {
+			return fmt.Errorf("server %q: set either password or password_env", server.Name)
+		}
+	}
+	return nil
+}
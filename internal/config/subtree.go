@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SubtreeFileName is the name of the nested config files of subtrees, which may also be named
+// FileName like the project config file
+const SubtreeFileName = "mta-bundler.yml"
+
+// subtreeSettings are the settings a nested config file may set, they apply per resource
+const subtreeSettings = "obfuscation, strip_debug, suppress_warnings, merge, exclude, verbatim and merge_exclude"
+
+// LoadSubtree reads a nested config file. Settings that apply to the whole build, such as
+// the output directory or packs, can only be set in the project config and are rejected.
+func LoadSubtree(path string) (Config, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	settings := []struct {
+		name string
+		set  bool
+	}{
+		{"output", cfg.Output != ""},
+		{"ignore", len(cfg.Ignore) > 0},
+		{"max_depth", cfg.MaxDepth != 0},
+		{"skip_categories", len(cfg.SkipCategories) > 0},
+		{"follow_symlinks", cfg.FollowSymlinks != nil},
+		{"packs", len(cfg.Packs) > 0},
+		{"splits", len(cfg.Splits) > 0},
+		{"dependencies", len(cfg.Dependencies) > 0},
+		{"build_info", cfg.BuildInfo != ""},
+		{"checksums", cfg.Checksums != nil},
+		{"scan", cfg.Scan != nil},
+		{"syntax_check", cfg.SyntaxCheck != nil},
+		{"preflight", cfg.Preflight != nil},
+		{"minify", cfg.Minify != nil},
+		{"rename_locals", cfg.RenameLocals != nil},
+		{"tree_shake", cfg.TreeShake != nil},
+		{"fold_constants", cfg.FoldConstants != nil},
+		{"constants", len(cfg.Constants) > 0},
+		{"client_cache", cfg.ClientCache != nil},
+		{"raise_min_version", cfg.RaiseMinVersion != nil},
+		{"level_fallback", cfg.LevelFallback != nil},
+		{"compiler_args", len(cfg.CompilerArgs) > 0},
+		{"compiler_max_age", cfg.CompilerMaxAge != ""},
+		{"compiler_mirror", cfg.CompilerMirror != ""},
+		{"compiler_proxy", cfg.CompilerProxy != ""},
+		{"download_timeout", cfg.DownloadTimeout != ""},
+		{"download_retries", cfg.DownloadRetries != nil},
+		{"retries", cfg.Retries != nil},
+		{"compiler_strategy", cfg.CompilerStrategy != ""},
+		{"compiler_image", cfg.CompilerImage != ""},
+		{"retention", cfg.Retention != Retention{}},
+		{"lint", len(cfg.Lint) > 0},
+		{"merge_order", cfg.MergeOrder != ""},
+		{"merge_strategy", cfg.MergeStrategy != ""},
+		{"merge_isolate", cfg.MergeIsolate != nil},
+		{"bundle_name", cfg.BundleName != ""},
+		{"banner", cfg.Banner != ""},
+		{"info", len(cfg.Info) > 0},
+		{"schedules", len(cfg.Schedules) > 0},
+		{"servers", len(cfg.Servers) > 0},
+		{"uploads", len(cfg.Uploads) > 0},
+		{"webhooks", len(cfg.Webhooks) > 0},
+		{"format", cfg.Format != Format{}},
+		{"lock", cfg.Lock.Compiler != nil},
+	}
+	var buildWide []string
+	for _, setting := range settings {
+		if setting.set {
+			buildWide = append(buildWide, setting.name)
+		}
+	}
+	if len(buildWide) > 0 {
+		return Config{}, fmt.Errorf("%s: %s can only be set in the project config at the input root (nested config files set %s)",
+			path, strings.Join(buildWide, ", "), subtreeSettings)
+	}
+	return cfg, nil
+}
+
+// Dir returns the directory of the config file, the root of the subtree it applies to
+func (c Config) Dir() string {
+	return filepath.Dir(c.Path)
+}
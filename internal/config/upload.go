@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Default environment variables holding the credentials of upload targets
+const (
+	DefaultAccessKeyEnv    = "AWS_ACCESS_KEY_ID"
+	DefaultSecretKeyEnv    = "AWS_SECRET_ACCESS_KEY"
+	DefaultSessionTokenEnv = "AWS_SESSION_TOKEN"
+)
+
+// Upload is an S3-compatible bucket the upload command pushes builds to
+type Upload struct {
+	Name             string `yaml:"name"`              // Unique upload target name
+	Endpoint         string `yaml:"endpoint"`          // Base URL of the storage service
+	Region           string `yaml:"region"`            // Region used to sign requests (default us-east-1)
+	Bucket           string `yaml:"bucket"`            // Bucket name
+	Prefix           string `yaml:"prefix"`            // Key prefix of the uploaded files
+	PathStyle        bool   `yaml:"path_style"`        // Address the bucket in the path, as most self-hosted services expect
+	ContentAddressed bool   `yaml:"content_addressed"` // Store files under their SHA-256 and map paths in the manifest
+	AccessKeyEnv     string `yaml:"access_key_env"`    // Environment variable holding the access key (default AWS_ACCESS_KEY_ID)
+	SecretKeyEnv     string `yaml:"secret_key_env"`    // Environment variable holding the secret key (default AWS_SECRET_ACCESS_KEY)
+	SessionTokenEnv  string `yaml:"session_token_env"` // Environment variable holding a session token (default AWS_SESSION_TOKEN)
+}
+
+// Upload returns the upload target named name
+func (c Config) Upload(name string) (Upload, bool) {
+	for _, upload := range c.Uploads {
+		if upload.Name == name {
+			return upload, true
+		}
+	}
+	return Upload{}, false
+}
+
+// SigningRegion returns the region used to sign requests
+func (u Upload) SigningRegion() string {
+	if u.Region == "" {
+		return "us-east-1"
+	}
+	return u.Region
+}
+
+// ResolveCredentials reads the access key, secret key and optional session token of the
+// target from the environment
+func (u Upload) ResolveCredentials() (string, string, string, error) {
+	accessKeyEnv := valueOr(u.AccessKeyEnv, DefaultAccessKeyEnv)
+	secretKeyEnv := valueOr(u.SecretKeyEnv, DefaultSecretKeyEnv)
+	accessKey, secretKey := os.Getenv(accessKeyEnv), os.Getenv(secretKeyEnv)
+	if accessKey == "" || secretKey == "" {
+		return "", "", "", fmt.Errorf("upload %q: set the credentials in %s and %s", u.Name, accessKeyEnv, secretKeyEnv)
+	}
+	return accessKey, secretKey, os.Getenv(valueOr(u.SessionTokenEnv, DefaultSessionTokenEnv)), nil
+}
+
+// validateUploads checks the upload target entries
+func (c Config) validateUploads() error {
+	names := make(map[string]bool)
+	for i, upload := range c.Uploads {
+		if upload.Name == "" {
+			return fmt.Errorf("upload %d has no name", i+1)
+		}
+		if names[upload.Name] {
+			return fmt.Errorf("duplicate upload name %q", upload.Name)
+		}
+		names[upload.Name] = true
+
+		if upload.Bucket == "" {
+			return fmt.Errorf("upload %q: bucket is required", upload.Name)
+		}
+		parsed, err := url.Parse(upload.Endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("upload %q: invalid endpoint %q (use https://host)", upload.Name, upload.Endpoint)
+		}
+	}
+	return nil
+}
+
+// valueOr returns value, or fallback when value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Webhook is a URL receiving a summary after each build
+type Webhook struct {
+	URL    string `yaml:"url"`     // Webhook URL
+	URLEnv string `yaml:"url_env"` // Environment variable holding the URL, instead of url, to keep its token out of the repository
+	Format string `yaml:"format"`  // discord, slack or json, detected from the URL when empty
+	On     string `yaml:"on"`      // always (default) or failure
+}
+
+// ResolveURL returns the webhook URL, read from URLEnv when set
+func (w Webhook) ResolveURL() (string, error) {
+	if w.URLEnv == "" {
+		return w.URL, nil
+	}
+	value := os.Getenv(w.URLEnv)
+	if value == "" {
+		return "", fmt.Errorf("webhook: environment variable %s is not set", w.URLEnv)
+	}
+	return value, nil
+}
+
+// validateWebhooks checks the webhook entries
+func (c Config) validateWebhooks() error {
+	for i, webhook := range c.Webhooks {
+		switch {
+		case webhook.URL == "" && webhook.URLEnv == "":
+			return fmt.Errorf("webhook %d: url or url_env is required", i+1)
+		case webhook.URL != "" && webhook.URLEnv != "":
+			return fmt.Errorf("webhook %d: set either url or url_env", i+1)
+		}
+		if webhook.URL != "" {
+			parsed, err := url.Parse(webhook.URL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("webhook %d: invalid url", i+1)
+			}
+		}
+		switch webhook.Format {
+		case "", "discord", "slack", "json":
+		default:
+			return fmt.Errorf("webhook %d: invalid format %q (use discord, slack or json)", i+1, webhook.Format)
+		}
+		switch webhook.On {
+		case "", "always", "failure":
+		default:
+			return fmt.Errorf("webhook %d: invalid on %q (use always or failure)", i+1, webhook.On)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"gopkg.in/yaml.v3"
+)
+
+// workspaceNamePattern restricts workspace names to characters safe in file names
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Workspace is an independent project served by a single mta-bundler instance.
+// Each workspace has its own input, config file and schedule state.
+type Workspace struct {
+	Name   string `yaml:"name"`   // Unique workspace name
+	Input  string `yaml:"input"`  // Input path (meta.xml file or directory)
+	Config string `yaml:"config"` // Config file (default is FileName at the input root)
+}
+
+// workspacesFile is the layout of a workspaces file
+type workspacesFile struct {
+	Workspaces []Workspace `yaml:"workspaces"`
+}
+
+// LoadWorkspaces reads a workspaces file. Relative paths are resolved from the file's directory.
+func LoadWorkspaces(path string) ([]Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspaces file: %w", err)
+	}
+
+	var file workspacesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse workspaces file %s: %w", path, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute workspaces path: %w", err)
+	}
+	baseDir := filepath.Dir(absPath)
+
+	if len(file.Workspaces) == 0 {
+		return nil, fmt.Errorf("no workspaces defined in %s", path)
+	}
+
+	names := make(map[string]bool)
+	for i := range file.Workspaces {
+		ws := &file.Workspaces[i]
+		if !workspaceNamePattern.MatchString(ws.Name) {
+			return nil, fmt.Errorf("invalid workspace name %q in %s (letters, digits, '.', '_' and '-' only)", ws.Name, path)
+		}
+		if names[ws.Name] {
+			return nil, fmt.Errorf("duplicate workspace name %q in %s", ws.Name, path)
+		}
+		names[ws.Name] = true
+
+		if ws.Input == "" {
+			return nil, fmt.Errorf("workspace %q has no input", ws.Name)
+		}
+		ws.Input = resolvePath(baseDir, ws.Input)
+		if ws.Config != "" {
+			ws.Config = resolvePath(baseDir, ws.Config)
+		}
+	}
+
+	return file.Workspaces, nil
+}
+
+// LoadConfig loads the config file of the workspace. A workspace without an explicit
+// config file and without a project config file at its input root gets an empty config.
+func (w Workspace) LoadConfig() (Config, error) {
+	path := w.Config
+	if path == "" {
+		found, ok := Find(w.Input)
+		if !ok {
+			return Config{}, nil
+		}
+		path = found
+	}
+	return Load(path)
+}
+
+// Apply merges the configured compilation settings on top of options and merge mode
+func (c Config) Apply(options compiler.CompilationOptions, mergeMode bool) (compiler.CompilationOptions, bool) {
+	if c.Obfuscation != nil {
+		options.ObfuscationLevel = compiler.ObfuscationLevel(*c.Obfuscation)
+	}
+	if c.StripDebug != nil {
+		options.StripDebug = *c.StripDebug
+	}
+	if c.SuppressWarnings != nil {
+		options.SuppressDecompileWarning = *c.SuppressWarnings
+	}
+	if c.Merge != nil {
+		mergeMode = *c.Merge
+	}
+	return options, mergeMode
+}
+
+// resolvePath resolves a relative path against baseDir
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
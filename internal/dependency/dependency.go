@@ -0,0 +1,270 @@
+package dependency
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/retention"
+)
+
+// Fetch returns the directory of the resource of dep, named after it, below cacheDir. The
+// archive is downloaded, verified against the pinned hash and extracted on first use; later
+// builds reuse the extracted resource without downloading it again. An archive whose hash does
+// not match is never extracted.
+func Fetch(dep config.Dependency, cacheDir string) (string, error) {
+	dir := cachedDir(dep, cacheDir)
+	if _, err := os.Stat(filepath.Join(dir, "meta.xml")); err == nil {
+		slog.Debug("Using cached dependency", "resource", dep.Name, "dir", dir)
+		// Marked as used, so retention policies keep the pins of recent builds
+		now := time.Now()
+		os.Chtimes(filepath.Dir(dir), now, now)
+		return dir, nil
+	}
+
+	archive, sum, err := downloadArchive(dep, cacheDir)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(archive)
+	if sum != dep.SHA256 {
+		return "", fmt.Errorf("archive %s has SHA-256 %s, expected %s", dep.URL, sum, dep.SHA256)
+	}
+	if err := install(dep, archive, cacheDir); err != nil {
+		return "", err
+	}
+
+	slog.Info("Verified dependency", "resource", dep.Name, "sha256", sum, "success", true)
+	return dir, nil
+}
+
+// Pin downloads the archive at the url of dep and returns dep pinned to its hash. The archive is
+// extracted into the cache like by Fetch, so it must contain the resource and the next build
+// uses it without downloading it again.
+func Pin(dep config.Dependency, cacheDir string) (config.Dependency, error) {
+	archive, sum, err := downloadArchive(dep, cacheDir)
+	if err != nil {
+		return dep, err
+	}
+	defer os.Remove(archive)
+
+	dep.SHA256 = sum
+	if err := install(dep, archive, cacheDir); err != nil {
+		return dep, err
+	}
+	return dep, nil
+}
+
+// CacheEntries returns the extracted dependencies in cacheDir as artifacts grouped by resource
+// name, with their last use as modification time. The entries of the pins of deps are pinned.
+// Leftovers of interrupted downloads are stale once they are an hour old.
+func CacheEntries(cacheDir string, deps []config.Dependency) ([]retention.Artifact, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dependency cache: %v", err)
+	}
+
+	pinned := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		pinned[filepath.Dir(cachedDir(dep, cacheDir))] = true
+	}
+
+	var artifacts []retention.Artifact
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(cacheDir, entry.Name())
+		artifact := retention.Artifact{Path: path, ModTime: info.ModTime(), Size: info.Size(), Pinned: pinned[path]}
+		if entry.IsDir() {
+			artifact.Size = retention.DirSize(path)
+		}
+		if name, _, ok := cutLast(entry.Name(), "-"); ok && entry.IsDir() && !strings.HasSuffix(entry.Name(), ".tmp") {
+			artifact.Group = name
+		} else {
+			// A download or extraction, kept while it may still be running
+			artifact.Group = "incomplete"
+			artifact.Pinned = time.Since(info.ModTime()) < time.Hour
+			artifact.Stale = !artifact.Pinned
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// cachedDir returns the cache directory of the resource of dep, which depends on its pin
+func cachedDir(dep config.Dependency, cacheDir string) string {
+	key := sha256.Sum256([]byte(dep.SHA256 + "\x00" + dep.Path))
+	return filepath.Join(cacheDir, dep.Name+"-"+hex.EncodeToString(key[:8]), dep.Name)
+}
+
+// downloadArchive downloads the archive of dep to a temporary file in cacheDir and returns its
+// path and hex-encoded SHA-256 hash. The caller removes the file.
+func downloadArchive(dep config.Dependency, cacheDir string) (string, string, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create dependency cache: %v", err)
+	}
+	archive, err := os.CreateTemp(cacheDir, dep.Name+"-*.zip")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create dependency archive: %v", err)
+	}
+	defer archive.Close()
+
+	slog.Info("Downloading dependency", "resource", dep.Name, "url", dep.URL)
+	sum, err := download(dep.URL, archive)
+	if err != nil {
+		os.Remove(archive.Name())
+		return "", "", fmt.Errorf("failed to download %s: %v", dep.URL, err)
+	}
+	return archive.Name(), sum, nil
+}
+
+// install extracts the verified archive of dep into its cache directory
+func install(dep config.Dependency, archive, cacheDir string) error {
+	// Extracted next to the final directory and renamed into place, so an interrupted
+	// extraction is never taken for a cached resource
+	root := filepath.Dir(cachedDir(dep, cacheDir))
+	staging, err := os.MkdirTemp(cacheDir, dep.Name+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to extract dependency: %v", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := extract(archive, dep.Path, filepath.Join(staging, dep.Name)); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(root); err != nil {
+		return fmt.Errorf("failed to replace cached dependency: %v", err)
+	}
+	if err := os.Rename(staging, root); err != nil {
+		return fmt.Errorf("failed to cache dependency: %v", err)
+	}
+	return nil
+}
+
+// download writes the content of url to out and returns its hex-encoded SHA-256 hash
+func download(url string, out io.Writer) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extract writes the files of the zip archive below dir, the archive directory holding
+// meta.xml, into targetDir. When dir is empty, meta.xml must be at the root of the archive or
+// of its only top-level directory, as in archives of source repositories.
+func extract(archivePath, dir, targetDir string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open dependency archive: %v", err)
+	}
+	defer reader.Close()
+
+	prefix, err := resourcePrefix(reader.File, dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		name, ok := strings.CutPrefix(file.Name, prefix)
+		if !ok || name == "" || file.FileInfo().IsDir() {
+			continue
+		}
+		if !file.Mode().IsRegular() {
+			slog.Warn("Skipping special file in dependency archive", "file", file.Name)
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) || strings.Contains(name, "\\") {
+			return fmt.Errorf("dependency archive entry %q leaves the resource directory", file.Name)
+		}
+		if err := extractFile(file, filepath.Join(targetDir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resourcePrefix returns the prefix of the archive entries of the resource: dir followed by a
+// slash, or the directory of the meta.xml found at the root or in the only top-level directory
+func resourcePrefix(files []*zip.File, dir string) (string, error) {
+	names := make(map[string]bool, len(files))
+	topLevel := make(map[string]bool)
+	for _, file := range files {
+		names[file.Name] = true
+		topLevel[strings.SplitN(file.Name, "/", 2)[0]] = true
+	}
+
+	if dir != "" {
+		prefix := strings.TrimSuffix(path.Clean(dir), "/") + "/"
+		if !names[prefix+"meta.xml"] {
+			return "", fmt.Errorf("dependency archive has no meta.xml in %s", dir)
+		}
+		return prefix, nil
+	}
+	if names["meta.xml"] {
+		return "", nil
+	}
+	if len(topLevel) == 1 {
+		for top := range topLevel {
+			if names[top+"/meta.xml"] {
+				return top + "/", nil
+			}
+		}
+	}
+	return "", fmt.Errorf("dependency archive has no meta.xml at its root, set the path of the resource in the archive")
+}
+
+// extractFile writes the content of an archive entry to target
+func extractFile(file *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to extract %s: %v", file.Name, err)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %v", file.Name, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %v", file.Name, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to extract %s: %v", file.Name, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to extract %s: %v", file.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package dependency
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+func TestFetch(t *testing.T) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"scoreboard-main/meta.xml":        `<meta><script src="server.lua" type="server" /></meta>`,
+		"scoreboard-main/server.lua":      "local x = 1\n",
+		"scoreboard-main/docs/README.txt": "docs",
+	} {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archive.Bytes())
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	dep := config.Dependency{Name: "scoreboard", URL: server.URL + "/scoreboard.zip", SHA256: hex.EncodeToString(sum[:])}
+	dir, err := Fetch(dep, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if filepath.Base(dir) != "scoreboard" {
+		t.Errorf("Expected the resource directory to be named after the dependency, got %s", dir)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "docs", "README.txt")); err != nil || string(data) != "docs" {
+		t.Errorf("Expected the files of the top-level directory to be extracted, got %q, %v", data, err)
+	}
+
+	if _, err := Fetch(dep, cacheDir); err != nil || downloads != 1 {
+		t.Errorf("Expected the cached resource to be reused, got %d downloads, %v", downloads, err)
+	}
+
+	dep.SHA256 = strings.Repeat("0", 64)
+	if _, err := Fetch(dep, cacheDir); err == nil || !strings.Contains(err.Error(), "expected "+dep.SHA256) {
+		t.Errorf("Expected a hash mismatch, got %v", err)
+	}
+	entries, _ := os.ReadDir(cacheDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected the mismatching archive to leave nothing behind, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,98 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// TokenEnv is the environment variable holding a GitHub token for the release checks, which
+// raises the API rate limit
+const TokenEnv = "GITHUB_TOKEN"
+
+// githubAPI is the base URL of the GitHub REST API, replaced by tests
+var githubAPI = "https://api.github.com"
+
+// Release is the latest upstream release of a dependency
+type Release struct {
+	Version string // Release tag
+	URL     string // URL of the zip archive of the release
+}
+
+// Outdated reports whether the pin of dep belongs to a different release than r
+func (r Release) Outdated(dep config.Dependency) bool {
+	return r.Version != dep.Version
+}
+
+// githubRelease is the part of a GitHub release the update check uses
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Latest returns the latest release of the GitHub repository of dep. Its archive is the zip
+// asset of the release named like the pinned one with the version replaced, or the only zip
+// asset, or else the source archive of the tag.
+func Latest(dep config.Dependency) (Release, error) {
+	if dep.GitHub == "" {
+		return Release{}, fmt.Errorf("dependency %s has no github repository to check", dep.Name)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(http.MethodGet, githubAPI+"/repos/"+dep.GitHub+"/releases/latest", nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv(TokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to check releases of %s: %v", dep.GitHub, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("failed to check releases of %s: bad status: %s", dep.GitHub, resp.Status)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return Release{}, fmt.Errorf("failed to parse latest release of %s: %v", dep.GitHub, err)
+	}
+	if release.TagName == "" {
+		return Release{}, fmt.Errorf("latest release of %s has no tag", dep.GitHub)
+	}
+	return Release{Version: release.TagName, URL: releaseArchive(dep, release)}, nil
+}
+
+// releaseArchive picks the URL of the zip archive of release for dep
+func releaseArchive(dep config.Dependency, release githubRelease) string {
+	var zips []string
+	pinned := path.Base(dep.URL)
+	if dep.Version != "" {
+		pinned = strings.ReplaceAll(pinned, strings.TrimPrefix(dep.Version, "v"), strings.TrimPrefix(release.TagName, "v"))
+	}
+	for _, asset := range release.Assets {
+		if !strings.HasSuffix(strings.ToLower(asset.Name), ".zip") {
+			continue
+		}
+		if asset.Name == pinned {
+			return asset.URL
+		}
+		zips = append(zips, asset.URL)
+	}
+	if len(zips) == 1 {
+		return zips[0]
+	}
+	return "https://github.com/" + dep.GitHub + "/archive/refs/tags/" + release.TagName + ".zip"
+}
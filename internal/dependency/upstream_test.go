@@ -0,0 +1,54 @@
+package dependency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+func TestLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/example/scoreboard/releases/latest":
+			w.Write([]byte(`{"tag_name": "v1.5", "assets": [
+				{"name": "scoreboard-1.5.zip", "browser_download_url": "https://example.com/scoreboard-1.5.zip"},
+				{"name": "scoreboard-docs-1.5.zip", "browser_download_url": "https://example.com/scoreboard-docs-1.5.zip"}]}`))
+		case "/repos/example/editor/releases/latest":
+			w.Write([]byte(`{"tag_name": "2.0", "assets": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(api string) { githubAPI = api }(githubAPI)
+	githubAPI = server.URL
+
+	dep := config.Dependency{Name: "scoreboard", URL: "https://example.com/scoreboard-1.4.zip", GitHub: "example/scoreboard", Version: "v1.4"}
+	release, err := Latest(dep)
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if release.Version != "v1.5" || release.URL != "https://example.com/scoreboard-1.5.zip" {
+		t.Errorf("Expected the asset named like the pinned one, got %+v", release)
+	}
+	if !release.Outdated(dep) {
+		t.Error("Expected the pin to be outdated")
+	}
+
+	release, err = Latest(config.Dependency{Name: "editor", GitHub: "example/editor", Version: "2.0"})
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if release.URL != "https://github.com/example/editor/archive/refs/tags/2.0.zip" {
+		t.Errorf("Expected the source archive without zip assets, got %s", release.URL)
+	}
+	if release.Outdated(config.Dependency{Version: "2.0"}) {
+		t.Error("Expected the pin to be up to date")
+	}
+
+	if _, err := Latest(config.Dependency{Name: "missing", GitHub: "example/missing"}); err == nil {
+		t.Error("Expected a missing repository to fail")
+	}
+}
@@ -0,0 +1,337 @@
+package deploy
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Extension is the file extension of deployment bundles
+const Extension = ".mtadeploy"
+
+// Names of the bundle entries holding the manifest, its signature and the deployed files
+const (
+	manifestName  = "manifest.json"
+	signatureName = "manifest.sig"
+	filesPrefix   = "files/"
+)
+
+// Manifest describes a deployment request. It is signed by the requester, and lists the
+// hash of every file so the signature covers the complete bundle content.
+type Manifest struct {
+	Target      string    `json:"target"`       // Directory the files are deployed to
+	Source      string    `json:"source"`       // Build directory the bundle was created from
+	RequestedBy string    `json:"requested_by"` // Who requested the deployment
+	CreatedAt   time.Time `json:"created_at"`   // When the request was created
+	Files       []File    `json:"files"`        // Files to deploy, sorted by path
+}
+
+// File is a file of a deployment bundle
+type File struct {
+	Path   string `json:"path"`   // Slash-separated path relative to the target
+	SHA256 string `json:"sha256"` // Hex encoded SHA-256 of the content
+	Size   int64  `json:"size"`   // Size in bytes
+}
+
+// TotalSize returns the size of all files in the manifest
+func (m Manifest) TotalSize() int64 {
+	var total int64
+	for _, file := range m.Files {
+		total += file.Size
+	}
+	return total
+}
+
+// Hash returns the SHA-256 of the signed manifest, identifying the deployment
+func (m Manifest) Hash() string {
+	data, _ := json.MarshalIndent(m, "", "  ")
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Create writes a deployment bundle at bundlePath containing every file below sourceDir,
+// signed with key. The returned manifest includes the file list.
+func Create(bundlePath, sourceDir string, manifest Manifest, key ed25519.PrivateKey) (Manifest, error) {
+	absBundle, err := filepath.Abs(bundlePath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to get absolute bundle path: %w", err)
+	}
+
+	var relPaths []string
+	err = filepath.Walk(sourceDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Never package the bundle being written
+		if absPath, err := filepath.Abs(p); err == nil && absPath == absBundle {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			relPath, err := filepath.Rel(sourceDir, p)
+			if err != nil {
+				return err
+			}
+			relPaths = append(relPaths, filepath.ToSlash(relPath))
+		}
+		return nil
+	})
+	if err != nil {
+		return manifest, fmt.Errorf("failed to list files in %s: %w", sourceDir, err)
+	}
+	if len(relPaths) == 0 {
+		return manifest, fmt.Errorf("no files to deploy in %s", sourceDir)
+	}
+	sort.Strings(relPaths)
+
+	out, err := os.Create(bundlePath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	manifest.Files = nil
+	for _, relPath := range relPaths {
+		file, err := addFile(zw, sourceDir, relPath)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := addEntry(zw, manifestName, manifestData); err != nil {
+		return manifest, err
+	}
+	if err := addEntry(zw, signatureName, ed25519.Sign(key, manifestData)); err != nil {
+		return manifest, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	return manifest, out.Close()
+}
+
+// addFile copies a file into the bundle and returns its manifest entry
+func addFile(zw *zip.Writer, sourceDir, relPath string) (File, error) {
+	in, err := os.Open(filepath.Join(sourceDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return File{}, fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+	defer in.Close()
+
+	w, err := zw.Create(filesPrefix + relPath)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to add %s to bundle: %w", relPath, err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hash), in)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to add %s to bundle: %w", relPath, err)
+	}
+
+	return File{Path: relPath, SHA256: hex.EncodeToString(hash.Sum(nil)), Size: size}, nil
+}
+
+// addEntry adds an in-memory entry to the bundle
+func addEntry(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// Bundle is an opened deployment bundle whose signature has been verified
+type Bundle struct {
+	Manifest Manifest
+
+	zr    *zip.ReadCloser
+	files map[string]*zip.File
+}
+
+// Open opens the bundle at bundlePath and verifies its manifest signature with key.
+// The bundle must be closed by the caller.
+func Open(bundlePath string, key ed25519.PublicKey) (*Bundle, error) {
+	zr, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	bundle, err := verify(zr, key)
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// verify checks the signature of the manifest and that the bundle holds exactly its files
+func verify(zr *zip.ReadCloser, key ed25519.PublicKey) (*Bundle, error) {
+	bundle := &Bundle{zr: zr, files: make(map[string]*zip.File)}
+
+	var manifestData, signature []byte
+	for _, entry := range zr.File {
+		switch {
+		case entry.Name == manifestName:
+			data, err := readEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			manifestData = data
+		case entry.Name == signatureName:
+			data, err := readEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			signature = data
+		case strings.HasPrefix(entry.Name, filesPrefix):
+			bundle.files[strings.TrimPrefix(entry.Name, filesPrefix)] = entry
+		default:
+			return nil, fmt.Errorf("unexpected bundle entry %q", entry.Name)
+		}
+	}
+
+	if manifestData == nil || signature == nil {
+		return nil, fmt.Errorf("bundle is missing its manifest or signature")
+	}
+	if !ed25519.Verify(key, manifestData, signature) {
+		return nil, fmt.Errorf("bundle signature is invalid (wrong key or modified manifest)")
+	}
+	if err := json.Unmarshal(manifestData, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("bundle manifest is corrupt: %w", err)
+	}
+
+	if len(bundle.files) != len(bundle.Manifest.Files) {
+		return nil, fmt.Errorf("bundle contains %d files, manifest lists %d", len(bundle.files), len(bundle.Manifest.Files))
+	}
+	for _, file := range bundle.Manifest.Files {
+		if !isLocalPath(file.Path) {
+			return nil, fmt.Errorf("bundle file %q escapes the target directory", file.Path)
+		}
+		if _, ok := bundle.files[file.Path]; !ok {
+			return nil, fmt.Errorf("bundle is missing %s", file.Path)
+		}
+	}
+
+	return bundle, nil
+}
+
+// Close releases the bundle file
+func (b *Bundle) Close() error {
+	return b.zr.Close()
+}
+
+// Apply writes the bundle files into targetDir and returns the number of files written.
+// All files are checked against their manifest hash before anything is written, so a
+// tampered bundle leaves the target untouched.
+func (b *Bundle) Apply(targetDir string) (int, error) {
+	for _, file := range b.Manifest.Files {
+		if err := b.checkFile(file); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, file := range b.Manifest.Files {
+		if err := b.applyFile(targetDir, file); err != nil {
+			return i, err
+		}
+	}
+	return len(b.Manifest.Files), nil
+}
+
+// checkFile verifies the content of a bundle file against its manifest hash
+func (b *Bundle) checkFile(file File) error {
+	rc, err := b.files[file.Path].Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer rc.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("hash mismatch for %s: bundle was modified after signing", file.Path)
+	}
+	return nil
+}
+
+// applyFile extracts one file through a temporary file, so a failed write never
+// leaves a partially written file behind
+func (b *Bundle) applyFile(targetDir string, file File) error {
+	target := filepath.Join(targetDir, filepath.FromSlash(file.Path))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+	}
+
+	rc, err := b.files[file.Path].Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".mta-bundler-deploy-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.Path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), rc); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", file.Path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.Path, err)
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != file.SHA256 {
+		return fmt.Errorf("hash mismatch for %s: bundle was modified after signing", file.Path)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.Path, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.Path, err)
+	}
+	return nil
+}
+
+// readEntry reads the full content of a bundle entry
+func readEntry(entry *zip.File) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle entry %s: %w", entry.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle entry %s: %w", entry.Name, err)
+	}
+	return data, nil
+}
+
+// isLocalPath reports whether a slash-separated path stays inside the directory it is relative to
+func isLocalPath(p string) bool {
+	return !strings.Contains(p, `\`) && filepath.IsLocal(filepath.FromSlash(p))
+}
@@ -0,0 +1,156 @@
+package deploy
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files below dir
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// newKeys generates a key pair in a temp directory and returns the key file paths
+func newKeys(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "builder.key"), filepath.Join(dir, "builder.pub")
+	if err := GenerateKeys(privatePath, publicPath); err != nil {
+		t.Fatalf("GenerateKeys failed: %v", err)
+	}
+	return privatePath, publicPath
+}
+
+func TestCreateAndApply(t *testing.T) {
+	buildDir := t.TempDir()
+	writeTree(t, buildDir, map[string]string{
+		"race/meta.xml":     `<meta><script src="client.luac" type="client" /></meta>`,
+		"race/client.luac":  "compiled",
+		"race/images/a.png": "png",
+	})
+
+	privatePath, publicPath := newKeys(t)
+	private, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	public, err := LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey failed: %v", err)
+	}
+
+	bundlePath := filepath.Join(t.TempDir(), "request"+Extension)
+	manifest, err := Create(bundlePath, buildDir, Manifest{Target: "/srv/mta", RequestedBy: "builder"}, private)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(manifest.Files) != 3 || manifest.Files[0].Path != "race/client.luac" {
+		t.Fatalf("Unexpected manifest files: %+v", manifest.Files)
+	}
+
+	bundle, err := Open(bundlePath, public)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer bundle.Close()
+
+	if bundle.Manifest.RequestedBy != "builder" || bundle.Manifest.Target != "/srv/mta" {
+		t.Errorf("Unexpected manifest: %+v", bundle.Manifest)
+	}
+
+	targetDir := t.TempDir()
+	written, err := bundle.Apply(targetDir)
+	if err != nil || written != 3 {
+		t.Fatalf("Apply failed: wrote %d file(s), err=%v", written, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(targetDir, "race", "client.luac"))
+	if err != nil || string(data) != "compiled" {
+		t.Errorf("Expected deployed client.luac, got %q (err=%v)", data, err)
+	}
+}
+
+func TestOpenRejectsWrongKey(t *testing.T) {
+	buildDir := t.TempDir()
+	writeTree(t, buildDir, map[string]string{"race/meta.xml": "<meta />"})
+
+	privatePath, _ := newKeys(t)
+	_, otherPublicPath := newKeys(t)
+	private, _ := LoadPrivateKey(privatePath)
+	otherPublic, _ := LoadPublicKey(otherPublicPath)
+
+	bundlePath := filepath.Join(t.TempDir(), "request"+Extension)
+	if _, err := Create(bundlePath, buildDir, Manifest{Target: "/srv/mta"}, private); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := Open(bundlePath, otherPublic); err == nil {
+		t.Error("Expected a signature error with another requester's key")
+	}
+}
+
+func TestApplyRejectsModifiedFile(t *testing.T) {
+	buildDir := t.TempDir()
+	writeTree(t, buildDir, map[string]string{
+		"race/client.luac": "compiled",
+		"race/meta.xml":    "<meta />",
+	})
+
+	privatePath, publicPath := newKeys(t)
+	private, _ := LoadPrivateKey(privatePath)
+	public, _ := LoadPublicKey(publicPath)
+
+	bundlePath := filepath.Join(t.TempDir(), "request"+Extension)
+	if _, err := Create(bundlePath, buildDir, Manifest{Target: "/srv/mta"}, private); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Rewrite the bundle with a modified file but the original signed manifest
+	tamperedPath := filepath.Join(t.TempDir(), "tampered"+Extension)
+	zr, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		t.Fatalf("Failed to open bundle: %v", err)
+	}
+	out, _ := os.Create(tamperedPath)
+	zw := zip.NewWriter(out)
+	for _, entry := range zr.File {
+		rc, _ := entry.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if strings.HasSuffix(entry.Name, "client.luac") {
+			data = append(data, []byte("backdoor")...)
+		}
+		w, _ := zw.Create(entry.Name)
+		w.Write(data)
+	}
+	zw.Close()
+	out.Close()
+	zr.Close()
+
+	bundle, err := Open(tamperedPath, public)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer bundle.Close()
+
+	targetDir := t.TempDir()
+	if _, err := bundle.Apply(targetDir); err == nil {
+		t.Fatal("Expected a hash mismatch error")
+	}
+	if entries, _ := os.ReadDir(targetDir); len(entries) != 0 {
+		t.Errorf("Expected nothing to be deployed, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,297 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HelperResource is the resource installed on the server so deployments can refresh and
+// restart resources through the HTTP interface
+const HelperResource = "mta_bundler_deploy"
+
+// helperMeta and helperScript make up the helper resource. Its functions are exported over
+// HTTP and need the ACL rights it requests.
+const (
+	helperMeta = `<meta>
+    <info name="` + HelperResource + `" author="mta-bundler" type="script" description="Generated by mta-bundler: refreshes and restarts resources after deployments" />
+    <script src="server.lua" type="server" />
+    <export function="refreshAll" type="server" http="true" />
+    <export function="restart" type="server" http="true" />
+    <aclrequest>
+        <right name="function.refreshResources" access="true" />
+        <right name="function.restartResource" access="true" />
+    </aclrequest>
+</meta>
+`
+	helperScript = `-- Generated by mta-bundler: refreshes and restarts resources after deployments
+
+function refreshAll()
+	return refreshResources(true)
+end
+
+-- restart restarts the running resources among names and returns the names restarted
+function restart(names)
+	local restarted = {}
+	for _, name in ipairs(names or {}) do
+		local res = getResourceFromName(name)
+		if res and getResourceState(res) == "running" and restartResource(res) then
+			table.insert(restarted, name)
+		end
+	end
+	return restarted
+end
+`
+)
+
+// skippedFiles are build bookkeeping files that are not copied to the server
+var skippedFiles = map[string]bool{
+	".mta-bundler-manifest.json": true,
+	".mta-bundler-stamp.json":    true,
+}
+
+// Sync copies every file below buildDir into resourcesDir, skipping files whose content is
+// already there. It returns the slash-separated paths of the copied files, relative to both.
+func Sync(buildDir, resourcesDir string) ([]string, error) {
+	var changed []string
+	err := filepath.Walk(buildDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || skippedFiles[info.Name()] {
+			return nil
+		}
+		relPath, err := filepath.Rel(buildDir, p)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(resourcesDir, relPath)
+		same, err := sameFile(p, target)
+		if err != nil {
+			return err
+		}
+		if same {
+			return nil
+		}
+		if err := copyTo(p, target); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", relPath, err)
+		}
+		changed = append(changed, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return changed, fmt.Errorf("failed to copy build to %s: %w", resourcesDir, err)
+	}
+	return changed, nil
+}
+
+// ResourceNames returns the sorted names of the resources holding paths. Paths are relative to
+// a resources directory, where [category] directories group resources and resources may be
+// zipped.
+func ResourceNames(paths []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, p := range paths {
+		for _, part := range strings.Split(p, "/") {
+			if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+				continue
+			}
+			name := strings.TrimSuffix(part, ".zip")
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			break
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InstallHelper writes the helper resource into resourcesDir. It returns true when the files
+// changed, in which case the server has to start the new version before it can be used.
+func InstallHelper(resourcesDir string) (bool, error) {
+	dir := filepath.Join(resourcesDir, HelperResource)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to install %s: %w", HelperResource, err)
+	}
+
+	changed := false
+	for name, content := range map[string]string{"meta.xml": helperMeta, "server.lua": helperScript} {
+		path := filepath.Join(dir, name)
+		if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return false, fmt.Errorf("failed to install %s: %w", HelperResource, err)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// Client calls the helper resource through the HTTP interface of an MTA server
+type Client struct {
+	URL      string // Base URL of the HTTP interface, such as http://127.0.0.1:22005
+	User     string // Account allowed to call the helper resource
+	Password string
+
+	HTTPClient *http.Client // Client used for requests (nil uses a client with a 30 second timeout)
+}
+
+// Refresh makes the server rescan its resources directory for new and changed resources
+func (c Client) Refresh() error {
+	var result []any
+	if err := c.call("refreshAll", nil, &result); err != nil {
+		return err
+	}
+	if len(result) > 0 && result[0] == false {
+		return fmt.Errorf("the server failed to refresh its resources")
+	}
+	return nil
+}
+
+// Restart restarts the running resources among names and returns the names restarted
+func (c Client) Restart(names []string) ([]string, error) {
+	var result [][]string
+	if err := c.call("restart", []any{names}, &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result[0], nil
+}
+
+// call calls an exported function of the helper resource. Arguments and return values are
+// JSON arrays, as the MTA HTTP interface expects.
+func (c Client) call(function string, args []any, result any) error {
+	if args == nil {
+		args = []any{}
+	}
+	body, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("failed to encode arguments of %s: %w", function, err)
+	}
+
+	endpoint := strings.TrimSuffix(c.URL, "/") + "/" + HelperResource + "/call/" + function
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid server url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Password)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", function, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response of %s: %w", function, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("the server rejected the credentials (HTTP %d)", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s is not running on the server (HTTP 404)", HelperResource)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("call to %s failed: HTTP %d", function, resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("unexpected response to %s: %w", function, err)
+	}
+	return nil
+}
+
+// sameFile reports whether target exists with the same content as src
+func sameFile(src, target string) (bool, error) {
+	targetInfo, err := os.Stat(target)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, err
+	}
+	if srcInfo.Size() != targetInfo.Size() {
+		return false, nil
+	}
+
+	srcHash, err := hashFile(src)
+	if err != nil {
+		return false, err
+	}
+	targetHash, err := hashFile(target)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(srcHash, targetHash), nil
+}
+
+// hashFile returns the SHA-256 of a file
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// copyTo copies src to target through a temporary file, so the server never loads a partially
+// written file
+func copyTo(src, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".mta-bundler-deploy-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,96 @@
+package deploy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	buildDir, resourcesDir := t.TempDir(), t.TempDir()
+	writeTree(t, buildDir, map[string]string{
+		"[gameplay]/race/meta.xml":    `<meta><script src="client.luac" type="client" /></meta>`,
+		"[gameplay]/race/client.luac": "compiled",
+		"admin.zip":                   "archive",
+		".mta-bundler-stamp.json":     "{}",
+	})
+	writeTree(t, resourcesDir, map[string]string{
+		"[gameplay]/race/meta.xml": `<meta><script src="client.luac" type="client" /></meta>`,
+	})
+
+	changed, err := Sync(buildDir, resourcesDir)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if want := []string{"[gameplay]/race/client.luac", "admin.zip"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Changed files = %v, want %v", changed, want)
+	}
+	if _, err := os.Stat(filepath.Join(resourcesDir, ".mta-bundler-stamp.json")); !os.IsNotExist(err) {
+		t.Error("Build state file was copied to the server")
+	}
+	if names := ResourceNames(changed); !reflect.DeepEqual(names, []string{"admin", "race"}) {
+		t.Errorf("ResourceNames = %v, want [admin race]", names)
+	}
+
+	changed, err = Sync(buildDir, resourcesDir)
+	if err != nil {
+		t.Fatalf("Second Sync failed: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("Second Sync copied %v, want nothing", changed)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "deployer" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, r.URL.Path+" "+string(body))
+
+		switch r.URL.Path {
+		case "/" + HelperResource + "/call/refreshAll":
+			w.Write([]byte("[true]"))
+		case "/" + HelperResource + "/call/restart":
+			var args [][]string
+			json.Unmarshal(body, &args)
+			json.NewEncoder(w).Encode([][]string{args[0][:1]})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := Client{URL: server.URL + "/", User: "deployer", Password: "secret"}
+	if err := client.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	restarted, err := client.Restart([]string{"admin", "race"})
+	if err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if !reflect.DeepEqual(restarted, []string{"admin"}) {
+		t.Errorf("Restarted = %v, want [admin]", restarted)
+	}
+
+	want := []string{
+		"/" + HelperResource + "/call/refreshAll []",
+		"/" + HelperResource + "/call/restart [[\"admin\",\"race\"]]",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Calls = %v, want %v", calls, want)
+	}
+
+	client.Password = "wrong"
+	if err := client.Refresh(); err == nil {
+		t.Error("Refresh succeeded with wrong credentials")
+	}
+}
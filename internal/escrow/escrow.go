@@ -0,0 +1,217 @@
+package escrow
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the escrow archive written into each compiled resource.
+// It is never referenced by meta.xml, so MTA does not send it to clients.
+const FileName = "mta-bundler.escrow"
+
+// manifestName is the name of the manifest entry inside the archive
+const manifestName = "escrow.json"
+
+// magic identifies escrow archives and their format version
+var magic = []byte("MTAESC1\n")
+
+// Manifest describes the contents of an escrow archive and how they were last compiled
+type Manifest struct {
+	Resource         string    `json:"resource"`              // Resource name
+	CreatedAt        time.Time `json:"created_at"`            // When the archive was written
+	ObfuscationLevel int       `json:"obfuscation_level"`     // Obfuscation level of the deployed build
+	StripDebug       bool      `json:"strip_debug"`           // Whether the deployed build stripped debug info
+	MergeMode        bool      `json:"merge_mode"`            // Whether the deployed build merged scripts
+	BundleName       string    `json:"bundle_name,omitempty"` // Path of the merged bundles when not the default
+	Files            []string  `json:"files"`                 // Slash-separated paths stored in the archive
+}
+
+// LoadKey reads a key file and derives the 256-bit archive key from its contents
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read escrow key file: %w", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("escrow key file %s is empty", path)
+	}
+
+	key := sha256.Sum256(data)
+	return key[:], nil
+}
+
+// Write creates an encrypted escrow archive at path containing the given source files.
+// Files are given as paths relative to baseDir.
+func Write(path string, key []byte, baseDir string, files []string, manifest Manifest) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	manifest.Files = nil
+	for _, relPath := range files {
+		data, err := os.ReadFile(filepath.Join(baseDir, relPath))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+
+		name := filepath.ToSlash(filepath.Clean(relPath))
+		w, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to escrow: %w", relPath, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to add %s to escrow: %w", relPath, err)
+		}
+		manifest.Files = append(manifest.Files, name)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode escrow manifest: %w", err)
+	}
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		return fmt.Errorf("failed to add escrow manifest: %w", err)
+	}
+	if _, err := w.Write(manifestData); err != nil {
+		return fmt.Errorf("failed to add escrow manifest: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish escrow archive: %w", err)
+	}
+
+	sealed, err := seal(key, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create escrow directory: %w", err)
+	}
+	return os.WriteFile(path, sealed, 0600)
+}
+
+// Extract decrypts the escrow archive at path into destDir and returns its manifest
+func Extract(path string, key []byte, destDir string) (Manifest, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read escrow archive: %w", err)
+	}
+
+	data, err := open(key, sealed)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("escrow archive is corrupt: %w", err)
+	}
+
+	var manifest Manifest
+	for _, file := range zr.File {
+		content, err := readZipFile(file)
+		if err != nil {
+			return Manifest{}, err
+		}
+
+		if file.Name == manifestName {
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return Manifest{}, fmt.Errorf("escrow manifest is corrupt: %w", err)
+			}
+			continue
+		}
+
+		// Reject entries escaping the destination directory
+		target := filepath.Join(destDir, filepath.FromSlash(file.Name))
+		if rel, err := filepath.Rel(destDir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return Manifest{}, fmt.Errorf("escrow entry %q escapes the destination directory", file.Name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return Manifest{}, fmt.Errorf("failed to create directory for %s: %w", file.Name, err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return Manifest{}, fmt.Errorf("failed to extract %s: %w", file.Name, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// readZipFile reads the full content of an archive entry
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open escrow entry %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read escrow entry %s: %w", file.Name, err)
+	}
+	return content, nil
+}
+
+// seal encrypts data with AES-256-GCM and prefixes the magic header and nonce
+func seal(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append([]byte{}, magic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, magic), nil
+}
+
+// open verifies and decrypts data produced by seal
+func open(key, sealed []byte) ([]byte, error) {
+	if !bytes.HasPrefix(sealed, magic) {
+		return nil, fmt.Errorf("not an escrow archive produced by mta-bundler")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := sealed[len(magic):]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("escrow archive is truncated")
+	}
+
+	data, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt escrow archive (wrong key or corrupt file)")
+	}
+	return data, nil
+}
+
+// newGCM creates the AES-GCM cipher used for escrow archives
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid escrow key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
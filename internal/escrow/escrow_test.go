@@ -0,0 +1,74 @@
+package escrow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAndExtract(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"meta.xml":         `<meta><script src="client.lua" type="client" /></meta>`,
+		"client.lua":       `outputChatBox("hello")`,
+		"utils/helper.lua": `function helper() end`,
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyPath, []byte("team secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	key, err := LoadKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), FileName)
+	manifest := Manifest{Resource: "test", ObfuscationLevel: 2, StripDebug: true}
+	if err := Write(archivePath, key, srcDir, []string{"meta.xml", "client.lua", filepath.Join("utils", "helper.lua")}, manifest); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	destDir := t.TempDir()
+	extracted, err := Extract(archivePath, key, destDir)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	if extracted.Resource != "test" || extracted.ObfuscationLevel != 2 || !extracted.StripDebug {
+		t.Errorf("Unexpected manifest: %+v", extracted)
+	}
+	if len(extracted.Files) != 3 {
+		t.Errorf("Expected 3 files in manifest, got %v", extracted.Files)
+	}
+
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(destDir, name))
+		if err != nil {
+			t.Errorf("Expected %s to be extracted: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Content mismatch for %s: %q", name, data)
+		}
+	}
+
+	// A different key must not decrypt the archive
+	otherKeyPath := filepath.Join(t.TempDir(), "other")
+	if err := os.WriteFile(otherKeyPath, []byte("other secret"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	otherKey, _ := LoadKey(otherKeyPath)
+	if _, err := Extract(archivePath, otherKey, t.TempDir()); err == nil {
+		t.Error("Expected extraction with the wrong key to fail")
+	}
+}
@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Graph is the include graph of a set of resources: the resources each one includes with
+// <include resource="..."/> in its meta.xml
+type Graph struct {
+	names    []string            // Resources in the order they were added
+	includes map[string][]string // Resources included by each resource, as declared
+}
+
+// CycleError is returned when resources include each other
+type CycleError struct {
+	Cycle []string // Resources of the cycle, the first one repeated at the end
+}
+
+// Error returns the cycle as a chain of includes
+func (e *CycleError) Error() string {
+	return "include cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+// New returns an empty graph
+func New() *Graph {
+	return &Graph{includes: make(map[string][]string)}
+}
+
+// Add adds the resource name and the resources it includes. Adding a resource again replaces
+// its includes.
+func (g *Graph) Add(name string, includes []string) {
+	if _, ok := g.includes[name]; !ok {
+		g.names = append(g.names, name)
+	}
+	g.includes[name] = includes
+}
+
+// Has reports whether the resource name is part of the graph
+func (g *Graph) Has(name string) bool {
+	_, ok := g.includes[name]
+	return ok
+}
+
+// Names returns the resources of the graph in the order they were added
+func (g *Graph) Names() []string {
+	return g.names
+}
+
+// Includes returns the resources included by name as declared, including the ones that are not
+// part of the graph
+func (g *Graph) Includes(name string) []string {
+	return g.includes[name]
+}
+
+// Missing returns the resources included by name that are not part of the graph
+func (g *Graph) Missing(name string) []string {
+	var missing []string
+	for _, include := range g.includes[name] {
+		if !g.Has(include) {
+			missing = append(missing, include)
+		}
+	}
+	return missing
+}
+
+// Order returns the resources of the graph with every resource after the ones it includes,
+// otherwise keeping the order they were added in. Includes of resources that are not part of
+// the graph are ignored.
+func (g *Graph) Order() ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(g.names))
+	order := make([]string, 0, len(g.names))
+	var stack []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for stack[start] != name {
+				start++
+			}
+			return &CycleError{Cycle: append(append([]string{}, stack[start:]...), name)}
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, include := range g.includes[name] {
+			if !g.Has(include) {
+				continue
+			}
+			if err := visit(include); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range g.names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Dependents returns the sorted resources that include name, directly or through other
+// resources
+func (g *Graph) Dependents(name string) []string {
+	includedBy := make(map[string][]string)
+	for _, n := range g.names {
+		for _, include := range g.includes[n] {
+			includedBy[include] = append(includedBy[include], n)
+		}
+	}
+
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	var dependents []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range includedBy[current] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				dependents = append(dependents, dependent)
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
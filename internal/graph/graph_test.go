@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	g := New()
+	g.Add("race", []string{"vehicles", "scoreboard"})
+	g.Add("scoreboard", nil)
+	g.Add("vehicles", []string{"utils", "admin"})
+	g.Add("utils", nil)
+	g.Add("freeroam", []string{"utils"})
+
+	order, err := g.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(order, " "), "utils vehicles scoreboard race freeroam"; got != want {
+		t.Errorf("Order() = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(g.Dependents("utils"), " "), "freeroam race vehicles"; got != want {
+		t.Errorf("Dependents(utils) = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(g.Missing("vehicles"), " "), "admin"; got != want {
+		t.Errorf("Missing(vehicles) = %s, want %s", got, want)
+	}
+
+	g.Add("utils", []string{"race"})
+	_, err = g.Order()
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Order() error = %v, want a cycle", err)
+	}
+	if got, want := err.Error(), "include cycle: race -> vehicles -> utils -> race"; got != want {
+		t.Errorf("Order() error = %s, want %s", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	g := New()
+	g.Add("race", []string{"utils", "admin"})
+	g.Add("utils", nil)
+	details := map[string][]string{"race": {"3 files, 12.0 KB"}}
+
+	var b strings.Builder
+	if err := g.Write(&b, FormatDOT, details); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph resources {
+    rankdir=LR;
+    node [shape=box];
+    "race" [label="race\n3 files, 12.0 KB"];
+    "utils" [label="utils"];
+    "admin" [style=dashed];
+    "race" -> "utils";
+    "race" -> "admin" [style=dashed];
+}
+`
+	if b.String() != want {
+		t.Errorf("DOT output:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := g.Write(&b, FormatMermaid, details); err != nil {
+		t.Fatal(err)
+	}
+	want = `flowchart LR
+    r0["race<br/>3 files, 12.0 KB"]
+    r1["utils"]
+    r2("admin")
+    r0 --> r1
+    r0 -.-> r2
+`
+	if b.String() != want {
+		t.Errorf("Mermaid output:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	if err := g.Write(&b, "svg", nil); err == nil {
+		t.Error("Write(svg) succeeded, want an error")
+	}
+}
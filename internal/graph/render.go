@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats a graph can be written in
+const (
+	FormatDOT     = "dot"     // Graphviz
+	FormatMermaid = "mermaid" // Mermaid flowchart, rendered by GitHub and GitLab in Markdown
+)
+
+// Write writes the graph in format. details adds lines below the name of a resource, such as
+// its file count, and may be nil. Includes of resources that are not part of the graph are
+// drawn with dashed edges to a node of their own.
+func (g *Graph) Write(w io.Writer, format string, details map[string][]string) error {
+	switch format {
+	case FormatDOT:
+		return g.writeDOT(w, details)
+	case FormatMermaid:
+		return g.writeMermaid(w, details)
+	}
+	return fmt.Errorf("unknown graph format %q (use %s or %s)", format, FormatDOT, FormatMermaid)
+}
+
+// writeDOT writes the graph as a Graphviz digraph, with edges from a resource to the ones it
+// includes
+func (g *Graph) writeDOT(w io.Writer, details map[string][]string) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph resources {")
+	fmt.Fprintln(out, "    rankdir=LR;")
+	fmt.Fprintln(out, "    node [shape=box];")
+	for _, name := range g.names {
+		label := strings.Join(append([]string{name}, details[name]...), "\n")
+		fmt.Fprintf(out, "    %s [label=%s];\n", strconv.Quote(name), strconv.Quote(label))
+	}
+	for _, name := range g.external() {
+		fmt.Fprintf(out, "    %s [style=dashed];\n", strconv.Quote(name))
+	}
+	for _, name := range g.names {
+		for _, include := range g.includes[name] {
+			style := ""
+			if !g.Has(include) {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(out, "    %s -> %s%s;\n", strconv.Quote(name), strconv.Quote(include), style)
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart. Nodes get generated ids, as resource
+// names may hold characters Mermaid does not accept in ids.
+func (g *Graph) writeMermaid(w io.Writer, details map[string][]string) error {
+	ids := make(map[string]string)
+	id := func(name string) string {
+		if _, ok := ids[name]; !ok {
+			ids[name] = fmt.Sprintf("r%d", len(ids))
+		}
+		return ids[name]
+	}
+	label := func(lines []string) string {
+		for i, line := range lines {
+			lines[i] = strings.ReplaceAll(line, `"`, "#quot;")
+		}
+		return `"` + strings.Join(lines, "<br/>") + `"`
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "flowchart LR")
+	for _, name := range g.names {
+		fmt.Fprintf(out, "    %s[%s]\n", id(name), label(append([]string{name}, details[name]...)))
+	}
+	for _, name := range g.external() {
+		fmt.Fprintf(out, "    %s(%s)\n", id(name), label([]string{name}))
+	}
+	for _, name := range g.names {
+		for _, include := range g.includes[name] {
+			arrow := "-->"
+			if !g.Has(include) {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(out, "    %s %s %s\n", id(name), arrow, id(include))
+		}
+	}
+	return out.Flush()
+}
+
+// external returns the included resources that are not part of the graph, in the order they
+// are first included
+func (g *Graph) external() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range g.names {
+		for _, include := range g.Missing(name) {
+			if !seen[include] {
+				seen[include] = true
+				names = append(names, include)
+			}
+		}
+	}
+	return names
+}
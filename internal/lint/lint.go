@@ -0,0 +1,174 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// Severity tells whether a finding fails the build or is only reported
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning" // Logged, the resource is still built
+	SeverityError   Severity = "error"   // Fails the resource and checks
+)
+
+// Rules of the linter. They are stable, so config files keep matching across versions.
+const (
+	RuleClientFileSize  = "client-file-size" // A file downloaded by clients is larger than the limit
+	RuleScriptType      = "script-type"      // A script has no type attribute and runs on the server only
+	RuleOOP             = "oop"              // meta.xml does not declare <oop>
+	RuleInfoVersion     = "info-version"     // The <info> element has no version attribute
+	RuleUndefinedGlobal = "undefined-global" // A script reads a global that is never defined
+	RuleAPISide         = "api-side"         // A script calls an MTA function of the other side
+)
+
+// DefaultMaxSize is the limit of client-file-size when none is configured
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// Setting is how a rule is applied
+type Setting struct {
+	Enabled  bool
+	Severity Severity
+	MaxSize  int64    // Limit in bytes of client-file-size
+	Globals  []string // Globals undefined-global accepts on top of the Lua and MTA ones
+}
+
+// Finding is a rule broken by a resource
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Resource string
+	Src      string // Referenced file the finding is about, empty for the whole meta.xml
+	Message  string
+}
+
+// String returns the finding as a single line
+func (f Finding) String() string {
+	if f.Src == "" {
+		return fmt.Sprintf("[%s] %s: %s", f.Rule, f.Resource, f.Message)
+	}
+	return fmt.Sprintf("[%s] %s/%s: %s", f.Rule, f.Resource, f.Src, f.Message)
+}
+
+// rule is a check of the linter
+type rule struct {
+	name     string
+	defaults Setting
+	check    func(res *resource.Resource, setting Setting, report func(src, format string, args ...any))
+}
+
+var rules = []rule{
+	{RuleClientFileSize, Setting{Enabled: true, Severity: SeverityWarning, MaxSize: DefaultMaxSize}, checkClientFileSize},
+	{RuleScriptType, Setting{Enabled: true, Severity: SeverityWarning}, checkScriptType},
+	{RuleOOP, Setting{Severity: SeverityWarning}, checkOOP},
+	{RuleInfoVersion, Setting{Severity: SeverityWarning}, checkInfoVersion},
+	{RuleUndefinedGlobal, Setting{Enabled: true, Severity: SeverityWarning}, checkUndefinedGlobal},
+	{RuleAPISide, Setting{Enabled: true, Severity: SeverityWarning}, checkAPISide},
+}
+
+// Rules returns the names of the rules
+func Rules() []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.name
+	}
+	return names
+}
+
+// Defaults returns the setting of a rule when it is not configured, and false for an unknown rule
+func Defaults(name string) (Setting, bool) {
+	for _, r := range rules {
+		if r.name == name {
+			return r.defaults, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Lint applies the enabled rules to res. Rules missing from settings use their defaults.
+func Lint(res *resource.Resource, settings map[string]Setting) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		setting, ok := settings[r.name]
+		if !ok {
+			setting = r.defaults
+		}
+		if !setting.Enabled {
+			continue
+		}
+		r.check(res, setting, func(src, format string, args ...any) {
+			findings = append(findings, Finding{Rule: r.name, Severity: setting.Severity, Resource: res.Name, Src: src,
+				Message: fmt.Sprintf(format, args...)})
+		})
+	}
+	return findings
+}
+
+// checkClientFileSize reports the scripts, files and configs downloaded by clients that are
+// larger than the limit
+func checkClientFileSize(res *resource.Resource, setting Setting, report func(src, format string, args ...any)) {
+	var srcs []string
+	for _, script := range res.Meta.Scripts {
+		if kind := strings.ToLower(script.Type); (kind == "client" || kind == "shared") && !resource.IsURL(script.Src) {
+			srcs = append(srcs, script.Src)
+		}
+	}
+	for _, file := range res.Meta.Files {
+		srcs = append(srcs, file.Src)
+	}
+	for _, config := range res.Meta.Configs {
+		if strings.ToLower(config.Type) == "client" {
+			srcs = append(srcs, config.Src)
+		}
+	}
+
+	for _, src := range srcs {
+		info, err := os.Stat(filepath.Join(res.BaseDir, src))
+		if err != nil || !info.Mode().IsRegular() {
+			// Missing files are reported by the resource checks
+			continue
+		}
+		if info.Size() > setting.MaxSize {
+			report(src, "client file is %s, over the limit of %s", formatSize(info.Size()), formatSize(setting.MaxSize))
+		}
+	}
+}
+
+// checkScriptType reports the scripts without a type attribute
+func checkScriptType(res *resource.Resource, _ Setting, report func(src, format string, args ...any)) {
+	for _, script := range res.Meta.Scripts {
+		if strings.TrimSpace(script.Type) == "" {
+			report(script.Src, "script has no type attribute and runs on the server only")
+		}
+	}
+}
+
+// checkOOP reports a meta.xml without an <oop> element
+func checkOOP(res *resource.Resource, _ Setting, report func(src, format string, args ...any)) {
+	if res.Meta.OOP == nil {
+		report("", "meta.xml does not declare <oop>true</oop> or <oop>false</oop>")
+	}
+}
+
+// checkInfoVersion reports an <info> element without a version
+func checkInfoVersion(res *resource.Resource, _ Setting, report func(src, format string, args ...any)) {
+	if strings.TrimSpace(res.Meta.Info.Version) == "" {
+		report("", "<info> has no version attribute")
+	}
+}
+
+// formatSize returns a size in bytes in the largest unit it reaches
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,158 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+func TestLint(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "race")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{"client.lua": 2048, "server.lua": 4096, "untyped.lua": 10, "skin.dff": 3072, "small.txd": 100}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := `<meta>
+	<info author="someone"/>
+	<script src="client.lua" type="client"/>
+	<script src="server.lua" type="server"/>
+	<script src="untyped.lua"/>
+	<file src="skin.dff"/>
+	<file src="small.txd"/>
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resource.NewResource(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lint := func(settings map[string]Setting) string {
+		var got []string
+		for _, finding := range Lint(res, settings) {
+			got = append(got, string(finding.Severity)+" "+finding.String())
+		}
+		return strings.Join(got, "\n")
+	}
+
+	if got, want := lint(nil), "warning [script-type] race/untyped.lua: script has no type attribute and runs on the server only"; got != want {
+		t.Errorf("Lint() with defaults =\n%s\nwant\n%s", got, want)
+	}
+
+	settings := map[string]Setting{
+		RuleClientFileSize: {Enabled: true, Severity: SeverityError, MaxSize: 1024},
+		RuleScriptType:     {Enabled: false},
+		RuleOOP:            {Enabled: true, Severity: SeverityWarning},
+		RuleInfoVersion:    {Enabled: true, Severity: SeverityError},
+	}
+	want := strings.Join([]string{
+		"error [client-file-size] race/client.lua: client file is 2.0 KB, over the limit of 1.0 KB",
+		"error [client-file-size] race/skin.dff: client file is 3.0 KB, over the limit of 1.0 KB",
+		"warning [oop] race: meta.xml does not declare <oop>true</oop> or <oop>false</oop>",
+		"error [info-version] race: <info> has no version attribute",
+	}, "\n")
+	if got := lint(settings); got != want {
+		t.Errorf("Lint() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUndefinedGlobal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "chat")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	scripts := map[string]string{
+		"shared.lua": "config = {}\nfunction formatMessage(text) return text end",
+		"client.lua": "addEventHandler('onClientRender', root, function()\n\toutputChatbox(formatMessage(config.text))\n\tdrawCache(localPlayer)\n\toutputChatbox('again')\nend)",
+		"server.lua": "local function helper() return cache end\nhelper()\nconfig.text = 'hi'",
+		"broken.lua": "if then",
+	}
+	for name, src := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := `<meta>
+	<script src="shared.lua" type="shared"/>
+	<script src="client.lua" type="client"/>
+	<script src="server.lua" type="server"/>
+	<script src="broken.lua" type="server"/>
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resource.NewResource(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setting := Setting{Enabled: true, Severity: SeverityWarning, Globals: []string{"cache"}}
+	var got []string
+	for _, finding := range Lint(res, map[string]Setting{RuleUndefinedGlobal: setting, RuleScriptType: {}}) {
+		got = append(got, finding.String())
+	}
+	want := []string{
+		"[undefined-global] chat/client.lua: line 2: outputChatbox is not defined, did you mean outputChatBox?",
+		"[undefined-global] chat/client.lua: line 3: drawCache is not defined",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAPISide(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hud")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	scripts := map[string]string{
+		"client.lua": "dxDrawText('hi', 0, 0)\nlocal ip = getPlayerIP(localPlayer)\nkickPlayer(localPlayer)\nkickPlayer(localPlayer)\nsyncHud()",
+		"server.lua": "function syncHud() end\ntriggerClientEvent('sync', root)\nguiCreateWindow(0, 0, 1, 1, 'x', false)",
+		"shared.lua": "if triggerServerEvent then triggerServerEvent('ready', localPlayer) end\ndxDrawRectangle(0, 0, 1, 1)\noutputChatBox('shared')",
+		"merged.lua": "outputServerLog('untyped scripts run on the server')",
+	}
+	for name, src := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := `<meta>
+	<script src="client.lua" type="client"/>
+	<script src="server.lua" type="server"/>
+	<script src="shared.lua" type="shared"/>
+	<script src="merged.lua"/>
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resource.NewResource(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, finding := range Lint(res, map[string]Setting{RuleScriptType: {}, RuleUndefinedGlobal: {}}) {
+		got = append(got, finding.String())
+	}
+	want := []string{
+		"[api-side] hud/client.lua: line 2: getPlayerIP is a server-only function, client scripts cannot call it",
+		"[api-side] hud/client.lua: line 3: kickPlayer is a server-only function, client scripts cannot call it",
+		"[api-side] hud/server.lua: line 3: guiCreateWindow is a client-only function, server scripts cannot call it",
+		"[api-side] hud/shared.lua: line 2: dxDrawRectangle is a client-only function, it fails when this shared script runs on the server",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
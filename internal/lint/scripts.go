@@ -0,0 +1,164 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/lua"
+	"github.com/davidbozo/mta-bundler/internal/mtaapi"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+// script is a parsed Lua script of a resource
+type script struct {
+	src      string
+	side     mtaapi.Side // Where the script runs, shared scripts run on both sides
+	accesses []lua.GlobalAccess
+}
+
+// parseScripts parses the Lua scripts of res and resolves their globals. Scripts that do not
+// parse, including compiled ones, are skipped; syntax errors are reported by the syntax check.
+func parseScripts(res *resource.Resource) []script {
+	var scripts []script
+	for _, s := range res.Meta.Scripts {
+		if resource.IsURL(s.Src) || strings.ToLower(filepath.Ext(s.Src)) != ".lua" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(res.BaseDir, s.Src))
+		if err != nil {
+			continue
+		}
+		chunk, err := lua.Parse(data)
+		if err != nil {
+			continue
+		}
+		side := mtaapi.SideServer
+		switch strings.ToLower(s.Type) {
+		case "client":
+			side = mtaapi.SideClient
+		case "shared":
+			side = mtaapi.SideShared
+		}
+		scripts = append(scripts, script{src: s.Src, side: side, accesses: lua.Globals(chunk)})
+	}
+	return scripts
+}
+
+// checkUndefinedGlobal reports reads of globals that no script of the resource assigns and that
+// neither Lua nor MTA define, such as outputChatbox for outputChatBox. Each global is reported
+// once per script, at its first read.
+func checkUndefinedGlobal(res *resource.Resource, setting Setting, report func(src, format string, args ...any)) {
+	scripts := parseScripts(res)
+	defined := make(map[string]bool)
+	for _, name := range setting.Globals {
+		defined[name] = true
+	}
+	for _, s := range scripts {
+		for _, access := range s.accesses {
+			if access.Write {
+				// Scripts of a resource share their globals, whatever the order they are loaded in
+				defined[access.Name] = true
+			}
+		}
+	}
+
+	for _, s := range scripts {
+		reported := make(map[string]bool)
+		for _, access := range s.accesses {
+			if access.Write || defined[access.Name] || reported[access.Name] {
+				continue
+			}
+			if _, ok := mtaapi.Lookup(access.Name); ok {
+				continue
+			}
+			reported[access.Name] = true
+			if suggestion := suggestGlobal(access.Name, defined); suggestion != "" {
+				report(s.src, "line %d: %s is not defined, did you mean %s?", access.Line, access.Name, suggestion)
+			} else {
+				report(s.src, "line %d: %s is not defined", access.Line, access.Name)
+			}
+		}
+	}
+}
+
+// suggestGlobal returns the Lua, MTA or resource global that differs from name only in case, or
+// "" when there is none
+func suggestGlobal(name string, defined map[string]bool) string {
+	if suggestion := mtaapi.Suggest(name); suggestion != "" {
+		return suggestion
+	}
+	var matches []string
+	for global := range defined {
+		if strings.EqualFold(global, name) {
+			matches = append(matches, global)
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	slices.Sort(matches)
+	return matches[0]
+}
+
+// checkAPISide reports calls of server-only MTA functions from client scripts and of client-only
+// ones from server scripts. Shared scripts run on both sides, and in merge mode are compiled into
+// both bundles, so they may call neither. Functions the resource defines itself on the side of
+// the script are not reported, nor are calls in shared scripts that also test the function, as
+// in if triggerServerEvent then ... end. Each function is reported once per script.
+func checkAPISide(res *resource.Resource, _ Setting, report func(src, format string, args ...any)) {
+	scripts := parseScripts(res)
+	// Globals the resource assigns on each side
+	defined := map[mtaapi.Side]map[string]bool{mtaapi.SideClient: {}, mtaapi.SideServer: {}}
+	for _, s := range scripts {
+		for _, access := range s.accesses {
+			if !access.Write {
+				continue
+			}
+			if s.side != mtaapi.SideServer {
+				defined[mtaapi.SideClient][access.Name] = true
+			}
+			if s.side != mtaapi.SideClient {
+				defined[mtaapi.SideServer][access.Name] = true
+			}
+		}
+	}
+
+	for _, s := range scripts {
+		tested := make(map[string]bool)
+		if s.side == mtaapi.SideShared {
+			for _, access := range s.accesses {
+				if !access.Write && !access.Call {
+					tested[access.Name] = true
+				}
+			}
+		}
+
+		reported := make(map[string]bool)
+		for _, access := range s.accesses {
+			if !access.Call || reported[access.Name] || tested[access.Name] {
+				continue
+			}
+			side, ok := mtaapi.Lookup(access.Name)
+			if !ok || side == mtaapi.SideShared || side == s.side {
+				continue
+			}
+			// The side the function is missing on
+			missing := mtaapi.SideClient
+			if side == mtaapi.SideClient {
+				missing = mtaapi.SideServer
+			}
+			if defined[missing][access.Name] {
+				continue
+			}
+			reported[access.Name] = true
+			if s.side == mtaapi.SideShared {
+				report(s.src, "line %d: %s is a %s-only function, it fails when this shared script runs on the %s",
+					access.Line, access.Name, side, missing)
+			} else {
+				report(s.src, "line %d: %s is a %s-only function, %s scripts cannot call it", access.Line, access.Name, side, s.side)
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package lua
+
+// Node is a node of the syntax tree of a chunk
+type Node interface {
+	Pos() Position
+}
+
+// Stat is a statement
+type Stat interface {
+	Node
+	stat()
+}
+
+// Expr is an expression
+type Expr interface {
+	Node
+	expr()
+}
+
+// Chunk is a parsed source, the body of its main function
+type Chunk struct {
+	Block *Block
+}
+
+// Block is a list of statements, the body of a chunk, function or control structure
+type Block struct {
+	Position
+	Stats []Stat
+}
+
+// LocalStat is local a, b = x, y
+type LocalStat struct {
+	Position
+	Names  []*Name
+	Values []Expr
+}
+
+// AssignStat is a, t.b = x, y
+type AssignStat struct {
+	Position
+	Targets []Expr // Names and IndexExprs
+	Values  []Expr
+}
+
+// CallStat is a function or method call used as a statement
+type CallStat struct {
+	Position
+	Call Expr // CallExpr or MethodCallExpr
+}
+
+// DoStat is do ... end
+type DoStat struct {
+	Position
+	Body *Block
+}
+
+// WhileStat is while cond do ... end
+type WhileStat struct {
+	Position
+	Cond Expr
+	Body *Block
+}
+
+// RepeatStat is repeat ... until cond. The condition sees the locals of the body.
+type RepeatStat struct {
+	Position
+	Body *Block
+	Cond Expr
+}
+
+// IfStat is if cond then ... elseif cond then ... else ... end
+type IfStat struct {
+	Position
+	Clauses []*IfClause // The if clause and every elseif clause
+	Else    *Block      // nil without an else clause
+	ElsePos Position    // Position of else, zero without an else clause
+	End     Position    // Position of the closing end
+}
+
+// IfClause is a condition of an if statement and the block run when it holds
+type IfClause struct {
+	Position // Position of if or elseif
+	Cond     Expr
+	Body     *Block
+}
+
+// NumericForStat is for i = start, limit, step do ... end
+type NumericForStat struct {
+	Position
+	Var   *Name
+	Start Expr
+	Limit Expr
+	Step  Expr // nil when left out
+	Body  *Block
+}
+
+// GenericForStat is for k, v in explist do ... end
+type GenericForStat struct {
+	Position
+	Names  []*Name
+	Values []Expr
+	Body   *Block
+}
+
+// FunctionStat is function a.b:c() ... end, an assignment of a function to a variable or field
+type FunctionStat struct {
+	Position
+	Target Expr // Name, or IndexExpr for a.b and a:b
+	Method bool // Declared with a colon, with an implicit self parameter
+	Func   *FunctionExpr
+}
+
+// LocalFunctionStat is local function f() ... end. Unlike local f = function, the function
+// sees its own name.
+type LocalFunctionStat struct {
+	Position
+	Name *Name
+	Func *FunctionExpr
+}
+
+// ReturnStat is return explist, always the last statement of its block
+type ReturnStat struct {
+	Position
+	Values []Expr
+}
+
+// BreakStat is break, always the last statement of its block
+type BreakStat struct {
+	Position
+}
+
+func (*LocalStat) stat()         {}
+func (*AssignStat) stat()        {}
+func (*CallStat) stat()          {}
+func (*DoStat) stat()            {}
+func (*WhileStat) stat()         {}
+func (*RepeatStat) stat()        {}
+func (*IfStat) stat()            {}
+func (*NumericForStat) stat()    {}
+func (*GenericForStat) stat()    {}
+func (*FunctionStat) stat()      {}
+func (*LocalFunctionStat) stat() {}
+func (*ReturnStat) stat()        {}
+func (*BreakStat) stat()         {}
+
+// Name is a variable, as declared by local, for or a parameter, or as used in an expression
+type Name struct {
+	Position
+	Name string
+}
+
+// NilExpr is nil
+type NilExpr struct {
+	Position
+}
+
+// TrueExpr is true
+type TrueExpr struct {
+	Position
+}
+
+// FalseExpr is false
+type FalseExpr struct {
+	Position
+}
+
+// VarargExpr is ...
+type VarargExpr struct {
+	Position
+}
+
+// NumberExpr is a numeric literal
+type NumberExpr struct {
+	Position
+	Text  string // As written in the source
+	Value float64
+}
+
+// StringExpr is a string literal
+type StringExpr struct {
+	Position
+	Text  string // As written in the source, with quotes or long brackets; empty for keys written as names (t.k, k = v)
+	Value string
+}
+
+// FunctionExpr is function(params) ... end
+type FunctionExpr struct {
+	Position
+	Params []*Name // With self first for methods
+	Vararg bool
+	Body   *Block
+	End    Position // Position of the closing end
+}
+
+// FieldKind is the form of a table constructor field
+type FieldKind int
+
+const (
+	FieldPositional FieldKind = iota // value
+	FieldNamed                       // name = value
+	FieldKeyed                       // [key] = value
+)
+
+// Field is a field of a table constructor
+type Field struct {
+	Position
+	Kind  FieldKind
+	Key   Expr // nil for positional fields, a StringExpr for named ones
+	Value Expr
+}
+
+// TableExpr is a table constructor
+type TableExpr struct {
+	Position
+	Fields []*Field
+}
+
+// BinaryExpr is a binary operation, Op is the operator as written, such as .. or and
+type BinaryExpr struct {
+	Position
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// UnaryExpr is not x, -x or #x
+type UnaryExpr struct {
+	Position
+	Op      string
+	Operand Expr
+}
+
+// ParenExpr is an expression in parentheses, which keeps only the first value of a call or ...
+type ParenExpr struct {
+	Position
+	Inner Expr
+}
+
+// IndexExpr is t[k], or t.k with Dot set and a StringExpr key
+type IndexExpr struct {
+	Position
+	Object Expr
+	Key    Expr
+	Dot    bool
+}
+
+// CallExpr is f(args), f"str" or f{...}
+type CallExpr struct {
+	Position
+	Func Expr
+	Args []Expr
+}
+
+// MethodCallExpr is o:m(args)
+type MethodCallExpr struct {
+	Position
+	Object Expr
+	Method string
+	Args   []Expr
+}
+
+func (*Name) expr()           {}
+func (*NilExpr) expr()        {}
+func (*TrueExpr) expr()       {}
+func (*FalseExpr) expr()      {}
+func (*VarargExpr) expr()     {}
+func (*NumberExpr) expr()     {}
+func (*StringExpr) expr()     {}
+func (*FunctionExpr) expr()   {}
+func (*TableExpr) expr()      {}
+func (*BinaryExpr) expr()     {}
+func (*UnaryExpr) expr()      {}
+func (*ParenExpr) expr()      {}
+func (*IndexExpr) expr()      {}
+func (*CallExpr) expr()       {}
+func (*MethodCallExpr) expr() {}
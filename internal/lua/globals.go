@@ -0,0 +1,236 @@
+package lua
+
+// GlobalAccess is a read or an assignment of a global variable
+type GlobalAccess struct {
+	Position
+	Name  string
+	Write bool // Assigned by a = x or function a() ... end, rather than read
+	Call  bool // Read as the function of a call, a(args)
+}
+
+// Local is a local variable, declared by local or local function, as a parameter or as a for
+// variable
+type Local struct {
+	Decl     *Name
+	Uses     []*Name // Reads and assignments in its scope, in source order
+	Slot     int     // Number of locals in scope where it is declared, those of enclosing functions included
+	Implicit bool    // The self parameter of a method, which is not written in the source
+}
+
+// Globals returns the accesses to global variables in chunk, in source order. A name is global
+// where no local, parameter or for variable of that name is in scope. Accesses through _G with
+// a constant key, such as _G.a or _G["a"], count as accesses to a.
+func Globals(chunk *Chunk) []GlobalAccess {
+	r := &resolver{}
+	r.block(chunk.Block)
+	return r.accesses
+}
+
+// Locals returns the local variables of chunk, in the order they are declared
+func Locals(chunk *Chunk) []*Local {
+	r := &resolver{}
+	r.block(chunk.Block)
+	return r.locals
+}
+
+// resolver walks a chunk keeping track of the locals in scope
+type resolver struct {
+	scopes   []map[string]*Local
+	inScope  int // Number of locals in scope, shadowed ones included
+	counts   []int
+	accesses []GlobalAccess
+	locals   []*Local
+}
+
+func (r *resolver) open() {
+	r.scopes = append(r.scopes, make(map[string]*Local))
+	r.counts = append(r.counts, r.inScope)
+}
+
+func (r *resolver) close() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.inScope = r.counts[len(r.counts)-1]
+	r.counts = r.counts[:len(r.counts)-1]
+}
+
+func (r *resolver) declare(names ...*Name) {
+	for _, name := range names {
+		local := &Local{Decl: name, Slot: r.inScope}
+		r.scopes[len(r.scopes)-1][name.Name] = local
+		r.locals = append(r.locals, local)
+		r.inScope++
+	}
+}
+
+// lookup returns the local name refers to, nil for a global
+func (r *resolver) lookup(name string) *Local {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if local, ok := r.scopes[i][name]; ok {
+			return local
+		}
+	}
+	return nil
+}
+
+func (r *resolver) isLocal(name string) bool {
+	return r.lookup(name) != nil
+}
+
+// use records a use of the local name refers to, if any
+func (r *resolver) use(name *Name) {
+	if local := r.lookup(name.Name); local != nil {
+		local.Uses = append(local.Uses, name)
+	}
+}
+
+// global returns the name of the global e accesses, if any
+func (r *resolver) global(e Expr) (*Name, bool) {
+	switch e := e.(type) {
+	case *Name:
+		if !r.isLocal(e.Name) {
+			return e, true
+		}
+	case *IndexExpr:
+		if object, ok := e.Object.(*Name); ok && object.Name == "_G" && !r.isLocal("_G") {
+			if key, ok := e.Key.(*StringExpr); ok {
+				return &Name{Position: e.Position, Name: key.Value}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (r *resolver) block(b *Block) {
+	r.open()
+	r.stats(b)
+	r.close()
+}
+
+func (r *resolver) stats(b *Block) {
+	for _, stat := range b.Stats {
+		r.stat(stat)
+	}
+}
+
+func (r *resolver) stat(stat Stat) {
+	switch s := stat.(type) {
+	case *LocalStat:
+		r.exprs(s.Values)
+		r.declare(s.Names...)
+	case *AssignStat:
+		r.exprs(s.Values)
+		for _, target := range s.Targets {
+			if name, ok := r.global(target); ok {
+				r.accesses = append(r.accesses, GlobalAccess{Position: name.Position, Name: name.Name, Write: true})
+			} else if name, ok := target.(*Name); ok {
+				r.use(name)
+			} else if index, ok := target.(*IndexExpr); ok {
+				r.expr(index.Object)
+				r.expr(index.Key)
+			}
+		}
+	case *CallStat:
+		r.expr(s.Call)
+	case *DoStat:
+		r.block(s.Body)
+	case *WhileStat:
+		r.expr(s.Cond)
+		r.block(s.Body)
+	case *RepeatStat:
+		r.open()
+		r.stats(s.Body)
+		r.expr(s.Cond)
+		r.close()
+	case *IfStat:
+		for _, clause := range s.Clauses {
+			r.expr(clause.Cond)
+			r.block(clause.Body)
+		}
+		if s.Else != nil {
+			r.block(s.Else)
+		}
+	case *NumericForStat:
+		r.exprs([]Expr{s.Start, s.Limit, s.Step})
+		r.open()
+		r.declare(s.Var)
+		r.block(s.Body)
+		r.close()
+	case *GenericForStat:
+		r.exprs(s.Values)
+		r.open()
+		r.declare(s.Names...)
+		r.block(s.Body)
+		r.close()
+	case *FunctionStat:
+		if name, ok := s.Target.(*Name); ok && !r.isLocal(name.Name) {
+			r.accesses = append(r.accesses, GlobalAccess{Position: name.Position, Name: name.Name, Write: true})
+		} else {
+			// function a.b() reads a
+			r.expr(s.Target)
+		}
+		r.function(s.Func, s.Method)
+	case *LocalFunctionStat:
+		r.declare(s.Name)
+		r.function(s.Func, false)
+	case *ReturnStat:
+		r.exprs(s.Values)
+	}
+}
+
+func (r *resolver) function(f *FunctionExpr, method bool) {
+	r.open()
+	r.declare(f.Params...)
+	if method {
+		r.locals[len(r.locals)-len(f.Params)].Implicit = true
+	}
+	r.block(f.Body)
+	r.close()
+}
+
+func (r *resolver) exprs(exprs []Expr) {
+	for _, e := range exprs {
+		if e != nil {
+			r.expr(e)
+		}
+	}
+}
+
+func (r *resolver) expr(expr Expr) {
+	if name, ok := r.global(expr); ok {
+		r.accesses = append(r.accesses, GlobalAccess{Position: name.Position, Name: name.Name})
+		return
+	}
+	switch e := expr.(type) {
+	case *Name:
+		r.use(e)
+	case *FunctionExpr:
+		r.function(e, false)
+	case *TableExpr:
+		for _, field := range e.Fields {
+			if field.Kind == FieldKeyed {
+				r.expr(field.Key)
+			}
+			r.expr(field.Value)
+		}
+	case *BinaryExpr:
+		r.expr(e.Left)
+		r.expr(e.Right)
+	case *UnaryExpr:
+		r.expr(e.Operand)
+	case *ParenExpr:
+		r.expr(e.Inner)
+	case *IndexExpr:
+		r.expr(e.Object)
+		r.expr(e.Key)
+	case *CallExpr:
+		if name, ok := r.global(e.Func); ok {
+			r.accesses = append(r.accesses, GlobalAccess{Position: name.Position, Name: name.Name, Call: true})
+		} else {
+			r.expr(e.Func)
+		}
+		r.exprs(e.Args)
+	case *MethodCallExpr:
+		r.expr(e.Object)
+		r.exprs(e.Args)
+	}
+}
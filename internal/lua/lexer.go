@@ -0,0 +1,372 @@
+package lua
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TokenKind is the kind of a token
+type TokenKind int
+
+const (
+	TokenEOF     TokenKind = iota
+	TokenName              // Identifier
+	TokenKeyword           // Reserved word, such as local or end
+	TokenNumber            // Numeric literal
+	TokenString            // Quoted or long string literal
+	TokenSymbol            // Operator or punctuation, such as == or (
+)
+
+// keywords are the reserved words of Lua 5.1
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "if": true, "in": true, "local": true,
+	"nil": true, "not": true, "or": true, "repeat": true, "return": true, "then": true,
+	"true": true, "until": true, "while": true,
+}
+
+// IsKeyword reports whether name is a reserved word, which cannot be used as a variable name
+func IsKeyword(name string) bool {
+	return keywords[name]
+}
+
+// Position is a line and a byte column in a source, both starting at 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Pos returns the position, so nodes embedding it implement Node
+func (p Position) Pos() Position {
+	return p
+}
+
+// Token is a lexical element of a source
+type Token struct {
+	Kind  TokenKind
+	Text  string // Text as written in the source
+	Value string // Value of a string literal, with escape sequences resolved
+	Position
+	Comments []string // Comments between the previous token and this one, as written
+}
+
+// near returns the token as quoted by error messages
+func (t Token) near() string {
+	if t.Kind == TokenEOF {
+		return "'<eof>'"
+	}
+	return "'" + t.Text + "'"
+}
+
+// SyntaxError is the first syntax error of a source
+type SyntaxError struct {
+	Position
+	Message string // Message in the words of luac, such as 'end' expected near '<eof>'
+}
+
+// Error returns the error as line:column: message
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+var (
+	decimalNumber = regexp.MustCompile(`^([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+	hexNumber     = regexp.MustCompile(`^0[xX][0-9a-fA-F]+([pP][0-9]+)?$`)
+)
+
+// lexer splits a source into tokens the way the Lua 5.1 lexer does
+type lexer struct {
+	src    []byte
+	offset int
+	line   int
+	column int
+}
+
+// newLexer returns a lexer of src. A UTF-8 byte order mark is skipped, and so is a first line
+// starting with #, as luac does for files.
+func newLexer(src []byte) *lexer {
+	l := &lexer{src: src, line: 1, column: 1}
+	if strings.HasPrefix(string(src), "\xEF\xBB\xBF") {
+		l.offset = 3
+	}
+	if l.peek(0) == '#' {
+		for l.offset < len(l.src) && !isNewline(l.peek(0)) {
+			l.advance()
+		}
+	}
+	return l
+}
+
+// peek returns the byte n bytes ahead, 0 past the end of the source
+func (l *lexer) peek(n int) byte {
+	if l.offset+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.offset+n]
+}
+
+// advance moves past the current byte, counting \n, \r, \r\n and \n\r as a single line break
+func (l *lexer) advance() {
+	c := l.src[l.offset]
+	l.offset++
+	if !isNewline(c) {
+		l.column++
+		return
+	}
+	if next := l.peek(0); isNewline(next) && next != c {
+		l.offset++
+	}
+	l.line++
+	l.column = 1
+}
+
+// errorf returns a syntax error at pos
+func (l *lexer) errorf(pos Position, format string, args ...any) error {
+	return &SyntaxError{Position: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// next returns the next token, with the comments before it
+func (l *lexer) next() (Token, error) {
+	var comments []string
+	for {
+		start := l.offset
+		switch c := l.peek(0); {
+		case l.offset >= len(l.src):
+			return Token{Kind: TokenEOF, Position: l.pos(), Comments: comments}, nil
+		case isNewline(c) || c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			l.advance()
+		case c == '-' && l.peek(1) == '-':
+			pos := l.pos()
+			l.advance()
+			l.advance()
+			if l.peek(0) == '[' {
+				if level, ok := l.longBracket(); ok {
+					if _, err := l.longString(pos, level, "comment"); err != nil {
+						return Token{}, err
+					}
+					comments = append(comments, string(l.src[start:l.offset]))
+					continue
+				}
+			}
+			for l.offset < len(l.src) && !isNewline(l.peek(0)) {
+				l.advance()
+			}
+			comments = append(comments, string(l.src[start:l.offset]))
+		default:
+			token, err := l.token()
+			token.Comments = comments
+			return token, err
+		}
+	}
+}
+
+// pos returns the position of the current byte
+func (l *lexer) pos() Position {
+	return Position{Line: l.line, Column: l.column}
+}
+
+// token reads the token starting at the current byte, which is not a space or a comment
+func (l *lexer) token() (Token, error) {
+	pos, start := l.pos(), l.offset
+	symbol := func(n int) (Token, error) {
+		for i := 0; i < n; i++ {
+			l.advance()
+		}
+		return Token{Kind: TokenSymbol, Text: string(l.src[start:l.offset]), Position: pos}, nil
+	}
+
+	switch c := l.peek(0); {
+	case c == '[':
+		if l.peek(1) == '[' || l.peek(1) == '=' {
+			level, ok := l.longBracket()
+			if !ok {
+				l.offset, l.column = start, pos.Column
+				if level > 0 {
+					return Token{}, l.errorf(pos, "invalid long string delimiter near '%s'", "["+strings.Repeat("=", level))
+				}
+				return symbol(1)
+			}
+			value, err := l.longString(pos, level, "string")
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Kind: TokenString, Text: string(l.src[start:l.offset]), Value: value, Position: pos}, nil
+		}
+		return symbol(1)
+	case c == '=' || c == '<' || c == '>' || c == '~':
+		if l.peek(1) == '=' {
+			return symbol(2)
+		}
+		return symbol(1)
+	case c == '"' || c == '\'':
+		return l.quotedString(pos)
+	case c == '.':
+		if l.peek(1) == '.' {
+			if l.peek(2) == '.' {
+				return symbol(3)
+			}
+			return symbol(2)
+		}
+		if isDigit(l.peek(1)) {
+			return l.number(pos)
+		}
+		return symbol(1)
+	case isDigit(c):
+		return l.number(pos)
+	case isNameStart(c):
+		for isNameStart(l.peek(0)) || isDigit(l.peek(0)) {
+			l.advance()
+		}
+		text := string(l.src[start:l.offset])
+		if keywords[text] {
+			return Token{Kind: TokenKeyword, Text: text, Position: pos}, nil
+		}
+		return Token{Kind: TokenName, Text: text, Position: pos}, nil
+	}
+	// Any other byte is a symbol of its own, the parser rejects the ones Lua does not know
+	return symbol(1)
+}
+
+// longBracket reads the opening [[, [=[, [==[... of a long string or comment and returns its
+// level. It returns false, and the number of = read, when the [ does not open a long bracket.
+func (l *lexer) longBracket() (int, bool) {
+	l.advance()
+	level := 0
+	for l.peek(0) == '=' {
+		level++
+		l.advance()
+	}
+	if l.peek(0) != '[' {
+		return level, false
+	}
+	l.advance()
+	return level, true
+}
+
+// longString reads the content of a long string or comment up to its closing bracket, and
+// returns it without the line break directly after the opening bracket
+func (l *lexer) longString(pos Position, level int, what string) (string, error) {
+	if isNewline(l.peek(0)) {
+		l.advance()
+	}
+	start := l.offset
+	closing := "]" + strings.Repeat("=", level) + "]"
+	for {
+		if l.offset >= len(l.src) {
+			return "", l.errorf(l.pos(), "unfinished long %s near '<eof>'", what)
+		}
+		if strings.HasPrefix(string(l.src[l.offset:min(l.offset+len(closing), len(l.src))]), closing) {
+			value := string(l.src[start:l.offset])
+			for range closing {
+				l.advance()
+			}
+			return value, nil
+		}
+		// Lua 5.1 rejects [[ inside a [[...]] string or comment
+		if level == 0 && l.peek(0) == '[' && l.peek(1) == '[' {
+			return "", l.errorf(l.pos(), "nesting of [[...]] is deprecated near '['")
+		}
+		l.advance()
+	}
+}
+
+// quotedString reads a string in single or double quotes
+func (l *lexer) quotedString(pos Position) (Token, error) {
+	start := l.offset
+	quote := l.peek(0)
+	l.advance()
+	var value strings.Builder
+	for {
+		c := l.peek(0)
+		switch {
+		case l.offset >= len(l.src):
+			return Token{}, l.errorf(l.pos(), "unfinished string near '<eof>'")
+		case isNewline(c):
+			return Token{}, l.errorf(l.pos(), "unfinished string near '%s'", l.src[start:l.offset])
+		case c == quote:
+			l.advance()
+			return Token{Kind: TokenString, Text: string(l.src[start:l.offset]), Value: value.String(), Position: pos}, nil
+		case c == '\\':
+			l.advance()
+			escape := l.peek(0)
+			if l.offset >= len(l.src) {
+				continue
+			}
+			if i := strings.IndexByte("abfnrtv", escape); i >= 0 {
+				value.WriteByte("\a\b\f\n\r\t\v"[i])
+				l.advance()
+				continue
+			}
+			if isNewline(escape) {
+				value.WriteByte('\n')
+				l.advance()
+				continue
+			}
+			if !isDigit(escape) {
+				// \\, \", \' and any other byte stand for themselves
+				value.WriteByte(escape)
+				l.advance()
+				continue
+			}
+			code := 0
+			for i := 0; i < 3 && isDigit(l.peek(0)); i++ {
+				code = code*10 + int(l.peek(0)-'0')
+				l.advance()
+			}
+			if code > 255 {
+				return Token{}, l.errorf(l.pos(), "escape sequence too large near '%s'", l.src[start:l.offset])
+			}
+			value.WriteByte(byte(code))
+		default:
+			value.WriteByte(c)
+			l.advance()
+		}
+	}
+}
+
+// number reads a numeric literal. Like Lua 5.1, it takes every letter, digit and dot that
+// follows, so 3x is a malformed number rather than a number followed by a name.
+func (l *lexer) number(pos Position) (Token, error) {
+	start := l.offset
+	for isDigit(l.peek(0)) || l.peek(0) == '.' {
+		l.advance()
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		l.advance()
+		if c := l.peek(0); c == '+' || c == '-' {
+			l.advance()
+		}
+	}
+	for isNameStart(l.peek(0)) || isDigit(l.peek(0)) {
+		l.advance()
+	}
+	text := string(l.src[start:l.offset])
+	if !decimalNumber.MatchString(text) && !hexNumber.MatchString(text) {
+		return Token{}, l.errorf(pos, "malformed number near '%s'", text)
+	}
+	return Token{Kind: TokenNumber, Text: text, Position: pos}, nil
+}
+
+// NumberValue returns the value of a numeric literal as Lua 5.1 reads it
+func NumberValue(text string) (float64, bool) {
+	if hexNumber.MatchString(text) && !strings.ContainsAny(text, "pP") {
+		value, err := strconv.ParseUint(text[2:], 16, 64)
+		return float64(value), err == nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	return value, err == nil
+}
+
+func isNewline(c byte) bool {
+	return c == '\n' || c == '\r'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
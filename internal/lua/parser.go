@@ -0,0 +1,499 @@
+package lua
+
+import "fmt"
+
+// binaryPriority are the left and right priorities of the binary operators of Lua 5.1. A right
+// priority lower than the left one makes the operator right associative.
+var binaryPriority = map[string][2]int{
+	"or": {1, 1}, "and": {2, 2},
+	"<": {3, 3}, ">": {3, 3}, "<=": {3, 3}, ">=": {3, 3}, "~=": {3, 3}, "==": {3, 3},
+	"..": {5, 4}, "+": {6, 6}, "-": {6, 6}, "*": {7, 7}, "/": {7, 7}, "%": {7, 7}, "^": {10, 9},
+}
+
+// unaryPriority is the priority of not, - and #, above every binary operator but ^
+const unaryPriority = 8
+
+// Parse parses a Lua 5.1 source. The first syntax error is returned as a *SyntaxError, worded
+// like the errors of luac.
+func Parse(src []byte) (chunk *Chunk, err error) {
+	p := &parser{lex: newLexer(src), fn: &funcState{vararg: true}}
+	defer func() {
+		// Errors are raised as panics deep in the recursion and end the parse here
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			chunk, err = nil, syntaxErr
+		}
+	}()
+
+	p.next()
+	block := p.block()
+	if p.tok.Kind != TokenEOF {
+		p.expected("<eof>")
+	}
+	return &Chunk{Block: block}, nil
+}
+
+// funcState is the function being parsed
+type funcState struct {
+	vararg bool // Declares ..., as the main chunk does
+	loops  int  // Loops around the current statement, break needs one
+}
+
+// parser is a recursive descent parser following the grammar and error messages of lparser.c
+type parser struct {
+	lex      *lexer
+	tok      Token // Current token
+	ahead    *Token
+	lastLine int // Line of the previous token
+	fn       *funcState
+}
+
+// next moves to the next token
+func (p *parser) next() {
+	p.lastLine = p.tok.Line
+	if p.ahead != nil {
+		p.tok, p.ahead = *p.ahead, nil
+		return
+	}
+	tok, err := p.lex.next()
+	if err != nil {
+		panic(err)
+	}
+	p.tok = tok
+}
+
+// peek returns the token after the current one
+func (p *parser) peek() Token {
+	if p.ahead == nil {
+		tok, err := p.lex.next()
+		if err != nil {
+			panic(err)
+		}
+		p.ahead = &tok
+	}
+	return *p.ahead
+}
+
+// is reports whether the current token is the keyword or symbol text
+func (p *parser) is(text string) bool {
+	return (p.tok.Kind == TokenKeyword || p.tok.Kind == TokenSymbol) && p.tok.Text == text
+}
+
+// accept moves past the current token if it is the keyword or symbol text
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// errorf raises a syntax error near the current token
+func (p *parser) errorf(format string, args ...any) {
+	panic(&SyntaxError{Position: p.tok.Position, Message: fmt.Sprintf(format, args...) + " near " + p.tok.near()})
+}
+
+// expected raises an error for a missing keyword or symbol
+func (p *parser) expected(text string) {
+	p.errorf("'%s' expected", text)
+}
+
+// check moves past the keyword or symbol text, which must be the current token
+func (p *parser) check(text string) {
+	if !p.accept(text) {
+		p.expected(text)
+	}
+}
+
+// checkMatch moves past the keyword or symbol closing what opened on line
+func (p *parser) checkMatch(what, opener string, line int) {
+	if p.accept(what) {
+		return
+	}
+	if line == p.tok.Line {
+		p.expected(what)
+	}
+	p.errorf("'%s' expected (to close '%s' at line %d)", what, opener, line)
+}
+
+// name reads a name
+func (p *parser) name() *Name {
+	if p.tok.Kind != TokenName {
+		p.errorf("<name> expected")
+	}
+	name := &Name{Position: p.tok.Position, Name: p.tok.Text}
+	p.next()
+	return name
+}
+
+// blockFollow reports whether the current token ends a block
+func (p *parser) blockFollow() bool {
+	if p.tok.Kind == TokenEOF {
+		return true
+	}
+	return p.tok.Kind == TokenKeyword &&
+		(p.tok.Text == "else" || p.tok.Text == "elseif" || p.tok.Text == "end" || p.tok.Text == "until")
+}
+
+// block reads statements up to the end of the block. return and break end it early, whatever
+// follows them is left to the caller, which expects the end of the block.
+func (p *parser) block() *Block {
+	block := &Block{Position: p.tok.Position}
+	for !p.blockFollow() {
+		last := p.is("return") || p.is("break")
+		block.Stats = append(block.Stats, p.statement())
+		p.accept(";")
+		if last {
+			break
+		}
+	}
+	return block
+}
+
+// statement reads a statement
+func (p *parser) statement() Stat {
+	pos, line := p.tok.Position, p.tok.Line
+	switch {
+	case p.accept("if"):
+		stat := &IfStat{Position: pos}
+		stat.Clauses = append(stat.Clauses, p.ifClause(pos))
+		for clause := p.tok.Position; p.accept("elseif"); clause = p.tok.Position {
+			stat.Clauses = append(stat.Clauses, p.ifClause(clause))
+		}
+		if elsePos := p.tok.Position; p.accept("else") {
+			stat.ElsePos = elsePos
+			stat.Else = p.block()
+		}
+		stat.End = p.tok.Position
+		p.checkMatch("end", "if", line)
+		return stat
+	case p.accept("while"):
+		cond := p.expr()
+		p.check("do")
+		body := p.loopBlock()
+		p.checkMatch("end", "while", line)
+		return &WhileStat{Position: pos, Cond: cond, Body: body}
+	case p.accept("do"):
+		body := p.block()
+		p.checkMatch("end", "do", line)
+		return &DoStat{Position: pos, Body: body}
+	case p.accept("for"):
+		return p.forStat(pos, line)
+	case p.accept("repeat"):
+		body := p.loopBlock()
+		p.checkMatch("until", "repeat", line)
+		return &RepeatStat{Position: pos, Body: body, Cond: p.expr()}
+	case p.accept("function"):
+		var target Expr = p.name()
+		for p.is(".") || p.is(":") {
+			method := p.is(":")
+			p.next()
+			key := p.name()
+			target = &IndexExpr{Position: pos, Object: target, Key: &StringExpr{Position: key.Position, Value: key.Name}, Dot: true}
+			if method {
+				return &FunctionStat{Position: pos, Target: target, Method: true, Func: p.body(pos, line, true)}
+			}
+		}
+		return &FunctionStat{Position: pos, Target: target, Func: p.body(pos, line, false)}
+	case p.accept("local"):
+		if p.accept("function") {
+			name := p.name()
+			return &LocalFunctionStat{Position: pos, Name: name, Func: p.body(pos, line, false)}
+		}
+		stat := &LocalStat{Position: pos, Names: []*Name{p.name()}}
+		for p.accept(",") {
+			stat.Names = append(stat.Names, p.name())
+		}
+		if p.accept("=") {
+			stat.Values = p.exprList()
+		}
+		return stat
+	case p.accept("return"):
+		stat := &ReturnStat{Position: pos}
+		if !p.blockFollow() && !p.is(";") {
+			stat.Values = p.exprList()
+		}
+		return stat
+	case p.accept("break"):
+		if p.fn.loops == 0 {
+			p.errorf("no loop to break")
+		}
+		return &BreakStat{Position: pos}
+	}
+	return p.exprStat()
+}
+
+// ifClause reads the condition and block of the if or elseif clause starting at pos
+func (p *parser) ifClause(pos Position) *IfClause {
+	cond := p.expr()
+	p.check("then")
+	return &IfClause{Position: pos, Cond: cond, Body: p.block()}
+}
+
+// loopBlock reads the body of a loop, where break is allowed
+func (p *parser) loopBlock() *Block {
+	p.fn.loops++
+	defer func() { p.fn.loops-- }()
+	return p.block()
+}
+
+// forStat reads a numeric or generic for loop after the for keyword
+func (p *parser) forStat(pos Position, line int) Stat {
+	first := p.name()
+	var stat Stat
+	var body **Block
+	switch {
+	case p.accept("="):
+		numeric := &NumericForStat{Position: pos, Var: first, Start: p.expr()}
+		p.check(",")
+		numeric.Limit = p.expr()
+		if p.accept(",") {
+			numeric.Step = p.expr()
+		}
+		stat, body = numeric, &numeric.Body
+	case p.is(",") || p.is("in"):
+		generic := &GenericForStat{Position: pos, Names: []*Name{first}}
+		for p.accept(",") {
+			generic.Names = append(generic.Names, p.name())
+		}
+		p.check("in")
+		generic.Values = p.exprList()
+		stat, body = generic, &generic.Body
+	default:
+		p.errorf("'=' or 'in' expected")
+	}
+	p.check("do")
+	*body = p.loopBlock()
+	p.checkMatch("end", "for", line)
+	return stat
+}
+
+// exprStat reads an assignment or a call statement
+func (p *parser) exprStat() Stat {
+	pos := p.tok.Position
+	first := p.suffixedExpr()
+	if !p.is("=") && !p.is(",") {
+		switch first.(type) {
+		case *CallExpr, *MethodCallExpr:
+			return &CallStat{Position: pos, Call: first}
+		}
+		p.errorf("syntax error")
+	}
+
+	stat := &AssignStat{Position: pos, Targets: []Expr{first}}
+	for {
+		switch stat.Targets[len(stat.Targets)-1].(type) {
+		case *Name, *IndexExpr:
+		default:
+			p.errorf("syntax error")
+		}
+		if !p.accept(",") {
+			break
+		}
+		stat.Targets = append(stat.Targets, p.suffixedExpr())
+	}
+	p.check("=")
+	stat.Values = p.exprList()
+	return stat
+}
+
+// exprList reads one or more expressions separated by commas
+func (p *parser) exprList() []Expr {
+	list := []Expr{p.expr()}
+	for p.accept(",") {
+		list = append(list, p.expr())
+	}
+	return list
+}
+
+// expr reads an expression
+func (p *parser) expr() Expr {
+	return p.subExpr(0)
+}
+
+// subExpr reads an expression whose binary operators bind tighter than limit
+func (p *parser) subExpr(limit int) Expr {
+	pos := p.tok.Position
+	var left Expr
+	if p.is("not") || p.is("-") || p.is("#") {
+		op := p.tok.Text
+		p.next()
+		left = &UnaryExpr{Position: pos, Op: op, Operand: p.subExpr(unaryPriority)}
+	} else {
+		left = p.simpleExpr()
+	}
+
+	for p.tok.Kind == TokenSymbol || p.tok.Kind == TokenKeyword {
+		op := p.tok.Text
+		priority, ok := binaryPriority[op]
+		if !ok || priority[0] <= limit {
+			break
+		}
+		p.next()
+		left = &BinaryExpr{Position: pos, Op: op, Left: left, Right: p.subExpr(priority[1])}
+	}
+	return left
+}
+
+// simpleExpr reads a literal, a function, a table constructor or a suffixed expression
+func (p *parser) simpleExpr() Expr {
+	tok := p.tok
+	switch {
+	case tok.Kind == TokenNumber:
+		p.next()
+		value, _ := NumberValue(tok.Text)
+		return &NumberExpr{Position: tok.Position, Text: tok.Text, Value: value}
+	case tok.Kind == TokenString:
+		p.next()
+		return &StringExpr{Position: tok.Position, Text: tok.Text, Value: tok.Value}
+	case p.accept("nil"):
+		return &NilExpr{Position: tok.Position}
+	case p.accept("true"):
+		return &TrueExpr{Position: tok.Position}
+	case p.accept("false"):
+		return &FalseExpr{Position: tok.Position}
+	case p.is("..."):
+		if !p.fn.vararg {
+			p.errorf("cannot use '...' outside a vararg function")
+		}
+		p.next()
+		return &VarargExpr{Position: tok.Position}
+	case p.is("{"):
+		return p.table()
+	case p.accept("function"):
+		return p.body(tok.Position, tok.Line, false)
+	}
+	return p.suffixedExpr()
+}
+
+// primaryExpr reads a name or an expression in parentheses
+func (p *parser) primaryExpr() Expr {
+	pos, line := p.tok.Position, p.tok.Line
+	if p.tok.Kind == TokenName {
+		return p.name()
+	}
+	if p.accept("(") {
+		inner := p.expr()
+		p.checkMatch(")", "(", line)
+		return &ParenExpr{Position: pos, Inner: inner}
+	}
+	p.errorf("unexpected symbol")
+	return nil
+}
+
+// suffixedExpr reads a primary expression followed by field accesses, indexes and calls
+func (p *parser) suffixedExpr() Expr {
+	pos := p.tok.Position
+	expr := p.primaryExpr()
+	for {
+		switch {
+		case p.accept("."):
+			key := p.name()
+			expr = &IndexExpr{Position: pos, Object: expr, Key: &StringExpr{Position: key.Position, Value: key.Name}, Dot: true}
+		case p.is("["):
+			line := p.tok.Line
+			p.next()
+			key := p.expr()
+			p.checkMatch("]", "[", line)
+			expr = &IndexExpr{Position: pos, Object: expr, Key: key}
+		case p.accept(":"):
+			method := p.name()
+			expr = &MethodCallExpr{Position: pos, Object: expr, Method: method.Name, Args: p.callArgs()}
+		case p.is("(") || p.is("{") || p.tok.Kind == TokenString:
+			expr = &CallExpr{Position: pos, Func: expr, Args: p.callArgs()}
+		default:
+			return expr
+		}
+	}
+}
+
+// callArgs reads the arguments of a call: a list in parentheses, a table or a string
+func (p *parser) callArgs() []Expr {
+	tok := p.tok
+	switch {
+	case tok.Kind == TokenString:
+		p.next()
+		return []Expr{&StringExpr{Position: tok.Position, Text: tok.Text, Value: tok.Value}}
+	case p.is("{"):
+		return []Expr{p.table()}
+	case p.is("("):
+		// Lua 5.1 cannot tell f\n(g)() from two statements
+		if tok.Line != p.lastLine {
+			p.errorf("ambiguous syntax (function call x new statement)")
+		}
+		p.next()
+		var args []Expr
+		if !p.is(")") {
+			args = p.exprList()
+		}
+		p.checkMatch(")", "(", tok.Line)
+		return args
+	}
+	p.errorf("function arguments expected")
+	return nil
+}
+
+// table reads a table constructor
+func (p *parser) table() *TableExpr {
+	pos, line := p.tok.Position, p.tok.Line
+	p.check("{")
+	table := &TableExpr{Position: pos}
+	for !p.is("}") {
+		fieldPos := p.tok.Position
+		switch {
+		case p.tok.Kind == TokenName && p.peek().Kind == TokenSymbol && p.peek().Text == "=":
+			key := p.name()
+			p.next()
+			table.Fields = append(table.Fields, &Field{Position: fieldPos, Kind: FieldNamed,
+				Key: &StringExpr{Position: key.Position, Value: key.Name}, Value: p.expr()})
+		case p.accept("["):
+			key := p.expr()
+			p.check("]")
+			p.check("=")
+			table.Fields = append(table.Fields, &Field{Position: fieldPos, Kind: FieldKeyed, Key: key, Value: p.expr()})
+		default:
+			table.Fields = append(table.Fields, &Field{Position: fieldPos, Kind: FieldPositional, Value: p.expr()})
+		}
+		if !p.accept(",") && !p.accept(";") {
+			break
+		}
+	}
+	p.checkMatch("}", "{", line)
+	return table
+}
+
+// body reads the parameters and body of a function opened on line, with an implicit self
+// parameter for methods
+func (p *parser) body(pos Position, line int, method bool) *FunctionExpr {
+	fn := &FunctionExpr{Position: pos}
+	if method {
+		fn.Params = append(fn.Params, &Name{Position: pos, Name: "self"})
+	}
+	p.check("(")
+	if !p.is(")") {
+		for {
+			if p.tok.Kind == TokenName {
+				fn.Params = append(fn.Params, p.name())
+			} else if p.accept("...") {
+				fn.Vararg = true
+			} else {
+				p.errorf("<name> or '...' expected")
+			}
+			if fn.Vararg || !p.accept(",") {
+				break
+			}
+		}
+	}
+	p.check(")")
+
+	outer := p.fn
+	p.fn = &funcState{vararg: fn.Vararg}
+	fn.Body = p.block()
+	p.fn = outer
+	fn.End = p.tok.Position
+	p.checkMatch("end", "function", line)
+	return fn
+}
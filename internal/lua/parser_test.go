@@ -0,0 +1,159 @@
+package lua
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"",
+		"#!/usr/bin/lua\nprint(1)",
+		"\xEF\xBB\xBFprint('bom')",
+		"local a, b = 1, 2.5e3 local c = 0x1F + .5 - 3.",
+		"local t = { x = 1, [2] = 'y', 'z'; f = function(...) return ... end, }",
+		"function t.a.b:m(x) return self end",
+		"local function f(a, b, ...) local c = {...} return #c end",
+		"for i = 1, 10, 2 do if i > 5 then break end end",
+		"for k, v in pairs(t) do print(k, v) end",
+		"while a < 10 do a = a + 1 end repeat local z = 1 until z == 1",
+		"if a then elseif b then else end",
+		"do local s = [==[\nlong ]] string]==] end",
+		"--[[ comment\n]] print 'hi' print { 1 } t:m \"x\" f[[long]]",
+		"a = -2 ^ 2 .. 'x' .. 'y' local x = not a == b and a or b",
+		"x = t.a.b['c']:d(1)(2) a, t[1], t.b = 1, 2",
+		"local s = 'esc \\' \\\" \\n \\065 \\\n'",
+		"return",
+		"return 1;",
+		"f()\n;(g)()",
+	}
+	for _, src := range valid {
+		if _, err := Parse([]byte(src)); err != nil {
+			t.Errorf("Parse(%q) failed: %v", src, err)
+		}
+	}
+
+	invalid := []struct {
+		src      string
+		expected string
+	}{
+		{"local x = 1\nif x then\nprint(x)\n", "4:1: 'end' expected (to close 'if' at line 2) near '<eof>'"},
+		{"if x then print(x)", "1:19: 'end' expected near '<eof>'"},
+		{"local x = 3x", "1:11: malformed number near '3x'"},
+		{"local x = 1..2", "1:11: malformed number near '1..2'"},
+		{"local s = \"abc\n", "1:15: unfinished string near '\"abc'"},
+		{"local s = 'abc", "1:15: unfinished string near '<eof>'"},
+		{"local s = '\\300'", "1:16: escape sequence too large near ''\\300'"},
+		{"x = [[ a", "1:9: unfinished long string near '<eof>'"},
+		{"--[==[ a", "1:9: unfinished long comment near '<eof>'"},
+		{"x = [[ a [[ b ]]", "1:10: nesting of [[...]] is deprecated near '['"},
+		{"x = [=x", "1:5: invalid long string delimiter near '[='"},
+		{"f()\n(g)()", "2:1: ambiguous syntax (function call x new statement) near '('"},
+		{"break", "1:6: no loop to break near '<eof>'"},
+		{"function f() return ... end", "1:21: cannot use '...' outside a vararg function near '...'"},
+		{"x\ny = 1", "2:1: syntax error near 'y'"},
+		{"a.b:c() = 1", "1:9: syntax error near '='"},
+		{"local t = {1, 2\nprint(t)", "2:1: '}' expected (to close '{' at line 1) near 'print'"},
+		{"return 1\nprint(2)", "2:1: '<eof>' expected near 'print'"},
+		{"x = 1 & 2", "1:7: unexpected symbol near '&'"},
+		{"x = a ~ b", "1:7: unexpected symbol near '~'"},
+		{"local 1 = 2", "1:7: <name> expected near '1'"},
+		{"for i do end", "1:7: '=' or 'in' expected near 'do'"},
+		{"function f(a, 1) end", "1:15: <name> or '...' expected near '1'"},
+		{"function a:b.c() end", "1:13: '(' expected near '.'"},
+		{"x = f.", "1:7: <name> expected near '<eof>'"},
+		{"x = o:m", "1:8: function arguments expected near '<eof>'"},
+		{"goto = 1 local end = 2", "1:16: <name> expected near 'end'"},
+	}
+	for _, tt := range invalid {
+		_, err := Parse([]byte(tt.src))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Parse(%q): expected error %q, got %v", tt.src, tt.expected, err)
+		}
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	chunk, err := Parse([]byte("local a = 1 + 2 * 3 ^ 2 ^ 1\nfunction t:m() end"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Block.Stats) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(chunk.Block.Stats))
+	}
+
+	// 1 + (2 * (3 ^ (2 ^ 1)))
+	local := chunk.Block.Stats[0].(*LocalStat)
+	sum := local.Values[0].(*BinaryExpr)
+	product := sum.Right.(*BinaryExpr)
+	power := product.Right.(*BinaryExpr)
+	if sum.Op != "+" || product.Op != "*" || power.Op != "^" || power.Right.(*BinaryExpr).Op != "^" {
+		t.Errorf("Unexpected operator tree for %q", "1 + 2 * 3 ^ 2 ^ 1")
+	}
+
+	method := chunk.Block.Stats[1].(*FunctionStat)
+	if !method.Method || method.Pos() != (Position{Line: 2, Column: 1}) {
+		t.Errorf("Expected a method at 2:1, got %+v", method)
+	}
+	var params []string
+	for _, param := range method.Func.Params {
+		params = append(params, param.Name)
+	}
+	if !reflect.DeepEqual(params, []string{"self"}) {
+		t.Errorf("Expected the parameters [self], got %v", params)
+	}
+}
+
+func TestStringValue(t *testing.T) {
+	tests := map[string]string{
+		`'a\tb'`:         "a\tb",
+		`"\65\066\0677"`: "ABC7",
+		`'\q\\\''`:       `q\'`,
+		"[==[\nx]]y]==]": "x]]y",
+		"'a\\\nb'":       "a\nb",
+	}
+	for src, expected := range tests {
+		tok, err := newLexer([]byte(src)).next()
+		if err != nil {
+			t.Errorf("Lexing %q failed: %v", src, err)
+			continue
+		}
+		if tok.Kind != TokenString || tok.Value != expected {
+			t.Errorf("Lexing %q: expected the string %q, got %q", src, expected, tok.Value)
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	src := `local a = b
+function f(x) return x + y end
+function t.m() end
+c, t.k = 1, z
+_G.d = _G["e"]
+for i = 1, n do local w = i end
+repeat local r = 1 until r
+local function g() return g, w end
+print(t)`
+	chunk, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, access := range Globals(chunk) {
+		kind := "read"
+		if access.Write {
+			kind = "write"
+		} else if access.Call {
+			kind = "call"
+		}
+		got = append(got, fmt.Sprintf("%d %s %s", access.Line, kind, access.Name))
+	}
+	want := []string{
+		"1 read b", "2 write f", "2 read y", "3 read t", "4 read z", "4 write c", "4 read t",
+		"5 read e", "5 write d", "6 read n", "8 read w", "9 call print", "9 read t",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Globals() =\n%v\nwant\n%v", got, want)
+	}
+}
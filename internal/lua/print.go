@@ -0,0 +1,148 @@
+package lua
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Tokens splits src into tokens, without the end of file. Comments are kept on the tokens
+// following them.
+func Tokens(src []byte) ([]Token, error) {
+	l := newLexer(src)
+	var tokens []Token
+	for {
+		token, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if token.Kind == TokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// Print writes tokens back as source, without their comments. Each token goes on the line it
+// was read from, so error messages and source maps keep their line numbers, and tokens on a
+// line are separated by a space only where they would otherwise read as one. Tokens with a
+// line of 0 go on the line of the token before them.
+func Print(tokens []Token) []byte {
+	var out bytes.Buffer
+	line, prev := 1, ""
+	for _, token := range tokens {
+		if token.Line > line {
+			for ; line < token.Line; line++ {
+				out.WriteByte('\n')
+			}
+			prev = ""
+		}
+		if prev != "" && Merges(prev, token.Text) {
+			out.WriteByte(' ')
+		}
+		out.WriteString(token.Text)
+		line += lineBreaks(token.Text)
+		prev = token.Text
+	}
+	if out.Len() > 0 {
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// Merges reports whether the tokens a and b written next to each other do not read back as
+// the same two tokens, such as a name followed by a keyword or - followed by -
+func Merges(a, b string) bool {
+	l := newLexer([]byte(a + b))
+	first, err := l.next()
+	if err != nil || first.Text != a || len(first.Comments) > 0 {
+		return true
+	}
+	second, err := l.next()
+	return err != nil || second.Text != b || len(second.Comments) > 0
+}
+
+// lineBreaks counts the line breaks in text the way the lexer does, with \r\n and \n\r
+// counting as one
+func lineBreaks(text string) int {
+	count := 0
+	for i := 0; i < len(text); i++ {
+		if !isNewline(text[i]) {
+			continue
+		}
+		if i+1 < len(text) && isNewline(text[i+1]) && text[i+1] != text[i] {
+			i++
+		}
+		count++
+	}
+	return count
+}
+
+// Edit replaces the text of a source between two positions
+type Edit struct {
+	From Position // First byte replaced
+	To   Position // Byte after the last one replaced
+	Text string
+}
+
+// End returns the position just after the token
+func (t Token) End() Position {
+	end := t.Position
+	for i := 0; i < len(t.Text); i++ {
+		if !isNewline(t.Text[i]) {
+			end.Column++
+			continue
+		}
+		if i+1 < len(t.Text) && isNewline(t.Text[i+1]) && t.Text[i+1] != t.Text[i] {
+			i++
+		}
+		end.Line++
+		end.Column = 1
+	}
+	return end
+}
+
+// Apply returns src with edits applied, leaving everything else, comments and spacing included,
+// as it is. Edits may come in any order but must not overlap.
+func Apply(src []byte, edits []Edit) []byte {
+	starts := lineStarts(src)
+	offset := func(pos Position) int {
+		if pos.Line < 1 || pos.Line > len(starts) {
+			return len(src)
+		}
+		return min(starts[pos.Line-1]+pos.Column-1, len(src))
+	}
+	sorted := slices.Clone(edits)
+	slices.SortFunc(sorted, func(a, b Edit) int {
+		return offset(a.From) - offset(b.From)
+	})
+
+	var out bytes.Buffer
+	done := 0
+	for _, edit := range sorted {
+		out.Write(src[done:offset(edit.From)])
+		out.WriteString(edit.Text)
+		done = offset(edit.To)
+	}
+	out.Write(src[done:])
+	return out.Bytes()
+}
+
+// lineStarts returns the offset in src at which each line starts, the way the lexer counts lines
+// and columns
+func lineStarts(src []byte) []int {
+	start := 0
+	if bytes.HasPrefix(src, []byte("\xEF\xBB\xBF")) {
+		start = 3
+	}
+	starts := []int{start}
+	for i := start; i < len(src); i++ {
+		if !isNewline(src[i]) {
+			continue
+		}
+		if i+1 < len(src) && isNewline(src[i+1]) && src[i+1] != src[i] {
+			i++
+		}
+		starts = append(starts, i+1)
+	}
+	return starts
+}
@@ -0,0 +1,409 @@
+package mtaapi
+
+// luaGlobals are the globals of the Lua 5.1 standard library, including the ones MTA disables,
+// which are defined but fail when called
+const luaGlobals = `
+_G _VERSION assert collectgarbage dofile error gcinfo getfenv getmetatable ipairs load loadfile
+loadstring module newproxy next pairs pcall print rawequal rawget rawset require select setfenv
+setmetatable tonumber tostring type unpack xpcall
+coroutine debug io math os package string table utf8
+`
+
+// sharedVariables are the globals MTA predefines in client and server scripts. Event variables
+// are only set while an event handler runs.
+const sharedVariables = `
+root resourceRoot resource exports source this eventName sourceResource sourceResourceRoot sourceTimer
+`
+
+// clientVariables are the globals MTA predefines in client scripts
+const clientVariables = `
+localPlayer guiRoot
+`
+
+// serverVariables are the globals MTA predefines in server scripts
+const serverVariables = `
+client
+`
+
+// oopClasses are the classes of MTA's object oriented API
+const oopClasses = `
+Vector2 Vector3 Vector4 Matrix Element Player Ped Vehicle Object Pickup Marker Blip ColShape Team
+Water RadarArea Timer Resource Account ACL ACLGroup Ban Connection QueryHandle File XML Sound
+Sound3D Browser Camera Effect Light SearchLight Projectile Weapon Engine EngineCOL EngineDFF
+EngineTXD EngineIFP EngineIMG DxMaterial DxTexture DxShader DxFont DxRenderTarget DxScreenSource
+GuiElement GuiWindow GuiButton GuiEdit GuiLabel GuiMemo GuiGridList GuiCheckBox GuiRadioButton
+GuiScrollBar GuiScrollPane GuiStaticImage GuiTabPanel GuiTab GuiProgressBar GuiComboBox
+GuiBrowser GuiFont TextDisplay TextItem
+`
+
+// sharedFunctions are the functions of the MTA scripting API defined on both sides
+const sharedFunctions = `
+addDebugHook removeDebugHook debugSleep base64Decode base64Encode bitAnd bitArShift bitExtract
+bitLRotate bitLShift bitNot bitOr bitReplace bitRRotate bitRShift bitTest bitXor decodeString
+encodeString fromJSON toJSON getColorFromString getDistanceBetweenPoints2D
+getDistanceBetweenPoints3D getEasingValue getNetworkStats getNetworkUsageData getPerformanceStats
+getProcessMemoryStats getRealTime getTickCount getTimerDetails getTimers gettok getUserdataType
+hash inspect interpolateBetween iprint isOOPEnabled isTimer isTimerPaused killTimer md5
+passwordHash passwordVerify pregFind pregMatch pregReplace ref deref resetTimer setTimer
+setTimerPaused sha256 split teaDecode teaEncode tocolor utfChar utfCode utfLen utfSeek utfSub
+getVersion generateKeyPair encryptString decryptString getFPSLimit setFPSLimit isVoiceEnabled
+getDevelopmentMode setDevelopmentMode getValidPedModels
+
+outputChatBox outputConsole outputDebugString clearChatBox showChat isChatVisible
+
+addEvent addEventHandler removeEventHandler triggerEvent cancelEvent wasEventCancelled
+getEventHandlers cancelLatentEvent getLatentEventHandles getLatentEventStatus
+
+addCommandHandler removeCommandHandler executeCommandHandler getCommandHandlers bindKey unbindKey
+getKeyBoundToFunction getFunctionsBoundToKey getControlState setControlState toggleControl
+toggleAllControls isControlEnabled isKeyBound getKeyState
+
+fileCreate fileOpen fileClose fileRead fileWrite fileFlush fileExists fileDelete fileRename
+fileCopy fileGetPos fileSetPos fileGetSize fileIsEOF fileGetPath fileGetContents
+pathIsDirectory pathIsFile pathListDir
+
+xmlCreateFile xmlLoadFile xmlLoadString xmlCopyFile xmlSaveFile xmlUnloadFile xmlCreateChild
+xmlDestroyNode xmlFindChild xmlNodeGetAttribute xmlNodeGetAttributes xmlNodeGetChildren
+xmlNodeGetName xmlNodeGetParent xmlNodeGetValue xmlNodeSetAttribute xmlNodeSetName
+xmlNodeSetValue
+
+attachElements detachElements createElement destroyElement getAttachedElements
+getAttachedOffsets getElementAlpha getElementAttachedTo getElementByID getElementByIndex
+getElementChild getElementChildren getElementChildrenCount getElementCollisionsEnabled
+getElementColShape getElementData getAllElementData hasElementData getElementDimension
+getElementHealth getElementID getElementInterior getElementMatrix getElementModel
+getElementParent getElementPosition getElementRotation getElementsByType
+getElementsWithinColShape getElementsWithinRange getElementType getElementVelocity
+getElementTurnVelocity getElementAngularVelocity getElementZoneName getLowLODElement
+getRootElement isElement isElementAttached isElementCallPropagationEnabled
+isElementDoubleSided isElementFrozen isElementInWater isElementLowLOD isElementWithinColShape
+isElementWithinMarker setElementAlpha setElementAngularVelocity setElementAttachedOffsets
+setElementCallPropagationEnabled setElementCollisionsEnabled setElementData
+setElementDimension setElementDoubleSided setElementFrozen setElementHealth setElementID
+setElementInterior setElementModel setElementParent setElementPosition setElementRotation
+setElementVelocity setElementTurnVelocity setLowLODElement setElementMatrix removeElementData
+
+getResourceFromName getResourceName getResourceRootElement getResourceDynamicElementRoot
+getResourceConfig getResourceState getThisResource getResourceExportedFunctions call
+fetchRemote getRemoteRequests getRemoteRequestInfo abortRemoteRequest getResources
+getResourceInfo
+
+createBlip createBlipAttachedTo getBlipColor getBlipIcon getBlipOrdering getBlipSize
+getBlipVisibleDistance setBlipColor setBlipIcon setBlipOrdering setBlipSize
+setBlipVisibleDistance
+
+getCameraInterior getCameraMatrix getCameraTarget setCameraInterior setCameraMatrix
+setCameraTarget fadeCamera showCursor isCursorShowing
+
+addPedClothes removePedClothes getPedClothes getBodyPartName getClothesByTypeIndex
+getTypeIndexFromClothes getClothesTypeName
+
+createColCircle createColCuboid createColPolygon createColRectangle createColSphere
+createColTube getColShapeRadius getColShapeSize getColShapeType getColPolygonPoints
+getColPolygonPointPosition setColPolygonPointPosition addColPolygonPoint removeColPolygonPoint
+getColPolygonHeight setColPolygonHeight setColShapeRadius setColShapeSize isInsideColShape
+
+createExplosion detonateSatchels
+
+createMarker getMarkerColor getMarkerCount getMarkerIcon getMarkerSize getMarkerTarget
+getMarkerType setMarkerColor setMarkerIcon setMarkerSize setMarkerTarget setMarkerType
+
+createObject moveObject stopObject getObjectScale setObjectScale isObjectMoving
+setObjectBreakable isObjectBreakable
+
+createPed doesPedHaveJetPack getPedAmmoInClip getPedArmor getPedContactElement
+getPedFightingStyle getPedGravity getPedOccupiedVehicle getPedOccupiedVehicleSeat getPedStat
+getPedTarget getPedTotalAmmo getPedWalkingStyle getPedWeapon getPedWeaponSlot isPedChoking
+isPedDead isPedDoingGangDriveby isPedDucked isPedHeadless isPedInVehicle isPedOnFire
+isPedOnGround isPedWearingJetpack isPedReloadingWeapon removePedFromVehicle setPedAnimation
+setPedAnimationProgress setPedAnimationSpeed setPedArmor setPedChoking setPedDoingGangDriveby
+setPedFightingStyle setPedGravity setPedHeadless setPedOnFire setPedStat setPedWalkingStyle
+setPedWeaponSlot setPedWearingJetpack warpPedIntoVehicle reloadPedWeapon getPedAnimation
+getPedControlState setPedControlState killPed givePedWeapon
+
+createPickup getPickupAmmo getPickupAmount getPickupRespawnInterval getPickupType
+getPickupWeapon setPickupType setPickupRespawnInterval
+
+getPlayerFromName getPlayerName getPlayerNametagColor getPlayerNametagText getPlayerPing
+getPlayerTeam getPlayerWantedLevel isPlayerNametagShowing setPlayerNametagColor
+setPlayerNametagShowing setPlayerNametagText getPlayerSerial getPlayerMoney givePlayerMoney
+setPlayerMoney takePlayerMoney setPlayerHudComponentVisible forcePlayerMap isPlayerMapForced
+getPlayerBlurLevel setPlayerBlurLevel
+
+createRadarArea getRadarAreaColor getRadarAreaSize isInsideRadarArea isRadarAreaFlashing
+setRadarAreaColor setRadarAreaFlashing setRadarAreaSize
+
+getTeamFromName getTeamName getTeamColor getPlayersInTeam countPlayersInTeam
+getTeamFriendlyFire
+
+addVehicleUpgrade attachTrailerToVehicle blowVehicle createVehicle detachTrailerFromVehicle
+fixVehicle getOriginalHandling getTrainDirection getTrainPosition getTrainSpeed getTrainTrack
+getVehicleColor getVehicleCompatibleUpgrades getVehicleController getVehicleDoorOpenRatio
+getVehicleDoorState getVehicleEngineState getVehicleHandling getVehicleHeadLightColor
+getVehicleLandingGearDown getVehicleLightState getVehicleMaxPassengers getVehicleModelFromName
+getVehicleName getVehicleNameFromModel getVehicleOccupant getVehicleOccupants
+getVehicleOverrideLights getVehiclePaintjob getVehiclePanelState getVehiclePlateText
+getVehicleSirenParams getVehicleSirens getVehicleSirensOn getVehicleTowedByVehicle
+getVehicleTowingVehicle getVehicleTurretPosition getVehicleType getVehicleUpgradeOnSlot
+getVehicleUpgradeSlotName getVehicleUpgrades getVehicleVariant getVehicleWheelStates
+getVehiclesOfType isTrainDerailable isTrainDerailed isVehicleBlown isVehicleDamageProof
+isVehicleFuelTankExplodable isVehicleLocked isVehicleOnGround isVehicleTaxiLightOn
+removeVehicleUpgrade setTrainDerailable setTrainDerailed setTrainDirection setTrainPosition
+setTrainSpeed setTrainTrack setVehicleColor setVehicleDamageProof setVehicleDoorOpenRatio
+setVehicleDoorState setVehicleDoorsUndamageable setVehicleEngineState
+setVehicleFuelTankExplodable setVehicleHandling setVehicleHeadLightColor
+setVehicleLandingGearDown setVehicleLightState setVehicleLocked setVehicleOverrideLights
+setVehiclePaintjob setVehiclePanelState setVehiclePlateText setVehicleSirens setVehicleSirensOn
+setVehicleTaxiLightOn setVehicleTurretPosition setVehicleVariant setVehicleWheelStates
+addVehicleSirens removeVehicleSirens getVehicleAdjustableProperty
+
+createWater getWaterVertexPosition setWaterVertexPosition getWaveHeight setWaveHeight
+getWaterColor setWaterColor resetWaterColor setWaterLevel resetWaterLevel
+
+getWeaponNameFromID getWeaponIDFromName getSlotFromWeapon getWeaponProperty setWeaponProperty
+getOriginalWeaponProperty createWeapon fireWeapon getWeaponState setWeaponState getWeaponTarget
+setWeaponTarget resetWeaponTarget getWeaponFlags setWeaponFlags getWeaponFiringRate
+setWeaponFiringRate resetWeaponFiringRate getWeaponAmmo setWeaponAmmo getWeaponClipAmmo
+setWeaponClipAmmo getWeaponOwner setWeaponOwner
+
+getTime setTime getWeather setWeather setWeatherBlended getGravity setGravity getGameSpeed
+setGameSpeed getSkyGradient setSkyGradient resetSkyGradient getHeatHaze setHeatHaze
+resetHeatHaze getFarClipDistance setFarClipDistance resetFarClipDistance getFogDistance
+setFogDistance resetFogDistance getSunColor setSunColor resetSunColor getSunSize setSunSize
+resetSunSize getMoonSize setMoonSize resetMoonSize getRainLevel setRainLevel resetRainLevel
+getCloudsEnabled setCloudsEnabled getInteriorSoundsEnabled setInteriorSoundsEnabled
+getJetpackMaxHeight setJetpackMaxHeight getAircraftMaxHeight setAircraftMaxHeight
+getAircraftMaxVelocity setAircraftMaxVelocity getMinuteDuration setMinuteDuration
+getOcclusionsEnabled setOcclusionsEnabled getTrafficLightState setTrafficLightState
+setTrafficLightsLocked areTrafficLightsLocked getWindVelocity setWindVelocity
+resetWindVelocity getZoneName isGarageOpen setGarageOpen removeWorldModel restoreWorldModel
+restoreAllWorldModels isWorldSpecialPropertyEnabled setWorldSpecialPropertyEnabled
+getJetpackWeaponEnabled setJetpackWeaponEnabled getWaterLevel playSoundFrontEnd
+
+getAnimations getAnimationBlocks
+`
+
+// clientFunctions are the functions of the MTA scripting API defined in client scripts only
+const clientFunctions = `
+triggerServerEvent triggerLatentServerEvent getLocalPlayer
+
+getKeyboardLayout getLocalization setClipboard setWindowFlashing createTrayNotification
+isTrayNotificationEnabled downloadFile setDiscordApplicationID setDiscordRichPresenceAsset
+setDiscordRichPresenceButton setDiscordRichPresenceDetails setDiscordRichPresencePartySize
+setDiscordRichPresenceSmallAsset setDiscordRichPresenceStartTime setDiscordRichPresenceEndTime
+setDiscordRichPresenceState resetDiscordRichPresenceData isDiscordRichPresenceConnected
+getDiscordRichPresenceUserID clearDebugBox getChatboxLayout getChatboxCharacterLimit
+isChatBoxInputActive isConsoleActive isDebugViewActive setDebugViewActive isMainMenuActive
+isMTAWindowActive isMTAWindowFocused isTransferBoxActive isTransferBoxVisible
+setTransferBoxVisible getCursorPosition setCursorPosition getCursorAlpha setCursorAlpha
+getAnalogControlState setAnalogControlState getBoundKeys getCommandsBoundToKey
+getKeyBoundToCommand isCapsLockEnabled
+
+playSound playSound3D stopSound setSoundVolume getSoundVolume setSoundSpeed getSoundSpeed
+setSoundPosition getSoundPosition getSoundLength getSoundBufferLength setSoundPaused
+isSoundPaused setSoundMinDistance getSoundMinDistance setSoundMaxDistance getSoundMaxDistance
+getSoundMetaTags setSoundEffectEnabled getSoundEffects setSoundEffectParameter
+getSoundEffectParameters setSoundPan getSoundPan getSoundProperties setSoundProperties
+getSoundFFTData getSoundWaveData getSoundLevelData getSoundBPM setSoundLooped isSoundLooped
+playSFX playSFX3D getSFXStatus setRadioChannel getRadioChannel getRadioChannelName
+setAmbientSoundEnabled isAmbientSoundEnabled resetAmbientSounds setWorldSoundEnabled
+isWorldSoundEnabled resetWorldSounds
+
+createBrowser guiCreateBrowser guiGetBrowser loadBrowserURL getBrowserURL getBrowserTitle
+getBrowserSource getBrowserProperty setBrowserProperty executeBrowserJavascript focusBrowser
+isBrowserFocused injectBrowserMouseDown injectBrowserMouseMove injectBrowserMouseUp
+injectBrowserMouseWheel isBrowserDomainBlocked isBrowserLoading navigateBrowserBack
+navigateBrowserForward canBrowserNavigateBack canBrowserNavigateForward reloadBrowserPage
+requestBrowserDomains resizeBrowser setBrowserAjaxHandler setBrowserRenderingPaused
+isBrowserRenderingPaused setBrowserVolume toggleBrowserDevTools isBrowserGPUEnabled
+
+getCamera getCameraClip setCameraClip getCameraFieldOfView setCameraFieldOfView
+getCameraGoggleEffect setCameraGoggleEffect getCameraShakeLevel setCameraShakeLevel
+getCameraViewMode setCameraViewMode getCameraDrunkLevel setCameraDrunkLevel
+
+dxDrawCircle dxDrawImage dxDrawImageSection dxDrawLine dxDrawLine3D dxDrawMaterialLine3D
+dxDrawMaterialPrimitive dxDrawMaterialPrimitive3D dxDrawMaterialSectionLine3D dxDrawPrimitive
+dxDrawPrimitive3D dxDrawRectangle dxDrawText dxDrawWiredSphere dxDrawModel3D dxGetBlendMode
+dxGetFontHeight dxGetMaterialSize dxGetPixelColor dxGetPixelsFormat dxGetPixelsSize dxGetStatus
+dxGetTextSize dxGetTextWidth dxGetTexturePixels dxIsAspectRatioAdjustmentEnabled
+dxSetAspectRatioAdjustmentEnabled dxSetBlendMode dxSetPixelColor dxSetRenderTarget
+dxSetShaderValue dxSetShaderTessellation dxSetShaderTransform dxSetTestMode dxSetTextureEdge
+dxSetTexturePixels dxUpdateScreenSource dxCreateFont dxCreateRenderTarget dxCreateScreenSource
+dxCreateShader dxCreateTexture dxConvertPixels dxGetTextBoundingBox
+
+createEffect fxAddBlood fxAddBulletImpact fxAddBulletSplash fxAddDebris fxAddFootSplash
+fxAddGlass fxAddGunshot fxAddPunchImpact fxAddSparks fxAddTankFire fxAddTyreBurst
+fxAddWaterHydrant fxAddWaterSplash fxAddWood fxCreateParticle getEffectDensity
+setEffectDensity getEffectSpeed setEffectSpeed setEffectInterior createFire extinguishFire
+
+engineApplyShaderToWorldTexture engineRemoveShaderFromWorldTexture engineFreeModel
+engineGetModelIDFromName engineGetModelLODDistance engineGetModelNameFromID
+engineGetModelPhysicalPropertiesGroup engineGetModelTextureNames engineGetModelTextures
+engineGetObjectGroupPhysicalProperty engineGetSurfaceProperties engineGetVisibleTextureNames
+engineImportTXD engineLoadCOL engineLoadDFF engineLoadIFP engineLoadTXD engineLoadIMG
+engineReplaceAnimation engineReplaceCOL engineReplaceModel engineRequestModel
+engineResetModelLODDistance engineResetSurfaceProperties engineRestoreAnimation
+engineRestoreCOL engineRestoreModel engineRestoreModelPhysicalPropertiesGroup
+engineRestoreObjectGroupPhysicalProperties engineSetAsynchronousLoading
+engineSetModelLODDistance engineSetModelPhysicalPropertiesGroup
+engineSetObjectGroupPhysicalProperty engineSetSurfaceProperties engineStreamingFreeUpMemory
+engineStreamingGetUsedMemory engineGetModelFlags engineSetModelFlags engineGetModelFlag
+engineSetModelFlag engineResetModelFlags engineRestreamWorld engineGetModelVisibleTime
+engineSetModelVisibleTime engineGetModelTXDID engineAddImage engineRemoveImage
+engineImageGetFilesCount engineImageGetFiles engineImageGetFile engineImageLinkDFF
+engineImageLinkTXD engineRestoreDFFImage engineRestoreTXDImage engineSetPoolCapacity
+engineGetPoolCapacity engineGetPoolDefaultCapacity engineGetPoolUsedCapacity
+enginePreloadWorldArea engineStreamingSetBufferSize engineStreamingGetBufferSize
+engineStreamingSetMemorySize engineStreamingGetMemorySize engineStreamingRestoreBufferSize
+engineStreamingRestoreMemorySize engineStreamingSetModelCacheLimits
+
+guiBringToFront guiCreateFont guiBlur guiFocus guiGetAlpha guiGetEnabled guiGetFont
+guiGetInputEnabled guiGetInputMode guiGetPosition guiGetProperties guiGetProperty
+guiGetScreenSize guiGetSize guiGetText guiGetVisible guiMoveToBack guiSetAlpha guiSetEnabled
+guiSetFont guiSetInputEnabled guiSetInputMode guiSetPosition guiSetProperty guiSetSize
+guiSetText guiSetVisible guiGetCursorType guiCreateButton guiCreateCheckBox
+guiCheckBoxGetSelected guiCheckBoxSetSelected guiCreateComboBox guiComboBoxAddItem
+guiComboBoxClear guiComboBoxGetItemText guiComboBoxGetSelected guiComboBoxRemoveItem
+guiComboBoxSetItemText guiComboBoxSetSelected guiComboBoxGetItemCount guiComboBoxIsOpen
+guiComboBoxSetOpen guiCreateEdit guiEditGetCaretIndex guiEditGetMaxLength guiEditIsMasked
+guiEditIsReadOnly guiEditSetCaretIndex guiEditSetMasked guiEditSetMaxLength
+guiEditSetReadOnly guiCreateGridList guiGridListAddColumn guiGridListAddRow
+guiGridListAutoSizeColumn guiGridListClear guiGridListGetColumnCount guiGridListGetColumnTitle
+guiGridListGetColumnWidth guiGridListGetHorizontalScrollPosition guiGridListGetItemColor
+guiGridListGetItemData guiGridListGetItemText guiGridListGetRowCount
+guiGridListGetSelectedCount guiGridListGetSelectedItem guiGridListGetSelectedItems
+guiGridListGetSelectionMode guiGridListGetVerticalScrollPosition guiGridListInsertRowAfter
+guiGridListIsSortingEnabled guiGridListRemoveColumn guiGridListRemoveRow
+guiGridListSetColumnTitle guiGridListSetColumnWidth guiGridListSetHorizontalScrollPosition
+guiGridListSetItemColor guiGridListSetItemData guiGridListSetItemText guiGridListSetScrollBars
+guiGridListSetSelectedItem guiGridListSetSelectionMode guiGridListSetSortingEnabled
+guiGridListSetVerticalScrollPosition guiCreateLabel guiLabelGetColor guiLabelGetFontHeight
+guiLabelGetTextExtent guiLabelSetColor guiLabelSetHorizontalAlign guiLabelSetVerticalAlign
+guiCreateMemo guiMemoGetCaretIndex guiMemoIsReadOnly guiMemoSetCaretIndex guiMemoSetReadOnly
+guiMemoGetVerticalScrollPosition guiMemoSetVerticalScrollPosition guiCreateProgressBar
+guiProgressBarGetProgress guiProgressBarSetProgress guiCreateRadioButton
+guiRadioButtonGetSelected guiRadioButtonSetSelected guiCreateScrollBar
+guiScrollBarGetScrollPosition guiScrollBarSetScrollPosition guiCreateScrollPane
+guiScrollPaneGetHorizontalScrollPosition guiScrollPaneGetVerticalScrollPosition
+guiScrollPaneSetHorizontalScrollPosition guiScrollPaneSetScrollBars
+guiScrollPaneSetVerticalScrollPosition guiCreateStaticImage guiStaticImageGetNativeSize
+guiStaticImageLoadImage guiCreateTabPanel guiGetSelectedTab guiSetSelectedTab guiCreateTab
+guiDeleteTab guiCreateWindow guiWindowIsMovable guiWindowIsSizable guiWindowSetMovable
+guiWindowSetSizable
+
+createLight getLightColor getLightDirection getLightRadius getLightType setLightColor
+setLightDirection setLightRadius createSearchLight getSearchLightEndPosition
+getSearchLightEndRadius getSearchLightStartPosition getSearchLightStartRadius
+setSearchLightEndPosition setSearchLightEndRadius setSearchLightStartPosition
+setSearchLightStartRadius
+
+setCoronaReflectionEnabled isCoronaReflectionEnabled breakObject getObjectMass setObjectMass
+respawnObject toggleObjectRespawn getObjectProperty setObjectProperty
+
+getElementBoundingBox getElementDistanceFromCentreOfMassToBaseOfModel getElementRadius
+isElementCollidableWith isElementLocal isElementOnScreen isElementStreamable
+isElementStreamedIn isElementSyncer isElementWaitingForGroundToLoad setElementCollidableWith
+setElementStreamable getElementBoneMatrix getElementBonePosition getElementBoneRotation
+getElementBoneQuaternion setElementBoneMatrix setElementBonePosition setElementBoneRotation
+setElementBoneQuaternion updateElementRpHAnim getElementLighting getResourceGUIElement
+
+canPedBeKnockedOffBike getPedAnalogControlState setPedAnalogControlState getPedBonePosition
+getPedCameraRotation setPedCameraRotation getPedMoveState getPedOxygenLevel setPedOxygenLevel
+getPedSimplestTask getPedTask getPedTargetCollision getPedTargetEnd getPedTargetStart
+getPedVoice setPedVoice getPedWeaponMuzzlePosition isPedBleeding setPedBleeding isPedDoingTask
+isPedTargetingMarkerEnabled setPedTargetingMarkerEnabled setPedAimTarget
+setPedCanBeKnockedOffBike setPedEnterVehicle setPedExitVehicle setPedFootBloodEnabled
+setPedLookAt
+
+isPlayerHudComponentVisible isPlayerMapVisible getPlayerMapBoundingBox getPlayerMapOpacity
+
+createProjectile getProjectileCounter getProjectileCreator getProjectileForce
+getProjectileTarget getProjectileType setProjectileCounter
+
+getHeliBladeCollisionsEnabled setHeliBladeCollisionsEnabled getHelicopterRotorSpeed
+setHelicopterRotorSpeed getVehicleComponentPosition setVehicleComponentPosition
+getVehicleComponentRotation setVehicleComponentRotation getVehicleComponentScale
+setVehicleComponentScale getVehicleComponentVisible setVehicleComponentVisible
+getVehicleComponents resetVehicleComponentPosition resetVehicleComponentRotation
+resetVehicleComponentScale getVehicleCurrentGear getVehicleGravity setVehicleGravity
+getVehicleModelDummyPosition setVehicleModelDummyPosition getVehicleModelDummyDefaultPosition
+getVehicleModelExhaustFumesPosition setVehicleModelExhaustFumesPosition
+getVehicleModelWheelSize setVehicleModelWheelSize getVehicleNitroCount setVehicleNitroCount
+getVehicleNitroLevel setVehicleNitroLevel isVehicleNitroActivated setVehicleNitroActivated
+isVehicleNitroRecharging getVehicleWheelFrictionState getVehicleWheelScale
+setVehicleWheelScale isVehicleWheelOnGround isVehicleWindowOpen setVehicleWindowOpen
+getVehicleDummyPosition setVehicleDummyPosition resetVehicleDummyPositions
+spawnVehicleFlyingComponent setVehicleSmokeTrailEnabled isVehicleSmokeTrailEnabled
+
+testLineAgainstWater createSWATRope getBirdsEnabled setBirdsEnabled getGarageBoundingBox
+getGaragePosition getGarageSize getGroundPosition getRoofPosition getScreenFromWorldPosition
+getWorldFromScreenPosition isLineOfSightClear processLineOfSight getNearClipDistance
+setNearClipDistance resetNearClipDistance getVehiclesLODDistance setVehiclesLODDistance
+resetVehiclesLODDistance getPedsLODDistance setPedsLODDistance resetPedsLODDistance
+getColorFilter setColorFilter resetColorFilter setGrainMultiplier setGrainLevel
+testSphereAgainstWorld getDynamicPedShadowsEnabled setDynamicPedShadowsEnabled
+getWorldProperty setWorldProperty resetWorldProperty resetWorldProperties
+getInteriorFurnitureEnabled setInteriorFurnitureEnabled
+`
+
+// serverFunctions are the functions of the MTA scripting API defined in server scripts only
+const serverFunctions = `
+triggerClientEvent triggerLatentClientEvent getCancelReason
+
+addAccount copyAccountData getAccount getAccountData getAllAccountData getAccountName
+getAccountPlayer getAccountSerial getAccounts getAccountsBySerial getAccountsByData
+getAccountsByIP getAccountID getAccountByID getAccountIP getAccountType getPlayerAccount
+isGuestAccount logIn logOut removeAccount setAccountData setAccountName setAccountPassword
+setAccountSerial
+
+aclCreate aclCreateGroup aclDestroy aclDestroyGroup aclGet aclGetGroup aclGetName aclGetRight
+aclGroupAddACL aclGroupAddObject aclGroupGetName aclGroupList aclGroupListACL
+aclGroupListObjects aclGroupRemoveACL aclGroupRemoveObject aclList aclListRights aclReload
+aclRemoveRight aclSave aclSetRight aclObjectGetGroups hasObjectPermissionTo isObjectInACLGroup
+
+addBan banPlayer getBanAdmin getBanIP getBanNick getBanReason getBanSerial getBans getBanTime
+getUnbanTime isBan kickPlayer reloadBans removeBan setBanAdmin setBanNick setBanReason
+setUnbanTime getPlayerAnnounceValue setPlayerAnnounceValue resendPlayerModInfo
+resendPlayerACInfo getRuleValue setRuleValue removeRuleValue
+
+getServerConfigSetting setServerConfigSetting getServerName getMaxPlayers setMaxPlayers
+getServerPort getServerHttpPort getServerPassword setServerPassword isGlitchEnabled
+setGlitchEnabled shutdown getLoadedModules getModuleInfo outputServerLog callRemote get set
+
+getMapName setMapName getGameType setGameType loadMapData saveMapData resetMapInfo
+
+startResource stopResource restartResource createResource copyResource deleteResource
+renameResource setResourceInfo getResourceLastStartTime getResourceLoadTime
+getResourceLoadFailureReason getResourceOrganizationalPath getResourceACLRequests
+updateResourceACLRequest addResourceMap addResourceConfig removeResourceFile refreshResources
+isResourceArchived getResourceMapRootElement isResourceProtected setResourceProtected
+
+cloneElement clearElementVisibleTo getElementSyncer setElementSyncer isElementVisibleTo
+setElementVisibleTo addElementDataSubscriber removeElementDataSubscriber
+hasElementDataSubscriber
+
+giveWeapon takeWeapon takeAllWeapons
+
+getAlivePlayers getDeadPlayers getRandomPlayer getPlayerCount getPlayerIP getPlayerVersion
+getPlayerIdleTime getPlayerACInfo getPlayerScriptDebugLevel setPlayerScriptDebugLevel
+isPlayerMuted setPlayerMuted redirectPlayer spawnPlayer takePlayerScreenShot setPlayerName
+setPlayerTeam setPlayerWantedLevel setPlayerVoiceBroadcastTo setPlayerVoiceIgnoreFrom
+getPlayerFromSerial
+
+isPickupSpawned usePickup
+
+createTeam setTeamName setTeamColor setTeamFriendlyFire
+
+dbConnect dbExec dbFree dbPoll dbPrepareString dbQuery executeSQLQuery executeSQLCreateTable
+executeSQLDelete executeSQLInsert executeSQLSelect executeSQLUpdate executeSQLDropTable
+
+textCreateDisplay textCreateTextItem textDestroyDisplay textDestroyTextItem
+textDisplayAddObserver textDisplayAddText textDisplayGetObservers textDisplayIsObserver
+textDisplayRemoveObserver textDisplayRemoveText textItemGetColor textItemGetPosition
+textItemGetPriority textItemGetScale textItemGetText textItemSetColor textItemSetPosition
+textItemSetPriority textItemSetScale textItemSetText
+
+resetVehicleExplosionTime resetVehicleIdleTime respawnVehicle setVehicleIdleRespawnDelay
+setVehicleRespawnDelay setVehicleRespawnPosition setVehicleRespawnRotation spawnVehicle
+toggleVehicleRespawn getVehicleRespawnPosition getVehicleRespawnRotation
+`
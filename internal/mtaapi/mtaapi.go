@@ -0,0 +1,66 @@
+package mtaapi
+
+import "strings"
+
+// Side tells where a global of the MTA scripting API is defined
+type Side int
+
+const (
+	SideShared Side = iota // Defined in client and server scripts
+	SideClient             // Defined in client scripts only
+	SideServer             // Defined in server scripts only
+)
+
+// String returns the side as written in the type attribute of meta.xml scripts
+func (s Side) String() string {
+	switch s {
+	case SideClient:
+		return "client"
+	case SideServer:
+		return "server"
+	}
+	return "shared"
+}
+
+var (
+	// sides are the sides of every known global, filled from the lists below
+	sides = make(map[string]Side)
+	// lowerNames are the known globals by their lower case name, for suggestions
+	lowerNames = make(map[string]string)
+)
+
+func init() {
+	for _, list := range []struct {
+		side  Side
+		names string
+	}{
+		{SideShared, luaGlobals}, {SideShared, sharedVariables}, {SideShared, oopClasses}, {SideShared, sharedFunctions},
+		{SideClient, clientVariables}, {SideClient, clientFunctions},
+		{SideServer, serverVariables}, {SideServer, serverFunctions},
+	} {
+		for _, name := range strings.Fields(list.names) {
+			if side, ok := sides[name]; ok && side != list.side {
+				// Listed for both sides
+				list.side = SideShared
+			}
+			sides[name] = list.side
+			lowerNames[strings.ToLower(name)] = name
+		}
+	}
+}
+
+// Lookup returns the side a global of the Lua standard library or the MTA scripting API is
+// defined on, and false for names MTA does not define
+func Lookup(name string) (Side, bool) {
+	side, ok := sides[name]
+	return side, ok
+}
+
+// Suggest returns the known global name differing from name only in case, such as
+// outputChatBox for outputChatbox, or "" when there is none
+func Suggest(name string) string {
+	if known, ok := lowerNames[strings.ToLower(name)]; ok && known != name {
+		return known
+	}
+	return ""
+}
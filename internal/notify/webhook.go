@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// Payload formats of webhooks
+const (
+	FormatDiscord = "discord" // Discord webhook with an embed
+	FormatSlack   = "slack"   // Slack incoming webhook with a text message
+	FormatJSON    = "json"    // The Message as JSON, for custom receivers
+)
+
+// maxFailures is the number of failed resources named in a message
+const maxFailures = 10
+
+// Webhook is a URL receiving a summary after each build
+type Webhook struct {
+	URL          string
+	Format       string // Payload format, detected from the URL when empty
+	OnlyFailures bool   // Only notify builds with failures
+
+	HTTPClient *http.Client // Client used for requests (nil uses a client with a 10 second timeout)
+}
+
+// Message summarizes a build for a webhook
+type Message struct {
+	Title      string        `json:"title"`       // What was built, such as the input path
+	Resources  int           `json:"resources"`   // Resources processed
+	Succeeded  int           `json:"succeeded"`   // Resources built or unchanged
+	Failed     int           `json:"failed"`      // Resources that failed
+	Unchanged  int           `json:"unchanged"`   // Resources skipped as unchanged since the last build
+	Skipped    int           `json:"skipped"`     // Resources not built after a failure with -fail-fast
+	InputSize  int64         `json:"input_size"`  // Source size of the compiled scripts in bytes
+	OutputSize int64         `json:"output_size"` // Compiled size of the scripts in bytes
+	Duration   time.Duration `json:"duration_ns"` // Duration of the build
+	Failures   []string      `json:"failures"`    // Names of the first failed resources
+	Error      string        `json:"error,omitempty"`
+}
+
+// NewMessage summarizes the result of a build. runErr is the error that stopped the build
+// before any resource was built, if any.
+func NewMessage(title string, result bundler.BuildResult, runErr error) Message {
+	msg := Message{Title: title, Resources: len(result.Resources), Skipped: result.Skipped, Duration: result.Duration}
+	if runErr != nil {
+		msg.Error = runErr.Error()
+	}
+	for _, res := range result.Resources {
+		msg.InputSize += res.Compile.Compilation.TotalInputSize
+		msg.OutputSize += res.Compile.Compilation.TotalOutputSize
+		if res.Unchanged {
+			msg.Unchanged++
+		}
+		if res.Error == nil {
+			msg.Succeeded++
+			continue
+		}
+		msg.Failed++
+		if len(msg.Failures) < maxFailures {
+			msg.Failures = append(msg.Failures, resourceName(res))
+		}
+	}
+	return msg
+}
+
+// OK reports whether the build succeeded entirely
+func (m Message) OK() bool {
+	return m.Error == "" && m.Failed == 0 && m.Skipped == 0
+}
+
+// Reduction returns the size reduction of the compiled scripts in percent
+func (m Message) Reduction() float64 {
+	if m.InputSize <= 0 {
+		return 0
+	}
+	return (1 - float64(m.OutputSize)/float64(m.InputSize)) * 100
+}
+
+// Send posts the message to the webhook, writing sizes and durations in format. Successful
+// builds are not sent to webhooks that only want failures.
+func (w Webhook) Send(msg Message, format units.Format) error {
+	if w.OnlyFailures && msg.OK() {
+		return nil
+	}
+
+	var payload any
+	switch w.format() {
+	case FormatDiscord:
+		payload = discordPayload(msg, format)
+	case FormatSlack:
+		payload = map[string]string{"text": "*" + headline(msg) + "*\n" + strings.Join(details(msg, format), "\n")}
+	default:
+		payload = msg
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	httpClient := w.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := httpClient.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL usually holds a secret token, keep it out of the message
+		return fmt.Errorf("failed to send webhook: %v", redact(err, w.URL))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook rejected the build summary: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// format returns the payload format, detected from well-known webhook hosts when not set
+func (w Webhook) format() string {
+	switch {
+	case w.Format != "":
+		return w.Format
+	case strings.Contains(w.URL, "discord.com/api/webhooks/") || strings.Contains(w.URL, "discordapp.com/api/webhooks/"):
+		return FormatDiscord
+	case strings.Contains(w.URL, "hooks.slack.com/"):
+		return FormatSlack
+	}
+	return FormatJSON
+}
+
+// headline returns the first line of a message
+func headline(msg Message) string {
+	switch {
+	case msg.Error != "":
+		return "Build failed: " + msg.Title
+	case !msg.OK():
+		return fmt.Sprintf("Build finished with %d failure(s): %s", msg.Failed, msg.Title)
+	}
+	return "Build succeeded: " + msg.Title
+}
+
+// details returns the summary lines of a message
+func details(msg Message, format units.Format) []string {
+	if msg.Error != "" {
+		return []string{msg.Error}
+	}
+	lines := []string{
+		fmt.Sprintf("Resources: %d built, %d failed", msg.Succeeded, msg.Failed),
+	}
+	if msg.Unchanged > 0 || msg.Skipped > 0 {
+		lines = append(lines, fmt.Sprintf("Unchanged: %d, not built: %d", msg.Unchanged, msg.Skipped))
+	}
+	if msg.InputSize > 0 {
+		lines = append(lines, fmt.Sprintf("Scripts: %s → %s (%s%% smaller)",
+			format.Size(msg.InputSize), format.Size(msg.OutputSize), format.Number(msg.Reduction(), 1)))
+	}
+	lines = append(lines, "Duration: "+format.Duration(msg.Duration))
+	if len(msg.Failures) > 0 {
+		failures := strings.Join(msg.Failures, ", ")
+		if msg.Failed > len(msg.Failures) {
+			failures += fmt.Sprintf(" and %d more", msg.Failed-len(msg.Failures))
+		}
+		lines = append(lines, "Failed: "+failures)
+	}
+	return lines
+}
+
+// discordPayload returns a Discord webhook payload with the summary as an embed
+func discordPayload(msg Message, format units.Format) any {
+	color := 0x2ecc71
+	if !msg.OK() {
+		color = 0xe74c3c
+	}
+	return map[string]any{
+		"embeds": []map[string]any{{
+			"title":       headline(msg),
+			"description": strings.Join(details(msg, format), "\n"),
+			"color":       color,
+		}},
+	}
+}
+
+// resourceName returns the name of a built resource, falling back to its meta.xml directory
+func resourceName(res bundler.ResourceResult) string {
+	if res.Resource != nil {
+		return res.Resource.Name
+	}
+	return filepath.Base(filepath.Dir(res.MetaXMLPath))
+}
+
+// redact removes url from the message of err
+func redact(err error, url string) string {
+	return strings.ReplaceAll(err.Error(), url, "<webhook url>")
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+func TestSend(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	result := bundler.BuildResult{
+		Duration: 1500 * time.Millisecond,
+		Resources: []bundler.ResourceResult{
+			{MetaXMLPath: "resources/race/meta.xml", Compile: resource.CompileResult{
+				Compilation: resource.BatchCompilationResult{TotalInputSize: 4000, TotalOutputSize: 1000},
+			}},
+			{MetaXMLPath: "resources/admin/meta.xml", Error: errors.New("syntax error")},
+		},
+	}
+	msg := NewMessage("resources", result, nil)
+	if msg.Succeeded != 1 || msg.Failed != 1 || msg.Reduction() != 75 || msg.Failures[0] != "admin" {
+		t.Fatalf("Unexpected message: %+v", msg)
+	}
+
+	discord := Webhook{URL: server.URL, Format: FormatDiscord}
+	if err := discord.Send(msg, units.Format{}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	var payload struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &payload); err != nil || len(payload.Embeds) != 1 {
+		t.Fatalf("Unexpected Discord payload %s: %v", bodies[0], err)
+	}
+	for _, want := range []string{"3.9 KB → 1000 B (75.0% smaller)", "Duration: 1.5s", "Failed: admin"} {
+		if !strings.Contains(payload.Embeds[0].Description, want) {
+			t.Errorf("Discord description is missing %q:\n%s", want, payload.Embeds[0].Description)
+		}
+	}
+
+	failuresOnly := Webhook{URL: server.URL, OnlyFailures: true}
+	if err := failuresOnly.Send(NewMessage("resources", bundler.BuildResult{}, nil), units.Format{}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Errorf("A successful build was sent to a failures-only webhook")
+	}
+}
@@ -0,0 +1,114 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// ABRuns is the number of measurement columns in the A/B report template
+const ABRuns = 5
+
+// abVariant is a row of the variants table of the A/B report
+type abVariant struct {
+	Resource    string
+	Name        string
+	Variant     string
+	Obfuscation int
+	StripDebug  bool
+	Scripts     int
+	ScriptSize  string
+	Error       string
+}
+
+// abTemplate is the markdown template owners fill in while measuring client load times
+var abTemplate = template.Must(template.New("ab").Funcs(template.FuncMap{
+	"runs": func() []int {
+		runs := make([]int, ABRuns)
+		for i := range runs {
+			runs[i] = i + 1
+		}
+		return runs
+	},
+}).Parse(`# Obfuscation A/B test
+
+Input: ` + "`{{.Input}}`" + `
+Generated: {{.Generated}}
+
+## Variants
+
+| Resource | Variant resource | Obfuscation | Strip debug | Scripts | Script size |
+|----------|------------------|-------------|-------------|---------|-------------|
+{{- range .Variants}}
+| {{.Resource}} | {{if .Error}}failed: {{.Error}}{{else}}{{.Name}}{{end}} | {{.Obfuscation}} | {{.StripDebug}} | {{.Scripts}} | {{.ScriptSize}} |
+{{- end}}
+
+## How to measure
+
+1. Deploy the variant resources and start **one variant of a resource at a time**, so both variants never compete for the same client.
+2. Join the server with a clean client cache and run ` + "`/debugscript 3`" + `.
+3. Reconnect and note the ` + "`[ab-test] <resource> loaded in N ms`" + ` line for the running variant.
+4. Stop it, start the other variant and repeat. Alternate variants for at least {{.Runs}} runs each, on the same machine and with the same graphics settings.
+5. Fill in the table below and compare the averages. Differences within a few milliseconds are usually noise.
+
+Variant resources are renamed, so calls to other resources by name (exports, ` + "`getResourceFromName`" + `) still target the original name.
+Do not deploy the variants to production: they log their load time to every client.
+
+## Measurements (ms)
+
+| Variant resource |{{range runs}} Run {{.}} |{{end}} Average |
+|------------------|{{range runs}}-------|{{end}}---------|
+{{- range .Variants}}{{if not .Error}}
+| {{.Name}} |{{range runs}}       |{{end}}         |
+{{- end}}{{end}}
+`))
+
+// WriteABTemplate writes the A/B test report template for variant builds of input to path,
+// with sizes written in format
+func WriteABTemplate(path, input string, results []bundler.VariantResult, format units.Format) error {
+	data := struct {
+		Input     string
+		Generated string
+		Runs      int
+		Variants  []abVariant
+	}{
+		Input:     input,
+		Generated: time.Now().UTC().Format(time.DateTime + " UTC"),
+		Runs:      ABRuns,
+	}
+
+	for _, result := range results {
+		row := abVariant{
+			Resource:    resourceName(result.Result),
+			Name:        result.Name,
+			Variant:     result.Variant.Name,
+			Obfuscation: int(result.Result.Options.ObfuscationLevel),
+			StripDebug:  result.Result.Options.StripDebug,
+			Scripts:     len(result.Result.Compile.Compilation.Results),
+			ScriptSize:  format.Size(result.Result.Compile.Compilation.TotalOutputSize),
+			Error:       errorString(result.Result.Error),
+		}
+		data.Variants = append(data.Variants, row)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write A/B report: %w", err)
+	}
+	defer file.Close()
+
+	if err := abTemplate.Execute(file, data); err != nil {
+		return fmt.Errorf("failed to generate A/B report: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// htmlTemplate is the browsable form of a report: the build summary, an inventory of every
+// resource with its <info> and README, and the cases needing attention
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>mta-bundler report: {{.InputPath}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+.failed { color: #b00020; }
+.muted { color: #777; }
+pre { white-space: pre-wrap; background: #f8f8f8; padding: 0.8em; }
+</style>
+</head>
+<body>
+<h1>Build of {{.InputPath}}</h1>
+<p>Built by mta-bundler {{.Version}} on {{.StartedAt.Format "2006-01-02 15:04:05 MST"}}{{with .OutputDir}} into {{.}}{{end}}.
+{{.Summary.Resources}} resources: {{.Summary.Succeeded}} succeeded, {{.Summary.Failed}} failed{{with .Summary.Unchanged}}, {{.}} unchanged{{end}}.
+{{with .Display}}Output size {{.OutputSize}} ({{.CompressionRatio}} of {{.InputSize}}) in {{.Duration}}.{{end}}</p>
+
+<h2>Resources</h2>
+<table>
+<tr><th>Resource</th><th>Type</th><th>Version</th><th>Author</th><th>Description</th><th>Build</th></tr>
+{{- range .Resources}}
+<tr id="{{.Name}}">
+<td><strong>{{.Name}}</strong>{{with .Info}}{{if .Name}}<br><span class="muted">{{.Name}}</span>{{end}}{{end}}</td>
+{{- with .Info}}
+<td>{{.Type}}{{with .Gamemodes}}<br><span class="muted">for {{.}}</span>{{end}}</td><td>{{.Version}}</td><td>{{.Author}}</td><td>{{.Description}}</td>
+{{- else}}
+<td colspan="4" class="muted">No &lt;info&gt; in meta.xml</td>
+{{- end}}
+<td>{{if .Error}}<span class="failed">Failed: {{.Error}}</span>{{else if .Unchanged}}Unchanged{{else}}Built{{with .Display}}, {{.OutputSize}}{{end}}{{end}}</td>
+</tr>
+{{- with .Readme}}
+<tr><td></td><td colspan="5"><details><summary>{{.File}}</summary><pre>{{.Text}}{{if .Truncated}}
+…{{end}}</pre></details></td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- if .Attention}}
+
+<h2>Attention</h2>
+<table>
+<tr><th>Code</th><th>Resource</th><th>Source</th><th>Message</th></tr>
+{{- range .Attention}}
+<tr><td>{{.Code}}</td><td><a href="#{{.Resource}}">{{.Resource}}</a></td><td>{{.Src}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML page to path, or to stdout when path is "-"
+func WriteHTML(report Report, path string) error {
+	var page bytes.Buffer
+	if err := htmlTemplate.Execute(&page, report); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return writeFile(page.Bytes(), path)
+}
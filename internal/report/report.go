@@ -0,0 +1,398 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/advisor"
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/units"
+)
+
+// Default paths of the reports when no path is given
+const (
+	DefaultJSONPath = "mta-bundler-report.json"
+	DefaultHTMLPath = "mta-bundler-report.html"
+)
+
+// Report is the machine-readable representation of a build
+type Report struct {
+	Version    string           `json:"version"`
+	InputPath  string           `json:"input_path"`
+	OutputDir  string           `json:"output_dir,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	DurationMs float64          `json:"duration_ms"`
+	Summary    Summary          `json:"summary"`
+	Resources  []ResourceReport `json:"resources"`
+	Display    *DisplayReport   `json:"display,omitempty"`
+
+	Attention       []AttentionReport      `json:"attention,omitempty"`
+	Recommendations []RecommendationReport `json:"recommendations,omitempty"`
+}
+
+// Summary aggregates the results of all resources
+type Summary struct {
+	Resources        int     `json:"resources"`
+	Succeeded        int     `json:"succeeded"`
+	Failed           int     `json:"failed"`
+	Skipped          int     `json:"skipped,omitempty"`
+	Unchanged        int     `json:"unchanged,omitempty"`
+	Attention        int     `json:"attention,omitempty"`
+	ScriptsCompiled  int     `json:"scripts_compiled"`
+	ScriptErrors     int     `json:"script_errors"`
+	FilesCopied      int     `json:"files_copied"`
+	CopyErrors       int     `json:"copy_errors"`
+	InputSize        int64   `json:"input_size"`
+	OutputSize       int64   `json:"output_size"`
+	CompressionRatio float64 `json:"compression_ratio"`
+}
+
+// ResourceReport describes the build of a single resource
+type ResourceReport struct {
+	Name        string            `json:"name"`
+	MetaXMLPath string            `json:"meta_xml"`
+	OutputDir   string            `json:"output_dir,omitempty"`
+	Archive     string            `json:"archive,omitempty"`
+	MergeMode   bool              `json:"merge_mode"`
+	Unchanged   bool              `json:"unchanged,omitempty"`
+	Options     OptionsReport     `json:"options"`
+	DurationMs  float64           `json:"duration_ms"`
+	Error       string            `json:"error,omitempty"`
+	Affected    []string          `json:"affected,omitempty"`
+	Compilation CompilationReport `json:"compilation"`
+	FileCopy    FileCopyReport    `json:"file_copy"`
+	Display     *DisplayReport    `json:"display,omitempty"`
+	Info        *InfoReport       `json:"info,omitempty"`
+	Readme      *ReadmeReport     `json:"readme,omitempty"`
+}
+
+// InfoReport mirrors resource.Info, the <info> element of the resource's meta.xml
+type InfoReport struct {
+	Name        string `json:"name,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Gamemodes   string `json:"gamemodes,omitempty"`
+}
+
+// ReadmeReport mirrors resource.Readme
+type ReadmeReport struct {
+	File      string `json:"file"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// DisplayReport holds sizes and durations formatted for people, with the units, precision and
+// locale chosen for the build. The raw values stay in the numeric fields.
+type DisplayReport struct {
+	Duration         string `json:"duration"`
+	InputSize        string `json:"input_size"`
+	OutputSize       string `json:"output_size"`
+	CompressionRatio string `json:"compression_ratio"`
+}
+
+// OptionsReport describes the effective compilation options of a resource
+type OptionsReport struct {
+	ObfuscationLevel         int  `json:"obfuscation_level"`
+	StripDebug               bool `json:"strip_debug"`
+	SuppressDecompileWarning bool `json:"suppress_decompile_warning"`
+}
+
+// CompilationReport mirrors resource.BatchCompilationResult
+type CompilationReport struct {
+	TotalFiles       int            `json:"total_files"`
+	SuccessCount     int            `json:"success_count"`
+	ErrorCount       int            `json:"error_count"`
+	TotalInputSize   int64          `json:"total_input_size"`
+	TotalOutputSize  int64          `json:"total_output_size"`
+	CompressionRatio float64        `json:"compression_ratio"`
+	TotalTimeMs      float64        `json:"total_time_ms"`
+	Results          []ScriptReport `json:"results"`
+}
+
+// ScriptReport mirrors compiler.CompilationResult
+type ScriptReport struct {
+	InputFile        string  `json:"input_file"`
+	OutputFile       string  `json:"output_file"`
+	Success          bool    `json:"success"`
+	Error            string  `json:"error,omitempty"`
+	CompileTimeMs    float64 `json:"compile_time_ms"`
+	InputSize        int64   `json:"input_size"`
+	OutputSize       int64   `json:"output_size"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	Retries          int     `json:"retries,omitempty"` // Times the compilation was run again after a transient failure
+}
+
+// FileCopyReport mirrors resource.FileCopyBatchResult
+type FileCopyReport struct {
+	TotalFiles   int          `json:"total_files"`
+	SuccessCount int          `json:"success_count"`
+	ErrorCount   int          `json:"error_count"`
+	TotalSize    int64        `json:"total_size"`
+	Results      []FileReport `json:"results"`
+}
+
+// FileReport mirrors resource.FileCopyResult
+type FileReport struct {
+	RelativePath string `json:"relative_path"`
+	OutputPath   string `json:"output_path"`
+	Success      bool   `json:"success"`
+	Error        string `json:"error,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// AttentionReport mirrors resource.Attention
+type AttentionReport struct {
+	Code     string `json:"code"`
+	Resource string `json:"resource"`
+	Src      string `json:"src,omitempty"`
+	Message  string `json:"message"`
+}
+
+// RecommendationReport mirrors advisor.Recommendation
+type RecommendationReport struct {
+	Resource    string  `json:"resource"`
+	Option      string  `json:"option"`
+	Current     string  `json:"current"`
+	Suggested   string  `json:"suggested"`
+	SizeChange  float64 `json:"size_change"`
+	SampleFiles int     `json:"sample_files"`
+	Tradeoff    string  `json:"tradeoff"`
+	Message     string  `json:"message"`
+}
+
+// New builds a report from the result of a bundler run
+func New(result bundler.BuildResult, version string) Report {
+	report := Report{
+		Version:    version,
+		InputPath:  result.InputPath,
+		OutputDir:  result.OutputDir,
+		StartedAt:  result.StartedAt,
+		DurationMs: milliseconds(result.Duration),
+		Resources:  make([]ResourceReport, 0, len(result.Resources)),
+	}
+
+	for _, res := range result.Resources {
+		resReport := newResourceReport(res)
+		report.Resources = append(report.Resources, resReport)
+
+		report.Summary.Resources++
+		if res.Error != nil {
+			report.Summary.Failed++
+		} else {
+			report.Summary.Succeeded++
+		}
+		report.Summary.ScriptsCompiled += resReport.Compilation.SuccessCount
+		report.Summary.ScriptErrors += resReport.Compilation.ErrorCount
+		report.Summary.FilesCopied += resReport.FileCopy.SuccessCount
+		report.Summary.CopyErrors += resReport.FileCopy.ErrorCount
+		report.Summary.InputSize += resReport.Compilation.TotalInputSize
+		report.Summary.OutputSize += resReport.Compilation.TotalOutputSize
+	}
+	report.Summary.Skipped = result.Skipped
+	report.Summary.Unchanged = result.UnchangedCount()
+	report.Summary.CompressionRatio = ratio(report.Summary.InputSize, report.Summary.OutputSize)
+
+	for _, c := range result.Attention() {
+		report.Attention = append(report.Attention, AttentionReport{Code: c.Code, Resource: c.Resource, Src: c.Src, Message: c.Message})
+	}
+	report.Summary.Attention = len(report.Attention)
+
+	return report
+}
+
+// AddDisplay adds the formatted sizes and durations of the build and of every resource
+func (r *Report) AddDisplay(format units.Format) {
+	duration := time.Duration(r.DurationMs * float64(time.Millisecond))
+	r.Display = newDisplayReport(format, duration, r.Summary.InputSize, r.Summary.OutputSize)
+	for i, res := range r.Resources {
+		duration := time.Duration(res.DurationMs * float64(time.Millisecond))
+		r.Resources[i].Display = newDisplayReport(format, duration, res.Compilation.TotalInputSize, res.Compilation.TotalOutputSize)
+	}
+}
+
+// newDisplayReport formats the sizes and duration of a build or resource
+func newDisplayReport(format units.Format, duration time.Duration, input, output int64) *DisplayReport {
+	return &DisplayReport{
+		Duration:         format.Duration(duration),
+		InputSize:        format.Size(input),
+		OutputSize:       format.Size(output),
+		CompressionRatio: format.Number(ratio(input, output)*100, 1) + "%",
+	}
+}
+
+// newResourceReport converts the result of a single resource
+func newResourceReport(res bundler.ResourceResult) ResourceReport {
+	report := ResourceReport{
+		Name:        resourceName(res),
+		MetaXMLPath: res.MetaXMLPath,
+		OutputDir:   res.Compile.OutputDir,
+		Archive:     res.Archive,
+		MergeMode:   res.Compile.MergeMode,
+		Unchanged:   res.Unchanged,
+		Options: OptionsReport{
+			ObfuscationLevel:         int(res.Options.ObfuscationLevel),
+			StripDebug:               res.Options.StripDebug,
+			SuppressDecompileWarning: res.Options.SuppressDecompileWarning,
+		},
+		DurationMs:  milliseconds(res.Duration),
+		Error:       errorString(res.Error),
+		Affected:    res.Affected,
+		Compilation: newCompilationReport(res.Compile.Compilation),
+		FileCopy:    newFileCopyReport(res.Compile.FileCopy),
+	}
+
+	if res.Resource != nil {
+		if info := res.Resource.Meta.Info; info != (resource.Info{}) {
+			report.Info = &InfoReport{
+				Name:        info.Name,
+				Author:      info.Author,
+				Version:     info.Version,
+				Description: info.Description,
+				Type:        info.Type,
+				Gamemodes:   info.Gamemodes,
+			}
+		}
+		// A README that cannot be read is left out, it never fails the report
+		if readme, ok, err := res.Resource.Readme(); err == nil && ok {
+			report.Readme = &ReadmeReport{File: readme.File, Text: readme.Text, Truncated: readme.Truncated}
+		}
+	}
+	return report
+}
+
+// newCompilationReport converts a batch of compilation results
+func newCompilationReport(batch resource.BatchCompilationResult) CompilationReport {
+	report := CompilationReport{
+		TotalFiles:       batch.TotalFiles,
+		SuccessCount:     batch.SuccessCount,
+		ErrorCount:       batch.ErrorCount,
+		TotalInputSize:   batch.TotalInputSize,
+		TotalOutputSize:  batch.TotalOutputSize,
+		CompressionRatio: ratio(batch.TotalInputSize, batch.TotalOutputSize),
+		TotalTimeMs:      milliseconds(batch.TotalTime),
+		Results:          make([]ScriptReport, 0, len(batch.Results)),
+	}
+
+	for _, result := range batch.Results {
+		report.Results = append(report.Results, newScriptReport(result))
+	}
+	return report
+}
+
+// newScriptReport converts a single compilation result
+func newScriptReport(result compiler.CompilationResult) ScriptReport {
+	return ScriptReport{
+		InputFile:        result.InputFile,
+		OutputFile:       result.OutputFile,
+		Success:          result.Success,
+		Error:            errorString(result.Error),
+		CompileTimeMs:    milliseconds(result.CompileTime),
+		InputSize:        result.InputSize,
+		OutputSize:       result.OutputSize,
+		CompressionRatio: result.CompressionRatio(),
+		Retries:          result.Retries,
+	}
+}
+
+// newFileCopyReport converts a batch of file copy results
+func newFileCopyReport(batch resource.FileCopyBatchResult) FileCopyReport {
+	report := FileCopyReport{
+		TotalFiles:   batch.TotalFiles,
+		SuccessCount: batch.SuccessCount,
+		ErrorCount:   batch.ErrorCount,
+		TotalSize:    batch.TotalSize,
+		Results:      make([]FileReport, 0, len(batch.Results)),
+	}
+
+	for _, result := range batch.Results {
+		report.Results = append(report.Results, FileReport{
+			RelativePath: result.RelativePath,
+			OutputPath:   result.OutputPath,
+			Success:      result.Success,
+			Error:        errorString(result.Error),
+			Size:         result.Size,
+		})
+	}
+	return report
+}
+
+// NewRecommendations converts the recommendations of the size advisor
+func NewRecommendations(recommendations []advisor.Recommendation) []RecommendationReport {
+	var reports []RecommendationReport
+	for _, rec := range recommendations {
+		reports = append(reports, RecommendationReport{
+			Resource:    rec.Resource,
+			Option:      rec.Option,
+			Current:     rec.Current,
+			Suggested:   rec.Suggested,
+			SizeChange:  rec.SizeChange,
+			SampleFiles: rec.SampleFiles,
+			Tradeoff:    rec.Tradeoff,
+			Message:     rec.Message(),
+		})
+	}
+	return reports
+}
+
+// WriteJSON writes the report as indented JSON to path, or to stdout when path is "-"
+func WriteJSON(report Report, path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return writeFile(append(data, '\n'), path)
+}
+
+// writeFile writes a report to path, creating its directory, or to stdout when path is "-"
+func writeFile(data []byte, path string) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ratio returns output/input, or 0 when either size is unknown
+func ratio(input, output int64) float64 {
+	if input > 0 && output > 0 {
+		return float64(output) / float64(input)
+	}
+	return 0
+}
+
+// resourceName returns the name of a built resource, falling back to its meta.xml directory
+func resourceName(res bundler.ResourceResult) string {
+	if res.Resource != nil {
+		return res.Resource.Name
+	}
+	return filepath.Base(filepath.Dir(res.MetaXMLPath))
+}
+
+// errorString returns the error message, or an empty string for nil errors
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+)
+
+func TestNewAndWriteJSON(t *testing.T) {
+	result := bundler.BuildResult{
+		InputPath: "resources",
+		StartedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Duration:  2 * time.Second,
+		Resources: []bundler.ResourceResult{
+			{
+				MetaXMLPath: filepath.Join("resources", "race", "meta.xml"),
+				Compile: resource.CompileResult{
+					Compilation: resource.BatchCompilationResult{
+						Results: []compiler.CompilationResult{
+							{InputFile: "client.lua", Success: true, InputSize: 100, OutputSize: 50},
+							{InputFile: "server.lua", Error: errors.New("syntax error")},
+						},
+						TotalFiles:      2,
+						SuccessCount:    1,
+						ErrorCount:      1,
+						TotalInputSize:  100,
+						TotalOutputSize: 50,
+					},
+					FileCopy: resource.FileCopyBatchResult{
+						Results:      []resource.FileCopyResult{{RelativePath: "logo.png", Success: true, Size: 10}},
+						TotalFiles:   1,
+						SuccessCount: 1,
+						TotalSize:    10,
+					},
+				},
+				Error: errors.New("compilation completed with 1 errors"),
+			},
+			{
+				MetaXMLPath: filepath.Join("resources", "admin", "meta.xml"),
+			},
+		},
+	}
+
+	report := New(result, "1.2.3")
+
+	if report.Summary.Resources != 2 || report.Summary.Failed != 1 || report.Summary.Succeeded != 1 {
+		t.Errorf("Unexpected resource counts: %+v", report.Summary)
+	}
+	if report.Summary.ScriptsCompiled != 1 || report.Summary.ScriptErrors != 1 || report.Summary.FilesCopied != 1 {
+		t.Errorf("Unexpected file counts: %+v", report.Summary)
+	}
+	if report.Summary.CompressionRatio != 0.5 {
+		t.Errorf("Expected compression ratio 0.5, got %v", report.Summary.CompressionRatio)
+	}
+	if report.Resources[0].Name != "race" {
+		t.Errorf("Expected resource name to fall back to the directory name, got %q", report.Resources[0].Name)
+	}
+	if report.Resources[0].Compilation.Results[1].Error != "syntax error" {
+		t.Errorf("Expected script error to be serialized, got %q", report.Resources[0].Compilation.Results[1].Error)
+	}
+
+	path := filepath.Join(t.TempDir(), "out", "report.json")
+	if err := WriteJSON(report, path); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	if decoded.Version != "1.2.3" || len(decoded.Resources) != 2 {
+		t.Errorf("Unexpected decoded report: %+v", decoded)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "race")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta := `<meta><info author="Jane" version="2.1" type="gamemode" description="Races &amp; more"/><script src="server.lua"/></meta>`
+	if err := os.WriteFile(filepath.Join(dir, "meta.xml"), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme.MD"), []byte("# Race\n<b>Start</b> with /race\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resource.NewResource(filepath.Join(dir, "meta.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := New(bundler.BuildResult{Resources: []bundler.ResourceResult{
+		{MetaXMLPath: res.MetaXMLPath, Resource: res},
+		{MetaXMLPath: filepath.Join("resources", "admin", "meta.xml")},
+	}}, "1.2.3")
+	race := report.Resources[0]
+	if race.Info == nil || race.Info.Author != "Jane" || race.Info.Version != "2.1" || race.Info.Type != "gamemode" || race.Info.Description != "Races & more" {
+		t.Errorf("Unexpected info: %+v", race.Info)
+	}
+	if race.Readme == nil || race.Readme.File != "readme.MD" || !strings.Contains(race.Readme.Text, "/race") {
+		t.Errorf("Unexpected readme: %+v", race.Readme)
+	}
+	if report.Resources[1].Info != nil || report.Resources[1].Readme != nil {
+		t.Errorf("Expected no inventory without a parsed resource: %+v", report.Resources[1])
+	}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := WriteHTML(report, path); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"<td>Jane</td>", "Races &amp; more", "<summary>readme.MD</summary>", "&lt;b&gt;Start&lt;/b&gt;", "No &lt;info&gt; in meta.xml"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in the HTML report:\n%s", expected, data)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package resource
 import (
 	"encoding/xml"
 	"path/filepath"
+	"regexp"
 )
 
 // ReferenceType represents how a file was referenced in meta.xml
@@ -16,44 +17,87 @@ const (
 	ReferenceTypeHTML
 )
 
-// Meta represents the root meta.xml structure with only file-related fields
+// Meta represents the root meta.xml structure with only file-related fields, includes and exports
 type Meta struct {
-	XMLName xml.Name `xml:"meta"`
-	Scripts []Script `xml:"script"`
-	Maps    []Map    `xml:"map"`
-	Files   []File   `xml:"file"`
-	Configs []Config `xml:"config"`
-	HTMLs   []HTML   `xml:"html"`
+	XMLName xml.Name  `xml:"meta"`
+	Info    Info      `xml:"info"`
+	Scripts []Script  `xml:"script"`
+	Maps    []Map     `xml:"map"`
+	Files   []File    `xml:"file"`
+	Configs []Config  `xml:"config"`
+	HTMLs   []HTML    `xml:"html"`
+	Exports []Export  `xml:"export"`
+	Include []Include `xml:"include"`
+	OOP     *string   `xml:"oop"` // "true" enables the object-oriented API, nil when meta.xml has no <oop>
 }
 
-// Script represents a script file reference
+// Info is what a resource tells about itself in its <info> element
+type Info struct {
+	Name        string `xml:"name,attr"`
+	Author      string `xml:"author,attr"`
+	Version     string `xml:"version,attr"`
+	Description string `xml:"description,attr"`
+	Type        string `xml:"type,attr"`      // "gamemode", "script", "map" or "misc"
+	Gamemodes   string `xml:"gamemodes,attr"` // Gamemodes a map is for, comma-separated
+}
+
+// Script represents a script file reference. Attributes left out of meta.xml are empty.
 type Script struct {
-	Src  string `xml:"src,attr"`  // The file name of the source code
-	Type string `xml:"type,attr"` // "client", "server" or "shared"
+	Src      string `xml:"src,attr"`      // The file name of the source code
+	Type     string `xml:"type,attr"`     // "client", "server" or "shared"
+	Cache    string `xml:"cache,attr"`    // "false" keeps a client script out of the client's disk cache
+	Validate string `xml:"validate,attr"` // "false" skips the validation of the script
 }
 
 // Map represents a map file reference
 type Map struct {
-	Src string `xml:"src,attr"` // .map file name (can be path too)
+	Src       string `xml:"src,attr"`       // .map file name (can be path too)
+	Dimension string `xml:"dimension,attr"` // Dimension the map is loaded in, 0 when empty
 }
 
 // File represents a client-side file reference
 type File struct {
-	Src string `xml:"src,attr"` // Client-side file name (can be path too)
+	Src      string `xml:"src,attr"`      // Client-side file name (can be path too)
+	Download string `xml:"download,attr"` // "false" when the client downloads the file on demand
 }
 
 // Config represents a config file reference
 type Config struct {
-	Src string `xml:"src,attr"` // The file name of the config file
+	Src  string `xml:"src,attr"`  // The file name of the config file
+	Type string `xml:"type,attr"` // "client" or "server", server when empty
 }
 
 // HTML represents an HTML file reference
 type HTML struct {
-	Src string `xml:"src,attr"` // The filename for the HTTP file (can be a path)
+	Src     string `xml:"src,attr"`     // The filename for the HTTP file (can be a path)
+	Default string `xml:"default,attr"` // "true" for the page served at the resource root
+	Raw     string `xml:"raw,attr"`     // "true" for files served as they are, without parsing Lua blocks
+}
+
+// Include is another resource the resource needs, MTA starts it first
+type Include struct {
+	Resource   string `xml:"resource,attr"`   // Name of the included resource
+	MinVersion string `xml:"minversion,attr"` // Oldest accepted version of the included resource
+	MaxVersion string `xml:"maxversion,attr"` // Newest accepted version of the included resource
+}
+
+// Export represents a function exported to other resources
+type Export struct {
+	Function string `xml:"function,attr"` // Name of the exported function
+	Type     string `xml:"type,attr"`     // "client", "server" or "shared", server when empty
+	HTTP     string `xml:"http,attr"`     // "true" when the function can be called over HTTP
 }
 
 type AbsPath string
 
+// urlRegex matches srcs starting with a URL scheme, such as https://
+var urlRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+
+// IsURL reports whether src is a URL rather than a path inside the resource
+func IsURL(src string) bool {
+	return urlRegex.MatchString(src)
+}
+
 // FileReference represents a file reference with its full path and reference type
 type FileReference struct {
 	FullPath      string        // Absolute file path
@@ -68,8 +112,11 @@ func GetAllFiles(meta Meta, metaXMLPath string) ([]FileReference, error) {
 	// Get the directory containing the meta.xml file
 	baseDir := filepath.Dir(metaXMLPath)
 
-	// Process Script files
+	// Process Script files, scripts with a URL src are not part of the resource
 	for _, script := range meta.Scripts {
+		if IsURL(script.Src) {
+			continue
+		}
 		fullPath := filepath.Join(baseDir, script.Src)
 		files = append(files, FileReference{
 			FullPath:      fullPath,
@@ -0,0 +1,299 @@
+package resource
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// metaDocument is a meta.xml split into tokens that keep their source text. Elements can be
+// edited, removed and added while comments, CDATA sections, whitespace, attribute quoting and
+// unknown tags are written back exactly as they were.
+type metaDocument struct {
+	tokens []*metaToken
+}
+
+// metaToken is a token of a metaDocument
+type metaToken struct {
+	text    string            // Source text, edits of start tags change only the edited attribute
+	start   *xml.StartElement // Start tag of an element, nil for other tokens
+	end     bool              // End tag of an element, with empty text for a self-closing element
+	depth   int               // Number of enclosing elements, 0 for the root element
+	removed bool              // The token is not written
+}
+
+// parseMetaDocument splits the content of a meta.xml into tokens
+func parseMetaDocument(content string) (*metaDocument, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	doc := &metaDocument{}
+	depth, offset := 0, 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		next := int(decoder.InputOffset())
+		t := &metaToken{text: content[offset:next], depth: depth}
+		offset = next
+		switch token := token.(type) {
+		case xml.StartElement:
+			start := token.Copy()
+			t.start = &start
+			depth++
+		case xml.EndElement:
+			depth--
+			t.end, t.depth = true, depth
+		}
+		doc.tokens = append(doc.tokens, t)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unclosed element")
+	}
+	if offset < len(content) {
+		doc.tokens = append(doc.tokens, &metaToken{text: content[offset:]})
+	}
+	return doc, nil
+}
+
+// String returns the content of the document
+func (d *metaDocument) String() string {
+	var b strings.Builder
+	for _, t := range d.tokens {
+		if !t.removed {
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+// root returns the index of the start tag of the root element, or -1
+func (d *metaDocument) root() int {
+	for i, t := range d.tokens {
+		if t.start != nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// children returns the indices of the start tags of the child elements of the root called name
+func (d *metaDocument) children(name string) []int {
+	var indices []int
+	for i, t := range d.tokens {
+		if t.start != nil && !t.removed && t.depth == 1 && t.start.Name.Local == name {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// endOf returns the index of the end tag of the element starting at i
+func (d *metaDocument) endOf(i int) int {
+	for j := i + 1; j < len(d.tokens); j++ {
+		if d.tokens[j].end && d.tokens[j].depth == d.tokens[i].depth {
+			return j
+		}
+	}
+	return len(d.tokens) - 1
+}
+
+// attr returns the value of the attribute name of a start tag, or an empty string
+func (t *metaToken) attr(name string) string {
+	for _, a := range t.start.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// setAttr sets the attribute name of a start tag. An existing value is replaced in place, keeping
+// its quotes, and a missing attribute is added after the others.
+func (t *metaToken) setAttr(name, value string) {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(value))
+
+	for i, a := range t.start.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			t.start.Attr[i].Value = value
+			if start, end, ok := attrValueSpan(t.text, name); ok {
+				t.text = t.text[:start] + escaped.String() + t.text[end:]
+			}
+			return
+		}
+	}
+
+	t.start.Attr = append(t.start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+	end := strings.TrimSuffix(strings.TrimSuffix(t.text, ">"), "/")
+	end = strings.TrimRight(end, " \t\r\n")
+	t.text = end + " " + name + `="` + escaped.String() + `"` + t.text[len(end):]
+}
+
+// attrValueSpan returns the start and end offsets of the value of the attribute name in the
+// source text of a well-formed start tag, without its quotes
+func attrValueSpan(tag, name string) (int, int, bool) {
+	i := strings.IndexAny(tag, " \t\r\n")
+	for i >= 0 && i < len(tag) {
+		for i < len(tag) && strings.ContainsRune(" \t\r\n", rune(tag[i])) {
+			i++
+		}
+		eq := strings.IndexByte(tag[i:], '=')
+		if eq < 0 {
+			return 0, 0, false
+		}
+		attr := strings.TrimRight(tag[i:i+eq], " \t\r\n")
+		i += eq + 1
+		for i < len(tag) && strings.ContainsRune(" \t\r\n", rune(tag[i])) {
+			i++
+		}
+		if i >= len(tag) {
+			return 0, 0, false
+		}
+		quote := tag[i]
+		closing := strings.IndexByte(tag[i+1:], quote)
+		if closing < 0 {
+			return 0, 0, false
+		}
+		start, end := i+1, i+1+closing
+		if attr == name {
+			return start, end, true
+		}
+		i = end + 1
+	}
+	return 0, 0, false
+}
+
+// removeElement removes the element starting at i, together with the indentation of its line
+func (d *metaDocument) removeElement(i int) {
+	end := d.endOf(i)
+	for j := i; j <= end; j++ {
+		d.tokens[j].removed = true
+	}
+	for j := i - 1; j >= 0; j-- {
+		if d.tokens[j].removed {
+			continue
+		}
+		if prev := d.tokens[j]; isIndentation(prev) {
+			prev.text = strings.TrimSuffix(prev.text[:strings.LastIndex(prev.text, "\n")], "\r")
+		}
+		return
+	}
+}
+
+// appendChild adds the element tag as the last child of the root element, on its own line and
+// indented like the other children. A self-closing root element gets an end tag.
+func (d *metaDocument) appendChild(tag string) error {
+	fragment, err := parseFragment(tag)
+	if err != nil {
+		return err
+	}
+	root := d.root()
+	if root < 0 {
+		return fmt.Errorf("meta.xml has no root element")
+	}
+	end := d.openRoot(root)
+
+	// Inserted before the line break ahead of the end tag of the root, so it stays on its own line
+	position := end
+	for position-1 > root && d.tokens[position-1].removed {
+		position--
+	}
+	if position-1 > root && isIndentation(d.tokens[position-1]) {
+		position--
+	}
+	d.insert(position, append([]*metaToken{{text: d.indentation(), depth: 1}}, fragment...))
+	return nil
+}
+
+// insertChild adds the element tag as a child of the root element before the child starting at
+// i, on its own line and indented like the other children
+func (d *metaDocument) insertChild(i int, tag string) error {
+	fragment, err := parseFragment(tag)
+	if err != nil {
+		return err
+	}
+	d.insert(i, append(fragment, &metaToken{text: d.indentation(), depth: 1}))
+	return nil
+}
+
+// child returns the index of the start tag of the first child element of the root called name,
+// adding an empty one as the first child when there is none
+func (d *metaDocument) child(name string) (int, error) {
+	if children := d.children(name); len(children) > 0 {
+		return children[0], nil
+	}
+	fragment, err := parseFragment("<" + name + " />")
+	if err != nil {
+		return -1, err
+	}
+	root := d.root()
+	if root < 0 {
+		return -1, fmt.Errorf("meta.xml has no root element")
+	}
+	d.openRoot(root)
+	d.insert(root+1, append([]*metaToken{{text: d.indentation(), depth: 1}}, fragment...))
+	return root + 2, nil
+}
+
+// openRoot gives a self-closing root element starting at root an end tag, and returns the index
+// of the end tag
+func (d *metaDocument) openRoot(root int) int {
+	end := d.endOf(root)
+	if d.tokens[end].text == "" {
+		open := d.tokens[root]
+		open.text = strings.TrimRight(strings.TrimSuffix(open.text, "/>"), " \t\r\n") + ">"
+		d.tokens[end].text = "\n</" + qualifiedName(open.start.Name) + ">"
+	}
+	return end
+}
+
+// insert adds tokens to the document before the token at i
+func (d *metaDocument) insert(i int, tokens []*metaToken) {
+	d.tokens = append(d.tokens[:i], append(tokens, d.tokens[i:]...)...)
+}
+
+// parseFragment splits the element tag into tokens for a child of the root element
+func parseFragment(tag string) ([]*metaToken, error) {
+	fragment, err := parseMetaDocument(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid element %s: %v", tag, err)
+	}
+	for _, t := range fragment.tokens {
+		t.depth++
+	}
+	return fragment.tokens, nil
+}
+
+// indentation returns the line break and indentation ahead of the child elements of the root,
+// a line feed and four spaces by default
+func (d *metaDocument) indentation() string {
+	for i, t := range d.tokens {
+		if t.start != nil && t.depth == 1 && i > 0 && isIndentation(d.tokens[i-1]) {
+			text := d.tokens[i-1].text
+			lineBreak := strings.LastIndex(text, "\n")
+			if lineBreak > 0 && text[lineBreak-1] == '\r' {
+				lineBreak--
+			}
+			return text[lineBreak:]
+		}
+	}
+	return "\n    "
+}
+
+// isIndentation reports whether t is whitespace ending a line break
+func isIndentation(t *metaToken) bool {
+	return t.start == nil && !t.end && strings.Contains(t.text, "\n") && strings.TrimSpace(t.text) == ""
+}
+
+// qualifiedName returns a name with its namespace prefix, as written in the source
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
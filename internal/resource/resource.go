@@ -15,8 +15,26 @@ type Resource struct {
 	Name        string          // Resource name (derived from directory name)
 	Meta        Meta            // Parsed meta.xml structure
 	Files       []FileReference // All file references from meta.xml
+	Globs       ScriptGlobs     // Script srcs with wildcards and the srcs they expand to, nil without any
+	Verbatim    map[string]bool // Script srcs copied as source instead of compiled, slash-separated
+	Unmerged    map[string]bool // Script srcs compiled on their own in merge mode instead of into the bundles, slash-separated
+	SkipAssets  bool            // Only meta.xml and scripts are written, non-script files are not copied
+	LinkAssets  bool            // Non-script files are hardlinked (or symlinked) into the output instead of copied
+	NoCache     bool            // Client and shared script tags get cache="false", so clients do not keep them on disk
+	RegexMeta   bool            // meta.xml is rewritten with regular expressions instead of the token editor
+	MetaOrder   bool            // Merged bundles keep the meta.xml order of scripts instead of appending shared scripts last
+	Concat      bool            // Merged bundles are compiled from their scripts concatenated into one source
+	Isolate     bool            // Scripts of concatenated bundles run through pcall, an error in one does not stop the others
+	BundleName  string          // Path of the merged bundles with {type} for client or server, DefaultBundleName when empty
+	Transform   SourceTransform // Rewrites the sources of scripts before they are compiled, nil compiles the files as they are
+	Info        []InfoAttr      // Attributes set on the <info> tag of the output meta.xml
+	MinVersion  string          // MTA version the compiled scripts need, the output <min_mta_version> is raised to it, none when empty
+	Banner      string          // Written at the top of the output meta.xml and merged bundles, {resource} and {version} replaced, none when empty
 }
 
+// DefaultBundleName is the path of the merged bundles when none is configured
+const DefaultBundleName = "{type}.luac"
+
 // NewResource creates a new Resource from a meta.xml file path
 func NewResource(metaXMLPath string) (*Resource, error) {
 	// Read the meta.xml file
@@ -42,11 +60,19 @@ func NewResource(metaXMLPath string) (*Resource, error) {
 	baseDir := filepath.Dir(absPath)
 	resourceName := filepath.Base(baseDir)
 
+	// Script srcs with wildcards are replaced by the scripts they match
+	scripts, globs, err := expandScripts(meta.Scripts, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	meta.Scripts = scripts
+
 	resource := &Resource{
 		MetaXMLPath: absPath,
 		BaseDir:     baseDir,
 		Name:        resourceName,
 		Meta:        meta,
+		Globs:       globs,
 	}
 
 	// Get all file references
@@ -69,10 +95,58 @@ func (r *Resource) GetLuaFiles() []FileReference {
 	return luaFiles
 }
 
-// GetLuaFilesByType returns Lua script files grouped by type (client, server, shared)
+// BundlePath returns the slash-separated path of the merged bundle of kind (client or server)
+// in the output resource
+func (r *Resource) BundlePath(kind string) string {
+	name := r.BundleName
+	if name == "" {
+		name = DefaultBundleName
+	}
+	return strings.ReplaceAll(name, "{type}", kind)
+}
+
+// IsVerbatim reports whether the script src is copied as source instead of compiled
+func (r *Resource) IsVerbatim(src string) bool {
+	return r.Verbatim[filepath.ToSlash(src)]
+}
+
+// IsUnmerged reports whether the script src keeps its own .luac file in merge mode
+func (r *Resource) IsUnmerged(src string) bool {
+	return r.Unmerged[filepath.ToSlash(src)]
+}
+
+// isMerged reports whether the script src is compiled into the merged bundles
+func (r *Resource) isMerged(src string) bool {
+	return strings.ToLower(filepath.Ext(src)) == ".lua" && !IsURL(src) && !r.IsVerbatim(src) && !r.IsUnmerged(src)
+}
+
+// unmergedLuaFiles returns the compiled Lua script files left out of the merged bundles
+func (r *Resource) unmergedLuaFiles() []FileReference {
+	var luaFiles []FileReference
+	for _, fileRef := range r.compiledLuaFiles() {
+		if r.IsUnmerged(fileRef.RelativePath) {
+			luaFiles = append(luaFiles, fileRef)
+		}
+	}
+	return luaFiles
+}
+
+// compiledLuaFiles returns the Lua script files that are compiled, leaving out verbatim scripts
+func (r *Resource) compiledLuaFiles() []FileReference {
+	var luaFiles []FileReference
+	for _, fileRef := range r.GetLuaFiles() {
+		if !r.IsVerbatim(fileRef.RelativePath) {
+			luaFiles = append(luaFiles, fileRef)
+		}
+	}
+	return luaFiles
+}
+
+// GetLuaFilesByType returns the Lua script files merged into the bundles grouped by type
+// (client, server, shared), leaving out verbatim and unmerged scripts
 func (r *Resource) GetLuaFilesByType() (client, server, shared []FileReference) {
 	for _, script := range r.Meta.Scripts {
-		if strings.ToLower(filepath.Ext(script.Src)) == ".lua" {
+		if r.isMerged(script.Src) {
 			fileRef := FileReference{
 				FullPath:      filepath.Join(r.BaseDir, script.Src),
 				ReferenceType: ReferenceTypeScript,
@@ -94,3 +168,44 @@ func (r *Resource) GetLuaFilesByType() (client, server, shared []FileReference)
 	}
 	return client, server, shared
 }
+
+// GetMergedLuaFiles returns the compiled Lua script files merged into the client and server
+// bundles. Shared scripts go into both, after the client or server scripts, or in their
+// meta.xml position with MetaOrder, so scripts run in the order the resource declares them.
+func (r *Resource) GetMergedLuaFiles() (client, server []FileReference) {
+	clientFiles, serverFiles, sharedFiles := r.GetLuaFilesByType()
+	if !r.MetaOrder {
+		return append(clientFiles, sharedFiles...), append(serverFiles, sharedFiles...)
+	}
+
+	for _, script := range r.Meta.Scripts {
+		if !r.isMerged(script.Src) {
+			continue
+		}
+		fileRef := FileReference{
+			FullPath:      filepath.Join(r.BaseDir, script.Src),
+			ReferenceType: ReferenceTypeScript,
+			RelativePath:  script.Src,
+		}
+		switch strings.ToLower(script.Type) {
+		case "client":
+			client = append(client, fileRef)
+		case "shared":
+			client = append(client, fileRef)
+			server = append(server, fileRef)
+		default:
+			server = append(server, fileRef)
+		}
+	}
+	return client, server
+}
+
+// FindLuaFile returns the Lua script reference whose absolute path matches fullPath
+func (r *Resource) FindLuaFile(fullPath string) (FileReference, bool) {
+	for _, fileRef := range r.GetLuaFiles() {
+		if filepath.Clean(fileRef.FullPath) == filepath.Clean(fullPath) {
+			return fileRef, true
+		}
+	}
+	return FileReference{}, false
+}
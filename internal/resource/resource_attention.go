@@ -0,0 +1,124 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Attention codes. They are stable, so migrations can be triaged by code across builds.
+const (
+	AttentionScriptTypeDefaulted = "script-type-defaulted" // A script without a valid type was merged as a server script
+	AttentionURLScriptSkipped    = "url-script-skipped"    // A script with a URL src was left as it is instead of compiled
+	AttentionMetaElementDropped  = "meta-element-dropped"  // A meta.xml element of a pack member is not in the pack
+	AttentionObfuscationLowered  = "obfuscation-lowered"   // A resource is built with a lower obfuscation level than the build
+	AttentionExportNotMerged     = "export-not-merged"     // An exported function is only defined by a script left out of the merge
+)
+
+// Attention is a decision the build made on its own that a person should review, such as a
+// meta.xml entry it could not carry over. Unlike problems, they do not fail the build.
+type Attention struct {
+	Code     string // One of the Attention codes
+	Resource string // Resource name
+	Src      string // Script or meta.xml element the case is about, empty for the whole resource
+	Message  string
+}
+
+// String returns the case as a single line
+func (a Attention) String() string {
+	if a.Src == "" {
+		return "[" + a.Code + "] " + a.Resource + ": " + a.Message
+	}
+	return "[" + a.Code + "] " + a.Resource + "/" + a.Src + ": " + a.Message
+}
+
+// packedElements are the meta.xml elements a pack carries over from its members. The info
+// element is replaced by the pack's own.
+var packedElements = map[string]bool{
+	"info": true, "oop": true, "script": true, "file": true, "map": true, "config": true,
+	"html": true, "export": true, "aclrequest": true, "include": true,
+}
+
+// Attention returns the cases of the resource that need a decision: scripts with a URL src,
+// and in merge mode scripts whose type is missing or unknown, merged as server scripts
+func (r *Resource) Attention(mergeMode bool) []Attention {
+	var cases []Attention
+	for _, script := range r.Meta.Scripts {
+		if IsURL(script.Src) {
+			cases = append(cases, Attention{Code: AttentionURLScriptSkipped, Resource: r.Name, Src: script.Src,
+				Message: "script src is a URL, its tag is kept as it is and nothing is compiled"})
+			continue
+		}
+		if !mergeMode || !r.isMerged(script.Src) {
+			continue
+		}
+		switch strings.ToLower(script.Type) {
+		case "client", "server", "shared":
+		case "":
+			cases = append(cases, Attention{Code: AttentionScriptTypeDefaulted, Resource: r.Name, Src: script.Src,
+				Message: "script has no type, merged into " + r.BundlePath("server")})
+		default:
+			cases = append(cases, Attention{Code: AttentionScriptTypeDefaulted, Resource: r.Name, Src: script.Src,
+				Message: fmt.Sprintf("unknown script type %q, merged into %s", script.Type, r.BundlePath("server"))})
+		}
+	}
+	return cases
+}
+
+// PackAttention returns the cases of the resource as a pack member: its merge mode cases,
+// and the meta.xml elements the pack does not carry over, such as settings
+func (r *Resource) PackAttention() ([]Attention, error) {
+	cases := r.Attention(true)
+
+	data, err := os.ReadFile(r.MetaXMLPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meta.xml of %s: %v", r.Name, err)
+	}
+	dropped, err := droppedElements(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse meta.xml of %s: %v", r.Name, err)
+	}
+	for _, name := range dropped {
+		cases = append(cases, Attention{Code: AttentionMetaElementDropped, Resource: r.Name, Src: "<" + name + ">",
+			Message: "element is not carried over to the pack"})
+	}
+	return cases, nil
+}
+
+// droppedElements returns the names of the top-level meta.xml elements that are not packed
+func droppedElements(data []byte) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	seen := make(map[string]bool)
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 1 && !packedElements[strings.ToLower(t.Name.Local)] {
+				seen[t.Name.Local] = true
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,75 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttention(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta := `<meta>
+	<info name="Shop" />
+	<script src="client.lua" type="client"/>
+	<script src="server.lua"/>
+	<script src="legacy.lua" type="clinet"/>
+	<script src="https://example.com/lib.lua" type="client"/>
+	<settings><setting name="*price" value="5"/></settings>
+	<min_mta_version client="1.6.0"/>
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetLuaFiles()) != 3 {
+		t.Errorf("Expected the URL script to be left out of the files, got %v", res.GetLuaFiles())
+	}
+
+	describe := func(cases []Attention) string {
+		var lines []string
+		for _, c := range cases {
+			lines = append(lines, c.String())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	if got, want := describe(res.Attention(false)), "[url-script-skipped] shop/https://example.com/lib.lua: script src is a URL, its tag is kept as it is and nothing is compiled"; got != want {
+		t.Errorf("Unexpected cases without merge mode:\n%s", got)
+	}
+
+	got := describe(res.Attention(true))
+	for _, want := range []string{
+		"[script-type-defaulted] shop/server.lua: script has no type, merged into server.luac",
+		`[script-type-defaulted] shop/legacy.lua: unknown script type "clinet", merged into server.luac`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in merge mode cases:\n%s", want, got)
+		}
+	}
+
+	cases, err := res.PackAttention()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = describe(cases)
+	for _, want := range []string{
+		"[meta-element-dropped] shop/<min_mta_version>: element is not carried over to the pack",
+		"[meta-element-dropped] shop/<settings>: element is not carried over to the pack",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in pack cases:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<setting>") || strings.Contains(got, "<info>") {
+		t.Errorf("Expected only dropped top-level elements in pack cases:\n%s", got)
+	}
+}
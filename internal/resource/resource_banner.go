@@ -0,0 +1,82 @@
+package resource
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bytecode"
+)
+
+// expand returns text, a banner or an <info> attribute value, with {resource} replaced by the
+// name of r and {version} by the version of its <info> tag
+func (r *Resource) expand(text string) string {
+	return strings.NewReplacer("{resource}", r.Name, "{version}", r.Meta.Info.Version).Replace(text)
+}
+
+// bannerComment adds banner as a comment block at the top of a meta.xml content, after its XML
+// declaration if any. XML comments cannot hold "--", so dashes in pairs are spaced out.
+func bannerComment(content, banner string) string {
+	if banner == "" {
+		return content
+	}
+	var comment strings.Builder
+	comment.WriteString("<!--\n")
+	for _, line := range strings.Split(strings.TrimRight(banner, "\r\n"), "\n") {
+		for strings.Contains(line, "--") {
+			line = strings.ReplaceAll(line, "--", "- -")
+		}
+		comment.WriteString(strings.TrimRight("    "+strings.TrimRight(line, "\r"), " \t") + "\n")
+	}
+	comment.WriteString("-->\n")
+
+	position, separator := 0, ""
+	if strings.HasPrefix(content, string(utf8BOM)) {
+		position = len(utf8BOM)
+	}
+	if strings.HasPrefix(content[position:], "<?xml") {
+		if end := strings.Index(content, "?>"); end >= 0 {
+			position = end + len("?>")
+			switch {
+			case strings.HasPrefix(content[position:], "\r\n"):
+				position += len("\r\n")
+			case strings.HasPrefix(content[position:], "\n"):
+				position++
+			default:
+				separator = "\n"
+			}
+		}
+	}
+	return content[:position] + separator + comment.String() + content[position:]
+}
+
+// bannerSources adds banner to the first script of a merged bundle that is not compiled, as a
+// string the compiled bundle keeps: comments do not survive luac_mta. It goes on the first line,
+// after a byte order mark or a line starting with #, so the lines of the script do not change.
+func bannerSources(sources [][]byte, banner string) {
+	if banner == "" {
+		return
+	}
+	statement := []byte("do local _ = " + luaQuote(banner) + " end ")
+	for i, src := range sources {
+		if kind := bytecode.DetectKind(src); kind == bytecode.KindCompiled || kind == bytecode.KindObfuscated {
+			continue
+		}
+		position := 0
+		if bytes.HasPrefix(src, utf8BOM) {
+			position = len(utf8BOM)
+		}
+		if bytes.HasPrefix(src[position:], []byte("#")) {
+			end := bytes.IndexByte(src[position:], '\n')
+			if end < 0 {
+				// Nothing but the skipped line
+				continue
+			}
+			position += end + 1
+		}
+		out := make([]byte, 0, len(src)+len(statement))
+		out = append(out, src[:position]...)
+		out = append(out, statement...)
+		sources[i] = append(out, src[position:]...)
+		return
+	}
+}
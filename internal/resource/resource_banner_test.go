@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"testing"
+)
+
+func TestBannerComment(t *testing.T) {
+	banner := "Copyright (c) Example -- all rights reserved\nContact: admin@example.com\n"
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			"No declaration",
+			"<meta>\n</meta>\n",
+			"<!--\n    Copyright (c) Example - - all rights reserved\n    Contact: admin@example.com\n-->\n<meta>\n</meta>\n",
+		},
+		{
+			"After declaration",
+			"<?xml version=\"1.0\"?>\r\n<meta/>",
+			"<?xml version=\"1.0\"?>\r\n<!--\n    Copyright (c) Example - - all rights reserved\n    Contact: admin@example.com\n-->\n<meta/>",
+		},
+		{
+			"Declaration on the line of meta",
+			"\xEF\xBB\xBF<?xml version=\"1.0\"?><meta/>",
+			"\xEF\xBB\xBF<?xml version=\"1.0\"?>\n<!--\n    Copyright (c) Example - - all rights reserved\n    Contact: admin@example.com\n-->\n<meta/>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bannerComment(tt.content, banner); got != tt.expected {
+				t.Errorf("Unexpected content:\n%q\nexpected:\n%q", got, tt.expected)
+			}
+		})
+	}
+
+	if got := bannerComment("<meta/>", ""); got != "<meta/>" {
+		t.Errorf("Expected no change without a banner, got %q", got)
+	}
+	if got := bannerComment("<meta/>", "a---b"); got != "<!--\n    a- - -b\n-->\n<meta/>" {
+		t.Errorf("Expected the dashes to be spaced out, got %q", got)
+	}
+}
+
+func TestBannerSources(t *testing.T) {
+	sources := [][]byte{
+		[]byte("\x1bLua\x51\x00compiled"),
+		[]byte("#!/usr/bin/lua\nprint(1)\n"),
+		[]byte("print(2)\n"),
+	}
+	bannerSources(sources, "(c) \"Example\"\nbuild 7")
+	if string(sources[0]) != "\x1bLua\x51\x00compiled" {
+		t.Errorf("Expected the compiled script to be left alone, got %q", sources[0])
+	}
+	if expected := "#!/usr/bin/lua\ndo local _ = \"(c) \\034Example\\034\\010build 7\" end print(1)\n"; string(sources[1]) != expected {
+		t.Errorf("Unexpected first script:\n%q\nexpected:\n%q", sources[1], expected)
+	}
+	if string(sources[2]) != "print(2)\n" {
+		t.Errorf("Expected the banner in the first script only, got %q", sources[2])
+	}
+
+	r := &Resource{Name: "race", Meta: Meta{Info: Info{Version: "1.2"}}, Banner: "{resource} {version} {build}"}
+	if got := r.expand(r.Banner); got != "race 1.2 {build}" {
+		t.Errorf("Unexpected banner %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem is an issue found in a resource by Check
+type Problem struct {
+	Resource string // Resource name
+	Src      string // Referenced file the problem is about, empty for the whole meta.xml
+	Message  string
+}
+
+// String returns the problem as a single line
+func (p Problem) String() string {
+	if p.Src == "" {
+		return p.Resource + ": " + p.Message
+	}
+	return p.Resource + "/" + p.Src + ": " + p.Message
+}
+
+// Check lints the meta.xml of the resource and verifies that every file it references exists
+// and can be read, without building anything
+func (r *Resource) Check() []Problem {
+	var problems []Problem
+	report := func(src, format string, args ...any) {
+		problems = append(problems, Problem{Resource: r.Name, Src: src, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, script := range r.Meta.Scripts {
+		switch strings.ToLower(script.Type) {
+		case "", "client", "server", "shared":
+		default:
+			report(script.Src, "invalid script type %q (use client, server or shared)", script.Type)
+		}
+	}
+	patterns := make([]string, 0, len(r.Globs))
+	for pattern := range r.Globs {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if len(r.Globs[pattern]) == 0 {
+			report(pattern, "script pattern matches no files")
+		}
+	}
+	for _, export := range r.Meta.Exports {
+		if export.Function == "" {
+			report("", "export without a function name")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range r.Files {
+		src := file.RelativePath
+		if strings.TrimSpace(src) == "" {
+			report("", "%s entry without a src attribute", referenceTag(file.ReferenceType))
+			continue
+		}
+		if filepath.IsAbs(src) || !filepath.IsLocal(filepath.FromSlash(src)) {
+			report(src, "path leaves the resource directory")
+			continue
+		}
+
+		key := referenceTag(file.ReferenceType) + " " + filepath.ToSlash(filepath.Clean(src))
+		if seen[key] {
+			report(src, "referenced twice by %s entries", referenceTag(file.ReferenceType))
+			continue
+		}
+		seen[key] = true
+
+		info, err := os.Stat(file.FullPath)
+		switch {
+		case os.IsNotExist(err):
+			report(src, "file not found")
+		case err != nil:
+			report(src, "cannot read file: %v", err)
+		case info.IsDir():
+			report(src, "is a directory, not a file")
+		default:
+			if f, err := os.Open(file.FullPath); err != nil {
+				report(src, "cannot read file: %v", err)
+			} else {
+				f.Close()
+			}
+		}
+	}
+	return problems
+}
+
+// referenceTag returns the meta.xml tag of a reference type
+func referenceTag(referenceType ReferenceType) string {
+	switch referenceType {
+	case ReferenceTypeScript:
+		return "<script>"
+	case ReferenceTypeMap:
+		return "<map>"
+	case ReferenceTypeConfig:
+		return "<config>"
+	case ReferenceTypeHTML:
+		return "<html>"
+	}
+	return "<file>"
+}
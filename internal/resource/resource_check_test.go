@@ -0,0 +1,57 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	if err := os.MkdirAll(filepath.Join(dir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"client.lua", "server.lua"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("local x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := `<meta>
+	<script src="client.lua" type="client"/>
+	<script src="server.lua" type="server"/>
+	<script src="server.lua" type="server"/>
+	<script src="shared.lua" type="shared"/>
+	<script src="client.lua" type="clinet"/>
+	<file src="../other/logo.png"/>
+	<file src="images"/>
+	<file src=""/>
+	<export function="" type="server"/>
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, problem := range res.Check() {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		`shop/client.lua: invalid script type "clinet" (use client, server or shared)`,
+		"shop: export without a function name",
+		"shop/server.lua: referenced twice by <script> entries",
+		"shop/shared.lua: file not found",
+		"shop/client.lua: referenced twice by <script> entries",
+		"shop/../other/logo.png: path leaves the resource directory",
+		"shop/images: is a directory, not a file",
+		"shop: <file> entry without a src attribute",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -2,35 +2,80 @@ package resource
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/davidbozo/mta-bundler/internal/compiler"
 )
 
+// BatchCompilationResult represents the result of compiling the Lua scripts of an MTA resource,
+// either file by file or merged into client.luac and server.luac
+type BatchCompilationResult struct {
+	Results         []compiler.CompilationResult // Individual compilation results
+	TotalFiles      int                          // Number of compilations performed
+	SuccessCount    int                          // Number of successful compilations
+	ErrorCount      int                          // Number of failed compilations
+	TotalInputSize  int64                        // Total size of the compiled sources in bytes
+	TotalOutputSize int64                        // Total size of the compiled outputs in bytes
+	TotalTime       time.Duration                // Time spent compiling
+}
+
+// CompileResult represents the result of compiling a whole MTA resource
+type CompileResult struct {
+	MergeMode   bool                   // Whether scripts were merged into client.luac and server.luac
+	OutputDir   string                 // Directory the resource was written to
+	Compilation BatchCompilationResult // Results of the Lua compilation
+	FileCopy    FileCopyBatchResult    // Results of copying non-script files
+}
+
+// add records a single compilation result in the batch
+func (b *BatchCompilationResult) add(result compiler.CompilationResult) {
+	b.Results = append(b.Results, result)
+	b.TotalFiles++
+	if result.Success {
+		b.SuccessCount++
+		b.TotalInputSize += result.InputSize
+		b.TotalOutputSize += result.OutputSize
+	} else {
+		b.ErrorCount++
+	}
+}
+
 // Compile compiles all Lua scripts in the resource
-func (r *Resource) Compile(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions, mergeMode bool) error {
-	fmt.Printf("Compiling resource: %s\n", r.Name)
-	fmt.Printf("Base directory: %s\n", r.BaseDir)
+func (r *Resource) Compile(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions, mergeMode bool) (CompileResult, error) {
+	slog.Info("Compiling resource", "resource", r.Name, "base_dir", r.BaseDir)
+
+	result := CompileResult{MergeMode: mergeMode}
+	if outputDir, err := r.OutputDir(inputPath, outputFile); err == nil {
+		result.OutputDir = outputDir
+	}
 
+	var err error
 	if mergeMode {
-		return r.compileMerged(comp, inputPath, outputFile, options)
+		err = r.compileMerged(comp, inputPath, outputFile, options, &result)
 	} else {
-		return r.compileIndividual(comp, inputPath, outputFile, options)
+		err = r.compileIndividual(comp, inputPath, outputFile, options, &result)
 	}
+	return result, err
 }
 
 // compileIndividual compiles each file individually (original behavior)
-func (r *Resource) compileIndividual(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions) error {
+func (r *Resource) compileIndividual(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions, result *CompileResult) error {
 	// Get all Lua script files
-	luaFiles := r.GetLuaFiles()
-	if len(luaFiles) == 0 {
-		fmt.Printf("  Warning: No Lua script files found in resource %s\n", r.Name)
+	log := r.logger()
+	luaFiles := r.compiledLuaFiles()
+	if len(r.GetLuaFiles()) == 0 {
+		log.Warn("No Lua script files found")
 		return nil
 	}
 
-	fmt.Printf("  Found %d Lua script(s) to compile\n", len(luaFiles))
+	log.Info("Found Lua scripts to compile", "count", len(luaFiles))
+	if verbatim := len(r.GetLuaFiles()) - len(luaFiles); verbatim > 0 {
+		log.Info("Copying verbatim scripts without compiling", "count", verbatim)
+	}
 
 	// Get absolute paths for calculation
 	absInputPath, err := filepath.Abs(inputPath)
@@ -49,125 +94,172 @@ func (r *Resource) compileIndividual(comp compiler.CLICompiler, inputPath, outpu
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
-	// Copy meta.xml file to output directory
-	if err := r.copyMetaFile(baseOutputDir, absInputPath, outputFile); err != nil {
-		return fmt.Errorf("failed to copy meta.xml: %v", err)
-	}
-
 	// Copy all non-script file references to output directory
 	copyResult, err := r.copyFileReferences(baseOutputDir, absInputPath, outputFile)
 	if err != nil {
 		return fmt.Errorf("failed to copy file references: %v", err)
 	}
+	result.FileCopy = copyResult
 
 	// Log file copy results
-	printFileCopyResults(copyResult)
+	logFileCopyResults(log, copyResult)
 
 	// Compile each file individually while preserving directory structure
-	var successCount, errorCount int
+	batch := &result.Compilation
 	totalStartTime := time.Now()
 
 	for _, fileRef := range luaFiles {
-		fmt.Printf("  Processing: %s\n", fileRef.RelativePath)
-
-		outputPath, err := r.calculateOutputPath(absInputPath, outputFile, baseOutputDir, fileRef)
-		if err != nil {
-			fmt.Printf("    ✗ Failed to calculate output path: %v\n", err)
-			errorCount++
-			continue
+		// Scripts are not compiled once the build is interrupted
+		if err := comp.Err(); err != nil {
+			batch.TotalTime = time.Since(totalStartTime)
+			return fmt.Errorf("compilation interrupted: %w", err)
 		}
+		compileResult, _ := r.compileScript(comp, absInputPath, outputFile, baseOutputDir, fileRef, options)
+		batch.add(compileResult)
+	}
 
-		// Ensure output subdirectory exists
-		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-			fmt.Printf("    ✗ Failed to create output directory: %v\n", err)
-			errorCount++
-			continue
-		}
+	batch.TotalTime = time.Since(totalStartTime)
+	// A compilation killed by an interruption fails the batch, the meta.xml is not written
+	if err := comp.Err(); err != nil {
+		return fmt.Errorf("compilation interrupted: %w", err)
+	}
 
-		// Compile the file
-		result, err := comp.CompileFile(fileRef.FullPath, outputPath, options)
-		if err != nil {
-			fmt.Printf("    ✗ %s: %v\n", fileRef.RelativePath, err)
-			errorCount++
-		} else if result.Success {
-			// Show relative output path from baseOutputDir
-			relativeOutputPath, err := filepath.Rel(baseOutputDir, outputPath)
-			if err != nil {
-				relativeOutputPath = filepath.Base(outputPath)
-			}
-
-			// Format size information
-			sizeInfo := ""
-			if result.InputSize > 0 && result.OutputSize > 0 {
-				reduction := (1.0 - result.CompressionRatio()) * 100
-				if reduction > 0 {
-					sizeInfo = fmt.Sprintf(" [%s → %s, %.0f%% reduction]",
-						compiler.FormatSize(result.InputSize), compiler.FormatSize(result.OutputSize), reduction)
-				} else {
-					sizeInfo = fmt.Sprintf(" [%s → %s]",
-						compiler.FormatSize(result.InputSize), compiler.FormatSize(result.OutputSize))
-				}
-			}
-
-			fmt.Printf("    ✓ %s -> %s (%v)%s\n", fileRef.RelativePath, relativeOutputPath, result.CompileTime, sizeInfo)
-			successCount++
-		} else {
-			fmt.Printf("    ✗ %s: %v\n", fileRef.RelativePath, result.Error)
-			errorCount++
-		}
+	// Copy meta.xml file to output directory once the scripts it lists are written, so an
+	// interrupted build never leaves a meta.xml pointing at missing scripts
+	if err := r.copyMetaFile(baseOutputDir, absInputPath, outputFile); err != nil {
+		return fmt.Errorf("failed to copy meta.xml: %v", err)
 	}
 
-	totalTime := time.Since(totalStartTime)
+	log.Info("Compilation completed", batch.logAttrs()...)
 
-	// Calculate resource-level size summary
-	var totalInputSize, totalOutputSize int64
-	for _, fileRef := range luaFiles {
-		if info, err := os.Stat(fileRef.FullPath); err == nil {
-			totalInputSize += info.Size()
-		}
+	if batch.ErrorCount > 0 {
+		return fmt.Errorf("compilation completed with %d errors", batch.ErrorCount)
 	}
 
-	// Sum up output sizes from successful compilations
-	for _, fileRef := range luaFiles {
-		outputPath, err := r.calculateOutputPath(absInputPath, outputFile, baseOutputDir, fileRef)
-		if err == nil {
-			if info, err := os.Stat(outputPath); err == nil {
-				totalOutputSize += info.Size()
-			}
-		}
+	return nil
+}
+
+// CompileScript compiles a single Lua script of the resource in individual mode.
+// It is used to rebuild only the script that changed without touching the rest of the resource.
+func (r *Resource) CompileScript(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions, fileRef FileReference) (compiler.CompilationResult, error) {
+	// Get absolute paths for calculation
+	absInputPath, err := filepath.Abs(inputPath)
+	if err != nil {
+		return compiler.CompilationResult{}, fmt.Errorf("failed to get absolute input path: %v", err)
+	}
+
+	// Determine base output directory
+	baseOutputDir, err := r.getBaseOutputDir(outputFile)
+	if err != nil {
+		return compiler.CompilationResult{}, err
+	}
+
+	return r.compileScript(comp, absInputPath, outputFile, baseOutputDir, fileRef, options)
+}
+
+// compileScript compiles one Lua file to its individual output path and logs the outcome
+func (r *Resource) compileScript(comp compiler.CLICompiler, absInputPath, outputFile, baseOutputDir string, fileRef FileReference, options compiler.CompilationOptions) (compiler.CompilationResult, error) {
+	r.logger().Debug("Processing script", "file", fileRef.RelativePath)
+
+	result := compiler.CompilationResult{InputFile: fileRef.FullPath}
+
+	outputPath, err := r.calculateOutputPath(absInputPath, outputFile, baseOutputDir, fileRef)
+	if err != nil {
+		r.logger().Error("Failed to calculate output path", "file", fileRef.RelativePath, "error", err)
+		result.Error = err
+		return result, err
+	}
+	r.logger().Debug("Calculated output path", "file", fileRef.RelativePath, "input_root", absInputPath, "output_dir", baseOutputDir, "output", outputPath)
+
+	// Ensure output subdirectory exists
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		r.logger().Error("Failed to create output directory", "file", fileRef.RelativePath, "error", err)
+		result.OutputFile = outputPath
+		result.Error = err
+		return result, err
 	}
 
-	fmt.Printf("  Compilation completed: %d successful, %d errors\n", successCount, errorCount)
-	if totalInputSize > 0 && totalOutputSize > 0 && successCount > 0 {
-		reduction := (1.0 - float64(totalOutputSize)/float64(totalInputSize)) * 100
-		fmt.Printf("  Resource size summary: %s \u2192 %s (%.0f%% reduction)\n",
-			compiler.FormatSize(totalInputSize), compiler.FormatSize(totalOutputSize), reduction)
+	// Compile the file
+	if r.Transform != nil {
+		result, err = r.compileTransformed(comp, "", []FileReference{fileRef}, outputPath, options)
+	} else {
+		result, err = comp.CompileFile(fileRef.FullPath, outputPath, options)
+	}
+	if err == nil && !result.Success {
+		err = result.Error
+	}
+	if err != nil {
+		r.logger().Error("Failed to compile", "file", fileRef.RelativePath, "error", err)
+		return result, err
 	}
-	fmt.Printf("  Total time: %v\n", totalTime)
 
-	if errorCount > 0 {
-		return fmt.Errorf("compilation completed with %d errors", errorCount)
+	// Show relative output path from baseOutputDir
+	relativeOutputPath, err := filepath.Rel(baseOutputDir, outputPath)
+	if err != nil {
+		relativeOutputPath = filepath.Base(outputPath)
 	}
 
-	return nil
+	args := []any{"file", fileRef.RelativePath, "output", relativeOutputPath, "success", true, "duration", result.CompileTime}
+	if result.Retries > 0 {
+		args = append(args, "retries", result.Retries)
+	}
+	r.logger().Info("Compiled", append(args, sizeAttrs(result.InputSize, result.OutputSize)...)...)
+	return result, nil
 }
 
-// compileMerged compiles scripts into client.luac and server.luac files
-func (r *Resource) compileMerged(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions) error {
+// logger returns the logger for messages about this resource
+func (r *Resource) logger() *slog.Logger {
+	return slog.With("resource", r.Name)
+}
+
+// sizeAttrs returns the log attributes describing a size change, or none when a size is unknown
+func sizeAttrs(inputSize, outputSize int64) []any {
+	if inputSize <= 0 || outputSize <= 0 {
+		return nil
+	}
+
+	attrs := []any{"input_size", inputSize, "output_size", outputSize}
+	if reduction := (1.0 - float64(outputSize)/float64(inputSize)) * 100; reduction > 0 {
+		attrs = append(attrs, "reduction", fmt.Sprintf("%.0f%%", reduction))
+	}
+	return attrs
+}
+
+// logAttrs returns the log attributes summarizing the batch
+func (b BatchCompilationResult) logAttrs() []any {
+	attrs := []any{"succeeded", b.SuccessCount, "failed", b.ErrorCount, "duration", b.TotalTime}
+	if b.SuccessCount > 0 {
+		attrs = append(attrs, sizeAttrs(b.TotalInputSize, b.TotalOutputSize)...)
+	}
+	return attrs
+}
+
+// compileMerged compiles scripts into the client and server bundles, client.luac and server.luac
+// unless the resource has a BundleName
+func (r *Resource) compileMerged(comp compiler.CLICompiler, inputPath, outputFile string, options compiler.CompilationOptions, result *CompileResult) error {
 	// Get scripts grouped by type
 	clientFiles, serverFiles, sharedFiles := r.GetLuaFilesByType()
 
 	// Combine shared files with both client and server
-	allClientFiles := append(clientFiles, sharedFiles...)
-	allServerFiles := append(serverFiles, sharedFiles...)
+	allClientFiles, allServerFiles := r.GetMergedLuaFiles()
+
+	// Unmerged scripts are compiled file by file next to the bundles
+	unmergedFiles := r.unmergedLuaFiles()
 
-	if len(allClientFiles) == 0 && len(allServerFiles) == 0 {
-		fmt.Printf("  Warning: No Lua script files found in resource %s\n", r.Name)
+	log := r.logger()
+	verbatim := len(r.GetLuaFiles()) - len(r.compiledLuaFiles())
+	if len(allClientFiles) == 0 && len(allServerFiles) == 0 && len(unmergedFiles) == 0 && verbatim == 0 {
+		log.Warn("No Lua script files found")
 		return nil
 	}
 
-	fmt.Printf("  Found %d client script(s), %d server script(s), %d shared script(s)\n",
-		len(clientFiles), len(serverFiles), len(sharedFiles))
+	log.Info("Found Lua scripts to merge", "client", len(clientFiles), "server", len(serverFiles), "shared", len(sharedFiles))
+	if len(unmergedFiles) > 0 {
+		log.Info("Compiling unmerged scripts individually", "count", len(unmergedFiles))
+	}
+	if verbatim > 0 {
+		log.Info("Copying verbatim scripts without compiling", "count", verbatim)
+	}
 
 	// Get absolute paths for calculation
 	absInputPath, err := filepath.Abs(inputPath)
@@ -186,126 +278,136 @@ func (r *Resource) compileMerged(comp compiler.CLICompiler, inputPath, outputFil
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
-	// Copy meta.xml file to output directory (will be updated for merged files)
-	if err := r.copyMergedMetaFile(baseOutputDir, absInputPath, outputFile, len(allClientFiles) > 0, len(allServerFiles) > 0); err != nil {
-		return fmt.Errorf("failed to copy meta.xml: %v", err)
-	}
-
 	// Copy all non-script file references to output directory
 	copyResult, err := r.copyFileReferences(baseOutputDir, absInputPath, outputFile)
 	if err != nil {
 		return fmt.Errorf("failed to copy file references: %v", err)
 	}
+	result.FileCopy = copyResult
 
-	printFileCopyResults(copyResult)
+	logFileCopyResults(log, copyResult)
 
-	var successCount, errorCount int
+	batch := &result.Compilation
 	totalStartTime := time.Now()
 
 	// Compile client files if any
 	if len(allClientFiles) > 0 {
-		clientOutputPath := filepath.Join(baseOutputDir, "client.luac")
-		if outputFile != "" {
-			relativeFromInput, err := filepath.Rel(absInputPath, r.BaseDir)
-			if err == nil && relativeFromInput != "" && relativeFromInput != "." {
-				clientOutputPath = filepath.Join(baseOutputDir, relativeFromInput, "client.luac")
-			}
-		}
-
-		// Ensure output directory exists
-		if err := os.MkdirAll(filepath.Dir(clientOutputPath), 0755); err != nil {
-			fmt.Printf("    ✗ Failed to create client output directory: %v\n", err)
-			errorCount++
-		} else {
-			// Get file paths for compilation
-			var clientPaths []string
-			for _, fileRef := range allClientFiles {
-				clientPaths = append(clientPaths, fileRef.FullPath)
-			}
-
-			fmt.Printf("  Compiling client files to client.luac...\n")
-			result, err := comp.Compile(clientPaths, clientOutputPath, options)
-			if err != nil {
-				fmt.Printf("    ✗ Client compilation failed: %v\n", err)
-				errorCount++
-			} else if result.Success {
-				// Format size information for merged client files
-				sizeInfo := ""
-				if result.InputSize > 0 && result.OutputSize > 0 {
-					reduction := (1.0 - result.CompressionRatio()) * 100
-					if reduction > 0 {
-						sizeInfo = fmt.Sprintf(" [%s → %s, %.0f%% reduction]",
-							compiler.FormatSize(result.InputSize), compiler.FormatSize(result.OutputSize), reduction)
-					} else {
-						sizeInfo = fmt.Sprintf(" [%s → %s, %.0f%% reduction]",
-							compiler.FormatSize(result.InputSize), compiler.FormatSize(result.OutputSize), reduction)
-					}
-				}
-				fmt.Printf("    ✓ Client compilation successful: client.luac (%v)%s\n", result.CompileTime, sizeInfo)
-				successCount++
-			} else {
-				fmt.Printf("    ✗ Client compilation failed: %v\n", result.Error)
-				errorCount++
-			}
-		}
+		batch.add(r.compileBundle(comp, "client", allClientFiles, absInputPath, outputFile, baseOutputDir, options))
 	}
 
 	// Compile server files if any
 	if len(allServerFiles) > 0 {
-		serverOutputPath := filepath.Join(baseOutputDir, "server.luac")
-		if outputFile != "" {
-			relativeFromInput, err := filepath.Rel(absInputPath, r.BaseDir)
-			if err == nil && relativeFromInput != "" && relativeFromInput != "." {
-				serverOutputPath = filepath.Join(baseOutputDir, relativeFromInput, "server.luac")
-			}
-		}
+		batch.add(r.compileBundle(comp, "server", allServerFiles, absInputPath, outputFile, baseOutputDir, options))
+	}
 
-		// Ensure output directory exists
-		if err := os.MkdirAll(filepath.Dir(serverOutputPath), 0755); err != nil {
-			fmt.Printf("    ✗ Failed to create server output directory: %v\n", err)
-			errorCount++
-		} else {
-			// Get file paths for compilation
-			var serverPaths []string
-			for _, fileRef := range allServerFiles {
-				serverPaths = append(serverPaths, fileRef.FullPath)
-			}
-
-			fmt.Printf("  Compiling server files to server.luac...\n")
-			result, err := comp.Compile(serverPaths, serverOutputPath, options)
-			if err != nil {
-				fmt.Printf("    ✗ Server compilation failed: %v\n", err)
-				errorCount++
-			} else if result.Success {
-				// Format size information for merged server files
-				sizeInfo := ""
-				if result.InputSize > 0 && result.OutputSize > 0 {
-					reduction := (1.0 - result.CompressionRatio()) * 100
-					if reduction > 0 {
-						sizeInfo = fmt.Sprintf(" [%s → %s, %.0f%% reduction]",
-							compiler.FormatSize(result.InputSize), compiler.FormatSize(result.OutputSize), reduction)
-					} else {
-						sizeInfo = fmt.Sprintf(" [%s → %s]",
-							compiler.FormatSize(result.InputSize), compiler.FormatSize(result.OutputSize))
-					}
-				}
-				fmt.Printf("    ✓ Server compilation successful: server.luac (%v)%s\n", result.CompileTime, sizeInfo)
-				successCount++
-			} else {
-				fmt.Printf("    ✗ Server compilation failed: %v\n", result.Error)
-				errorCount++
-			}
+	for _, fileRef := range unmergedFiles {
+		if err := comp.Err(); err != nil {
+			break
 		}
+		compileResult, _ := r.compileScript(comp, absInputPath, outputFile, baseOutputDir, fileRef, options)
+		batch.add(compileResult)
 	}
 
-	totalTime := time.Since(totalStartTime)
-	fmt.Printf("  Merge compilation completed: %d successful, %d errors\n", successCount, errorCount)
-	fmt.Printf("  Total time: %v\n", totalTime)
+	batch.TotalTime = time.Since(totalStartTime)
+	if err := comp.Err(); err != nil {
+		return fmt.Errorf("compilation interrupted: %w", err)
+	}
+
+	// Copy meta.xml file to output directory, listing the bundles, once they are written
+	if err := r.copyMergedMetaFile(baseOutputDir, absInputPath, outputFile, len(allClientFiles) > 0, len(allServerFiles) > 0); err != nil {
+		return fmt.Errorf("failed to copy meta.xml: %v", err)
+	}
+	log.Info("Merge compilation completed", batch.logAttrs()...)
 
-	if errorCount > 0 {
-		return fmt.Errorf("compilation completed with %d errors", errorCount)
+	if batch.ErrorCount > 0 {
+		return fmt.Errorf("compilation completed with %d errors", batch.ErrorCount)
 	}
 
 	return nil
 }
 
+// compileBundle compiles a group of scripts into the single bundle file of kind
+func (r *Resource) compileBundle(comp compiler.CLICompiler, kind string, files []FileReference, absInputPath, outputFile, baseOutputDir string, options compiler.CompilationOptions) compiler.CompilationResult {
+	log := r.logger()
+	bundleName := r.BundlePath(kind)
+
+	outputPath := filepath.Join(baseOutputDir, filepath.FromSlash(bundleName))
+	if outputFile != "" {
+		relativeFromInput, err := filepath.Rel(absInputPath, r.BaseDir)
+		if err == nil && relativeFromInput != "" && relativeFromInput != "." {
+			outputPath = filepath.Join(baseOutputDir, relativeFromInput, filepath.FromSlash(bundleName))
+		}
+	}
+
+	r.logger().Debug("Calculated output path", "bundle", bundleName, "input_root", absInputPath, "output_dir", baseOutputDir, "output", outputPath)
+
+	// Get file paths for compilation
+	var paths []string
+	for _, fileRef := range files {
+		paths = append(paths, fileRef.FullPath)
+	}
+
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		log.Error("Failed to create output directory", "bundle", bundleName, "error", err)
+		return compiler.CompilationResult{
+			InputFile:  strings.Join(paths, ", "),
+			OutputFile: outputPath,
+			Error:      err,
+		}
+	}
+
+	log.Info("Compiling "+kind+" scripts", "bundle", bundleName, "count", len(files))
+	var result compiler.CompilationResult
+	var err error
+	switch {
+	case r.Concat:
+		result, err = r.compileConcat(comp, kind, files, bundleName, outputPath, options)
+	case r.Transform != nil || r.Banner != "":
+		result, err = r.compileTransformed(comp, kind, files, outputPath, options)
+	default:
+		result, err = comp.Compile(paths, outputPath, options)
+	}
+	if err == nil && !result.Success {
+		err = result.Error
+	}
+	if err != nil {
+		log.Error("Failed to compile", "bundle", bundleName, "error", err)
+		return result
+	}
+
+	args := []any{"bundle", bundleName, "success", true, "duration", result.CompileTime}
+	if result.Retries > 0 {
+		args = append(args, "retries", result.Retries)
+	}
+	log.Info("Compiled", append(args, sizeAttrs(result.InputSize, result.OutputSize)...)...)
+	return result
+}
+
+// compileConcat compiles files, the scripts of the kind bundle, concatenated into one source into
+// the bundle at outputPath, each script isolated from the errors of the others with Isolate. The
+// result lists the scripts as inputs, so source maps and reports cover them as for a bundle
+// compiled from the files.
+func (r *Resource) compileConcat(comp compiler.CLICompiler, kind string, files []FileReference, bundleName, outputPath string, options compiler.CompilationOptions) (compiler.CompilationResult, error) {
+	var paths []string
+	for _, fileRef := range files {
+		paths = append(paths, fileRef.FullPath)
+	}
+
+	chunkName := concatChunkName(bundleName)
+	sources, err := r.readSources(kind, files)
+	if err != nil {
+		return compiler.CompilationResult{InputFile: strings.Join(paths, ", "), InputFiles: paths, OutputFile: outputPath, Error: err}, err
+	}
+	source := concatSources(files, sources, chunkName, r.Isolate)
+	r.logger().Debug("Concatenated scripts", "bundle", bundleName, "chunk", chunkName, "isolate", r.Isolate, "size", len(source))
+
+	result, err := comp.CompileSource(chunkName, source, outputPath, options)
+	result.InputFile = strings.Join(paths, ", ")
+	result.InputFiles = paths
+	result.OutputFile = outputPath
+	if inputSize, sizeErr := compiler.CalculateTotalSize(paths); sizeErr == nil {
+		result.InputSize = inputSize
+	}
+	return result, err
+}
@@ -0,0 +1,51 @@
+package resource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+func TestCompileInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake compiler is a shell script")
+	}
+	// Compiles a.lua right away and hangs on b.lua until it is killed
+	compilerPath := filepath.Join(t.TempDir(), "luac_mta")
+	os.WriteFile(compilerPath, []byte(`#!/bin/sh
+while [ $# -gt 1 ]; do [ "$1" = "-o" ] && out="$2"; shift; done
+case "$1" in *b.lua) exec sleep 10;; esac
+printf '\033Lua' > "$out"
+`), 0755)
+	comp, err := compiler.NewCLICompiler(compilerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mergeMode := range []bool{false, true} {
+		inputDir := t.TempDir()
+		os.WriteFile(filepath.Join(inputDir, "meta.xml"), []byte(`<meta><script src="a.lua" type="server" /><script src="b.lua" type="client" /></meta>`), 0644)
+		os.WriteFile(filepath.Join(inputDir, "a.lua"), []byte("print(1)"), 0644)
+		os.WriteFile(filepath.Join(inputDir, "b.lua"), []byte("print(2)"), 0644)
+		outputDir := t.TempDir()
+		res, err := NewResource(filepath.Join(inputDir, "meta.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(300*time.Millisecond, cancel)
+		if _, err := res.Compile(comp.WithContext(ctx), inputDir, outputDir, compiler.CompilationOptions{}, mergeMode); err == nil {
+			t.Fatalf("Expected an interrupted compilation to fail (merge mode %v)", mergeMode)
+		}
+		cancel()
+		if _, err := os.Stat(filepath.Join(outputDir, "meta.xml")); !os.IsNotExist(err) {
+			t.Errorf("Expected no meta.xml pointing at scripts that were not compiled (merge mode %v)", mergeMode)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some editors write at the start of scripts
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// isolationRunner is the Lua function running each script of an isolated bundle. It is
+// declared on the first line of the bundle, before the first script, and gets the script's src
+// and its first and last bundle lines. Errors are reported with debug level 1 at the original
+// script and line, and the bundle goes on with the next script. MTA_BUNDLER_ISOLATED is set
+// while reporting, so the source map shim does not translate the report again.
+const isolationRunner = `local function mtaBundlerRun(src, first, last, f, ...) ` +
+	`local ok, err = pcall(f, ...) ` +
+	`if not ok then ` +
+	`err = tostring(err):gsub(%s .. ":(%%d+):", function(line) line = tonumber(line) ` +
+	`if line >= first and line <= last then return src .. ":" .. (line - first + 1) .. ":" end end) ` +
+	`MTA_BUNDLER_ISOLATED = true outputDebugString("Error in " .. src .. ": " .. err, 1) MTA_BUNDLER_ISOLATED = nil ` +
+	`end end `
+
+// concatSources returns sources, the scripts of files, concatenated into one Lua source compiled as the
+// chunk chunkName. Every script runs in its own do...end block, so its locals stay private to
+// it as in a chunk of its own, with a comment naming the script. With isolate, every script
+// runs in a function called through pcall instead, so an error in one does not stop the
+// others. The added code shares lines with the scripts, so every line of the source is the
+// line a source map of the files places there.
+func concatSources(files []FileReference, sources [][]byte, chunkName string, isolate bool) []byte {
+	var source bytes.Buffer
+	pending := ""
+	if isolate {
+		pending = fmt.Sprintf(isolationRunner, luaQuote(luaPattern(chunkName)))
+	}
+	end := "end "
+	if isolate {
+		end = "end, ...) "
+	}
+
+	line := 1
+	for i, fileRef := range files {
+		data := bytes.TrimPrefix(sources[i], utf8BOM)
+		// Lua skips a first line starting with # in files, not inside a source
+		if bytes.HasPrefix(data, []byte("#")) {
+			data = append([]byte("--"), data...)
+		}
+		lines := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			lines++
+		}
+
+		marker := "do --[==[ " + fileRef.RelativePath + " ]==] "
+		if isolate {
+			marker = fmt.Sprintf("--[==[ %s ]==] mtaBundlerRun(%s, %d, %d, function(...) ",
+				fileRef.RelativePath, luaQuote(fileRef.RelativePath), line, line+lines-1)
+		}
+		if len(data) == 0 {
+			// Empty scripts cover no line, their block closes right away
+			pending += marker + end
+			continue
+		}
+		source.WriteString(pending + marker)
+		source.Write(data)
+		if data[len(data)-1] != '\n' {
+			source.WriteByte('\n')
+		}
+		pending = end
+		line += lines
+	}
+	if pending != "" {
+		source.WriteString(strings.TrimSpace(pending) + "\n")
+	}
+	return source.Bytes()
+}
+
+// concatChunkName returns the chunk name of a concatenated bundle, such as client.lua for
+// client.luac, so it does not depend on where the build runs
+func concatChunkName(bundleName string) string {
+	return strings.TrimSuffix(path.Base(bundleName), ".luac") + ".lua"
+}
+
+// luaQuote returns s as a Lua string literal. Control characters, quotes and backslashes are
+// escaped with decimal escapes, which Lua 5.1 understands unlike Go's hexadecimal ones.
+func luaQuote(s string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7F || c == '"' || c == '\\' {
+			quoted.WriteString(fmt.Sprintf("\\%03d", c))
+			continue
+		}
+		quoted.WriteByte(c)
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+// luaPattern escapes the magic characters of Lua patterns in s
+func luaPattern(s string) string {
+	var escaped strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune("^$()%.[]*+-?", c) {
+			escaped.WriteByte('%')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
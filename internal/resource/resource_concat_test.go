@@ -0,0 +1,70 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConcatSources(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"a.lua":       "local x = 1\n-- trailing comment",
+		"empty.lua":   "",
+		"utils/b.lua": "\xEF\xBB\xBFlocal y = 2\nprint(y)\n",
+	}
+	var files []FileReference
+	for _, src := range []string{"a.lua", "empty.lua", "utils/b.lua"} {
+		path := filepath.Join(dir, filepath.FromSlash(src))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(scripts[src]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, FileReference{FullPath: path, ReferenceType: ReferenceTypeScript, RelativePath: src})
+	}
+
+	sources, err := (&Resource{}).readSources("client", files)
+	if err != nil {
+		t.Fatalf("readSources failed: %v", err)
+	}
+	source := concatSources(files, sources, "client.lua", false)
+	expected := "do --[==[ a.lua ]==] local x = 1\n" +
+		"-- trailing comment\n" +
+		"end do --[==[ empty.lua ]==] end do --[==[ utils/b.lua ]==] local y = 2\n" +
+		"print(y)\n" +
+		"end\n"
+	if string(source) != expected {
+		t.Errorf("Unexpected source:\n%s\nexpected:\n%s", source, expected)
+	}
+
+	isolated := concatSources(files, sources, "client.lua", true)
+	lines := strings.Split(strings.TrimSuffix(string(isolated), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected the isolated source to keep the script lines:\n%s", isolated)
+	}
+	for _, expected := range []string{
+		`gsub("client%.lua" .. ":(%d+):"`,
+		`--[==[ a.lua ]==] mtaBundlerRun("a.lua", 1, 2, function(...) local x = 1`,
+	} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("Expected %q on the first line: %s", expected, lines[0])
+		}
+	}
+	if expected := `end, ...) --[==[ empty.lua ]==] mtaBundlerRun("empty.lua", 3, 2, function(...) end, ...) --[==[ utils/b.lua ]==] mtaBundlerRun("utils/b.lua", 3, 4, function(...) local y = 2`; lines[2] != expected {
+		t.Errorf("Unexpected third line:\n%s\nexpected:\n%s", lines[2], expected)
+	}
+	if lines[4] != "end, ...)" {
+		t.Errorf("Unexpected last line: %s", lines[4])
+	}
+
+	if quoted := luaQuote("a\"b\n1"); quoted != `"a\034b\0101"` {
+		t.Errorf("Unexpected Lua string %s", quoted)
+	}
+
+	if name := concatChunkName("dist/bundle_client.luac"); name != "bundle_client.lua" {
+		t.Errorf("Unexpected chunk name %q", name)
+	}
+}
@@ -0,0 +1,111 @@
+package resource
+
+import "strings"
+
+// Description is the resolved structure of a resource, written as JSON by the inspect command so
+// external tools and editors do not have to parse meta.xml themselves. Fields are only ever
+// added, so consumers can rely on the existing ones.
+type Description struct {
+	Name     string          `json:"name"`
+	BaseDir  string          `json:"base_dir"`
+	MetaXML  string          `json:"meta_xml"`
+	Info     InfoDescription `json:"info"`
+	OOP      bool            `json:"oop"`
+	Files    []FileEntry     `json:"files"`
+	Scripts  ScriptGroups    `json:"scripts"`
+	Patterns ScriptGlobs     `json:"script_patterns,omitempty"`
+	Includes []string        `json:"includes"`
+	Exports  []ExportEntry   `json:"exports"`
+}
+
+// InfoDescription is the <info> element of a resource, attributes left out of meta.xml are empty
+type InfoDescription struct {
+	Name        string `json:"name"`
+	Author      string `json:"author"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+}
+
+// FileEntry is a file referenced by meta.xml
+type FileEntry struct {
+	Src  string `json:"src"`  // Path as written in meta.xml
+	Type string `json:"type"` // Tag referencing the file: script, map, config, file or html
+	Path string `json:"path"` // Absolute path
+}
+
+// ScriptGroups are the script srcs of a resource by the side they run on. Scripts without a type
+// run on the server.
+type ScriptGroups struct {
+	Client []string `json:"client"`
+	Server []string `json:"server"`
+	Shared []string `json:"shared"`
+}
+
+// ExportEntry is a function exported to other resources
+type ExportEntry struct {
+	Function string `json:"function"`
+	Type     string `json:"type"` // client, server or shared
+	HTTP     bool   `json:"http"`
+}
+
+// String returns the meta.xml tag name of a reference type
+func (t ReferenceType) String() string {
+	switch t {
+	case ReferenceTypeScript:
+		return "script"
+	case ReferenceTypeMap:
+		return "map"
+	case ReferenceTypeConfig:
+		return "config"
+	case ReferenceTypeHTML:
+		return "html"
+	}
+	return "file"
+}
+
+// Describe returns the resolved structure of the resource, with script srcs with wildcards
+// already expanded
+func (r *Resource) Describe() Description {
+	info := r.Meta.Info
+	d := Description{
+		Name:     r.Name,
+		BaseDir:  r.BaseDir,
+		MetaXML:  r.MetaXMLPath,
+		Info:     InfoDescription{info.Name, info.Author, info.Version, info.Description, info.Type},
+		OOP:      r.Meta.OOP != nil && strings.TrimSpace(*r.Meta.OOP) == "true",
+		Files:    []FileEntry{},
+		Scripts:  ScriptGroups{Client: []string{}, Server: []string{}, Shared: []string{}},
+		Patterns: r.Globs,
+		Includes: []string{},
+		Exports:  []ExportEntry{},
+	}
+
+	for _, file := range r.Files {
+		d.Files = append(d.Files, FileEntry{Src: file.RelativePath, Type: file.ReferenceType.String(), Path: file.FullPath})
+	}
+	for _, script := range r.Meta.Scripts {
+		switch strings.ToLower(script.Type) {
+		case "client":
+			d.Scripts.Client = append(d.Scripts.Client, script.Src)
+		case "shared":
+			d.Scripts.Shared = append(d.Scripts.Shared, script.Src)
+		default:
+			d.Scripts.Server = append(d.Scripts.Server, script.Src)
+		}
+	}
+	for _, include := range r.Meta.Include {
+		if name := strings.TrimSpace(include.Resource); name != "" {
+			d.Includes = append(d.Includes, name)
+		}
+	}
+	for _, export := range r.Meta.Exports {
+		kind := strings.ToLower(export.Type)
+		if kind == "" {
+			kind = "server"
+		}
+		d.Exports = append(d.Exports, ExportEntry{Function: export.Function, Type: kind,
+			HTTP: strings.TrimSpace(export.HTTP) == "true"})
+	}
+	return d
+}
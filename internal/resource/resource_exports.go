@@ -0,0 +1,113 @@
+package resource
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// exportSides returns the sides an export is called on: client, server or both for shared
+// exports. Exports without a type are server exports.
+func exportSides(export Export) []string {
+	switch strings.ToLower(export.Type) {
+	case "client":
+		return []string{"client"}
+	case "shared":
+		return []string{"client", "server"}
+	}
+	return []string{"server"}
+}
+
+// runsOn reports whether a script of the given type runs on side. Scripts without a valid type
+// are merged as server scripts, so they count as server scripts.
+func runsOn(scriptType, side string) bool {
+	switch strings.ToLower(scriptType) {
+	case "shared":
+		return true
+	case "client":
+		return side == "client"
+	}
+	return side == "server"
+}
+
+// ExportedFunctions returns the functions the resource exports on side, client or server
+func (r *Resource) ExportedFunctions(side string) []string {
+	var functions []string
+	for _, export := range r.Meta.Exports {
+		if export.Function != "" && slices.Contains(exportSides(export), side) {
+			functions = append(functions, export.Function)
+		}
+	}
+	return functions
+}
+
+// SideScripts returns the srcs of the scripts running on side, client or server, in their
+// meta.xml order. Shared scripts run on both sides.
+func (r *Resource) SideScripts(side string) []string {
+	var srcs []string
+	for _, script := range r.Meta.Scripts {
+		if runsOn(script.Type, side) {
+			srcs = append(srcs, script.Src)
+		}
+	}
+	return srcs
+}
+
+// CheckExports verifies in merge mode that every function listed in an <export> is still
+// defined by a script merged into the bundle of its side. A function defined only by a script
+// left out of the bundles, verbatim or merge excluded, is returned as a case needing attention:
+// the export keeps working only as long as that script stays out. A function no script of its
+// side defines is returned as a problem. Sides with compiled or URL scripts, which cannot be
+// read, are not reported.
+func (r *Resource) CheckExports() ([]Problem, []Attention) {
+	var problems []Problem
+	var cases []Attention
+	for _, export := range r.Meta.Exports {
+		if export.Function == "" {
+			continue
+		}
+		for _, side := range exportSides(export) {
+			var merged, excluded []string
+			unreadable := false
+			for _, script := range r.Meta.Scripts {
+				if !runsOn(script.Type, side) {
+					continue
+				}
+				switch {
+				case IsURL(script.Src) || strings.ToLower(filepath.Ext(script.Src)) != ".lua":
+					unreadable = true
+				case r.isMerged(script.Src):
+					merged = append(merged, filepath.Join(r.BaseDir, script.Src))
+				default:
+					excluded = append(excluded, script.Src)
+				}
+			}
+
+			if definesFunction(merged, export.Function) {
+				continue
+			}
+			if src, ok := definingScript(r.BaseDir, excluded, export.Function); ok {
+				cases = append(cases, Attention{Code: AttentionExportNotMerged, Resource: r.Name, Src: src,
+					Message: fmt.Sprintf("exported function %s is defined outside of %s, by a script left out of the merge", export.Function, r.BundlePath(side))})
+				continue
+			}
+			if !unreadable {
+				problems = append(problems, Problem{Resource: r.Name,
+					Message: fmt.Sprintf("exported function %s is not defined by any %s script", export.Function, side)})
+			}
+		}
+	}
+	return problems, cases
+}
+
+// definingScript returns the first of the script srcs, relative to baseDir, that defines the
+// global function
+func definingScript(baseDir string, srcs []string, function string) (string, bool) {
+	for _, src := range srcs {
+		if definesFunction([]string{filepath.Join(baseDir, src)}, function) {
+			return src, true
+		}
+	}
+	return "", false
+}
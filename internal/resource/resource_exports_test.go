@@ -0,0 +1,60 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckExports(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	scripts := map[string]string{
+		"server.lua":  "function buyItem(player, item)\nend\n",
+		"client.lua":  "showShop = function()\nend\n",
+		"plugins.lua": "function loadPlugin(name)\nend\n",
+	}
+	for name, content := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := `<meta>
+    <script src="server.lua" type="server" />
+    <script src="client.lua" type="client" />
+    <script src="plugins.lua" type="server" />
+    <export function="buyItem" />
+    <export function="showShop" type="client" />
+    <export function="loadPlugin" type="server" />
+    <export function="sellItem" type="server" />
+    <export function="buyItem" type="shared" />
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Unmerged = map[string]bool{"plugins.lua": true}
+
+	problems, cases := res.CheckExports()
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.Message)
+	}
+	expected := []string{
+		"exported function sellItem is not defined by any server script",
+		"exported function buyItem is not defined by any client script",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected problems:\n%s\nexpected:\n%s", strings.Join(messages, "\n"), strings.Join(expected, "\n"))
+	}
+	if len(cases) != 1 || cases[0].Code != AttentionExportNotMerged || cases[0].Src != "plugins.lua" {
+		t.Errorf("Expected loadPlugin to need attention, got %v", cases)
+	}
+}
@@ -58,6 +58,9 @@ func (r *Resource) calculateOutputPath(absInputPath, outputFile, baseOutputDir s
 // copyFileReferences copies all non-script file references to the output directory
 func (r *Resource) copyFileReferences(baseOutputDir, absInputPath, outputFile string) (FileCopyBatchResult, error) {
 	nonScriptFiles := r.getNonScriptFiles()
+	if r.SkipAssets {
+		r.logSkippedAssets()
+	}
 	result := FileCopyBatchResult{
 		Results:      make([]FileCopyResult, 0, len(nonScriptFiles)),
 		TotalFiles:   len(nonScriptFiles),
@@ -92,17 +95,31 @@ func (r *Resource) calculateFileOutputPath(absInputPath, outputFile, baseOutputD
 	return filepath.Join(baseOutputDir, fileRef.RelativePath), nil
 }
 
-// getNonScriptFiles returns all non-script file references
+// getNonScriptFiles returns all file references that are copied as is: non-script files, unless
+// assets are skipped, and verbatim scripts
 func (r *Resource) getNonScriptFiles() []FileReference {
 	var nonScriptFiles []FileReference
 	for _, fileRef := range r.Files {
-		if fileRef.ReferenceType != ReferenceTypeScript {
+		if (fileRef.ReferenceType != ReferenceTypeScript && !r.SkipAssets) || r.IsVerbatim(fileRef.RelativePath) {
 			nonScriptFiles = append(nonScriptFiles, fileRef)
 		}
 	}
 	return nonScriptFiles
 }
 
+// logSkippedAssets reports the non-script files left out of the output
+func (r *Resource) logSkippedAssets() {
+	var count int
+	for _, fileRef := range r.Files {
+		if fileRef.ReferenceType != ReferenceTypeScript {
+			count++
+		}
+	}
+	if count > 0 {
+		r.logger().Info("Skipped non-script files", "count", count)
+	}
+}
+
 // processSingleFile handles the copying of a single file and returns the result
 func (r *Resource) processSingleFile(fileRef FileReference, absInputPath, outputFile, baseOutputDir string) FileCopyResult {
 	copyResult := FileCopyResult{
@@ -118,13 +135,21 @@ func (r *Resource) processSingleFile(fileRef FileReference, absInputPath, output
 		return copyResult
 	}
 	copyResult.OutputPath = outputPath
+	r.logger().Debug("Calculated output path", "file", fileRef.RelativePath, "input_root", absInputPath, "output_dir", baseOutputDir, "output", outputPath)
 
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
 		copyResult.Error = fmt.Errorf("failed to create output directory: %v", err)
 		return copyResult
 	}
 
-	if err := copyFile(fileRef.FullPath, outputPath); err != nil {
+	if r.LinkAssets {
+		method, err := linkFile(fileRef.FullPath, outputPath)
+		if err != nil {
+			copyResult.Error = fmt.Errorf("failed to link file: %v", err)
+			return copyResult
+		}
+		r.logger().Debug("Linked file", "file", fileRef.RelativePath, "method", method)
+	} else if err := copyFile(fileRef.FullPath, outputPath); err != nil {
 		copyResult.Error = fmt.Errorf("failed to copy file: %v", err)
 		return copyResult
 	}
@@ -198,8 +223,13 @@ func buildFullRelativeDir(relativeFromInput, relativeDir string) string {
 	return relativeDir
 }
 
-// copyFile copies a file from src to dst
+// copyFile copies a file from src to dst. An existing dst is replaced rather than written to,
+// since it may be a link to a source file left by a build with linked assets.
 func copyFile(src, dst string) error {
+	if ok, err := replaceable(src, dst); !ok {
+		return err
+	}
+
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return err
@@ -225,3 +255,65 @@ func copyFile(src, dst string) error {
 
 	return os.Chmod(dst, sourceInfo.Mode())
 }
+
+// OutputDir returns the directory the compiled resource is written to
+func (r *Resource) OutputDir(inputPath, outputFile string) (string, error) {
+	baseOutputDir, err := r.getBaseOutputDir(outputFile)
+	if err != nil {
+		return "", err
+	}
+	if outputFile == "" {
+		return baseOutputDir, nil
+	}
+
+	absInputPath, err := filepath.Abs(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute input path: %v", err)
+	}
+
+	relativeFromInput, err := filepath.Rel(absInputPath, r.BaseDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate relative path: %v", err)
+	}
+	return filepath.Join(baseOutputDir, relativeFromInput), nil
+}
+
+// linkFile hardlinks dst to src, or symlinks it when hardlinks are not supported (for example
+// across file systems), and copies the file as a last resort. It returns the method used.
+func linkFile(src, dst string) (string, error) {
+	if ok, err := replaceable(src, dst); !ok {
+		return "none", err
+	}
+
+	if err := os.Link(src, dst); err == nil {
+		return "hardlink", nil
+	}
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Symlink(absSrc, dst); err == nil {
+		return "symlink", nil
+	}
+	return "copy", copyFile(src, dst)
+}
+
+// replaceable removes dst so it can be written from src. It returns false when there is
+// nothing to write because dst is src itself, as in in-place builds.
+func replaceable(src, dst string) (bool, error) {
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return false, err
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return false, err
+	}
+	if absSrc == absDst {
+		return false, nil
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,218 @@
+package resource
+
+import (
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ScriptGlobs maps the script srcs with wildcards of a meta.xml to the srcs they expand to
+type ScriptGlobs map[string][]string
+
+// IsGlob reports whether a script src is a wildcard pattern expanded at build time, such as
+// scripts/*.lua or scripts/**/*.lua
+func IsGlob(src string) bool {
+	return strings.ContainsAny(src, "*?[") && !IsURL(src)
+}
+
+// expandScripts replaces the scripts whose src is a wildcard pattern by one script per
+// matching file of baseDir, in sorted order and with the attributes of the pattern entry. Files
+// referenced by another script entry are left out, so a pattern can follow the scripts that
+// must load first. It returns the scripts and the srcs each pattern expands to.
+func expandScripts(scripts []Script, baseDir string) ([]Script, ScriptGlobs, error) {
+	listed := make(map[string]bool)
+	for _, script := range scripts {
+		if !IsGlob(script.Src) {
+			listed[path.Clean(filepath.ToSlash(script.Src))] = true
+		}
+	}
+
+	var expanded []Script
+	var globs ScriptGlobs
+	for _, script := range scripts {
+		if !IsGlob(script.Src) {
+			expanded = append(expanded, script)
+			continue
+		}
+		if globs == nil {
+			globs = make(ScriptGlobs)
+		}
+		if _, ok := globs[script.Src]; ok {
+			// The files were added by the first entry of the pattern
+			continue
+		}
+
+		matches, err := globFiles(baseDir, script.Src)
+		if err != nil {
+			return nil, nil, err
+		}
+		srcs := []string{}
+		for _, match := range matches {
+			if listed[match] {
+				continue
+			}
+			listed[match] = true
+			srcs = append(srcs, match)
+			entry := script
+			entry.Src = match
+			expanded = append(expanded, entry)
+		}
+		globs[script.Src] = srcs
+	}
+	return expanded, globs, nil
+}
+
+// globFiles returns the slash-separated paths of the regular files of baseDir matching pattern,
+// sorted. A ** segment matches any number of directories.
+func globFiles(baseDir, pattern string) ([]string, error) {
+	segments := patternSegments(pattern)
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid script pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Only the directory before the first wildcard is walked
+	prefix := 0
+	for prefix < len(segments)-1 && !strings.ContainsAny(segments[prefix], "*?[") {
+		prefix++
+	}
+	root := path.Join(segments[:prefix]...)
+	if root != "" && !filepath.IsLocal(filepath.FromSlash(root)) {
+		return nil, fmt.Errorf("script pattern %q leaves the resource directory", pattern)
+	}
+
+	var matches []string
+	err := filepath.WalkDir(filepath.Join(baseDir, filepath.FromSlash(root)), func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if p == filepath.Join(baseDir, filepath.FromSlash(root)) {
+				// A missing directory matches no files
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(baseDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchSegments(segments, strings.Split(rel, "/")) {
+			matches = append(matches, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand script pattern %q: %v", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// MatchesGlob reports whether the file at fullPath matches a script src with wildcards of the
+// resource, so adding or removing it changes the scripts of the resource
+func (r *Resource) MatchesGlob(fullPath string) bool {
+	rel, err := filepath.Rel(r.BaseDir, fullPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	name := strings.Split(filepath.ToSlash(rel), "/")
+	for pattern := range r.Globs {
+		if matchSegments(patternSegments(pattern), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternSegments splits a script src with wildcards into its path segments
+func patternSegments(pattern string) []string {
+	return strings.Split(path.Clean(filepath.ToSlash(pattern)), "/")
+}
+
+// matchSegments reports whether the segments of a slash-separated path match the segments of a
+// pattern, where ** matches zero or more segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// expandGlobTags replaces the script tags of a meta.xml content whose src is a wildcard pattern
+// by one tag per file the pattern expands to, on lines of their own with the indentation of the
+// pattern tag. Patterns matching no files lose their tag.
+func (r *Resource) expandGlobTags(content string) string {
+	if len(r.Globs) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range scriptTagRegex.FindAllStringIndex(content, -1) {
+		tag := content[span[0]:span[1]]
+		srcs, ok := r.Globs[srcAttrValue(tag)]
+		if !ok {
+			continue
+		}
+
+		// The copies are separated like the pattern tag is from the line before it
+		lineStart := strings.LastIndex(content[:span[0]], "\n") + 1
+		separator := ""
+		if lineStart > 0 && strings.TrimSpace(content[lineStart:span[0]]) == "" {
+			separator = "\n" + content[lineStart:span[0]]
+			if lineStart >= 2 && content[lineStart-2] == '\r' {
+				separator = "\r" + separator
+			}
+		}
+
+		start := span[0]
+		if len(srcs) == 0 && separator != "" {
+			// The line of the tag is removed with its line break
+			start = span[0] - len(separator)
+		}
+		b.WriteString(content[last:start])
+		for i, src := range srcs {
+			if i > 0 {
+				b.WriteString(separator)
+			}
+			b.WriteString(setSrcAttr(tag, src))
+		}
+		last = span[1]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// setSrcAttr replaces the value of the first src attribute of tag, keeping its quotes
+func setSrcAttr(tag, src string) string {
+	match := srcAttrRegex.FindStringSubmatchIndex(tag)
+	if match == nil {
+		return tag
+	}
+	start, end := match[2], match[3]
+	if start < 0 {
+		start, end = match[4], match[5]
+	}
+	return tag[:start] + html.EscapeString(src) + tag[end:]
+}
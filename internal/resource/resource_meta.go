@@ -2,15 +2,21 @@ package resource
 
 import (
 	"fmt"
+	"html"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 // luaToLuacRegex is the compiled regex pattern for replacing .lua with .luac in src attributes
 var luaToLuacRegex = regexp.MustCompile(`(src\s*=\s*"[^"]*?)\.lua(")|(src\s*=\s*'[^']*?)\.lua(')`)
 
+// scriptTagRegex matches <script...> tags (both self-closing and with closing tags)
+var scriptTagRegex = regexp.MustCompile(`(?s)<script\b[^>]*?(?:/>|>.*?</script>)`)
+
 // copyMetaFile copies the meta.xml file to the output directory and updates lua file references to luac
 func (r *Resource) copyMetaFile(baseOutputDir, absInputPath, outputFile string) error {
 	// Calculate the output path for meta.xml
@@ -43,11 +49,12 @@ func (r *Resource) copyMetaFile(baseOutputDir, absInputPath, outputFile string)
 		return fmt.Errorf("failed to copy and modify meta.xml: %v", err)
 	}
 
-	fmt.Printf("  ✓ Copied and updated meta.xml\n")
+	r.logger().Info("Copied and updated meta.xml", "success", true)
 	return nil
 }
 
-// copyAndModifyMetaFile copies the meta.xml file and updates .lua file extensions to .luac using regex
+// CopyAndModifyMetaFile copies the meta.xml file and updates .lua file extensions of compiled
+// scripts to .luac. Only the edited script tags change, the rest of the file is copied as is.
 func (r *Resource) CopyAndModifyMetaFile(src, dst string) error {
 	// Read the source meta.xml file
 	content, err := os.ReadFile(src)
@@ -55,18 +62,20 @@ func (r *Resource) CopyAndModifyMetaFile(src, dst string) error {
 		return fmt.Errorf("failed to read source meta.xml: %v", err)
 	}
 
-	// Convert to string for regex processing
-	metaContent := string(content)
-
-	// Use regex to replace .lua with .luac in src attributes
-	// Replace .lua with .luac while preserving the quotes
-	modifiedContent := luaToLuacRegex.ReplaceAllStringFunc(metaContent, func(match string) string {
-		if strings.Contains(match, `"`) {
-			return strings.Replace(match, ".lua\"", ".luac\"", 1)
-		} else {
-			return strings.Replace(match, ".lua'", ".luac'", 1)
-		}
-	})
+	var modifiedContent string
+	if r.RegexMeta {
+		modifiedContent = r.rewriteMetaRegex(r.expandGlobTags(string(content)))
+	} else if modifiedContent, err = r.rewriteMeta(r.expandGlobTags(string(content))); err != nil {
+		return fmt.Errorf("failed to parse meta.xml: %v (use -meta-regex to rewrite it with regular expressions)", err)
+	}
+	client, server := r.compiledSides()
+	if modifiedContent, err = r.raiseMinVersion(modifiedContent, client, server); err != nil {
+		return err
+	}
+	if modifiedContent, err = r.setInfoAttrs(modifiedContent); err != nil {
+		return err
+	}
+	modifiedContent = bannerComment(modifiedContent, r.expand(r.Banner))
 
 	// Write the modified content to the destination file
 	err = os.WriteFile(dst, []byte(modifiedContent), 0644)
@@ -77,6 +86,55 @@ func (r *Resource) CopyAndModifyMetaFile(src, dst string) error {
 	return nil
 }
 
+// rewriteMeta points the script tags of a meta.xml content to their .luac file. Verbatim
+// scripts are copied as source and keep their .lua src, as do URL scripts.
+func (r *Resource) rewriteMeta(content string) (string, error) {
+	doc, err := parseMetaDocument(content)
+	if err != nil {
+		return "", err
+	}
+	for _, i := range doc.children("script") {
+		script := doc.tokens[i]
+		if src := script.attr("src"); strings.HasSuffix(src, ".lua") && !r.IsVerbatim(src) && !IsURL(src) {
+			script.setAttr("src", src+"c")
+		}
+	}
+	if r.NoCache {
+		doc.uncacheScripts()
+	}
+	return doc.String(), nil
+}
+
+// rewriteMetaRegex is rewriteMeta using regular expressions on the whole content, as done
+// before meta.xml files were tokenized
+func (r *Resource) rewriteMetaRegex(content string) string {
+	// Replace .lua with .luac while preserving the quotes
+	modifiedContent := luaToLuacRegex.ReplaceAllStringFunc(content, func(match string) string {
+		// Verbatim scripts are copied as source and keep their .lua src, as do URL scripts
+		if src := srcAttrValue(match); r.IsVerbatim(src) || IsURL(src) {
+			return match
+		}
+		return luacSrc(match)
+	})
+	if r.NoCache {
+		modifiedContent = uncachedScriptTags(modifiedContent)
+	}
+	return modifiedContent
+}
+
+// luacSrc replaces .lua with .luac in a src attribute matched by luaToLuacRegex, preserving the quotes
+func luacSrc(match string) string {
+	if strings.Contains(match, `"`) {
+		return strings.Replace(match, ".lua\"", ".luac\"", 1)
+	}
+	return strings.Replace(match, ".lua'", ".luac'", 1)
+}
+
+// luacScriptTag returns the script tag with its .lua src replaced by the compiled .luac file
+func luacScriptTag(tag string) string {
+	return luaToLuacRegex.ReplaceAllStringFunc(tag, luacSrc)
+}
+
 // copyMergedMetaFile copies the meta.xml file to the output directory and updates it for merged compilation
 func (r *Resource) copyMergedMetaFile(baseOutputDir, absInputPath, outputFile string, hasClientFiles, hasServerFiles bool) error {
 	// Calculate the output path for meta.xml
@@ -109,11 +167,13 @@ func (r *Resource) copyMergedMetaFile(baseOutputDir, absInputPath, outputFile st
 		return fmt.Errorf("failed to copy and modify meta.xml: %v", err)
 	}
 
-	fmt.Printf("  ✓ Copied and updated meta.xml for merged compilation\n")
+	r.logger().Info("Copied and updated meta.xml for merged compilation", "success", true)
 	return nil
 }
 
-// copyAndModifyMergedMetaFile copies the meta.xml file and updates it for merged compilation
+// CopyAndModifyMergedMetaFile copies the meta.xml file and updates it for merged compilation:
+// the merged script tags are replaced by the tags of the bundles. Only the edited script tags
+// change, the rest of the file is copied as is.
 func (r *Resource) CopyAndModifyMergedMetaFile(src, dst string, hasClientFiles, hasServerFiles bool) error {
 	// Read the source meta.xml file
 	content, err := os.ReadFile(src)
@@ -121,61 +181,373 @@ func (r *Resource) CopyAndModifyMergedMetaFile(src, dst string, hasClientFiles,
 		return fmt.Errorf("failed to read source meta.xml: %v", err)
 	}
 
-	// Convert to string for regex processing
-	metaContent := string(content)
-
-	// Remove all existing <script> tags using regex
-	// This regex matches <script...> tags (both self-closing and with closing tags)
-	scriptRegex := regexp.MustCompile(`(?s)<script[^>]*(?:/>|>.*?</script>)`)
-	modifiedContent := scriptRegex.ReplaceAllString(metaContent, "")
-
 	// Build replacement script tags
 	var scriptTags []string
-
 	if hasClientFiles {
-		scriptTags = append(scriptTags, `    <script src="client.luac" type="client" cache="true" />`)
+		scriptTags = append(scriptTags, bundleScriptTag(r.logger(), r.BundlePath("client"), "client", r.bundleScripts("client")))
 	}
-
 	if hasServerFiles {
-		scriptTags = append(scriptTags, `    <script src="server.luac" type="server" cache="true" />`)
+		scriptTags = append(scriptTags, bundleScriptTag(r.logger(), r.BundlePath("server"), "server", r.bundleScripts("server")))
 	}
 
-	// Find the position to insert the new script tags
-	// Look for the closing </meta> tag and insert before it
-	metaEndRegex := regexp.MustCompile(`(\s*</meta>)`)
-	if metaEndRegex.MatchString(modifiedContent) {
-		// Insert the new script tags before the closing </meta> tag
-		replacement := ""
-		if len(scriptTags) > 0 {
-			replacement = strings.Join(scriptTags, "\n") + "\n$1"
-		} else {
-			replacement = "$1"
-		}
-		modifiedContent = metaEndRegex.ReplaceAllString(modifiedContent, replacement)
-	} else {
-		// Fallback: if no closing </meta> tag found, look for <meta> self-closing tag
-		metaSelfClosingRegex := regexp.MustCompile(`(<meta[^>]*)/>\s*$`)
-		if metaSelfClosingRegex.MatchString(modifiedContent) {
-			// Convert self-closing <meta/> to <meta>...</meta> format
-			replacement := "$1>\n"
-			if len(scriptTags) > 0 {
-				replacement += strings.Join(scriptTags, "\n") + "\n"
+	var modifiedContent string
+	if r.RegexMeta {
+		modifiedContent = r.rewriteMergedMetaRegex(r.expandGlobTags(string(content)), scriptTags)
+	} else if modifiedContent, err = r.rewriteMergedMeta(r.expandGlobTags(string(content)), scriptTags); err != nil {
+		return fmt.Errorf("failed to parse meta.xml: %v (use -meta-regex to rewrite it with regular expressions)", err)
+	}
+	client, server := r.compiledSides()
+	if modifiedContent, err = r.raiseMinVersion(modifiedContent, client, server); err != nil {
+		return err
+	}
+	if modifiedContent, err = r.setInfoAttrs(modifiedContent); err != nil {
+		return err
+	}
+	modifiedContent = bannerComment(modifiedContent, r.expand(r.Banner))
+
+	// Write the modified content to the destination file
+	err = os.WriteFile(dst, []byte(modifiedContent), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write modified meta.xml: %v", err)
+	}
+
+	return nil
+}
+
+// rewriteMergedMeta removes the merged script tags of a meta.xml content and adds scriptTags at
+// the end of the <meta> element. Verbatim and URL scripts stay as they are and unmerged scripts
+// point to their own .luac file.
+func (r *Resource) rewriteMergedMeta(content string, scriptTags []string) (string, error) {
+	doc, err := parseMetaDocument(content)
+	if err != nil {
+		return "", err
+	}
+	for _, i := range doc.children("script") {
+		script := doc.tokens[i]
+		src := script.attr("src")
+		switch {
+		case r.IsVerbatim(src) || IsURL(src):
+		case r.IsUnmerged(src):
+			if strings.HasSuffix(src, ".lua") {
+				script.setAttr("src", src+"c")
 			}
-			replacement += "</meta>"
-			modifiedContent = metaSelfClosingRegex.ReplaceAllString(modifiedContent, replacement)
+		default:
+			doc.removeElement(i)
+		}
+	}
+	for _, tag := range scriptTags {
+		if err := doc.appendChild(tag); err != nil {
+			return "", err
+		}
+	}
+	if r.NoCache {
+		doc.uncacheScripts()
+	}
+	return doc.String(), nil
+}
+
+// rewriteMergedMetaRegex is rewriteMergedMeta using regular expressions on the whole content, as
+// done before meta.xml files were tokenized
+func (r *Resource) rewriteMergedMetaRegex(content string, scriptTags []string) string {
+	// Remove all existing <script> tags using regex, except verbatim and URL scripts which stay as
+	// they are and unmerged scripts which point to their own .luac file
+	modifiedContent := scriptTagRegex.ReplaceAllStringFunc(content, func(match string) string {
+		src := srcAttrValue(match)
+		if r.IsVerbatim(src) || IsURL(src) {
+			return match
+		}
+		if r.IsUnmerged(src) {
+			return luacScriptTag(match)
+		}
+		return ""
+	})
+	for i, tag := range scriptTags {
+		scriptTags[i] = "    " + tag
+	}
+
+	inserted := ""
+	if len(scriptTags) > 0 {
+		inserted = strings.Join(scriptTags, "\n") + "\n"
+	}
+
+	// Insert the new script tags before the whitespace ahead of the closing </meta> tag, or convert
+	// a self-closing <meta/> to <meta>...</meta>, or as a last resort append them at the end
+	trimmed := strings.TrimRight(modifiedContent, " \t\r\n")
+	open := strings.LastIndex(trimmed, "<meta")
+	if end := strings.LastIndex(modifiedContent, "</meta>"); end >= 0 {
+		end = len(strings.TrimRight(modifiedContent[:end], " \t\r\n"))
+		modifiedContent = modifiedContent[:end] + inserted + modifiedContent[end:]
+	} else if open >= 0 && strings.HasSuffix(trimmed, "/>") && !strings.Contains(trimmed[open:len(trimmed)-2], ">") {
+		modifiedContent = trimmed[:len(trimmed)-2] + ">\n" + inserted + "</meta>"
+	} else if inserted != "" {
+		modifiedContent = strings.TrimSpace(modifiedContent) + "\n" + inserted
+	}
+
+	if r.NoCache {
+		modifiedContent = uncachedScriptTags(modifiedContent)
+	}
+	return modifiedContent
+}
+
+// Patterns used to rewrite the cache attribute of script tags
+var (
+	cacheAttrRegex = regexp.MustCompile(`\s+cache\s*=\s*(?:"[^"]*"|'[^']*')`)
+	typeAttrRegex  = regexp.MustCompile(`\btype\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// uncacheScripts sets cache="false" on the client and shared script tags of a meta.xml, so
+// clients run them without writing them to their disk cache. Scripts with a URL src are left as
+// they are.
+func (d *metaDocument) uncacheScripts() {
+	for _, i := range d.children("script") {
+		script := d.tokens[i]
+		if IsURL(script.attr("src")) {
+			continue
+		}
+		if kind := strings.ToLower(script.attr("type")); kind == "client" || kind == "shared" {
+			script.setAttr("cache", "false")
+		}
+	}
+}
+
+// uncachedScriptTags sets cache="false" on the client and shared script tags of a meta.xml
+// content, so clients run them without writing them to their disk cache. Scripts with a URL
+// src are left as they are.
+func uncachedScriptTags(content string) string {
+	return scriptTagRegex.ReplaceAllStringFunc(content, func(tag string) string {
+		if IsURL(srcAttrValue(tag)) {
+			return tag
+		}
+		match := typeAttrRegex.FindStringSubmatch(tag)
+		if match == nil {
+			return tag
+		}
+		if kind := strings.ToLower(match[1] + match[2]); kind != "client" && kind != "shared" {
+			return tag
+		}
+
+		end := strings.Index(tag, ">")
+		open := cacheAttrRegex.ReplaceAllString(tag[:end+1], "")
+		if strings.HasSuffix(open, "/>") {
+			open = strings.TrimRight(strings.TrimSuffix(open, "/>"), " \t\r\n") + ` cache="false" />`
 		} else {
-			// Last resort: append before the end of the file
-			if len(scriptTags) > 0 {
-				modifiedContent = strings.TrimSpace(modifiedContent) + "\n" + strings.Join(scriptTags, "\n") + "\n"
-			}
+			open = strings.TrimRight(strings.TrimSuffix(open, ">"), " \t\r\n") + ` cache="false">`
+		}
+		return open + tag[end+1:]
+	})
+}
+
+// bundleScripts returns the meta.xml entries of the scripts merged into the bundle of kind
+func (r *Resource) bundleScripts(kind string) []Script {
+	var scripts []Script
+	for _, script := range r.Meta.Scripts {
+		if r.isMerged(script.Src) && runsOn(script.Type, kind) {
+			scripts = append(scripts, script)
 		}
 	}
+	return scripts
+}
 
-	// Write the modified content to the destination file
-	err = os.WriteFile(dst, []byte(modifiedContent), 0644)
+// bundleScriptTag returns the script tag of the bundle src of kind, carrying over the attributes
+// of the scripts merged into it. The bundle is kept out of the client cache when any of its
+// scripts is, so merging never writes a protected script to the client's disk, and it keeps
+// the validate attribute its scripts agree on.
+func bundleScriptTag(log *slog.Logger, src, kind string, scripts []Script) string {
+	cache := "true"
+	validate, agreed := "", true
+	for i, script := range scripts {
+		if strings.EqualFold(script.Cache, "false") {
+			cache = "false"
+		}
+		if i == 0 {
+			validate = script.Validate
+		} else if !strings.EqualFold(script.Validate, validate) {
+			agreed = false
+		}
+	}
+
+	tag := `<script src="` + html.EscapeString(src) + `" type="` + kind + `" cache="` + cache + `"`
+	if !agreed {
+		log.Warn("Merged scripts disagree on the validate attribute, the bundle uses the default", "bundle", src)
+	} else if validate != "" {
+		tag += ` validate="` + html.EscapeString(validate) + `"`
+	}
+	return tag + " />"
+}
+
+// srcAttrRegex matches the src attribute of a tag
+var srcAttrRegex = regexp.MustCompile(`\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// srcAttrValue returns the value of the first src attribute in tag, or an empty string
+func srcAttrValue(tag string) string {
+	match := srcAttrRegex.FindStringSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+	return match[1] + match[2]
+}
+
+// SetLazyFiles rewrites the meta.xml at metaPath so that the <file> entries whose src is in
+// lazy use download="false", and adds a client script tag for loader when it is missing.
+// The loader is placed before the other scripts so they can use it while loading.
+func SetLazyFiles(metaPath string, lazy map[string]bool, loader string) error {
+	content, err := os.ReadFile(metaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read meta.xml: %v", err)
+	}
+
+	doc, err := parseMetaDocument(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse meta.xml: %v", err)
+	}
+	for _, i := range doc.children("file") {
+		if file := doc.tokens[i]; lazy[filepath.ToSlash(file.attr("src"))] {
+			file.setAttr("download", "false")
+		}
+	}
+	if err := doc.prependScript(loader, "client"); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(metaPath, []byte(doc.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write modified meta.xml: %v", err)
+	}
+	return nil
+}
+
+// AddClientScript adds a client script tag for src to the meta.xml at metaPath, before the
+// other scripts so they can use it while loading. Nothing changes if the tag already exists.
+func AddClientScript(metaPath, src string) error {
+	return AddScript(metaPath, src, "client")
+}
+
+// AddScript adds a script tag of the given type for src to the meta.xml at metaPath, before
+// the other scripts. Nothing changes if the tag already exists.
+func AddScript(metaPath, src, kind string) error {
+	content, err := os.ReadFile(metaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read meta.xml: %v", err)
+	}
+
+	doc, err := parseMetaDocument(string(content))
 	if err != nil {
+		return fmt.Errorf("failed to parse meta.xml: %v", err)
+	}
+	if err := doc.prependScript(src, kind); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(metaPath, []byte(doc.String()), 0644); err != nil {
 		return fmt.Errorf("failed to write modified meta.xml: %v", err)
 	}
+	return nil
+}
+
+// StampMeta sets the build attribute of the <info> tag of the meta.xml at metaPath to build,
+// adding the tag when it is missing, and adds a shared script tag for script before the other
+// scripts so they can use the constants it defines. Stamping an already stamped meta.xml
+// replaces the previous build number.
+func StampMeta(metaPath string, build int, script string) error {
+	content, err := os.ReadFile(metaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read meta.xml: %v", err)
+	}
+
+	doc, err := parseMetaDocument(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse meta.xml: %v", err)
+	}
+	info, err := doc.child("info")
+	if err != nil {
+		return err
+	}
+	doc.tokens[info].setAttr("build", strconv.Itoa(build))
+	if err := doc.prependScript(script, "shared"); err != nil {
+		return err
+	}
 
+	if err := os.WriteFile(metaPath, []byte(doc.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write modified meta.xml: %v", err)
+	}
 	return nil
 }
+
+// infoAttrNamePattern matches the attribute names accepted for the <info> tag
+var infoAttrNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// InfoAttr is an attribute set on the <info> tag of the output meta.xml
+type InfoAttr struct {
+	Name   string
+	Value  string // {resource} and {version} are replaced by the name and version of the resource
+	Append bool   // Value is appended to the value of the source meta.xml instead of replacing it
+}
+
+// ParseInfoAttr parses an <info> attribute given as name=value, or name+=value to append value
+// to the attribute of the source meta.xml
+func ParseInfoAttr(spec string) (InfoAttr, error) {
+	name, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		return InfoAttr{}, fmt.Errorf("invalid info attribute %q (use name=value or name+=value)", spec)
+	}
+	attr := InfoAttr{Name: strings.TrimSpace(name), Value: value}
+	if trimmed, found := strings.CutSuffix(attr.Name, "+"); found {
+		attr.Name, attr.Append = strings.TrimSpace(trimmed), true
+	}
+	if !infoAttrNamePattern.MatchString(attr.Name) {
+		return InfoAttr{}, fmt.Errorf("invalid info attribute name %q", attr.Name)
+	}
+	return attr, nil
+}
+
+// String returns the attribute as name=value, or name+=value when it is appended
+func (a InfoAttr) String() string {
+	if a.Append {
+		return a.Name + "+=" + a.Value
+	}
+	return a.Name + "=" + a.Value
+}
+
+// setInfoAttrs sets the Info attributes of r on the <info> tag of a meta.xml content, adding the
+// tag when it is missing. Existing values are replaced in place, keeping their quotes, and other
+// attributes are added after the others.
+func (r *Resource) setInfoAttrs(content string) (string, error) {
+	if len(r.Info) == 0 {
+		return content, nil
+	}
+	doc, err := parseMetaDocument(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse meta.xml: %v", err)
+	}
+	i, err := doc.child("info")
+	if err != nil {
+		return "", err
+	}
+
+	info := doc.tokens[i]
+	for _, attr := range r.Info {
+		value := r.expand(attr.Value)
+		if attr.Append {
+			value = info.attr(attr.Name) + value
+		}
+		info.setAttr(attr.Name, value)
+	}
+	return doc.String(), nil
+}
+
+// prependScript adds a script tag of the given type for src before the first script tag of the
+// document, or at the end of the root element when it has no scripts. Nothing changes if a script
+// tag for src already exists.
+func (d *metaDocument) prependScript(src, kind string) error {
+	scripts := d.children("script")
+	for _, i := range scripts {
+		if d.tokens[i].attr("src") == src {
+			return nil
+		}
+	}
+
+	tag := `<script src="` + html.EscapeString(src) + `" type="` + html.EscapeString(kind) + `" />`
+	if len(scripts) == 0 {
+		return d.appendChild(tag)
+	}
+	return d.insertChild(scripts[0], tag)
+}
@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// compiledSides reports whether r has compiled client and server scripts. Shared scripts run on
+// both sides, verbatim and URL scripts are not compiled.
+func (r *Resource) compiledSides() (client, server bool) {
+	for _, script := range r.Meta.Scripts {
+		if r.IsVerbatim(script.Src) || IsURL(script.Src) {
+			continue
+		}
+		switch strings.ToLower(script.Type) {
+		case "client":
+			client = true
+		case "shared":
+			client, server = true, true
+		default:
+			server = true
+		}
+	}
+	return client, server
+}
+
+// raiseMinVersion raises the <min_mta_version> of a meta.xml content to the MinVersion of r on
+// the client and server sides, those with compiled scripts
+func (r *Resource) raiseMinVersion(content string, client, server bool) (string, error) {
+	if r.MinVersion == "" {
+		return content, nil
+	}
+	var sides []string
+	if client {
+		sides = append(sides, "client")
+	}
+	if server {
+		sides = append(sides, "server")
+	}
+	return raiseMinVersion(r.logger(), content, r.MinVersion, sides)
+}
+
+// RaiseMinVersion raises the client and server attributes of the <min_mta_version> of a meta.xml
+// content to version, for a resource compiled at an obfuscation level that older versions
+// cannot run
+func RaiseMinVersion(content, version string) (string, error) {
+	return raiseMinVersion(slog.Default(), content, version, []string{"client", "server"})
+}
+
+// raiseMinVersion sets the attributes named sides of the <min_mta_version> of a meta.xml content
+// to version when they are missing or older, adding the tag when there is none. Versions that
+// cannot be parsed are left as they are.
+func raiseMinVersion(log *slog.Logger, content, version string, sides []string) (string, error) {
+	if len(sides) == 0 {
+		return content, nil
+	}
+	doc, err := parseMetaDocument(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse meta.xml: %v", err)
+	}
+	i, err := doc.child("min_mta_version")
+	if err != nil {
+		return "", err
+	}
+
+	tag := doc.tokens[i]
+	for _, side := range sides {
+		if current := tag.attr(side); current != "" {
+			comparison, err := compiler.CompareVersions(current, version)
+			if err != nil {
+				log.Warn("Cannot check the minimum MTA version needed by the obfuscation level", "side", side, "error", err)
+				continue
+			}
+			if comparison >= 0 {
+				continue
+			}
+			log.Info("Raised minimum MTA version for the obfuscation level", "side", side, "from", current, "to", version)
+		}
+		tag.setAttr(side, version)
+	}
+	return doc.String(), nil
+}
@@ -0,0 +1,51 @@
+package resource
+
+import (
+	"testing"
+)
+
+func TestRaiseMinVersion(t *testing.T) {
+	r := &Resource{Name: "race", MinVersion: "1.5.6-9.18728"}
+	tests := []struct {
+		name           string
+		content        string
+		client, server bool
+		expected       string
+	}{
+		{
+			"Missing tag",
+			"<meta>\n    <script src=\"a.luac\" type=\"client\" />\n</meta>",
+			true, false,
+			"<meta>\n    <min_mta_version client=\"1.5.6-9.18728\" />\n    <script src=\"a.luac\" type=\"client\" />\n</meta>",
+		},
+		{
+			"Older and newer sides",
+			"<meta>\n    <min_mta_version server='1.5.0' client=\"1.6.0\"></min_mta_version>\n</meta>",
+			true, true,
+			"<meta>\n    <min_mta_version server='1.5.6-9.18728' client=\"1.6.0\"></min_mta_version>\n</meta>",
+		},
+		{
+			"Invalid version left alone",
+			"<meta>\n    <min_mta_version client=\"latest\"/>\n</meta>",
+			true, true,
+			"<meta>\n    <min_mta_version client=\"latest\" server=\"1.5.6-9.18728\"/>\n</meta>",
+		},
+		{
+			"No compiled scripts",
+			"<meta>\n</meta>",
+			false, false,
+			"<meta>\n</meta>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.raiseMinVersion(tt.content, tt.client, tt.server)
+			if err != nil {
+				t.Fatalf("raiseMinVersion failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Unexpected meta.xml:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
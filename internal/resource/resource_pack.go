@@ -0,0 +1,309 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// Patterns used to combine the meta.xml files of pack members
+var (
+	commentRegex      = regexp.MustCompile(`(?s)<!--.*?-->`)
+	oopRegex          = regexp.MustCompile(`<oop>\s*true\s*</oop>`)
+	settingsRegex     = regexp.MustCompile(`<settings\b`)
+	includeRegex      = regexp.MustCompile(`(?s)<include\b[^>]*?(?:/>|>.*?</include>)`)
+	resourceAttrRegex = regexp.MustCompile(`\bresource\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// Patterns matching the meta.xml entries carried over from the members to the pack
+var (
+	fileEntryRegex       = regexp.MustCompile(`(?s)<file\b[^>]*?(?:/>|>.*?</file>)`)
+	mapEntryRegex        = regexp.MustCompile(`(?s)<map\b[^>]*?(?:/>|>.*?</map>)`)
+	configEntryRegex     = regexp.MustCompile(`(?s)<config\b[^>]*?(?:/>|>.*?</config>)`)
+	htmlEntryRegex       = regexp.MustCompile(`(?s)<html\b[^>]*?(?:/>|>.*?</html>)`)
+	exportEntryRegex     = regexp.MustCompile(`(?s)<export\b[^>]*?(?:/>|>.*?</export>)`)
+	aclRequestEntryRegex = regexp.MustCompile(`(?s)<aclrequest\b[^>]*?(?:/>|>.*?</aclrequest>)`)
+)
+
+// packEntryRegexes match the meta.xml entries carried over from the members to the pack, in output order
+var packEntryRegexes = []*regexp.Regexp{
+	fileEntryRegex,
+	mapEntryRegex,
+	configEntryRegex,
+	htmlEntryRegex,
+	exportEntryRegex,
+	aclRequestEntryRegex,
+}
+
+// CompilePack builds the members into a single resource named name in outputDir. Their scripts
+// are merged per type into client.luac and server.luac, except unmerged scripts which keep their
+// own .luac file, their files are copied into the same tree and their meta.xml entries are
+// combined. Two members cannot provide different files at the same path.
+func CompilePack(comp compiler.CLICompiler, name string, members []*Resource, outputDir string, options compiler.CompilationOptions) (CompileResult, error) {
+	pack := &Resource{Name: name, BaseDir: outputDir}
+	if len(members) > 0 {
+		// Members share the build's bundle name and merge strategy
+		pack.BundleName = members[0].BundleName
+		pack.Concat = members[0].Concat
+		pack.Isolate = members[0].Isolate
+		pack.Transform = members[0].Transform
+		pack.NoCache = members[0].NoCache
+		pack.Info = members[0].Info
+		pack.MinVersion = members[0].MinVersion
+		pack.Banner = members[0].Banner
+	}
+	log := pack.logger()
+	result := CompileResult{MergeMode: true, OutputDir: outputDir}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return result, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	var client, server []FileReference
+	owners := make(map[string]FileReference)
+	var copies []FileReference
+	var copyOwners []*Resource
+	scriptOwners := make(map[string]FileReference)
+	var unmerged []FileReference
+	var unmergedOwners []*Resource
+	for _, member := range members {
+		memberClient, memberServer := member.GetMergedLuaFiles()
+		client = append(client, memberClient...)
+		server = append(server, memberServer...)
+
+		for _, fileRef := range member.unmergedLuaFiles() {
+			src := filepath.ToSlash(filepath.Clean(fileRef.RelativePath))
+			if owner, ok := scriptOwners[src]; ok {
+				same, err := sameContent(owner.FullPath, fileRef.FullPath)
+				if err != nil {
+					return result, fmt.Errorf("failed to compare %s: %v", src, err)
+				}
+				if !same {
+					return result, fmt.Errorf("members provide different unmerged scripts at %s (%s and %s)", src, owner.FullPath, fileRef.FullPath)
+				}
+				continue
+			}
+			scriptOwners[src] = fileRef
+			unmerged = append(unmerged, fileRef)
+			unmergedOwners = append(unmergedOwners, member)
+		}
+
+		for _, fileRef := range member.getNonScriptFiles() {
+			src := filepath.ToSlash(filepath.Clean(fileRef.RelativePath))
+			if owner, ok := owners[src]; ok {
+				same, err := sameContent(owner.FullPath, fileRef.FullPath)
+				if err != nil {
+					return result, fmt.Errorf("failed to compare %s: %v", src, err)
+				}
+				if !same {
+					return result, fmt.Errorf("members provide different files at %s (%s and %s)", src, owner.FullPath, fileRef.FullPath)
+				}
+				continue
+			}
+			owners[src] = fileRef
+			copies = append(copies, fileRef)
+			copyOwners = append(copyOwners, member)
+		}
+	}
+
+	log.Info("Found Lua scripts to pack", "resources", len(members), "client", len(client), "server", len(server), "unmerged", len(unmerged))
+
+	result.FileCopy = FileCopyBatchResult{Results: make([]FileCopyResult, 0, len(copies)), TotalFiles: len(copies)}
+	for i, fileRef := range copies {
+		copyResult := copyOwners[i].processSingleFile(fileRef, "", "", outputDir)
+		result.FileCopy.Results = append(result.FileCopy.Results, copyResult)
+		if copyResult.Success {
+			result.FileCopy.SuccessCount++
+			result.FileCopy.TotalSize += copyResult.Size
+		} else {
+			result.FileCopy.ErrorCount++
+		}
+	}
+	logFileCopyResults(log, result.FileCopy)
+
+	batch := &result.Compilation
+	totalStartTime := time.Now()
+	if len(client) > 0 {
+		batch.add(pack.compileBundle(comp, "client", client, "", "", outputDir, options))
+	}
+	if len(server) > 0 {
+		batch.add(pack.compileBundle(comp, "server", server, "", "", outputDir, options))
+	}
+	for i, fileRef := range unmerged {
+		compileResult, _ := unmergedOwners[i].compileScript(comp, "", "", outputDir, fileRef, options)
+		batch.add(compileResult)
+	}
+	batch.TotalTime = time.Since(totalStartTime)
+	log.Info("Merge compilation completed", batch.logAttrs()...)
+
+	if err := writePackMeta(pack, members, len(client) > 0, len(server) > 0); err != nil {
+		return result, err
+	}
+	log.Info("Wrote combined meta.xml", "success", true)
+
+	if batch.ErrorCount > 0 {
+		return result, fmt.Errorf("compilation completed with %d errors", batch.ErrorCount)
+	}
+	if result.FileCopy.ErrorCount > 0 {
+		return result, fmt.Errorf("failed to copy %d file(s)", result.FileCopy.ErrorCount)
+	}
+	return result, nil
+}
+
+// writePackMeta writes the meta.xml of a pack, combining the entries of its members. Includes
+// of other members are dropped since they are part of the pack.
+func writePackMeta(pack *Resource, members []*Resource, hasClientFiles, hasServerFiles bool) error {
+	packed := map[string]bool{pack.Name: true}
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		packed[member.Name] = true
+		names = append(names, member.Name)
+	}
+
+	contents := make([]string, 0, len(members))
+	oop := false
+	for _, member := range members {
+		data, err := os.ReadFile(member.MetaXMLPath)
+		if err != nil {
+			return fmt.Errorf("failed to read meta.xml of %s: %v", member.Name, err)
+		}
+		content := commentRegex.ReplaceAllString(member.expandGlobTags(string(data)), "")
+		oop = oop || oopRegex.MatchString(content)
+		contents = append(contents, content)
+	}
+
+	lines := []string{
+		fmt.Sprintf(`    <info name="%s" author="mta-bundler" type="script" description="Generated by mta-bundler: pack of %s" />`,
+			html.EscapeString(pack.Name), html.EscapeString(strings.Join(names, ", "))),
+	}
+	if oop {
+		lines = append(lines, "    <oop>true</oop>")
+	}
+
+	// Entries are added once, file entries once per src with the attributes of the first member
+	seen := make(map[string]bool)
+	add := func(entry string) {
+		key := entry
+		if src := srcAttrValue(entry); src != "" {
+			key = entry[:strings.IndexAny(entry, " \t\r\n")] + " " + filepath.ToSlash(filepath.Clean(src))
+		}
+		if !seen[key] {
+			seen[key] = true
+			lines = append(lines, "    "+entry)
+		}
+	}
+	// Verbatim, URL and unmerged scripts keep their own tags, the merged bundles load after them
+	for i, member := range members {
+		for _, tag := range scriptTagRegex.FindAllString(contents[i], -1) {
+			src := srcAttrValue(tag)
+			if member.IsVerbatim(src) || IsURL(src) {
+				add(tag)
+			} else if member.IsUnmerged(src) {
+				add(luacScriptTag(tag))
+			}
+		}
+	}
+	var clientScripts, serverScripts []Script
+	for _, member := range members {
+		clientScripts = append(clientScripts, member.bundleScripts("client")...)
+		serverScripts = append(serverScripts, member.bundleScripts("server")...)
+	}
+	if hasClientFiles {
+		add(bundleScriptTag(pack.logger(), pack.BundlePath("client"), "client", clientScripts))
+	}
+	if hasServerFiles {
+		add(bundleScriptTag(pack.logger(), pack.BundlePath("server"), "server", serverScripts))
+	}
+
+	for _, entryRegex := range packEntryRegexes {
+		for _, content := range contents {
+			for _, entry := range entryRegex.FindAllString(content, -1) {
+				add(entry)
+			}
+		}
+	}
+	for _, content := range contents {
+		for _, entry := range includeRegex.FindAllString(content, -1) {
+			match := resourceAttrRegex.FindStringSubmatch(entry)
+			if match != nil && packed[match[1]+match[2]] {
+				continue
+			}
+			add(entry)
+		}
+	}
+
+	meta := "<meta>\n" + strings.Join(lines, "\n") + "\n</meta>\n"
+	if pack.NoCache {
+		meta = uncachedScriptTags(meta)
+	}
+	var client, server bool
+	for _, member := range members {
+		memberClient, memberServer := member.compiledSides()
+		client, server = client || memberClient, server || memberServer
+	}
+	meta, err := pack.raiseMinVersion(meta, client, server)
+	if err != nil {
+		return err
+	}
+	if meta, err = pack.setInfoAttrs(meta); err != nil {
+		return err
+	}
+	meta = bannerComment(meta, pack.expand(pack.Banner))
+	if err := os.WriteFile(filepath.Join(pack.BaseDir, "meta.xml"), []byte(meta), 0644); err != nil {
+		return fmt.Errorf("failed to write pack meta.xml: %v", err)
+	}
+	return nil
+}
+
+// sameContent reports whether two files have identical content
+func sameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		n, errA := io.ReadFull(fileA, bufA)
+		_, errB := io.ReadFull(fileB, bufB[:n])
+		if !bytes.Equal(bufA[:n], bufB[:n]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
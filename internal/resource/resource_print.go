@@ -1,19 +1,19 @@
 package resource
 
-import "fmt"
+import "log/slog"
 
-// printFileCopyResults logs the results of file copy operations
-func printFileCopyResults(result FileCopyBatchResult) {
+// logFileCopyResults logs the results of file copy operations
+func logFileCopyResults(log *slog.Logger, result FileCopyBatchResult) {
 	if result.TotalFiles == 0 {
 		return
 	}
 
-	fmt.Printf("  Copying %d non-script file(s)\n", result.TotalFiles)
+	log.Info("Copying non-script files", "count", result.TotalFiles)
 	for _, copyResult := range result.Results {
 		if copyResult.Success {
-			fmt.Printf("    ✓ Copied %s\n", copyResult.RelativePath)
+			log.Info("Copied", "file", copyResult.RelativePath, "success", true)
 		} else {
-			fmt.Printf("    ✗ Failed to copy %s: %v\n", copyResult.RelativePath, copyResult.Error)
+			log.Error("Failed to copy", "file", copyResult.RelativePath, "error", copyResult.Error)
 		}
 	}
 }
@@ -0,0 +1,64 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxReadmeSize is the number of bytes of a README read for reports, longer ones are truncated
+const MaxReadmeSize = 64 * 1024
+
+// readmeExtensions are the README extensions looked up at the resource root, in order of preference
+var readmeExtensions = []string{".md", ".txt", ""}
+
+// Readme is the README found at the root of a resource
+type Readme struct {
+	File      string // File name, such as README.md
+	Text      string // Content, up to MaxReadmeSize bytes
+	Truncated bool   // The file is longer than Text
+}
+
+// Readme returns the README at the root of the resource, matched without regard to case. The
+// bool is false when the resource has none.
+func (r *Resource) Readme() (Readme, bool, error) {
+	entries, err := os.ReadDir(r.BaseDir)
+	if err != nil {
+		return Readme{}, false, fmt.Errorf("failed to list %s: %v", r.BaseDir, err)
+	}
+
+	name := ""
+	for _, ext := range readmeExtensions {
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && strings.EqualFold(entry.Name(), "README"+ext) {
+				name = entry.Name()
+				break
+			}
+		}
+		if name != "" {
+			break
+		}
+	}
+	if name == "" {
+		return Readme{}, false, nil
+	}
+
+	file, err := os.Open(filepath.Join(r.BaseDir, name))
+	if err != nil {
+		return Readme{}, false, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MaxReadmeSize+1))
+	if err != nil {
+		return Readme{}, false, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	readme := Readme{File: name, Text: string(data)}
+	if len(data) > MaxReadmeSize {
+		readme.Text = strings.ToValidUTF8(string(data[:MaxReadmeSize]), "")
+		readme.Truncated = true
+	}
+	return readme, true, nil
+}
@@ -0,0 +1,254 @@
+package resource
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// SplitPart is a resource split off another one: the scripts and files below its folders
+type SplitPart struct {
+	Name    string   // Name of the part resource
+	Folders []string // Folders of the split resource, relative to its directory
+}
+
+// Patterns used to split a meta.xml into parts
+var (
+	// srcEntryRegexes match the meta.xml entries referencing a file of the resource
+	srcEntryRegexes   = []*regexp.Regexp{scriptTagRegex, fileEntryRegex, mapEntryRegex, configEntryRegex, htmlEntryRegex}
+	minVersionRegex   = regexp.MustCompile(`(?s)<min_mta_version\b[^>]*?(?:/>|>.*?</min_mta_version>)`)
+	functionAttrRegex = regexp.MustCompile(`\bfunction\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// removedRegex matches the placeholder left by a removed entry or comment, with the line
+	// holding it when nothing else is on it
+	removedRegex = regexp.MustCompile(`(?m)^[ \t]*(?:\x00[ \t]*)+\r?\n|[ \t]*\x00`)
+)
+
+// Split divides the resource into a main resource keeping its name and one resource per part,
+// each holding the meta.xml entries whose src is below one of the part's folders. Their meta.xml
+// files are generated in metaDir, while their scripts and files stay in the resource directory.
+// Exports defined by the scripts of a part move to that part; the returned map gives the part
+// of each moved export function. Parts matching no entry are left out.
+func (r *Resource) Split(parts []SplitPart, metaDir string) (*Resource, []*Resource, map[string]string, error) {
+	data, err := os.ReadFile(r.MetaXMLPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read meta.xml: %v", err)
+	}
+	expanded := r.expandGlobTags(string(data))
+	content := commentRegex.ReplaceAllString(expanded, "")
+
+	// Comments are dropped from the main resource too, they may hold entries of a part
+	entries := make([][]string, len(parts))
+	mainContent := commentRegex.ReplaceAllString(expanded, "\x00")
+	for _, entryRegex := range srcEntryRegexes {
+		mainContent = entryRegex.ReplaceAllStringFunc(mainContent, func(entry string) string {
+			i := partOf(parts, srcAttrValue(entry))
+			if i < 0 {
+				return entry
+			}
+			entries[i] = append(entries[i], entry)
+			return "\x00"
+		})
+	}
+	mainContent = removedRegex.ReplaceAllString(mainContent, "")
+
+	owners := make(map[string]string)
+	var subs []*Resource
+	for i, part := range parts {
+		if len(entries[i]) == 0 {
+			r.logger().Warn("Split part matches no script or file", "part", part.Name)
+			continue
+		}
+
+		lines := []string{
+			fmt.Sprintf(`    <info name="%s" author="mta-bundler" type="script" description="Generated by mta-bundler: part of %s" />`,
+				html.EscapeString(part.Name), html.EscapeString(r.Name)),
+		}
+		if oopRegex.MatchString(content) {
+			lines = append(lines, "    <oop>true</oop>")
+		}
+		for _, entry := range append(minVersionRegex.FindAllString(content, -1), entries[i]...) {
+			lines = append(lines, "    "+entry)
+		}
+
+		// Exports move to the part whose scripts define them, the main resource forwards them
+		scripts := partScripts(r, entries[i])
+		for _, export := range exportEntryRegex.FindAllString(content, -1) {
+			match := functionAttrRegex.FindStringSubmatch(export)
+			if match == nil {
+				continue
+			}
+			function := match[1] + match[2]
+			if _, ok := owners[function]; !ok && definesFunction(scripts, function) {
+				owners[function] = part.Name
+				lines = append(lines, "    "+export)
+			}
+		}
+
+		// Scripts of the part may rely on the dependencies and rights of the resource
+		for _, entry := range includeRegex.FindAllString(content, -1) {
+			lines = append(lines, "    "+entry)
+		}
+		for _, entry := range aclRequestEntryRegex.FindAllString(content, -1) {
+			lines = append(lines, "    "+entry)
+		}
+		if len(scripts) > 0 && settingsRegex.MatchString(content) {
+			r.logger().Warn("Settings stay with the main resource", "part", part.Name)
+		}
+
+		sub, err := r.splitResource(part.Name, filepath.Join(metaDir, part.Name), "<meta>\n"+strings.Join(lines, "\n")+"\n</meta>\n")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	mainRes, err := r.splitResource(r.Name, filepath.Join(metaDir, r.Name), mainContent)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return mainRes, subs, owners, nil
+}
+
+// splitResource writes the meta.xml content of a split resource to dir and returns the resource,
+// which shares the directory and build settings of r
+func (r *Resource) splitResource(name, dir, content string) (*Resource, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for split meta.xml: %v", err)
+	}
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write split meta.xml: %v", err)
+	}
+
+	var meta Meta
+	if err := xml.Unmarshal([]byte(content), &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse split meta.xml of %s: %v", name, err)
+	}
+	files, err := GetAllFiles(meta, r.MetaXMLPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Resource{
+		MetaXMLPath: metaPath,
+		BaseDir:     r.BaseDir,
+		Name:        name,
+		Meta:        meta,
+		Files:       files,
+		Verbatim:    r.Verbatim,
+		Unmerged:    r.Unmerged,
+		SkipAssets:  r.SkipAssets,
+		LinkAssets:  r.LinkAssets,
+		NoCache:     r.NoCache,
+		RegexMeta:   r.RegexMeta,
+		MetaOrder:   r.MetaOrder,
+		Concat:      r.Concat,
+		Isolate:     r.Isolate,
+		BundleName:  r.BundleName,
+		Transform:   r.Transform,
+		Info:        r.Info,
+		MinVersion:  r.MinVersion,
+		Banner:      r.Banner,
+	}, nil
+}
+
+// partOf returns the index of the part whose folders contain src, or -1
+func partOf(parts []SplitPart, src string) int {
+	if src == "" {
+		return -1
+	}
+	src = filepath.ToSlash(filepath.Clean(src))
+	for i, part := range parts {
+		for _, folder := range part.Folders {
+			folder = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(folder)), "/")
+			if src == folder || strings.HasPrefix(src, folder+"/") {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// partScripts returns the paths of the Lua scripts among the entries of a part
+func partScripts(r *Resource, entries []string) []string {
+	var scripts []string
+	for _, entry := range entries {
+		src := srcAttrValue(entry)
+		if strings.HasPrefix(entry, "<script") && strings.ToLower(filepath.Ext(src)) == ".lua" {
+			scripts = append(scripts, filepath.Join(r.BaseDir, src))
+		}
+	}
+	return scripts
+}
+
+// definesFunction reports whether one of the scripts defines the global function
+func definesFunction(scripts []string, function string) bool {
+	name := regexp.QuoteMeta(function)
+	definition := regexp.MustCompile(`(?m)^\s*function\s+` + name + `\s*\(|^\s*` + name + `\s*=\s*function\b`)
+	for _, path := range scripts {
+		if data, err := os.ReadFile(path); err == nil && definition.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileTo builds the resource into outputDir, instead of the output directory derived from
+// its location. Unlike Compile, a resource without scripts still gets its meta.xml and files.
+func (r *Resource) CompileTo(comp compiler.CLICompiler, outputDir string, options compiler.CompilationOptions, mergeMode bool) (CompileResult, error) {
+	if len(r.GetLuaFiles()) > 0 {
+		return r.Compile(comp, r.BaseDir, outputDir, options, mergeMode)
+	}
+
+	slog.Info("Copying resource without scripts", "resource", r.Name, "base_dir", r.BaseDir)
+	result := CompileResult{MergeMode: mergeMode, OutputDir: outputDir}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return result, fmt.Errorf("failed to create output directory: %v", err)
+	}
+	if err := r.copyMetaFile(outputDir, r.BaseDir, outputDir); err != nil {
+		return result, fmt.Errorf("failed to copy meta.xml: %v", err)
+	}
+
+	var err error
+	result.FileCopy, err = r.copyFileReferences(outputDir, r.BaseDir, outputDir)
+	if err != nil {
+		return result, fmt.Errorf("failed to copy file references: %v", err)
+	}
+	logFileCopyResults(r.logger(), result.FileCopy)
+	if result.FileCopy.ErrorCount > 0 {
+		return result, fmt.Errorf("failed to copy %d file(s)", result.FileCopy.ErrorCount)
+	}
+	return result, nil
+}
+
+// AppendMetaEntries adds entries before the closing </meta> tag of the meta.xml at metaPath
+func AppendMetaEntries(metaPath string, entries []string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(metaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read meta.xml: %v", err)
+	}
+
+	position := strings.LastIndex(string(content), "</meta>")
+	if position < 0 {
+		return fmt.Errorf("meta.xml has no closing </meta> tag")
+	}
+	var added strings.Builder
+	for _, entry := range entries {
+		added.WriteString("    " + entry + "\n")
+	}
+	modifiedContent := string(content[:position]) + added.String() + string(content[position:])
+
+	if err := os.WriteFile(metaPath, []byte(modifiedContent), 0644); err != nil {
+		return fmt.Errorf("failed to write modified meta.xml: %v", err)
+	}
+	return nil
+}
@@ -246,3 +246,560 @@ func TestCopyAndModifyMergedMetaFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSetLazyFiles(t *testing.T) {
+	metaPath := filepath.Join(t.TempDir(), "meta.xml")
+	content := `<meta>
+    <script src="client.luac" type="client" />
+    <file src="images/map.png" download="true" />
+    <file src='sounds/intro.mp3'></file>
+    <file src="logo.png" />
+</meta>`
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write meta.xml: %v", err)
+	}
+
+	lazy := map[string]bool{"images/map.png": true, "sounds/intro.mp3": true}
+	for i := 0; i < 2; i++ {
+		if err := SetLazyFiles(metaPath, lazy, "loader.luac"); err != nil {
+			t.Fatalf("SetLazyFiles failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(metaPath)
+	want := `<meta>
+    <script src="loader.luac" type="client" />
+    <script src="client.luac" type="client" />
+    <file src="images/map.png" download="false" />
+    <file src='sounds/intro.mp3' download="false"></file>
+    <file src="logo.png" />
+</meta>`
+	if string(data) != want {
+		t.Errorf("Unexpected meta.xml:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestStampMeta(t *testing.T) {
+	metaPath := filepath.Join(t.TempDir(), "meta.xml")
+	content := `<meta>
+    <info author="me" build="1"/>
+    <script src="client.luac" type="client" />
+</meta>`
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write meta.xml: %v", err)
+	}
+
+	for _, build := range []int{2, 3} {
+		if err := StampMeta(metaPath, build, "stamp.luac"); err != nil {
+			t.Fatalf("StampMeta failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(metaPath)
+	want := `<meta>
+    <info author="me" build="3"/>
+    <script src="stamp.luac" type="shared" />
+    <script src="client.luac" type="client" />
+</meta>`
+	if string(data) != want {
+		t.Errorf("Unexpected meta.xml:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestAddScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			"Tab indentation",
+			"<meta>\n\t<info author=\"me\" />\n\t<script src=\"client.luac\" type=\"client\" />\n\t<file src=\"logo.png\" />\n</meta>",
+			"<meta>\n\t<info author=\"me\" />\n\t<script src=\"stamp.luac\" type=\"shared\" />\n\t<script src=\"client.luac\" type=\"client\" />\n\t<file src=\"logo.png\" />\n</meta>",
+		},
+		{
+			"Commented script",
+			"<meta>\n    <!-- <script src=\"old.luac\" /> -->\n    <file src=\"logo.png\" />\n</meta>\n",
+			"<meta>\n    <!-- <script src=\"old.luac\" /> -->\n    <file src=\"logo.png\" />\n    <script src=\"stamp.luac\" type=\"shared\" />\n</meta>\n",
+		},
+		{
+			"Self-closing meta",
+			"<meta/>",
+			"<meta>\n    <script src=\"stamp.luac\" type=\"shared\" />\n</meta>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaPath := filepath.Join(t.TempDir(), "meta.xml")
+			if err := os.WriteFile(metaPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write meta.xml: %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				if err := AddScript(metaPath, "stamp.luac", "shared"); err != nil {
+					t.Fatalf("AddScript failed: %v", err)
+				}
+			}
+
+			data, _ := os.ReadFile(metaPath)
+			if string(data) != tt.expected {
+				t.Errorf("Unexpected meta.xml:\n%s\nwant:\n%s", data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetInfoAttrs(t *testing.T) {
+	var attrs []InfoAttr
+	for _, spec := range []string{"version+=-{resource}", "build=7", "commit=a&b"} {
+		attr, err := ParseInfoAttr(spec)
+		if err != nil {
+			t.Fatalf("ParseInfoAttr(%q) failed: %v", spec, err)
+		}
+		attrs = append(attrs, attr)
+	}
+	r := &Resource{Name: "race", Info: attrs}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			"Existing attributes",
+			"<meta>\n    <info author='me' version='1.2' build=\"1\"/>\n</meta>",
+			"<meta>\n    <info author='me' version='1.2-race' build=\"7\" commit=\"a&amp;b\"/>\n</meta>",
+		},
+		{
+			"Missing tag",
+			"<meta>\n    <script src=\"a.luac\" />\n</meta>",
+			"<meta>\n    <info version=\"-race\" build=\"7\" commit=\"a&amp;b\" />\n    <script src=\"a.luac\" />\n</meta>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.setInfoAttrs(tt.content)
+			if err != nil {
+				t.Fatalf("setInfoAttrs failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Unexpected meta.xml:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	for _, spec := range []string{"version", "=1", "bad name=1"} {
+		if _, err := ParseInfoAttr(spec); err == nil {
+			t.Errorf("Expected ParseInfoAttr(%q) to fail", spec)
+		}
+	}
+}
+
+func TestVerbatimScripts(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.xml")
+	content := `<meta>
+    <script src="client.lua" type="client" />
+    <script src='config/settings.lua' type="server" />
+    <file src="logo.png" />
+</meta>`
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write meta.xml: %v", err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatalf("NewResource failed: %v", err)
+	}
+	res.Verbatim = map[string]bool{"config/settings.lua": true}
+
+	if files := res.compiledLuaFiles(); len(files) != 1 || files[0].RelativePath != "client.lua" {
+		t.Errorf("Expected only client.lua to be compiled, got %v", files)
+	}
+	if client, server, _ := res.GetLuaFilesByType(); len(client) != 1 || len(server) != 0 {
+		t.Errorf("Expected the verbatim server script to be left out, got client=%v server=%v", client, server)
+	}
+	if files := res.getNonScriptFiles(); len(files) != 2 || files[0].RelativePath != "config/settings.lua" {
+		t.Errorf("Expected the verbatim script to be copied, got %v", files)
+	}
+
+	outputPath := filepath.Join(dir, "out.xml")
+	if err := res.CopyAndModifyMetaFile(metaPath, outputPath); err != nil {
+		t.Fatalf("CopyAndModifyMetaFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	for _, want := range []string{`<script src="client.luac" type="client" />`, `<script src='config/settings.lua' type="server" />`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected meta.xml to contain %q, got:\n%s", want, data)
+		}
+	}
+
+	if err := res.CopyAndModifyMergedMetaFile(metaPath, outputPath, true, false); err != nil {
+		t.Fatalf("CopyAndModifyMergedMetaFile failed: %v", err)
+	}
+	data, _ = os.ReadFile(outputPath)
+	if strings.Contains(string(data), `src="client.lua"`) {
+		t.Errorf("Expected the merged script tag to be removed, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), `<script src='config/settings.lua' type="server" />`) {
+		t.Errorf("Expected the verbatim script tag to be kept, got:\n%s", data)
+	}
+}
+
+func TestUnmergedScripts(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.xml")
+	content := `<meta>
+    <script src="client.lua" type="client" />
+    <script src='plugins/optional.lua' type="shared" />
+    <script src="server.lua" type="server" />
+</meta>`
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write meta.xml: %v", err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatalf("NewResource failed: %v", err)
+	}
+	res.Unmerged = map[string]bool{"plugins/optional.lua": true}
+
+	if files := res.unmergedLuaFiles(); len(files) != 1 || files[0].RelativePath != "plugins/optional.lua" {
+		t.Errorf("Expected only plugins/optional.lua to be unmerged, got %v", files)
+	}
+	for _, metaOrder := range []bool{false, true} {
+		res.MetaOrder = metaOrder
+		if client, server := res.GetMergedLuaFiles(); len(client) != 1 || len(server) != 1 {
+			t.Errorf("Expected the unmerged script to be left out of the bundles (meta order %v), got client=%v server=%v", metaOrder, client, server)
+		}
+	}
+
+	outputPath := filepath.Join(dir, "out.xml")
+	if err := res.CopyAndModifyMergedMetaFile(metaPath, outputPath, true, true); err != nil {
+		t.Fatalf("CopyAndModifyMergedMetaFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	if strings.Contains(string(data), `src="client.lua"`) {
+		t.Errorf("Expected the merged script tag to be removed, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), `<script src='plugins/optional.luac' type="shared" />`) {
+		t.Errorf("Expected the unmerged script tag to point to its .luac file, got:\n%s", data)
+	}
+}
+
+func TestMergedScriptAttributes(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.xml")
+	content := `<meta>
+    <script src="client.lua" type="client" validate="false" />
+    <script src="secret.lua" type="client" cache="false" validate="false" />
+    <script src="shared.lua" type="shared" />
+    <script src="server.lua" type="server" />
+    <map src="arena.map" dimension="5" />
+    <file src="music.mp3" download="false" />
+    <config src="settings.xml" type="client" />
+    <html src="index.htm" default="true" raw="false" />
+</meta>`
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write meta.xml: %v", err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatalf("NewResource failed: %v", err)
+	}
+	meta := res.Meta
+	if meta.Scripts[1].Cache != "false" || meta.Maps[0].Dimension != "5" || meta.Files[0].Download != "false" ||
+		meta.Configs[0].Type != "client" || meta.HTMLs[0].Default != "true" || meta.HTMLs[0].Raw != "false" {
+		t.Errorf("Expected the attributes of every entry to be parsed, got %+v", meta)
+	}
+
+	outputPath := filepath.Join(dir, "out.xml")
+	if err := res.CopyAndModifyMergedMetaFile(metaPath, outputPath, true, true); err != nil {
+		t.Fatalf("CopyAndModifyMergedMetaFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	// The shared script merged into the client bundle sets no validate attribute
+	for _, expected := range []string{
+		`<script src="client.luac" type="client" cache="false" />`,
+		`<script src="server.luac" type="server" cache="true" />`,
+		`<map src="arena.map" dimension="5" />`,
+		`<file src="music.mp3" download="false" />`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %s in:\n%s", expected, data)
+		}
+	}
+
+	res.Meta.Scripts[2].Validate = "false"
+	if tag := bundleScriptTag(res.logger(), "client.luac", "client", res.bundleScripts("client")); tag != `<script src="client.luac" type="client" cache="false" validate="false" />` {
+		t.Errorf("Expected the validate attribute every script agrees on to be kept, got %s", tag)
+	}
+}
+
+func TestUncachedScriptTags(t *testing.T) {
+	content := `<meta>
+    <script src="client.luac" type="client" cache="true" />
+    <script src='shared.luac' type='shared'></script>
+    <script src="server.luac" type="server" />
+    <script src="https://example.com/remote.lua" type="client" />
+</meta>`
+	expected := `<meta>
+    <script src="client.luac" type="client" cache="false" />
+    <script src='shared.luac' type='shared' cache="false"></script>
+    <script src="server.luac" type="server" />
+    <script src="https://example.com/remote.lua" type="client" />
+</meta>`
+	if result := uncachedScriptTags(content); result != expected {
+		t.Errorf("Unexpected meta.xml:\n%s\nexpected:\n%s", result, expected)
+	}
+}
+
+func TestSkipAssets(t *testing.T) {
+	res := Resource{
+		Files: []FileReference{
+			{RelativePath: "client.lua", ReferenceType: ReferenceTypeScript},
+			{RelativePath: "config.lua", ReferenceType: ReferenceTypeScript},
+			{RelativePath: "models/car.dff", ReferenceType: ReferenceTypeFile},
+		},
+		Verbatim:   map[string]bool{"config.lua": true},
+		SkipAssets: true,
+	}
+
+	if files := res.getNonScriptFiles(); len(files) != 1 || files[0].RelativePath != "config.lua" {
+		t.Errorf("Expected only the verbatim script to be copied, got %v", files)
+	}
+}
+
+func TestLinkFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "model.dff")
+	dst := filepath.Join(dir, "out", "model.dff")
+	os.WriteFile(src, []byte("model"), 0644)
+	os.MkdirAll(filepath.Dir(dst), 0755)
+
+	if _, err := linkFile(src, dst); err != nil {
+		t.Fatalf("linkFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "model" {
+		t.Errorf("Expected the linked file to have the source content, got %q", data)
+	}
+
+	// Copying over a link must replace it instead of writing through to the source
+	other := filepath.Join(dir, "other.dff")
+	os.WriteFile(other, []byte("other"), 0644)
+	if err := copyFile(other, dst); err != nil {
+		t.Fatalf("copyFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(src); string(data) != "model" {
+		t.Errorf("Expected the source to be untouched, got %q", data)
+	}
+
+	// In-place builds copy files onto themselves
+	if err := copyFile(src, src); err != nil {
+		t.Fatalf("copyFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(src); string(data) != "model" {
+		t.Errorf("Expected an in-place copy to keep the file, got %q", data)
+	}
+}
+
+func TestGetMergedLuaFiles(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.xml")
+	content := `<meta>
+    <script src="shared/config.lua" type="shared" />
+    <script src="client.lua" type="client" />
+    <script src="server.lua" />
+    <script src="shared/utils.lua" type="shared" />
+    <script src="client_late.lua" type="client" />
+</meta>`
+	if err := os.WriteFile(metaPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write meta.xml: %v", err)
+	}
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatalf("NewResource failed: %v", err)
+	}
+
+	srcs := func(files []FileReference) string {
+		var names []string
+		for _, file := range files {
+			names = append(names, file.RelativePath)
+		}
+		return strings.Join(names, " ")
+	}
+
+	client, server := res.GetMergedLuaFiles()
+	if got := srcs(client); got != "client.lua client_late.lua shared/config.lua shared/utils.lua" {
+		t.Errorf("Unexpected client scripts by type: %s", got)
+	}
+	if got := srcs(server); got != "server.lua shared/config.lua shared/utils.lua" {
+		t.Errorf("Unexpected server scripts by type: %s", got)
+	}
+
+	res.MetaOrder = true
+	client, server = res.GetMergedLuaFiles()
+	if got := srcs(client); got != "shared/config.lua client.lua shared/utils.lua client_late.lua" {
+		t.Errorf("Unexpected client scripts in meta.xml order: %s", got)
+	}
+	if got := srcs(server); got != "shared/config.lua server.lua shared/utils.lua" {
+		t.Errorf("Unexpected server scripts in meta.xml order: %s", got)
+	}
+}
+
+func TestRewriteMetaFidelity(t *testing.T) {
+	content := "<?xml version=\"1.0\"?>\r\n<meta>\r\n" +
+		"    <!-- <script src=\"old.lua\" type=\"server\" /> -->\r\n" +
+		"    <info name='Race'   author=\"me &amp; you\"/>\r\n" +
+		"    <script src='client.lua' type='client'/>\r\n" +
+		"    <script src=\"server.lua\" type=\"server\"></script>\r\n" +
+		"    <settings><setting name=\"*motd\" value=\"[&quot;Welcome&quot;]\"/></settings>\r\n" +
+		"    <custom><![CDATA[<script src=\"x.lua\"/>]]></custom>\r\n" +
+		"</meta>\r\n"
+	res := &Resource{NoCache: true}
+
+	individual, err := res.rewriteMeta(content)
+	if err != nil {
+		t.Fatalf("rewriteMeta failed: %v", err)
+	}
+	expected := strings.NewReplacer(
+		"<script src='client.lua' type='client'/>", `<script src='client.luac' type='client' cache="false"/>`,
+		`<script src="server.lua" type="server">`, `<script src="server.luac" type="server">`,
+	).Replace(content)
+	if individual != expected {
+		t.Errorf("Expected only the script tags to change, got:\n%s", individual)
+	}
+
+	merged, err := res.rewriteMergedMeta(content, []string{`<script src="client.luac" type="client" cache="true" />`})
+	if err != nil {
+		t.Fatalf("rewriteMergedMeta failed: %v", err)
+	}
+	expected = strings.NewReplacer(
+		"\r\n    <script src='client.lua' type='client'/>", "",
+		"\r\n    <script src=\"server.lua\" type=\"server\"></script>", "",
+		"</custom>", "</custom>\r\n    <script src=\"client.luac\" type=\"client\" cache=\"false\" />",
+	).Replace(content)
+	if merged != expected {
+		t.Errorf("Expected the bundle to replace the scripts, got:\n%s", merged)
+	}
+
+	selfClosing, err := res.rewriteMergedMeta(`<meta/>`, []string{`<script src="server.luac" type="server" cache="true" />`})
+	if err != nil {
+		t.Fatalf("rewriteMergedMeta failed: %v", err)
+	}
+	if selfClosing != "<meta>\n    <script src=\"server.luac\" type=\"server\" cache=\"true\" />\n</meta>" {
+		t.Errorf("Expected the self-closing root to get an end tag, got:\n%s", selfClosing)
+	}
+}
+
+func TestScriptGlobs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gamemode")
+	for _, name := range []string{"scripts/b.lua", "scripts/a.lua", "scripts/core/init.lua", "scripts/core/vehicles/car.lua", "scripts/readme.txt", "server.lua"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("local x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := "<meta>\r\n" +
+		"    <script src=\"scripts/core/init.lua\" type=\"client\" />\r\n" +
+		"    <script src=\"scripts/**/*.lua\" type=\"client\" cache=\"false\" />\r\n" +
+		"    <script src=\"server.lua\" type=\"server\" />\r\n" +
+		"    <script src=\"missing/*.lua\" type=\"server\" />\r\n" +
+		"</meta>\r\n"
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatalf("NewResource failed: %v", err)
+	}
+	var srcs []string
+	for _, script := range res.Meta.Scripts {
+		srcs = append(srcs, script.Src+" "+script.Type+" "+script.Cache)
+	}
+	want := []string{
+		"scripts/core/init.lua client ",
+		"scripts/a.lua client false",
+		"scripts/b.lua client false",
+		"scripts/core/vehicles/car.lua client false",
+		"server.lua server ",
+	}
+	if strings.Join(srcs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expanded scripts =\n%s\nwant\n%s", strings.Join(srcs, "\n"), strings.Join(want, "\n"))
+	}
+	if problems := res.Check(); len(problems) != 1 || problems[0].String() != "gamemode/missing/*.lua: script pattern matches no files" {
+		t.Errorf("Check() = %v, want the pattern matching no files", problems)
+	}
+
+	outputPath := filepath.Join(dir, "out.xml")
+	if err := res.CopyAndModifyMetaFile(metaPath, outputPath); err != nil {
+		t.Fatalf("CopyAndModifyMetaFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	wantMeta := "<meta>\r\n" +
+		"    <script src=\"scripts/core/init.luac\" type=\"client\" />\r\n" +
+		"    <script src=\"scripts/a.luac\" type=\"client\" cache=\"false\" />\r\n" +
+		"    <script src=\"scripts/b.luac\" type=\"client\" cache=\"false\" />\r\n" +
+		"    <script src=\"scripts/core/vehicles/car.luac\" type=\"client\" cache=\"false\" />\r\n" +
+		"    <script src=\"server.luac\" type=\"server\" />\r\n" +
+		"</meta>\r\n"
+	if string(data) != wantMeta {
+		t.Errorf("CopyAndModifyMetaFile wrote:\n%q\nwant:\n%q", data, wantMeta)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta := `<meta>
+    <info name="Shop" version="1.2.0" />
+    <oop>true</oop>
+    <include resource="utils" />
+    <script src="client.lua" type="client" />
+    <script src="server.lua" />
+    <script src="shared.lua" type="Shared" />
+    <map src="shop.map" />
+    <export function="buy" type="shared" http="true" />
+    <export function="sell" />
+</meta>`
+	metaPath := filepath.Join(dir, "meta.xml")
+	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewResource(metaPath)
+	if err != nil {
+		t.Fatalf("NewResource failed: %v", err)
+	}
+	d := res.Describe()
+	if d.Name != "shop" || d.BaseDir != dir || d.Info.Version != "1.2.0" || !d.OOP {
+		t.Errorf("Describe() = %+v", d)
+	}
+	var files []string
+	for _, file := range d.Files {
+		files = append(files, file.Type+" "+file.Src)
+		if file.Path != filepath.Join(dir, file.Src) {
+			t.Errorf("path of %s = %s", file.Src, file.Path)
+		}
+	}
+	if got, want := strings.Join(files, ", "), "script client.lua, script server.lua, script shared.lua, map shop.map"; got != want {
+		t.Errorf("Files = %s, want %s", got, want)
+	}
+	scripts := d.Scripts
+	if len(scripts.Client) != 1 || len(scripts.Server) != 1 || len(scripts.Shared) != 1 || scripts.Server[0] != "server.lua" {
+		t.Errorf("Scripts = %+v", scripts)
+	}
+	if len(d.Includes) != 1 || d.Includes[0] != "utils" {
+		t.Errorf("Includes = %v, want [utils]", d.Includes)
+	}
+	if len(d.Exports) != 2 || !d.Exports[0].HTTP || d.Exports[1].Type != "server" {
+		t.Errorf("Exports = %+v", d.Exports)
+	}
+}
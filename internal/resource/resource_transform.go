@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// SourceTransform rewrites the sources of scripts of r compiled together before luac_mta reads
+// them: the scripts of the merged bundle of side bundle (client or server), or a single script
+// when bundle is empty. sources holds the source of each of files, in order, and is rewritten in
+// place.
+type SourceTransform func(r *Resource, bundle string, files []FileReference, sources [][]byte) error
+
+// readSources reads the scripts of files, compiled into bundle ("" for a single script), and
+// applies the Transform of r to them, unless it is nil. The scripts of a merged bundle get the
+// Banner of r.
+func (r *Resource) readSources(bundle string, files []FileReference) ([][]byte, error) {
+	sources := make([][]byte, len(files))
+	for i, fileRef := range files {
+		data, err := os.ReadFile(fileRef.FullPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", fileRef.RelativePath, err)
+		}
+		sources[i] = data
+	}
+	if r.Transform != nil {
+		if err := r.Transform(r, bundle, files, sources); err != nil {
+			return nil, err
+		}
+	}
+	if bundle != "" {
+		bannerSources(sources, r.expand(r.Banner))
+	}
+	return sources, nil
+}
+
+// compileTransformed compiles files, the scripts of bundle or a single script when bundle is
+// empty, into outputPath from their transformed sources, each named after its src. The result
+// lists the files as inputs with their original size, as for a compilation from the files.
+func (r *Resource) compileTransformed(comp compiler.CLICompiler, bundle string, files []FileReference, outputPath string, options compiler.CompilationOptions) (compiler.CompilationResult, error) {
+	var paths []string
+	for _, fileRef := range files {
+		paths = append(paths, fileRef.FullPath)
+	}
+
+	data, err := r.readSources(bundle, files)
+	if err != nil {
+		return compiler.CompilationResult{InputFile: strings.Join(paths, ", "), InputFiles: paths, OutputFile: outputPath, Error: err}, err
+	}
+	sources := make([]compiler.Source, len(files))
+	for i, fileRef := range files {
+		sources[i] = compiler.Source{Name: sourceName(fileRef.RelativePath), Data: data[i]}
+	}
+
+	result, err := comp.CompileSources(sources, outputPath, options)
+	result.InputFile = strings.Join(paths, ", ")
+	result.InputFiles = paths
+	result.OutputFile = outputPath
+	if inputSize, sizeErr := compiler.CalculateTotalSize(paths); sizeErr == nil {
+		result.InputSize = inputSize
+	}
+	return result, err
+}
+
+// sourceName returns the name a transformed script is compiled under, its src unless it
+// points outside the resource
+func sourceName(src string) string {
+	name := path.Clean(filepath.ToSlash(src))
+	if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		return path.Base(name)
+	}
+	return name
+}
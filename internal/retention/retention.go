@@ -0,0 +1,143 @@
+package retention
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy limits the artifacts kept of a kind. An artifact is removed when it is not among the
+// KeepLast most recent of its group or when it is older than MaxAge.
+type Policy struct {
+	KeepLast int           // Most recent artifacts kept per group, 0 keeps any number
+	MaxAge   time.Duration // Artifacts older than this are removed, 0 keeps them regardless of age
+}
+
+// Artifact is a file or directory a policy applies to
+type Artifact struct {
+	Path    string    // File or directory removed as a whole
+	Group   string    // Artifacts are counted per group, such as the pins of one dependency
+	ModTime time.Time // Time the artifact was written or last used
+	Size    int64     // Size in bytes, including the files of a directory
+	Pinned  bool      // Never removed, such as the cache entry of a current pin
+	Stale   bool      // Always removed, such as the leftovers of an interrupted download
+}
+
+// Enabled reports whether the policy removes anything
+func (p Policy) Enabled() bool {
+	return p.KeepLast > 0 || p.MaxAge > 0
+}
+
+// Expired returns the artifacts the policy removes at now, oldest first
+func (p Policy) Expired(artifacts []Artifact, now time.Time) []Artifact {
+	if !p.Enabled() {
+		return nil
+	}
+
+	groups := make(map[string][]Artifact)
+	for _, artifact := range artifacts {
+		groups[artifact.Group] = append(groups[artifact.Group], artifact)
+	}
+
+	var expired []Artifact
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].ModTime.After(group[j].ModTime) })
+		for i, artifact := range group {
+			if artifact.Pinned {
+				continue
+			}
+			if artifact.Stale || (p.KeepLast > 0 && i >= p.KeepLast) || (p.MaxAge > 0 && now.Sub(artifact.ModTime) > p.MaxAge) {
+				expired = append(expired, artifact)
+			}
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ModTime.Before(expired[j].ModTime) })
+	return expired
+}
+
+// Remove deletes the artifacts and returns the number of bytes freed
+func Remove(artifacts []Artifact) (int64, error) {
+	var freed int64
+	for _, artifact := range artifacts {
+		if err := os.RemoveAll(artifact.Path); err != nil {
+			return freed, fmt.Errorf("failed to remove %s: %w", artifact.Path, err)
+		}
+		freed += artifact.Size
+	}
+	return freed, nil
+}
+
+// Files returns the files of dir whose name matches pattern as artifacts of group. A missing
+// directory has no artifacts.
+func Files(dir, pattern, group string) ([]Artifact, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	var artifacts []Artifact
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, entry.Name()); !matched {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		artifacts = append(artifacts, Artifact{
+			Path:    filepath.Join(dir, entry.Name()),
+			Group:   group,
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+		})
+	}
+	return artifacts, nil
+}
+
+// DirSize returns the total size of the regular files below dir
+func DirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// ParseAge parses a maximum age such as 30d, 12h or 90m. Days are 24 hours. An empty value or 0
+// gives no limit.
+func ParseAge(value string) (time.Duration, error) {
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q (use a number of days such as 30d, or a duration such as 12h)", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid age %q (use a number of days such as 30d, or a duration such as 12h)", value)
+	}
+	return age, nil
+}
@@ -0,0 +1,63 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	artifacts := []Artifact{
+		{Path: "a1", Group: "a", ModTime: now.Add(-1 * day)},
+		{Path: "a2", Group: "a", ModTime: now.Add(-2 * day)},
+		{Path: "a3", Group: "a", ModTime: now.Add(-3 * day)},
+		{Path: "a4", Group: "a", ModTime: now.Add(-40 * day), Pinned: true},
+		{Path: "b1", Group: "b", ModTime: now.Add(-10 * day)},
+		{Path: "b2", Group: "b", ModTime: now.Add(-50 * day)},
+		{Path: "c1", Group: "c", ModTime: now.Add(-1 * day), Stale: true},
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{"disabled", Policy{}, nil},
+		{"keep last", Policy{KeepLast: 2}, []string{"a3", "c1"}},
+		{"max age", Policy{MaxAge: 30 * day}, []string{"b2", "c1"}},
+		{"both", Policy{KeepLast: 1, MaxAge: 30 * day}, []string{"b2", "a3", "a2", "c1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expired := tt.policy.Expired(artifacts, now)
+			var got []string
+			for _, artifact := range expired {
+				got = append(got, artifact.Path)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v to expire, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v to expire oldest first, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	if age, err := ParseAge("30d"); err != nil || age != 30*24*time.Hour {
+		t.Errorf("Expected 30 days, got %v, %v", age, err)
+	}
+	if age, err := ParseAge("12h"); err != nil || age != 12*time.Hour {
+		t.Errorf("Expected 12 hours, got %v, %v", age, err)
+	}
+	for _, value := range []string{"d", "-1d", "30", "1.5d", "-2h"} {
+		if _, err := ParseAge(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected ParseCron(%q) to fail", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 30, 0, 0, time.UTC) // Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, time.March, 16, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * mon-wed", time.Date(2024, time.March, 18, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, time.March, 17, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches
+		{"0 0 20 * 6", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, time.March, 16, 10, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := cron.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextImpossible(t *testing.T) {
+	cron, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+	if got := cron.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Expected no next time for February 30th, got %s", got)
+	}
+}
+
+func TestMissed(t *testing.T) {
+	nightly, _ := ParseCron("0 2 * * *")
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	entries := []Entry{
+		{Name: "missed", Cron: nightly, CatchUp: true},
+		{Name: "recent", Cron: nightly, CatchUp: true},
+		{Name: "no-catch-up", Cron: nightly},
+		{Name: "never-run", Cron: nightly, CatchUp: true},
+	}
+	state := State{LastRun: map[string]time.Time{
+		"missed":      now.Add(-48 * time.Hour),
+		"recent":      now.Add(-time.Hour),
+		"no-catch-up": now.Add(-48 * time.Hour),
+	}}
+
+	missed := Missed(entries, state, now)
+	if len(missed) != 1 || missed[0].Name != "missed" {
+		t.Errorf("Expected only \"missed\" to catch up, got %v", entryNames(missed))
+	}
+}
@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a named job trigger
+type Entry struct {
+	Name    string // Unique schedule name, used to persist its last run
+	Cron    Cron   // When the job runs
+	CatchUp bool   // Run once at startup if a scheduled time was missed while stopped
+}
+
+// State records the last run of every schedule so missed runs can be detected after downtime
+type State struct {
+	LastRun map[string]time.Time `json:"last_run"`
+
+	path string
+}
+
+// LoadState reads the state file at path. A missing file yields an empty state.
+func LoadState(path string) (State, error) {
+	state := State{LastRun: make(map[string]time.Time), path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read schedule state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse schedule state %s: %w", path, err)
+	}
+	if state.LastRun == nil {
+		state.LastRun = make(map[string]time.Time)
+	}
+	return state, nil
+}
+
+// Save writes the state file
+func (s State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create schedule state directory: %w", err)
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Missed returns the entries that should catch up: their next scheduled time after the
+// recorded last run has already passed. Entries without a recorded run never catch up.
+func Missed(entries []Entry, state State, now time.Time) []Entry {
+	var missed []Entry
+	for _, entry := range entries {
+		if !entry.CatchUp {
+			continue
+		}
+		lastRun, ok := state.LastRun[entry.Name]
+		if !ok {
+			continue
+		}
+		if next := entry.Cron.Next(lastRun); !next.IsZero() && !next.After(now) {
+			missed = append(missed, entry)
+		}
+	}
+	return missed
+}
+
+// Job is executed when a schedule fires
+type Job func(entry Entry) error
+
+// Run executes job according to the entries until ctx is cancelled. Missed runs are caught up
+// first, and the state file is updated after every run.
+func Run(ctx context.Context, entries []Entry, state State, job Job) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("no schedules configured")
+	}
+
+	now := time.Now()
+	for _, entry := range Missed(entries, state, now) {
+		slog.Info("Catching up missed run", "schedule", entry.Name, "last_run", state.LastRun[entry.Name].Format(time.RFC3339))
+		runEntry(entry, state, job)
+	}
+
+	// First start: record a baseline so later downtime can be detected
+	for _, entry := range entries {
+		if _, ok := state.LastRun[entry.Name]; !ok {
+			state.LastRun[entry.Name] = now
+		}
+	}
+	if err := state.Save(); err != nil {
+		slog.Warn("Cannot save schedule state", "error", err)
+	}
+
+	for {
+		due, at := nextDue(entries, time.Now())
+		if len(due) == 0 {
+			return fmt.Errorf("no schedule has a future run time")
+		}
+
+		slog.Info("Next scheduled run", "at", at.Format(time.RFC3339), "schedules", entryNames(due))
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		for _, entry := range due {
+			runEntry(entry, state, job)
+		}
+	}
+}
+
+// runEntry runs the job for an entry and records the run
+func runEntry(entry Entry, state State, job Job) {
+	slog.Info("Running schedule", "schedule", entry.Name)
+	if err := job(entry); err != nil {
+		slog.Error("Schedule failed", "schedule", entry.Name, "error", err)
+	}
+
+	state.LastRun[entry.Name] = time.Now()
+	if err := state.Save(); err != nil {
+		slog.Warn("Cannot save schedule state", "error", err)
+	}
+}
+
+// nextDue returns the entries with the earliest next run time after now, and that time
+func nextDue(entries []Entry, now time.Time) ([]Entry, time.Time) {
+	var due []Entry
+	var earliest time.Time
+
+	for _, entry := range entries {
+		next := entry.Cron.Next(now)
+		if next.IsZero() {
+			continue
+		}
+		switch {
+		case earliest.IsZero() || next.Before(earliest):
+			earliest = next
+			due = []Entry{entry}
+		case next.Equal(earliest):
+			due = append(due, entry)
+		}
+	}
+	return due, earliest
+}
+
+// entryNames returns the sorted, comma separated names of entries
+func entryNames(entries []Entry) string {
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,565 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/lua"
+)
+
+// Constant returns value as a constant of Fold: nil, a bool, a float64 or a string. Integers
+// are converted to float64, and false is returned for values of other types.
+func Constant(value any) (any, bool) {
+	switch v := value.(type) {
+	case nil, bool, float64, string:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return nil, false
+}
+
+// Fold replaces the reads of the globals named in constants with their values, evaluates the
+// constant expressions of src, such as 60 * 1000 or "v" .. VERSION, and removes the branches of
+// if statements whose conditions are constant, as in if DEBUG then ... end with DEBUG false.
+// Assignments to the constants are left as they are. Line breaks are kept, so the code left
+// keeps its line numbers. An error is returned when src does not parse or a constant has a
+// value Constant does not accept.
+func Fold(src []byte, constants map[string]any) ([]byte, error) {
+	chunk, err := lua.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lua.Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &folder{tokens: tokens, index: make(map[lua.Position]int), values: make(map[lua.Position]any)}
+	for i, token := range tokens {
+		f.index[token.Position] = i
+	}
+	for _, access := range lua.Globals(chunk) {
+		value, ok := constants[access.Name]
+		if !ok || access.Write {
+			continue
+		}
+		if value, ok = Constant(value); !ok {
+			return nil, fmt.Errorf("constant %s has an unsupported value %v", access.Name, constants[access.Name])
+		}
+		f.values[access.Position] = value
+	}
+	f.block(chunk.Block)
+
+	out := lua.Apply(src, f.edits)
+	if _, err := lua.Parse(out); err != nil {
+		return nil, fmt.Errorf("invalid source after folding constants: %w", err)
+	}
+	return out, nil
+}
+
+// folder evaluates the constant expressions of a chunk and collects the edits replacing them
+type folder struct {
+	tokens []lua.Token
+	index  map[lua.Position]int // Index in tokens of the token at each position
+	values map[lua.Position]any // Values of the names reading constants, by position
+	edits  []lua.Edit
+}
+
+func (f *folder) block(b *lua.Block) {
+	for _, stat := range b.Stats {
+		f.stat(stat)
+	}
+}
+
+func (f *folder) stat(stat lua.Stat) {
+	switch s := stat.(type) {
+	case *lua.LocalStat:
+		f.exprs(s.Values)
+	case *lua.AssignStat:
+		for _, target := range s.Targets {
+			if index, ok := target.(*lua.IndexExpr); ok {
+				f.indexExpr(index)
+			}
+		}
+		f.exprs(s.Values)
+	case *lua.CallStat:
+		f.expr(s.Call)
+	case *lua.DoStat:
+		f.block(s.Body)
+	case *lua.WhileStat:
+		f.expr(s.Cond)
+		f.block(s.Body)
+	case *lua.RepeatStat:
+		f.block(s.Body)
+		f.expr(s.Cond)
+	case *lua.IfStat:
+		f.ifStat(s)
+	case *lua.NumericForStat:
+		f.exprs([]lua.Expr{s.Start, s.Limit, s.Step})
+		f.block(s.Body)
+	case *lua.GenericForStat:
+		f.exprs(s.Values)
+		f.block(s.Body)
+	case *lua.FunctionStat:
+		f.block(s.Func.Body)
+	case *lua.LocalFunctionStat:
+		f.block(s.Func.Body)
+	case *lua.ReturnStat:
+		f.exprs(s.Values)
+	}
+}
+
+// ifStat removes the clauses of s whose conditions are always false, and those following a
+// condition that always holds, which becomes an else, or a do block when it is the first left
+func (f *folder) ifStat(s *lua.IfStat) {
+	var kept []*lua.IfClause
+	holds := false
+	for _, clause := range s.Clauses {
+		truth, known := f.truthy(clause.Cond)
+		if known && !truth {
+			continue
+		}
+		kept = append(kept, clause)
+		if known {
+			holds = true
+			break
+		}
+	}
+	if len(kept) == len(s.Clauses) && !holds {
+		for _, clause := range s.Clauses {
+			f.expr(clause.Cond)
+			f.block(clause.Body)
+		}
+		if s.Else != nil {
+			f.block(s.Else)
+		}
+		return
+	}
+
+	if len(kept) == 0 && s.Else == nil {
+		// Nothing is left of the statement; do end keeps a following parenthesis from
+		// reading as a call
+		end := lua.Position{Line: s.End.Line, Column: s.End.Column + len("end")}
+		text := ""
+		if next := f.index[s.End] + 1; next < len(f.tokens) && f.tokens[next].Text == "(" {
+			text = "do end"
+		}
+		f.replace(s.Position, end, text)
+		return
+	}
+
+	// Where the clause i ends: at the next clause, else or end
+	bound := func(i int) lua.Position {
+		switch {
+		case i+1 < len(s.Clauses):
+			return s.Clauses[i+1].Position
+		case s.Else != nil:
+			return s.ElsePos
+		}
+		return s.End
+	}
+	first := true
+	for i, clause := range s.Clauses {
+		switch {
+		case !containsClause(kept, clause):
+			f.replace(clause.Position, bound(i), "")
+		case holds && clause == kept[len(kept)-1]:
+			// The condition always holds
+			keyword := "else "
+			if first {
+				keyword = "do "
+			}
+			f.replace(clause.Position, clause.Body.Position, keyword)
+			f.block(clause.Body)
+		default:
+			if first && i > 0 {
+				f.replace(clause.Position, lua.Position{Line: clause.Line, Column: clause.Column + len("elseif")}, "if")
+			}
+			f.expr(clause.Cond)
+			f.block(clause.Body)
+		}
+		if containsClause(kept, clause) {
+			first = false
+		}
+	}
+	if s.Else != nil {
+		switch {
+		case holds:
+			f.replace(s.ElsePos, s.End, "")
+		case len(kept) == 0:
+			f.replace(s.ElsePos, s.Else.Position, "do ")
+			f.block(s.Else)
+		default:
+			f.block(s.Else)
+		}
+	}
+}
+
+// containsClause reports whether clauses holds clause
+func containsClause(clauses []*lua.IfClause, clause *lua.IfClause) bool {
+	for _, c := range clauses {
+		if c == clause {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *folder) exprs(exprs []lua.Expr) {
+	for _, e := range exprs {
+		if e != nil {
+			f.expr(e)
+		}
+	}
+}
+
+// expr folds e, an expression used as a value
+func (f *folder) expr(e lua.Expr) {
+	switch e.(type) {
+	case *lua.NilExpr, *lua.TrueExpr, *lua.FalseExpr, *lua.NumberExpr, *lua.StringExpr, *lua.VarargExpr:
+		return
+	}
+	if value, ok := f.eval(e); ok {
+		if text, ok := literal(value); ok {
+			first, last := f.index[e.Pos()], f.lastToken(e)
+			f.replaceTokens(first, last, text)
+			return
+		}
+	}
+
+	switch e := e.(type) {
+	case *lua.BinaryExpr:
+		if e.Op == "and" || e.Op == "or" {
+			// true and x, false or x: x, unless x can have several values the operator truncates
+			// to one: local a, b = true and f() gives b nil
+			if truth, known := f.truthy(e.Left); known && truth == (e.Op == "and") && !multiValued(e.Right) {
+				f.replaceTokens(f.index[e.Pos()], f.index[e.Right.Pos()]-1, "")
+				f.expr(e.Right)
+				return
+			}
+		}
+		f.expr(e.Left)
+		f.expr(e.Right)
+	case *lua.UnaryExpr:
+		f.expr(e.Operand)
+	case *lua.ParenExpr:
+		f.expr(e.Inner)
+	case *lua.FunctionExpr:
+		f.block(e.Body)
+	case *lua.TableExpr:
+		for _, field := range e.Fields {
+			if field.Kind == lua.FieldKeyed {
+				f.expr(field.Key)
+			}
+			f.expr(field.Value)
+		}
+	case *lua.IndexExpr:
+		f.indexExpr(e)
+	case *lua.CallExpr:
+		f.prefix(e.Func)
+		f.exprs(e.Args)
+	case *lua.MethodCallExpr:
+		f.prefix(e.Object)
+		f.exprs(e.Args)
+	}
+}
+
+// multiValued reports whether e can have several values, as a call or ... can
+func multiValued(e lua.Expr) bool {
+	switch e.(type) {
+	case *lua.CallExpr, *lua.MethodCallExpr, *lua.VarargExpr:
+		return true
+	}
+	return false
+}
+
+// indexExpr folds the object and key of e
+func (f *folder) indexExpr(e *lua.IndexExpr) {
+	f.prefix(e.Object)
+	if !e.Dot {
+		f.expr(e.Key)
+	}
+}
+
+// prefix folds inside e, the object of an index or a call, which cannot be replaced by a
+// literal: "a":upper() is not valid Lua
+func (f *folder) prefix(e lua.Expr) {
+	switch e := e.(type) {
+	case *lua.Name:
+	case *lua.ParenExpr:
+		f.expr(e.Inner)
+	default:
+		f.expr(e)
+	}
+}
+
+// replace replaces the source between from and to with text, followed by the line breaks of the
+// source replaced
+func (f *folder) replace(from, to lua.Position, text string) {
+	f.edits = append(f.edits, lua.Edit{From: from, To: to, Text: text + strings.Repeat("\n", to.Line-from.Line)})
+}
+
+// replaceTokens replaces the tokens first to last with text, separated by a space from the
+// tokens right next to them where they would otherwise read as one. Without text, the spacing
+// after the tokens goes too.
+func (f *folder) replaceTokens(first, last int, text string) {
+	from, to := f.tokens[first].Position, f.tokens[last].End()
+	var prev, next string
+	if first > 0 && f.tokens[first-1].End() == from {
+		prev = f.tokens[first-1].Text
+	}
+	if last+1 < len(f.tokens) {
+		if text == "" && f.tokens[last+1].Line == to.Line {
+			to = f.tokens[last+1].Position
+		}
+		if f.tokens[last+1].Position == to {
+			next = f.tokens[last+1].Text
+		}
+	}
+
+	if text == "" {
+		if prev != "" && next != "" && lua.Merges(prev, next) {
+			text = " "
+		}
+	} else if tokens, err := lua.Tokens([]byte(text)); err == nil && len(tokens) > 0 {
+		if prev != "" && lua.Merges(prev, tokens[0].Text) {
+			text = " " + text
+		}
+		if next != "" && lua.Merges(tokens[len(tokens)-1].Text, next) {
+			text += " "
+		}
+	}
+	f.replace(from, to, text)
+}
+
+// lastToken returns the index of the last token of e, an expression eval evaluates
+func (f *folder) lastToken(e lua.Expr) int {
+	switch e := e.(type) {
+	case *lua.UnaryExpr:
+		return f.lastToken(e.Operand)
+	case *lua.BinaryExpr:
+		return f.lastToken(e.Right)
+	case *lua.ParenExpr:
+		return f.lastToken(e.Inner) + 1
+	}
+	return f.index[e.Pos()]
+}
+
+// truthy reports whether e is always true or always false as a condition, without evaluating
+// anything that could have an effect: false and f() is always false
+func (f *folder) truthy(e lua.Expr) (truth, known bool) {
+	switch e := e.(type) {
+	case *lua.BinaryExpr:
+		if e.Op == "and" || e.Op == "or" {
+			left, known := f.truthy(e.Left)
+			if !known {
+				return false, false
+			}
+			if left != (e.Op == "and") {
+				return left, true
+			}
+			return f.truthy(e.Right)
+		}
+	case *lua.UnaryExpr:
+		if e.Op == "not" {
+			truth, known := f.truthy(e.Operand)
+			return !truth, known
+		}
+	case *lua.ParenExpr:
+		return f.truthy(e.Inner)
+	}
+	value, ok := f.eval(e)
+	return ok && value != nil && value != false, ok
+}
+
+// eval returns the value of e when it is made of literals, constants and operators only
+func (f *folder) eval(e lua.Expr) (any, bool) {
+	switch e := e.(type) {
+	case *lua.NilExpr:
+		return nil, true
+	case *lua.TrueExpr:
+		return true, true
+	case *lua.FalseExpr:
+		return false, true
+	case *lua.NumberExpr:
+		return e.Value, true
+	case *lua.StringExpr:
+		return e.Value, e.Text != ""
+	case *lua.Name:
+		value, ok := f.values[e.Position]
+		return value, ok
+	case *lua.ParenExpr:
+		return f.eval(e.Inner)
+	case *lua.UnaryExpr:
+		operand, ok := f.eval(e.Operand)
+		if !ok {
+			return nil, false
+		}
+		switch e.Op {
+		case "not":
+			return operand == nil || operand == false, true
+		case "-":
+			n, ok := operand.(float64)
+			return -n, ok
+		case "#":
+			s, ok := operand.(string)
+			return float64(len(s)), ok
+		}
+	case *lua.BinaryExpr:
+		left, ok := f.eval(e.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := f.eval(e.Right)
+		if !ok {
+			return nil, false
+		}
+		return binary(e.Op, left, right)
+	}
+	return nil, false
+}
+
+// binary returns the value of left op right, and false when it is an error or not worth folding,
+// as for arithmetic on strings or results that are not finite
+func binary(op string, left, right any) (any, bool) {
+	truthy := left != nil && left != false
+	switch op {
+	case "and":
+		if !truthy {
+			return left, true
+		}
+		return right, true
+	case "or":
+		if truthy {
+			return left, true
+		}
+		return right, true
+	case "==":
+		return left == right, true
+	case "~=":
+		return left != right, true
+	case "..":
+		a, ok := concatString(left)
+		if !ok {
+			return nil, false
+		}
+		b, ok := concatString(right)
+		return a + b, ok
+	}
+
+	if a, ok := left.(string); ok {
+		b, ok := right.(string)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case "<":
+			return a < b, true
+		case "<=":
+			return a <= b, true
+		case ">":
+			return a > b, true
+		case ">=":
+			return a >= b, true
+		}
+		return nil, false
+	}
+
+	a, ok := left.(float64)
+	if !ok {
+		return nil, false
+	}
+	b, ok := right.(float64)
+	if !ok {
+		return nil, false
+	}
+	var result float64
+	switch op {
+	case "<":
+		return a < b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case ">=":
+		return a >= b, true
+	case "+":
+		result = a + b
+	case "-":
+		result = a - b
+	case "*":
+		result = a * b
+	case "/":
+		result = a / b
+	case "%":
+		result = a - math.Floor(a/b)*b
+	case "^":
+		result = math.Pow(a, b)
+	default:
+		return nil, false
+	}
+	return result, !math.IsInf(result, 0) && !math.IsNaN(result)
+}
+
+// concatString returns value as the .. operator converts it, numbers formatted as Lua 5.1 does
+func concatString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'g', 14, 64), true
+	}
+	return "", false
+}
+
+// literal returns the Lua literal of value, with negative numbers in parentheses so they do
+// not join a - before them into a comment
+func literal(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "nil", true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return "", false
+		}
+		if math.Signbit(v) {
+			return "(-" + shortestNumber(strconv.FormatFloat(-v, 'f', -1, 64)) + ")", true
+		}
+		return shortestNumber(strconv.FormatFloat(v, 'f', -1, 64)), true
+	case string:
+		return quote(v), true
+	}
+	return "", false
+}
+
+// quote returns s as a double-quoted Lua string
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c < ' ' || c == 0x7f:
+			fmt.Fprintf(&b, `\%03d`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package transform
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	constants := map[string]any{"DEBUG": false, "VERSION": "1.2", "LEVEL": 3}
+	tests := []struct {
+		name, src, want string
+	}{
+		{"Constants inlined", "print(VERSION, LEVEL * 2)", "print(\"1.2\", 6)"},
+		{"Expressions folded", "local t = 60 * 1000\nlocal s = \"v\" .. VERSION .. \".\" .. 1\nlocal n = -(2 ^ 3)", "local t = 6e4\nlocal s = \"v1.2.1\"\nlocal n = (-8)"},
+		{"Debug branch removed", "if DEBUG then\n  print(\"debug\")\nend\nx = 1", "\n\n\nx = 1"},
+		{"Else kept", "if DEBUG then a() else b() end", "do b() end"},
+		{"Always true", "if not DEBUG then a() elseif x then b() end", "do a() end"},
+		{"Middle clause", "if x then a() elseif DEBUG then b() elseif LEVEL > 2 then c() else d() end", "if x then a() else c() end"},
+		{"First clause dropped", "if DEBUG then a() elseif x then b() end", "if x then b() end"},
+		{"Short circuits", "if DEBUG and f() then a() end\nlocal v = not DEBUG and x.y", "\nlocal v = x.y"},
+		{"Call truncated", "local a, b = not DEBUG and f()\nreturn not DEBUG and o:m()", "local a, b = true and f()\nreturn true and o:m()"},
+		{"Vararg truncated", "print(DEBUG or ...)", "print(false or ...)"},
+		{"Parenthesized call", "local a, b = not DEBUG and (f())", "local a, b = (f())"},
+		{"Locals and assignments untouched", "local DEBUG = true\nif DEBUG then a() end", "local DEBUG = true\nif DEBUG then a() end"},
+		{"Prefixes untouched", "local s = VERSION:upper() .. (VERSION)", "local s = VERSION:upper() .. \"1.2\""},
+		{"Call after removed statement", "x = y\nif DEBUG then a() end\n(f)()", "x = y\ndo end\n(f)()"},
+		{"Division by zero kept", "local inf = 1 / 0", "local inf = 1 / 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Fold([]byte(test.src), constants)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("Fold() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := Fold([]byte("print(X)"), map[string]any{"X": []string{"a"}}); err == nil {
+		t.Error("Fold() with a table constant succeeded")
+	}
+}
+
+func TestFoldSpacing(t *testing.T) {
+	got, err := Fold([]byte("return(LEVEL)..x==DEBUG and(1+1)or-LEVEL"), map[string]any{"DEBUG": true, "LEVEL": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "return 3 ..x==true and 2 or(-3)"; string(got) != want {
+		t.Errorf("Fold() = %q, want %q", got, want)
+	}
+}
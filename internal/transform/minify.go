@@ -0,0 +1,67 @@
+package transform
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/lua"
+)
+
+// Minify strips the comments and collapses the whitespace of a Lua source, and writes numbers
+// in their shortest form. Line breaks are kept, so error messages and source maps still point
+// at the lines of the original source. An error is returned when src does not parse.
+func Minify(src []byte) ([]byte, error) {
+	if _, err := lua.Parse(src); err != nil {
+		return nil, err
+	}
+	tokens, err := lua.Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+	for i, token := range tokens {
+		if token.Kind == lua.TokenNumber {
+			tokens[i].Text = shortestNumber(token.Text)
+		}
+	}
+	return lua.Print(tokens), nil
+}
+
+// shortestNumber returns the shortest way to write the numeric literal text, such as 16 for
+// 0x10 or 1e6 for 1000000. Lua numbers are doubles, so any text of the same value will do.
+func shortestNumber(text string) string {
+	value, ok := lua.NumberValue(text)
+	if !ok {
+		return text
+	}
+	decimal := strconv.FormatFloat(value, 'f', -1, 64)
+	shortest := text
+	for _, candidate := range []string{
+		decimal,
+		strings.TrimPrefix(decimal, "0"),
+		exponent(value),
+	} {
+		if len(candidate) >= len(shortest) {
+			continue
+		}
+		// Infinities and NaN have no literal
+		tokens, err := lua.Tokens([]byte(candidate))
+		if err != nil || len(tokens) != 1 || tokens[0].Kind != lua.TokenNumber {
+			continue
+		}
+		if v, ok := lua.NumberValue(candidate); ok && v == value {
+			shortest = candidate
+		}
+	}
+	return shortest
+}
+
+// exponent returns value in exponent notation without a plus sign or leading zeros in the
+// exponent, such as 1e6 or 2.5e-7
+func exponent(value float64) string {
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(value, 'e', -1, 64), "e")
+	n, err := strconv.Atoi(exp)
+	if err != nil {
+		return ""
+	}
+	return mantissa + "e" + strconv.Itoa(n)
+}
@@ -0,0 +1,30 @@
+package transform
+
+import "testing"
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"Comments and spaces", "-- header\nlocal  a = 1 -- one\n\n--[[ long\ncomment ]] print( a )\n", "\nlocal a=1\n\n\nprint(a)\n"},
+		{"Separators kept", "local x = a - -b .. 1 .. 'c'\nreturn x == y", "local x=a- -b..1 ..'c'\nreturn x==y\n"},
+		{"Long strings", "s = [[\nfirst\nsecond]] t = [=[]]]=]\nu = 1", "s=[[\nfirst\nsecond]]t=[=[]]]=]\nu=1\n"},
+		{"Numbers", "n = {0x10, 1000000, 0.50, 3.0, 1e-3, 255, 0.00001}", "n={16,1e6,.5,3,1e-3,255,1e-5}\n"},
+		{"Empty", "-- nothing\n", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Minify([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("Minify() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := Minify([]byte("if then")); err == nil {
+		t.Error("Minify() of an invalid source succeeded")
+	}
+}
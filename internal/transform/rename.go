@@ -0,0 +1,110 @@
+package transform
+
+import (
+	"github.com/davidbozo/mta-bundler/internal/lua"
+)
+
+// nameChars are the characters short names are made of, the first character being one of the
+// letters
+const nameChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_0123456789"
+
+// reservedNames are names locals are never renamed to: self and arg are given their meaning by
+// Lua itself, and _G and _ read as meaningful to anyone debugging the output
+var reservedNames = []string{"self", "arg", "_G", "_"}
+
+// RenameLocals renames the locals of a Lua source, parameters and for variables included, to
+// short meaningless names. Locals in scope at the same time get distinct names, and no local is
+// renamed to a global of the source, so the renamed source behaves as the original. Comments,
+// spacing and line breaks are kept. An error is returned when src does not parse.
+func RenameLocals(src []byte) ([]byte, error) {
+	chunk, err := lua.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lua.Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool)
+	for _, name := range reservedNames {
+		taken[name] = true
+	}
+	for _, access := range lua.Globals(chunk) {
+		taken[access.Name] = true
+	}
+	names := newNames(taken)
+
+	renamed := make(map[lua.Position]string)
+	for _, local := range lua.Locals(chunk) {
+		if local.Implicit {
+			// The self of a method is not written in the source, so it keeps its name
+			continue
+		}
+		name := names.at(local.Slot)
+		renamed[local.Decl.Position] = name
+		for _, use := range local.Uses {
+			renamed[use.Position] = name
+		}
+	}
+
+	var edits []lua.Edit
+	for _, token := range tokens {
+		if token.Kind != lua.TokenName {
+			continue
+		}
+		if name, ok := renamed[token.Position]; ok && name != token.Text {
+			edits = append(edits, lua.Edit{From: token.Position, To: token.End(), Text: name})
+		}
+	}
+	return lua.Apply(src, edits), nil
+}
+
+// names hands out short names in order, a to z, A to Z, then two characters and so on, skipping
+// keywords and taken names. A local gets the name at its slot, so locals in scope together, which
+// have distinct slots, get distinct names, while locals of sibling scopes share names.
+type names struct {
+	taken map[string]bool
+	list  []string
+	next  int // Index of the next candidate in the sequence of all names
+}
+
+func newNames(taken map[string]bool) *names {
+	return &names{taken: taken}
+}
+
+// at returns the name at slot
+func (n *names) at(slot int) string {
+	for len(n.list) <= slot {
+		name := candidate(n.next)
+		n.next++
+		if !n.taken[name] && !lua.IsKeyword(name) {
+			n.list = append(n.list, name)
+		}
+	}
+	return n.list[slot]
+}
+
+// candidate returns the name at index i of the sequence of all names: the names of one
+// character, then those of two and so on. Names cannot start with a digit.
+func candidate(i int) string {
+	first := len(nameChars) - 10
+	if i < first {
+		return nameChars[i : i+1]
+	}
+	i -= first
+	// Names of n+1 characters: a first character followed by n of any
+	count, rest := first*len(nameChars), 1
+	for i >= count {
+		i -= count
+		count *= len(nameChars)
+		rest++
+	}
+	name := make([]byte, rest+1)
+	for j := rest; j > 0; j-- {
+		name[j] = nameChars[i%len(nameChars)]
+		i /= len(nameChars)
+	}
+	name[0] = nameChars[i]
+	return string(name)
+}
@@ -0,0 +1,42 @@
+package transform
+
+import "testing"
+
+func TestRenameLocals(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"Locals and parameters", "local count = 0\nlocal function add(amount) count = count + amount end",
+			"local a = 0\nlocal function b(c) a = a + c end"},
+		{"Comments kept", "-- total\nlocal total = 1 -- one\nprint(total)", "-- total\nlocal a = 1 -- one\nprint(a)"},
+		{"Globals not reused", "local x = 1\nlocal y = a + x", "local b = 1\nlocal c = a + b"},
+		{"Shadowing", "local v = 1\ndo local v = v + 1 print(v) end\nprint(v)", "local a = 1\ndo local b = a + 1 print(b) end\nprint(a)"},
+		{"Sibling scopes", "do local p = 1 end\ndo local q = 2 end", "do local a = 1 end\ndo local a = 2 end"},
+		{"Method self", "local t = {}\nfunction t:get(key) return self[key] end", "local a = {}\nfunction a:get(c) return self[c] end"},
+		{"For variables", "for index, value in pairs(list) do print(index, value) end", "for a, b in pairs(list) do print(a, b) end"},
+		{"Keys untouched", "local name = {name = 1}\nreturn name.name", "local a = {name = 1}\nreturn a.name"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := RenameLocals([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("RenameLocals() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := RenameLocals([]byte("if then")); err == nil {
+		t.Error("RenameLocals() of an invalid source succeeded")
+	}
+}
+
+func TestCandidate(t *testing.T) {
+	for i, want := range map[int]string{0: "a", 52: "_", 53: "aa", 53 + 63: "ba", 53 + 53*63: "aaa"} {
+		if got := candidate(i); got != want {
+			t.Errorf("candidate(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package transform
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/lua"
+)
+
+// dynamicGlobals are the globals through which code can read any global by name, such as
+// _G[name] or loadstring("return " .. name)
+var dynamicGlobals = []string{"_G", "getfenv", "setfenv", "loadstring", "load"}
+
+// Removed is a top-level function dropped by Shake
+type Removed struct {
+	Script string // Name of the script defining it
+	Name   string
+	Line   int
+}
+
+// References returns the globals src reads, assignments left out. An error is returned when src
+// does not parse, or when it reads globals by name, through _G[name], loadstring or getfenv, so
+// that any global may be read.
+func References(src []byte) ([]string, error) {
+	chunk, err := lua.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	accesses := lua.Globals(chunk)
+	if err := checkDynamic(accesses); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, access := range accesses {
+		if !access.Write && !slices.Contains(names, access.Name) {
+			names = append(names, access.Name)
+		}
+	}
+	return names, nil
+}
+
+// checkDynamic returns an error when accesses read one of the dynamicGlobals
+func checkDynamic(accesses []lua.GlobalAccess) error {
+	for _, access := range accesses {
+		if !access.Write && slices.Contains(dynamicGlobals, access.Name) {
+			return fmt.Errorf("line %d: %s reads globals by name", access.Line, access.Name)
+		}
+	}
+	return nil
+}
+
+// shakeable is a top-level function statement Shake may remove
+type shakeable struct {
+	script   int // Index of the source defining it
+	name     string
+	stat     lua.Stat
+	function *lua.FunctionExpr
+	local    *lua.Local   // Local function, nil for a global one
+	reaches  []*shakeable // Functions its body refers to
+	live     bool
+}
+
+// Shake removes the top-level functions of sources, the scripts named names compiled into one
+// bundle, that no code left in the bundle can call: local functions and global functions
+// defined once, by function name() ... end, that are only referred to by other removed
+// functions, if at all. keep lists the globals used from outside of the bundle, such as exported
+// functions, which are never removed. Removed functions leave their line breaks behind, so the
+// lines of the code after them do not change. An error is returned, and sources are left as they
+// are, when a source does not parse or reads globals by name, as through _G[name].
+func Shake(names []string, sources [][]byte, keep []string) ([]Removed, error) {
+	chunks := make([]*lua.Chunk, len(sources))
+	accesses := make([][]lua.GlobalAccess, len(sources))
+	writes := make(map[string]int)
+	for i, src := range sources {
+		chunk, err := lua.Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", names[i], err)
+		}
+		chunks[i] = chunk
+		accesses[i] = lua.Globals(chunk)
+		if err := checkDynamic(accesses[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", names[i], err)
+		}
+		for _, access := range accesses[i] {
+			if access.Write {
+				writes[access.Name]++
+			}
+		}
+	}
+
+	// Functions that may be removed
+	var functions []*shakeable
+	globals := make(map[string]*shakeable)
+	locals := make(map[*lua.Local]*shakeable)
+	for i, chunk := range chunks {
+		declared := make(map[*lua.Name]*lua.Local)
+		for _, local := range lua.Locals(chunk) {
+			declared[local.Decl] = local
+		}
+		for _, stat := range chunk.Block.Stats {
+			switch s := stat.(type) {
+			case *lua.FunctionStat:
+				name, ok := s.Target.(*lua.Name)
+				if !ok || writes[name.Name] != 1 || slices.Contains(keep, name.Name) || !isGlobalWrite(accesses[i], name) {
+					continue
+				}
+				f := &shakeable{script: i, name: name.Name, stat: s, function: s.Func}
+				globals[name.Name] = f
+				functions = append(functions, f)
+			case *lua.LocalFunctionStat:
+				f := &shakeable{script: i, name: s.Name.Name, stat: s, function: s.Func, local: declared[s.Name]}
+				locals[f.local] = f
+				functions = append(functions, f)
+			}
+		}
+	}
+
+	// Mark the functions the code outside of them refers to, and link the others to the
+	// functions their bodies refer to
+	var roots []*shakeable
+	refer := func(script int, pos lua.Position, target *shakeable) {
+		if from := enclosing(functions, script, pos); from != nil {
+			from.reaches = append(from.reaches, target)
+		} else {
+			roots = append(roots, target)
+		}
+	}
+	for i := range chunks {
+		for _, access := range accesses[i] {
+			if f, ok := globals[access.Name]; ok && !access.Write {
+				refer(i, access.Position, f)
+			}
+		}
+	}
+	for local, f := range locals {
+		for _, use := range local.Uses {
+			refer(f.script, use.Position, f)
+		}
+	}
+	for len(roots) > 0 {
+		f := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+		if !f.live {
+			f.live = true
+			roots = append(roots, f.reaches...)
+		}
+	}
+
+	var removed []Removed
+	shaken := make([][]byte, len(sources))
+	for i, src := range sources {
+		var dead []*shakeable
+		for _, f := range functions {
+			if f.script == i && !f.live {
+				dead = append(dead, f)
+			}
+		}
+		if len(dead) == 0 {
+			shaken[i] = src
+			continue
+		}
+		out, err := removeStats(src, dead)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", names[i], err)
+		}
+		shaken[i] = out
+		for _, f := range dead {
+			removed = append(removed, Removed{Script: names[i], Name: f.name, Line: f.stat.Pos().Line})
+		}
+	}
+	copy(sources, shaken)
+	return removed, nil
+}
+
+// isGlobalWrite reports whether accesses assign the global at name
+func isGlobalWrite(accesses []lua.GlobalAccess, name *lua.Name) bool {
+	for _, access := range accesses {
+		if access.Write && access.Position == name.Position {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosing returns the function of script whose statement contains pos, nil when pos is in no
+// such function
+func enclosing(functions []*shakeable, script int, pos lua.Position) *shakeable {
+	for _, f := range functions {
+		if f.script == script && !before(pos, f.stat.Pos()) && !before(f.function.End, pos) {
+			return f
+		}
+	}
+	return nil
+}
+
+// before reports whether a comes before b in a source
+func before(a, b lua.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// removeStats removes the top-level function statements dead from src, in source order, with
+// the semicolon following them if any. A semicolon is left in place of a statement between
+// another one and a parenthesis, so they cannot read as a call, as in a = b (f)(). The result
+// is parsed again to make sure it is valid.
+func removeStats(src []byte, dead []*shakeable) ([]byte, error) {
+	tokens, err := lua.Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[lua.Position]int)
+	for i, token := range tokens {
+		index[token.Position] = i
+	}
+
+	var edits []lua.Edit
+	// Index of the token after the last removed statement, and whether the code before it
+	// ends with a separator
+	after, separated := -1, true
+	for _, f := range dead {
+		first, last := index[f.stat.Pos()], index[f.function.End]
+		if first != after {
+			separated = first == 0 || isSemicolon(tokens[first-1])
+		}
+		if last+1 < len(tokens) && isSemicolon(tokens[last+1]) {
+			last++
+		}
+		text := ""
+		if !separated && last+1 < len(tokens) && tokens[last+1].Kind == lua.TokenSymbol && tokens[last+1].Text == "(" {
+			text, separated = ";", true
+		}
+		end := tokens[last].End()
+		text += strings.Repeat("\n", end.Line-f.stat.Pos().Line)
+		edits = append(edits, lua.Edit{From: f.stat.Pos(), To: end, Text: text})
+		after = last + 1
+	}
+
+	out := lua.Apply(src, edits)
+	if _, err := lua.Parse(out); err != nil {
+		return nil, fmt.Errorf("invalid source after removing functions: %w", err)
+	}
+	return out, nil
+}
+
+// isSemicolon reports whether token is the ; separating statements
+func isSemicolon(token lua.Token) bool {
+	return token.Kind == lua.TokenSymbol && token.Text == ";"
+}
@@ -0,0 +1,44 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShake(t *testing.T) {
+	names := []string{"util.lua", "main.lua"}
+	sources := [][]byte{
+		[]byte("function used() return helper() end\nfunction helper() end\nfunction unused()\n  return helper()\nend\nlocal function private() end\nfunction exported() end\n"),
+		[]byte("local function loop() loop() end\nx = 1 function dead() end (used)()\naddEventHandler(\"onStart\", root, function() used() end)\n"),
+	}
+	removed, err := Shake(names, sources, []string{"exported"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Removed{
+		{Script: "util.lua", Name: "unused", Line: 3},
+		{Script: "util.lua", Name: "private", Line: 6},
+		{Script: "main.lua", Name: "loop", Line: 1},
+		{Script: "main.lua", Name: "dead", Line: 2},
+	}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("Shake() removed %v, want %v", removed, want)
+	}
+	if got, want := string(sources[0]), "function used() return helper() end\nfunction helper() end\n\n\n\n\nfunction exported() end\n"; got != want {
+		t.Errorf("Shake() util.lua = %q, want %q", got, want)
+	}
+	if got, want := string(sources[1]), "\nx = 1 ; (used)()\naddEventHandler(\"onStart\", root, function() used() end)\n"; got != want {
+		t.Errorf("Shake() main.lua = %q, want %q", got, want)
+	}
+}
+
+func TestShakeDynamic(t *testing.T) {
+	sources := [][]byte{[]byte("function unused() end\nlocal f = _G[name]")}
+	if _, err := Shake([]string{"a.lua"}, sources, nil); err == nil {
+		t.Error("Shake() of a source reading _G by name succeeded")
+	}
+	if string(sources[0]) != "function unused() end\nlocal f = _G[name]" {
+		t.Errorf("Shake() changed the source to %q", sources[0])
+	}
+}
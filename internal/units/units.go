@@ -0,0 +1,183 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SizeUnits is the unit system of byte sizes
+type SizeUnits string
+
+// Supported unit systems. The zero value counts in 1024-byte units labelled KB, MB and so on,
+// the output of earlier versions.
+const (
+	Binary  SizeUnits = "binary"  // 1024-byte units labelled KiB, MiB, ...
+	Decimal SizeUnits = "decimal" // 1000-byte units labelled kB, MB, ...
+)
+
+// DefaultDurationPrecision is the precision of durations when none is set
+const DefaultDurationPrecision = time.Microsecond
+
+// Format controls how sizes, durations and numbers are written. The zero value gives the
+// output of earlier versions.
+type Format struct {
+	Sizes             SizeUnits     // Unit system of sizes
+	DurationPrecision time.Duration // Durations are rounded to a multiple of it (0 for DefaultDurationPrecision)
+	Locale            Locale        // Separators of numbers
+}
+
+// Locale holds the separators of a language
+type Locale struct {
+	Tag     string // Language tag the locale was parsed from, empty for the default
+	Decimal string // Decimal separator
+	Group   string // Separator of thousands, empty to not group digits
+}
+
+// separators maps language codes to their decimal and thousands separators
+var separators = map[string][2]string{
+	"en": {".", ","}, "ja": {".", ","}, "zh": {".", ","}, "ko": {".", ","}, "th": {".", ","},
+	"de": {",", "."}, "es": {",", "."}, "it": {",", "."}, "nl": {",", "."}, "pt": {",", "."},
+	"tr": {",", "."}, "id": {",", "."}, "da": {",", "."}, "ro": {",", "."}, "el": {",", "."},
+	"hr": {",", "."}, "sr": {",", "."}, "sl": {",", "."},
+	"fr": {",", "\u00a0"}, "ru": {",", "\u00a0"}, "pl": {",", "\u00a0"}, "cs": {",", "\u00a0"},
+	"sk": {",", "\u00a0"}, "sv": {",", "\u00a0"}, "fi": {",", "\u00a0"}, "nb": {",", "\u00a0"},
+	"no": {",", "\u00a0"}, "uk": {",", "\u00a0"}, "hu": {",", "\u00a0"}, "bg": {",", "\u00a0"},
+	"lt": {",", "\u00a0"}, "lv": {",", "\u00a0"}, "et": {",", "\u00a0"},
+}
+
+// regionSeparators overrides the separators of a language in some regions
+var regionSeparators = map[string][2]string{
+	"de-ch": {".", "'"}, "it-ch": {".", "'"}, "fr-ch": {".", "'"},
+	"es-mx": {".", ","}, "pt-br": {",", "."}, "en-za": {",", "\u00a0"},
+}
+
+// ParseSizeUnits parses a unit system name. An empty name gives the default units.
+func ParseSizeUnits(name string) (SizeUnits, error) {
+	switch units := SizeUnits(strings.ToLower(name)); units {
+	case "", Binary, Decimal:
+		return units, nil
+	}
+	return "", fmt.Errorf("invalid size units %q (use binary or decimal)", name)
+}
+
+// ParseDurationPrecision parses a duration precision such as 1ms or 1s. An empty value gives
+// the default precision.
+func ParseDurationPrecision(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	precision, err := time.ParseDuration(value)
+	if err != nil || precision <= 0 {
+		return 0, fmt.Errorf("invalid duration precision %q (use a duration such as 1ms or 1s)", value)
+	}
+	return precision, nil
+}
+
+// ParseLocale returns the separators of a locale given as a language tag (de, pt-BR) or a POSIX
+// locale name (de_DE.UTF-8). "auto" reads the locale from LC_ALL, LC_NUMERIC or LANG, and an
+// empty name gives the default: a decimal point and no grouping.
+func ParseLocale(name string) (Locale, error) {
+	if name == "auto" {
+		name = systemLocale()
+	}
+	if name == "" || name == "C" || name == "POSIX" {
+		return Locale{Decimal: "."}, nil
+	}
+
+	tag := strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	language, _, _ := strings.Cut(tag, "-")
+
+	seps, ok := regionSeparators[tag]
+	if !ok {
+		seps, ok = separators[language]
+	}
+	if !ok {
+		return Locale{}, fmt.Errorf("unsupported locale %q", name)
+	}
+	return Locale{Tag: tag, Decimal: seps[0], Group: seps[1]}, nil
+}
+
+// systemLocale returns the locale of numbers set in the environment
+func systemLocale() string {
+	for _, key := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Size formats a size in bytes, such as "1.5 KB"
+func (f Format) Size(bytes int64) string {
+	unit, labels := int64(1024), []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+	switch f.Sizes {
+	case Binary:
+		labels = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	case Decimal:
+		unit, labels = 1000, []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	}
+
+	if bytes > -unit && bytes < unit {
+		return f.Number(float64(bytes), 0) + " B"
+	}
+	div, exp := unit, 0
+	for n := bytes / unit; n >= unit || n <= -unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return f.Number(float64(bytes)/float64(div), 1) + " " + labels[exp]
+}
+
+// Duration formats a duration rounded to the precision of f, such as "1.5ms"
+func (f Format) Duration(d time.Duration) string {
+	precision := f.DurationPrecision
+	if precision <= 0 {
+		precision = DefaultDurationPrecision
+	}
+	text := d.Round(precision).String()
+	if decimal := f.Locale.Decimal; decimal != "" && decimal != "." {
+		text = strings.ReplaceAll(text, ".", decimal)
+	}
+	return text
+}
+
+// Number formats a number with the given count of decimals and the separators of the locale
+func (f Format) Number(value float64, decimals int) string {
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return text
+	}
+
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(text, ".")
+
+	if group := f.Locale.Group; group != "" && len(whole) > 3 {
+		var grouped strings.Builder
+		for i, digit := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				grouped.WriteString(group)
+			}
+			grouped.WriteRune(digit)
+		}
+		whole = grouped.String()
+	}
+
+	if !hasFraction {
+		return sign + whole
+	}
+	decimal := f.Locale.Decimal
+	if decimal == "" {
+		decimal = "."
+	}
+	return sign + whole + decimal + fraction
+}
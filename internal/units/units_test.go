@@ -0,0 +1,46 @@
+package units
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	german, err := ParseLocale("de_DE.UTF-8")
+	if err != nil {
+		t.Fatalf("ParseLocale failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Default size", Format{}.Size(2048), "2.0 KB"},
+		{"Default small size", Format{}.Size(512), "512 B"},
+		{"Binary size", Format{Sizes: Binary}.Size(1536 * 1024), "1.5 MiB"},
+		{"Decimal size", Format{Sizes: Decimal}.Size(1500), "1.5 kB"},
+		{"German size", Format{Locale: german}.Size(1_024_512), "1.000,5 KB"},
+		{"Default duration", Format{}.Duration(1500 * time.Microsecond), "1.5ms"},
+		{"Rounded duration", Format{DurationPrecision: time.Millisecond}.Duration(1500 * time.Microsecond), "2ms"},
+		{"German duration", Format{Locale: german}.Duration(2500 * time.Millisecond), "2,5s"},
+		{"English number", Format{Locale: Locale{Decimal: ".", Group: ","}}.Number(-1234567.891, 2), "-1,234,567.89"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	if locale, err := ParseLocale("pt-BR"); err != nil || locale.Decimal != "," || locale.Group != "." {
+		t.Errorf("ParseLocale(pt-BR) = %+v, %v", locale, err)
+	}
+	if locale, err := ParseLocale(""); err != nil || locale.Decimal != "." || locale.Group != "" {
+		t.Errorf("ParseLocale(\"\") = %+v, %v", locale, err)
+	}
+	if _, err := ParseLocale("xx"); err == nil {
+		t.Error("ParseLocale accepted an unknown language")
+	}
+}
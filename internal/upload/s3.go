@@ -0,0 +1,303 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestName is the object listing every uploaded file, written under the prefix last
+const ManifestName = "manifest.json"
+
+// hashHeader is the object metadata holding the SHA-256 of its content
+const hashHeader = "X-Amz-Meta-Sha256"
+
+// skippedFiles are build bookkeeping files that are not uploaded
+var skippedFiles = map[string]bool{
+	".mta-bundler-manifest.json": true,
+	".mta-bundler-stamp.json":    true,
+}
+
+// Bucket is an S3-compatible bucket receiving build outputs
+type Bucket struct {
+	Endpoint     string // Base URL of the storage service, such as https://s3.eu-central-1.amazonaws.com
+	Region       string // Region used to sign requests
+	Name         string // Bucket name
+	Prefix       string // Key prefix of the uploaded files, without leading or trailing slash
+	PathStyle    bool   // Address the bucket as <endpoint>/<bucket> instead of <bucket>.<endpoint host>
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // Temporary credentials token, empty for long-term keys
+
+	// ContentAddressed stores files under <prefix>/objects/<sha256> instead of their path, so
+	// files shared by builds are stored once and never overwritten. The manifest maps paths
+	// to objects.
+	ContentAddressed bool
+
+	HTTPClient *http.Client // Client used for requests (nil uses a client with a 5 minute timeout)
+}
+
+// Manifest lists the files of an upload, so servers can download and verify them
+type Manifest struct {
+	UploadedAt time.Time      `json:"uploaded_at"`
+	Files      []ManifestFile `json:"files"`
+}
+
+// ManifestFile is an uploaded file
+type ManifestFile struct {
+	Path   string `json:"path"`   // Path relative to the build directory, slash-separated
+	Key    string `json:"key"`    // Object key
+	Size   int64  `json:"size"`   // Size in bytes
+	SHA256 string `json:"sha256"` // Hex SHA-256 of the content
+}
+
+// Result summarizes an upload
+type Result struct {
+	Manifest Manifest
+	Uploaded int   // Files sent to the bucket
+	Skipped  int   // Files already in the bucket with the same content
+	Bytes    int64 // Size of the files sent
+}
+
+// Upload sends the files of dir to the bucket. Files whose object already holds the same
+// content, according to its SHA-256 metadata, are not sent again. The manifest of the upload
+// is written last, so a complete manifest always describes complete files.
+func (b Bucket) Upload(dir string) (Result, error) {
+	var result Result
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || skippedFiles[info.Name()] {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		file := ManifestFile{Path: filepath.ToSlash(relPath), Size: int64(len(data)), SHA256: hashHex(data)}
+		file.Key = b.key(file.Path)
+		if b.ContentAddressed {
+			file.Key = b.key("objects/" + file.SHA256)
+		}
+
+		stored, err := b.storedHash(file.Key)
+		if err != nil {
+			return err
+		}
+		if stored == file.SHA256 {
+			result.Skipped++
+		} else {
+			if err := b.put(file.Key, data, contentType(file.Path)); err != nil {
+				return fmt.Errorf("failed to upload %s: %w", file.Path, err)
+			}
+			result.Uploaded++
+			result.Bytes += file.Size
+		}
+		result.Manifest.Files = append(result.Manifest.Files, file)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Manifest.UploadedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(result.Manifest, "", "  ")
+	if err != nil {
+		return result, fmt.Errorf("failed to encode upload manifest: %w", err)
+	}
+	if err := b.put(b.key(ManifestName), append(data, '\n'), "application/json"); err != nil {
+		return result, fmt.Errorf("failed to upload %s: %w", ManifestName, err)
+	}
+	return result, nil
+}
+
+// key returns the object key of a path below the prefix
+func (b Bucket) key(relPath string) string {
+	if prefix := strings.Trim(b.Prefix, "/"); prefix != "" {
+		return prefix + "/" + relPath
+	}
+	return relPath
+}
+
+// storedHash returns the SHA-256 metadata of the object at key, or an empty string when the
+// object does not exist
+func (b Bucket) storedHash(key string) (string, error) {
+	resp, err := b.do(http.MethodHead, key, nil, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get(hashHeader), nil
+	case http.StatusNotFound:
+		return "", nil
+	}
+	return "", fmt.Errorf("failed to check %s: HTTP %d", key, resp.StatusCode)
+}
+
+// put stores data at key
+func (b Bucket) put(key string, data []byte, contentType string) error {
+	resp, err := b.do(http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// do sends a signed request for the object at key
+func (b Bucket) do(method, key string, data []byte, contentType string) (*http.Response, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(b.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", b.Endpoint)
+	}
+	objectPath := "/" + key
+	if b.PathStyle {
+		objectPath = "/" + b.Name + objectPath
+	} else {
+		endpoint.Host = b.Name + "." + endpoint.Host
+	}
+	// The path is sent with every reserved character escaped, the encoding the signature expects
+	endpoint.RawPath = endpoint.EscapedPath() + uriEncode(objectPath)
+	endpoint.Path = endpoint.Path + objectPath
+
+	req, err := http.NewRequest(method, endpoint.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(data))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if method == http.MethodPut {
+		req.Header.Set(hashHeader, hashHex(data))
+	}
+	b.sign(req, hashHex(data), time.Now().UTC())
+
+	httpClient := b.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Minute}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", endpoint.Host, err)
+	}
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (b Bucket) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.SessionToken)
+	}
+
+	// Canonical headers: host and every x-amz-* and content-type header, sorted by name
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+b.SecretKey), date)
+	key = hmacSHA256(key, b.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.AccessKey, scope, signedHeaders, signature))
+}
+
+// uriEncode escapes every byte of an object path except unreserved characters and slashes
+func uriEncode(objectPath string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(objectPath); i++ {
+		c := objectPath[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// contentType returns the MIME type of a file from its extension
+func contentType(relPath string) string {
+	if t := mime.TypeByExtension(path.Ext(relPath)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// hashHex returns the hex SHA-256 of data
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,112 @@
+package upload
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory S3 server for path-style requests, checking request signatures
+type fakeS3 struct {
+	t       *testing.T
+	bucket  Bucket
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    []string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Sign the request as received and compare with the signature sent
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.EscapedPath(), nil)
+	for name, values := range r.Header {
+		if name != "Authorization" {
+			check.Header[name] = values
+		}
+	}
+	s.bucket.sign(check, r.Header.Get("X-Amz-Content-Sha256"), now)
+	if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		s.t.Errorf("Signature mismatch for %s %s", r.Method, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/"+s.bucket.Name+"/")
+	switch r.Method {
+	case http.MethodHead:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(hashHeader, hashHex(data))
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+		s.puts = append(s.puts, key)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	buildDir := t.TempDir()
+	for name, content := range map[string]string{
+		"[gameplay]/race/meta.xml":    "<meta />",
+		"[gameplay]/race/client.luac": "compiled",
+		"admin.zip":                   "archive",
+		".mta-bundler-manifest.json":  "{}",
+	} {
+		path := filepath.Join(buildDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bucket := Bucket{Region: "eu-central-1", Name: "builds", Prefix: "release/", PathStyle: true, AccessKey: "AKID", SecretKey: "secret"}
+	s3 := &fakeS3{t: t, bucket: bucket, objects: map[string][]byte{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+	bucket.Endpoint = server.URL
+
+	result, err := bucket.Upload(buildDir)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if result.Uploaded != 3 || result.Skipped != 0 || len(result.Manifest.Files) != 3 {
+		t.Errorf("Unexpected first upload: %+v", result)
+	}
+	if string(s3.objects["release/[gameplay]/race/client.luac"]) != "compiled" {
+		t.Errorf("Script not stored under its path, got keys %v", s3.puts)
+	}
+	if _, ok := s3.objects["release/"+ManifestName]; !ok {
+		t.Error("Manifest not uploaded")
+	}
+
+	result, err = bucket.Upload(buildDir)
+	if err != nil {
+		t.Fatalf("Second upload failed: %v", err)
+	}
+	if result.Uploaded != 0 || result.Skipped != 3 {
+		t.Errorf("Second upload sent unchanged files: %+v", result)
+	}
+
+	bucket.ContentAddressed = true
+	if _, err := bucket.Upload(buildDir); err != nil {
+		t.Fatalf("Content-addressed upload failed: %v", err)
+	}
+	if _, ok := s3.objects["release/objects/"+hashHex([]byte("archive"))]; !ok {
+		t.Error("Content-addressed object not stored under its hash")
+	}
+}
@@ -1,14 +1,35 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/davidbozo/mta-bundler/internal/advisor"
+	"github.com/davidbozo/mta-bundler/internal/bundler"
 	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/escrow"
+	"github.com/davidbozo/mta-bundler/internal/lint"
+	"github.com/davidbozo/mta-bundler/internal/notify"
+	"github.com/davidbozo/mta-bundler/internal/report"
 	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/retention"
+	"github.com/davidbozo/mta-bundler/internal/transform"
+	"github.com/davidbozo/mta-bundler/internal/units"
 )
 
 var (
@@ -18,6 +39,156 @@ var (
 	suppressWarn   = flag.Bool("d", false, "suppress decompile warning")
 	showVersion    = flag.Bool("v", false, "show version information")
 	mergeMode      = flag.Bool("m", false, "merge all scripts into client.luac and server.luac")
+	bundleName     = flag.String("bundle-name", "", "path of the merged bundles in the output resource, {type} is replaced by client or server (default {type}.luac)")
+	banner         = flag.String("banner", "", "comment written at the top of output meta.xml files and merged bundles, {resource}, {version}, {build} (with -stamp) and {commit} are replaced")
+	infoList       = flag.String("info", "", "comma-separated name=value attributes set on the <info> tag of output meta.xml files, name+=value appends to the source value, added to the config file's info")
+	mergeOrder     = flag.String("merge-order", "", "order of merged scripts: type (client or server scripts, then shared scripts) or meta (meta.xml order)")
+	mergeIsolate   = flag.Bool("merge-isolate", false, "run every merged script through pcall, so an error in one does not stop the rest of the bundle (implies -merge-strategy concat)")
+	mergeStrategy  = flag.String("merge-strategy", "", "how scripts are merged: files (one luac_mta input per script) or concat (one concatenated source, each script in a do...end block)")
+	sourceMaps     = flag.Bool("source-map", false, "write a source map next to each merged bundle, mapping bundle lines to the original scripts")
+	sourceMapShim  = flag.Bool("source-map-shim", false, "also add a script to merged resources that reports errors at their original script and line (implies -source-map)")
+	watchMode      bool
+	quietMode      bool
+	verboseMode    bool
+	escrowKeyFile  = flag.String("escrow-key", "", "write an encrypted source escrow into each compiled resource using this key file")
+	reportSpec     = flag.String("report", "", "write a build report with an inventory of the resources: json[=path] or html[=path] (path \"-\" writes to stdout)")
+	configPath     = flag.String("config", "", "path to a config file (default is "+config.FileName+" at the input root)")
+	buildInfo      = flag.String("build-info", "", "generate a resource with this name showing the build on the client loading screen (requires -o)")
+	onlyResources  = flag.String("only", "", "comma-separated resource names or path globs to build, skipping all others")
+	excludeList    = flag.String("exclude", "", "comma-separated resource names or path globs to skip, added to the config file's exclude list")
+	ignoreList     = flag.String("ignore", "", "comma-separated directory names or path globs not searched for resources, added to the config file's ignore list (.git, .svn, .hg and node_modules are never searched)")
+	maxDepth       = flag.Int("max-depth", 0, "search resources at most this many directories below the input (0 for no limit)")
+	followSymlinks = flag.Bool("follow-symlinks", false, "also search symlinked directories for resources, each directory once even if several links point to it")
+	skipCategories = flag.String("skip-categories", "", "comma-separated category folders not searched for resources, by name without brackets (e.g. disabled for [disabled]), added to the config file's skip_categories list")
+	verbatimList   = flag.String("verbatim", "", "comma-separated script src globs copied as source instead of compiled, added to the config file's verbatim list")
+	mergeExclude   = flag.String("merge-exclude", "", "comma-separated script src globs compiled to their own .luac file in merge mode instead of into the bundles, added to the config file's merge_exclude list")
+	scriptsOnly    = flag.Bool("scripts-only", false, "write only meta.xml and compiled scripts, without copying non-script files")
+	linkAssets     = flag.Bool("link-assets", false, "hardlink (or symlink) non-script files into the output instead of copying them")
+	metaRegex      = flag.Bool("meta-regex", false, "rewrite meta.xml with the regular expressions of earlier versions instead of the XML token editor")
+	raiseMinVer    = flag.Bool("raise-min-version", true, "raise <min_mta_version> of output meta.xml files to the MTA version obfuscation levels 2 and 3 need, false leaves it as it is")
+	levelFallback  = flag.Bool("level-fallback", false, "lower an obfuscation level the luac_mta binary is too old for to the highest it supports, with a warning, instead of failing before the build")
+	clientCache    = flag.Bool("client-cache", true, "let clients cache client scripts on disk, false sets cache=\"false\" on every client and shared script of the output meta.xml")
+	zipOutput      = flag.Bool("zip", false, "package each compiled resource as <name>.zip instead of a directory (requires -o)")
+	syntaxCheck    = flag.Bool("syntax-check", true, "parse every script before compiling and stop before building anything if one has a syntax error, false leaves syntax errors to luac_mta")
+	preflight      = flag.Bool("preflight", false, "parse every script with luac_mta -p in large batches before compiling and stop before building anything if one has a syntax error")
+	minify         = flag.Bool("minify", false, "strip comments and whitespace from scripts before compiling them, keeping their line numbers")
+	renameLocals   = flag.Bool("rename-locals", false, "rename the local variables and functions of scripts to short meaningless names before compiling them")
+	foldConstants  = flag.Bool("fold-constants", false, "fold the constant expressions of scripts and remove if branches with constant conditions before compiling them")
+	defineList     = flag.String("define", "", "comma-separated NAME=value constants inlined for reads of these globals in scripts, added to the config file's constants (implies -fold-constants)")
+	treeShake      = flag.Bool("tree-shake", false, "in merge mode, drop the top-level functions of the bundles that no code, export or event handler of the bundle refers to")
+	scanBackdoors  = flag.Bool("scan", false, "scan scripts for backdoor patterns before compiling, failing resources with findings not acknowledged in their "+config.ResourceFileName)
+	checksums      = flag.Bool("checksums", false, "write checksums.txt and checksums.json listing the SHA-256 and size of every output file (requires -o)")
+	stampSpec      = flag.String("stamp", "", "stamp a build number into every output resource: auto (last build + 1) or a number (requires -o)")
+	deployTo       = flag.String("deploy", "", "after a successful build, deploy the output to this server of the config file (requires -o)")
+	webhookURL     = flag.String("webhook", "", "post a build summary to this webhook URL (Discord, Slack or JSON), added to the config file's webhooks")
+	uploadTo       = flag.String("upload", "", "after a successful build, upload the output to this bucket of the config file (requires -o)")
+	frozenLock     = flag.Bool("frozen", false, "fail instead of updating "+config.LockFileName+" when the build inputs resolve differently")
+	offlineMode    = flag.Bool("offline", false, "never download luac_mta, failing when no local or cached binary is found")
+	refreshComp    = flag.Bool("refresh-compiler", false, "download luac_mta again even when it is cached, replacing the cached binary")
+	compilerMaxAge = flag.String("compiler-max-age", "", "download luac_mta again when the cached binary is older than this, such as 30d or 12h (default: no limit)")
+	compilerProxy  = flag.String("compiler-proxy", "", "download luac_mta through this proxy, such as http://proxy.example.com:3128 (default: the HTTPS_PROXY and HTTP_PROXY environment variables)")
+	dlTimeout      = flag.String("download-timeout", "2m", "interrupt a luac_mta download taking longer than this, to be resumed by the next run (0 for no limit)")
+	dlRetries      = flag.Int("download-retries", compiler.DefaultDownloadRetries, "retry a luac_mta download that failed because of the connection or the server this many times, waiting longer each time")
+	compRetries    = flag.Int("retries", 0, "run a compilation that failed for a transient reason (busy binary, file locked by an antivirus, temporary I/O error) again up to this many times, after a short delay")
+	compilerPath   = flag.String("compiler", "", "path of the luac_mta binary to use, skipping binary detection and the vendored compiler (default: the "+compiler.BinaryEnv+" environment variable)")
+	compStrategy   = flag.String("compiler-strategy", "", "how luac_mta runs: native (a local or downloaded binary of this system), docker (the Linux binary in a Docker container), rosetta (a macOS binary, without downloads) or remote (the compile API, requires -remote-compile) (default native)")
+	remoteCompile  = flag.Bool("remote-compile", false, "upload scripts to the compile API of luac.mtasa.com when no luac_mta binary can run, or always with -compiler-strategy remote (scripts leave this machine)")
+	remoteJobs     = flag.Int("remote-jobs", compiler.DefaultRemoteJobs, "number of scripts compiled with the compile API at the same time")
+	sandboxMode    = flag.Bool("sandbox", false, "run luac_mta in a bubblewrap (bwrap) sandbox without network access, seeing only its input scripts (Linux)")
+	lockFile       = flag.String("lock-file", "", "path of the lock file (default "+config.LockFileName+" next to the config file, or at the input root)")
+	writeLock      = flag.Bool("lock", false, "create "+config.LockFileName+" at the input root even without a config file")
+	forceBuild     = flag.Bool("force", false, "rebuild every resource, even those unchanged since the last build")
+	cleanOutput    = flag.Bool("clean", false, "remove files in the output resources that the build no longer produces (requires -o)")
+	failFast       = flag.Bool("fail-fast", false, "stop the build at the first resource that fails")
+	keepGoing      = flag.Bool("keep-going", false, "build every resource even if some fail (default), the exit status is still non-zero")
+	adviseMode     = flag.Bool("advise", false, "trial-compile a sample of each resource with alternative options and suggest size optimizations")
+	noColor        = flag.Bool("no-color", false, "disable colored output (also disabled by the NO_COLOR environment variable)")
+	diagnostics    = flag.String("diagnostics", "", "also write warnings and errors to this file as NDJSON, one JSON object per line as they happen")
+	frontendMode   = flag.String("frontend", "", "replace the console output with events for graphical frontends: json (newline-delimited JSON on stdout)")
+	sizeUnits      = flag.String("size-units", "", "units of sizes in the output and reports: binary (KiB, MiB) or decimal (kB, MB)")
+	durationPrec   = flag.String("duration-precision", "", "round durations in the output and reports to this precision, such as 1ms (default 1µs)")
+	numberLocale   = flag.String("locale", "", "format numbers in the output and reports for this locale, such as de or pt-BR (auto reads LANG)")
+	checkOnly      = flag.Bool("check-only", false, "validate meta.xml files, referenced files and the compiler without building anything")
+	silentMode     = flag.Bool("silent", false, "print nothing, report the -check-only result only through the exit status (requires -check-only)")
+	emitSpec       = flag.String("emit", "", "write the build plan as a build file running the bundler for each resource instead of building: ninja[=path] or make[=path] (path \"-\" writes to stdout, requires -o)")
+
+	// verbatimPatterns are the verbatim script globs of the config file
+	verbatimPatterns []string
+	// mergeExcludePatterns are the merge exclude script globs of the config file
+	mergeExcludePatterns []string
+	// packs are the resource packs of the config file
+	packs []bundler.Pack
+	// splits are the resource splits of the config file
+	splits []bundler.Split
+	// dependencies are the third-party resources of the config file
+	dependencies []config.Dependency
+	// ignorePatterns are the directory globs of the config file not searched for resources
+	ignorePatterns []string
+	// categoryPatterns are the category folders of the config file not searched for resources
+	categoryPatterns []string
+	// lintRules are the meta.xml lint rules of the config file
+	lintRules map[string]lint.Setting
+	// configConstants are the constants of the config file inlined by constant folding
+	configConstants config.Constants
+	// configInfo are the <info> attributes of the config file, as name=value or name+=value
+	configInfo []string
+	// compilerArgs are the luac_mta arguments given with -compiler-arg
+	compilerArgs listFlag
+	// configCompilerArgs are the luac_mta arguments of the config file
+	configCompilerArgs []string
+	// configMirror is the URL template of the config file luac_mta is downloaded from
+	configMirror string
+	// configImage is the image of the config file luac_mta runs in with the docker strategy
+	configImage string
+	// subtrees are the config files nested below the input root
+	subtrees []config.Config
+	// deployTarget is the config file server given with -deploy
+	deployTarget config.Server
+	// uploadTarget is the config file bucket given with -upload
+	uploadTarget config.Upload
+	// webhooks receive a summary after each build, from the config file and -webhook
+	webhooks []notify.Webhook
+	// outputFormat is how sizes and durations are written in the console output and reports
+	outputFormat units.Format
+
+	// silenceErrors keeps exitWithError from printing once a -silent check started, invalid
+	// flags are still reported
+	silenceErrors bool
+
+	// reportFormat is the format of the report given with -report
+	reportFormat string
+
+	// emitFormat and emitPath are the format and path of the build file given with -emit
+	emitFormat, emitPath string
+
+	// lockFilePath is the lock file recording the resolved build inputs, empty outside builds
+	lockFilePath string
+	// lockWritable is set when the lock file may be created: a config file, -lock-file or -lock
+	// asks for one. An existing lock file is always kept up to date.
+	lockWritable bool
+
+	// compilerSandbox is the sandbox luac_mta runs in with -sandbox, nil runs it directly
+	compilerSandbox *compiler.Sandbox
+	// compilerContainer is the container luac_mta runs in with the docker strategy, nil runs it
+	// directly
+	compilerContainer *compiler.Container
+	// buildCache keeps file hashes and parsed resources between the builds of the daemon, nil
+	// outside it
+	buildCache *bundler.Cache
+
+	// inputPaths are the meta.xml files and directories of several input paths or of an input
+	// list given as @path or -, nil when the single input path is built
+	inputPaths []string
+
+	// compilerLock is the compiler pinned by the config file, nil when builds detect luac_mta
+	compilerLock *config.CompilerLock
+
+	// logLevel is the level of the console logger, set from -q and -vv
+	logLevel = new(slog.LevelVar)
+	// console is the handler of the default logger
+	console = bundler.NewConsoleHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
+	// frontend replaces console with -frontend json, nil otherwise
+	frontend *bundler.FrontendHandler
 
 	// Build-time variables set by GoReleaser
 	version = "dev"
@@ -25,32 +196,107 @@ var (
 	date    = "unknown"
 )
 
+// subcommands maps command names to their handlers. Each handler receives the
+// arguments following the command name and parses its own flags.
+var subcommands = map[string]func(args []string) error{
+	"inspect":        runInspect,
+	"scan-compiled":  runScanCompiled,
+	"escrow-rebuild": runEscrowRebuild,
+	"serve":          runServe,
+	"daemon":         runDaemon,
+	"request":        runRequest,
+	"deploy":         runDeploy,
+	"upload":         runUpload,
+	"history":        runHistory,
+	"compiler":       runCompilerCommand,
+	"deps":           runDepsCommand,
+	"prune":          runPrune,
+	"validate":       runValidate,
+	"graph":          runGraph,
+	"ab-test":        runABTest,
+}
+
 func init() {
+	flag.BoolVar(&watchMode, "w", false, "watch the input for changes and recompile affected resources (requires -o)")
+	flag.BoolVar(&watchMode, "watch", false, "watch the input for changes and recompile affected resources (requires -o)")
+	flag.BoolVar(&quietMode, "q", false, "only show errors and the final summary")
+	flag.BoolVar(&quietMode, "quiet", false, "only show errors and the final summary")
+	flag.BoolVar(&verboseMode, "vv", false, "show debug output (luac_mta command lines, binary detection, output paths)")
+	flag.BoolVar(&verboseMode, "verbose", false, "show debug output (luac_mta command lines, binary detection, output paths)")
+	flag.Var(&compilerArgs, "compiler-arg", "argument passed to luac_mta after the options the bundler models, for luac_mta options it does not know about (repeatable), added to the config file's compiler_args")
+
 	flag.Usage = func() {
 		binaryName := filepath.Base(os.Args[0])
 		fmt.Fprintf(os.Stderr, "MTA Lua Compiler - Compile and obfuscate Lua resources for Multi Theft Auto\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] input_path\n\n", binaryName)
-		fmt.Fprintf(os.Stderr, "MTA Lua Compiler accepts only two input types:\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] input_path [input_path...]\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s <command> [arguments]\n\n", binaryName)
+		fmt.Fprintf(os.Stderr, "MTA Lua Compiler accepts these input types:\n")
 		fmt.Fprintf(os.Stderr, "  • Single meta.xml file - Compiles all referenced scripts in the resource\n")
-		fmt.Fprintf(os.Stderr, "  • Directory - Recursively finds and compiles ALL meta.xml files found\n\n")
+		fmt.Fprintf(os.Stderr, "  • Directory - Recursively finds and compiles ALL meta.xml files found\n")
+		fmt.Fprintf(os.Stderr, "  • Single .lua file - Compiles one standalone script without a meta.xml\n")
+		fmt.Fprintf(os.Stderr, "  • Several meta.xml files and directories - Compiles their resources under one output root\n")
+		fmt.Fprintf(os.Stderr, "  • @list file or - (stdin) - Compiles the meta.xml files or resource directories listed one per line\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  %s /path/to/resource/meta.xml    # Compile single MTA resource\n", binaryName)
 		fmt.Fprintf(os.Stderr, "  %s /path/to/resources/           # Compile ALL resources in directory\n", binaryName)
 		fmt.Fprintf(os.Stderr, "  %s -o compiled/ /path/to/resources/ # Compile all resources to output dir\n", binaryName)
 		fmt.Fprintf(os.Stderr, "  %s -e3 -s /path/to/resources/    # Max obfuscation + strip debug for all resources\n", binaryName)
 		fmt.Fprintf(os.Stderr, "  %s -m /path/to/resource/meta.xml # Merge mode: create client.luac and server.luac\n", binaryName)
+		fmt.Fprintf(os.Stderr, "  %s -e 3 -o out/ script.lua        # Compile a single script to out/script.luac\n", binaryName)
+		fmt.Fprintf(os.Stderr, "  %s -w -o compiled/ /path/to/resources/ # Rebuild on every change while developing\n", binaryName)
+		fmt.Fprintf(os.Stderr, "\nCommands:\n")
+		fmt.Fprintf(os.Stderr, "  inspect <file.luac>    Print bytecode header information and statistics\n")
+		fmt.Fprintf(os.Stderr, "  scan-compiled <dir>    Report compiled files that are easily decompilable\n")
+		fmt.Fprintf(os.Stderr, "  escrow-rebuild <dir>   Recompile deployed resources in place from their source escrow\n")
+		fmt.Fprintf(os.Stderr, "  serve <input_path>     Run the builds scheduled in the config file until interrupted\n")
+		fmt.Fprintf(os.Stderr, "  daemon <input_path>    Keep caches in memory and build whenever a request comes in\n")
+		fmt.Fprintf(os.Stderr, "  request [input_path]   Ask the running daemon to build\n")
+		fmt.Fprintf(os.Stderr, "  deploy                 Request and approve signed deployments of a build\n")
+		fmt.Fprintf(os.Stderr, "  history                List builds and deployments recorded in the audit log\n")
+		fmt.Fprintf(os.Stderr, "  compiler vendor        Copy luac_mta into the project and pin it in the config file\n")
+		fmt.Fprintf(os.Stderr, "  deps outdated|update   Check dependencies for newer releases and update their pins\n")
+		fmt.Fprintf(os.Stderr, "  prune [input_path]     Remove old cached dependencies and deployment bundles\n")
+		fmt.Fprintf(os.Stderr, "  validate <input_path>  Report meta.xml and referenced file problems without compiling\n")
+		fmt.Fprintf(os.Stderr, "  graph <input_path>     Write the resources and their includes as a DOT or Mermaid graph\n")
+		fmt.Fprintf(os.Stderr, "  ab-test <input_path>   Build resources at two obfuscation levels to compare load times\n")
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
+	console.SetStyle(bundler.NewStyle(bundler.ColorEnabled(os.Stdout)))
+	slog.SetDefault(slog.New(console))
+
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+	}
+
 	flag.Parse()
 
 	if err := runCompiler(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exitWithError(err)
+	}
+}
+
+// exitWithError prints err to stderr and exits with status 1. Once a -silent check runs, it
+// only sets the exit status. With -frontend json, the error is the last event on stdout.
+func exitWithError(err error) {
+	if silenceErrors {
+		os.Exit(1)
+	}
+	if frontend != nil {
+		frontend.Fatal(err)
 		os.Exit(1)
 	}
+	style := bundler.NewStyle(!*noColor && bundler.ColorEnabled(os.Stderr))
+	fmt.Fprintf(os.Stderr, "%s %v\n", style.Failure("Error:"), err)
+	os.Exit(1)
 }
 
 func runCompiler() error {
@@ -62,40 +308,694 @@ func runCompiler() error {
 		return nil
 	}
 
-	// Handle obfuscation level flags
-	obfuscationLevel := *obfuscateLevel
+	if args := flag.Args(); len(args) == 1 && isLuaScript(args[0]) {
+		return compileScript(args[0])
+	}
 
-	// Validate obfuscation level
-	if obfuscationLevel < 0 || obfuscationLevel > 3 {
-		return fmt.Errorf("invalid obfuscation level: %d (must be 0-3)", obfuscationLevel)
+	inputPath, reportPath, cfg, err := prepareBuild()
+	if err != nil {
+		return err
+	}
+
+	if *checkOnly {
+		silenceErrors = *silentMode
+		return checkResources(inputPath, cfg.Exclude)
+	}
+
+	if emitFormat != "" {
+		return emitBuildFile(inputPath, cfg)
+	}
+
+	slog.Info("Build options",
+		"input", inputPath,
+		"output", *outputFile,
+		"strip_debug", *stripDebug,
+		"obfuscation", *obfuscateLevel,
+		"suppress_warnings", *suppressWarn,
+		"merge", *mergeMode,
+		"watch", watchMode,
+	)
+
+	// Implement actual compilation logic
+	return compileResources(inputPath, cfg.Exclude, reportPath)
+}
+
+// prepareBuild validates the input path and build flags, and applies the project config file.
+// It returns the input path, the report path (empty when disabled) and the loaded config.
+func prepareBuild() (string, string, config.Config, error) {
+	if err := configureLogging(); err != nil {
+		return "", "", config.Config{}, err
 	}
 
 	args := flag.Args()
 	if len(args) == 0 {
-		return fmt.Errorf("no input path provided")
+		return "", "", config.Config{}, fmt.Errorf("no input path provided")
 	}
 
-	if len(args) > 1 {
-		return fmt.Errorf("only one input path is allowed, got %d arguments", len(args))
+	inputPath := args[0]
+	if len(args) > 1 || isInputList(inputPath) {
+		if watchMode {
+			return "", "", config.Config{}, fmt.Errorf("-w cannot be used with several input paths or an input list, it watches a single input")
+		}
+		var err error
+		if len(args) > 1 {
+			inputPath, err = resolveInputs(args)
+			inputPaths = args
+		} else {
+			inputPaths, err = readInputList(inputPath)
+			inputPath = "."
+		}
+		if err != nil {
+			return "", "", config.Config{}, err
+		}
 	}
 
-	inputPath := args[0]
+	if *failFast && *keepGoing {
+		return "", "", config.Config{}, fmt.Errorf("-fail-fast and -keep-going cannot be used together")
+	}
+
+	if *checkOnly && (watchMode || *deployTo != "" || *uploadTo != "" || *reportSpec != "") {
+		return "", "", config.Config{}, fmt.Errorf("-check-only cannot be used with -w, -report, -deploy or -upload, it builds nothing")
+	}
+
+	if *emitSpec != "" && (watchMode || *checkOnly || *reportSpec != "" || *deployTo != "" || *uploadTo != "" || *webhookURL != "" ||
+		*zipOutput || *stampSpec != "" || *adviseMode) {
+		return "", "", config.Config{}, fmt.Errorf("-emit cannot be used with -w, -check-only, -report, -deploy, -upload, -webhook, -zip, -stamp or -advise")
+	}
 
 	// Validate input path before proceeding
 	if err := validateInputPath(inputPath); err != nil {
-		return err
+		return "", "", config.Config{}, err
 	}
 
-	// Print parsed arguments for demonstration
-	fmt.Printf("Input path: %s\n", inputPath)
-	fmt.Printf("Output file: %s\n", *outputFile)
-	fmt.Printf("Strip debug: %t\n", *stripDebug)
-	fmt.Printf("Obfuscate level: %d\n", obfuscationLevel)
-	fmt.Printf("Suppress warnings: %t\n", *suppressWarn)
-	fmt.Printf("Merge mode: %t\n", *mergeMode)
+	var reportPath string
+	var err error
+	if reportFormat, reportPath, err = parseReportSpec(*reportSpec); err != nil {
+		return "", "", config.Config{}, err
+	}
+	if emitFormat, emitPath, err = parseEmitSpec(*emitSpec); err != nil {
+		return "", "", config.Config{}, err
+	}
+	if frontend != nil && (reportPath == "-" || emitPath == "-") {
+		return "", "", config.Config{}, fmt.Errorf("-frontend json writes events to stdout, -report and -emit need a path other than -")
+	}
 
-	// Implement actual compilation logic
-	return compileResources(inputPath, obfuscationLevel)
+	// Load the project config file, CLI flags take precedence over its values
+	cfg, err := loadConfig(inputPath)
+	if err != nil {
+		return "", "", config.Config{}, err
+	}
+	applyConfig(cfg)
+	// Nested config files are searched for like resources, never in ignored or excluded folders
+	walk := bundler.WalkOptions{Ignore: append(ignorePatterns, splitList(*ignoreList)...), MaxDepth: *maxDepth,
+		SkipCategories: append(categoryPatterns, splitList(*skipCategories)...), FollowSymlinks: *followSymlinks}
+	if *outputFile != "" {
+		walk.Skip = []string{*outputFile}
+	}
+	if subtrees, err = bundler.FindSubtrees(inputPath, walk, append(cfg.Exclude, splitList(*excludeList)...)); err != nil {
+		return "", "", config.Config{}, err
+	}
+	for _, subtree := range subtrees {
+		slog.Info("Using subtree config file", "path", subtree.Path)
+	}
+	lockFilePath = config.LockFilePath(inputPath, cfg)
+	if *lockFile != "" {
+		lockFilePath = *lockFile
+	}
+	lockWritable = cfg.Path != "" || *lockFile != "" || *writeLock
+	if err := configureFormat(); err != nil {
+		return "", "", config.Config{}, err
+	}
+
+	// Validate obfuscation level
+	if *obfuscateLevel < 0 || *obfuscateLevel > 3 {
+		return "", "", config.Config{}, fmt.Errorf("invalid obfuscation level: %d (must be 0-3)", *obfuscateLevel)
+	}
+	if *maxDepth < 0 {
+		return "", "", config.Config{}, fmt.Errorf("invalid -max-depth: %d (must be 0 or more)", *maxDepth)
+	}
+
+	if *buildInfo != "" {
+		if err := config.ValidateResourceName(*buildInfo); err != nil {
+			return "", "", config.Config{}, fmt.Errorf("-build-info: %v", err)
+		}
+		if *outputFile == "" {
+			return "", "", config.Config{}, fmt.Errorf("-build-info requires an output directory (-o)")
+		}
+	}
+
+	if *zipOutput {
+		if *outputFile == "" {
+			return "", "", config.Config{}, fmt.Errorf("-zip requires an output directory (-o)")
+		}
+		if *scriptsOnly {
+			return "", "", config.Config{}, fmt.Errorf("-zip cannot be used with -scripts-only, the archives would miss the assets")
+		}
+	}
+
+	if err := config.ValidateMergeOrder(*mergeOrder); err != nil {
+		return "", "", config.Config{}, fmt.Errorf("-merge-order: %v", err)
+	}
+	if err := config.ValidateMergeStrategy(*mergeStrategy); err != nil {
+		return "", "", config.Config{}, fmt.Errorf("-merge-strategy: %v", err)
+	}
+	if *mergeIsolate && *mergeStrategy == config.MergeStrategyFiles {
+		return "", "", config.Config{}, fmt.Errorf("-merge-isolate requires -merge-strategy concat, it wraps the concatenated scripts")
+	}
+	if err := config.ValidateBundleName(*bundleName); err != nil {
+		return "", "", config.Config{}, fmt.Errorf("-bundle-name: %v", err)
+	}
+	if err := config.ValidatePlaceholders(*banner); err != nil {
+		return "", "", config.Config{}, fmt.Errorf("-banner: %v", err)
+	}
+	for _, spec := range splitList(*infoList) {
+		if err := config.ValidateInfoAttr(spec); err != nil {
+			return "", "", config.Config{}, fmt.Errorf("-info: %v", err)
+		}
+	}
+	if err := validateCompilerFlags(); err != nil {
+		return "", "", config.Config{}, err
+	}
+	if usesPlaceholder("{build}") && *stampSpec == "" {
+		return "", "", config.Config{}, fmt.Errorf("{build} in the banner or info attributes requires -stamp")
+	}
+	if _, err := parseDefines(*defineList); err != nil {
+		return "", "", config.Config{}, fmt.Errorf("-define: %v", err)
+	}
+
+	if (*sourceMaps || *sourceMapShim) && *stripDebug {
+		slog.Warn("Source maps are of little use with -s, stripped bundles report no line numbers")
+	}
+
+	if *checksums && *outputFile == "" {
+		return "", "", config.Config{}, fmt.Errorf("-checksums requires an output directory (-o)")
+	}
+
+	if *stampSpec != "" {
+		if _, err := parseStamp(*stampSpec); err != nil {
+			return "", "", config.Config{}, err
+		}
+		if *outputFile == "" {
+			return "", "", config.Config{}, fmt.Errorf("-stamp requires an output directory (-o)")
+		}
+	}
+
+	if *deployTo != "" {
+		server, ok := cfg.Server(*deployTo)
+		if !ok {
+			return "", "", config.Config{}, fmt.Errorf("-deploy: server %q is not defined in the config file", *deployTo)
+		}
+		if *outputFile == "" {
+			return "", "", config.Config{}, fmt.Errorf("-deploy requires an output directory (-o)")
+		}
+		deployTarget = server
+	}
+
+	if *uploadTo != "" {
+		target, ok := cfg.Upload(*uploadTo)
+		if !ok {
+			return "", "", config.Config{}, fmt.Errorf("-upload: bucket %q is not defined in the config file", *uploadTo)
+		}
+		if *outputFile == "" {
+			return "", "", config.Config{}, fmt.Errorf("-upload requires an output directory (-o)")
+		}
+		uploadTarget = target
+	}
+
+	if *cleanOutput && *scriptsOnly {
+		return "", "", config.Config{}, fmt.Errorf("-clean cannot be used with -scripts-only, it would remove the assets already in the output")
+	}
+
+	if *cleanOutput {
+		if err := validateCleanOutput(inputPath, *outputFile); err != nil {
+			return "", "", config.Config{}, err
+		}
+	}
+
+	return inputPath, reportPath, cfg, nil
+}
+
+// validateCompilerFlags checks the flags choosing and running luac_mta, shared by resource
+// builds and single .lua inputs
+func validateCompilerFlags() error {
+	if *offlineMode && *refreshComp {
+		return fmt.Errorf("-refresh-compiler cannot be used with -offline")
+	}
+	if _, err := retention.ParseAge(*compilerMaxAge); err != nil {
+		return fmt.Errorf("-compiler-max-age: %v", err)
+	}
+	if err := compiler.ValidateArgs(compilerArgs); err != nil {
+		return fmt.Errorf("-compiler-arg: %v", err)
+	}
+	if err := compiler.ValidateProxy(*compilerProxy); err != nil {
+		return fmt.Errorf("-compiler-proxy: %v", err)
+	}
+	if timeout, err := time.ParseDuration(*dlTimeout); err != nil || timeout < 0 {
+		return fmt.Errorf("-download-timeout: invalid duration %q, such as 30s or 5m", *dlTimeout)
+	}
+	if err := compiler.ValidateStrategy(*compStrategy); err != nil {
+		return fmt.Errorf("-compiler-strategy: %v", err)
+	}
+	if *compStrategy == compiler.StrategyDocker && *sandboxMode {
+		return fmt.Errorf("-sandbox cannot be used with the docker strategy, the container already isolates luac_mta")
+	}
+	if *compStrategy == compiler.StrategyRemote && !*remoteCompile {
+		return fmt.Errorf("-compiler-strategy remote uploads every script to luac.mtasa.com, confirm it with -remote-compile")
+	}
+	if *remoteJobs < 1 {
+		return fmt.Errorf("-remote-jobs: must be at least 1, got %d", *remoteJobs)
+	}
+	if *dlRetries < 0 {
+		return fmt.Errorf("-download-retries: must not be negative, got %d", *dlRetries)
+	}
+	if *compRetries < 0 {
+		return fmt.Errorf("-retries: must not be negative, got %d", *compRetries)
+	}
+	return nil
+}
+
+// parseStamp parses the -stamp value: "auto" gives 0, meaning the build number following the
+// last one, otherwise a positive build number
+func parseStamp(spec string) (int, error) {
+	if spec == "auto" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(spec)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid -stamp value %q (use auto or a positive build number)", spec)
+	}
+	return number, nil
+}
+
+// validateCleanOutput checks that -clean can only remove files from a separate output directory.
+// Pruning the source tree would delete the scripts being compiled.
+func validateCleanOutput(inputPath, outputDir string) error {
+	if outputDir == "" {
+		return fmt.Errorf("-clean requires an output directory (-o)")
+	}
+
+	inputRoot := inputPath
+	if info, err := os.Stat(inputPath); err == nil && !info.IsDir() {
+		inputRoot = filepath.Dir(inputPath)
+	}
+	absInput, err := filepath.Abs(inputRoot)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute input path: %v", err)
+	}
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return fmt.Errorf("cannot get absolute output path: %v", err)
+	}
+	if absOutput == absInput {
+		return fmt.Errorf("-clean cannot be used when the output directory is the input directory")
+	}
+	return nil
+}
+
+// configureLogging sets the console log level from the verbosity flags, disables
+// colors with -no-color, replaces the console with the events of -frontend and adds the
+// diagnostics file of -diagnostics
+func configureLogging() error {
+	if *noColor {
+		console.SetStyle(bundler.NewStyle(false))
+	}
+
+	switch {
+	case *silentMode && !*checkOnly:
+		return fmt.Errorf("-silent requires -check-only")
+	case *silentMode && verboseMode:
+		return fmt.Errorf("-silent and -vv cannot be used together")
+	case *silentMode:
+		// Not even the summary is printed, the exit status is the only output
+		logLevel.Set(bundler.LevelSummary + 1)
+	case quietMode && verboseMode:
+		return fmt.Errorf("-q and -vv cannot be used together")
+	case quietMode:
+		logLevel.Set(slog.LevelError)
+	case verboseMode:
+		logLevel.Set(slog.LevelDebug)
+	}
+
+	var handler slog.Handler = console
+	switch *frontendMode {
+	case "":
+	case "json":
+		if *silentMode {
+			return fmt.Errorf("-silent and -frontend cannot be used together")
+		}
+		frontend = bundler.NewFrontendHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
+		frontend.Hello(version)
+		handler = frontend
+		slog.SetDefault(slog.New(handler))
+	default:
+		return fmt.Errorf("unsupported frontend: %s (supported: json)", *frontendMode)
+	}
+
+	if *diagnostics != "" {
+		file, err := os.Create(*diagnostics)
+		if err != nil {
+			return fmt.Errorf("failed to open diagnostics file: %v", err)
+		}
+		// The file stays open until the process exits, records are written unbuffered
+		slog.SetDefault(slog.New(bundler.TeeHandler{handler, bundler.NewDiagnosticsHandler(file)}))
+	}
+	return nil
+}
+
+// configureFormat sets how sizes and durations are written from the format flags
+func configureFormat() error {
+	var err error
+	if outputFormat.Sizes, err = units.ParseSizeUnits(*sizeUnits); err != nil {
+		return fmt.Errorf("-size-units: %v", err)
+	}
+	if outputFormat.DurationPrecision, err = units.ParseDurationPrecision(*durationPrec); err != nil {
+		return fmt.Errorf("-duration-precision: %v", err)
+	}
+	if outputFormat.Locale, err = units.ParseLocale(*numberLocale); err != nil {
+		return fmt.Errorf("-locale: %v", err)
+	}
+	console.SetFormat(outputFormat)
+	return nil
+}
+
+// parseDefines parses the -define value, comma-separated NAME=value constants. Values are
+// true, false, nil, numbers, or strings, with or without quotes.
+func parseDefines(value string) (config.Constants, error) {
+	constants := make(config.Constants)
+	for _, define := range splitList(value) {
+		name, text, ok := strings.Cut(define, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not NAME=value", define)
+		}
+		name, text = strings.TrimSpace(name), strings.TrimSpace(text)
+		var constant any = text
+		switch {
+		case text == "true" || text == "false":
+			constant = text == "true"
+		case text == "nil":
+			constant = nil
+		case len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0]:
+			constant = text[1 : len(text)-1]
+		default:
+			if number, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+				constant = number
+			}
+		}
+		if err := config.ValidateConstant(name, constant); err != nil {
+			return nil, err
+		}
+		constants[name] = constant
+	}
+	return constants, nil
+}
+
+// infoAttrs returns the <info> attributes of the config file and then of -info, so those of -info
+// are applied last
+func infoAttrs() []resource.InfoAttr {
+	var attrs []resource.InfoAttr
+	for _, spec := range append(slices.Clone(configInfo), splitList(*infoList)...) {
+		// Validated with the config file and the flags
+		if attr, err := resource.ParseInfoAttr(spec); err == nil {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// usesPlaceholder reports whether the banner or an <info> attribute contains placeholder
+func usesPlaceholder(placeholder string) bool {
+	if strings.Contains(*banner, placeholder) {
+		return true
+	}
+	for _, attr := range infoAttrs() {
+		if strings.Contains(attr.Value, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildCommit returns the short hash of the git commit checked out at inputPath
+func buildCommit(inputPath string) (string, error) {
+	dir := inputPath
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("{commit} needs the input to be in a git repository: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// foldingConstants returns the constants of cfg and then defines, those of defines taking
+// precedence, with numbers as float64 for the constant folding pass
+func foldingConstants(cfg config.Constants, defines config.Constants) map[string]any {
+	if len(cfg)+len(defines) == 0 {
+		return nil
+	}
+	constants := make(map[string]any)
+	for _, source := range []config.Constants{cfg, defines} {
+		for name, value := range source {
+			// Validated with the config file and the flags
+			constants[name], _ = transform.Constant(value)
+		}
+	}
+	return constants
+}
+
+// splitList splits a comma-separated flag value, ignoring blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// listFlag is a flag that can be given several times, collecting its values in order
+type listFlag []string
+
+// String returns the values given so far
+func (l *listFlag) String() string {
+	return strings.Join(*l, " ")
+}
+
+// Set adds a value given with the flag
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseReportSpec parses the -report value ("json" or "html", optionally followed by "=path")
+// and returns the report format and path. An empty spec returns an empty path.
+func parseReportSpec(spec string) (string, string, error) {
+	if spec == "" {
+		return "", "", nil
+	}
+
+	format, path, hasPath := strings.Cut(spec, "=")
+	defaultPath, ok := map[string]string{"json": report.DefaultJSONPath, "html": report.DefaultHTMLPath}[format]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported report format: %s (supported: json, html)", format)
+	}
+	if !hasPath {
+		return format, defaultPath, nil
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("empty report path in -report %s", spec)
+	}
+	return format, path, nil
+}
+
+// loadConfig loads the config file given with -config, or the project config file found at the
+// input root. A missing project config file is not an error.
+func loadConfig(inputPath string) (config.Config, error) {
+	path := *configPath
+	if path == "" {
+		found, ok := config.Find(inputPath)
+		if !ok {
+			return config.Config{}, nil
+		}
+		path = found
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	slog.Info("Using config file", "path", cfg.Path)
+	return cfg, nil
+}
+
+// applyConfig copies config values into the flag variables that were not set explicitly
+func applyConfig(cfg config.Config) {
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	if cfg.Output != "" && !setFlags["o"] {
+		*outputFile = cfg.Output
+	}
+	if cfg.Obfuscation != nil && !setFlags["e"] {
+		*obfuscateLevel = *cfg.Obfuscation
+	}
+	if cfg.StripDebug != nil && !setFlags["s"] {
+		*stripDebug = *cfg.StripDebug
+	}
+	if cfg.SuppressWarnings != nil && !setFlags["d"] {
+		*suppressWarn = *cfg.SuppressWarnings
+	}
+	if cfg.MaxDepth != 0 && !setFlags["max-depth"] {
+		*maxDepth = cfg.MaxDepth
+	}
+	if cfg.Merge != nil && !setFlags["m"] {
+		*mergeMode = *cfg.Merge
+	}
+	if cfg.MergeOrder != "" && !setFlags["merge-order"] {
+		*mergeOrder = cfg.MergeOrder
+	}
+	if cfg.MergeStrategy != "" && !setFlags["merge-strategy"] {
+		*mergeStrategy = cfg.MergeStrategy
+	}
+	if cfg.MergeIsolate != nil && !setFlags["merge-isolate"] {
+		*mergeIsolate = *cfg.MergeIsolate
+	}
+	if cfg.BundleName != "" && !setFlags["bundle-name"] {
+		*bundleName = cfg.BundleName
+	}
+	if cfg.Banner != "" && !setFlags["banner"] {
+		*banner = cfg.Banner
+	}
+	if cfg.BuildInfo != "" && !setFlags["build-info"] {
+		*buildInfo = cfg.BuildInfo
+	}
+	if cfg.FollowSymlinks != nil && !setFlags["follow-symlinks"] {
+		*followSymlinks = *cfg.FollowSymlinks
+	}
+	if cfg.SyntaxCheck != nil && !setFlags["syntax-check"] {
+		*syntaxCheck = *cfg.SyntaxCheck
+	}
+	if cfg.Preflight != nil && !setFlags["preflight"] {
+		*preflight = *cfg.Preflight
+	}
+	if cfg.Minify != nil && !setFlags["minify"] {
+		*minify = *cfg.Minify
+	}
+	if cfg.RenameLocals != nil && !setFlags["rename-locals"] {
+		*renameLocals = *cfg.RenameLocals
+	}
+	if cfg.FoldConstants != nil && !setFlags["fold-constants"] {
+		*foldConstants = *cfg.FoldConstants
+	}
+	if cfg.TreeShake != nil && !setFlags["tree-shake"] {
+		*treeShake = *cfg.TreeShake
+	}
+	if cfg.Checksums != nil && !setFlags["checksums"] {
+		*checksums = *cfg.Checksums
+	}
+	if cfg.Scan != nil && !setFlags["scan"] {
+		*scanBackdoors = *cfg.Scan
+	}
+	if cfg.ClientCache != nil && !setFlags["client-cache"] {
+		*clientCache = *cfg.ClientCache
+	}
+	if cfg.RaiseMinVersion != nil && !setFlags["raise-min-version"] {
+		*raiseMinVer = *cfg.RaiseMinVersion
+	}
+	if cfg.CompilerMaxAge != "" && !setFlags["compiler-max-age"] {
+		*compilerMaxAge = cfg.CompilerMaxAge
+	}
+	if cfg.CompilerProxy != "" && !setFlags["compiler-proxy"] {
+		*compilerProxy = cfg.CompilerProxy
+	}
+	if cfg.DownloadTimeout != "" && !setFlags["download-timeout"] {
+		*dlTimeout = cfg.DownloadTimeout
+	}
+	if cfg.DownloadRetries != nil && !setFlags["download-retries"] {
+		*dlRetries = *cfg.DownloadRetries
+	}
+	if cfg.Retries != nil && !setFlags["retries"] {
+		*compRetries = *cfg.Retries
+	}
+	if cfg.CompilerStrategy != "" && !setFlags["compiler-strategy"] {
+		*compStrategy = cfg.CompilerStrategy
+	}
+	if cfg.LevelFallback != nil && !setFlags["level-fallback"] {
+		*levelFallback = *cfg.LevelFallback
+	}
+	if cfg.Format.SizeUnits != "" && !setFlags["size-units"] {
+		*sizeUnits = cfg.Format.SizeUnits
+	}
+	if cfg.Format.DurationPrecision != "" && !setFlags["duration-precision"] {
+		*durationPrec = cfg.Format.DurationPrecision
+	}
+	if cfg.Format.Locale != "" && !setFlags["locale"] {
+		*numberLocale = cfg.Format.Locale
+	}
+	verbatimPatterns = cfg.Verbatim
+	configConstants = cfg.Constants
+	configInfo = cfg.Info
+	configCompilerArgs = cfg.CompilerArgs
+	configMirror = cfg.CompilerMirror
+	configImage = cfg.CompilerImage
+	ignorePatterns = cfg.Ignore
+	categoryPatterns = cfg.SkipCategories
+	mergeExcludePatterns = cfg.MergeExclude
+	packs = configPacks(cfg)
+	splits = configSplits(cfg)
+	dependencies = cfg.Dependencies
+	webhooks = configWebhooks(cfg)
+	if *webhookURL != "" {
+		webhooks = append(webhooks, notify.Webhook{URL: *webhookURL})
+	}
+	compilerLock = cfg.Lock.Compiler
+	// Validated when the config file was loaded
+	lintRules, _ = cfg.LintSettings()
+}
+
+// configPacks converts the packs of the config file to bundler packs
+func configPacks(cfg config.Config) []bundler.Pack {
+	var packs []bundler.Pack
+	for _, pack := range cfg.Packs {
+		packs = append(packs, bundler.Pack{Name: pack.Name, Resources: pack.Resources})
+	}
+	return packs
+}
+
+// dependencyCacheDir returns the directory the archives of dependencies are extracted to. It
+// is only needed when there are dependencies, an empty path is returned otherwise.
+func dependencyCacheDir(deps []config.Dependency) (string, error) {
+	if len(deps) == 0 {
+		return "", nil
+	}
+	return dependencyCacheRoot()
+}
+
+// dependencyCacheRoot returns the directory of the dependency cache shared by all projects
+func dependencyCacheRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %v", err)
+	}
+	return filepath.Join(cacheDir, "mta-bundler", "dependencies"), nil
+}
+
+// configSplits converts the splits of the config file to bundler splits
+func configSplits(cfg config.Config) []bundler.Split {
+	var splits []bundler.Split
+	for _, split := range cfg.Splits {
+		parts := make([]resource.SplitPart, 0, len(split.Parts))
+		for _, part := range split.Parts {
+			parts = append(parts, resource.SplitPart{Name: part.Name, Folders: part.Folders})
+		}
+		splits = append(splits, bundler.Split{Resource: split.Resource, Parts: parts})
+	}
+	return splits
 }
 
 // validateInputPath validates that the input path is either a meta.xml file or a directory
@@ -119,74 +1019,449 @@ func validateInputPath(inputPath string) error {
 	}
 }
 
-// compileResources handles the compilation of MTA resources using the compiler.go implementation
-func compileResources(inputPath string, obfuscationLevel int) error {
-	fmt.Printf("Starting compilation for: %s\n", inputPath)
+// newCompiler creates the CLI compiler, using the binary given with -compiler or LUAC_MTA, else
+// the binary pinned by the config file's lock section when there is one, and detecting the
+// luac_mta binary otherwise
+func newCompiler() (compiler.CLICompiler, error) {
+	if *sandboxMode && compilerSandbox == nil {
+		sandbox, err := compiler.NewSandbox()
+		if err != nil {
+			return compiler.CLICompiler{}, fmt.Errorf("-sandbox: %v", err)
+		}
+		compilerSandbox = &sandbox
+		slog.Info("Running luac_mta in a sandbox")
+	}
+	if *compStrategy == compiler.StrategyDocker && compilerContainer == nil {
+		container, err := compiler.NewContainer(configImage)
+		if err != nil {
+			return compiler.CLICompiler{}, fmt.Errorf("-compiler-strategy: %v", err)
+		}
+		compilerContainer = &container
+		slog.Info("Running luac_mta in a container")
+	}
 
-	// Detect luac_mta binary path
-	detector := compiler.NewBinaryDetector()
-	binaryPath, err := detector.DetectAndValidate()
+	binaryPath, explicit, err := explicitCompiler()
+	if err != nil {
+		return compiler.CLICompiler{}, err
+	}
+	switch {
+	case explicit:
+		if compilerLock != nil {
+			slog.Info("Ignoring the vendored compiler of the config file", "path", compilerLock.Path)
+		}
+	case compilerLock != nil:
+		binaryPath, err = lockedCompiler(*compilerLock)
+	case *compStrategy == compiler.StrategyRemote:
+		return newRemoteCompiler()
+	default:
+		binaryPath, err = detectCompiler()
+		if err != nil && *remoteCompile {
+			slog.Warn("No usable luac_mta binary, compiling with the compile API instead", "error", err)
+			return newRemoteCompiler()
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to detect luac_mta binary: %v", err)
+		return compiler.CLICompiler{}, err
+	}
+	if *compStrategy == compiler.StrategyRosetta {
+		rosetta, err := compiler.CheckRosetta(binaryPath)
+		if err != nil {
+			return compiler.CLICompiler{}, fmt.Errorf("-compiler-strategy: %v", err)
+		}
+		if rosetta {
+			slog.Info("Running luac_mta under Rosetta", "path", binaryPath)
+		}
 	}
 
 	// Initialize the CLI compiler with detected binary path
 	cliCompiler, err := compiler.NewCLICompiler(binaryPath)
 	if err != nil {
-		return fmt.Errorf("failed to initialize compiler: %v", err)
+		return compiler.CLICompiler{}, fmt.Errorf("failed to initialize compiler: %v", err)
+	}
+	if compilerSandbox != nil {
+		cliCompiler = cliCompiler.WithSandbox(*compilerSandbox)
+	}
+	if compilerContainer != nil {
+		cliCompiler = cliCompiler.WithContainer(*compilerContainer)
+	}
+	// Those of the config file first, so -compiler-arg can follow up on them
+	if args := append(slices.Clone(configCompilerArgs), compilerArgs...); len(args) > 0 {
+		cliCompiler = cliCompiler.WithArgs(args)
+	}
+	if *compRetries > 0 {
+		cliCompiler = cliCompiler.WithRetries(*compRetries)
+	}
+
+	return cliCompiler, nil
+}
+
+// newRemoteCompiler creates the compiler uploading scripts to the compile API of the MTA
+// servers, or of the LUAC_MTA_REMOTE environment variable, allowed with -remote-compile
+func newRemoteCompiler() (compiler.CLICompiler, error) {
+	if len(configCompilerArgs)+len(compilerArgs) > 0 {
+		return compiler.CLICompiler{}, fmt.Errorf("-compiler-arg: luac_mta arguments cannot be passed to the compile API")
+	}
+	if *preflight {
+		return compiler.CLICompiler{}, fmt.Errorf("-preflight: the compile API cannot parse scripts without compiling them")
+	}
+	apiURL := os.Getenv(compiler.RemoteEnv)
+	if err := compiler.ValidateRemoteURL(apiURL); err != nil {
+		return compiler.CLICompiler{}, fmt.Errorf("%s: %v", compiler.RemoteEnv, err)
+	}
+	// Validated with the flags
+	timeout, _ := time.ParseDuration(*dlTimeout)
+	client, err := compiler.NewDownloadClient(*compilerProxy, timeout)
+	if err != nil {
+		return compiler.CLICompiler{}, fmt.Errorf("-compiler-proxy: %v", err)
 	}
 
-	// Get file info (validation already done in validateInputPath)
-	fileInfo, _ := os.Stat(inputPath)
-	var metaPaths []string
+	remote := compiler.NewRemoteCompiler(apiURL, client, *remoteJobs, *dlRetries)
+	slog.Warn("Compiling remotely, every script is uploaded", "url", remote.URL(), "jobs", *remoteJobs)
+	return compiler.CLICompiler{}.WithRemote(remote), nil
+}
 
-	if fileInfo.IsDir() {
-		// If it's a directory, find all meta.xml files
-		fmt.Println("Searching for meta.xml files in directory...")
-		metaPaths, err = FindMTAResourceMetas(inputPath)
+// detectCompiler finds or downloads the luac_mta binary
+func detectCompiler() (string, error) {
+	// Downloads show a progress bar on terminals unless the output is quiet
+	var progress compiler.DownloadProgress
+	switch {
+	case frontend != nil:
+		progress = frontend
+	case bundler.IsTerminal(os.Stdout) && logLevel.Level() <= slog.LevelInfo:
+		progress = console
+	}
+	detector := newBinaryDetector(progress)
+	switch *compStrategy {
+	case compiler.StrategyDocker:
+		detector = detector.WithPlatform("linux", "amd64")
+	case compiler.StrategyRosetta:
+		// The MTA servers publish no macOS binary
+		detector = detector.WithoutDownloads()
+	}
+	// Validated with the flags
+	maxAge, _ := retention.ParseAge(*compilerMaxAge)
+	detector = detector.WithRefresh(*refreshComp, maxAge)
+	if *offlineMode {
+		detector = detector.WithOffline()
+	}
+	// The mirror of the machine wins over the mirror of the project
+	mirror, source := os.Getenv(compiler.MirrorEnv), compiler.MirrorEnv
+	if mirror == "" {
+		mirror, source = configMirror, "compiler_mirror"
+	}
+	if mirror != "" {
+		if err := compiler.ValidateMirror(mirror); err != nil {
+			return "", fmt.Errorf("%s: %v", source, err)
+		}
+		detector = detector.WithMirror(mirror)
+	}
+	if list := os.Getenv(compiler.ChecksumEnv); list != "" {
+		trusted, err := compiler.ParseChecksumList(list)
 		if err != nil {
-			return fmt.Errorf("error finding meta.xml files: %v", err)
+			return "", fmt.Errorf("%s: %v", compiler.ChecksumEnv, err)
 		}
+		detector = detector.WithTrusted(trusted)
+	}
+	// The lock file only chooses among the trusted binaries, or pins the download when no hash is
+	// published for the platform
+	if checksum := lockedChecksum(); checksum != "" {
+		detector = detector.WithPin(checksum)
+	}
+	// Validated with the flags
+	timeout, _ := time.ParseDuration(*dlTimeout)
+	client, err := compiler.NewDownloadClient(*compilerProxy, timeout)
+	if err != nil {
+		return "", fmt.Errorf("-compiler-proxy: %v", err)
+	}
+	detector = detector.WithClient(client).WithRetries(*dlRetries)
 
-		if len(metaPaths) == 0 {
-			return fmt.Errorf("no meta.xml files found in directory: %s", inputPath)
+	// Ctrl+C interrupts a download, which the next run resumes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	binaryPath, err := detector.DetectAndValidate(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("luac_mta download interrupted, run again to resume it")
 		}
-	} else {
-		// Single meta.xml file (already validated)
-		absPath, err := filepath.Abs(inputPath)
-		if err != nil {
-			return fmt.Errorf("cannot get absolute path: %v", err)
+		if errors.Is(err, compiler.ErrOffline) {
+			return "", fmt.Errorf("-offline: no luac_mta binary found in PATH or common locations, and none cached: use -compiler, or build once with network access to cache it")
+		}
+		var mismatch compiler.ChecksumError
+		if errors.As(err, &mismatch) {
+			if len(mismatch.Expected) == 1 && strings.EqualFold(mismatch.Expected[0], lockedChecksum()) {
+				return "", fmt.Errorf("downloaded luac_mta (SHA-256 %.12s) is not the binary %s pins (%.12s): the MTA servers published a new binary, remove the %s/%s entry of the lock file to accept it",
+					mismatch.Actual, lockFilePath, mismatch.Expected[0], runtime.GOOS, runtime.GOARCH)
+			}
+			return "", fmt.Errorf("downloaded luac_mta does not match any trusted SHA-256 (%v): the download was corrupted or tampered with, or the MTA servers published a new binary, update mta-bundler or set %s to trust it",
+				mismatch, compiler.ChecksumEnv)
 		}
-		metaPaths = []string{absPath}
+		switch {
+		case *compStrategy == compiler.StrategyRosetta:
+			return "", fmt.Errorf("-compiler-strategy: no luac_mta binary found in PATH or common locations, the rosetta strategy needs a macOS build of luac_mta: use -compiler")
+		case runtime.GOOS == "darwin" && *compStrategy != compiler.StrategyDocker:
+			return "", fmt.Errorf("failed to detect luac_mta binary: %v (the MTA servers publish no macOS binary: use -compiler-strategy docker, or rosetta with a macOS build of luac_mta)", err)
+		}
+		return "", fmt.Errorf("failed to detect luac_mta binary: %v", err)
 	}
+	return binaryPath, nil
+}
 
-	fmt.Printf("Found %d meta.xml file(s) to process\n", len(metaPaths))
+// newBinaryDetector creates the luac_mta detector, validating binaries in the compiler sandbox
+// when there is one
+func newBinaryDetector(progress compiler.DownloadProgress) compiler.BinaryDetector {
+	detector := compiler.NewBinaryDetector(progress)
+	if compilerSandbox != nil {
+		detector = detector.WithSandbox(*compilerSandbox)
+	}
+	if compilerContainer != nil {
+		detector = detector.WithContainer(*compilerContainer)
+	}
+	return detector
+}
 
-	// Process each meta.xml file
-	for i, metaPath := range metaPaths {
-		fmt.Printf("\n[%d/%d] Processing: %s\n", i+1, len(metaPaths), metaPath)
+// newBundler creates a bundler for inputPath from the build flags. When adv is set, every
+// built resource is submitted to it.
+func newBundler(cliCompiler compiler.CLICompiler, inputPath string, exclude []string, adv *advisor.Advisor) (bundler.Bundler, error) {
+	var escrowKey []byte
+	if *escrowKeyFile != "" {
+		key, err := escrow.LoadKey(*escrowKeyFile)
+		if err != nil {
+			return bundler.Bundler{}, err
+		}
+		escrowKey = key
+	}
 
-		res, err := resource.NewResource(metaPath)
+	var stamp bundler.BuildStamp
+	if *stampSpec != "" {
+		number, err := parseStamp(*stampSpec)
 		if err != nil {
-			fmt.Printf("Error processing %s: %v\n", metaPath, err)
-			continue
+			return bundler.Bundler{}, err
+		}
+		if stamp, err = bundler.ReserveBuildNumber(*outputFile, number); err != nil {
+			return bundler.Bundler{}, err
 		}
+		slog.Info("Stamping build number", "build", stamp.Number)
+	}
+
+	// A live progress bar replaces the per-file lines on terminals at the default verbosity
+	// Frontends always get progress events
+	var progress bundler.ProgressReporter
+	switch {
+	case frontend != nil:
+		progress = frontend
+	case bundler.IsTerminal(os.Stdout) && logLevel.Level() == slog.LevelInfo:
+		progress = console
+	}
 
-		// Create compilation options
-		options := compiler.CompilationOptions{
-			ObfuscationLevel:         compiler.ObfuscationLevel(obfuscationLevel),
+	var onResource func(bundler.ResourceResult)
+	if adv != nil {
+		onResource = adv.Submit
+	}
+
+	dependencyCache, err := dependencyCacheDir(dependencies)
+	if err != nil {
+		return bundler.Bundler{}, err
+	}
+
+	// Validated with the other flags
+	defines, _ := parseDefines(*defineList)
+
+	var commit string
+	if usesPlaceholder("{commit}") {
+		if commit, err = buildCommit(inputPath); err != nil {
+			return bundler.Bundler{}, err
+		}
+	}
+
+	return bundler.NewBundler(cliCompiler, bundler.Options{
+		InputPath: inputPath,
+		Inputs:    inputPaths,
+		OutputDir: *outputFile,
+		Compilation: compiler.CompilationOptions{
+			ObfuscationLevel:         compiler.ObfuscationLevel(*obfuscateLevel),
 			StripDebug:               *stripDebug,
 			SuppressDecompileWarning: *suppressWarn,
+		},
+		MergeMode:       *mergeMode,
+		MetaOrder:       *mergeOrder == config.MergeOrderMeta,
+		Concat:          *mergeStrategy == config.MergeStrategyConcat || *mergeIsolate,
+		Isolate:         *mergeIsolate,
+		Minify:          *minify,
+		RenameLocals:    *renameLocals,
+		TreeShake:       *treeShake,
+		FoldConstants:   *foldConstants,
+		Constants:       foldingConstants(configConstants, defines),
+		BundleName:      *bundleName,
+		Banner:          *banner,
+		Info:            infoAttrs(),
+		Commit:          commit,
+		Exclude:         append(exclude, splitList(*excludeList)...),
+		Ignore:          append(ignorePatterns, splitList(*ignoreList)...),
+		MaxDepth:        *maxDepth,
+		SkipCategories:  append(categoryPatterns, splitList(*skipCategories)...),
+		FollowSymlinks:  *followSymlinks,
+		Subtrees:        subtrees,
+		Only:            splitList(*onlyResources),
+		Verbatim:        append(verbatimPatterns, splitList(*verbatimList)...),
+		Unmerged:        append(mergeExcludePatterns, splitList(*mergeExclude)...),
+		EscrowKey:       escrowKey,
+		ScriptsOnly:     *scriptsOnly,
+		LinkAssets:      *linkAssets,
+		NoCache:         !*clientCache,
+		NoMinVersion:    !*raiseMinVer,
+		LevelFallback:   *levelFallback,
+		NoSyntaxCheck:   !*syntaxCheck,
+		Preflight:       *preflight,
+		RegexMeta:       *metaRegex,
+		Packs:           packs,
+		Splits:          splits,
+		Dependencies:    dependencies,
+		DependencyCache: dependencyCache,
+		Zip:             *zipOutput,
+		Stamp:           stamp,
+		Force:           *forceBuild,
+		Clean:           *cleanOutput,
+		FailFast:        *failFast,
+		BuildInfo:       *buildInfo,
+		Checksums:       *checksums,
+		Scan:            *scanBackdoors,
+		Lint:            lintRules,
+		SourceMaps:      *sourceMaps || *sourceMapShim,
+		MapShim:         *sourceMapShim,
+		Progress:        progress,
+		OnResource:      onResource,
+		Cache:           buildCache,
+	}), nil
+}
+
+// compileResources handles the compilation of MTA resources using the bundler implementation
+func compileResources(inputPath string, exclude []string, reportPath string) error {
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	if err := checkLockFile(cliCompiler); err != nil {
+		return err
+	}
+
+	var adv *advisor.Advisor
+	if *adviseMode {
+		adv = advisor.New(cliCompiler, advisor.DefaultSampleSize)
+	}
+
+	b, err := newBundler(cliCompiler, inputPath, exclude, adv)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := b.WithContext(ctx).Run()
+	recordBuild(inputPath, result, err)
+	notifyBuild(webhooks, inputPath, result, err)
+	if err != nil {
+		return err
+	}
+
+	var recommendations []advisor.Recommendation
+	if adv != nil {
+		recommendations = adv.Wait()
+		logRecommendations(recommendations)
+	}
+
+	if err := writeReport(result, recommendations, reportPath); err != nil {
+		return err
+	}
+
+	// Rebuilds in watch mode are not uploaded or deployed, only the initial build
+	if *uploadTo != "" {
+		if err := buildError(result); err != nil {
+			slog.Warn("Skipping upload, the build failed", "upload", uploadTarget.Name)
+		} else if err := uploadBuild(uploadTarget, *outputFile); err != nil {
+			return err
+		}
+	}
+	if *deployTo != "" {
+		if err := buildError(result); err != nil {
+			slog.Warn("Skipping deployment, the build failed", "server", deployTarget.Name)
+		} else if err := deployToServer(deployTarget, *outputFile); err != nil {
+			return err
 		}
+	}
 
-		err = res.Compile(cliCompiler, inputPath, *outputFile, options, *mergeMode)
-		if err != nil {
-			fmt.Printf("Error compiling resource %s: %v\n", res.Name, err)
-			continue
+	if watchMode {
+		// Interrupting watch mode ends the process
+		stop()
+		return b.Watch()
+	}
+
+	return buildError(result)
+}
+
+// interruptContext returns a context done once the process receives SIGINT or SIGTERM, so a
+// build interrupted with Ctrl+C stops cleanly. A second signal ends the process right away.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			slog.Warn("Interrupted, stopping the build (interrupt again to exit right away)")
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// buildError returns an error when resources failed, so that the exit status reports the failure
+func buildError(result bundler.BuildResult) error {
+	failed := result.FailedCount()
+	switch {
+	case result.Skipped > 0:
+		return fmt.Errorf("build stopped at the first failure, %d resource(s) not built", result.Skipped)
+	case failed > 0:
+		return fmt.Errorf("%d of %d resource(s) failed to build", failed, len(result.Resources))
+	}
+	return nil
+}
 
-		fmt.Printf("Successfully compiled resource: %s\n", res.Name)
+// writeReport writes the JSON build report when a report path is set
+func writeReport(result bundler.BuildResult, recommendations []advisor.Recommendation, reportPath string) error {
+	if reportPath == "" {
+		return nil
 	}
 
+	buildReport := report.New(result, version)
+	buildReport.AddDisplay(outputFormat)
+	buildReport.Recommendations = report.NewRecommendations(recommendations)
+	write := report.WriteJSON
+	if reportFormat == "html" {
+		write = report.WriteHTML
+	}
+	if err := write(buildReport, reportPath); err != nil {
+		return err
+	}
+	if reportPath != "-" {
+		slog.Info("Build report written", "path", reportPath)
+	}
 	return nil
 }
+
+// logRecommendations prints the suggestions of the size advisor
+func logRecommendations(recommendations []advisor.Recommendation) {
+	if len(recommendations) == 0 {
+		slog.Info("No size optimizations found")
+		return
+	}
+
+	slog.Info("Size recommendations", "count", len(recommendations))
+	for _, rec := range recommendations {
+		slog.Info(rec.Message(), "resource", rec.Resource, "tradeoff", rec.Tradeoff, "sample_files", rec.SampleFiles)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/notify"
+)
+
+// configWebhooks converts the webhooks of the config file. Webhooks whose URL variable is not
+// set are skipped with a warning.
+func configWebhooks(cfg config.Config) []notify.Webhook {
+	var hooks []notify.Webhook
+	for _, webhook := range cfg.Webhooks {
+		url, err := webhook.ResolveURL()
+		if err != nil {
+			slog.Warn("Skipping webhook", "error", err)
+			continue
+		}
+		hooks = append(hooks, notify.Webhook{URL: url, Format: webhook.Format, OnlyFailures: webhook.On == "failure"})
+	}
+	return hooks
+}
+
+// notifyBuild sends the summary of a build to the webhooks. Failures are logged but never fail
+// the build.
+func notifyBuild(hooks []notify.Webhook, inputPath string, result bundler.BuildResult, buildErr error) {
+	if len(hooks) == 0 {
+		return
+	}
+	title := inputPath
+	if absInput, err := filepath.Abs(inputPath); err == nil {
+		title = filepath.Base(absInput)
+	}
+
+	msg := notify.NewMessage(title, result, buildErr)
+	for _, hook := range hooks {
+		if err := hook.Send(msg, outputFormat); err != nil {
+			slog.Warn("Failed to send build notification", "error", err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/dependency"
+	"github.com/davidbozo/mta-bundler/internal/deploy"
+	"github.com/davidbozo/mta-bundler/internal/retention"
+)
+
+// runPrune implements the prune command, which applies the retention policy to the dependency
+// cache and the deployment bundles
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	keepLast := fs.Int("keep", 0, "most recent artifacts kept of each kind, such as the pins of one dependency (overrides retention.keep_last)")
+	maxAge := fs.String("max-age", "", "remove artifacts older than this, such as 30d or 12h (overrides retention.max_age)")
+	bundles := fs.String("bundles", "", "directory of deployment bundles to prune (overrides retention.bundles)")
+	cfgPath := fs.String("config", "", "config file with the retention settings (default is "+config.FileName+" in the input)")
+	dryRun := fs.Bool("dry-run", false, "list what would be removed without removing anything")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s prune [options] [input_path]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Removes cached dependencies and deployment bundles beyond the retention policy\n")
+		fmt.Fprintf(os.Stderr, "of the config file at the input (default: the current directory) or the options.\n")
+		fmt.Fprintf(os.Stderr, "The cached dependencies of the current pins are always kept.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected at most one input path")
+	}
+	inputPath := "."
+	if fs.NArg() == 1 {
+		inputPath = fs.Arg(0)
+	}
+
+	var cfg config.Config
+	if *cfgPath == "" {
+		*cfgPath, _ = config.Find(inputPath)
+	}
+	if *cfgPath != "" {
+		loaded, err := config.Load(*cfgPath)
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+	}
+
+	settings := cfg.Retention
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "keep":
+			settings.KeepLast = *keepLast
+		case "max-age":
+			settings.MaxAge = *maxAge
+		case "bundles":
+			settings.Bundles = *bundles
+		}
+	})
+	if settings.KeepLast < 0 {
+		return fmt.Errorf("-keep must not be negative")
+	}
+	return pruneArtifacts(settings, cfg.Dependencies, *dryRun)
+}
+
+// pruneArtifacts removes the cached dependencies and deployment bundles beyond the retention
+// settings. The cache entries of deps are kept. With dryRun, nothing is removed.
+func pruneArtifacts(settings config.Retention, deps []config.Dependency, dryRun bool) error {
+	policy, err := settings.Policy()
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled() {
+		return fmt.Errorf("no retention policy, set retention in %s or use -keep or -max-age", config.FileName)
+	}
+
+	cacheDir, err := dependencyCacheRoot()
+	if err != nil {
+		return err
+	}
+	artifacts, err := dependency.CacheEntries(cacheDir, deps)
+	if err != nil {
+		return err
+	}
+	if settings.Bundles != "" {
+		bundleFiles, err := retention.Files(settings.Bundles, "*"+deploy.Extension, "bundles")
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, bundleFiles...)
+	}
+
+	expired := policy.Expired(artifacts, time.Now())
+	if len(expired) == 0 {
+		slog.Info("Nothing to prune", "artifacts", len(artifacts))
+		return nil
+	}
+
+	var freed int64
+	for _, artifact := range expired {
+		modified := artifact.ModTime.Local().Format("2006-01-02 15:04")
+		if dryRun {
+			slog.Info("Would remove", "path", artifact.Path, "modified", modified, "artifact_size", artifact.Size)
+			freed += artifact.Size
+			continue
+		}
+		removed, err := retention.Remove([]retention.Artifact{artifact})
+		if err != nil {
+			return err
+		}
+		freed += removed
+		slog.Info("Removed", "path", artifact.Path, "modified", modified, "artifact_size", artifact.Size)
+	}
+
+	if dryRun {
+		slog.Info("Dry run, nothing was removed", "count", len(expired), "freed_size", freed)
+		return nil
+	}
+	slog.Info("Pruned artifacts", "count", len(expired), "kept", len(artifacts)-len(expired), "freed_size", freed, "success", true)
+	return nil
+}
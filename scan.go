@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/bytecode"
+)
+
+// scanResult holds the risk assessment of a single compiled file
+type scanResult struct {
+	Path   string
+	Risk   bytecode.Risk
+	Reason string
+}
+
+// runScanCompiled implements the scan-compiled command, which reports compiled .luac files
+// that were built without obfuscation or without stripping debug information
+func runScanCompiled(args []string) error {
+	fs := flag.NewFlagSet("scan-compiled", flag.ExitOnError)
+	showAll := fs.Bool("a", false, "list every file, including low risk ones")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s scan-compiled [options] <dir>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Reports compiled .luac files that are easily decompilable.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one directory")
+	}
+	rootDir := fs.Arg(0)
+
+	if info, err := os.Stat(rootDir); err != nil {
+		return fmt.Errorf("cannot access input path '%s': %v", rootDir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("input must be a directory, got: %s", filepath.Base(rootDir))
+	}
+
+	var results []scanResult
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			slog.Warn("Cannot access path", "path", path, "error", err)
+			return nil
+		}
+		if info.IsDir() || strings.ToLower(filepath.Ext(path)) != ".luac" {
+			return nil
+		}
+
+		inspected, err := bytecode.InspectFile(path)
+		if err != nil {
+			slog.Warn("Cannot inspect file", "path", path, "error", err)
+			return nil
+		}
+
+		risk, reason := inspected.DecompileRisk()
+		results = append(results, scanResult{Path: path, Risk: risk, Reason: reason})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking directory tree: %v", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("No .luac files found in %s\n", rootDir)
+		return nil
+	}
+
+	// Highest risk first, then by path
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Risk != results[j].Risk {
+			return results[i].Risk > results[j].Risk
+		}
+		return results[i].Path < results[j].Path
+	})
+
+	counts := make(map[bytecode.Risk]int)
+	for _, result := range results {
+		counts[result.Risk]++
+		if result.Risk == bytecode.RiskLow && !*showAll {
+			continue
+		}
+
+		relPath, err := filepath.Rel(rootDir, result.Path)
+		if err != nil {
+			relPath = result.Path
+		}
+		fmt.Printf("[%-8s] %s: %s\n", result.Risk, relPath, result.Reason)
+	}
+
+	fmt.Printf("\nScanned %d compiled file(s): %d critical, %d high, %d medium, %d low risk\n",
+		len(results), counts[bytecode.RiskCritical], counts[bytecode.RiskHigh], counts[bytecode.RiskMedium], counts[bytecode.RiskLow])
+
+	if counts[bytecode.RiskCritical]+counts[bytecode.RiskHigh]+counts[bytecode.RiskMedium] > 0 {
+		fmt.Println("Rebuild affected resources with obfuscation and stripped debug information, e.g.:")
+		fmt.Printf("  %s -e 3 -s -o compiled/ /path/to/resources/\n", filepath.Base(os.Args[0]))
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+)
+
+// scriptFlags are the flags that apply to compiling a single .lua file. The others need
+// resources and are rejected.
+var scriptFlags = map[string]bool{
+	"o": true, "e": true, "s": true, "d": true, "config": true, "sandbox": true,
+	"q": true, "quiet": true, "vv": true, "verbose": true, "no-color": true, "diagnostics": true,
+	"size-units": true, "duration-precision": true, "locale": true,
+	"compiler": true, "compiler-arg": true, "offline": true, "refresh-compiler": true, "compiler-max-age": true,
+	"compiler-proxy": true, "download-timeout": true, "download-retries": true, "retries": true,
+	"compiler-strategy": true, "remote-compile": true, "remote-jobs": true,
+}
+
+// isLuaScript reports whether the input path is a standalone .lua file
+func isLuaScript(inputPath string) bool {
+	info, err := os.Stat(inputPath)
+	return err == nil && !info.IsDir() && strings.ToLower(filepath.Ext(inputPath)) == ".lua"
+}
+
+// compileScript compiles a single .lua file without a meta.xml, with the obfuscation and strip
+// options of the build. The output is written to the -o directory, or next to the source.
+func compileScript(inputPath string) error {
+	if err := configureLogging(); err != nil {
+		return err
+	}
+	var unsupported []string
+	flag.Visit(func(f *flag.Flag) {
+		if !scriptFlags[f.Name] {
+			unsupported = append(unsupported, "-"+f.Name)
+		}
+	})
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("%s cannot be used with a single .lua input, only with resources", strings.Join(unsupported, ", "))
+	}
+
+	cfg, err := loadConfig(inputPath)
+	if err != nil {
+		return err
+	}
+	applyConfig(cfg)
+	if err := configureFormat(); err != nil {
+		return err
+	}
+	if *obfuscateLevel < 0 || *obfuscateLevel > 3 {
+		return fmt.Errorf("invalid obfuscation level: %d (must be 0-3)", *obfuscateLevel)
+	}
+	if err := validateCompilerFlags(); err != nil {
+		return err
+	}
+
+	outputDir := *outputFile
+	if outputDir == "" {
+		outputDir = filepath.Dir(inputPath)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	name := filepath.Base(inputPath)
+	outputPath := filepath.Join(outputDir, strings.TrimSuffix(name, filepath.Ext(name))+".luac")
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	result, err := cliCompiler.CompileFile(inputPath, outputPath, compiler.CompilationOptions{
+		ObfuscationLevel:         compiler.ObfuscationLevel(*obfuscateLevel),
+		StripDebug:               *stripDebug,
+		SuppressDecompileWarning: *suppressWarn,
+	})
+	if err == nil && !result.Success {
+		err = result.Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compile %s: %v", inputPath, err)
+	}
+	slog.Info("Compiled", "file", inputPath, "output", outputPath, "success", true, "duration", result.CompileTime,
+		"input_size", result.InputSize, "output_size", result.OutputSize)
+	return nil
+}
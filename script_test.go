@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCompileScriptWithCompiler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake compiler is a shell script")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	compilerPath := filepath.Join(dir, "luac_mta")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do [ \"$1\" = \"-o\" ] && printf '\\033LuaQ' > \"$2\"; shift; done\n"
+	if err := os.WriteFile(compilerPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	inputPath := filepath.Join(dir, "a.lua")
+	if err := os.WriteFile(inputPath, []byte("print(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(dir, "out")
+
+	// The flags are set on a copy of the command line sharing their values, so the flags of the
+	// test binary are not seen as set, nor are those set here by later tests once restored
+	commandLine := flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet(commandLine.Name(), flag.ContinueOnError)
+	commandLine.VisitAll(func(f *flag.Flag) {
+		flag.CommandLine.Var(f.Value, f.Name, f.Usage)
+	})
+	// -q lowers the log level of the process
+	logger, level := slog.Default(), logLevel.Level()
+	t.Cleanup(func() {
+		flag.CommandLine = commandLine
+		slog.SetDefault(logger)
+		logLevel.Set(level)
+	})
+	for name, value := range map[string]string{"compiler": compilerPath, "retries": "2", "o": outputDir, "q": "true"} {
+		f := commandLine.Lookup(name)
+		before := f.Value.String()
+		t.Cleanup(func() { f.Value.Set(before) })
+		if err := flag.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := compileScript(inputPath); err != nil {
+		t.Fatalf("Expected the script to compile with the given compiler: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "a.luac")); err != nil {
+		t.Errorf("Expected the compiled script: %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/notify"
+	"github.com/davidbozo/mta-bundler/internal/resource"
+	"github.com/davidbozo/mta-bundler/internal/schedule"
+)
+
+// runServe implements the serve command, which keeps running and rebuilds the input
+// according to the schedules of the project config file
+func runServe(args []string) error {
+	workspacesPath := flag.String("workspaces", "", "serve every workspace listed in this file instead of a single input path")
+	flag.Usage = func() {
+		binaryName := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [options] <input_path>\n", binaryName)
+		fmt.Fprintf(os.Stderr, "       %s serve -workspaces <file>\n\n", binaryName)
+		fmt.Fprintf(os.Stderr, "Runs the builds defined under \"schedules\" in %s until interrupted.\n", config.FileName)
+		fmt.Fprintf(os.Stderr, "All build options are accepted and apply to every scheduled build.\n")
+		fmt.Fprintf(os.Stderr, "With -workspaces, each listed project is built only from its own config file.\n\nOptions:\n")
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+
+	if watchMode {
+		return fmt.Errorf("serve does not support watch mode")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *workspacesPath != "" {
+		return serveWorkspaces(ctx, *workspacesPath)
+	}
+
+	inputPath, reportPath, cfg, err := prepareBuild()
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Schedules) == 0 {
+		return fmt.Errorf("no schedules configured, add a \"schedules\" section to %s", config.FileName)
+	}
+	entries, err := cfg.ScheduleEntries()
+	if err != nil {
+		return err
+	}
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+	b, err := newBundler(cliCompiler, inputPath, cfg.Exclude, nil)
+	if err != nil {
+		return err
+	}
+
+	statePath, err := scheduleStatePath(inputPath)
+	if err != nil {
+		return err
+	}
+	state, err := schedule.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Serving schedules", "input", inputPath, "count", len(entries))
+	logSchedules(slog.Default(), entries)
+
+	return schedule.Run(ctx, entries, state, buildJob(b.WithContext(ctx), inputPath, reportPath, webhooks, cfg, nil))
+}
+
+// servedWorkspace is a workspace prepared for serving
+type servedWorkspace struct {
+	workspace config.Workspace
+	config    config.Config
+	outputDir string
+	bundler   bundler.Bundler
+	entries   []schedule.Entry
+	state     schedule.State
+	webhooks  []notify.Webhook
+}
+
+// outputFlags are the flags allowed with -workspaces: they change the console output, not the builds
+var outputFlags = map[string]bool{
+	"workspaces": true, "q": true, "quiet": true, "vv": true, "verbose": true, "no-color": true,
+	"size-units": true, "duration-precision": true, "locale": true, "diagnostics": true,
+}
+
+// serveWorkspaces serves every workspace of a workspaces file. Workspaces share nothing but
+// the compiler: each one has its own config, output directory and schedule state.
+func serveWorkspaces(ctx context.Context, path string) error {
+	if err := setupWorkspaces(); err != nil {
+		return err
+	}
+
+	workspaces, err := config.LoadWorkspaces(path)
+	if err != nil {
+		return err
+	}
+
+	cliCompiler, err := newCompiler()
+	if err != nil {
+		return err
+	}
+
+	served := make([]servedWorkspace, 0, len(workspaces))
+	targets := make(map[string]string)
+	for _, ws := range workspaces {
+		sw, err := prepareWorkspace(ws, cliCompiler)
+		if err != nil {
+			return fmt.Errorf("workspace %q: %v", ws.Name, err)
+		}
+
+		if err := claimOutput(targets, ws, sw.outputDir); err != nil {
+			return err
+		}
+		served = append(served, sw)
+	}
+
+	slog.Info("Serving workspaces", "count", len(served))
+	for _, sw := range served {
+		slog.Info("Workspace", "workspace", sw.workspace.Name, "input", sw.workspace.Input)
+		logSchedules(slog.With("workspace", sw.workspace.Name), sw.entries)
+	}
+
+	// Builds run one at a time so their output does not interleave
+	var buildMu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(served))
+	for i, sw := range served {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := schedule.Run(ctx, sw.entries, sw.state, buildJob(sw.bundler.WithContext(ctx), sw.workspace.Input, "", sw.webhooks, sw.config, &buildMu)); err != nil {
+				errs[i] = fmt.Errorf("workspace %q: %v", sw.workspace.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// setupWorkspaces configures the console output of a command serving a workspaces file, where
+// each workspace is built from its own config file. Input paths and build options are
+// rejected, apart from the flags in allowed.
+func setupWorkspaces(allowed ...string) error {
+	if flag.NArg() > 0 {
+		return fmt.Errorf("an input path cannot be combined with -workspaces")
+	}
+	if err := configureLogging(); err != nil {
+		return err
+	}
+	if err := configureFormat(); err != nil {
+		return err
+	}
+	var setFlags []string
+	flag.Visit(func(f *flag.Flag) {
+		if !outputFlags[f.Name] && !slices.Contains(allowed, f.Name) {
+			setFlags = append(setFlags, "-"+f.Name)
+		}
+	})
+	if len(setFlags) > 0 {
+		return fmt.Errorf("build options (%s) cannot be combined with -workspaces, set them in each workspace's config file", strings.Join(setFlags, ", "))
+	}
+	return nil
+}
+
+// claimOutput records the directory the workspace ws writes to in targets, failing when
+// another workspace writes there: they would overwrite each other's builds
+func claimOutput(targets map[string]string, ws config.Workspace, outputDir string) error {
+	target := outputDir
+	if target == "" {
+		target = ws.Input
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return fmt.Errorf("workspace %q: cannot get absolute output path: %v", ws.Name, err)
+	}
+	if other, ok := targets[target]; ok {
+		return fmt.Errorf("workspaces %q and %q write to the same directory: %s", other, ws.Name, target)
+	}
+	targets[target] = ws.Name
+	return nil
+}
+
+// prepareWorkspace loads the config of a workspace and creates its bundler and schedule state
+func prepareWorkspace(ws config.Workspace, cliCompiler compiler.CLICompiler) (servedWorkspace, error) {
+	if err := validateInputPath(ws.Input); err != nil {
+		return servedWorkspace{}, err
+	}
+
+	cfg, err := ws.LoadConfig()
+	if err != nil {
+		return servedWorkspace{}, err
+	}
+	if len(cfg.Schedules) == 0 {
+		return servedWorkspace{}, fmt.Errorf("no schedules configured")
+	}
+	entries, err := cfg.ScheduleEntries()
+	if err != nil {
+		return servedWorkspace{}, err
+	}
+	// Prefix schedule names so log lines identify the workspace
+	for i := range entries {
+		entries[i].Name = ws.Name + "/" + entries[i].Name
+	}
+
+	statePath, err := workspaceStatePath(ws.Name)
+	if err != nil {
+		return servedWorkspace{}, err
+	}
+	state, err := schedule.LoadState(statePath)
+	if err != nil {
+		return servedWorkspace{}, err
+	}
+
+	b, err := workspaceBundler(ws, cfg, cliCompiler, nil)
+	if err != nil {
+		return servedWorkspace{}, err
+	}
+	return servedWorkspace{workspace: ws, config: cfg, outputDir: cfg.Output, bundler: b, entries: entries, state: state, webhooks: configWebhooks(cfg)}, nil
+}
+
+// workspaceBundler creates the bundler of a workspace from its config file alone, keeping
+// file hashes and parsed resources in cache when it is set
+func workspaceBundler(ws config.Workspace, cfg config.Config, cliCompiler compiler.CLICompiler, cache *bundler.Cache) (bundler.Bundler, error) {
+	var info []resource.InfoAttr
+	for _, spec := range cfg.Info {
+		// Validated when the config file was loaded
+		attr, _ := resource.ParseInfoAttr(spec)
+		info = append(info, attr)
+	}
+	// Workspace builds are not stamped, and the commit would be read once for all of them
+	for _, placeholder := range []string{"{build}", "{commit}"} {
+		used := strings.Contains(cfg.Banner, placeholder) || slices.ContainsFunc(info, func(attr resource.InfoAttr) bool {
+			return strings.Contains(attr.Value, placeholder)
+		})
+		if used {
+			return bundler.Bundler{}, fmt.Errorf("%s cannot be used in the banner or info attributes of workspace builds", placeholder)
+		}
+	}
+
+	walk := bundler.WalkOptions{Ignore: cfg.Ignore, MaxDepth: cfg.MaxDepth, SkipCategories: cfg.SkipCategories,
+		FollowSymlinks: cfg.FollowSymlinks != nil && *cfg.FollowSymlinks}
+	if cfg.Output != "" {
+		walk.Skip = []string{cfg.Output}
+	}
+	subtrees, err := bundler.FindSubtrees(ws.Input, walk, cfg.Exclude)
+	if err != nil {
+		return bundler.Bundler{}, err
+	}
+
+	dependencyCache, err := dependencyCacheDir(cfg.Dependencies)
+	if err != nil {
+		return bundler.Bundler{}, err
+	}
+	options, mergeMode := cfg.Apply(compiler.CompilationOptions{}, false)
+	isolate := cfg.MergeIsolate != nil && *cfg.MergeIsolate
+	if len(cfg.CompilerArgs) > 0 {
+		if cliCompiler.Remote() {
+			return bundler.Bundler{}, fmt.Errorf("compiler_args: luac_mta arguments cannot be passed to the compile API")
+		}
+		cliCompiler = cliCompiler.WithArgs(cfg.CompilerArgs)
+	}
+	if cfg.Preflight != nil && *cfg.Preflight && cliCompiler.Remote() {
+		return bundler.Bundler{}, fmt.Errorf("preflight: the compile API cannot parse scripts without compiling them")
+	}
+	if cfg.Retries != nil {
+		cliCompiler = cliCompiler.WithRetries(*cfg.Retries)
+	}
+	return bundler.NewBundler(cliCompiler, bundler.Options{
+		InputPath:       ws.Input,
+		OutputDir:       cfg.Output,
+		Compilation:     options,
+		MergeMode:       mergeMode,
+		Concat:          cfg.MergeStrategy == config.MergeStrategyConcat || isolate,
+		Isolate:         isolate,
+		Minify:          cfg.Minify != nil && *cfg.Minify,
+		RenameLocals:    cfg.RenameLocals != nil && *cfg.RenameLocals,
+		TreeShake:       cfg.TreeShake != nil && *cfg.TreeShake,
+		FoldConstants:   cfg.FoldConstants != nil && *cfg.FoldConstants,
+		Constants:       foldingConstants(cfg.Constants, nil),
+		BundleName:      cfg.BundleName,
+		Banner:          cfg.Banner,
+		Info:            info,
+		Exclude:         cfg.Exclude,
+		Ignore:          cfg.Ignore,
+		MaxDepth:        cfg.MaxDepth,
+		SkipCategories:  cfg.SkipCategories,
+		FollowSymlinks:  cfg.FollowSymlinks != nil && *cfg.FollowSymlinks,
+		Subtrees:        subtrees,
+		Verbatim:        cfg.Verbatim,
+		Unmerged:        cfg.MergeExclude,
+		Packs:           configPacks(cfg),
+		Splits:          configSplits(cfg),
+		Dependencies:    cfg.Dependencies,
+		DependencyCache: dependencyCache,
+		BuildInfo:       cfg.BuildInfo,
+		Scan:            cfg.Scan != nil && *cfg.Scan,
+		NoCache:         cfg.ClientCache != nil && !*cfg.ClientCache,
+		NoMinVersion:    cfg.RaiseMinVersion != nil && !*cfg.RaiseMinVersion,
+		LevelFallback:   cfg.LevelFallback != nil && *cfg.LevelFallback,
+		NoSyntaxCheck:   cfg.SyntaxCheck != nil && !*cfg.SyntaxCheck,
+		Preflight:       cfg.Preflight != nil && *cfg.Preflight,
+		Cache:           cache,
+	}), nil
+}
+
+// buildJob returns a schedule job running a full build of inputPath and sending its summary to
+// hooks, then applying the retention policy of cfg. When mu is set, the build holds it.
+func buildJob(b bundler.Bundler, inputPath, reportPath string, hooks []notify.Webhook, cfg config.Config, mu *sync.Mutex) schedule.Job {
+	return func(entry schedule.Entry) error {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+
+		result, err := b.Run()
+		recordBuild(inputPath, result, err)
+		notifyBuild(hooks, inputPath, result, err)
+		if policy, _ := cfg.Retention.Policy(); policy.Enabled() {
+			if err := pruneArtifacts(cfg.Retention, cfg.Dependencies, false); err != nil {
+				slog.Warn("Cannot prune artifacts", "error", err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeReport(result, nil, reportPath); err != nil {
+			return err
+		}
+		if failed := result.FailedCount(); failed > 0 {
+			return fmt.Errorf("%d of %d resource(s) failed", failed, len(result.Resources))
+		}
+		return nil
+	}
+}
+
+// logSchedules lists schedule entries
+func logSchedules(log *slog.Logger, entries []schedule.Entry) {
+	for _, entry := range entries {
+		log.Info("Schedule", "schedule", entry.Name, "cron", entry.Cron.String(), "catch_up", entry.CatchUp)
+	}
+}
+
+// scheduleStatePath returns the state file recording the last scheduled runs for inputPath.
+// State is kept per input so several serve processes do not share it.
+func scheduleStatePath(inputPath string) (string, error) {
+	stateDir, err := scheduleStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot get absolute input path: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(absInput))
+	return filepath.Join(stateDir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// workspaceStatePath returns the state file recording the last scheduled runs of a workspace
+func workspaceStatePath(name string) (string, error) {
+	stateDir, err := scheduleStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "workspaces", name+".json"), nil
+}
+
+// scheduleStateDir returns the directory holding schedule state files
+func scheduleStateDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %v", err)
+	}
+	return filepath.Join(cacheDir, "mta-bundler", "schedule"), nil
+}
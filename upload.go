@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/audit"
+	"github.com/davidbozo/mta-bundler/internal/config"
+	"github.com/davidbozo/mta-bundler/internal/upload"
+)
+
+// runUpload implements the upload command, which pushes a build directory to an S3-compatible
+// bucket of the config file
+func runUpload(args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	target := fs.String("target", "", "upload target of the config file (required)")
+	configFile := fs.String("config", "", "config file defining the upload targets (default is "+config.FileName+" in the current directory)")
+	prefix := fs.String("prefix", "", "key prefix overriding the one of the config file")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s upload -target <name> [-config <file>] [-prefix <prefix>] <build_dir>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Uploads the files of a build directory to an S3-compatible bucket, skipping files\n")
+		fmt.Fprintf(os.Stderr, "the bucket already holds, and writes a manifest listing them with their SHA-256.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one build directory")
+	}
+	if *target == "" {
+		fs.Usage()
+		return fmt.Errorf("an upload target is required (-target)")
+	}
+
+	path := *configFile
+	if path == "" {
+		found, ok := config.Find(".")
+		if !ok {
+			return fmt.Errorf("no %s in the current directory, upload targets are defined in a config file (-config)", config.FileName)
+		}
+		path = found
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	uploadTarget, ok := cfg.Upload(*target)
+	if !ok {
+		return fmt.Errorf("upload target %q is not defined in %s", *target, cfg.Path)
+	}
+	if *prefix != "" {
+		uploadTarget.Prefix = *prefix
+	}
+	return uploadBuild(uploadTarget, fs.Arg(0))
+}
+
+// uploadBuild uploads the files of buildDir to the bucket of target and records the upload
+// in the audit log
+func uploadBuild(target config.Upload, buildDir string) error {
+	if info, err := os.Stat(buildDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("build directory does not exist: %s", buildDir)
+	}
+	accessKey, secretKey, sessionToken, err := target.ResolveCredentials()
+	if err != nil {
+		return err
+	}
+
+	bucket := upload.Bucket{
+		Endpoint:         target.Endpoint,
+		Region:           target.SigningRegion(),
+		Name:             target.Bucket,
+		Prefix:           target.Prefix,
+		PathStyle:        target.PathStyle,
+		AccessKey:        accessKey,
+		SecretKey:        secretKey,
+		SessionToken:     sessionToken,
+		ContentAddressed: target.ContentAddressed,
+	}
+	result, err := bucket.Upload(buildDir)
+	entry := audit.NewEntry(audit.ActionUpload, "s3://"+target.Bucket+"/"+target.Prefix)
+	if err != nil {
+		entry.Summary = fmt.Sprintf("stopped after %d file(s): %v", result.Uploaded, err)
+		recordAudit(entry)
+		return fmt.Errorf("upload to %s stopped after %d file(s): %v", target.Name, result.Uploaded, err)
+	}
+	entry.Success = true
+	entry.Summary = fmt.Sprintf("%d file(s) uploaded, %d unchanged, from %s", result.Uploaded, result.Skipped, buildDir)
+	if data, err := json.Marshal(result.Manifest.Files); err == nil {
+		sum := sha256.Sum256(data)
+		entry.ManifestHash = hex.EncodeToString(sum[:])
+	}
+	recordAudit(entry)
+
+	slog.Info("Uploaded build", "upload", target.Name, "bucket", target.Bucket, "prefix", target.Prefix,
+		"files", result.Uploaded, "unchanged", result.Skipped, "total_size", result.Bytes, "success", true)
+	return nil
+}
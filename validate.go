@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbozo/mta-bundler/internal/bundler"
+	"github.com/davidbozo/mta-bundler/internal/compiler"
+	"github.com/davidbozo/mta-bundler/internal/config"
+)
+
+// runValidate implements the validate command, which checks that every meta.xml of a path
+// parses, references existing, readable files and follows the lint rules. Unlike -check-only
+// it needs no compiler and ignores the build options, so it suits merge gates on machines
+// without luac_mta.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	only := fs.String("only", "", "comma-separated resource names or path globs to validate, skipping all others")
+	exclude := fs.String("exclude", "", "comma-separated resource names or path globs to skip, added to the config file's exclude list")
+	cfgPath := fs.String("config", "", "config file with the exclude list and lint rules (default is "+config.FileName+" in the input)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s validate [options] <input_path>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Parses every meta.xml of the input and reports missing, duplicate, unreadable\n")
+		fmt.Fprintf(os.Stderr, "and out-of-resource files, entries without a src, unknown script types\n")
+		fmt.Fprintf(os.Stderr, "and breaks of the lint rules, without compiling anything. Exits with status 1\n")
+		fmt.Fprintf(os.Stderr, "when any problem or lint error is found.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one input path")
+	}
+	inputPath := fs.Arg(0)
+	if err := validateInputPath(inputPath); err != nil {
+		return err
+	}
+
+	var cfg config.Config
+	if *cfgPath == "" {
+		*cfgPath, _ = config.Find(inputPath)
+	}
+	if *cfgPath != "" {
+		loaded, err := config.Load(*cfgPath)
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+	}
+	rules, err := cfg.LintSettings()
+	if err != nil {
+		return err
+	}
+
+	b := bundler.NewBundler(compiler.CLICompiler{}, bundler.Options{
+		InputPath:      inputPath,
+		Exclude:        append(cfg.Exclude, splitList(*exclude)...),
+		Ignore:         cfg.Ignore,
+		MaxDepth:       cfg.MaxDepth,
+		SkipCategories: cfg.SkipCategories,
+		FollowSymlinks: cfg.FollowSymlinks != nil && *cfg.FollowSymlinks,
+		Only:           splitList(*only),
+		NoSyntaxCheck:  cfg.SyntaxCheck != nil && !*cfg.SyntaxCheck,
+		Lint:           rules,
+	})
+	result, err := b.Check()
+	if err != nil {
+		return err
+	}
+	// Attention cases depend on the build options, which a validation leaves out
+	result.Attention = nil
+	return reportCheck(result, "Validation completed")
+}